@@ -0,0 +1,275 @@
+// Command valkeysender-bench drives load through a sender against a live
+// Valkey and reports throughput and latency percentiles, so performance
+// regressions can be measured. It reads the same VALKEY_SENDER_* environment
+// configuration as the library, optionally layered over a config file.
+//
+// Usage:
+//
+//	valkeysender-bench [flags]
+//
+// Each worker sends back to back for -duration (or until -messages have been
+// sent), one message per call or -batch messages per SendBatch call. Latency
+// is measured per call. The configured rate limit is lifted unless -rate is
+// given, and the queue is purged afterwards unless -keep is set.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// errUsage reports invalid command line usage; the usage text has already been printed
+var errUsage = errors.New("invalid usage")
+
+// benchOptions are the load parameters
+type benchOptions struct {
+	queue       string
+	size        int
+	concurrency int
+	batch       int
+	duration    time.Duration
+	messages    int64
+	rate        int
+	keep        bool
+}
+
+// result summarizes a run
+type result struct {
+	Queue       string        `json:"queue"`
+	Size        int           `json:"message_size"`
+	Concurrency int           `json:"concurrency"`
+	Batch       int           `json:"batch"`
+	Messages    int64         `json:"messages"`
+	Calls       int           `json:"calls"`
+	Errors      int64         `json:"errors"`
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	PerSecond   float64       `json:"messages_per_sec"`
+	MBPerSecond float64       `json:"mb_per_sec"`
+	Min         time.Duration `json:"latency_min_ns"`
+	Avg         time.Duration `json:"latency_avg_ns"`
+	P50         time.Duration `json:"latency_p50_ns"`
+	P90         time.Duration `json:"latency_p90_ns"`
+	P99         time.Duration `json:"latency_p99_ns"`
+	P999        time.Duration `json:"latency_p999_ns"`
+	Max         time.Duration `json:"latency_max_ns"`
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "valkeysender-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the flags, connects, runs the load and prints the result
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var opts benchOptions
+	flags := flag.NewFlagSet("valkeysender-bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "config file (YAML, JSON or TOML); environment variables override it")
+	flags.StringVar(&opts.queue, "queue", "valkeysender-bench", "queue to send to")
+	flags.IntVar(&opts.size, "size", 256, "payload size in bytes")
+	flags.IntVar(&opts.concurrency, "concurrency", 8, "concurrent senders")
+	flags.IntVar(&opts.batch, "batch", 1, "messages per call; above 1 sends with SendBatch")
+	flags.DurationVar(&opts.duration, "duration", 10*time.Second, "how long to send")
+	flags.Int64Var(&opts.messages, "messages", 0, "stop after this many messages (0 runs for -duration)")
+	flags.IntVar(&opts.rate, "rate", 0, "sender rate limit in calls per second (0 lifts the limit)")
+	flags.BoolVar(&opts.keep, "keep", false, "keep the sent messages instead of purging the queue")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: valkeysender-bench [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 || opts.size < 0 || opts.concurrency < 1 || opts.batch < 1 || opts.duration <= 0 || opts.messages < 0 || opts.rate < 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	config.RateLimitRequests = math.MaxInt32
+	config.RateLimitBurst = math.MaxInt32
+	if opts.rate > 0 {
+		config.RateLimitRequests = opts.rate
+		config.RateLimitBurst = opts.rate
+	}
+
+	// Keep stdout for the result; only warnings and errors are logged
+	options := &valkeysender.SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	sender, err := valkeysender.NewSender(config, options)
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	res, err := runLoad(ctx, sender, opts)
+	if err != nil {
+		return err
+	}
+
+	if !opts.keep {
+		// The run context may be cancelled already; purging is still wanted
+		if _, err := sender.PurgeQueue(context.Background(), opts.queue); err != nil {
+			fmt.Fprintf(stderr, "failed to purge %s: %v\n", opts.queue, err)
+		}
+	}
+
+	if *asJSON {
+		return json.NewEncoder(stdout).Encode(res)
+	}
+	printResult(stdout, res)
+	return nil
+}
+
+// loadConfig loads the environment configuration, layered over a file if given
+func loadConfig(path string) (*valkeysender.Config, error) {
+	if path != "" {
+		return valkeysender.LoadConfigWithOverrides(path)
+	}
+	return valkeysender.LoadConfig()
+}
+
+// runLoad sends from opts.concurrency workers until the duration elapses,
+// the message limit is reached or ctx is done
+func runLoad(ctx context.Context, sender valkeysender.Sender, opts benchOptions) (result, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	payload := make([]byte, opts.size)
+	for i := range payload {
+		payload[i] = 'a' + byte(i%26)
+	}
+	batch := make([]interface{}, opts.batch)
+	for i := range batch {
+		batch[i] = payload
+	}
+
+	var (
+		issued, sent, failed int64
+		firstErr             error
+		errOnce              sync.Once
+		wg                   sync.WaitGroup
+	)
+	latencies := make([][]time.Duration, opts.concurrency)
+
+	start := time.Now()
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				// Claim the next messages so the limit is never overshot
+				n := int64(opts.batch)
+				if opts.messages > 0 {
+					end := atomic.AddInt64(&issued, n)
+					if end-n >= opts.messages {
+						return
+					}
+					n = min(n, opts.messages-(end-n))
+				}
+
+				callStart := time.Now()
+				var err error
+				if opts.batch == 1 {
+					err = sender.SendMessage(ctx, opts.queue, payload)
+				} else {
+					err = sender.SendBatch(ctx, opts.queue, batch[:n])
+				}
+				if err != nil {
+					// Sends cut short by the end of the run aren't failures
+					if ctx.Err() != nil {
+						return
+					}
+					atomic.AddInt64(&failed, 1)
+					errOnce.Do(func() { firstErr = err })
+					continue
+				}
+				latencies[w] = append(latencies[w], time.Since(callStart))
+				atomic.AddInt64(&sent, n)
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if sent == 0 && firstErr != nil {
+		return result{}, fmt.Errorf("every send failed: %w", firstErr)
+	}
+	return summarize(opts, slices.Concat(latencies...), sent, failed, elapsed), nil
+}
+
+// summarize computes throughput and latency percentiles of a run
+func summarize(opts benchOptions, latencies []time.Duration, sent, failed int64, elapsed time.Duration) result {
+	res := result{
+		Queue:       opts.queue,
+		Size:        opts.size,
+		Concurrency: opts.concurrency,
+		Batch:       opts.batch,
+		Messages:    sent,
+		Calls:       len(latencies),
+		Errors:      failed,
+		Elapsed:     elapsed,
+	}
+	if elapsed > 0 {
+		res.PerSecond = float64(sent) / elapsed.Seconds()
+		res.MBPerSecond = res.PerSecond * float64(opts.size) / (1 << 20)
+	}
+	if len(latencies) == 0 {
+		return res
+	}
+
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[int(math.Ceil(p*float64(len(latencies))))-1]
+	}
+	res.Min = latencies[0]
+	res.Avg = total / time.Duration(len(latencies))
+	res.P50 = percentile(0.50)
+	res.P90 = percentile(0.90)
+	res.P99 = percentile(0.99)
+	res.P999 = percentile(0.999)
+	res.Max = latencies[len(latencies)-1]
+	return res
+}
+
+// printResult prints a human readable summary
+func printResult(w io.Writer, res result) {
+	fmt.Fprintf(w, "queue %s, %d byte messages, %d workers, batch %d\n", res.Queue, res.Size, res.Concurrency, res.Batch)
+	fmt.Fprintf(w, "sent %d messages in %d calls over %s (%d errors)\n", res.Messages, res.Calls, res.Elapsed.Round(time.Millisecond), res.Errors)
+	fmt.Fprintf(w, "throughput: %.0f msg/s, %.2f MB/s\n", res.PerSecond, res.MBPerSecond)
+	fmt.Fprintf(w, "latency per call: min %s avg %s p50 %s p90 %s p99 %s p99.9 %s max %s\n",
+		res.Min, res.Avg, res.P50, res.P90, res.P99, res.P999, res.Max)
+}
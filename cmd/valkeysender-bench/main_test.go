@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestBench(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"single", []string{"-concurrency", "3"}},
+		{"batch", []string{"-concurrency", "2", "-batch", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-json", "-messages", "10", "-size", "32"}, tt.args...)
+			if err := run(context.Background(), args, &stdout, &stderr); err != nil {
+				t.Fatalf("run failed: %v\n%s", err, stderr.String())
+			}
+
+			var res result
+			if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+				t.Fatalf("Invalid JSON output %q: %v", stdout.String(), err)
+			}
+			if res.Messages != 10 || res.Errors != 0 {
+				t.Errorf("Expected 10 messages without errors, got %+v", res)
+			}
+			if res.Calls == 0 || res.P50 <= 0 || res.Max < res.P99 || res.P99 < res.P50 {
+				t.Errorf("Unexpected latencies: %+v", res)
+			}
+			if server.Exists("queue:valkeysender-bench") {
+				t.Error("Expected the queue to be purged")
+			}
+		})
+	}
+
+	var stdout bytes.Buffer
+	if err := run(context.Background(), []string{"-duration", "50ms", "-keep"}, &stdout, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "throughput:") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+	if !server.Exists("queue:valkeysender-bench") {
+		t.Error("Expected -keep to leave the queue")
+	}
+}
+
+func TestBenchUsage(t *testing.T) {
+	err := run(context.Background(), []string{"-concurrency", "0"}, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, errUsage) {
+		t.Errorf("Expected errUsage, got %v", err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	res := summarize(benchOptions{size: 1 << 20}, latencies, 200, 0, 2*time.Second)
+	if res.PerSecond != 100 || res.MBPerSecond != 100 {
+		t.Errorf("Unexpected throughput: %+v", res)
+	}
+	if res.Min != time.Millisecond || res.P50 != 50*time.Millisecond || res.P99 != 99*time.Millisecond || res.Max != 100*time.Millisecond {
+		t.Errorf("Unexpected latencies: %+v", res)
+	}
+}
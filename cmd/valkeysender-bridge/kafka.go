@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// pollRecords bounds how many records a poll buffers
+const pollRecords = 500
+
+// kafkaSource is a valkeysender.KafkaSource consuming with a franz-go
+// consumer group client. Auto-commit is off: offsets are committed by the
+// bridge, after the records were sent.
+type kafkaSource struct {
+	client   *kgo.Client
+	buffered []*kgo.Record
+}
+
+// newKafkaSource joins group on brokers and consumes topics
+func newKafkaSource(brokers []string, group string, topics []string, opts ...kgo.Opt) (*kafkaSource, error) {
+	opts = append([]kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(topics...),
+		kgo.DisableAutoCommit(),
+	}, opts...)
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	return &kafkaSource{client: client}, nil
+}
+
+// Fetch returns the next buffered record, polling for more when none is left
+func (s *kafkaSource) Fetch(ctx context.Context) (valkeysender.KafkaRecord, error) {
+	for len(s.buffered) == 0 {
+		fetches := s.client.PollRecords(ctx, pollRecords)
+		if fetches.IsClientClosed() {
+			return valkeysender.KafkaRecord{}, errors.New("Kafka client closed")
+		}
+		// Keep records polled as ctx ended: the client has moved past them
+		s.buffered = fetches.Records()
+		if len(s.buffered) > 0 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return valkeysender.KafkaRecord{}, err
+		}
+		for _, fetchErr := range fetches.Errors() {
+			return valkeysender.KafkaRecord{}, fmt.Errorf("%s/%d: %w", fetchErr.Topic, fetchErr.Partition, fetchErr.Err)
+		}
+	}
+
+	record := s.buffered[0]
+	s.buffered = s.buffered[1:]
+
+	headers := make(map[string]string, len(record.Headers))
+	for _, header := range record.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	return valkeysender.KafkaRecord{
+		Topic:     record.Topic,
+		Partition: int(record.Partition),
+		Offset:    record.Offset,
+		Key:       record.Key,
+		Value:     record.Value,
+		Headers:   headers,
+		Time:      record.Timestamp,
+	}, nil
+}
+
+// Commit commits the offsets after records in their partitions
+func (s *kafkaSource) Commit(ctx context.Context, records ...valkeysender.KafkaRecord) error {
+	committed := make([]*kgo.Record, len(records))
+	for i, record := range records {
+		committed[i] = &kgo.Record{
+			Topic:       record.Topic,
+			Partition:   int32(record.Partition),
+			Offset:      record.Offset,
+			LeaderEpoch: -1,
+		}
+	}
+	return s.client.CommitRecords(ctx, committed...)
+}
+
+// Close leaves the consumer group and closes the client
+func (s *kafkaSource) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,124 @@
+// Command valkeysender-bridge forwards Kafka records into Valkey queues with
+// a valkeysender.Bridge, so producers can move off Kafka one at a time while
+// consumers already read from Valkey. It reads the same VALKEY_SENDER_*
+// environment configuration as the library, optionally layered over a
+// config file.
+//
+// Usage:
+//
+//	valkeysender-bridge [flags]
+//
+// The topics of VALKEY_SENDER_BRIDGE_ROUTES are consumed from the brokers of
+// VALKEY_SENDER_BRIDGE_BROKERS in the consumer group VALKEY_SENDER_BRIDGE_GROUP,
+// and forwarded to their queues. Offsets are committed only after records
+// were sent. The bridge runs until interrupted, then commits what it has
+// forwarded and exits; it exits with an error on records the sender rejects
+// and on Kafka failures, so run it under a supervisor that restarts it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// errUsage reports invalid command line usage; the usage text has already been printed
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "valkeysender-bridge: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the flags, connects to Valkey and Kafka and forwards records
+// until ctx is done. Extra client options are for tests.
+func run(ctx context.Context, args []string, stderr io.Writer, kafkaOpts ...kgo.Opt) error {
+	flags := flag.NewFlagSet("valkeysender-bridge", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "config file (YAML, JSON or TOML); environment variables override it")
+	debug := flags.Bool("debug", false, "log every commit")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: valkeysender-bridge [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	bridgeConfig := config.BridgeConfig()
+	if len(config.BridgeBrokers) == 0 {
+		return fmt.Errorf("no Kafka brokers, set VALKEY_SENDER_BRIDGE_BROKERS")
+	}
+	if len(bridgeConfig.Routes) == 0 {
+		return fmt.Errorf("no topics to bridge, set VALKEY_SENDER_BRIDGE_ROUTES")
+	}
+
+	level := slog.LevelInfo
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	sender, err := valkeysender.NewSender(config, &valkeysender.SenderOptions{Logger: logger})
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	topics := slices.Sorted(maps.Keys(bridgeConfig.Routes))
+	source, err := newKafkaSource(config.BridgeBrokers, config.BridgeGroup, topics, kafkaOpts...)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	bridgeConfig.Logger = logger
+	bridge, err := valkeysender.NewBridge(source, sender, bridgeConfig)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Bridging Kafka topics",
+		slog.Any("topics", topics),
+		slog.Any("brokers", config.BridgeBrokers),
+		slog.String("group", config.BridgeGroup),
+	)
+	return bridge.Run(ctx)
+}
+
+// loadConfig loads the environment configuration, layered over a file if given
+func loadConfig(path string) (*valkeysender.Config, error) {
+	if path != "" {
+		return valkeysender.LoadConfigWithOverrides(path)
+	}
+	return valkeysender.LoadConfig()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/kversion"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+func TestBridge(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "orders"))
+	if err != nil {
+		t.Fatalf("Failed to start Kafka: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	brokers := strings.Join(cluster.ListenAddrs(), ",")
+
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+	t.Setenv("VALKEY_SENDER_BRIDGE_BROKERS", brokers)
+	t.Setenv("VALKEY_SENDER_BRIDGE_GROUP", "bridge-test")
+	t.Setenv("VALKEY_SENDER_BRIDGE_ROUTES", "orders=orders-queue")
+	t.Setenv("VALKEY_SENDER_BRIDGE_COMMIT_INTERVAL", "20ms")
+
+	// kfake speaks the protocol versions of Kafka 3.7
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.MaxVersions(kversion.V3_7_0()))
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	var stderr bytes.Buffer
+	go func() {
+		done <- run(ctx, nil, &stderr, kgo.FetchMaxWait(50*time.Millisecond), kgo.MaxVersions(kversion.V3_7_0()))
+	}()
+
+	records := []*kgo.Record{
+		{Topic: "orders", Key: []byte("order-1"), Value: []byte(`{"id":1}`), Headers: []kgo.RecordHeader{{Key: "trace", Value: []byte("abc")}}},
+		{Topic: "orders", Key: []byte("order-2"), Value: []byte(`{"id":2}`)},
+	}
+	if err := producer.ProduceSync(context.Background(), records...).FirstErr(); err != nil {
+		t.Fatalf("Failed to produce: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		list, _ := server.List("queue:orders-queue")
+		return len(list) == 2
+	})
+	list, _ := server.List("queue:orders-queue")
+	slices.Reverse(list) // pushed at the head
+	for i, element := range list {
+		var envelope valkeysender.MessageEnvelope
+		if err := json.Unmarshal([]byte(element), &envelope); err != nil {
+			t.Fatalf("Invalid envelope %q: %v", element, err)
+		}
+		if string(envelope.Payload) != string(records[i].Value) {
+			t.Errorf("Expected payload %s, got %s", records[i].Value, envelope.Payload)
+		}
+		if envelope.Headers[valkeysender.DefaultBridgeKeyHeader] != string(records[i].Key) {
+			t.Errorf("Expected key header %q, got %v", records[i].Key, envelope.Headers)
+		}
+		if i == 0 && envelope.Headers["trace"] != "abc" {
+			t.Errorf("Expected the Kafka header to be kept, got %v", envelope.Headers)
+		}
+	}
+
+	// Forwarded records are committed on the interval
+	waitFor(t, func() bool { return committedOffset(t, producer, "bridge-test", "orders") == 2 })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run failed: %v\n%s", err, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after cancel")
+	}
+}
+
+func TestBridgeConfigRequired(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+
+	t.Setenv("VALKEY_SENDER_BRIDGE_ROUTES", "orders=orders-queue")
+	err := run(context.Background(), nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "VALKEY_SENDER_BRIDGE_BROKERS") {
+		t.Errorf("Expected a missing brokers error, got %v", err)
+	}
+
+	t.Setenv("VALKEY_SENDER_BRIDGE_ROUTES", "")
+	t.Setenv("VALKEY_SENDER_BRIDGE_BROKERS", "localhost:9092")
+	err = run(context.Background(), nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "VALKEY_SENDER_BRIDGE_ROUTES") {
+		t.Errorf("Expected a missing routes error, got %v", err)
+	}
+}
+
+func TestBridgeUsage(t *testing.T) {
+	err := run(context.Background(), []string{"extra"}, &bytes.Buffer{})
+	if !errors.Is(err, errUsage) {
+		t.Errorf("Expected errUsage, got %v", err)
+	}
+}
+
+// committedOffset returns the offset group committed for partition 0 of topic
+func committedOffset(t *testing.T, client *kgo.Client, group, topic string) int64 {
+	t.Helper()
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = group
+	reqTopic := kmsg.NewOffsetFetchRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.Partitions = []int32{0}
+	req.Topics = append(req.Topics, reqTopic)
+
+	resp, err := req.RequestWith(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Failed to fetch offsets: %v", err)
+	}
+	for _, respTopic := range resp.Topics {
+		for _, partition := range respTopic.Partitions {
+			return partition.Offset
+		}
+	}
+	return -1
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
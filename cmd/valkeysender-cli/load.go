@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// loadOffset is the content of the offset file: how far the input was sent
+type loadOffset struct {
+	Offset int64 `json:"offset"` // bytes of input sent
+	Lines  int64 `json:"lines"`  // lines of input sent, including blank ones
+	Sent   int64 `json:"sent"`   // messages sent
+}
+
+// runLoad streams newline-delimited JSON into a queue in batches. After
+// every batch the input position is saved to the offset file, so an
+// interrupted load resumes after the last batch sent; the file is removed
+// once the whole input was sent.
+func runLoad(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("load", "", config)
+	path := flags.String("file", "-", "NDJSON file to load, - for stdin")
+	size := flags.Int("size", 500, "messages per batch")
+	offsetPath := flags.String("offset-file", "", "file recording progress for resuming (default <file>.offset, none for stdin)")
+	interval := flags.Duration("progress", 5*time.Second, "progress report interval (0 disables)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 0 || *size < 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	input := stdin
+	var total int64 // input size, 0 if unknown
+	if *path != "-" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			total = info.Size()
+		}
+		input = file
+		if *offsetPath == "" {
+			*offsetPath = *path + ".offset"
+		}
+	}
+
+	// Skip what an interrupted load already sent
+	progress, err := readLoadOffset(*offsetPath)
+	if err != nil {
+		return err
+	}
+	if progress.Offset > 0 {
+		if seeker, ok := input.(io.Seeker); ok {
+			_, err = seeker.Seek(progress.Offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, input, progress.Offset)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resume at byte %d: %w", progress.Offset, err)
+		}
+		fmt.Fprintf(stdout, "resuming at line %d (%d messages already sent)\n", progress.Lines+1, progress.Sent)
+	}
+
+	start := time.Now()
+	resumedFrom := progress.Sent
+	lastReport := start
+	report := func() {
+		rate := float64(progress.Sent-resumedFrom) / time.Since(start).Seconds()
+		if total > 0 {
+			fmt.Fprintf(stdout, "sent %d messages (%.1f%%, %.0f/s)\n", progress.Sent, 100*float64(progress.Offset)/float64(total), rate)
+		} else {
+			fmt.Fprintf(stdout, "sent %d messages (%.0f/s)\n", progress.Sent, rate)
+		}
+	}
+
+	// position is how far the input was read; progress only advances once sent
+	position := progress
+	messages := make([]interface{}, 0, *size)
+	flush := func() error {
+		if len(messages) > 0 {
+			if err := sender.SendBatch(ctx, *queue, messages); err != nil {
+				if *offsetPath != "" {
+					return fmt.Errorf("sent %d messages before failing, rerun to resume: %w", progress.Sent, err)
+				}
+				return fmt.Errorf("sent %d messages before failing: %w", progress.Sent, err)
+			}
+			position.Sent += int64(len(messages))
+			messages = messages[:0]
+		}
+		progress = position
+		if err := writeLoadOffset(*offsetPath, progress); err != nil {
+			return err
+		}
+		if *interval > 0 && time.Since(lastReport) >= *interval {
+			report()
+			lastReport = time.Now()
+		}
+		return nil
+	}
+
+	reader := bufio.NewReaderSize(input, 64*1024)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			message := bytes.TrimSpace(line)
+			if len(message) > 0 && !json.Valid(message) {
+				// Send what precedes the line, so a rerun resumes right at it
+				if err := flush(); err != nil {
+					return err
+				}
+				return fmt.Errorf("line %d is not valid JSON", position.Lines+1)
+			}
+			position.Offset += int64(len(line))
+			position.Lines++
+			if len(message) > 0 {
+				messages = append(messages, json.RawMessage(message))
+			}
+			if len(messages) == *size {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read messages: %w", readErr)
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// The load is complete; the next one starts from the beginning
+	if *offsetPath != "" {
+		if err := os.Remove(*offsetPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "sent %d messages to %s in %s\n", progress.Sent, *queue, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// readLoadOffset reads the offset file, if there is one
+func readLoadOffset(path string) (loadOffset, error) {
+	var offset loadOffset
+	if path == "" {
+		return offset, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return offset, nil
+	}
+	if err != nil {
+		return offset, err
+	}
+	if err := json.Unmarshal(data, &offset); err != nil {
+		return offset, fmt.Errorf("invalid offset file %s: %w", path, err)
+	}
+	return offset, nil
+}
+
+// writeLoadOffset replaces the offset file, so it is never left half written
+func writeLoadOffset(path string, offset loadOffset) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(offset)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+	return nil
+}
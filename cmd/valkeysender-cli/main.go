@@ -0,0 +1,489 @@
+// Command valkeysender-cli injects messages into and inspects valkeysender
+// queues. It reads the same VALKEY_SENDER_* environment configuration as the
+// library, optionally layered over a config file.
+//
+// Usage:
+//
+//	valkeysender-cli [-config file] <command> [flags] [args]
+//
+// Commands:
+//
+//	send    send one message (JSON argument, or stdin with "-")
+//	batch   send one message per line of a file (or stdin)
+//	load    stream NDJSON from a file (or stdin) into a queue, resumably
+//	peek    print messages without consuming them
+//	purge   remove all (or only expired) messages from a queue
+//	migrate move messages between queues, rate limited
+//	replay  move matching messages back, e.g. out of a dead letter queue
+//	stats   print statistics of one or all queues
+//	health  print the sender health; exits 1 when unhealthy
+//	ping    measure the round trip to Valkey
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+	"golang.org/x/time/rate"
+)
+
+// errUsage reports invalid command line usage; the usage text has already been printed
+var errUsage = errors.New("invalid usage")
+
+// command is one CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"send", "send one message", runSend},
+	{"batch", "send one message per input line", runBatch},
+	{"load", "bulk load NDJSON with progress and resume", runLoad},
+	{"peek", "print messages without consuming them", runPeek},
+	{"purge", "remove messages from a queue", runPurge},
+	{"migrate", "move messages from one queue to another", runMigrate},
+	{"replay", "re-drive dead letter messages into a queue", runReplay},
+	{"stats", "print queue statistics", runStats},
+	{"health", "print sender health", runHealth},
+	{"ping", "measure the round trip to Valkey", runPing},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "valkeysender-cli: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags, connects and dispatches to the subcommand
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("valkeysender-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "config file (YAML, JSON or TOML); environment variables override it")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: valkeysender-cli [-config file] <command> [flags] [args]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %-7s %s\n", c.name, c.summary)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == flags.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		return errUsage
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	// Keep stdout for command output; only warnings and errors are logged
+	options := &valkeysender.SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	sender, err := valkeysender.NewSender(config, options)
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	return cmd.run(ctx, sender, config, flags.Args()[1:], stdin, stdout)
+}
+
+// loadConfig loads the environment configuration, layered over a file if given
+func loadConfig(path string) (*valkeysender.Config, error) {
+	if path != "" {
+		return valkeysender.LoadConfigWithOverrides(path)
+	}
+	return valkeysender.LoadConfig()
+}
+
+// newFlags creates the flag set of a subcommand with the common -queue flag
+func newFlags(name, usage string, config *valkeysender.Config) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	queue := flags.String("queue", config.DefaultQueue, "queue name")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: valkeysender-cli %s [flags] %s\n", name, usage)
+		flags.PrintDefaults()
+	}
+	return flags, queue
+}
+
+// runSend sends one message
+func runSend(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("send", "<message | ->", config)
+	ttl := flags.Duration("ttl", 0, "message TTL (default from config)")
+	messageType := flags.String("type", "", "message type header")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	input := flags.Arg(0)
+	if input == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+		input = strings.TrimSpace(string(data))
+	}
+	message := parseMessage(input)
+
+	var err error
+	switch {
+	case *messageType != "":
+		err = sender.SendTyped(ctx, *queue, *messageType, message)
+	case *ttl > 0:
+		err = sender.SendMessageWithTTL(ctx, *queue, message, *ttl)
+	default:
+		err = sender.SendMessage(ctx, *queue, message)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "sent 1 message to %s\n", *queue)
+	return nil
+}
+
+// runBatch sends every non-empty input line as one message
+func runBatch(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("batch", "[file | -]", config)
+	size := flags.Int("size", 100, "messages per batch")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 1 || *size < 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	input := stdin
+	if name := flags.Arg(0); name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var sent int
+	messages := make([]interface{}, 0, *size)
+	flush := func() error {
+		if len(messages) == 0 {
+			return nil
+		}
+		if err := sender.SendBatch(ctx, *queue, messages); err != nil {
+			return fmt.Errorf("sent %d messages before failing: %w", sent, err)
+		}
+		sent += len(messages)
+		messages = messages[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		messages = append(messages, parseMessage(line))
+		if len(messages) == *size {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read messages: %w", err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "sent %d messages to %s\n", sent, *queue)
+	return nil
+}
+
+// runPeek prints messages as JSON lines, next to be consumed first
+func runPeek(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("peek", "", config)
+	offset := flags.Int64("offset", 0, "messages to skip")
+	count := flags.Int64("count", 10, "messages to print")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	envelopes, err := sender.PeekMessages(ctx, *queue, *offset, *count)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	for _, envelope := range envelopes {
+		if err := encoder.Encode(newPrintedEnvelope(envelope)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPurge removes all messages, or only expired ones, from a queue
+func runPurge(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("purge", "", config)
+	expired := flags.Bool("expired", false, "only remove messages whose TTL has elapsed (requires the message TTL strategy)")
+	yes := flags.Bool("yes", false, "confirm removing every message")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if !*expired && !*yes {
+		return fmt.Errorf("purging %s removes every message; pass -yes to confirm", *queue)
+	}
+
+	var removed int64
+	var err error
+	if *expired {
+		removed, err = sender.PurgeExpired(ctx, *queue)
+	} else {
+		removed, err = sender.PurgeQueue(ctx, *queue)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "removed %d messages from %s\n", removed, *queue)
+	return nil
+}
+
+// runMigrate moves messages between queues, e.g. to rename a queue or to
+// drain a poisoned one into quarantine, in steps paced by -rate
+func runMigrate(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := flags.String("from", "", "queue to move messages from")
+	to := flags.String("to", "", "queue to move messages to")
+	count := flags.Int64("count", 0, "messages to move (0 moves every message queued at the start)")
+	perSecond := flags.Int("rate", 0, "messages moved per second (0 for the sender's rate limit only)")
+	size := flags.Int("size", 100, "messages moved per step")
+	interval := flags.Duration("progress", 5*time.Second, "progress report interval (0 disables)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: valkeysender-cli migrate -from queue -to queue [flags]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 0 || *from == "" || *to == "" || *count < 0 || *perSecond < 0 || *size < 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	total := *count
+	if total == 0 {
+		size, err := sender.GetQueueSize(ctx, *from)
+		if err != nil {
+			return err
+		}
+		total = size
+	}
+
+	step := int64(*size)
+	var limiter *rate.Limiter
+	if *perSecond > 0 {
+		step = min(step, int64(*perSecond))
+		limiter = rate.NewLimiter(rate.Limit(*perSecond), int(step))
+	}
+
+	start := time.Now()
+	lastReport := start
+	var moved int64
+	for moved < total {
+		chunk := min(total-moved, step)
+		if limiter != nil {
+			if err := limiter.WaitN(ctx, int(chunk)); err != nil {
+				return fmt.Errorf("moved %d messages before stopping: %w", moved, err)
+			}
+		}
+		n, err := sender.MoveMessages(ctx, *from, *to, chunk)
+		moved += n
+		if err != nil {
+			return fmt.Errorf("moved %d messages before failing: %w", moved, err)
+		}
+		if n < chunk {
+			break // the source queue is empty
+		}
+		if *interval > 0 && time.Since(lastReport) >= *interval {
+			fmt.Fprintf(stdout, "moved %d of %d messages (%.0f/s)\n", moved, total, float64(moved)/time.Since(start).Seconds())
+			lastReport = time.Now()
+		}
+	}
+
+	fmt.Fprintf(stdout, "moved %d messages from %s to %s in %s\n", moved, *from, *to, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runStats prints statistics of the given queue, or of every queue with -all
+func runStats(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, queue := newFlags("stats", "", config)
+	all := flags.String("all", "", "print every queue matching this glob pattern instead (e.g. \"*\")")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	queues := []string{*queue}
+	if *all != "" {
+		var err error
+		if queues, err = sender.ListQueues(ctx, *all); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(stdout)
+	for _, name := range queues {
+		stats, err := sender.GetQueueStats(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHealth prints the sender health and fails if it is unhealthy
+func runHealth(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, _ := newFlags("health", "", config)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	health := sender.Health()
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(health); err != nil {
+		return err
+	}
+	if health.Status == "unhealthy" {
+		return fmt.Errorf("sender is unhealthy")
+	}
+	return nil
+}
+
+// runPing sends PINGs and prints their round trips, then min/avg/max
+func runPing(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, _ := newFlags("ping", "", config)
+	count := flags.Int("count", 4, "PINGs to send")
+	interval := flags.Duration("interval", time.Second, "wait between PINGs")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 0 || *count < 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	var total, fastest, slowest time.Duration
+	for i := 0; i < *count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(*interval):
+			}
+		}
+		rtt, err := sender.Ping(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "PONG from %s: time=%s\n", config.Address, rtt)
+		total += rtt
+		if i == 0 || rtt < fastest {
+			fastest = rtt
+		}
+		slowest = max(slowest, rtt)
+	}
+	fmt.Fprintf(stdout, "%d pings: min/avg/max = %s/%s/%s\n", *count, fastest, total/time.Duration(*count), slowest)
+	return nil
+}
+
+// parseMessage sends valid JSON as-is and anything else as a string
+func parseMessage(input string) interface{} {
+	if json.Valid([]byte(input)) {
+		return json.RawMessage(input)
+	}
+	return input
+}
+
+// printedEnvelope is an envelope with its payload shown as JSON when possible
+type printedEnvelope struct {
+	ID        string            `json:"id,omitempty"`
+	Queue     string            `json:"queue"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+	TTL       string            `json:"ttl,omitempty"`
+	Retries   int               `json:"retries,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   interface{}       `json:"payload"`
+}
+
+// newPrintedEnvelope prepares an envelope for printing
+func newPrintedEnvelope(envelope valkeysender.MessageEnvelope) printedEnvelope {
+	printed := printedEnvelope{
+		ID:      envelope.ID,
+		Queue:   envelope.Queue,
+		Retries: envelope.Retries,
+		Headers: envelope.Headers,
+		Payload: string(envelope.Payload),
+	}
+	if !envelope.Timestamp.IsZero() {
+		printed.Timestamp = &envelope.Timestamp
+	}
+	if envelope.TTL > 0 {
+		printed.TTL = envelope.TTL.String()
+	}
+	if json.Valid(envelope.Payload) {
+		printed.Payload = json.RawMessage(envelope.Payload)
+	}
+	return printed
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestCLI(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+
+	if _, err := runCLI(t, "", "send", "-queue", "orders", `{"id":"o1"}`); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	out, err := runCLI(t, "plain\n\n{\"id\":\"o2\"}\n", "batch", "-queue", "orders", "-size", "1")
+	if err != nil || !strings.Contains(out, "sent 2 messages") {
+		t.Fatalf("batch failed: %q (%v)", out, err)
+	}
+
+	out, err = runCLI(t, "", "peek", "-queue", "orders")
+	if err != nil {
+		t.Fatalf("peek failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"payload":{"id":"o1"}`) || !strings.Contains(lines[1], `"payload":"plain"`) {
+		t.Errorf("Unexpected peek output:\n%s", out)
+	}
+
+	out, err = runCLI(t, "", "stats", "-all", "*")
+	if err != nil || !strings.Contains(out, `"length":3`) {
+		t.Errorf("Unexpected stats output %q (%v)", out, err)
+	}
+
+	if _, err := runCLI(t, "", "purge", "-queue", "orders"); err == nil {
+		t.Error("Expected purge without -yes to fail")
+	}
+	out, err = runCLI(t, "", "purge", "-queue", "orders", "-yes")
+	if err != nil || !strings.Contains(out, "removed 3 messages") {
+		t.Errorf("Unexpected purge output %q (%v)", out, err)
+	}
+
+	out, err = runCLI(t, "", "health")
+	if err != nil || !strings.Contains(out, `"status": "healthy"`) {
+		t.Errorf("Unexpected health output %q (%v)", out, err)
+	}
+
+	out, err = runCLI(t, "", "ping", "-count", "2", "-interval", "0")
+	if err != nil || strings.Count(out, "PONG from "+server.Addr()) != 2 || !strings.Contains(out, "2 pings: min/avg/max") {
+		t.Errorf("Unexpected ping output %q (%v)", out, err)
+	}
+}
+
+func TestCLILoad(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+	path := filepath.Join(t.TempDir(), "data.ndjson")
+	
+	// The fourth line is invalid, so the load stops after the lines before it
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\n\n{\"n\":3}\noops\n{\"n\":4}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := runCLI(t, "", "load", "-queue", "backfill", "-file", path, "-size", "2")
+	if err == nil || !strings.Contains(err.Error(), "line 5 is not valid JSON") {
+		t.Fatalf("Expected load to stop at the invalid line, got %v", err)
+	}
+	if stored, _ := server.List("queue:backfill"); len(stored) != 3 {
+		t.Fatalf("Expected the lines before the invalid one to be sent, got %d", len(stored))
+	}
+	if _, err := os.Stat(path + ".offset"); err != nil {
+		t.Fatalf("Expected offset file to be kept for resuming: %v", err)
+	}
+	
+	// Fixing the line in place lets the load resume right at it
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\n\n{\"n\":3}\n\"ok\"\n{\"n\":4}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCLI(t, "", "load", "-queue", "backfill", "-file", path, "-size", "2")
+	if err != nil || !strings.Contains(out, "resuming at line 5") || !strings.Contains(out, "sent 5 messages") {
+		t.Fatalf("Unexpected resumed load output %q (%v)", out, err)
+	}
+	if stored, _ := server.List("queue:backfill"); len(stored) != 5 {
+		t.Errorf("Expected every line to be sent once, got %d", len(stored))
+	}
+	if _, err := os.Stat(path + ".offset"); !os.IsNotExist(err) {
+		t.Errorf("Expected offset file to be removed after a complete load, got %v", err)
+	}
+	
+	out, err = runCLI(t, "{\"n\":5}\n[6]", "load", "-queue", "stdin")
+	if err != nil || !strings.Contains(out, "sent 2 messages") {
+		t.Errorf("Unexpected stdin load output %q (%v)", out, err)
+	}
+}
+
+func TestCLIUsage(t *testing.T) {
+	if _, err := runCLI(t, ""); !errors.Is(err, errUsage) {
+		t.Errorf("Expected usage error without a command, got %v", err)
+	}
+	if _, err := runCLI(t, "", "bogus"); !errors.Is(err, errUsage) {
+		t.Errorf("Expected usage error for an unknown command, got %v", err)
+	}
+}
+
+func TestCLIMigrate(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+	for _, message := range []string{"m0", "m1", "m2", "m3", "m4"} {
+		server.Lpush("queue:poisoned", message)
+	}
+	
+	out, err := runCLI(t, "", "migrate", "-from", "poisoned", "-to", "quarantine", "-count", "2", "-size", "1")
+	if err != nil || !strings.Contains(out, "moved 2 messages from poisoned to quarantine") {
+		t.Fatalf("Unexpected output %q (%v)", out, err)
+	}
+	out, err = runCLI(t, "", "migrate", "-from", "poisoned", "-to", "quarantine", "-rate", "1000")
+	if err != nil || !strings.Contains(out, "moved 3 messages") {
+		t.Fatalf("Unexpected output %q (%v)", out, err)
+	}
+	
+	stored, _ := server.List("queue:quarantine")
+	if strings.Join(stored, ",") != "m4,m3,m2,m1,m0" {
+		t.Errorf("Expected every message moved in order, got %v", stored)
+	}
+	if server.Exists("queue:poisoned") {
+		t.Error("Expected source queue to be drained")
+	}
+	
+	if _, err := runCLI(t, "", "migrate", "-from", "poisoned"); err == nil {
+		t.Error("Expected migrate without -to to fail")
+	}
+}
+
+func TestCLIReplay(t *testing.T) {
+	server := miniredis.RunT(t)
+	t.Setenv("VALKEY_SENDER_ADDRESS", server.Addr())
+	
+	for _, message := range []string{`{"id":1}`, `{"id":2}`} {
+		if _, err := runCLI(t, "", "send", "-queue", "registrations-dlq", "-type", "signup", message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runCLI(t, "", "send", "-queue", "registrations-dlq", "-type", "login", `{"id":3}`); err != nil {
+		t.Fatal(err)
+	}
+	
+	out, err := runCLI(t, "", "replay", "-from", "registrations-dlq", "-to", "registrations",
+		"-match", "message-type=signup", "-retries", "reset", "-header", "fixed-in=v1.4.2")
+	if err != nil || !strings.Contains(out, "replayed 2 messages from registrations-dlq to registrations") {
+		t.Fatalf("Unexpected output %q (%v)", out, err)
+	}
+	
+	out, _ = runCLI(t, "", "peek", "-queue", "registrations", "-count", "5")
+	if strings.Count(out, `"fixed-in":"v1.4.2"`) != 2 || strings.Contains(out, `{"id":3}`) {
+		t.Errorf("Expected only the signups replayed with the new header, got %s", out)
+	}
+	if stored, _ := server.List("queue:registrations-dlq"); len(stored) != 1 {
+		t.Errorf("Expected the login to stay in the dead letter queue, got %d messages", len(stored))
+	}
+	
+	if _, err := runCLI(t, "", "replay", "-from", "registrations-dlq", "-to", "registrations", "-retries", "twice"); err == nil {
+		t.Error("Expected an invalid -retries value to fail")
+	}
+}
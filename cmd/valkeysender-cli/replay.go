@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// headerFlag collects repeated key=value flags
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	pairs := make([]string, 0, len(h))
+	for key, value := range h {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlag) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", pair)
+	}
+	h[key] = value
+	return nil
+}
+
+// runReplay moves messages, e.g. from a dead letter queue, back to a queue,
+// optionally only those matching the given headers or ID
+func runReplay(ctx context.Context, sender valkeysender.Sender, config *valkeysender.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	from := flags.String("from", "", "queue to replay messages from")
+	to := flags.String("to", "", "queue to replay messages to")
+	count := flags.Int64("count", 0, "messages to replay (0 for all matching)")
+	id := flags.String("id", "", "only replay the message with this ID")
+	match := headerFlag{}
+	flags.Var(match, "match", "only replay messages with this header, as key=value (repeatable)")
+	newID := flags.Bool("new-id", false, "give replayed messages a new ID")
+	retries := flags.String("retries", "keep", "retry counter of replayed messages: keep, reset or increment")
+	headers := headerFlag{}
+	flags.Var(headers, "header", "header to set on replayed messages, as key=value (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: valkeysender-cli replay -from queue -to queue [flags]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 0 || *from == "" || *to == "" || *count < 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	opts := valkeysender.ReplayOptions{Count: *count, NewID: *newID, Headers: headers}
+	switch *retries {
+	case "keep":
+	case valkeysender.ReplayRetriesReset, valkeysender.ReplayRetriesIncrement:
+		opts.Retries = *retries
+	default:
+		flags.Usage()
+		return errUsage
+	}
+	if *id != "" || len(match) > 0 {
+		opts.Filter = func(envelope valkeysender.MessageEnvelope) bool {
+			if *id != "" && envelope.ID != *id {
+				return false
+			}
+			for key, value := range match {
+				if envelope.Headers[key] != value {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	replayed, err := sender.ReplayWithOptions(ctx, *from, *to, opts)
+	if err != nil {
+		return fmt.Errorf("replayed %d messages before failing: %w", replayed, err)
+	}
+
+	fmt.Fprintf(stdout, "replayed %d messages from %s to %s\n", replayed, *from, *to)
+	return nil
+}
@@ -0,0 +1,51 @@
+// Package registration contains the Telegram user registration message
+// previously built into valkeysender, on top of the generic SendTyped API.
+package registration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// TypeName is the message-type header value of user registration messages
+const TypeName = "user_registration"
+
+// UserRegistrationData is a user registration coming from a Telegram bot
+type UserRegistrationData struct {
+	Name             string    `json:"name"`
+	Email            string    `json:"email"`
+	TelegramUserID   int64     `json:"telegram_user_id"`
+	TelegramUsername string    `json:"telegram_username,omitempty"`
+	FirstName        string    `json:"first_name,omitempty"`
+	LastName         string    `json:"last_name,omitempty"`
+	PhoneNumber      string    `json:"phone_number,omitempty"`
+	LanguageCode     string    `json:"language_code,omitempty"`
+	Source           string    `json:"source,omitempty"`
+	RegisteredAt     time.Time `json:"registered_at"`
+}
+
+// Validate checks the fields required by registration consumers
+func (d UserRegistrationData) Validate() error {
+	if d.TelegramUserID == 0 {
+		return fmt.Errorf("telegram user ID is required")
+	}
+	if d.Name == "" && d.FirstName == "" {
+		return fmt.Errorf("name or first name is required")
+	}
+	return nil
+}
+
+// SendUserRegistration validates and sends a user registration, stamping
+// RegisteredAt if it is unset
+func SendUserRegistration(ctx context.Context, sender valkeysender.Sender, queue string, data UserRegistrationData) error {
+	if err := data.Validate(); err != nil {
+		return &valkeysender.Error{Op: "send message", Queue: queue, Kind: valkeysender.ErrValidation, Err: err}
+	}
+	if data.RegisteredAt.IsZero() {
+		data.RegisteredAt = time.Now().UTC()
+	}
+	return sender.SendTyped(ctx, queue, TypeName, data)
+}
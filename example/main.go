@@ -31,13 +31,9 @@ func main() {
 		log.Fatalf("❌ Failed to create logger: %v", err)
 	}
 
+	// Config is logged with its credentials redacted
 	logger.Info("Starting Valkey sender example",
-		slog.Any("config", map[string]interface{}{
-			"address":       config.Address,
-			"database":      config.Database,
-			"default_queue": config.DefaultQueue,
-			"message_ttl":   config.MessageTTL,
-		}),
+		slog.Any("config", config),
 	)
 
 	fmt.Printf("📋 Configuration:\n")
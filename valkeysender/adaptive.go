@@ -0,0 +1,133 @@
+package valkeysender
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Adaptive rate limit steps: the limit is multiplied by adaptiveDecrease
+// when Valkey struggles and raised by RateLimitRequests/adaptiveRecoverySteps
+// per interval while it keeps up
+const (
+	adaptiveDecrease      = 0.5
+	adaptiveRecoverySteps = 20
+)
+
+// adaptiveErrorRate is the share of failed pushes in an interval at which
+// the adaptive limiter backs off
+const adaptiveErrorRate = 0.1
+
+// adaptiveLimiter adjusts the rate limiter AIMD-style from the round trips
+// and failures of the pushes observed during each adjust interval
+type adaptiveLimiter struct {
+	limiter *rate.Limiter
+	ceiling float64 // Config.RateLimitRequests
+	floor   float64 // Config.RateLimitMinRequests
+	burst   int     // Config.RateLimitBurst, scaled down with the limit
+	target  time.Duration
+
+	mu       sync.Mutex
+	pushes   int64
+	failures int64
+	latency  time.Duration // total round trip of the pushes
+}
+
+// newAdaptiveLimiter creates an adaptive limiter driving limiter, nil unless
+// Config.RateLimitAdaptive is set
+func newAdaptiveLimiter(config *Config, limiter *rate.Limiter) *adaptiveLimiter {
+	if !config.RateLimitAdaptive {
+		return nil
+	}
+	return &adaptiveLimiter{
+		limiter: limiter,
+		ceiling: float64(config.RateLimitRequests),
+		floor:   float64(config.RateLimitMinRequests),
+		burst:   config.RateLimitBurst,
+		target:  config.RateLimitLatencyTarget,
+	}
+}
+
+// observe records the round trip and outcome of one push
+func (a *adaptiveLimiter) observe(latency time.Duration, err error) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pushes++
+	a.latency += latency
+	if !isBreakerSuccess(err) {
+		a.failures++
+	}
+}
+
+// adjust applies the observations since the last call to the limit and
+// returns the new limit and whether it was lowered. Intervals without
+// pushes leave the limit unchanged.
+func (a *adaptiveLimiter) adjust() (limit float64, lowered bool) {
+	a.mu.Lock()
+	pushes, failures, latency := a.pushes, a.failures, a.latency
+	a.pushes, a.failures, a.latency = 0, 0, 0
+	a.mu.Unlock()
+
+	limit = float64(a.limiter.Limit())
+	if pushes == 0 {
+		return limit, false
+	}
+
+	average := latency / time.Duration(pushes)
+	if average > a.target || errorRate(pushes-failures, failures) >= adaptiveErrorRate {
+		limit = max(limit*adaptiveDecrease, a.floor)
+		lowered = true
+	} else {
+		limit = min(limit+a.ceiling/adaptiveRecoverySteps, a.ceiling)
+	}
+
+	a.limiter.SetLimit(rate.Limit(limit))
+	a.limiter.SetBurst(max(int(float64(a.burst)*limit/a.ceiling), 1))
+	return limit, lowered
+}
+
+// startAdaptiveRateLimit adjusts the rate limit every
+// Config.RateLimitAdjustInterval when adaptive rate limiting is enabled
+func (s *valkeySender) startAdaptiveRateLimit() {
+	if s.adaptive == nil {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.RateLimitAdjustInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.adjustRateLimit()
+			}
+		}
+	}()
+}
+
+// adjustRateLimit applies one adaptive step and logs changes
+func (s *valkeySender) adjustRateLimit() {
+	previous := float64(s.rateLimiter.Limit())
+	limit, lowered := s.adaptive.adjust()
+
+	switch {
+	case lowered && limit < previous:
+		s.logger.Warn("Lowered rate limit",
+			slog.Float64("from", previous),
+			slog.Float64("to", limit),
+		)
+	case limit == s.adaptive.ceiling && previous < limit:
+		s.logger.Info("Rate limit recovered", slog.Float64("limit", limit))
+	}
+}
@@ -0,0 +1,91 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"golang.org/x/time/rate"
+)
+
+func newTestAdaptiveLimiter() *adaptiveLimiter {
+	config := DefaultConfig()
+	config.RateLimitAdaptive = true
+	config.RateLimitRequests = 1000
+	config.RateLimitBurst = 2000
+	config.RateLimitMinRequests = 100
+	config.RateLimitLatencyTarget = 10 * time.Millisecond
+	return newAdaptiveLimiter(config, rate.NewLimiter(1000, 2000))
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	a := newTestAdaptiveLimiter()
+	
+	// Without pushes the limit stays put
+	if limit, lowered := a.adjust(); limit != 1000 || lowered {
+		t.Fatalf("Expected unchanged limit 1000, got %v (lowered %v)", limit, lowered)
+	}
+	
+	// Slow round trips halve the limit and scale the burst with it
+	a.observe(5*time.Millisecond, nil)
+	a.observe(25*time.Millisecond, nil)
+	if limit, lowered := a.adjust(); limit != 500 || !lowered {
+		t.Fatalf("Expected limit 500 after slow pushes, got %v (lowered %v)", limit, lowered)
+	}
+	if a.limiter.Limit() != 500 || a.limiter.Burst() != 1000 {
+		t.Errorf("Expected limiter at 500/1000, got %v/%d", a.limiter.Limit(), a.limiter.Burst())
+	}
+	
+	// Infrastructure failures back off too, caller errors don't
+	for i := 0; i < 9; i++ {
+		a.observe(time.Millisecond, ErrQueueFull)
+	}
+	a.observe(time.Millisecond, ErrNotConnected)
+	if limit, _ := a.adjust(); limit != 250 {
+		t.Fatalf("Expected limit 250 after failures, got %v", limit)
+	}
+	a.observe(time.Millisecond, ErrValidation)
+	if limit, lowered := a.adjust(); limit != 300 || lowered {
+		t.Fatalf("Expected limit to recover to 300, got %v (lowered %v)", limit, lowered)
+	}
+	
+	// Never below the floor
+	for i := 0; i < 5; i++ {
+		a.observe(time.Second, nil)
+		a.adjust()
+	}
+	if limit := float64(a.limiter.Limit()); limit != 100 {
+		t.Errorf("Expected limit to stop at the floor 100, got %v", limit)
+	}
+	
+	// Recovery stops at the configured limit
+	for i := 0; i < 30; i++ {
+		a.observe(time.Millisecond, nil)
+		a.adjust()
+	}
+	if a.limiter.Limit() != 1000 || a.limiter.Burst() != 2000 {
+		t.Errorf("Expected limiter back at 1000/2000, got %v/%d", a.limiter.Limit(), a.limiter.Burst())
+	}
+}
+
+func TestAdaptiveRateLimitObservesPushes(t *testing.T) {
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	s.rateLimiter = rate.NewLimiter(1000, 2000)
+	s.adaptive = newTestAdaptiveLimiter()
+	s.adaptive.limiter = s.rateLimiter
+	
+	err := s.guard(context.Background(), opSendMessage, "orders", nil, func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return errors.New("timeout")
+	})
+	if err == nil {
+		t.Fatal("Expected the push error")
+	}
+	
+	s.adjustRateLimit()
+	if got := s.Metrics().RateLimit; got != 500 {
+		t.Errorf("Expected Metrics to report the lowered limit 500, got %v", got)
+	}
+}
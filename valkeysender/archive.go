@@ -0,0 +1,149 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// archiveKeyPrefix is prepended to the queue name to form the key of its archive stream
+const archiveKeyPrefix = "archive:"
+
+// ArchivedMessage is a message read back from the archive of a queue
+type ArchivedMessage struct {
+	StreamID  string          // ID of the stream entry
+	Timestamp time.Time       // when the message was archived, taken from StreamID
+	Key       string          // list key the message was pushed to
+	Envelope  MessageEnvelope // the message, only Queue and Payload set if not an envelope
+}
+
+// archive copies every message pushed to the archived queues into a capped
+// stream per queue. A nil archive copies nothing.
+type archive struct {
+	client    func() redis.UniversalClient
+	keyPrefix string
+	maxLen    int64
+	timeout   time.Duration
+	queues    map[string]bool // archived queues, nil for all
+	logger    *slog.Logger
+}
+
+// newArchive returns the archive of the configuration, or nil if
+// Config.ArchiveMaxLen is not set
+func (s *valkeySender) newArchive() (*archive, error) {
+	config := s.config
+	if config.ArchiveMaxLen == 0 {
+		return nil, nil
+	}
+	if s.getClient() == nil {
+		return nil, fmt.Errorf("message archive requires the %q backend", BackendList)
+	}
+
+	archive := &archive{
+		client:    s.getClient,
+		keyPrefix: config.KeyPrefix,
+		maxLen:    int64(config.ArchiveMaxLen),
+		timeout:   config.WriteTimeout,
+		logger:    s.logger,
+	}
+	if len(config.ArchiveQueues) > 0 {
+		archive.queues = make(map[string]bool, len(config.ArchiveQueues))
+		for _, queue := range config.ArchiveQueues {
+			archive.queues[queue] = true
+		}
+	}
+	return archive, nil
+}
+
+// archiveKey returns the key of the archive stream of a queue
+func archiveKey(keyPrefix, queue string) string {
+	return keyPrefix + archiveKeyPrefix + queue
+}
+
+// tee adds the messages of a successful send to the archive streams of
+// their queues in one round trip. Messages skipped as duplicates are not
+// archived. Archive failures are logged but never fail the send.
+func (a *archive) tee(batches []*queueBatch, sendErr error) {
+	if a == nil || sendErr != nil {
+		return
+	}
+
+	// The send's context may be done already; the archive has its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	pipe := a.client().Pipeline()
+	for _, batch := range batches {
+		if a.queues != nil && !a.queues[batch.queue] {
+			continue
+		}
+		for i, data := range batch.data {
+			if i < len(batch.positions) && batch.positions[i] == 0 {
+				continue
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: archiveKey(a.keyPrefix, batch.queue),
+				MaxLen: a.maxLen,
+				Approx: true,
+				Values: []interface{}{"key", batch.key, "data", data},
+			})
+		}
+	}
+	if pipe.Len() == 0 {
+		return
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		a.logger.Error("Failed to archive messages", slog.Any("error", err))
+	}
+}
+
+// ReadArchive returns up to count messages archived for a queue, oldest
+// first, starting with those archived at since. It needs
+// Config.ArchiveMaxLen to have been set when the messages were sent.
+func (s *valkeySender) ReadArchive(ctx context.Context, queue string, since time.Time, count int64) ([]ArchivedMessage, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+	client := s.getClient()
+	if client == nil {
+		return nil, fmt.Errorf("message archive requires the %q backend", BackendList)
+	}
+
+	start := "-"
+	if !since.IsZero() {
+		start = strconv.FormatInt(since.UnixMilli(), 10)
+	}
+	entries, err := client.XRangeN(ctx, archiveKey(s.config.KeyPrefix, queue), start, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive of queue %s: %w", queue, err)
+	}
+
+	messages := make([]ArchivedMessage, 0, len(entries))
+	for _, entry := range entries {
+		key, _ := entry.Values["key"].(string)
+		data, _ := entry.Values["data"].(string)
+		messages = append(messages, ArchivedMessage{
+			StreamID:  entry.ID,
+			Timestamp: streamIDTime(entry.ID),
+			Key:       key,
+			Envelope:  s.decodeElement(queue, data),
+		})
+	}
+	return messages, nil
+}
+
+// streamIDTime returns the time encoded in the first part of a stream
+// entry ID, zero if the ID is malformed
+func streamIDTime(id string) time.Time {
+	millis, _, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
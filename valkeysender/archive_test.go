@@ -0,0 +1,79 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestArchive(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithArchive(1000, "payments"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+
+	before := time.Now().Add(-time.Second)
+	if err := sender.SendBatch(ctx, "payments", []interface{}{"p1", "p2"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := sender.SendMessageWithOptions(ctx, "payments", "p3", SendOptions{IdempotencyKey: "p3"}); err != nil {
+			t.Fatalf("SendMessageWithOptions failed: %v", err)
+		}
+	}
+	if err := sender.SendMessage(ctx, "orders", "not archived"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	// Consuming the queue leaves the archive untouched
+	server.Del("queue:payments")
+
+	archived, err := sender.ReadArchive(ctx, "payments", before, 10)
+	if err != nil {
+		t.Fatalf("ReadArchive failed: %v", err)
+	}
+	if len(archived) != 3 {
+		t.Fatalf("Expected the stored messages without the duplicate, got %d", len(archived))
+	}
+	for i, expected := range []string{"p1", "p2", "p3"} {
+		message := archived[i]
+		if string(message.Envelope.Payload) != expected || message.Envelope.ID == "" {
+			t.Errorf("Expected archived message %s, got %+v", expected, message.Envelope)
+		}
+		if message.Key != "queue:payments" || message.Timestamp.Before(before) {
+			t.Errorf("Unexpected archive entry %+v", message)
+		}
+	}
+
+	if archived, err := sender.ReadArchive(ctx, "payments", time.Now().Add(time.Hour), 10); err != nil || len(archived) != 0 {
+		t.Errorf("Expected nothing archived in the future, got %d, %v", len(archived), err)
+	}
+	if server.Exists("archive:orders") {
+		t.Error("Expected queues not listed not to be archived")
+	}
+}
+
+func TestArchiveConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.ArchiveMaxLen = -1
+	if err := config.validate(); err == nil {
+		t.Error("Expected negative archive length to be rejected")
+	}
+
+	config.ArchiveMaxLen = 100
+	config.Backend = BackendMemory
+	if err := config.validate(); err == nil {
+		t.Error("Expected the archive to require the list backend")
+	}
+}
@@ -0,0 +1,194 @@
+package valkeysender
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Results recorded in the audit log
+const (
+	AuditResultSent   = "sent"
+	AuditResultFailed = "failed"
+)
+
+// AuditRecord is the audit log entry of one message. Size and Checksum
+// (hex SHA-256) cover the list element as stored, so a record can be
+// matched against what a consumer pops.
+type AuditRecord struct {
+	Timestamp time.Time `json:"ts"`
+	Queue     string    `json:"queue"`
+	MessageID string    `json:"id,omitempty"` // empty for raw payloads and frames
+	Size      int       `json:"size"`
+	Checksum  string    `json:"sha256"`
+	Result    string    `json:"result"`          // AuditResultSent or AuditResultFailed
+	Error     string    `json:"error,omitempty"` // why the send failed
+}
+
+// auditLog writes audit records to the configured sinks. A nil log audits nothing.
+type auditLog struct {
+	client  func() redis.UniversalClient
+	stream  string
+	maxLen  int64
+	timeout time.Duration
+	queues  map[string]bool // audited queues, nil for all
+	handler func(AuditRecord)
+	logger  *slog.Logger
+	file    *RotatingFile // nil unless Config.AuditFile is set
+}
+
+// newAuditLog opens the audit sinks of the configuration, or returns nil
+// if neither they nor SenderOptions.AuditHandler are set
+func (s *valkeySender) newAuditLog() (*auditLog, error) {
+	config := s.config
+	if config.AuditStream == "" && config.AuditFile == "" && s.options.AuditHandler == nil {
+		return nil, nil
+	}
+	if config.AuditStream != "" && s.getClient() == nil {
+		return nil, fmt.Errorf("audit stream requires the %q backend", BackendList)
+	}
+
+	audit := &auditLog{
+		client:  s.getClient,
+		stream:  config.AuditStream,
+		maxLen:  int64(config.AuditStreamMaxLen),
+		timeout: config.WriteTimeout,
+		handler: s.options.AuditHandler,
+		logger:  s.logger,
+	}
+	if len(config.AuditQueues) > 0 {
+		audit.queues = make(map[string]bool, len(config.AuditQueues))
+		for _, queue := range config.AuditQueues {
+			audit.queues[queue] = true
+		}
+	}
+	if config.AuditFile != "" {
+		// Audit files are rotated by size but never pruned
+		file, err := OpenRotatingFile(config.AuditFile, RotationOptions{MaxSize: DefaultLogMaxSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file: %w", err)
+		}
+		audit.file = file
+	}
+	return audit, nil
+}
+
+// audit records the outcome of a send for every message of the batches.
+// Audit failures are logged but never fail the send.
+func (a *auditLog) audit(batches []*queueBatch, sendErr error) {
+	if a == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	result, reason := AuditResultSent, ""
+	if sendErr != nil {
+		result, reason = AuditResultFailed, sendErr.Error()
+	}
+
+	var records []AuditRecord
+	for _, batch := range batches {
+		if a.queues != nil && !a.queues[batch.queue] {
+			continue
+		}
+		for i, data := range batch.data {
+			value := memoryValue(data)
+			checksum := sha256.Sum256([]byte(value))
+			record := AuditRecord{
+				Timestamp: now,
+				Queue:     batch.queue,
+				Size:      len(value),
+				Checksum:  hex.EncodeToString(checksum[:]),
+				Result:    result,
+				Error:     reason,
+			}
+			if i < len(batch.envelopes) {
+				record.MessageID = batch.envelopes[i].ID
+			}
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if err := a.writeStream(records); err != nil {
+		a.logger.Error("Failed to write audit stream", slog.String("stream", a.stream), slog.Any("error", err))
+	}
+	if err := a.writeFile(records); err != nil {
+		a.logger.Error("Failed to write audit file", slog.Any("error", err))
+	}
+	if a.handler != nil {
+		for _, record := range records {
+			a.handler(record)
+		}
+	}
+}
+
+// writeStream adds the records to the audit stream in one round trip. It
+// runs on its own deadline, as the send's context may be what failed it.
+func (a *auditLog) writeStream(records []AuditRecord) error {
+	if a.stream == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	pipe := a.client().Pipeline()
+	for _, record := range records {
+		values := []interface{}{
+			"ts", record.Timestamp.Format(time.RFC3339Nano),
+			"queue", record.Queue,
+			"id", record.MessageID,
+			"size", record.Size,
+			"sha256", record.Checksum,
+			"result", record.Result,
+		}
+		if record.Error != "" {
+			values = append(values, "error", record.Error)
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: a.stream,
+			MaxLen: a.maxLen,
+			Approx: a.maxLen > 0,
+			Values: values,
+		})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// writeFile appends the records to the audit file as JSON lines
+func (a *auditLog) writeFile(records []AuditRecord) error {
+	if a.file == nil {
+		return nil
+	}
+
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	// One write per send keeps its records together in the file
+	_, err := a.file.Write(lines)
+	return err
+}
+
+// close closes the audit file
+func (a *auditLog) close() error {
+	if a == nil || a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
@@ -0,0 +1,127 @@
+package valkeysender
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestAuditLog(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithAuditLog("audit:payments", path, "payments"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+
+	if err := sender.SendMessage(ctx, "payments", map[string]int{"amount": 100}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if err := sender.SendMessage(ctx, "orders", "not audited"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	stored, err := server.List("queue:payments")
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected one stored message, got %v, %v", stored, err)
+	}
+	checksum := sha256.Sum256([]byte(stored[0]))
+
+	entries, err := server.Stream("audit:payments")
+	if err != nil {
+		t.Fatalf("Failed to read audit stream: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected one audit stream entry, got %d", len(entries))
+	}
+	fields := make(map[string]string)
+	for i := 0; i+1 < len(entries[0].Values); i += 2 {
+		fields[entries[0].Values[i]] = entries[0].Values[i+1]
+	}
+	if fields["queue"] != "payments" || fields["result"] != AuditResultSent || fields["id"] == "" {
+		t.Errorf("Unexpected audit stream entry %v", fields)
+	}
+	if fields["sha256"] != hex.EncodeToString(checksum[:]) {
+		t.Errorf("Expected checksum of the stored message, got %s", fields["sha256"])
+	}
+
+	if err := sender.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected one audit file record, got %d", len(records))
+	}
+	if records[0].MessageID != fields["id"] || records[0].Size != len(stored[0]) {
+		t.Errorf("Expected file and stream to record the same message, got %+v", records[0])
+	}
+}
+
+func TestAuditFailedSend(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var records []AuditRecord
+	s := newTestSender(t, server, &SenderOptions{AuditHandler: func(record AuditRecord) {
+		mu.Lock()
+		records = append(records, record)
+		mu.Unlock()
+	}})
+	s.config.MaxQueueLength = 1
+	s.config.OverflowPolicy = OverflowPolicyReject
+
+	if err := s.SendBatch(ctx, "payments", []interface{}{"a", "b"}); err == nil {
+		t.Fatal("Expected batch over the cap to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 2 {
+		t.Fatalf("Expected a record per message, got %d", len(records))
+	}
+	for _, record := range records {
+		if record.Result != AuditResultFailed || record.Error == "" {
+			t.Errorf("Expected failed record with the error, got %+v", record)
+		}
+	}
+}
+
+func TestAuditStreamRequiresValkey(t *testing.T) {
+	config := DefaultConfig()
+	config.Backend = BackendMemory
+	config.AuditStream = "audit"
+	if err := config.validate(); err == nil {
+		t.Error("Expected audit stream to be rejected with the memory backend")
+	}
+}
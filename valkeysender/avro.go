@@ -0,0 +1,171 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Envelope headers written by AvroSerializer
+const (
+	// HeaderAvroSchemaID carries the registry ID of the payload's schema
+	HeaderAvroSchemaID = "avro-schema-id"
+
+	// HeaderAvroSubject carries the registry subject of the payload's schema
+	HeaderAvroSubject = "avro-subject"
+)
+
+// AvroSerializer implements MessageSerializer with Avro binary encoding.
+// Its schema is registered in a Confluent-compatible schema registry, and
+// the schema ID and subject are recorded in the envelope headers, so
+// consumers can fetch the exact writer schema to decode a payload.
+//
+// Messages are encoded from their JSON form, like JSONSchemaValidator
+// sees them: structs and maps are encoded with encoding/json first, and
+// []byte, string and json.RawMessage messages are parsed as JSON. Records,
+// enums, arrays, maps, unions, fixed and all primitive types are
+// supported; logical types are written as their underlying type, and
+// []byte values (base64 in JSON) as bytes or fixed.
+type AvroSerializer struct {
+	registry *SchemaRegistry
+	subject  string
+	schemaID int
+	schema   *avroSchema
+
+	mu      sync.RWMutex
+	writers map[int]*avroSchema // parsed schemas fetched by ID
+}
+
+// NewAvroSerializer parses schema, registers it under subject (e.g.
+// "orders-value") and creates a serializer writing it
+func NewAvroSerializer(ctx context.Context, registry *SchemaRegistry, subject, schema string) (*AvroSerializer, error) {
+	if registry == nil {
+		return nil, fmt.Errorf("Avro serializer requires a schema registry")
+	}
+	parsed, err := parseAvroSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	id, err := registry.Register(ctx, subject, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AvroSerializer{
+		registry: registry,
+		subject:  subject,
+		schemaID: id,
+		schema:   parsed,
+		writers:  map[int]*avroSchema{id: parsed},
+	}, nil
+}
+
+// SchemaID returns the registry ID of the schema
+func (s *AvroSerializer) SchemaID() int {
+	return s.schemaID
+}
+
+// Serialize encodes a message with the schema
+func (s *AvroSerializer) Serialize(message interface{}) ([]byte, error) {
+	var data []byte
+	switch m := message.(type) {
+	case []byte:
+		data = m
+	case json.RawMessage:
+		data = m
+	case string:
+		data = []byte(m)
+	default:
+		var err error
+		if data, err = json.Marshal(message); err != nil {
+			return nil, fmt.Errorf("failed to serialize message to Avro: %w", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to serialize message to Avro: message is not JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.schema.encode(&buf, value); err != nil {
+		return nil, fmt.Errorf("failed to serialize message to Avro: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Deserialize decodes a payload written with the serializer's schema into
+// target, through its JSON form
+func (s *AvroSerializer) Deserialize(data []byte, target interface{}) error {
+	return decodeAvro(s.schema, data, target)
+}
+
+// ContentType returns the content type for Avro
+func (s *AvroSerializer) ContentType() string {
+	return "avro/binary"
+}
+
+// Headers records the schema ID and subject so consumers can decode the payload
+func (s *AvroSerializer) Headers(message interface{}) map[string]string {
+	return map[string]string{
+		HeaderAvroSchemaID: strconv.Itoa(s.schemaID),
+		HeaderAvroSubject:  s.subject,
+	}
+}
+
+// DeserializeEnvelope decodes an envelope payload into target with the
+// schema named by its schema ID header, fetched from the registry if it
+// is not the serializer's own
+func (s *AvroSerializer) DeserializeEnvelope(ctx context.Context, envelope MessageEnvelope, target interface{}) error {
+	header := envelope.Headers[HeaderAvroSchemaID]
+	if header == "" {
+		return fmt.Errorf("envelope %s has no %s header", envelope.ID, HeaderAvroSchemaID)
+	}
+	id, err := strconv.Atoi(header)
+	if err != nil {
+		return fmt.Errorf("envelope %s has an invalid schema ID %q", envelope.ID, header)
+	}
+
+	s.mu.RLock()
+	writer := s.writers[id]
+	s.mu.RUnlock()
+	if writer == nil {
+		schema, err := s.registry.Schema(ctx, id)
+		if err != nil {
+			return err
+		}
+		if writer, err = parseAvroSchema(schema); err != nil {
+			return fmt.Errorf("schema %d: %w", id, err)
+		}
+		s.mu.Lock()
+		s.writers[id] = writer
+		s.mu.Unlock()
+	}
+	return decodeAvro(writer, envelope.Payload, target)
+}
+
+// decodeAvro decodes data written with schema into target
+func decodeAvro(schema *avroSchema, data []byte, target interface{}) error {
+	reader := bytes.NewReader(data)
+	value, err := schema.decode(reader)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize Avro: %w", err)
+	}
+	if reader.Len() > 0 {
+		return fmt.Errorf("failed to deserialize Avro: %d trailing bytes", reader.Len())
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize Avro: %w", err)
+	}
+	if err := json.Unmarshal(encoded, target); err != nil {
+		return fmt.Errorf("failed to deserialize Avro to target: %w", err)
+	}
+	return nil
+}
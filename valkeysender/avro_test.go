@@ -0,0 +1,253 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSchemaRegistry serves the registry API from memory
+type fakeSchemaRegistry struct {
+	mu       sync.Mutex
+	schemas  []string
+	requests int
+	auth     string
+}
+
+func (f *fakeSchemaRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	if user, password, _ := r.BasicAuth(); f.auth != "" && user+":"+password != f.auth {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error_code": 401, "message": "Unauthorized"}`)
+		return
+	}
+
+	id, isGet := strings.CutPrefix(r.URL.Path, "/schemas/ids/")
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/subjects/"):
+		var body struct{ Schema string }
+		json.NewDecoder(r.Body).Decode(&body)
+		f.schemas = append(f.schemas, body.Schema)
+		fmt.Fprintf(w, `{"id": %d}`, len(f.schemas))
+	case r.Method == http.MethodGet && isGet:
+		n, _ := strconv.Atoi(id)
+		json.NewEncoder(w).Encode(map[string]string{"schema": f.schemas[n-1]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_code": 40403, "message": "Schema not found"}`)
+	}
+}
+
+const orderAvroSchema = `{
+	"type": "record", "name": "Order", "namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "customer", "type": "string"},
+		{"name": "total", "type": "double"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attributes", "type": {"type": "map", "values": "int"}},
+		{"name": "note", "type": ["null", "string"], "default": null},
+		{"name": "digest", "type": {"type": "fixed", "name": "MD5", "size": 2}},
+		{"name": "previous", "type": ["null", "Status"], "default": null}
+	]
+}`
+
+type avroOrder struct {
+	ID         int64          `json:"id"`
+	Customer   string         `json:"customer"`
+	Total      float64        `json:"total"`
+	Status     string         `json:"status"`
+	Tags       []string       `json:"tags"`
+	Attributes map[string]int `json:"attributes"`
+	Note       *string        `json:"note,omitempty"`
+	Digest     []byte         `json:"digest"`
+	Previous   *string        `json:"previous"`
+}
+
+func TestAvroEncoding(t *testing.T) {
+	for _, tt := range []struct {
+		schema string
+		value  string
+		want   []byte
+	}{
+		{`"long"`, `1`, []byte{0x02}},
+		{`"long"`, `-1`, []byte{0x01}},
+		{`"int"`, `64`, []byte{0x80, 0x01}},
+		{`"string"`, `"foo"`, []byte{0x06, 'f', 'o', 'o'}},
+		{`"boolean"`, `true`, []byte{0x01}},
+		{`"double"`, `1.5`, []byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}},
+		{`{"type": "array", "items": "long"}`, `[3, 27]`, []byte{0x04, 0x06, 0x36, 0x00}},
+		{`["null", "string"]`, `null`, []byte{0x00}},
+		{`["null", "string"]`, `"a"`, []byte{0x02, 0x02, 'a'}},
+		{`{"type": "string", "logicalType": "uuid"}`, `"a"`, []byte{0x02, 'a'}},
+	} {
+		schema, err := parseAvroSchema(tt.schema)
+		if err != nil {
+			t.Fatalf("parseAvroSchema(%s) failed: %v", tt.schema, err)
+		}
+		decoder := json.NewDecoder(strings.NewReader(tt.value))
+		decoder.UseNumber()
+		var value interface{}
+		decoder.Decode(&value)
+
+		var buf bytes.Buffer
+		if err := schema.encode(&buf, value); err != nil {
+			t.Errorf("Encoding %s as %s failed: %v", tt.value, tt.schema, err)
+		} else if !bytes.Equal(buf.Bytes(), tt.want) {
+			t.Errorf("Expected %s as %s to encode to %x, got %x", tt.value, tt.schema, tt.want, buf.Bytes())
+		}
+	}
+
+	for _, tt := range []struct{ schema, value string }{
+		{`"int"`, `4294967296`},
+		{`"string"`, `1`},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, `"B"`},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, `{}`},
+		{`["null", "int"]`, `"a"`},
+	} {
+		schema, _ := parseAvroSchema(tt.schema)
+		decoder := json.NewDecoder(strings.NewReader(tt.value))
+		decoder.UseNumber()
+		var value interface{}
+		decoder.Decode(&value)
+		if err := schema.encode(&bytes.Buffer{}, value); err == nil {
+			t.Errorf("Expected %s to be rejected by %s", tt.value, tt.schema)
+		}
+	}
+
+	for _, schema := range []string{`"decimal"`, `{"type": "record", "fields": []}`, `["null", ["int"]]`, `{"type": "enum", "name": "E", "symbols": []}`} {
+		if _, err := parseAvroSchema(schema); err == nil {
+			t.Errorf("Expected schema %s to be rejected", schema)
+		}
+	}
+}
+
+func TestAvroSerializer(t *testing.T) {
+	fake := &fakeSchemaRegistry{auth: "user:secret"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	registry, err := NewSchemaRegistry(strings.Replace(server.URL, "http://", "http://user:secret@", 1))
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry failed: %v", err)
+	}
+	serializer, err := NewAvroSerializer(ctx, registry, "orders-value", orderAvroSchema)
+	if err != nil {
+		t.Fatalf("NewAvroSerializer failed: %v", err)
+	}
+
+	note, previous := "leave at door", "NEW"
+	order := avroOrder{
+		ID: 42, Customer: "acme", Total: 99.5, Status: "PAID",
+		Tags: []string{"express"}, Attributes: map[string]int{"items": 3},
+		Note: &note, Digest: []byte{0xca, 0xfe}, Previous: &previous,
+	}
+	payload, err := serializer.Serialize(order)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	var decoded avroOrder
+	if err := serializer.Deserialize(payload, &decoded); err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, order) {
+		t.Errorf("Expected %+v after a round trip, got %+v", order, decoded)
+	}
+
+	headers := serializer.Headers(order)
+	if headers[HeaderAvroSchemaID] != "1" || headers[HeaderAvroSubject] != "orders-value" {
+		t.Errorf("Expected the schema ID and subject in the headers, got %v", headers)
+	}
+
+	// Optional fields take their defaults
+	if _, err := serializer.Serialize(`{"id": 1, "customer": "a", "total": 1, "status": "NEW", "tags": [], "attributes": {}, "digest": "AAA="}`); err != nil {
+		t.Errorf("Expected missing fields with defaults to be accepted, got %v", err)
+	}
+	if _, err := serializer.Serialize(map[string]interface{}{"id": "not a number"}); err == nil {
+		t.Error("Expected a message not matching the schema to be rejected")
+	}
+
+	// Registering again is served from the cache
+	requests := fake.requests
+	if _, err := NewAvroSerializer(ctx, registry, "orders-value", orderAvroSchema); err != nil || fake.requests != requests {
+		t.Errorf("Expected the schema ID to be cached, got %d more requests (%v)", fake.requests-requests, err)
+	}
+
+	// A consumer with a fresh registry client fetches the writer schema by ID
+	consumerRegistry, _ := NewSchemaRegistry(strings.Replace(server.URL, "http://", "http://user:secret@", 1))
+	consumer, err := NewAvroSerializer(ctx, consumerRegistry, "orders-v2-value", `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "long"}]}`)
+	if err != nil {
+		t.Fatalf("NewAvroSerializer failed: %v", err)
+	}
+	envelope := MessageEnvelope{ID: "1", Payload: payload, Headers: headers}
+	decoded = avroOrder{}
+	if err := consumer.DeserializeEnvelope(ctx, envelope, &decoded); err != nil || decoded.Customer != "acme" {
+		t.Errorf("Expected the envelope to be decoded with its writer schema, got %+v (%v)", decoded, err)
+	}
+	if err := consumer.DeserializeEnvelope(ctx, MessageEnvelope{ID: "2", Payload: payload}, &decoded); err == nil {
+		t.Error("Expected an envelope without a schema ID to be rejected")
+	}
+}
+
+func TestSchemaRegistryErrors(t *testing.T) {
+	server := httptest.NewServer(&fakeSchemaRegistry{auth: "user:secret"})
+	defer server.Close()
+	ctx := context.Background()
+
+	if _, err := NewSchemaRegistry("ftp://registry"); err == nil {
+		t.Error("Expected a non-HTTP registry URL to be rejected")
+	}
+
+	registry, _ := NewSchemaRegistry(server.URL)
+	if _, err := registry.Register(ctx, "orders-value", `"string"`); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected the registry error to be reported, got %v", err)
+	}
+	if _, err := NewAvroSerializer(ctx, nil, "orders-value", `"string"`); err == nil {
+		t.Error("Expected a serializer without a registry to be rejected")
+	}
+}
+
+func TestAvroSerializerSender(t *testing.T) {
+	server := httptest.NewServer(&fakeSchemaRegistry{})
+	defer server.Close()
+	ctx := context.Background()
+
+	registry, _ := NewSchemaRegistry(server.URL)
+	serializer, err := NewAvroSerializer(ctx, registry, "analytics-value", `{"type": "record", "name": "Click", "fields": [{"name": "page", "type": "string"}]}`)
+	if err != nil {
+		t.Fatalf("NewAvroSerializer failed: %v", err)
+	}
+
+	s := newMemorySender(t)
+	s.options.SerializerFor = func(queue string) MessageSerializer {
+		if queue == "analytics" {
+			return serializer
+		}
+		return nil
+	}
+	if err := s.SendMessage(ctx, "analytics", map[string]string{"page": "/home"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	envelopes, _ := s.PeekMessages(ctx, "analytics", 0, 1)
+	if len(envelopes) != 1 || !bytes.Equal(envelopes[0].Payload, []byte{0x0a, '/', 'h', 'o', 'm', 'e'}) || envelopes[0].Headers[HeaderAvroSchemaID] != "1" {
+		t.Fatalf("Expected an Avro payload with its schema ID, got %+v", envelopes)
+	}
+	var click struct{ Page string }
+	if err := serializer.DeserializeEnvelope(ctx, envelopes[0], &click); err != nil || click.Page != "/home" {
+		t.Errorf("Expected the click to be decoded, got %+v (%v)", click, err)
+	}
+}
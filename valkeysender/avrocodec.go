@@ -0,0 +1,480 @@
+package valkeysender
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// avroSchema is a parsed Avro schema. Logical types are encoded as their
+// underlying type.
+type avroSchema struct {
+	kind string // primitive name, "record", "enum", "array", "map", "union" or "fixed"
+	name string // full name of named types
+
+	fields   []avroField   // record
+	symbols  []string      // enum
+	items    *avroSchema   // array
+	values   *avroSchema   // map
+	branches []*avroSchema // union
+	size     int           // fixed
+}
+
+// avroField is a field of a record schema
+type avroField struct {
+	name       string
+	schema     *avroSchema
+	def        interface{}
+	hasDefault bool
+}
+
+// avroPrimitives are the primitive type names
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// parseAvroSchema parses an Avro schema in its JSON form
+func parseAvroSchema(schema string) (*avroSchema, error) {
+	decoder := json.NewDecoder(strings.NewReader(schema))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	parser := &avroParser{named: make(map[string]*avroSchema)}
+	return parser.parse(raw, "")
+}
+
+// avroParser resolves references to the named types of a schema
+type avroParser struct {
+	named map[string]*avroSchema
+}
+
+// parse parses a schema in the given enclosing namespace
+func (p *avroParser) parse(raw interface{}, namespace string) (*avroSchema, error) {
+	switch raw := raw.(type) {
+	case string:
+		if avroPrimitives[raw] {
+			return &avroSchema{kind: raw}, nil
+		}
+		if named, ok := p.named[avroFullName(raw, namespace)]; ok {
+			return named, nil
+		}
+		if named, ok := p.named[raw]; ok {
+			return named, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type %q", raw)
+
+	case []interface{}:
+		union := &avroSchema{kind: "union"}
+		for _, branch := range raw {
+			schema, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if schema.kind == "union" {
+				return nil, fmt.Errorf("Avro unions cannot contain unions")
+			}
+			union.branches = append(union.branches, schema)
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		return p.parseObject(raw, namespace)
+
+	default:
+		return nil, fmt.Errorf("invalid Avro schema %v", raw)
+	}
+}
+
+// parseObject parses a schema given as a JSON object
+func (p *avroParser) parseObject(raw map[string]interface{}, namespace string) (*avroSchema, error) {
+	kind, _ := raw["type"].(string)
+	switch kind {
+	case "record", "error", "enum", "fixed":
+	case "array":
+		items, err := p.parse(raw["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{kind: "array", items: items}, nil
+	case "map":
+		values, err := p.parse(raw["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{kind: "map", values: values}, nil
+	default:
+		// A primitive with attributes such as logicalType, or a nested type
+		return p.parse(raw["type"], namespace)
+	}
+
+	name, _ := raw["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("Avro %s requires a name", kind)
+	}
+	if ns, ok := raw["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	schema := &avroSchema{kind: kind, name: avroFullName(name, namespace)}
+	if i := strings.LastIndex(schema.name, "."); i >= 0 {
+		namespace = schema.name[:i]
+	}
+	if _, ok := p.named[schema.name]; ok {
+		return nil, fmt.Errorf("Avro type %s is defined twice", schema.name)
+	}
+	// Registered before the fields, so records can refer to themselves
+	p.named[schema.name] = schema
+
+	switch kind {
+	case "enum":
+		symbols, _ := raw["symbols"].([]interface{})
+		for _, symbol := range symbols {
+			s, ok := symbol.(string)
+			if !ok {
+				return nil, fmt.Errorf("Avro enum %s has an invalid symbol %v", schema.name, symbol)
+			}
+			schema.symbols = append(schema.symbols, s)
+		}
+		if len(schema.symbols) == 0 {
+			return nil, fmt.Errorf("Avro enum %s requires symbols", schema.name)
+		}
+	case "fixed":
+		size, err := avroInt(raw["size"])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("Avro fixed %s requires a size", schema.name)
+		}
+		schema.size = int(size)
+	default:
+		schema.kind = "record"
+		fields, ok := raw["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Avro record %s requires fields", schema.name)
+		}
+		for _, f := range fields {
+			object, _ := f.(map[string]interface{})
+			fieldName, _ := object["name"].(string)
+			if fieldName == "" {
+				return nil, fmt.Errorf("Avro record %s has a field without a name", schema.name)
+			}
+			fieldSchema, err := p.parse(object["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", schema.name, fieldName, err)
+			}
+			def, hasDefault := object["default"]
+			schema.fields = append(schema.fields, avroField{name: fieldName, schema: fieldSchema, def: def, hasDefault: hasDefault})
+		}
+	}
+	return schema, nil
+}
+
+// avroFullName qualifies name with namespace unless it is qualified already
+func avroFullName(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// typeName names the schema in errors
+func (s *avroSchema) typeName() string {
+	if s.name != "" {
+		return s.name
+	}
+	return s.kind
+}
+
+// encode appends the Avro binary encoding of a JSON value (as decoded by
+// encoding/json with UseNumber) to buf
+func (s *avroSchema) encode(buf *bytes.Buffer, value interface{}) error {
+	switch s.kind {
+	case "null":
+		if value != nil {
+			return fmt.Errorf("expected null, got %T", value)
+		}
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected boolean, got %T", value)
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case "int", "long":
+		n, err := avroInt(value)
+		if err != nil {
+			return err
+		}
+		if s.kind == "int" && (n < math.MinInt32 || n > math.MaxInt32) {
+			return fmt.Errorf("%d overflows int", n)
+		}
+		writeAvroLong(buf, n)
+	case "float", "double":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected number, got %T", value)
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return err
+		}
+		if s.kind == "float" {
+			buf.Write(binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(f))))
+		} else {
+			buf.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)))
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", value)
+		}
+		writeAvroLong(buf, int64(len(str)))
+		buf.WriteString(str)
+	case "bytes", "fixed":
+		// []byte values reach the encoder base64 encoded by encoding/json
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected base64 bytes, got %T", value)
+		}
+		data, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return fmt.Errorf("expected base64 bytes: %w", err)
+		}
+		if s.kind == "fixed" {
+			if len(data) != s.size {
+				return fmt.Errorf("expected %d bytes for %s, got %d", s.size, s.name, len(data))
+			}
+		} else {
+			writeAvroLong(buf, int64(len(data)))
+		}
+		buf.Write(data)
+	case "enum":
+		symbol, _ := value.(string)
+		for i, candidate := range s.symbols {
+			if candidate == symbol {
+				writeAvroLong(buf, int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not a symbol of %s", value, s.name)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected array, got %T", value)
+		}
+		if len(items) > 0 {
+			writeAvroLong(buf, int64(len(items)))
+			for i, item := range items {
+				if err := s.items.encode(buf, item); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+			}
+		}
+		buf.WriteByte(0)
+	case "map":
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected map, got %T", value)
+		}
+		if len(entries) > 0 {
+			keys := make([]string, 0, len(entries))
+			for key := range entries {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			writeAvroLong(buf, int64(len(keys)))
+			for _, key := range keys {
+				writeAvroLong(buf, int64(len(key)))
+				buf.WriteString(key)
+				if err := s.values.encode(buf, entries[key]); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			}
+		}
+		buf.WriteByte(0)
+	case "record":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object for %s, got %T", s.name, value)
+		}
+		for _, field := range s.fields {
+			fieldValue, ok := object[field.name]
+			if !ok {
+				if !field.hasDefault {
+					return fmt.Errorf("%s: missing field %s", s.name, field.name)
+				}
+				fieldValue = field.def
+			}
+			if err := field.schema.encode(buf, fieldValue); err != nil {
+				return fmt.Errorf("%s.%s: %w", s.name, field.name, err)
+			}
+		}
+	case "union":
+		// The first branch the value encodes with is written
+		var errs []error
+		for i, branch := range s.branches {
+			var encoded bytes.Buffer
+			if err := branch.encode(&encoded, value); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			writeAvroLong(buf, int64(i))
+			buf.Write(encoded.Bytes())
+			return nil
+		}
+		return fmt.Errorf("value matches no branch of the union: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// decode reads a value encoded with the schema from r, as the JSON value
+// encoding/json would produce for it (bytes and fixed become []byte)
+func (s *avroSchema) decode(r *bytes.Reader) (interface{}, error) {
+	switch s.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.ReadByte()
+		return b == 1, err
+	case "int", "long":
+		return binary.ReadVarint(r)
+	case "float":
+		var bits uint32
+		err := binary.Read(r, binary.LittleEndian, &bits)
+		return float64(math.Float32frombits(bits)), err
+	case "double":
+		var bits uint64
+		err := binary.Read(r, binary.LittleEndian, &bits)
+		return math.Float64frombits(bits), err
+	case "string", "bytes":
+		data, err := readAvroBytes(r)
+		if s.kind == "string" {
+			return string(data), err
+		}
+		return data, err
+	case "fixed":
+		return readAvroN(r, int64(s.size))
+	case "enum":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("invalid symbol %d of %s", i, s.name)
+		}
+		return s.symbols[i], nil
+	case "array":
+		items := []interface{}{}
+		err := readAvroBlocks(r, func() error {
+			item, err := s.items.decode(r)
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case "map":
+		entries := make(map[string]interface{})
+		err := readAvroBlocks(r, func() error {
+			key, err := readAvroBytes(r)
+			if err != nil {
+				return err
+			}
+			entries[string(key)], err = s.values.decode(r)
+			return err
+		})
+		return entries, err
+	case "record":
+		object := make(map[string]interface{}, len(s.fields))
+		for _, field := range s.fields {
+			value, err := field.schema.decode(r)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", s.name, field.name, err)
+			}
+			object[field.name] = value
+		}
+		return object, nil
+	case "union":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.branches)) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return s.branches[i].decode(r)
+	}
+	return nil, fmt.Errorf("unknown Avro type %s", s.typeName())
+}
+
+// avroInt converts a JSON number to an integer
+func avroInt(value interface{}) (int64, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected integer, got %T", value)
+	}
+	n, err := number.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("expected integer, got %s", number)
+	}
+	return n, nil
+}
+
+// writeAvroLong writes n as a zig-zag varint
+func writeAvroLong(buf *bytes.Buffer, n int64) {
+	buf.Write(binary.AppendVarint(nil, n))
+}
+
+// readAvroBytes reads a length-prefixed byte string
+func readAvroBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	return readAvroN(r, n)
+}
+
+// readAvroN reads n bytes
+func readAvroN(r *bytes.Reader, n int64) ([]byte, error) {
+	if n < 0 || n > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	data := make([]byte, n)
+	if n == 0 {
+		return data, nil
+	}
+	_, err := r.Read(data)
+	return data, err
+}
+
+// readAvroBlocks calls item for every item of an array or map. A negative
+// block count is followed by the block's size in bytes.
+func readAvroBlocks(r *bytes.Reader, item func() error) error {
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(r); err != nil {
+				return err
+			}
+		}
+		for range count {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
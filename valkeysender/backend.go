@@ -0,0 +1,151 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Storage backends for Config.Backend
+const (
+	// BackendList stores queues as Valkey lists (default)
+	BackendList = "list"
+
+	// BackendStream stores queues as Valkey streams, one XADD entry per
+	// message in the StreamMessageField field, for consumers reading with
+	// XREADGROUP
+	BackendStream = "stream"
+
+	// BackendMemory keeps queues in process memory, for local development
+	// and CI without a Valkey server. Senders in the same process that use
+	// the same address and database share their queues.
+	BackendMemory = "memory"
+)
+
+// Other backends are registered with RegisterBackend
+
+// pushPolicy describes how a push must treat the queue length cap and TTLs
+type pushPolicy struct {
+	ttlStrategy string
+	now         time.Time
+	limits      func(queue string) queueLimits // length cap and deduplication window of a queue
+}
+
+// queueUsage is the raw storage information behind QueueStats
+type queueUsage struct {
+	length    int64
+	memory    int64
+	idle      time.Duration
+	idleKnown bool
+
+	// Counters maintained by the enqueue path
+	enqueued   int64
+	duplicates int64
+	dropped    int64
+}
+
+// backend is the storage the sender pushes to. Keys passed to a backend are
+// full queue keys built with getQueueKey.
+type backend interface {
+	// ping checks that the storage is reachable
+	ping(ctx context.Context) error
+
+	// prepare readies the backend for pushes once it is reachable
+	prepare(ctx context.Context) error
+
+	// push stores every group of batches, skipping messages pushed to the
+	// same queue within the deduplication window and counting what was
+	// enqueued, skipped and dropped. Each group is all-or-nothing; groups
+	// rejected by the length cap are returned with the first batch that
+	// didn't fit.
+	push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error)
+
+	// transact pushes the batches and applies the key operations as one
+	// transaction. If a batch doesn't fit within the length cap nothing is
+	// applied and that batch is returned.
+	transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error)
+
+	// length returns the number of messages in a queue
+	length(ctx context.Context, key string) (int64, error)
+
+	// lengths returns the number of messages in each queue in one round trip
+	lengths(ctx context.Context, keys []string) ([]int64, error)
+
+	// usage returns length, memory, idle time and counters of a queue
+	usage(ctx context.Context, key string) (queueUsage, error)
+
+	// keys returns the keys matching a glob pattern, optionally only queues
+	keys(ctx context.Context, match string, queuesOnly bool) ([]string, error)
+
+	// purge deletes key and the extra keys and returns the length key had
+	purge(ctx context.Context, key string, extra ...string) (int64, error)
+
+	// del deletes keys
+	del(ctx context.Context, keys ...string) error
+
+	// position returns the position of value counted from the consumer end
+	// (1 = next to be consumed), 0 if it is not in the queue
+	position(ctx context.Context, key, value string) (int64, error)
+
+	// lrange returns messages between start and stop, counted from the newest
+	lrange(ctx context.Context, key string, start, stop int64) ([]string, error)
+
+	// purgeExpired removes up to chunk messages whose recorded expiry has
+	// passed, returning how many expiry records were examined and how many
+	// messages were removed
+	purgeExpired(ctx context.Context, key string, now time.Time, chunk int) (int64, int64, error)
+
+	// remove deletes the given messages from key, pushing them onto target if it is set
+	remove(ctx context.Context, key, target string, values []string) (int64, error)
+
+	// move moves up to count messages, oldest first, from the consumer end
+	// of key onto the producer end of target, stopping early once key is
+	// empty. Every message is moved atomically.
+	move(ctx context.Context, key, target string, count int64) (int64, error)
+
+	// replace deletes one occurrence of each value from key and pushes its
+	// replacement onto target, both in one atomic step per value
+	replace(ctx context.Context, key, target string, values, replacements []string) (int64, error)
+}
+
+// newBackend creates the storage backend selected in the configuration
+func (s *valkeySender) newBackend() (backend, error) {
+	switch strings.ToLower(s.config.Backend) {
+	case "", BackendList:
+		if err := s.initClient(); err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		return &redisBackend{client: s.getClient}, nil
+	case BackendStream:
+		if err := s.initClient(); err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		return &streamBackend{client: s.getClient}, nil
+	case BackendMemory:
+		return sharedMemoryBackend(s.config), nil
+	}
+	
+	factory, ok := registeredBackend(s.config.Backend)
+	if !ok {
+		return nil, fmt.Errorf("unsupported backend %q", s.config.Backend)
+	}
+	backend, err := factory(s.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend %q: %w", s.config.Backend, err)
+	}
+	return &customBackend{name: strings.ToLower(s.config.Backend), backend: backend}, nil
+}
+
+// groupBatches splits batches into push groups: one group for an atomic
+// push, otherwise one group per batch
+func groupBatches(batches []*queueBatch, atomic bool) [][]*queueBatch {
+	if atomic {
+		return [][]*queueBatch{batches}
+	}
+	groups := make([][]*queueBatch, len(batches))
+	for i, batch := range batches {
+		groups[i] = []*queueBatch{batch}
+	}
+	return groups
+}
@@ -0,0 +1,223 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueueBackend is a storage transport for queues, registered with
+// RegisterBackend and selected by its name in Config.Backend. The sender
+// keeps serialization, validation, rate limiting, the circuit breaker and
+// metrics, and hands the backend encoded messages.
+//
+// Keys are full queue keys built from Config.KeyPrefix, the queue prefix
+// and the queue name. Queues are ordered newest first like Valkey lists:
+// index 0 is the message pushed last and consumers take from the other end.
+// If the backend implements io.Closer it is closed with the sender.
+type QueueBackend interface {
+	// Ping checks that the storage is reachable
+	Ping(ctx context.Context) error
+
+	// Push appends the messages of every push to its queue, oldest first.
+	// The pushes of one call belong to one send and should be stored all
+	// or none.
+	Push(ctx context.Context, pushes []QueuePush) error
+
+	// Length returns the number of messages in a queue, 0 if it doesn't exist
+	Length(ctx context.Context, key string) (int64, error)
+
+	// Range returns the messages between start and stop, both included,
+	// counted from the newest with LRANGE index rules: negative indexes
+	// count from the oldest, -1 being the oldest message
+	Range(ctx context.Context, key string, start, stop int64) ([]string, error)
+
+	// Keys returns the keys of the queues matching a glob pattern
+	Keys(ctx context.Context, match string) ([]string, error)
+
+	// Delete deletes queues, ignoring keys that don't exist
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// QueuePush is a batch of messages for one queue handed to QueueBackend.Push
+type QueuePush struct {
+	Queue    string        // queue name
+	Key      string        // full queue key
+	Messages [][]byte      // encoded messages, oldest first
+	TTL      time.Duration // message TTL; how it applies is up to the backend
+}
+
+// BackendFactory creates a registered backend for a sender's configuration
+type BackendFactory func(config *Config) (QueueBackend, error)
+
+var (
+	backendFactories      = make(map[string]BackendFactory)
+	backendFactoriesMutex sync.RWMutex
+)
+
+// RegisterBackend makes a storage backend available under name, matched
+// case-insensitively against Config.Backend. It panics if the name is
+// empty, built in or already registered, so backends register from init.
+//
+// Queue length limits, deduplication, the message TTL strategy,
+// transactions and the queue management operations that rearrange messages
+// (SendAndConfirm, Replay, MoveMessages, PurgeExpired and the like) rely on
+// scripts of the built-in backends; with a registered backend they fail
+// with errors.ErrUnsupported.
+func RegisterBackend(name string, factory BackendFactory) {
+	name = strings.ToLower(name)
+	if name == "" || name == BackendList || name == BackendStream || name == BackendMemory || factory == nil {
+		panic(fmt.Sprintf("valkeysender: invalid backend registration %q", name))
+	}
+
+	backendFactoriesMutex.Lock()
+	defer backendFactoriesMutex.Unlock()
+	if _, ok := backendFactories[name]; ok {
+		panic(fmt.Sprintf("valkeysender: backend %q registered twice", name))
+	}
+	backendFactories[name] = factory
+}
+
+// registeredBackend returns the factory registered under name, if any
+func registeredBackend(name string) (BackendFactory, bool) {
+	backendFactoriesMutex.RLock()
+	defer backendFactoriesMutex.RUnlock()
+	factory, ok := backendFactories[strings.ToLower(name)]
+	return factory, ok
+}
+
+// customBackend adapts a registered QueueBackend to the sender
+type customBackend struct {
+	name    string
+	backend QueueBackend
+}
+
+// unsupported reports an operation the registered backend can't perform
+func (b *customBackend) unsupported(op string) error {
+	return fmt.Errorf("%w: %s with backend %q", errors.ErrUnsupported, op, b.name)
+}
+
+// ping pings the backend
+func (b *customBackend) ping(ctx context.Context) error {
+	return b.backend.Ping(ctx)
+}
+
+// prepare has nothing to load
+func (b *customBackend) prepare(ctx context.Context) error {
+	return nil
+}
+
+// push hands every group to the backend in one Push call each. Length
+// limits and deduplication can't be honored, so queues using them fail.
+func (b *customBackend) push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	for _, group := range groups {
+		pushes := make([]QueuePush, len(group))
+		for i, batch := range group {
+			if limits := policy.limits(batch.queue); limits.maxLength > 0 || batch.deduplicated() {
+				return nil, nil, b.unsupported("queue length limits and deduplication")
+			}
+			if policy.ttlStrategy == TTLStrategyMessage {
+				return nil, nil, b.unsupported("the message TTL strategy")
+			}
+			pushes[i] = QueuePush{Queue: batch.queue, Key: batch.key, TTL: batch.ttl, Messages: make([][]byte, len(batch.data))}
+			for j, data := range batch.data {
+				pushes[i].Messages[j] = []byte(memoryValue(data))
+			}
+		}
+		if err := b.backend.Push(ctx, pushes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+// transact is not supported
+func (b *customBackend) transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	return nil, b.unsupported("transactions")
+}
+
+// length returns the number of messages in a queue
+func (b *customBackend) length(ctx context.Context, key string) (int64, error) {
+	return b.backend.Length(ctx, key)
+}
+
+// lengths asks for the length of each queue in turn
+func (b *customBackend) lengths(ctx context.Context, keys []string) ([]int64, error) {
+	lengths := make([]int64, len(keys))
+	for i, key := range keys {
+		length, err := b.backend.Length(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		lengths[i] = length
+	}
+	return lengths, nil
+}
+
+// usage returns the queue length; the other statistics are not known
+func (b *customBackend) usage(ctx context.Context, key string) (queueUsage, error) {
+	length, err := b.backend.Length(ctx, key)
+	return queueUsage{length: length}, err
+}
+
+// keys returns the keys of the queues matching a glob pattern; the backend
+// stores nothing but queues
+func (b *customBackend) keys(ctx context.Context, match string, queuesOnly bool) ([]string, error) {
+	return b.backend.Keys(ctx, match)
+}
+
+// purge deletes a queue and returns how many messages it held
+func (b *customBackend) purge(ctx context.Context, key string, extra ...string) (int64, error) {
+	length, err := b.backend.Length(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return length, b.backend.Delete(ctx, append([]string{key}, extra...)...)
+}
+
+// del deletes queues
+func (b *customBackend) del(ctx context.Context, keys ...string) error {
+	return b.backend.Delete(ctx, keys...)
+}
+
+// position is not supported
+func (b *customBackend) position(ctx context.Context, key, value string) (int64, error) {
+	return 0, b.unsupported("reading message positions")
+}
+
+// lrange returns messages between start and stop
+func (b *customBackend) lrange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return b.backend.Range(ctx, key, start, stop)
+}
+
+// purgeExpired is not supported
+func (b *customBackend) purgeExpired(ctx context.Context, key string, now time.Time, chunk int) (int64, int64, error) {
+	return 0, 0, b.unsupported("purging expired messages")
+}
+
+// remove is not supported
+func (b *customBackend) remove(ctx context.Context, key, target string, values []string) (int64, error) {
+	return 0, b.unsupported("removing messages")
+}
+
+// move is not supported
+func (b *customBackend) move(ctx context.Context, key, target string, count int64) (int64, error) {
+	return 0, b.unsupported("moving messages")
+}
+
+// replace is not supported
+func (b *customBackend) replace(ctx context.Context, key, target string, values, replacements []string) (int64, error) {
+	return 0, b.unsupported("replacing messages")
+}
+
+// close closes the backend if it can be closed
+func (b *customBackend) close() error {
+	if closer, ok := b.backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
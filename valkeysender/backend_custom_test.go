@@ -0,0 +1,188 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path"
+	"sync"
+	"testing"
+)
+
+// mapBackend is a minimal QueueBackend keeping queues in a map, newest first
+type mapBackend struct {
+	mu     sync.Mutex
+	queues map[string][]string
+	pushes int
+	closed bool
+}
+
+func (b *mapBackend) Ping(ctx context.Context) error { return nil }
+
+func (b *mapBackend) Push(ctx context.Context, pushes []QueuePush) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pushes++
+	for _, push := range pushes {
+		for _, message := range push.Messages {
+			b.queues[push.Key] = append([]string{string(message)}, b.queues[push.Key]...)
+		}
+	}
+	return nil
+}
+
+func (b *mapBackend) Length(ctx context.Context, key string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int64(len(b.queues[key])), nil
+}
+
+func (b *mapBackend) Range(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := b.queues[key]
+	n := int64(len(queue))
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop, n-1)
+	if start > stop {
+		return nil, nil
+	}
+	return append([]string(nil), queue[start:stop+1]...), nil
+}
+
+func (b *mapBackend) Keys(ctx context.Context, match string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []string
+	for key := range b.queues {
+		if ok, _ := path.Match(match, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (b *mapBackend) Delete(ctx context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		delete(b.queues, key)
+	}
+	return nil
+}
+
+func (b *mapBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+var (
+	registerMapBackend sync.Once
+	mapBackends        = make(chan *mapBackend, 1)
+)
+
+// newMapSender creates a sender on a fresh mapBackend registered as "map"
+func newMapSender(t *testing.T) (*valkeySender, *mapBackend) {
+	t.Helper()
+	registerMapBackend.Do(func() {
+		RegisterBackend("map", func(config *Config) (QueueBackend, error) {
+			return <-mapBackends, nil
+		})
+	})
+
+	backend := &mapBackend{queues: make(map[string][]string)}
+	mapBackends <- backend
+	config := DefaultConfig()
+	config.Backend = "Map"
+	config.HealthCheckInterval = 0
+	sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender), backend
+}
+
+func TestCustomBackend(t *testing.T) {
+	ctx := context.Background()
+	s, backend := newMapSender(t)
+
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if backend.pushes != 2 {
+		t.Errorf("Expected one push per send, got %d", backend.pushes)
+	}
+
+	if size, err := s.GetQueueSize(ctx, "orders"); size != 3 || err != nil {
+		t.Errorf("Expected 3 messages, got %d (%v)", size, err)
+	}
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 10)
+	if err != nil || len(envelopes) != 3 {
+		t.Fatalf("Expected 3 messages, got %d (%v)", len(envelopes), err)
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if string(envelopes[i].Payload) != want {
+			t.Errorf("Expected message %d to be %s, got %s", i, want, envelopes[i].Payload)
+		}
+	}
+
+	queues, err := s.ListQueues(ctx, "")
+	if err != nil || len(queues) != 1 || queues[0] != "orders" {
+		t.Errorf("Expected [orders], got %v (%v)", queues, err)
+	}
+	if purged, err := s.PurgeQueue(ctx, "orders"); purged != 3 || err != nil {
+		t.Errorf("Expected 3 purged messages, got %d (%v)", purged, err)
+	}
+
+	s.Close()
+	if !backend.closed {
+		t.Error("Expected the backend to be closed with the sender")
+	}
+}
+
+func TestCustomBackendUnsupported(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newMapSender(t)
+
+	err := s.SendTransactional(ctx, func(tx TxSender) error { return tx.SendMessage("orders", "a") })
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected transactions to be unsupported, got %v", err)
+	}
+	if err := s.SendMessageWithOptions(ctx, "orders", "a", SendOptions{IdempotencyKey: "a"}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected deduplication to be unsupported, got %v", err)
+	}
+	if _, err := s.MoveMessages(ctx, "orders", "archive", 1); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected moving messages to be unsupported, got %v", err)
+	}
+}
+
+func TestRegisterBackendValidation(t *testing.T) {
+	for _, name := range []string{"", BackendList, "Stream", "Memory"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q to panic", name)
+				}
+			}()
+			RegisterBackend(name, func(config *Config) (QueueBackend, error) { return nil, nil })
+		}()
+	}
+
+	config := DefaultConfig()
+	config.Backend = "unregistered"
+	if err := config.validate(); err == nil {
+		t.Error("Expected an unregistered backend to be rejected")
+	}
+}
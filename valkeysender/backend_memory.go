@@ -0,0 +1,640 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStores holds the in-memory backends by address and database, so
+// senders in one process that point at the same "server" share queues
+var memoryStores sync.Map
+
+// memoryQueue is one queue of the in-memory backend. Messages are kept
+// newest first, matching the list layout used by the Valkey backend.
+type memoryQueue struct {
+	messages   []string
+	expiresAt  time.Time
+	expiries   map[string]time.Time // per-message expiry, TTLStrategyMessage only
+	lastAccess time.Time
+}
+
+// memoryCounters are the enqueue counters of a queue. Like the Valkey
+// counter hash they outlive the queue's messages.
+type memoryCounters struct {
+	enqueued   int64
+	duplicates int64
+	dropped    int64
+}
+
+// memoryString is a plain key written by a transaction
+type memoryString struct {
+	value     string
+	expiresAt time.Time
+}
+
+// memoryBackend keeps queues in process memory. It applies the same
+// deduplication, length cap, overflow and TTL semantics as the Valkey
+// backend; expired queues are removed lazily when they are next accessed.
+type memoryBackend struct {
+	mu       sync.Mutex
+	queues   map[string]*memoryQueue
+	counters map[string]*memoryCounters
+	dedup    map[string]time.Time // deduplication key to expiry
+	strings  map[string]*memoryString
+}
+
+// sharedMemoryBackend returns the in-memory backend for the configured
+// address and database, creating it on first use
+func sharedMemoryBackend(config *Config) *memoryBackend {
+	name := fmt.Sprintf("%s/%d", config.Address, config.Database)
+	store, _ := memoryStores.LoadOrStore(name, &memoryBackend{
+		queues:   make(map[string]*memoryQueue),
+		counters: make(map[string]*memoryCounters),
+		dedup:    make(map[string]time.Time),
+		strings:  make(map[string]*memoryString),
+	})
+	return store.(*memoryBackend)
+}
+
+// queue returns a live queue, dropping it if its TTL has elapsed. The
+// caller must hold the lock.
+func (b *memoryBackend) queue(key string, now time.Time) *memoryQueue {
+	q, ok := b.queues[key]
+	if !ok {
+		return nil
+	}
+	if !q.expiresAt.IsZero() && !now.Before(q.expiresAt) {
+		delete(b.queues, key)
+		return nil
+	}
+	return q
+}
+
+// ping always succeeds
+func (b *memoryBackend) ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// prepare has nothing to load
+func (b *memoryBackend) prepare(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// push stores the fresh messages of every group that fits within the length
+// cap, newest first
+func (b *memoryBackend) push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var full [][]*queueBatch
+	var fullBatch *queueBatch
+	for _, group := range groups {
+		fresh := make([][]int, len(group))
+		for i, batch := range group {
+			fresh[i] = b.fresh(batch, policy)
+		}
+		if batch := b.overflowing(group, fresh, policy); batch != nil {
+			full = append(full, group)
+			if fullBatch == nil {
+				fullBatch = batch
+			}
+			continue
+		}
+		for i, batch := range group {
+			b.pushBatch(batch, fresh[i], policy)
+		}
+	}
+
+	return full, fullBatch, nil
+}
+
+// transact pushes the batches and applies the key operations under one
+// lock. Like EXEC, a failing operation doesn't undo the others; the first
+// error is returned.
+func (b *memoryBackend) transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fresh := make([][]int, len(batches))
+	for i, batch := range batches {
+		fresh[i] = b.fresh(batch, policy)
+	}
+	if batch := b.overflowing(batches, fresh, policy); batch != nil {
+		return batch, nil
+	}
+	for i, batch := range batches {
+		b.pushBatch(batch, fresh[i], policy)
+	}
+
+	var firstErr error
+	for _, op := range ops {
+		if err := b.apply(op, policy.now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// apply runs a key operation. The caller must hold the lock.
+func (b *memoryBackend) apply(op keyOp, now time.Time) error {
+	switch op.kind {
+	case keyOpSet:
+		key := op.keys[0]
+		delete(b.queues, key)
+		value := &memoryString{value: op.value}
+		if op.expiration > 0 {
+			value.expiresAt = now.Add(op.expiration)
+		}
+		b.strings[key] = value
+	case keyOpIncrBy:
+		key := op.keys[0]
+		if b.queue(key, now) != nil {
+			return fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+		}
+		value := b.str(key, now)
+		if value == nil {
+			value = &memoryString{value: "0"}
+			b.strings[key] = value
+		}
+		n, err := strconv.ParseInt(value.value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		value.value = strconv.FormatInt(n+op.n, 10)
+	case keyOpDel:
+		for _, key := range op.keys {
+			b.delete(key)
+		}
+	}
+	return nil
+}
+
+// str returns a live plain key, dropping it if it has expired. The caller
+// must hold the lock.
+func (b *memoryBackend) str(key string, now time.Time) *memoryString {
+	value, ok := b.strings[key]
+	if !ok {
+		return nil
+	}
+	if !value.expiresAt.IsZero() && !now.Before(value.expiresAt) {
+		delete(b.strings, key)
+		return nil
+	}
+	return value
+}
+
+// fresh returns the indexes of the messages of a batch not pushed to its
+// queue within the deduplication window. The caller must hold the lock.
+func (b *memoryBackend) fresh(batch *queueBatch, policy pushPolicy) []int {
+	messages := make([]int, 0, len(batch.data))
+	seen := make(map[string]bool)
+	for i := range batch.data {
+		if batch.deduplicated() {
+			key := dedupKey(batch.key, batch.dedup[i])
+			if seen[key] {
+				continue
+			}
+			if expiresAt, ok := b.dedup[key]; ok && policy.now.Before(expiresAt) {
+				continue
+			}
+			seen[key] = true
+		}
+		messages = append(messages, i)
+	}
+	return messages
+}
+
+// overflowing returns the first batch of a group whose fresh messages don't
+// fit into its queue, or nil if the whole group can be pushed
+func (b *memoryBackend) overflowing(group []*queueBatch, fresh [][]int, policy pushPolicy) *queueBatch {
+	for i, batch := range group {
+		limits := policy.limits(batch.queue)
+		if limits.maxLength <= 0 || limits.dropOldest {
+			continue
+		}
+		var length int
+		if q := b.queue(batch.key, policy.now); q != nil {
+			length = len(q.messages)
+		}
+		if length+len(fresh[i]) > limits.maxLength {
+			return batch
+		}
+	}
+	return nil
+}
+
+// pushBatch prepends the fresh messages of a batch to its queue, records
+// their positions, applies the TTL strategy and updates the counters. The
+// caller must hold the lock.
+func (b *memoryBackend) pushBatch(batch *queueBatch, fresh []int, policy pushPolicy) {
+	limits := policy.limits(batch.queue)
+	counters := b.counters[batch.key]
+	if counters == nil {
+		counters = &memoryCounters{}
+		b.counters[batch.key] = counters
+	}
+	batch.positions = make([]int64, len(batch.data))
+	counters.duplicates += int64(len(batch.data) - len(fresh))
+	if len(fresh) == 0 {
+		return
+	}
+	counters.enqueued += int64(len(fresh))
+
+	q := b.queue(batch.key, policy.now)
+	if q == nil {
+		q = &memoryQueue{}
+		b.queues[batch.key] = q
+	}
+
+	messages := make([]string, len(fresh))
+	pushed := make([]string, 0, len(fresh)+len(q.messages))
+	for j := len(fresh) - 1; j >= 0; j-- {
+		i := fresh[j]
+		messages[j] = memoryValue(batch.data[i])
+		pushed = append(pushed, messages[j])
+		batch.positions[i] = int64(len(q.messages) + j + 1)
+		if batch.deduplicated() {
+			b.dedup[dedupKey(batch.key, batch.dedup[i])] = policy.now.Add(limits.dedupWindow)
+		}
+	}
+	q.messages = append(pushed, q.messages...)
+	q.lastAccess = policy.now
+
+	if limits.maxLength > 0 && len(q.messages) > limits.maxLength {
+		dropped := len(q.messages) - limits.maxLength
+		for _, message := range q.messages[limits.maxLength:] {
+			delete(q.expiries, message)
+		}
+		counters.dropped += int64(dropped)
+		q.messages = q.messages[:limits.maxLength]
+		for i, position := range batch.positions {
+			batch.positions[i] = max(position-int64(dropped), 0)
+		}
+	}
+
+	if batch.ttl <= 0 {
+		return
+	}
+
+	// Same rules as the PEXPIRE calls of enqueueScript
+	expiresAt := policy.now.Add(batch.ttl)
+	if q.expiresAt.IsZero() || (policy.ttlStrategy != TTLStrategyCreate && expiresAt.After(q.expiresAt)) {
+		q.expiresAt = expiresAt
+	}
+
+	if policy.ttlStrategy == TTLStrategyMessage {
+		if q.expiries == nil {
+			q.expiries = make(map[string]time.Time)
+		}
+		for _, message := range messages {
+			q.expiries[message] = expiresAt
+		}
+	}
+}
+
+// length returns the number of messages in a queue
+func (b *memoryBackend) length(ctx context.Context, key string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q := b.queue(key, time.Now()); q != nil {
+		return int64(len(q.messages)), nil
+	}
+	return 0, nil
+}
+
+// lengths returns the number of messages in each queue
+func (b *memoryBackend) lengths(ctx context.Context, keys []string) ([]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	lengths := make([]int64, len(keys))
+	for i, key := range keys {
+		if q := b.queue(key, now); q != nil {
+			lengths[i] = int64(len(q.messages))
+		}
+	}
+	return lengths, nil
+}
+
+// position finds the newest copy of value, like LPOS
+func (b *memoryBackend) position(ctx context.Context, key, value string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(key, time.Now())
+	if q == nil {
+		return 0, nil
+	}
+	for i, message := range q.messages {
+		if message == value {
+			return int64(len(q.messages) - i), nil
+		}
+	}
+	return 0, nil
+}
+
+// usage returns the queue length, the payload bytes held, the idle time and the counters
+func (b *memoryBackend) usage(ctx context.Context, key string) (queueUsage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var usage queueUsage
+	if counters := b.counters[key]; counters != nil {
+		usage.enqueued = counters.enqueued
+		usage.duplicates = counters.duplicates
+		usage.dropped = counters.dropped
+	}
+
+	now := time.Now()
+	q := b.queue(key, now)
+	if q == nil {
+		return usage, nil
+	}
+
+	usage.length = int64(len(q.messages))
+	usage.idle = now.Sub(q.lastAccess)
+	usage.idleKnown = true
+	for _, message := range q.messages {
+		usage.memory += int64(len(message))
+	}
+	return usage, nil
+}
+
+// keys returns the queue keys matching a glob pattern, sorted
+func (b *memoryBackend) keys(ctx context.Context, match string, queuesOnly bool) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var keys []string
+	for key := range b.queues {
+		if b.queue(key, now) == nil {
+			continue
+		}
+		matched, err := path.Match(match, key)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// purge deletes a queue and returns how many messages it held. Expiry sets
+// are part of their queue here, so the extra keys are simply deleted too.
+func (b *memoryBackend) purge(ctx context.Context, key string, extra ...string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var size int64
+	if q := b.queue(key, time.Now()); q != nil {
+		size = int64(len(q.messages))
+	}
+	delete(b.queues, key)
+	for _, k := range extra {
+		delete(b.queues, k)
+	}
+	return size, nil
+}
+
+// del deletes queues, counters and plain keys
+func (b *memoryBackend) del(ctx context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		b.delete(key)
+	}
+	return nil
+}
+
+// delete removes a queue, counter hash or plain key. The caller must hold the lock.
+func (b *memoryBackend) delete(key string) {
+	if strings.HasSuffix(key, countersKeySuffix) {
+		delete(b.counters, strings.TrimSuffix(key, countersKeySuffix))
+		return
+	}
+	delete(b.queues, key)
+	delete(b.strings, key)
+}
+
+// lrange returns messages between start and stop with LRANGE index rules
+func (b *memoryBackend) lrange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(key, time.Now())
+	if q == nil {
+		return []string{}, nil
+	}
+
+	n := int64(len(q.messages))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), q.messages[start:stop+1]...), nil
+}
+
+// purgeExpired removes up to chunk messages whose recorded expiry has passed
+func (b *memoryBackend) purgeExpired(ctx context.Context, key string, now time.Time, chunk int) (int64, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(key, time.Now())
+	if q == nil {
+		return 0, 0, nil
+	}
+
+	var members []string
+	for member, expiresAt := range q.expiries {
+		if !expiresAt.After(now) {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return q.expiries[members[i]].Before(q.expiries[members[j]])
+	})
+	if len(members) > chunk {
+		members = members[:chunk]
+	}
+
+	var removed int64
+	for _, member := range members {
+		removed += q.removeAll(member)
+		delete(q.expiries, member)
+	}
+	b.dropEmpty(key, q)
+	return int64(len(members)), removed, nil
+}
+
+// remove deletes one occurrence of each message, pushing it onto target if set
+func (b *memoryBackend) remove(ctx context.Context, key, target string, values []string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	q := b.queue(key, now)
+	if q == nil {
+		return 0, nil
+	}
+
+	var removed int64
+	for _, value := range values {
+		if !q.removeFirst(value) {
+			continue
+		}
+		removed++
+		if target != "" {
+			t := b.queue(target, now)
+			if t == nil {
+				t = &memoryQueue{}
+				b.queues[target] = t
+			}
+			t.messages = append([]string{value}, t.messages...)
+			t.lastAccess = now
+		}
+	}
+	if removed > 0 {
+		q.lastAccess = now
+	}
+	b.dropEmpty(key, q)
+	return removed, nil
+}
+
+// replace deletes one occurrence of each message, pushing its replacement onto target
+func (b *memoryBackend) replace(ctx context.Context, key, target string, values, replacements []string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	q := b.queue(key, now)
+	if q == nil {
+		return 0, nil
+	}
+
+	var replaced int64
+	for i, value := range values {
+		if !q.removeFirst(value) {
+			continue
+		}
+		replaced++
+		t := b.queue(target, now)
+		if t == nil {
+			t = &memoryQueue{}
+			b.queues[target] = t
+		}
+		t.messages = append([]string{replacements[i]}, t.messages...)
+		t.lastAccess = now
+	}
+	if replaced > 0 {
+		q.lastAccess = now
+	}
+	b.dropEmpty(key, q)
+	return replaced, nil
+}
+
+// dropEmpty deletes a queue left without messages, as Valkey deletes empty
+// lists. The caller must hold the lock.
+func (b *memoryBackend) dropEmpty(key string, q *memoryQueue) {
+	if len(q.messages) == 0 {
+		delete(b.queues, key)
+	}
+}
+
+// removeFirst removes the newest occurrence of a message, like LREM with count 1
+func (q *memoryQueue) removeFirst(value string) bool {
+	for i, message := range q.messages {
+		if message == value {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// removeAll removes every occurrence of a message, like LREM with count 0
+func (q *memoryQueue) removeAll(value string) int64 {
+	kept := q.messages[:0]
+	var removed int64
+	for _, message := range q.messages {
+		if message == value {
+			removed++
+			continue
+		}
+		kept = append(kept, message)
+	}
+	q.messages = kept
+	return removed
+}
+
+// memoryValue converts a batch element to the string stored in a queue
+func memoryValue(data interface{}) string {
+	switch v := data.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// move pops up to count of the oldest messages off key and pushes them onto target
+func (b *memoryBackend) move(ctx context.Context, key, target string, count int64) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	q := b.queue(key, now)
+	if q == nil {
+		return 0, nil
+	}
+	t := b.queue(target, now)
+	if t == nil {
+		t = &memoryQueue{}
+		b.queues[target] = t
+	}
+
+	var moved int64
+	for moved < count && len(q.messages) > 0 {
+		last := len(q.messages) - 1
+		t.messages = append([]string{q.messages[last]}, t.messages...)
+		q.messages = q.messages[:last]
+		moved++
+	}
+	q.lastAccess, t.lastAccess = now, now
+	b.dropEmpty(key, q)
+	b.dropEmpty(target, t)
+	return moved, nil
+}
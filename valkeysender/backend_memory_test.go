@@ -0,0 +1,176 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newMemorySender(t *testing.T) *valkeySender {
+	t.Helper()
+	
+	config := DefaultConfig()
+	config.Backend = BackendMemory
+	config.Address = t.Name()
+	config.HealthCheckInterval = 0
+	options := &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	
+	sender, err := NewSender(config, options)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() {
+		sender.Close()
+		memoryStores.Delete(config.Address + "/0")
+	})
+	return sender.(*valkeySender)
+}
+
+func TestMemoryBackendSendAndPeek(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 10)
+	if err != nil {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if len(envelopes) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(envelopes))
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if string(envelopes[i].Payload) != want {
+			t.Errorf("Expected message %d to be %s, got %s", i, want, envelopes[i].Payload)
+		}
+	}
+	
+	queues, err := s.ListQueues(ctx, "")
+	if err != nil || len(queues) != 1 || queues[0] != "orders" {
+		t.Errorf("Expected [orders], got %v (%v)", queues, err)
+	}
+	
+	stats, err := s.GetQueueStats(ctx, "orders")
+	if err != nil || stats.Length != 3 || stats.MemoryUsage == 0 {
+		t.Errorf("Unexpected stats %+v (%v)", stats, err)
+	}
+	
+	if removed, err := s.PurgeQueue(ctx, "orders"); err != nil || removed != 3 {
+		t.Errorf("Expected 3 purged messages, got %d (%v)", removed, err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 0 {
+		t.Errorf("Expected empty queue after purge, got %d", size)
+	}
+}
+
+func TestMemoryBackendSharedBetweenSenders(t *testing.T) {
+	ctx := context.Background()
+	first := newMemorySender(t)
+	
+	config := *first.config
+	second, err := NewSender(&config, &SenderOptions{Logger: first.logger})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer second.Close()
+	
+	if err := first.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if size, _ := second.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected second sender to see 1 message, got %d", size)
+	}
+}
+
+func TestMemoryBackendOverflow(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.MaxQueueLength = 2
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("Expected batch within the cap to succeed, got %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	
+	// Fan-out is all-or-nothing across queues
+	if err := s.SendToQueues(ctx, []string{"other", "orders"}, "d"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "other"); size != 0 {
+		t.Errorf("Expected fan-out to push nothing, got %d", size)
+	}
+	
+	s.config.OverflowPolicy = OverflowPolicyDropOldest
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Fatalf("Expected drop-oldest send to succeed, got %v", err)
+	}
+	envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+	if len(envelopes) != 2 || string(envelopes[0].Payload) != "b" {
+		t.Errorf("Expected oldest message to be dropped, got %v", envelopes)
+	}
+}
+
+func TestMemoryBackendTTL(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.TTLStrategy = TTLStrategyMessage
+	
+	if err := s.SendMessageWithTTL(ctx, "orders", "short", time.Millisecond); err != nil {
+		t.Fatalf("SendMessageWithTTL failed: %v", err)
+	}
+	if err := s.SendMessageWithTTL(ctx, "orders", "long", time.Hour); err != nil {
+		t.Fatalf("SendMessageWithTTL failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	
+	removed, err := s.PurgeExpired(ctx, "orders")
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 purged message, got %d (%v)", removed, err)
+	}
+	
+	// The whole queue expires once its longest-lived message has
+	s.config.TTLStrategy = TTLStrategyList
+	if err := s.SendMessageWithTTL(ctx, "events", "a", time.Millisecond); err != nil {
+		t.Fatalf("SendMessageWithTTL failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if size, _ := s.GetQueueSize(ctx, "events"); size != 0 {
+		t.Errorf("Expected expired queue to be gone, got %d messages", size)
+	}
+}
+
+func TestMemoryBackendSweep(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.ExpiredQueue = "expired"
+	
+	stale, _ := SerializeMessageEnvelope(MessageEnvelope{
+		ID:        "stale",
+		Timestamp: time.Now().Add(-2 * time.Hour),
+		TTL:       time.Hour,
+	})
+	batch := &queueBatch{queue: "orders", key: s.getQueueKey("orders"), ttl: time.Hour, data: []interface{}{stale}}
+	if err := s.pushBatches(ctx, []*queueBatch{batch}, false); err != nil {
+		t.Fatalf("pushBatches failed: %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "fresh"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	if removed, err := s.sweepQueue(ctx, "orders", s.getQueueKey("orders")); err != nil || removed != 1 {
+		t.Fatalf("Expected 1 swept message, got %d (%v)", removed, err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "expired"); size != 1 {
+		t.Errorf("Expected swept message in expired queue, got %d", size)
+	}
+}
@@ -0,0 +1,558 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanCount is the COUNT hint used when scanning the keyspace
+const scanCount = 100
+
+// queueFullReply is the error reply returned by enqueueBody
+const queueFullReply = "QUEUEFULL"
+
+// maxWatchRetries bounds how often a transaction is retried after a WATCHed queue changed
+const maxWatchRetries = 10
+
+// listCommands are the queue commands of enqueueBody for Valkey lists.
+// queue_push returns the length after the push and the expiry set member.
+const listCommands = `
+local function queue_length(key)
+	return redis.call('LLEN', key)
+end
+
+local function queue_push(key, message)
+	return redis.call('LPUSH', key, message), message
+end
+
+local function queue_trim(key, max)
+	redis.call('LTRIM', key, 0, max - 1)
+end
+`
+
+// enqueueBody is the enqueue path, run after the queue commands of a
+// backend. For one or more queues it atomically skips messages seen within
+// the deduplication window, enforces the maximum length (or trims the
+// oldest messages in drop mode), pushes, applies the TTL strategy and
+// updates the per-queue counters. With several queues, either all of them
+// are pushed or none.
+//
+// KEYS[1..n] queue keys; KEYS[n+1..2n] expiry set keys; KEYS[2n+1..3n]
+// counter hashes; followed by one deduplication key per message of every
+// deduplicated queue.
+//
+// ARGV[1] n; ARGV[2] TTL strategy; then seven settings per queue, at
+// ARGV[3+7(k-1)..2+7k]: message count, "1" if the queue is deduplicated,
+// max length (0 for no cap), "1" to drop oldest, TTL in milliseconds,
+// message expiry (unix ms) and deduplication window in milliseconds;
+// followed by the messages of each queue in order. Returns, per queue, the
+// position of every message: the queue length right after its push, less
+// what was trimmed, or 0 if it was skipped as a duplicate or trimmed itself.
+const enqueueBody = `
+local n = tonumber(ARGV[1])
+local strategy = ARGV[2]
+local first = 3 + 7 * n
+
+local function setting(k, field)
+	return ARGV[2 + 7 * (k - 1) + field]
+end
+
+local fresh, seen, pushes = {}, {}, {}
+local i, d = first, 3 * n
+for k = 1, n do
+	local dedup = setting(k, 2) == '1'
+	local max = tonumber(setting(k, 3))
+	local count = 0
+	for _ = 1, tonumber(setting(k, 1)) do
+		if dedup then
+			d = d + 1
+			if not seen[KEYS[d]] and redis.call('EXISTS', KEYS[d]) == 0 then
+				seen[KEYS[d]] = true
+				fresh[i] = true
+				count = count + 1
+			end
+		else
+			fresh[i] = true
+			count = count + 1
+		end
+		i = i + 1
+	end
+	pushes[k] = count
+	if max > 0 and setting(k, 4) ~= '1' and queue_length(KEYS[k]) + count > max then
+		return redis.error_reply('QUEUEFULL ' .. k)
+	end
+end
+
+local function expire(key, ttl)
+	local current = redis.call('PTTL', key)
+	if current == -1 or (strategy ~= 'create' and current < ttl) then
+		redis.call('PEXPIRE', key, ttl)
+	end
+end
+
+local result = {}
+i, d = first, 3 * n
+for k = 1, n do
+	local queue, counters = KEYS[k], KEYS[2 * n + k]
+	local total = tonumber(setting(k, 1))
+	local dedup = setting(k, 2) == '1'
+	local max = tonumber(setting(k, 3))
+	local drop = setting(k, 4) == '1'
+	local ttl = tonumber(setting(k, 5))
+	local positions = {}
+	for j = 1, total do
+		positions[j] = 0
+		if dedup then
+			d = d + 1
+		end
+		if fresh[i] then
+			local member
+			positions[j], member = queue_push(queue, ARGV[i])
+			if strategy == 'message' then
+				redis.call('ZADD', KEYS[n + k], setting(k, 6), member)
+			end
+			if dedup then
+				redis.call('SET', KEYS[d], '1', 'PX', setting(k, 7))
+			end
+		end
+		i = i + 1
+	end
+
+	if pushes[k] > 0 then
+		if drop and max > 0 then
+			local dropped = queue_length(queue) - max
+			if dropped > 0 then
+				queue_trim(queue, max)
+				redis.call('HINCRBY', counters, 'dropped', dropped)
+				for j, position in ipairs(positions) do
+					positions[j] = math.max(position - dropped, 0)
+				end
+			end
+		end
+		if ttl > 0 then
+			expire(queue, ttl)
+			if strategy == 'message' then
+				expire(KEYS[n + k], ttl)
+			end
+		end
+		redis.call('HINCRBY', counters, 'enqueued', pushes[k])
+	end
+	if total > pushes[k] then
+		redis.call('HINCRBY', counters, 'duplicates', total - pushes[k])
+	end
+	result[k] = positions
+end
+return result
+`
+
+// enqueueScript is the enqueue path for Valkey lists
+var enqueueScript = redis.NewScript(listCommands + enqueueBody)
+
+// purgeExpiredScript removes up to ARGV[2] messages whose expiry (ARGV[1], unix ms)
+// has passed from the list KEYS[1] and the expiry set KEYS[2]
+var purgeExpiredScript = redis.NewScript(`
+local members = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local removed = 0
+for _, member in ipairs(members) do
+	removed = removed + redis.call('LREM', KEYS[1], 0, member)
+	redis.call('ZREM', KEYS[2], member)
+end
+return {#members, removed}
+`)
+
+// sweepScript removes the given elements from KEYS[1] and, if ARGV[1] is "1",
+// pushes them onto KEYS[2]. ARGV[2..] are the elements to remove.
+var sweepScript = redis.NewScript(`
+local removed = 0
+for i = 2, #ARGV do
+	if redis.call('LREM', KEYS[1], 1, ARGV[i]) == 1 then
+		removed = removed + 1
+		if ARGV[1] == '1' then
+			redis.call('LPUSH', KEYS[2], ARGV[i])
+		end
+	end
+end
+return removed
+`)
+
+// replaceScript removes each of the first half of ARGV from KEYS[1] and
+// pushes the matching element of the second half onto KEYS[2]
+var replaceScript = redis.NewScript(`
+local n = #ARGV / 2
+local replaced = 0
+for i = 1, n do
+	if redis.call('LREM', KEYS[1], 1, ARGV[i]) == 1 then
+		redis.call('LPUSH', KEYS[2], ARGV[n + i])
+		replaced = replaced + 1
+	end
+end
+return replaced
+`)
+
+// redisBackend stores queues as Valkey lists
+type redisBackend struct {
+	client func() redis.UniversalClient
+}
+
+// ping checks the connection with PING
+func (b *redisBackend) ping(ctx context.Context) error {
+	return b.client().Ping(ctx).Err()
+}
+
+// prepare loads the enqueue script so sends can run it with EVALSHA
+func (b *redisBackend) prepare(ctx context.Context) error {
+	return enqueueScript.Load(ctx, b.client()).Err()
+}
+
+// push runs one enqueueScript call per group in a single pipeline
+func (b *redisBackend) push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	return pushGroups(ctx, b.client(), enqueueScript, groups, policy)
+}
+
+// pushGroups runs one call of an enqueue script per group in a single
+// pipeline and returns the groups rejected by the length cap
+func pushGroups(ctx context.Context, client redis.UniversalClient, script *redis.Script, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	pipe := client.Pipeline()
+	cmds := make([]*redis.Cmd, len(groups))
+	for i, group := range groups {
+		keys, args := enqueueArgs(group, policy)
+		cmds[i] = script.EvalSha(ctx, pipe, keys, args...)
+	}
+
+	// Load the script again if the server lost it (restart, SCRIPT FLUSH).
+	// Connection errors are not set on the commands, so return them here.
+	if _, err := pipe.Exec(ctx); err != nil {
+		switch {
+		case redis.HasErrorPrefix(err, "NOSCRIPT"):
+			if err := script.Load(ctx, client).Err(); err != nil {
+				return nil, nil, err
+			}
+			return pushGroups(ctx, client, script, groups, policy)
+		case !redis.HasErrorPrefix(err, queueFullReply):
+			return nil, nil, err
+		}
+	}
+
+	var full [][]*queueBatch
+	var fullBatch *queueBatch
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if err == nil {
+			setPositions(groups[i], cmd.Val())
+			continue
+		}
+		if !redis.HasErrorPrefix(err, queueFullReply) {
+			return nil, nil, err
+		}
+
+		full = append(full, groups[i])
+		if fullBatch == nil {
+			fullBatch = groups[i][0]
+			if k, convErr := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(err.Error(), queueFullReply))); convErr == nil && k >= 1 && k <= len(groups[i]) {
+				fullBatch = groups[i][k-1]
+			}
+		}
+	}
+
+	return full, fullBatch, nil
+}
+
+// enqueueArgs builds the keys and arguments of an enqueue script call for a group
+func enqueueArgs(group []*queueBatch, policy pushPolicy) ([]string, []interface{}) {
+	n := len(group)
+	keys := make([]string, 3*n)
+	args := []interface{}{n, policy.ttlStrategy}
+	for k, batch := range group {
+		keys[k] = batch.key
+		keys[n+k] = expiryKey(batch.key)
+		keys[2*n+k] = countersKey(batch.key)
+
+		limits := policy.limits(batch.queue)
+		dedup, drop := "0", "0"
+		if batch.deduplicated() {
+			dedup = "1"
+		}
+		if limits.dropOldest {
+			drop = "1"
+		}
+		args = append(args, len(batch.data), dedup, limits.maxLength, drop, batch.ttl.Milliseconds(),
+			policy.now.Add(batch.ttl).UnixMilli(), limits.dedupWindow.Milliseconds())
+	}
+	for _, batch := range group {
+		if batch.deduplicated() {
+			for _, hash := range batch.dedup {
+				keys = append(keys, dedupKey(batch.key, hash))
+			}
+		}
+		args = append(args, batch.data...)
+	}
+	return keys, args
+}
+
+// setPositions records the positions returned by an enqueue script on the batches
+func setPositions(group []*queueBatch, reply interface{}) {
+	lists, _ := reply.([]interface{})
+	for k, batch := range group {
+		if k >= len(lists) {
+			return
+		}
+		positions, _ := lists[k].([]interface{})
+		batch.positions = make([]int64, len(positions))
+		for j, position := range positions {
+			batch.positions[j], _ = position.(int64)
+		}
+	}
+}
+
+// transact pushes the batches and applies the key operations in one
+// MULTI/EXEC
+func (b *redisBackend) transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	return transactGroups(ctx, b.client(), enqueueScript, redis.Cmdable.LLen, batches, ops, policy)
+}
+
+// transactGroups runs an enqueue script for the batches and applies the key
+// operations in one MULTI/EXEC. Errors inside EXEC would not undo the other
+// commands, so when the length cap can reject the push, the queues are
+// WATCHed and their lengths, read with length, checked before the
+// transaction instead.
+func transactGroups(ctx context.Context, client redis.UniversalClient, script *redis.Script, length func(redis.Cmdable, context.Context, string) *redis.IntCmd,
+	batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	var enqueue *redis.Cmd
+	exec := func(pipe redis.Pipeliner) error {
+		if len(batches) > 0 {
+			// EVAL rather than EVALSHA, as a NOSCRIPT reply can't abort the transaction
+			keys, args := enqueueArgs(batches, policy)
+			enqueue = script.Eval(ctx, pipe, keys, args...)
+		}
+		for _, op := range ops {
+			op.apply(ctx, pipe)
+		}
+		return nil
+	}
+
+	defer func() {
+		if enqueue != nil && enqueue.Err() == nil {
+			setPositions(batches, enqueue.Val())
+		}
+	}()
+
+	capped := false
+	for _, batch := range batches {
+		if limits := policy.limits(batch.queue); limits.maxLength > 0 && !limits.dropOldest {
+			capped = true
+		}
+	}
+	if !capped {
+		_, err := client.TxPipelined(ctx, exec)
+		return nil, err
+	}
+
+	watched := make([]string, len(batches))
+	for i, batch := range batches {
+		watched[i] = batch.key
+	}
+
+	for attempt := 0; attempt < maxWatchRetries; attempt++ {
+		var full *queueBatch
+		err := client.Watch(ctx, func(tx *redis.Tx) error {
+			for _, batch := range batches {
+				length, err := length(tx, ctx, batch.key).Result()
+				if err != nil {
+					return err
+				}
+				limits := policy.limits(batch.queue)
+				if limits.maxLength > 0 && !limits.dropOldest && length+int64(len(batch.data)) > int64(limits.maxLength) {
+					full = batch
+					return nil
+				}
+			}
+			_, err := tx.TxPipelined(ctx, exec)
+			return err
+		}, watched...)
+		if err != redis.TxFailedErr {
+			return full, err
+		}
+	}
+	return nil, fmt.Errorf("queues kept changing during transaction after %d attempts", maxWatchRetries)
+}
+
+// length returns LLEN of the list
+func (b *redisBackend) length(ctx context.Context, key string) (int64, error) {
+	size, err := b.client().LLen(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return size, err
+}
+
+// lengths runs LLEN for every key in one pipeline
+func (b *redisBackend) lengths(ctx context.Context, keys []string) ([]int64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := b.client().Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.LLen(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	lengths := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		lengths[i] = cmd.Val()
+	}
+	return lengths, nil
+}
+
+// usage reads LLEN, MEMORY USAGE, OBJECT IDLETIME and the counters in one round trip
+func (b *redisBackend) usage(ctx context.Context, key string) (queueUsage, error) {
+	pipe := b.client().Pipeline()
+	length := pipe.LLen(ctx, key)
+	memory := pipe.MemoryUsage(ctx, key)
+	idle := pipe.ObjectIdleTime(ctx, key)
+	counters := pipe.HGetAll(ctx, countersKey(key))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return queueUsage{}, err
+	}
+
+	// MEMORY USAGE and OBJECT IDLETIME return nil for missing keys
+	usage := queueUsage{length: length.Val()}
+	if usage.length > 0 {
+		usage.memory = memory.Val()
+		usage.idle = idle.Val()
+		usage.idleKnown = idle.Err() == nil
+	}
+	values := counters.Val()
+	usage.enqueued, _ = strconv.ParseInt(values[counterEnqueued], 10, 64)
+	usage.duplicates, _ = strconv.ParseInt(values[counterDuplicates], 10, 64)
+	usage.dropped, _ = strconv.ParseInt(values[counterDropped], 10, 64)
+	return usage, nil
+}
+
+// keys scans the keyspace with SCAN, restricted to lists if queuesOnly is set
+func (b *redisBackend) keys(ctx context.Context, match string, queuesOnly bool) ([]string, error) {
+	client := b.client()
+
+	var iter *redis.ScanIterator
+	if queuesOnly {
+		iter = client.ScanType(ctx, 0, match, scanCount, "list").Iterator()
+	} else {
+		iter = client.Scan(ctx, 0, match, scanCount).Iterator()
+	}
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// purge reads the length and deletes the keys in one transaction
+func (b *redisBackend) purge(ctx context.Context, key string, extra ...string) (int64, error) {
+	var size *redis.IntCmd
+	_, err := b.client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		size = pipe.LLen(ctx, key)
+		pipe.Del(ctx, append([]string{key}, extra...)...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size.Val(), nil
+}
+
+// del deletes keys with DEL
+func (b *redisBackend) del(ctx context.Context, keys ...string) error {
+	return b.client().Del(ctx, keys...).Err()
+}
+
+// position finds the newest copy of value with LPOS and reads the length
+// in the same transaction
+func (b *redisBackend) position(ctx context.Context, key, value string) (int64, error) {
+	var index, size *redis.IntCmd
+	_, err := b.client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		index = pipe.LPos(ctx, key, value, redis.LPosArgs{})
+		size = pipe.LLen(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return size.Val() - index.Val(), nil
+}
+
+// lrange returns LRANGE of the list
+func (b *redisBackend) lrange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return b.client().LRange(ctx, key, start, stop).Result()
+}
+
+// purgeExpired runs purgeExpiredScript against the list and its expiry set
+func (b *redisBackend) purgeExpired(ctx context.Context, key string, now time.Time, chunk int) (int64, int64, error) {
+	keys := []string{key, expiryKey(key)}
+	result, err := purgeExpiredScript.Run(ctx, b.client(), keys, strconv.FormatInt(now.UnixMilli(), 10), chunk).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], result[1], nil
+}
+
+// remove runs sweepScript to delete, and optionally move, the messages
+func (b *redisBackend) remove(ctx context.Context, key, target string, values []string) (int64, error) {
+	keys := []string{key, key}
+	args := make([]interface{}, 0, len(values)+1)
+	args = append(args, "0")
+	if target != "" {
+		keys[1] = target
+		args[0] = "1"
+	}
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return sweepScript.Run(ctx, b.client(), keys, args...).Int64()
+}
+
+// move runs count LMOVEs in one pipeline; each pops the oldest message off
+// key and pushes it onto target, so nothing is lost if the pipeline breaks
+func (b *redisBackend) move(ctx context.Context, key, target string, count int64) (int64, error) {
+	pipe := b.client().Pipeline()
+	cmds := make([]*redis.StringCmd, count)
+	for i := range cmds {
+		cmds[i] = pipe.LMove(ctx, key, target, "RIGHT", "LEFT")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			break
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// replace runs replaceScript against the lists
+func (b *redisBackend) replace(ctx context.Context, key, target string, values, replacements []string) (int64, error) {
+	args := make([]interface{}, 0, len(values)+len(replacements))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	for _, replacement := range replacements {
+		args = append(args, replacement)
+	}
+	return replaceScript.Run(ctx, b.client(), []string{key, target}, args...).Int64()
+}
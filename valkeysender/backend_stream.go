@@ -0,0 +1,355 @@
+package valkeysender
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamMessageField is the field holding the encoded message in every
+// entry the stream backend adds, for consumers reading queues with XREAD
+// or XREADGROUP
+const StreamMessageField = "message"
+
+// streamPageSize is how many entries the stream scripts read per XRANGE
+const streamPageSize = 100
+
+// streamCommands are the queue commands of enqueueBody for Valkey streams,
+// along with the helpers of the other stream scripts. The expiry set of the
+// message TTL strategy holds entry IDs rather than messages.
+var streamCommands = `
+local function queue_length(key)
+	return redis.call('XLEN', key)
+end
+
+local function queue_push(key, message)
+	local id = redis.call('XADD', key, '*', '` + StreamMessageField + `', message)
+	return redis.call('XLEN', key), id
+end
+
+local function queue_trim(key, max)
+	redis.call('XTRIM', key, 'MAXLEN', max)
+end
+
+local function entry_message(entry)
+	local fields = entry[2]
+	for f = 1, #fields, 2 do
+		if fields[f] == '` + StreamMessageField + `' then
+			return fields[f + 1]
+		end
+	end
+	return ''
+end
+
+-- find_entries returns, per message, the newest entries holding one copy
+-- of each of ARGV[from..to], newest first
+local function find_entries(key, from, to)
+	local wanted, missing = {}, 0
+	for i = from, to do
+		wanted[ARGV[i]] = (wanted[ARGV[i]] or 0) + 1
+		missing = missing + 1
+	end
+
+	local found, last = {}, '+'
+	while missing > 0 do
+		local entries = redis.call('XREVRANGE', key, last, '-', 'COUNT', ` + strconv.Itoa(streamPageSize) + `)
+		for _, entry in ipairs(entries) do
+			local message = entry_message(entry)
+			if (wanted[message] or 0) > 0 then
+				wanted[message] = wanted[message] - 1
+				missing = missing - 1
+				found[message] = found[message] or {}
+				table.insert(found[message], entry)
+			end
+		end
+		if #entries < ` + strconv.Itoa(streamPageSize) + ` then
+			break
+		end
+		last = '(' .. entries[#entries][1]
+	end
+	return found
+end
+
+-- take_entry removes and returns the next entry found for a message, or nil
+local function take_entry(found, message)
+	local entries = found[message]
+	if entries == nil or #entries == 0 then
+		return nil
+	end
+	return table.remove(entries, 1)
+end
+`
+
+// streamEnqueueScript is the enqueue path for Valkey streams
+var streamEnqueueScript = redis.NewScript(streamCommands + enqueueBody)
+
+// streamPositionScript returns the position of the newest entry holding
+// ARGV[1] in the stream KEYS[1], counted from the oldest entry, or 0
+var streamPositionScript = redis.NewScript(streamCommands + `
+local length = queue_length(KEYS[1])
+local found = find_entries(KEYS[1], 1, 1)[ARGV[1]]
+if found == nil then
+	return 0
+end
+
+local newer = redis.call('XRANGE', KEYS[1], '(' .. found[1][1], '+')
+return length - #newer
+`)
+
+// streamRangeScript returns the messages of the stream KEYS[1] between the
+// indexes ARGV[1] and ARGV[2], counted from the newest entry like LRANGE
+var streamRangeScript = redis.NewScript(streamCommands + `
+local length = queue_length(KEYS[1])
+local start, stop = tonumber(ARGV[1]), tonumber(ARGV[2])
+if start < 0 then
+	start = math.max(length + start, 0)
+end
+if stop < 0 then
+	stop = length + stop
+end
+stop = math.min(stop, length - 1)
+if start > stop then
+	return {}
+end
+
+local entries = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', stop + 1)
+local messages = {}
+for i = start + 1, #entries do
+	messages[#messages + 1] = entry_message(entries[i])
+end
+return messages
+`)
+
+// streamPurgeExpiredScript removes up to ARGV[2] entries whose expiry
+// (ARGV[1], unix ms) has passed from the stream KEYS[1] and the expiry set
+// KEYS[2]
+var streamPurgeExpiredScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local removed = 0
+for _, id in ipairs(ids) do
+	removed = removed + redis.call('XDEL', KEYS[1], id)
+	redis.call('ZREM', KEYS[2], id)
+end
+return {#ids, removed}
+`)
+
+// streamSweepScript removes one entry holding each of ARGV[2..] from the
+// stream KEYS[1] and, if ARGV[1] is "1", adds it to KEYS[2]
+var streamSweepScript = redis.NewScript(streamCommands + `
+local found = find_entries(KEYS[1], 2, #ARGV)
+local removed = 0
+for i = 2, #ARGV do
+	local entry = take_entry(found, ARGV[i])
+	if entry then
+		redis.call('XDEL', KEYS[1], entry[1])
+		if ARGV[1] == '1' then
+			redis.call('XADD', KEYS[2], '*', unpack(entry[2]))
+		end
+		removed = removed + 1
+	end
+end
+return removed
+`)
+
+// streamReplaceScript removes an entry holding each of the first half of
+// ARGV from the stream KEYS[1] and adds the matching message of the second
+// half to KEYS[2]
+var streamReplaceScript = redis.NewScript(streamCommands + `
+local n = #ARGV / 2
+local found = find_entries(KEYS[1], 1, n)
+local replaced = 0
+for i = 1, n do
+	local entry = take_entry(found, ARGV[i])
+	if entry then
+		redis.call('XDEL', KEYS[1], entry[1])
+		queue_push(KEYS[2], ARGV[n + i])
+		replaced = replaced + 1
+	end
+end
+return replaced
+`)
+
+// streamMoveScript moves up to ARGV[1] of the oldest entries of the stream
+// KEYS[1] to KEYS[2]
+var streamMoveScript = redis.NewScript(`
+local entries = redis.call('XRANGE', KEYS[1], '-', '+', 'COUNT', tonumber(ARGV[1]))
+for _, entry in ipairs(entries) do
+	redis.call('XADD', KEYS[2], '*', unpack(entry[2]))
+	redis.call('XDEL', KEYS[1], entry[1])
+end
+return #entries
+`)
+
+// streamBackend stores queues as Valkey streams. Each message is one entry
+// added with XADD, so queues can be read by consumer groups; the oldest
+// entry is the next to be consumed. Streams are not deleted when their
+// last entry is removed, which would drop their consumer groups.
+type streamBackend struct {
+	client func() redis.UniversalClient
+}
+
+// ping checks the connection with PING
+func (b *streamBackend) ping(ctx context.Context) error {
+	return b.client().Ping(ctx).Err()
+}
+
+// prepare loads the enqueue script so sends can run it with EVALSHA
+func (b *streamBackend) prepare(ctx context.Context) error {
+	return streamEnqueueScript.Load(ctx, b.client()).Err()
+}
+
+// push runs one streamEnqueueScript call per group in a single pipeline
+func (b *streamBackend) push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	return pushGroups(ctx, b.client(), streamEnqueueScript, groups, policy)
+}
+
+// transact pushes the batches and applies the key operations in one
+// MULTI/EXEC
+func (b *streamBackend) transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	return transactGroups(ctx, b.client(), streamEnqueueScript, redis.Cmdable.XLen, batches, ops, policy)
+}
+
+// length returns XLEN of the stream
+func (b *streamBackend) length(ctx context.Context, key string) (int64, error) {
+	return b.client().XLen(ctx, key).Result()
+}
+
+// lengths runs XLEN for every key in one pipeline
+func (b *streamBackend) lengths(ctx context.Context, keys []string) ([]int64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := b.client().Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.XLen(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	lengths := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		lengths[i] = cmd.Val()
+	}
+	return lengths, nil
+}
+
+// usage reads XLEN, MEMORY USAGE, OBJECT IDLETIME and the counters in one round trip
+func (b *streamBackend) usage(ctx context.Context, key string) (queueUsage, error) {
+	pipe := b.client().Pipeline()
+	length := pipe.XLen(ctx, key)
+	memory := pipe.MemoryUsage(ctx, key)
+	idle := pipe.ObjectIdleTime(ctx, key)
+	counters := pipe.HGetAll(ctx, countersKey(key))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return queueUsage{}, err
+	}
+
+	usage := queueUsage{length: length.Val()}
+	if usage.length > 0 {
+		usage.memory = memory.Val()
+		usage.idle = idle.Val()
+		usage.idleKnown = idle.Err() == nil
+	}
+	values := counters.Val()
+	usage.enqueued, _ = strconv.ParseInt(values[counterEnqueued], 10, 64)
+	usage.duplicates, _ = strconv.ParseInt(values[counterDuplicates], 10, 64)
+	usage.dropped, _ = strconv.ParseInt(values[counterDropped], 10, 64)
+	return usage, nil
+}
+
+// keys scans the keyspace with SCAN, restricted to streams if queuesOnly is set
+func (b *streamBackend) keys(ctx context.Context, match string, queuesOnly bool) ([]string, error) {
+	client := b.client()
+
+	var iter *redis.ScanIterator
+	if queuesOnly {
+		iter = client.ScanType(ctx, 0, match, scanCount, "stream").Iterator()
+	} else {
+		iter = client.Scan(ctx, 0, match, scanCount).Iterator()
+	}
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// purge reads the length and deletes the keys in one transaction
+func (b *streamBackend) purge(ctx context.Context, key string, extra ...string) (int64, error) {
+	var size *redis.IntCmd
+	_, err := b.client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		size = pipe.XLen(ctx, key)
+		pipe.Del(ctx, append([]string{key}, extra...)...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size.Val(), nil
+}
+
+// del deletes keys with DEL
+func (b *streamBackend) del(ctx context.Context, keys ...string) error {
+	return b.client().Del(ctx, keys...).Err()
+}
+
+// position runs streamPositionScript
+func (b *streamBackend) position(ctx context.Context, key, value string) (int64, error) {
+	return streamPositionScript.Run(ctx, b.client(), []string{key}, value).Int64()
+}
+
+// lrange runs streamRangeScript
+func (b *streamBackend) lrange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return streamRangeScript.Run(ctx, b.client(), []string{key}, start, stop).StringSlice()
+}
+
+// purgeExpired runs streamPurgeExpiredScript against the stream and its expiry set
+func (b *streamBackend) purgeExpired(ctx context.Context, key string, now time.Time, chunk int) (int64, int64, error) {
+	keys := []string{key, expiryKey(key)}
+	result, err := streamPurgeExpiredScript.Run(ctx, b.client(), keys, strconv.FormatInt(now.UnixMilli(), 10), chunk).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], result[1], nil
+}
+
+// remove runs streamSweepScript to delete, and optionally move, the messages
+func (b *streamBackend) remove(ctx context.Context, key, target string, values []string) (int64, error) {
+	keys := []string{key, key}
+	args := make([]interface{}, 0, len(values)+1)
+	args = append(args, "0")
+	if target != "" {
+		keys[1] = target
+		args[0] = "1"
+	}
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return streamSweepScript.Run(ctx, b.client(), keys, args...).Int64()
+}
+
+// move runs streamMoveScript, which moves every entry in one atomic step
+func (b *streamBackend) move(ctx context.Context, key, target string, count int64) (int64, error) {
+	if count <= 0 {
+		return 0, nil
+	}
+	return streamMoveScript.Run(ctx, b.client(), []string{key, target}, count).Int64()
+}
+
+// replace runs streamReplaceScript against the streams
+func (b *streamBackend) replace(ctx context.Context, key, target string, values, replacements []string) (int64, error) {
+	args := make([]interface{}, 0, len(values)+len(replacements))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	for _, replacement := range replacements {
+		args = append(args, replacement)
+	}
+	return streamReplaceScript.Run(ctx, b.client(), []string{key, target}, args...).Int64()
+}
@@ -0,0 +1,177 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newStreamSender creates a sender with the stream backend on a fresh miniredis
+func newStreamSender(t *testing.T) *valkeySender {
+	t.Helper()
+	
+	config := DefaultConfig()
+	config.Backend = BackendStream
+	config.Address = miniredis.RunT(t).Addr()
+	config.HealthCheckInterval = 0
+	options := &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	
+	sender, err := NewSender(config, options)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender)
+}
+
+// streamPayloads decodes the payloads of a stream, oldest entry first
+func streamPayloads(t *testing.T, s *valkeySender, key string) []string {
+	t.Helper()
+	
+	entries, err := s.getClient().XRange(context.Background(), key, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE failed: %v", err)
+	}
+	payloads := make([]string, len(entries))
+	for i, entry := range entries {
+		envelope, err := DeserializeMessageEnvelope([]byte(entry.Values[StreamMessageField].(string)))
+		if err != nil {
+			t.Fatalf("Entry %s is not an envelope: %v", entry.ID, err)
+		}
+		payloads[i] = string(envelope.Payload)
+	}
+	return payloads
+}
+
+func TestStreamBackendSendAndPeek(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamSender(t)
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	// One entry per message, oldest first, for XREADGROUP consumers
+	if payloads := streamPayloads(t, s, "queue:orders"); strings.Join(payloads, ",") != "a,b,c" {
+		t.Errorf("Expected the messages as stream entries in send order, got %v", payloads)
+	}
+	
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 10)
+	if err != nil || len(envelopes) != 3 {
+		t.Fatalf("Expected 3 messages, got %d (%v)", len(envelopes), err)
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if string(envelopes[i].Payload) != want {
+			t.Errorf("Expected message %d to be %s, got %s", i, want, envelopes[i].Payload)
+		}
+	}
+	if envelopes, _ := s.PeekMessages(ctx, "orders", 1, 1); len(envelopes) != 1 || string(envelopes[0].Payload) != "b" {
+		t.Errorf("Expected to peek the second message, got %+v", envelopes)
+	}
+	
+	queues, err := s.ListQueues(ctx, "")
+	if err != nil || len(queues) != 1 || queues[0] != "orders" {
+		t.Errorf("Expected [orders], got %v (%v)", queues, err)
+	}
+	if stats, err := s.GetQueueStats(ctx, "orders"); err != nil || stats.Length != 3 || stats.Enqueued != 3 {
+		t.Errorf("Unexpected stats %+v (%v)", stats, err)
+	}
+	
+	if removed, err := s.PurgeQueue(ctx, "orders"); err != nil || removed != 3 {
+		t.Errorf("Expected 3 purged messages, got %d (%v)", removed, err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 0 {
+		t.Errorf("Expected empty queue after purge, got %d", size)
+	}
+}
+
+func TestStreamBackendDropOldest(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamSender(t)
+	s.config.MaxQueueLength = 2
+	s.config.OverflowPolicy = OverflowPolicyDropOldest
+	
+	for _, message := range []string{"a", "b", "c"} {
+		if err := s.SendMessage(ctx, "orders", message); err != nil {
+			t.Fatalf("Expected drop-oldest send to succeed, got %v", err)
+		}
+	}
+	if payloads := streamPayloads(t, s, "queue:orders"); strings.Join(payloads, ",") != "b,c" {
+		t.Errorf("Expected the oldest entry to be trimmed, got %v", payloads)
+	}
+	
+	s.config.OverflowPolicy = OverflowPolicyReject
+	if err := s.SendMessage(ctx, "orders", "d"); err == nil {
+		t.Error("Expected a full stream to reject the message")
+	}
+}
+
+func TestStreamBackendPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamSender(t)
+	s.config.TTLStrategy = TTLStrategyMessage
+	
+	s.SendMessageWithTTL(ctx, "orders", "short-lived", time.Millisecond)
+	s.SendMessageWithTTL(ctx, "orders", "long-lived", time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	
+	if removed, err := s.PurgeExpired(ctx, "orders"); err != nil || removed != 1 {
+		t.Fatalf("Expected 1 expired message, got %d (%v)", removed, err)
+	}
+	if payloads := streamPayloads(t, s, "queue:orders"); len(payloads) != 1 || payloads[0] != "long-lived" {
+		t.Errorf("Expected only the long-lived message to remain, got %v", payloads)
+	}
+}
+
+func TestStreamBackendSweep(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamSender(t)
+	s.config.SweepChunkSize = 3
+	s.config.ExpiredQueue = "expired"
+	
+	for i := 0; i < 6; i++ {
+		timestamp := time.Now()
+		if i%2 == 0 {
+			timestamp = timestamp.Add(-2 * time.Hour)
+		}
+		data, _ := SerializeMessageEnvelope(MessageEnvelope{
+			ID:        fmt.Sprintf("m%d", i),
+			Payload:   []byte(fmt.Sprint(i)),
+			Timestamp: timestamp,
+			TTL:       time.Hour,
+		})
+		s.getClient().XAdd(ctx, &redis.XAddArgs{Stream: "queue:orders", Values: []string{StreamMessageField, string(data)}})
+	}
+	
+	s.sweep(ctx)
+	
+	if payloads := streamPayloads(t, s, "queue:orders"); strings.Join(payloads, ",") != "1,3,5" {
+		t.Errorf("Expected the live envelopes to remain, got %v", payloads)
+	}
+	if payloads := streamPayloads(t, s, "queue:expired"); len(payloads) != 3 {
+		t.Errorf("Expected 3 envelopes routed to the expired queue, got %v", payloads)
+	}
+}
+
+func TestStreamBackendRejectsConsumers(t *testing.T) {
+	config := DefaultConfig()
+	config.Backend = BackendStream
+	if err := config.validate(); err != nil {
+		t.Fatalf("Expected the stream backend to be valid, got %v", err)
+	}
+	
+	_, err := NewConsumer(config, ConsumerConfig{Queue: "orders", Name: "worker"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "XREADGROUP") {
+		t.Errorf("Expected consumers to be rejected on the stream backend, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// benchEnvelope is a typical envelope with a small JSON payload and headers
+func benchEnvelope() MessageEnvelope {
+	return MessageEnvelope{
+		Version:   EnvelopeVersion,
+		ID:        "1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+		Queue:     "orders",
+		Payload:   []byte(`{"order_id":"o-12345","amount":99.5,"currency":"EUR"}`),
+		Headers:   map[string]string{HeaderMessageType: "OrderCreated"},
+		Timestamp: time.Date(2025, 5, 28, 10, 30, 0, 123456789, time.UTC),
+		TTL:       24 * time.Hour,
+	}
+}
+
+func BenchmarkJSONEnvelopeCodecEncode(b *testing.B) {
+	codec := NewJSONEnvelopeCodec()
+	envelope := benchEnvelope()
+	
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := codec.Encode(envelope); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewQueueBatch(b *testing.B) {
+	server := miniredis.RunT(b)
+	s := newTestSender(b, server, nil)
+	message := map[string]interface{}{"order_id": "o-12345", "amount": 99.5}
+	
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.newQueueBatch(context.Background(), "orders", []interface{}{message}, time.Hour, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newBenchSender creates a sender on the in-memory backend without a rate
+// limit, so the numbers are the sender's own overhead rather than a server's
+func newBenchSender(b *testing.B) *valkeySender {
+	config := DefaultConfig()
+	config.Backend = BackendMemory
+	config.Address = b.Name()
+	config.HealthCheckInterval = 0
+	config.RateLimitRequests = math.MaxInt32
+	config.RateLimitBurst = math.MaxInt32
+	sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		b.Fatalf("Failed to create sender: %v", err)
+	}
+	b.Cleanup(func() {
+		sender.Close()
+		memoryStores.Delete(config.Address + "/0")
+	})
+	return sender.(*valkeySender)
+}
+
+func BenchmarkSendMessage(b *testing.B) {
+	s := newBenchSender(b)
+	ctx := context.Background()
+	
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := s.SendMessage(ctx, "orders", "payload"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSendMessageParallel(b *testing.B) {
+	s := newBenchSender(b)
+	ctx := context.Background()
+	
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := s.SendMessage(ctx, "orders", "payload"); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkSendBatch(b *testing.B) {
+	for _, size := range []int{10, 100} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			s := newBenchSender(b)
+			ctx := context.Background()
+			messages := make([]interface{}, size)
+			for i := range messages {
+				messages[i] = map[string]interface{}{"order_id": "o-12345", "amount": 99.5}
+			}
+			
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := s.SendBatch(ctx, "orders", messages); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkJSONSerializerSerialize(b *testing.B) {
+	serializer := NewJSONSerializer()
+	message := map[string]interface{}{"order_id": "o-12345", "amount": 99.5}
+	
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := serializer.Serialize(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package valkeysender
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Headers added to envelopes forwarded by a Bridge
+const (
+	HeaderKafkaTopic     = "kafka-topic"
+	HeaderKafkaPartition = "kafka-partition"
+	HeaderKafkaOffset    = "kafka-offset"
+	HeaderKafkaTimestamp = "kafka-timestamp"
+
+	// DefaultBridgeKeyHeader holds the record key when BridgeConfig.KeyHeader is unset
+	DefaultBridgeKeyHeader = "kafka-key"
+)
+
+// Bridge defaults
+const (
+	defaultBridgeCommitBatch    = 100
+	defaultBridgeCommitInterval = time.Second
+	bridgeRetryMin              = 100 * time.Millisecond
+	bridgeRetryMax              = 10 * time.Second
+)
+
+// KafkaRecord is a record consumed from Kafka
+type KafkaRecord struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte // nil for tombstones
+	Headers   map[string]string
+	Time      time.Time
+}
+
+// KafkaSource consumes records for a Bridge. The library has no Kafka
+// client of its own; adapters wrap one, e.g. a kafka-go Reader in a
+// consumer group or a franz-go client.
+type KafkaSource interface {
+	// Fetch blocks until the next record is available or ctx is done
+	Fetch(ctx context.Context) (KafkaRecord, error)
+
+	// Commit marks records, and those before them in their partitions, as
+	// processed
+	Commit(ctx context.Context, records ...KafkaRecord) error
+}
+
+// BridgeConfig configures a Bridge
+type BridgeConfig struct {
+	// Routes maps Kafka topics to queues; records of other topics are
+	// forwarded to the queue named like their topic
+	Routes map[string]string
+
+	// KeyHeader is the envelope header holding the record key (default
+	// DefaultBridgeKeyHeader). Keys that are not valid UTF-8 are base64
+	// encoded, with KeyHeader+"-encoding" set to "base64".
+	KeyHeader string
+
+	// CommitBatch and CommitInterval bound how many forwarded records, and
+	// for how long, are left uncommitted (default 100 and 1s)
+	CommitBatch    int
+	CommitInterval time.Duration
+
+	// Logger for the bridge (default slog.Default())
+	Logger Logger
+}
+
+// Bridge forwards Kafka records into Valkey queues in the envelope format
+// of the sender, so producers can move off Kafka one at a time while
+// consumers read from Valkey only. The record value becomes the payload;
+// key, topic, partition, offset, timestamp and headers become envelope
+// headers.
+//
+// Records are committed only after they were sent, and sent with their
+// topic, partition and offset as idempotency key, so a record redelivered
+// after a crash is skipped as a duplicate within the deduplication window.
+// The value is sent as []byte, which the sender's serializer must pass
+// through, as the JSON serializer does.
+type Bridge struct {
+	source KafkaSource
+	sender Sender
+	config BridgeConfig
+	logger *slog.Logger
+}
+
+// NewBridge creates a bridge forwarding records from source through sender
+func NewBridge(source KafkaSource, sender Sender, config BridgeConfig) (*Bridge, error) {
+	if source == nil {
+		return nil, fmt.Errorf("source cannot be nil")
+	}
+	if sender == nil {
+		return nil, fmt.Errorf("sender cannot be nil")
+	}
+	for topic, queue := range config.Routes {
+		if topic == "" || queue == "" {
+			return nil, fmt.Errorf("bridge routes need a topic and a queue")
+		}
+	}
+
+	if config.KeyHeader == "" {
+		config.KeyHeader = DefaultBridgeKeyHeader
+	}
+	if config.CommitBatch <= 0 {
+		config.CommitBatch = defaultBridgeCommitBatch
+	}
+	if config.CommitInterval <= 0 {
+		config.CommitInterval = defaultBridgeCommitInterval
+	}
+
+	logger := slog.Default()
+	if config.Logger != nil {
+		logger = slogLogger(config.Logger)
+	}
+
+	return &Bridge{source: source, sender: sender, config: config, logger: logger}, nil
+}
+
+// Run forwards records until ctx is done, then commits the records already
+// forwarded and returns nil. A record failing to send is retried with
+// backoff, so records are never skipped or reordered; Run only gives up,
+// returning the error, on records that can't be sent at all (e.g. rejected
+// by the Validator) and on source failures.
+func (b *Bridge) Run(ctx context.Context) error {
+	var pending []KafkaRecord
+	var firstPending time.Time
+
+	for {
+		// Wake up to commit once the oldest uncommitted record is due
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(pending) > 0 {
+			fetchCtx, cancel = context.WithDeadline(ctx, firstPending.Add(b.config.CommitInterval))
+		}
+		record, err := b.source.Fetch(fetchCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() == nil && fetchCtx.Err() != nil {
+				if err := b.commit(ctx, pending); err != nil {
+					return err
+				}
+				pending = nil
+				continue
+			}
+			b.commitOnExit(pending)
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch Kafka record: %w", err)
+		}
+
+		if err := b.forwardWithRetry(ctx, record); err != nil {
+			b.commitOnExit(pending)
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if len(pending) == 0 {
+			firstPending = time.Now()
+		}
+		pending = append(pending, record)
+		if len(pending) >= b.config.CommitBatch {
+			if err := b.commit(ctx, pending); err != nil {
+				return err
+			}
+			pending = nil
+		}
+	}
+}
+
+// forwardWithRetry forwards a record, retrying transient failures with
+// exponential backoff until ctx is done
+func (b *Bridge) forwardWithRetry(ctx context.Context, record KafkaRecord) error {
+	delay := bridgeRetryMin
+	for {
+		err := b.forward(ctx, record)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrValidation) || errors.Is(err, ErrSerialization) || errors.Is(err, ErrMessageTooLarge) || errors.Is(err, ErrInvalidQueue) || errors.Is(err, ErrClosed) {
+			return fmt.Errorf("failed to forward %s/%d@%d: %w", record.Topic, record.Partition, record.Offset, err)
+		}
+
+		b.logger.Warn("Failed to forward Kafka record, retrying",
+			slog.String("topic", record.Topic),
+			slog.Int("partition", record.Partition),
+			slog.Int64("offset", record.Offset),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(delay*2, bridgeRetryMax)
+	}
+}
+
+// forward sends a record to its queue; tombstones are dropped
+func (b *Bridge) forward(ctx context.Context, record KafkaRecord) error {
+	if record.Value == nil {
+		return nil
+	}
+
+	queue := record.Topic
+	if routed, ok := b.config.Routes[record.Topic]; ok {
+		queue = routed
+	}
+
+	return b.sender.SendMessageWithOptions(ctx, queue, record.Value, SendOptions{
+		Headers:        b.headers(record),
+		IdempotencyKey: "kafka:" + record.Topic + ":" + strconv.Itoa(record.Partition) + ":" + strconv.FormatInt(record.Offset, 10),
+	})
+}
+
+// headers returns the envelope headers of a record: its Kafka headers, its
+// key and where it came from
+func (b *Bridge) headers(record KafkaRecord) map[string]string {
+	headers := make(map[string]string, len(record.Headers)+6)
+	maps.Copy(headers, record.Headers)
+
+	if record.Key != nil {
+		if utf8.Valid(record.Key) {
+			headers[b.config.KeyHeader] = string(record.Key)
+		} else {
+			headers[b.config.KeyHeader] = base64.StdEncoding.EncodeToString(record.Key)
+			headers[b.config.KeyHeader+"-encoding"] = "base64"
+		}
+	}
+	headers[HeaderKafkaTopic] = record.Topic
+	headers[HeaderKafkaPartition] = strconv.Itoa(record.Partition)
+	headers[HeaderKafkaOffset] = strconv.FormatInt(record.Offset, 10)
+	if !record.Time.IsZero() {
+		headers[HeaderKafkaTimestamp] = record.Time.UTC().Format(time.RFC3339Nano)
+	}
+	return headers
+}
+
+// commit commits forwarded records
+func (b *Bridge) commit(ctx context.Context, records []KafkaRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := b.source.Commit(ctx, records...); err != nil {
+		return fmt.Errorf("failed to commit Kafka records: %w", err)
+	}
+	b.logger.Debug("Kafka records committed", slog.Int("count", len(records)))
+	return nil
+}
+
+// commitOnExit commits forwarded records while Run returns, on a deadline
+// of its own since ctx may be done
+func (b *Bridge) commitOnExit(records []KafkaRecord) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.CommitInterval)
+	defer cancel()
+	if err := b.commit(ctx, records); err != nil {
+		// The records are forwarded again after a restart, as duplicates
+		b.logger.Warn("Failed to commit Kafka records on exit", slog.Any("error", err))
+	}
+}
+
+// parseBridgeRoutes parses "topic=queue" routes
+func parseBridgeRoutes(routes []string) (map[string]string, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string, len(routes))
+	for _, route := range routes {
+		topic, queue, ok := strings.Cut(route, "=")
+		topic, queue = strings.TrimSpace(topic), strings.TrimSpace(queue)
+		if !ok || topic == "" || queue == "" {
+			return nil, fmt.Errorf("invalid bridge route %q, expected topic=queue", route)
+		}
+		parsed[topic] = queue
+	}
+	return parsed, nil
+}
+
+// BridgeConfig returns the bridge settings of the configuration, loaded
+// from VALKEY_SENDER_BRIDGE_* (routes are validated by LoadConfig)
+func (c *Config) BridgeConfig() BridgeConfig {
+	routes, _ := parseBridgeRoutes(c.BridgeRoutes)
+	return BridgeConfig{
+		Routes:         routes,
+		KeyHeader:      c.BridgeKeyHeader,
+		CommitBatch:    c.BridgeCommitBatch,
+		CommitInterval: c.BridgeCommitInterval,
+	}
+}
@@ -0,0 +1,214 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeKafkaSource serves records from a channel and records commits
+type fakeKafkaSource struct {
+	records chan KafkaRecord
+
+	mu        sync.Mutex
+	committed []KafkaRecord
+}
+
+func newFakeKafkaSource(records ...KafkaRecord) *fakeKafkaSource {
+	source := &fakeKafkaSource{records: make(chan KafkaRecord, 100)}
+	for _, record := range records {
+		source.records <- record
+	}
+	return source
+}
+
+func (f *fakeKafkaSource) Fetch(ctx context.Context) (KafkaRecord, error) {
+	select {
+	case record := <-f.records:
+		return record, nil
+	case <-ctx.Done():
+		return KafkaRecord{}, ctx.Err()
+	}
+}
+
+func (f *fakeKafkaSource) Commit(_ context.Context, records ...KafkaRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, records...)
+	return nil
+}
+
+func (f *fakeKafkaSource) commits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.committed)
+}
+
+// runBridge runs a bridge until the test ends
+func runBridge(t *testing.T, source KafkaSource, sender Sender, config BridgeConfig) <-chan error {
+	t.Helper()
+
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	bridge, err := NewBridge(source, sender, config)
+	if err != nil {
+		t.Fatalf("NewBridge failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		done <- bridge.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-finished
+	})
+	return done
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	stamp := time.Date(2025, 5, 28, 10, 30, 0, 0, time.UTC)
+
+	source := newFakeKafkaSource(
+		KafkaRecord{Topic: "user-events", Partition: 2, Offset: 41, Key: []byte("user-1"), Value: []byte(`{"signup":true}`), Headers: map[string]string{"trace-id": "t1"}, Time: stamp},
+		KafkaRecord{Topic: "user-events", Partition: 2, Offset: 42, Key: []byte("user-1")}, // tombstone
+		KafkaRecord{Topic: "orders", Partition: 0, Offset: 7, Key: []byte{0xff, 0xfe}, Value: []byte(`{"order":1}`)},
+	)
+	runBridge(t, source, s, BridgeConfig{Routes: map[string]string{"user-events": "registrations"}, CommitInterval: 20 * time.Millisecond})
+
+	waitFor(t, func() bool { return source.commits() == 3 })
+
+	envelopes, err := s.PeekMessages(ctx, "registrations", 0, 10)
+	if err != nil || len(envelopes) != 1 {
+		t.Fatalf("Expected one routed message, got %d, %v", len(envelopes), err)
+	}
+	envelope := envelopes[0]
+	if string(envelope.Payload) != `{"signup":true}` {
+		t.Errorf("Expected the record value as payload, got %s", envelope.Payload)
+	}
+	expected := map[string]string{
+		"trace-id":           "t1",
+		"kafka-key":          "user-1",
+		HeaderKafkaTopic:     "user-events",
+		HeaderKafkaPartition: "2",
+		HeaderKafkaOffset:    "41",
+		HeaderKafkaTimestamp: "2025-05-28T10:30:00Z",
+	}
+	for key, value := range expected {
+		if envelope.Headers[key] != value {
+			t.Errorf("Expected header %s=%q, got %q", key, value, envelope.Headers[key])
+		}
+	}
+
+	envelopes, err = s.PeekMessages(ctx, "orders", 0, 10)
+	if err != nil || len(envelopes) != 1 {
+		t.Fatalf("Expected unrouted topic to be forwarded to its own queue, got %d, %v", len(envelopes), err)
+	}
+	if envelopes[0].Headers["kafka-key"] != "//4=" || envelopes[0].Headers["kafka-key-encoding"] != "base64" {
+		t.Errorf("Expected binary key to be base64 encoded, got %v", envelopes[0].Headers)
+	}
+}
+
+func TestBridgeRedelivery(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+
+	// A record forwarded but not committed before a crash is delivered again
+	record := KafkaRecord{Topic: "orders", Partition: 1, Offset: 5, Value: []byte(`{"order":5}`)}
+	source := newFakeKafkaSource(record, record)
+	runBridge(t, source, s, BridgeConfig{CommitBatch: 2})
+
+	waitFor(t, func() bool { return source.commits() == 2 })
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected redelivered record to be forwarded once, got size %d", size)
+	}
+}
+
+// flakySender fails the first sends as if Valkey were unreachable
+type flakySender struct {
+	Sender
+	failures int32
+}
+
+func (f *flakySender) SendMessageWithOptions(ctx context.Context, queue string, message interface{}, opts SendOptions) error {
+	if atomic.AddInt32(&f.failures, -1) >= 0 {
+		return &Error{Kind: ErrNotConnected, Err: errors.New("connection refused")}
+	}
+	return f.Sender.SendMessageWithOptions(ctx, queue, message, opts)
+}
+
+func TestBridgeRetry(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+
+	source := newFakeKafkaSource(KafkaRecord{Topic: "orders", Value: []byte(`{"order":1}`)})
+	runBridge(t, source, &flakySender{Sender: s, failures: 2}, BridgeConfig{CommitInterval: 10 * time.Millisecond})
+
+	waitFor(t, func() bool { return source.commits() == 1 })
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected record to be forwarded after retries, got size %d", size)
+	}
+}
+
+func TestBridgeRejectedRecord(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, &SenderOptions{Validator: func(string, interface{}) error {
+		return errors.New("not allowed")
+	}})
+
+	source := newFakeKafkaSource(KafkaRecord{Topic: "orders", Value: []byte(`{}`)})
+	done := runBridge(t, source, s, BridgeConfig{})
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected bridge to stop with ErrValidation, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected bridge to stop on a rejected record")
+	}
+	if source.commits() != 0 {
+		t.Error("Expected rejected record not to be committed")
+	}
+}
+
+func TestBridgeConfigFromEnv(t *testing.T) {
+	t.Setenv("VALKEY_SENDER_BRIDGE_ROUTES", "user-events=registrations, orders=orders")
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected routes to be valid, got %v", err)
+	}
+	bridge := config.BridgeConfig()
+	if bridge.Routes["user-events"] != "registrations" || bridge.Routes["orders"] != "orders" {
+		t.Errorf("Unexpected routes %v", bridge.Routes)
+	}
+
+	config.BridgeRoutes = []string{"orders"}
+	if err := config.validate(); err == nil {
+		t.Error("Expected route without queue to be rejected")
+	}
+}
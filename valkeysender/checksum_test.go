@@ -0,0 +1,136 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPayloadChecksum(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{ChecksumCRC32, "crc32:cbf43926"},
+		{"SHA256", "sha256:15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225"},
+	}
+	for _, tt := range tests {
+		got, err := PayloadChecksum(tt.algorithm, []byte("123456789"))
+		if err != nil || got != tt.want {
+			t.Errorf("PayloadChecksum(%s) = %s, %v, want %s", tt.algorithm, got, err, tt.want)
+		}
+	}
+	if _, err := PayloadChecksum("md5", nil); err == nil {
+		t.Error("Expected unsupported algorithm to be rejected")
+	}
+
+	envelope := MessageEnvelope{ID: "m1", Payload: []byte("123456789"), Checksum: "crc32:cbf43926"}
+	if err := envelope.VerifyChecksum(); err != nil {
+		t.Errorf("Expected checksum to match, got %v", err)
+	}
+	envelope.Payload = envelope.Payload[:5]
+	if err := envelope.VerifyChecksum(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected truncated payload to fail with ErrCorrupt, got %v", err)
+	}
+	if err := (MessageEnvelope{Payload: []byte("x")}).VerifyChecksum(); err != nil {
+		t.Errorf("Expected envelope without checksum to pass, got %v", err)
+	}
+}
+
+// corruptQueue replaces the messages of a queue with copies whose payload
+// is truncated but whose checksum is kept
+func corruptQueue(t *testing.T, server *miniredis.Miniredis, key string) {
+	t.Helper()
+
+	values, err := server.List(key)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", key, err)
+	}
+	server.Del(key)
+	for i := len(values) - 1; i >= 0; i-- {
+		envelope, err := DeserializeMessageEnvelope([]byte(values[i]))
+		if err != nil {
+			t.Fatalf("Expected stored envelope to verify, got %v", err)
+		}
+		envelope.Payload = envelope.Payload[:len(envelope.Payload)/2]
+		data, err := SerializeMessageEnvelope(envelope)
+		if err != nil {
+			t.Fatalf("Failed to encode envelope: %v", err)
+		}
+		server.Lpush(key, string(data))
+	}
+}
+
+func TestEnvelopeChecksum(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithEnvelopeChecksum(ChecksumSHA256),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+
+	if err := sender.SendMessage(ctx, "jobs", map[string]string{"job": "resize"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	envelopes, err := sender.PeekMessages(ctx, "jobs", 0, 1)
+	if err != nil || len(envelopes) != 1 {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if want, _ := PayloadChecksum(ChecksumSHA256, envelopes[0].Payload); envelopes[0].Checksum != want {
+		t.Errorf("Expected checksum %s, got %s", want, envelopes[0].Checksum)
+	}
+
+	corruptQueue(t, server, "queue:jobs")
+	if _, err := sender.PeekMessages(ctx, "jobs", 0, 1); err != nil {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if corrupt := sender.Metrics().CorruptMessages; corrupt != 1 {
+		t.Errorf("Expected one corrupt message counted, got %d", corrupt)
+	}
+
+	// Consumers detect it too and can dead-letter it
+	consumer := newTestConsumer(t, server, ConsumerConfig{Queue: "jobs", Name: "worker-1", DeadLetterQueue: "jobs-dead"})
+	delivery, err := consumer.Receive(ctx)
+	if !errors.Is(err, ErrCorrupt) || delivery == nil {
+		t.Fatalf("Expected delivery with ErrCorrupt, got %v", err)
+	}
+	if consumer.CorruptMessages() != 1 {
+		t.Errorf("Expected the consumer to count the corrupt message, got %d", consumer.CorruptMessages())
+	}
+	if err := consumer.Nack(ctx, delivery); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	if dead, _ := server.List("queue:jobs-dead"); len(dead) != 1 {
+		t.Errorf("Expected the corrupt message in the dead letter queue, got %d", len(dead))
+	}
+}
+
+func TestCloudEventsChecksum(t *testing.T) {
+	codec := NewCloudEventsCodec("test", "")
+	payload := []byte(`{"user": "u1"}`) // embedding would compact it
+	checksum, _ := PayloadChecksum(ChecksumCRC32, payload)
+
+	data, err := codec.Encode(MessageEnvelope{ID: "m1", Queue: "users", Payload: payload, Checksum: checksum})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	envelope, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Expected the checksum to verify, got %v", err)
+	}
+	if envelope.Checksum != checksum || string(envelope.Payload) != string(payload) {
+		t.Errorf("Expected payload and checksum to round trip, got %s, %s", envelope.Payload, envelope.Checksum)
+	}
+	if _, ok := envelope.Headers["checksum"]; ok {
+		t.Error("Expected the checksum not to be exposed as a header")
+	}
+}
@@ -0,0 +1,138 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultBatchChunkSize is the number of messages SendBatchChunked pushes
+// per round trip when Config.BatchChunkSize is unset (SendBatch then sends
+// batches whole)
+const DefaultBatchChunkSize = 1000
+
+// BatchProgress reports a chunk of a chunked batch that was sent
+type BatchProgress struct {
+	Queue  string
+	Chunk  int // 1-based index of the chunk just sent
+	Chunks int // total number of chunks
+	Sent   int // messages sent so far
+	Total  int // messages in the batch
+}
+
+// batchChunkSize returns the configured chunk size or the default
+func (s *valkeySender) batchChunkSize() int {
+	if s.config.BatchChunkSize > 0 {
+		return s.config.BatchChunkSize
+	}
+	return DefaultBatchChunkSize
+}
+
+// SendBatchChunked sends a large batch in chunks of Config.BatchChunkSize
+// messages, each serialized and pushed atomically in its own round trip, so
+// no single command grows with the batch. Rate limiting and the circuit
+// breaker apply per chunk, and SenderOptions.BatchProgressHandler is called
+// after every chunk.
+//
+// Chunks are sent in order and the first failure stops the batch: the
+// result reports how many messages were sent before it, and those stay in
+// the queue. The returned error is the one of the failed chunk.
+func (s *valkeySender) SendBatchChunked(ctx context.Context, queue string, messages []interface{}) (*BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+
+	start := time.Now()
+	size := s.batchChunkSize()
+	chunks := (len(messages) + size - 1) / size
+	result := &BatchResult{Chunks: chunks, Results: make([]MessageResult, 0, len(messages))}
+
+	for chunk := 0; chunk < chunks; chunk++ {
+		end := min((chunk+1)*size, len(messages))
+		chunkStart := time.Now()
+		batch, err := s.sendBatch(ctx, queue, messages[chunk*size:end])
+		result.addChunk(batch, end-chunk*size, chunkStart, err)
+		if err != nil {
+			// Later chunks are not attempted and fail with the same error
+			for range messages[end:] {
+				result.Results = append(result.Results, MessageResult{Error: err})
+			}
+			result.Failed = len(messages) - result.TotalSent
+			result.Duration = time.Since(start)
+			result.Error = err
+
+			s.logger.Warn("Chunked batch stopped",
+				slog.String("queue", queue),
+				slog.Int("chunk", chunk+1),
+				slog.Int("chunks", chunks),
+				slog.Int("sent", result.TotalSent),
+				slog.Int("total", len(messages)),
+				slog.Any("error", err),
+			)
+			return result, err
+		}
+
+		if s.options.BatchProgressHandler != nil {
+			s.options.BatchProgressHandler(BatchProgress{
+				Queue:  queue,
+				Chunk:  chunk + 1,
+				Chunks: chunks,
+				Sent:   result.TotalSent,
+				Total:  len(messages),
+			})
+		}
+	}
+
+	result.Success = true
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// SendBatchWithResult sends a batch like SendBatch, chunked if it is larger
+// than Config.BatchChunkSize, and reports its outcome: how many messages
+// were sent or failed, how long it took, and per message results whose
+// metadata carries the envelope ID, queue position and payload size, so
+// callers can log and meter batches without a success handler.
+//
+// The result is returned with the error when the batch fails; messages of
+// a batch that could not be built have no metadata.
+func (s *valkeySender) SendBatchWithResult(ctx context.Context, queue string, messages []interface{}) (*BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+	if chunk := s.config.BatchChunkSize; chunk > 0 && len(messages) > chunk {
+		return s.SendBatchChunked(ctx, queue, messages)
+	}
+
+	start := time.Now()
+	result := &BatchResult{Chunks: 1, Results: make([]MessageResult, 0, len(messages))}
+	batch, err := s.sendBatch(ctx, queue, messages)
+	result.addChunk(batch, len(messages), start, err)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result, err
+	}
+	result.Success = true
+	return result, nil
+}
+
+// addChunk records the outcome of count messages pushed as one batch
+func (r *BatchResult) addChunk(batch *queueBatch, count int, start time.Time, err error) {
+	duration := time.Since(start)
+	for i := 0; i < count; i++ {
+		message := MessageResult{Success: err == nil, Error: err, Duration: duration}
+		if batch != nil {
+			metadata := batch.metadata(i, "", start)
+			metadata.Tenant = metadata.Headers[HeaderTenant]
+			message.Metadata = &metadata
+		}
+		r.Results = append(r.Results, message)
+	}
+	if err != nil {
+		r.Failed += count
+		return
+	}
+	r.TotalSent += count
+}
@@ -0,0 +1,160 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSendBatchChunked(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	var progress []BatchProgress
+	s := newTestSender(t, server, &SenderOptions{
+		BatchProgressHandler: func(p BatchProgress) { progress = append(progress, p) },
+	})
+	s.config.BatchChunkSize = 3
+	
+	messages := make([]interface{}, 7)
+	for i := range messages {
+		messages[i] = i
+	}
+	result, err := s.SendBatchChunked(ctx, "orders", messages)
+	if err != nil || !result.Success || result.TotalSent != 7 || result.Chunks != 3 || result.Failed != 0 {
+		t.Fatalf("Expected 7 messages in 3 chunks, got %+v (%v)", result, err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 7 {
+		t.Errorf("Expected 7 messages in the queue, got %d", size)
+	}
+	want := []BatchProgress{
+		{Queue: "orders", Chunk: 1, Chunks: 3, Sent: 3, Total: 7},
+		{Queue: "orders", Chunk: 2, Chunks: 3, Sent: 6, Total: 7},
+		{Queue: "orders", Chunk: 3, Chunks: 3, Sent: 7, Total: 7},
+	}
+	if len(progress) != len(want) {
+		t.Fatalf("Expected %d progress reports, got %+v", len(want), progress)
+	}
+	for i := range want {
+		if progress[i] != want[i] {
+			t.Errorf("Progress %d: expected %+v, got %+v", i, want[i], progress[i])
+		}
+	}
+	
+	// Messages keep their order across chunks
+	peeked, err := s.PeekMessages(ctx, "orders", 0, 7)
+	if err != nil || len(peeked) != 7 || string(peeked[0].Payload) != "0" || string(peeked[6].Payload) != "6" {
+		t.Errorf("Expected messages in send order, got %v (%v)", peeked, err)
+	}
+}
+
+func TestSendBatchChunkedStopsAtFailure(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.BatchChunkSize = 3
+	s.config.MaxQueueLength = 5
+	
+	result, err := s.SendBatchChunked(ctx, "orders", []interface{}{1, 2, 3, 4, 5, 6, 7})
+	if !errors.Is(err, ErrQueueFull) || result.Error != err {
+		t.Fatalf("Expected ErrQueueFull from the second chunk, got %v", err)
+	}
+	if result.Success || result.TotalSent != 3 || result.Failed != 4 {
+		t.Errorf("Expected 3 sent and 4 failed, got %+v", result)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 3 {
+		t.Errorf("Expected the first chunk to stay in the queue, got %d messages", size)
+	}
+}
+
+func TestSendBatchChunksLargeBatches(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	var chunks int
+	s := newTestSender(t, server, &SenderOptions{
+		BatchProgressHandler: func(BatchProgress) { chunks++ },
+	})
+	s.config.BatchChunkSize = 2
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{1, 2}); err != nil || chunks != 0 {
+		t.Fatalf("Expected a batch within the chunk size to be sent whole, got %d chunks (%v)", chunks, err)
+	}
+	if err := s.SendBatch(ctx, "orders", []interface{}{3, 4, 5}); err != nil || chunks != 2 {
+		t.Errorf("Expected a larger batch to be chunked, got %d chunks (%v)", chunks, err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 5 {
+		t.Errorf("Expected 5 messages, got %d", size)
+	}
+}
+
+func TestSendBatchAtomicByDefault(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	var chunks int
+	s := newTestSender(t, server, &SenderOptions{
+		BatchProgressHandler: func(BatchProgress) { chunks++ },
+	})
+	if s.config.BatchChunkSize != 0 {
+		t.Fatalf("Expected chunking to be off by default, got %d", s.config.BatchChunkSize)
+	}
+	s.config.MaxQueueLength = DefaultBatchChunkSize
+	
+	messages := make([]interface{}, DefaultBatchChunkSize+1)
+	for i := range messages {
+		messages[i] = i
+	}
+	if err := s.SendBatch(ctx, "orders", messages); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected the whole batch to be rejected, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 0 || chunks != 0 {
+		t.Errorf("Expected nothing sent and no chunks, got %d messages in %d chunks", size, chunks)
+	}
+	
+	// SendBatchChunked still chunks, with the default chunk size
+	s.config.MaxQueueLength = 0
+	result, err := s.SendBatchChunked(ctx, "orders", messages)
+	if err != nil || result.Chunks != 2 || result.TotalSent != len(messages) {
+		t.Errorf("Expected 2 chunks of at most %d messages, got %+v (%v)", DefaultBatchChunkSize, result, err)
+	}
+}
+
+func TestSendBatchWithResult(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.BatchChunkSize = 3
+	s.config.MaxQueueLength = 5
+	
+	result, err := s.SendBatchWithResult(ctx, "orders", []interface{}{"a", "bb"})
+	if err != nil || !result.Success || result.TotalSent != 2 || result.Chunks != 1 || len(result.Results) != 2 || result.Duration <= 0 {
+		t.Fatalf("Expected 2 messages in one chunk, got %+v (%v)", result, err)
+	}
+	peeked, _ := s.PeekMessages(ctx, "orders", 0, 2)
+	for i, message := range result.Results {
+		if !message.Success || message.Metadata == nil || message.Metadata.MessageID != peeked[i].ID || message.Metadata.Position != int64(i+1) {
+			t.Errorf("Result %d: expected the metadata of %s, got %+v", i, peeked[i].ID, message)
+		}
+	}
+	if size := result.Results[1].Metadata.Size; size != 2 {
+		t.Errorf("Expected the payload size in the metadata, got %d", size)
+	}
+	
+	// Larger batches are chunked, and unsent chunks are reported as failed
+	result, err = s.SendBatchWithResult(ctx, "orders", []interface{}{1, 2, 3, 4})
+	if !errors.Is(err, ErrQueueFull) || result.Error != err || result.Chunks != 2 || result.TotalSent != 3 || result.Failed != 1 {
+		t.Fatalf("Expected the second chunk to fail, got %+v (%v)", result, err)
+	}
+	if len(result.Results) != 4 || !result.Results[2].Success || result.Results[3].Success || result.Results[3].Error != err {
+		t.Errorf("Expected per message outcomes for the whole batch, got %+v", result.Results)
+	}
+	
+	// Batches that cannot be built have no metadata
+	result, err = s.SendBatchWithResult(ctx, "orders", []interface{}{make(chan int)})
+	if !errors.Is(err, ErrSerialization) || result.Failed != 1 || result.Results[0].Metadata != nil {
+		t.Errorf("Expected a serialization failure without metadata, got %+v (%v)", result, err)
+	}
+}
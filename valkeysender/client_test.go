@@ -0,0 +1,77 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSharedClient(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	// Connection settings are ignored in favour of the shared client
+	config := DefaultConfig()
+	config.Address = "127.0.0.1:1"
+	options := &SenderOptions{Client: client, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	sender, err := NewSender(config, options)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	if err := sender.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if length, _ := client.LLen(ctx, DefaultQueuePrefix+"orders").Result(); length != 1 {
+		t.Errorf("Expected the message pushed through the shared client, got length %d", length)
+	}
+
+	consumer, err := NewConsumer(config, ConsumerConfig{Queue: "orders", Name: "worker-1"}, options)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	delivery, err := consumer.Receive(ctx)
+	if err != nil || string(delivery.Envelope.Payload) != "a" {
+		t.Fatalf("Expected to receive the message, got %v (%v)", delivery, err)
+	}
+
+	if err := consumer.Close(); err != nil {
+		t.Errorf("Consumer Close failed: %v", err)
+	}
+	if err := sender.Close(); err != nil {
+		t.Errorf("Sender Close failed: %v", err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Errorf("Expected the shared client to stay open, got %v", err)
+	}
+}
+
+func TestSharedClusterClientRejected(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}})
+	defer cluster.Close()
+	ring := redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"shard": server.Addr()}})
+	defer ring.Close()
+
+	for _, client := range []redis.UniversalClient{cluster, ring} {
+		options := &SenderOptions{Client: client, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+		if _, err := NewSender(DefaultConfig(), options); err == nil || !strings.Contains(err.Error(), "not supported") {
+			t.Errorf("Expected NewSender to reject %T, got %v", client, err)
+		}
+		if _, err := NewConsumer(DefaultConfig(), ConsumerConfig{Queue: "orders", Name: "worker-1"}, options); err == nil {
+			t.Errorf("Expected NewConsumer to reject %T", client)
+		}
+	}
+	if server.TotalConnectionCount() != 0 {
+		t.Errorf("Expected no connection to be opened, got %d", server.TotalConnectionCount())
+	}
+}
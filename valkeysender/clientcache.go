@@ -0,0 +1,249 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel carries the keys invalidated by CLIENT TRACKING
+const invalidationChannel = "__redis__:invalidate"
+
+// lengthCache serves queue lengths read on a connection with CLIENT
+// TRACKING enabled. Valkey remembers the keys read there and, once one of
+// them changes, publishes it on the invalidation channel, which drops the
+// cached length. go-redis cannot read RESP3 push messages from pooled
+// connections, so the invalidations are redirected to a dedicated
+// subscriber connection instead.
+type lengthCache struct {
+	client func() *redis.Client
+	ttl    time.Duration // bounds staleness should invalidations be lost
+
+	subscriber *redis.Client
+	pubsub     *redis.PubSub
+	redirect   int64 // client ID of the subscriber connection
+
+	mu       sync.Mutex
+	lengths  map[string]cachedLength
+	tracked  *redis.Conn   // connection reading lengths, nil until needed
+	source   *redis.Client // client the tracked connection belongs to
+	tracking int64         // redirect ID the tracked connection was set up with
+}
+
+// cachedLength is a queue length and when it was read
+type cachedLength struct {
+	length int64
+	readAt time.Time
+}
+
+// startClientCache enables client-side caching of queue lengths if
+// configured. Servers without CLIENT TRACKING leave it disabled.
+func (s *valkeySender) startClientCache() {
+	if !s.config.ClientCaching || s.getClient() == nil {
+		return
+	}
+	if !s.config.listBackend() {
+		s.logger.Warn("Client-side caching needs the list backend, reading queue lengths directly")
+		return
+	}
+
+	// Tracking needs a dedicated connection, which only a plain client hands out
+	if _, ok := s.getClient().(*redis.Client); !ok {
+		s.logger.Warn("Client-side caching needs a *redis.Client, reading queue lengths directly")
+		return
+	}
+	client := func() *redis.Client {
+		client, _ := s.getClient().(*redis.Client)
+		return client
+	}
+
+	cache, err := newLengthCache(s.ctx, s.config, s.options, client)
+	if err != nil {
+		s.logger.Warn("Client-side caching unavailable, reading queue lengths directly", slog.Any("error", err))
+		return
+	}
+	s.lengthCache = cache
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		messages := cache.pubsub.Channel()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				cache.invalidate(message)
+			}
+		}
+	}()
+}
+
+// queueLength returns the length of a list, from the client-side cache if
+// enabled. Should the cache fail, the length is read directly.
+func (s *valkeySender) queueLength(ctx context.Context, key string) (int64, error) {
+	if s.lengthCache != nil {
+		if length, err := s.lengthCache.length(ctx, key); err == nil {
+			return length, nil
+		}
+	}
+	return s.backend.length(ctx, key)
+}
+
+// newLengthCache subscribes to invalidations and enables tracking on a
+// first connection, failing if the server does not support it
+func newLengthCache(ctx context.Context, config *Config, options *SenderOptions, client func() *redis.Client) (*lengthCache, error) {
+	cache := &lengthCache{
+		client:  client,
+		ttl:     config.ClientCacheTTL,
+		lengths: make(map[string]cachedLength),
+	}
+
+	opts, err := newRedisOptions(config, options)
+	if err != nil {
+		return nil, err
+	}
+	opts.PoolSize = 1
+	opts.MinIdleConns = 0
+
+	// With RESP3 the server would send invalidations as push messages that
+	// the subscriber cannot parse; with RESP2 they arrive as pub/sub messages
+	opts.Protocol = 2
+
+	// The ID changes whenever the subscriber reconnects, and with it the
+	// REDIRECT target of the tracked connection
+	opts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read subscriber client ID: %w", err)
+		}
+		atomic.StoreInt64(&cache.redirect, id)
+		return nil
+	}
+	cache.subscriber = redis.NewClient(opts)
+
+	setupCtx, cancel := context.WithTimeout(ctx, config.DialTimeout)
+	defer cancel()
+
+	cache.pubsub = cache.subscriber.Subscribe(setupCtx, invalidationChannel)
+	if _, err := cache.pubsub.Receive(setupCtx); err != nil {
+		cache.close()
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	_, err = cache.trackedConn(setupCtx)
+	cache.mu.Unlock()
+	if err != nil {
+		cache.close()
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// length returns the cached length of the list, reading and tracking it first if needed
+func (c *lengthCache) length(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if length, ok := c.cached(key, time.Now()); ok {
+		return length, nil
+	}
+
+	conn, err := c.trackedConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Holding the lock while reading makes an invalidation racing the read
+	// wait for the length to be stored before dropping it
+	length, err := conn.LLen(ctx, key).Result()
+	if err == redis.Nil {
+		length, err = 0, nil
+	}
+	if err != nil {
+		// The tracking state of the connection is unknown now
+		c.reset()
+		return 0, err
+	}
+
+	c.lengths[key] = cachedLength{length: length, readAt: time.Now()}
+	return length, nil
+}
+
+// cached returns a length read less than the TTL ago
+func (c *lengthCache) cached(key string, now time.Time) (int64, bool) {
+	entry, ok := c.lengths[key]
+	if !ok || now.Sub(entry.readAt) >= c.ttl {
+		return 0, false
+	}
+	return entry.length, true
+}
+
+// trackedConn returns the connection with tracking enabled, setting it up
+// again after the client was replaced or the subscriber reconnected
+func (c *lengthCache) trackedConn(ctx context.Context) (*redis.Conn, error) {
+	client := c.client()
+	redirect := atomic.LoadInt64(&c.redirect)
+	if c.tracked != nil && c.source == client && c.tracking == redirect {
+		return c.tracked, nil
+	}
+	c.reset()
+
+	conn := client.Conn()
+	if err := conn.Process(ctx, redis.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", redirect)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable client tracking: %w", err)
+	}
+	c.tracked, c.source, c.tracking = conn, client, redirect
+	return conn, nil
+}
+
+// invalidate drops the lengths of the keys in an invalidation message; a
+// message without keys means the database was flushed
+func (c *lengthCache) invalidate(message *redis.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(message.PayloadSlice) == 0 && message.Payload == "" {
+		clear(c.lengths)
+		return
+	}
+	for _, key := range message.PayloadSlice {
+		delete(c.lengths, key)
+	}
+	if message.Payload != "" {
+		delete(c.lengths, message.Payload)
+	}
+}
+
+// reset forgets every length and closes the tracked connection
+func (c *lengthCache) reset() {
+	clear(c.lengths)
+	if c.tracked != nil {
+		c.tracked.Close()
+		c.tracked, c.source = nil, nil
+	}
+}
+
+// close releases the connections of the cache
+func (c *lengthCache) close() {
+	c.mu.Lock()
+	c.reset()
+	c.mu.Unlock()
+
+	if c.pubsub != nil {
+		c.pubsub.Close()
+	}
+	c.subscriber.Close()
+}
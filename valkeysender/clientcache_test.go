@@ -0,0 +1,68 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLengthCacheInvalidate(t *testing.T) {
+	now := time.Now()
+	cache := &lengthCache{ttl: time.Minute, lengths: map[string]cachedLength{
+		"queue:a": {length: 1, readAt: now},
+		"queue:b": {length: 2, readAt: now},
+		"queue:c": {length: 3, readAt: now.Add(-time.Minute)},
+	}}
+
+	if length, ok := cache.cached("queue:a", now); !ok || length != 1 {
+		t.Errorf("Expected the cached length 1, got %d (%v)", length, ok)
+	}
+	if _, ok := cache.cached("queue:c", now); ok {
+		t.Error("Expected a length older than the TTL not to be served")
+	}
+
+	cache.invalidate(&redis.Message{Channel: invalidationChannel, PayloadSlice: []string{"queue:a"}})
+	if _, ok := cache.cached("queue:a", now); ok {
+		t.Error("Expected an invalidated length not to be served")
+	}
+	if _, ok := cache.cached("queue:b", now); !ok {
+		t.Error("Expected other lengths to stay cached")
+	}
+
+	// A flush invalidates every key
+	cache.invalidate(&redis.Message{Channel: invalidationChannel})
+	if len(cache.lengths) != 0 {
+		t.Errorf("Expected a flush to clear the cache, %d lengths left", len(cache.lengths))
+	}
+}
+
+func TestClientCachingUnsupported(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.ClientCaching = true
+	sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+	s := sender.(*valkeySender)
+
+	// miniredis has no CLIENT TRACKING, so lengths are read directly
+	if s.lengthCache != nil {
+		t.Fatal("Expected client-side caching to be disabled")
+	}
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.GetQueueSize(ctx, "orders"); err != nil || size != 1 {
+		t.Errorf("Expected queue size 1, got %d (%v)", size, err)
+	}
+}
@@ -0,0 +1,246 @@
+package valkeysender
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CloudEventsSpecVersion is the CloudEvents specification version emitted by CloudEventsCodec
+const CloudEventsSpecVersion = "1.0"
+
+// cloudEventsAttributes are the context attributes handled explicitly by the codec
+var cloudEventsAttributes = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"time":            true,
+	"datacontenttype": true,
+	"data":            true,
+	"data_base64":     true,
+	"queue":           true,
+	"ttl":             true,
+	"retries":         true,
+	"checksum":        true,
+}
+
+// CloudEventsCodec implements EnvelopeCodec using the CloudEvents 1.0
+// structured JSON format, so messages can be bridged directly into
+// Knative/EventBridge-style consumers.
+//
+// Envelope headers are written as extension attributes. Extension names are
+// restricted to lowercase letters and digits, so header names are normalized
+// (e.g. "trace-id" becomes "traceid").
+type CloudEventsCodec struct {
+	// Source identifies the producer (CloudEvents "source" attribute)
+	Source string
+
+	// Type is the event type; if empty, "valkeysender.<queue>" is used
+	Type string
+
+	// DataContentType is used when the envelope has no content-type header (default application/json)
+	DataContentType string
+}
+
+// NewCloudEventsCodec creates a CloudEvents codec for the given source and event type
+func NewCloudEventsCodec(source, eventType string) *CloudEventsCodec {
+	return &CloudEventsCodec{
+		Source: source,
+		Type:   eventType,
+	}
+}
+
+// Encode converts an envelope to a structured CloudEvent
+func (c *CloudEventsCodec) Encode(envelope MessageEnvelope) ([]byte, error) {
+	if c.Source == "" {
+		return nil, fmt.Errorf("cloudevents source cannot be empty")
+	}
+
+	event := map[string]interface{}{
+		"specversion": CloudEventsSpecVersion,
+		"id":          envelope.ID,
+		"source":      c.Source,
+		"type":        c.eventType(envelope),
+		"time":        envelope.Timestamp.UTC().Format(time.RFC3339Nano),
+		"queue":       envelope.Queue,
+		"ttl":         int64(envelope.TTL / time.Second),
+		"retries":     envelope.Retries,
+	}
+	if envelope.Checksum != "" {
+		event["checksum"] = envelope.Checksum
+	}
+
+	contentType := c.DataContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	for k, v := range envelope.Headers {
+		if strings.EqualFold(k, "content-type") {
+			contentType = v
+			continue
+		}
+		if k == "ce-source" || k == "ce-type" {
+			continue
+		}
+		if name := cloudEventsExtensionName(k); name != "" && !cloudEventsAttributes[name] {
+			event[name] = v
+		}
+	}
+	event["datacontenttype"] = contentType
+
+	// JSON payloads are embedded as-is, everything else is base64 encoded
+	if isJSONContentType(contentType) && json.Valid(envelope.Payload) && embedsVerbatim(envelope) {
+		event["data"] = json.RawMessage(envelope.Payload)
+	} else if len(envelope.Payload) > 0 {
+		event["data_base64"] = base64.StdEncoding.EncodeToString(envelope.Payload)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cloudevent: %w", err)
+	}
+
+	return data, nil
+}
+
+// Decode parses a structured CloudEvent back into an envelope. The event
+// source and type are exposed as the ce-source and ce-type headers.
+func (c *CloudEventsCodec) Decode(data []byte) (MessageEnvelope, error) {
+	var envelope MessageEnvelope
+
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return envelope, fmt.Errorf("failed to decode cloudevent: %w", err)
+	}
+
+	var specVersion string
+	if err := decodeCloudEventsAttribute(event, "specversion", &specVersion); err != nil {
+		return envelope, err
+	}
+	if specVersion != CloudEventsSpecVersion {
+		return envelope, fmt.Errorf("unsupported cloudevents specversion %q", specVersion)
+	}
+
+	var source, eventType, eventTime, contentType string
+	var ttlSeconds int64
+	for name, target := range map[string]interface{}{
+		"id":              &envelope.ID,
+		"source":          &source,
+		"type":            &eventType,
+		"time":            &eventTime,
+		"datacontenttype": &contentType,
+		"queue":           &envelope.Queue,
+		"ttl":             &ttlSeconds,
+		"retries":         &envelope.Retries,
+		"checksum":        &envelope.Checksum,
+	} {
+		if err := decodeCloudEventsAttribute(event, name, target); err != nil {
+			return envelope, err
+		}
+	}
+
+	envelope.Version = EnvelopeVersion
+	envelope.TTL = time.Duration(ttlSeconds) * time.Second
+	if eventTime != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, eventTime)
+		if err != nil {
+			return envelope, fmt.Errorf("invalid cloudevent time: %w", err)
+		}
+		envelope.Timestamp = timestamp
+	}
+
+	envelope.Headers = map[string]string{
+		"ce-source": source,
+		"ce-type":   eventType,
+	}
+	if contentType != "" {
+		envelope.Headers["content-type"] = contentType
+	}
+	for name, raw := range event {
+		if cloudEventsAttributes[name] {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		envelope.Headers[name] = value
+	}
+
+	if raw, ok := event["data_base64"]; ok {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return envelope, fmt.Errorf("invalid cloudevent data_base64: %w", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return envelope, fmt.Errorf("invalid cloudevent data_base64: %w", err)
+		}
+		envelope.Payload = payload
+	} else if raw, ok := event["data"]; ok {
+		envelope.Payload = []byte(raw)
+	}
+
+	if err := envelope.VerifyChecksum(); err != nil {
+		return envelope, err
+	}
+
+	return envelope, nil
+}
+
+// ContentType returns the content type for structured CloudEvents
+func (c *CloudEventsCodec) ContentType() string {
+	return "application/cloudevents+json"
+}
+
+// embedsVerbatim reports whether a JSON payload reads back byte for byte
+// when embedded as data. Embedded JSON is compacted, so a payload with a
+// checksum that would change is base64 encoded instead.
+func embedsVerbatim(envelope MessageEnvelope) bool {
+	if envelope.Checksum == "" {
+		return true
+	}
+	embedded, err := json.Marshal(json.RawMessage(envelope.Payload))
+	return err == nil && bytes.Equal(embedded, envelope.Payload)
+}
+
+// eventType returns the CloudEvents type for an envelope
+func (c *CloudEventsCodec) eventType(envelope MessageEnvelope) string {
+	if c.Type != "" {
+		return c.Type
+	}
+	return "valkeysender." + envelope.Queue
+}
+
+// decodeCloudEventsAttribute unmarshals an optional attribute into target
+func decodeCloudEventsAttribute(event map[string]json.RawMessage, name string, target interface{}) error {
+	raw, ok := event[name]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("invalid cloudevent attribute %s: %w", name, err)
+	}
+	return nil
+}
+
+// cloudEventsExtensionName normalizes a header name into a valid extension attribute name
+func cloudEventsExtensionName(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(header) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isJSONContentType reports whether a content type denotes JSON data
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
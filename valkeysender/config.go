@@ -3,19 +3,40 @@ package valkeysender
 import (
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultMaxDatabases is the number of logical databases assumed when
+// Config.MaxDatabases is unset, the Valkey and Redis default
+const DefaultMaxDatabases = 16
+
+// DefaultQueuePrefix is the key prefix used for queue lists when Config.QueuePrefix is empty
+const DefaultQueuePrefix = "queue:"
+
 type Config struct {
 	// Core Valkey/Redis settings
+	URL      string // redis://, rediss://, valkey:// or valkeys:// URL; overrides Address, credentials, Database and TLS
 	Address  string
 	Username string
 	Password string
+	UsernameFile string // file containing the username (e.g. a mounted Kubernetes secret)
+	PasswordFile string // file containing the password (e.g. a mounted Kubernetes secret)
 	Database int
 	
+	// Number of logical databases the server is configured with (default
+	// 16); Database must be below it. -1 disables the upper bound, e.g.
+	// behind proxies that ignore SELECT.
+	MaxDatabases int
+	
+	// Storage backend: "list" (default), "stream", "memory" or the name of
+	// a backend registered with RegisterBackend; see BackendStream and
+	// BackendMemory
+	Backend  string
+	
 	// Connection settings
 	DialTimeout    time.Duration
 	ReadTimeout    time.Duration
@@ -25,20 +46,174 @@ type Config struct {
 	MaxIdleTime    time.Duration
 	ConnMaxLifetime time.Duration
 	
+	// How long a command waits for a free pool connection before failing
+	// with ErrPoolTimeout (0 uses ReadTimeout + 1s)
+	PoolTimeout time.Duration
+	
+	// Pool exhaustion warning: SenderOptions.PoolAlertHandler is called once
+	// the share of pool connections in use stays at or above
+	// PoolAlertThreshold (0 to 1, 0 disables) for PoolAlertDuration, and
+	// again when it drops back below
+	PoolAlertThreshold float64
+	PoolAlertDuration  time.Duration
+	
+	// Proxy to connect through, for servers only reachable via a bastion or
+	// egress proxy: socks5://, socks5h:// or http:// (HTTP CONNECT), with
+	// optional user:password. TLS runs end to end through the tunnel.
+	ProxyURL string
+	
+	// SSH jump host to connect through, using the system ssh client
+	SSHTunnel SSHTunnel
+	
+	// Re-resolve the host of Address at this interval and reconnect when its
+	// addresses change (0 disables)
+	DNSRefreshInterval time.Duration
+	
+	// Standby clusters: while the circuit breaker is open, sends to
+	// FailoverQueues (all queues if empty) go to the first reachable
+	// address instead, marked with HeaderFailover; see FailoverReport
+	FallbackAddresses []string
+	FailoverQueues    []string
+	
+	// Mirroring for migrations: every push is also written to the cluster
+	// at MirrorURL (settings missing from the URL are taken from the
+	// primary). MirrorBestEffort names the target whose failures don't
+	// fail sends: "primary", "mirror" or empty for neither.
+	MirrorURL        string
+	MirrorBestEffort string
+	
+	// Client-level retries of commands that failed on a network error, with
+	// a backoff growing from MinRetryBackoff to MaxRetryBackoff (0 retries
+	// at once). MaxRetries 0 disables them, so such errors reach the
+	// circuit breaker straight away.
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	
+	// Upper bound on a whole send, including the rate limiter wait, circuit
+	// breaker and round trip, even when the caller's context has no deadline
+	// (0 disables)
+	SendTimeout time.Duration
+	
+	// Background PING interval keeping connection state and latency fresh (0 disables)
+	HealthCheckInterval time.Duration
+	
+	// Reconnect manager: once a send or health check finds the connection
+	// lost, Valkey is pinged after ReconnectBackoffMin, doubling the delay up
+	// to ReconnectBackoffMax (0 disables) and varying it randomly by up to
+	// ReconnectJitter of itself. With ReconnectFailFast, sends fail with
+	// ErrNotConnected meanwhile instead of reaching Valkey and the breaker.
+	ReconnectBackoffMin time.Duration
+	ReconnectBackoffMax time.Duration
+	ReconnectJitter     float64
+	ReconnectFailFast   bool
+	
+	// Client-side caching: GetQueueSize and the queue watcher serve queue
+	// lengths from a local cache that Valkey invalidates with CLIENT
+	// TRACKING whenever a queue changes. ClientCacheTTL bounds how long a
+	// length is served should invalidations be lost (default 30s).
+	ClientCaching  bool
+	ClientCacheTTL time.Duration
+	
+	// Window over which Health computes the error rate (default 5m)
+	HealthWindow time.Duration
+	
 	// Message settings
 	DefaultQueue   string
+	KeyPrefix      string // prepended to every key, e.g. "myapp:"; not applied when a QueueNamer is set
+	QueuePrefix    string // prepended to queue names to build list keys (default "queue:")
 	MessageTTL     time.Duration
-	MaxRetries     int
+	TTLStrategy    string // "list" (default), "create" or "message"; see TTLStrategyList
+	EnvelopeChecksum string // payload checksum recorded in envelopes: "crc32", "sha256" or empty for none
+	MaxRetries     int // client-level retries of a failed command; see MinRetryBackoff
+	
+	// Deprecated: RetryDelay has no effect, retries wait MinRetryBackoff to
+	// MaxRetryBackoff. NewSender logs a warning if it is set.
 	RetryDelay     time.Duration
+	BatchChunkSize int // SendBatch splits larger batches into non-atomic chunks of this many messages (default 0 sends them whole)
+	MaxMessageBytes int // largest encoded message accepted, rejected before reaching Valkey (0 for no limit)
+	
+	// Linger window: single-message sends are held for up to LingerInterval
+	// (0 disables) and pushed together in one pipeline, at once when
+	// LingerMaxMessages are waiting (0 for no limit)
+	LingerInterval    time.Duration
+	LingerMaxMessages int
+	
+	// Producer identity: unless ProducerMetadata is false, every envelope's
+	// metadata records ProducerName (default the executable name), the
+	// hostname, process ID, library version and envelope schema version
+	ProducerName     string
+	ProducerMetadata bool
+	
+	// Stamp every envelope with the content-type and content-encoding of
+	// its payload, the producer name and the envelope schema version, see
+	// HeaderContentType
+	StandardHeaders bool
+	
+	// Queue length cap (0 disables) and what to do when a queue is full:
+	// "reject" fails with ErrQueueFull, "drop-oldest" trims the oldest
+	// messages, "block" waits up to OverflowBlockTimeout for consumers
+	MaxQueueLength       int
+	OverflowPolicy       string
+	OverflowBlockTimeout time.Duration
+	
+	// Frames written by SendBatchFramed: the compression registered with
+	// RegisterFrameCompressor ("gzip" by default, "zstd" or "none"), the
+	// number of envelopes per frame (default 100) and the largest
+	// decompressed frame body consumers accept (default 64 MiB)
+	FrameCompression string
+	FrameSize        int
+	MaxFrameBytes    int
+	
+	// Per-queue overrides of the TTL, rate limit, length cap, serializer and
+	// deduplication, keyed by queue name. Loaded from VALKEY_SENDER_QUEUES
+	// as JSON, e.g. {"payments": {"message_ttl": "720h"}}.
+	Queues    map[string]QueueProfile
+	queuesErr error // parse error of VALKEY_SENDER_QUEUES, reported by validate
+	
+	// Queue name rules checked on every send: names must match
+	// QueueNamePattern (a regular expression, empty allows any characters),
+	// be at most QueueNameMaxLength bytes (0 for no limit) and not start
+	// with one of ReservedQueuePrefixes. If AllowedQueues is set, only the
+	// listed names or glob patterns (e.g. "user-*") can be sent to.
+	QueueNamePattern      string
+	QueueNameMaxLength    int
+	ReservedQueuePrefixes []string
+	AllowedQueues         []string
+	
+	// Background sweeper removing messages whose envelope TTL has elapsed
+	// (0 disables), optionally moving them to ExpiredQueue for auditing
+	SweepInterval  time.Duration
+	SweepChunkSize int
+	ExpiredQueue   string
+	
+	// Queue depth watcher: polls WatchQueues every WatchInterval (0
+	// disables) and calls SenderOptions.QueueAlertHandler when a queue
+	// reaches QueueAlertThreshold messages and again when it recovers
+	WatchQueues         []string
+	WatchInterval       time.Duration
+	QueueAlertThreshold int
 	
 	// Circuit breaker settings
 	BreakerMaxRequests uint32
 	BreakerInterval    time.Duration
 	BreakerTimeout     time.Duration
+	BreakerConsecutiveFailures uint32  // trip when consecutive failures exceed this value (default 3)
+	BreakerFailureRatio        float64 // trip when the failure ratio reaches this value (0 disables)
+	BreakerMinRequests         uint32  // requests in the interval before the failure ratio applies
 	
 	// Rate limiting
 	RateLimitRequests int
 	RateLimitBurst    int
+	RateLimitMode     string // "wait" blocks until a token is available, "reject" fails with ErrRateLimited
+	
+	// Adaptive rate limiting: every RateLimitAdjustInterval the limit is
+	// halved (down to RateLimitMinRequests) when the average push round trip
+	// exceeded RateLimitLatencyTarget or 10% of pushes failed, and otherwise
+	// raised by a twentieth of RateLimitRequests until it is back there
+	RateLimitAdaptive       bool
+	RateLimitMinRequests    int
+	RateLimitLatencyTarget  time.Duration
+	RateLimitAdjustInterval time.Duration
 	
 	// TLS settings
 	TLSEnabled     bool
@@ -46,40 +221,94 @@ type Config struct {
 	TLSCertFile    string
 	TLSKeyFile     string
 	TLSCAFile      string
+	TLSServerName  string // expected server name, if different from the address host
+	TLSMinVersion  string // minimum TLS version: 1.0, 1.1, 1.2 or 1.3 (default 1.2)
+	TLSKeyPassword     string // passphrase for an encrypted TLS key
+	TLSKeyPasswordFile string // file containing the TLS key passphrase
+	
+	// Credential reload: poll secret and certificate files and rebuild the
+	// client when they change (0 disables)
+	ReloadInterval time.Duration
 	
 	// Logging
 	LogLevel string
+	LogFormat string // "json" (default), "text" or "console"; see LogFormatJSON
+	SlowSendThreshold time.Duration // warn about sends taking at least this long (0 disables)
+	LargePayloadBytes int           // warn about messages of at least this many encoded bytes (0 disables)
+	
+	// StatsD / Datadog metrics: send counts, errors and latency timings are
+	// pushed over UDP to StatsdAddress (empty disables), and the depth of
+	// WatchQueues on every watcher check. Tags are "key:value" pairs added
+	// to every metric.
+	StatsdAddress string
+	StatsdPrefix  string // prepended to metric names (default "valkeysender.")
+	StatsdTags    []string
+	
+	// Audit log: a compact record (timestamp, queue, message ID, size,
+	// checksum, result) of every message sent to AuditQueues (all queues
+	// if empty) is added to the Valkey stream AuditStream and appended to
+	// AuditFile. An empty name disables the sink.
+	AuditStream       string
+	AuditStreamMaxLen int // approximate stream length kept (0 keeps every record)
+	AuditFile         string
+	AuditQueues       []string
+	
+	// Message archive: every message pushed to ArchiveQueues (all queues
+	// if empty) is also added to the capped stream "archive:<queue>", for
+	// debugging and replay with ReadArchive
+	ArchiveMaxLen int // approximate entries kept per archive stream (0 disables the archive)
+	ArchiveQueues []string
+	
+	// Shadow traffic: a random ShadowQueueRatio (0 to 1, 0 disables) of the
+	// sent messages is also pushed to the queue key prefixed with
+	// ShadowKeyPrefix (default "shadow:"), on the cluster at ShadowURL if
+	// set, to try new consumer versions on real traffic. Shadow queues are
+	// trimmed to the newest ShadowMaxLen messages (0 for no limit).
+	ShadowQueueRatio float64
+	ShadowKeyPrefix  string
+	ShadowURL        string
+	ShadowMaxLen     int
+	
+	// Usage accounting: count the messages and payload bytes sent per
+	// tenant, named by the UsageHeader envelope header (default "tenant"),
+	// per hour, day and month for GetUsage. Counters are kept for
+	// UsageRetention after their period ends.
+	UsageAccounting bool
+	UsageHeader     string
+	UsageRetention  time.Duration
+	
+	// HTTP ingestion: serve IngestHandler on IngestAddress (empty disables),
+	// e.g. ":8080", for webhooks and producers that can't link the library
+	IngestAddress      string
+	IngestToken        string   // bearer token required from clients
+	IngestTokenFile    string   // file containing the bearer token
+	IngestAllowUnauthenticated bool // serve without a token; otherwise a missing token fails NewSender
+	IngestMaxBodyBytes int64    // largest accepted request body (default 1 MiB)
+	IngestQueues       []string // queues accepting messages over HTTP (empty allows all)
+	
+	// Kafka bridge settings, see BridgeConfig: "topic=queue" routes, the
+	// header holding record keys and how often forwarded records are
+	// committed. The valkeysender-bridge command consumes the routed
+	// topics from BridgeBrokers in the consumer group BridgeGroup.
+	BridgeRoutes         []string
+	BridgeKeyHeader      string
+	BridgeCommitBatch    int
+	BridgeCommitInterval time.Duration
+	BridgeBrokers        []string
+	BridgeGroup          string
 }
 
 func LoadConfig() (*Config, error) {
-	config := &Config{
-		// Default values
-		Address:         getEnvOrDefault("VALKEY_SENDER_ADDRESS", "localhost:6379"),
-		Username:        os.Getenv("VALKEY_SENDER_USERNAME"),
-		Password:        os.Getenv("VALKEY_SENDER_PASSWORD"),
-		Database:        parseIntOrDefault("VALKEY_SENDER_DATABASE", "0"),
-		DialTimeout:     parseDurationOrDefault("VALKEY_SENDER_DIAL_TIMEOUT", "5s"),
-		ReadTimeout:     parseDurationOrDefault("VALKEY_SENDER_READ_TIMEOUT", "3s"),
-		WriteTimeout:    parseDurationOrDefault("VALKEY_SENDER_WRITE_TIMEOUT", "3s"),
-		PoolSize:        parseIntOrDefault("VALKEY_SENDER_POOL_SIZE", "10"),
-		MinIdleConns:    parseIntOrDefault("VALKEY_SENDER_MIN_IDLE_CONNS", "2"),
-		MaxIdleTime:     parseDurationOrDefault("VALKEY_SENDER_MAX_IDLE_TIME", "5m"),
-		ConnMaxLifetime: parseDurationOrDefault("VALKEY_SENDER_CONN_MAX_LIFETIME", "1h"),
-		DefaultQueue:    getEnvOrDefault("VALKEY_SENDER_DEFAULT_QUEUE", "user-registrations"),
-		MessageTTL:      parseDurationOrDefault("VALKEY_SENDER_MESSAGE_TTL", "24h"),
-		MaxRetries:      parseIntOrDefault("VALKEY_SENDER_MAX_RETRIES", "3"),
-		RetryDelay:      parseDurationOrDefault("VALKEY_SENDER_RETRY_DELAY", "1s"),
-		BreakerMaxRequests: parseUint32OrDefault("VALKEY_SENDER_BREAKER_MAX_REQUESTS", "5"),
-		BreakerInterval:    parseDurationOrDefault("VALKEY_SENDER_BREAKER_INTERVAL", "2m"),
-		BreakerTimeout:     parseDurationOrDefault("VALKEY_SENDER_BREAKER_TIMEOUT", "60s"),
-		RateLimitRequests:  parseIntOrDefault("VALKEY_SENDER_RATE_LIMIT_REQUESTS", "1000"),
-		RateLimitBurst:     parseIntOrDefault("VALKEY_SENDER_RATE_LIMIT_BURST", "2000"),
-		TLSEnabled:         parseBoolOrDefault("VALKEY_SENDER_TLS_ENABLED", "false"),
-		TLSSkipVerify:      parseBoolOrDefault("VALKEY_SENDER_TLS_SKIP_VERIFY", "false"),
-		TLSCertFile:        os.Getenv("VALKEY_SENDER_TLS_CERT_FILE"),
-		TLSKeyFile:         os.Getenv("VALKEY_SENDER_TLS_KEY_FILE"),
-		TLSCAFile:          os.Getenv("VALKEY_SENDER_TLS_CA_FILE"),
-		LogLevel:           getEnvOrDefault("VALKEY_SENDER_LOG_LEVEL", "INFO"),
+	return loadValidatedConfig(os.Getenv)
+}
+
+// loadValidatedConfig loads configuration from the lookup, resolves the URL and validates it
+func loadValidatedConfig(lookup configSource) (*Config, error) {
+	config := loadConfig(lookup)
+	
+	// Apply the connection URL and read secret files
+	if err := config.resolve(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 	
 	// Validate configuration
@@ -90,13 +319,168 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// DefaultConfig returns a configuration populated with the default values
+// used by LoadConfig when no environment variables are set
+func DefaultConfig() *Config {
+	return loadConfig(func(string) string { return "" })
+}
+
+// loadConfig builds a configuration from the given key lookup, falling back to defaults
+func loadConfig(lookup configSource) *Config {
+	queues, queuesErr := lookup.profiles(queuesEnv)
+	return &Config{
+		// Default values
+		URL:             lookup("VALKEY_SENDER_URL"),
+		Address:         lookup.get("VALKEY_SENDER_ADDRESS", "localhost:6379"),
+		Username:        lookup("VALKEY_SENDER_USERNAME"),
+		Password:        lookup("VALKEY_SENDER_PASSWORD"),
+		UsernameFile:    lookup("VALKEY_SENDER_USERNAME_FILE"),
+		PasswordFile:    lookup("VALKEY_SENDER_PASSWORD_FILE"),
+		Database:        lookup.int("VALKEY_SENDER_DATABASE", "0"),
+		MaxDatabases:    lookup.int("VALKEY_SENDER_MAX_DATABASES", "16"),
+		Backend:         lookup.get("VALKEY_SENDER_BACKEND", BackendList),
+		DialTimeout:     lookup.duration("VALKEY_SENDER_DIAL_TIMEOUT", "5s"),
+		ReadTimeout:     lookup.duration("VALKEY_SENDER_READ_TIMEOUT", "3s"),
+		WriteTimeout:    lookup.duration("VALKEY_SENDER_WRITE_TIMEOUT", "3s"),
+		SendTimeout:     lookup.duration("VALKEY_SENDER_SEND_TIMEOUT", "10s"),
+		PoolSize:        lookup.int("VALKEY_SENDER_POOL_SIZE", "10"),
+		MinIdleConns:    lookup.int("VALKEY_SENDER_MIN_IDLE_CONNS", "2"),
+		MaxIdleTime:     lookup.duration("VALKEY_SENDER_MAX_IDLE_TIME", "5m"),
+		ConnMaxLifetime: lookup.duration("VALKEY_SENDER_CONN_MAX_LIFETIME", "1h"),
+		PoolTimeout:     lookup.duration("VALKEY_SENDER_POOL_TIMEOUT", "0s"),
+		PoolAlertThreshold: lookup.float64("VALKEY_SENDER_POOL_ALERT_THRESHOLD", "0"),
+		PoolAlertDuration:  lookup.duration("VALKEY_SENDER_POOL_ALERT_DURATION", "30s"),
+		ProxyURL:        lookup("VALKEY_SENDER_PROXY_URL"),
+		DNSRefreshInterval: lookup.duration("VALKEY_SENDER_DNS_REFRESH_INTERVAL", "0s"),
+		FallbackAddresses:  lookup.list("VALKEY_SENDER_FALLBACK_ADDRESSES"),
+		FailoverQueues:     lookup.list("VALKEY_SENDER_FAILOVER_QUEUES"),
+		MirrorURL:          lookup("VALKEY_SENDER_MIRROR_URL"),
+		MirrorBestEffort:   lookup("VALKEY_SENDER_MIRROR_BEST_EFFORT"),
+		SSHTunnel: SSHTunnel{
+			Host:    lookup("VALKEY_SENDER_SSH_HOST"),
+			User:    lookup("VALKEY_SENDER_SSH_USER"),
+			KeyFile: lookup("VALKEY_SENDER_SSH_KEY_FILE"),
+		},
+		MinRetryBackoff: lookup.duration("VALKEY_SENDER_MIN_RETRY_BACKOFF", "8ms"),
+		MaxRetryBackoff: lookup.duration("VALKEY_SENDER_MAX_RETRY_BACKOFF", "512ms"),
+		HealthCheckInterval: lookup.duration("VALKEY_SENDER_HEALTH_CHECK_INTERVAL", "30s"),
+		ReconnectBackoffMin: lookup.duration("VALKEY_SENDER_RECONNECT_BACKOFF_MIN", "100ms"),
+		ReconnectBackoffMax: lookup.duration("VALKEY_SENDER_RECONNECT_BACKOFF_MAX", "30s"),
+		ReconnectJitter:     lookup.float64("VALKEY_SENDER_RECONNECT_JITTER", "0.2"),
+		ReconnectFailFast:   lookup.bool("VALKEY_SENDER_RECONNECT_FAIL_FAST", "false"),
+		ClientCaching:       lookup.bool("VALKEY_SENDER_CLIENT_CACHING", "false"),
+		ClientCacheTTL:      lookup.duration("VALKEY_SENDER_CLIENT_CACHE_TTL", "30s"),
+		HealthWindow:        lookup.duration("VALKEY_SENDER_HEALTH_WINDOW", "5m"),
+		DefaultQueue:    lookup.get("VALKEY_SENDER_DEFAULT_QUEUE", "user-registrations"),
+		KeyPrefix:       lookup("VALKEY_SENDER_KEY_PREFIX"),
+		QueuePrefix:     lookup.get("VALKEY_SENDER_QUEUE_PREFIX", DefaultQueuePrefix),
+		MessageTTL:      lookup.duration("VALKEY_SENDER_MESSAGE_TTL", "24h"),
+		TTLStrategy:     lookup.get("VALKEY_SENDER_TTL_STRATEGY", TTLStrategyList),
+		EnvelopeChecksum: lookup("VALKEY_SENDER_ENVELOPE_CHECKSUM"),
+		MaxRetries:      lookup.int("VALKEY_SENDER_MAX_RETRIES", "3"),
+		RetryDelay:      lookup.duration("VALKEY_SENDER_RETRY_DELAY", "0s"),
+		BatchChunkSize:  lookup.int("VALKEY_SENDER_BATCH_CHUNK_SIZE", "0"),
+		MaxMessageBytes: lookup.int("VALKEY_SENDER_MAX_MESSAGE_BYTES", "0"),
+		LingerInterval:    lookup.duration("VALKEY_SENDER_LINGER_INTERVAL", "0s"),
+		LingerMaxMessages: lookup.int("VALKEY_SENDER_LINGER_MAX_MESSAGES", "100"),
+		ProducerName:     lookup("VALKEY_SENDER_PRODUCER_NAME"),
+		ProducerMetadata: lookup.bool("VALKEY_SENDER_PRODUCER_METADATA", "true"),
+		StandardHeaders:  lookup.bool("VALKEY_SENDER_STANDARD_HEADERS", "true"),
+		MaxQueueLength:       lookup.int("VALKEY_SENDER_MAX_QUEUE_LENGTH", "0"),
+		OverflowPolicy:       lookup.get("VALKEY_SENDER_OVERFLOW_POLICY", OverflowPolicyReject),
+		FrameCompression:     lookup.get("VALKEY_SENDER_FRAME_COMPRESSION", FrameCompressionGzip),
+		FrameSize:            lookup.int("VALKEY_SENDER_FRAME_SIZE", "100"),
+		MaxFrameBytes:        lookup.int("VALKEY_SENDER_MAX_FRAME_BYTES", "67108864"),
+		OverflowBlockTimeout: lookup.duration("VALKEY_SENDER_OVERFLOW_BLOCK_TIMEOUT", "5s"),
+		Queues:               queues,
+		queuesErr:            queuesErr,
+		QueueNamePattern:      lookup("VALKEY_SENDER_QUEUE_NAME_PATTERN"),
+		QueueNameMaxLength:    lookup.int("VALKEY_SENDER_QUEUE_NAME_MAX_LENGTH", "0"),
+		ReservedQueuePrefixes: lookup.list("VALKEY_SENDER_RESERVED_QUEUE_PREFIXES"),
+		AllowedQueues:         lookup.list("VALKEY_SENDER_ALLOWED_QUEUES"),
+		SweepInterval:        lookup.duration("VALKEY_SENDER_SWEEP_INTERVAL", "0s"),
+		SweepChunkSize:       lookup.int("VALKEY_SENDER_SWEEP_CHUNK_SIZE", "100"),
+		ExpiredQueue:         lookup("VALKEY_SENDER_EXPIRED_QUEUE"),
+		WatchQueues:          lookup.list("VALKEY_SENDER_WATCH_QUEUES"),
+		WatchInterval:        lookup.duration("VALKEY_SENDER_WATCH_INTERVAL", "0s"),
+		QueueAlertThreshold:  lookup.int("VALKEY_SENDER_QUEUE_ALERT_THRESHOLD", "1000"),
+		BreakerMaxRequests: lookup.uint32("VALKEY_SENDER_BREAKER_MAX_REQUESTS", "5"),
+		BreakerInterval:    lookup.duration("VALKEY_SENDER_BREAKER_INTERVAL", "2m"),
+		BreakerTimeout:     lookup.duration("VALKEY_SENDER_BREAKER_TIMEOUT", "60s"),
+		BreakerConsecutiveFailures: lookup.uint32("VALKEY_SENDER_BREAKER_CONSECUTIVE_FAILURES", "3"),
+		BreakerFailureRatio:        lookup.float64("VALKEY_SENDER_BREAKER_FAILURE_RATIO", "0"),
+		BreakerMinRequests:         lookup.uint32("VALKEY_SENDER_BREAKER_MIN_REQUESTS", "10"),
+		RateLimitRequests:  lookup.int("VALKEY_SENDER_RATE_LIMIT_REQUESTS", "1000"),
+		RateLimitBurst:     lookup.int("VALKEY_SENDER_RATE_LIMIT_BURST", "2000"),
+		RateLimitMode:      lookup.get("VALKEY_SENDER_RATE_LIMIT_MODE", RateLimitModeWait),
+		RateLimitAdaptive:       lookup.bool("VALKEY_SENDER_RATE_LIMIT_ADAPTIVE", "false"),
+		RateLimitMinRequests:    lookup.int("VALKEY_SENDER_RATE_LIMIT_MIN_REQUESTS", "10"),
+		RateLimitLatencyTarget:  lookup.duration("VALKEY_SENDER_RATE_LIMIT_LATENCY_TARGET", "50ms"),
+		RateLimitAdjustInterval: lookup.duration("VALKEY_SENDER_RATE_LIMIT_ADJUST_INTERVAL", "1s"),
+		TLSEnabled:         lookup.bool("VALKEY_SENDER_TLS_ENABLED", "false"),
+		TLSSkipVerify:      lookup.bool("VALKEY_SENDER_TLS_SKIP_VERIFY", "false"),
+		TLSCertFile:        lookup("VALKEY_SENDER_TLS_CERT_FILE"),
+		TLSKeyFile:         lookup("VALKEY_SENDER_TLS_KEY_FILE"),
+		TLSCAFile:          lookup("VALKEY_SENDER_TLS_CA_FILE"),
+		TLSServerName:      lookup("VALKEY_SENDER_TLS_SERVER_NAME"),
+		TLSMinVersion:      lookup.get("VALKEY_SENDER_TLS_MIN_VERSION", "1.2"),
+		TLSKeyPassword:     lookup("VALKEY_SENDER_TLS_KEY_PASSWORD"),
+		TLSKeyPasswordFile: lookup("VALKEY_SENDER_TLS_KEY_PASSWORD_FILE"),
+		ReloadInterval:     lookup.duration("VALKEY_SENDER_RELOAD_INTERVAL", "0s"),
+		LogLevel:           lookup.get("VALKEY_SENDER_LOG_LEVEL", "INFO"),
+		LogFormat:          lookup.get("VALKEY_SENDER_LOG_FORMAT", LogFormatJSON),
+		SlowSendThreshold:  lookup.duration("VALKEY_SENDER_SLOW_SEND_THRESHOLD", "0s"),
+		LargePayloadBytes:  lookup.int("VALKEY_SENDER_LARGE_PAYLOAD_BYTES", "0"),
+		StatsdAddress:      lookup("VALKEY_SENDER_STATSD_ADDRESS"),
+		StatsdPrefix:       lookup.get("VALKEY_SENDER_STATSD_PREFIX", DefaultStatsdPrefix),
+		StatsdTags:         lookup.list("VALKEY_SENDER_STATSD_TAGS"),
+		AuditStream:        lookup("VALKEY_SENDER_AUDIT_STREAM"),
+		AuditStreamMaxLen:  lookup.int("VALKEY_SENDER_AUDIT_STREAM_MAX_LEN", "0"),
+		AuditFile:          lookup("VALKEY_SENDER_AUDIT_FILE"),
+		AuditQueues:        lookup.list("VALKEY_SENDER_AUDIT_QUEUES"),
+		ArchiveMaxLen:      lookup.int("VALKEY_SENDER_ARCHIVE_MAX_LEN", "0"),
+		ArchiveQueues:      lookup.list("VALKEY_SENDER_ARCHIVE_QUEUES"),
+		ShadowQueueRatio:   lookup.float64("VALKEY_SENDER_SHADOW_QUEUE_RATIO", "0"),
+		ShadowKeyPrefix:    lookup.get("VALKEY_SENDER_SHADOW_KEY_PREFIX", DefaultShadowKeyPrefix),
+		ShadowURL:          lookup("VALKEY_SENDER_SHADOW_URL"),
+		ShadowMaxLen:       lookup.int("VALKEY_SENDER_SHADOW_MAX_LEN", "10000"),
+		UsageAccounting:    lookup.bool("VALKEY_SENDER_USAGE_ACCOUNTING", "false"),
+		UsageHeader:        lookup.get("VALKEY_SENDER_USAGE_HEADER", HeaderTenant),
+		UsageRetention:     lookup.duration("VALKEY_SENDER_USAGE_RETENTION", "2160h"),
+		IngestAddress:      lookup("VALKEY_SENDER_INGEST_ADDRESS"),
+		IngestToken:        lookup("VALKEY_SENDER_INGEST_TOKEN"),
+		IngestTokenFile:    lookup("VALKEY_SENDER_INGEST_TOKEN_FILE"),
+		IngestAllowUnauthenticated: lookup.bool("VALKEY_SENDER_INGEST_ALLOW_UNAUTHENTICATED", "false"),
+		IngestMaxBodyBytes: int64(lookup.int("VALKEY_SENDER_INGEST_MAX_BODY_BYTES", "1048576")),
+		IngestQueues:       lookup.list("VALKEY_SENDER_INGEST_QUEUES"),
+		BridgeRoutes:         lookup.list("VALKEY_SENDER_BRIDGE_ROUTES"),
+		BridgeKeyHeader:      lookup.get("VALKEY_SENDER_BRIDGE_KEY_HEADER", DefaultBridgeKeyHeader),
+		BridgeCommitBatch:    lookup.int("VALKEY_SENDER_BRIDGE_COMMIT_BATCH", "100"),
+		BridgeCommitInterval: lookup.duration("VALKEY_SENDER_BRIDGE_COMMIT_INTERVAL", "1s"),
+		BridgeBrokers:        lookup.list("VALKEY_SENDER_BRIDGE_BROKERS"),
+		BridgeGroup:          lookup.get("VALKEY_SENDER_BRIDGE_GROUP", "valkeysender-bridge"),
+	}
+}
+
 func (c *Config) validate() error {
 	if c.Address == "" {
 		return fmt.Errorf("address cannot be empty")
 	}
 	
-	if c.Database < 0 || c.Database > 15 {
-		return fmt.Errorf("database must be between 0 and 15")
+	if c.Database < 0 {
+		return fmt.Errorf("database cannot be negative")
+	}
+	
+	if c.MaxDatabases < -1 {
+		return fmt.Errorf("max databases must be positive, or -1 to disable the check")
+	}
+	
+	maxDatabases := c.MaxDatabases
+	if maxDatabases == 0 {
+		maxDatabases = DefaultMaxDatabases
+	}
+	if maxDatabases > 0 && c.Database >= maxDatabases {
+		return fmt.Errorf("database must be between 0 and %d", maxDatabases-1)
 	}
 	
 	if c.DialTimeout < time.Millisecond {
@@ -111,6 +495,52 @@ func (c *Config) validate() error {
 		return fmt.Errorf("write timeout must be at least 1ms")
 	}
 	
+	if c.SendTimeout < 0 {
+		return fmt.Errorf("send timeout cannot be negative")
+	}
+	
+	if c.ProxyURL != "" {
+		if _, err := parseProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+	
+	if c.DNSRefreshInterval < 0 {
+		return fmt.Errorf("DNS refresh interval cannot be negative")
+	}
+	
+	for _, address := range c.FallbackAddresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("invalid fallback address: %w", err)
+		}
+	}
+	if len(c.FallbackAddresses) > 0 && !c.listBackend() {
+		return fmt.Errorf("fallback addresses require the %q backend", BackendList)
+	}
+	
+	if c.MirrorURL != "" {
+		mirror := *c
+		if err := mirror.ApplyURL(c.MirrorURL); err != nil {
+			return fmt.Errorf("invalid mirror URL: %w", err)
+		}
+		if !c.listBackend() {
+			return fmt.Errorf("mirroring requires the %q backend", BackendList)
+		}
+	}
+	switch c.MirrorBestEffort {
+	case "", MirrorTargetPrimary, MirrorTargetMirror:
+	default:
+		return fmt.Errorf("mirror best effort must be %q, %q or empty", MirrorTargetPrimary, MirrorTargetMirror)
+	}
+	
+	if c.SSHTunnel.Host == "" && (c.SSHTunnel.User != "" || c.SSHTunnel.KeyFile != "") {
+		return fmt.Errorf("SSH tunnel user and key file require an SSH host")
+	}
+	
+	if c.SSHTunnel.Host != "" && c.ProxyURL != "" {
+		return fmt.Errorf("SSH tunnel and proxy URL cannot both be set")
+	}
+	
 	if c.PoolSize < 1 {
 		return fmt.Errorf("pool size must be at least 1")
 	}
@@ -123,6 +553,18 @@ func (c *Config) validate() error {
 		return fmt.Errorf("min idle connections cannot exceed pool size")
 	}
 	
+	if c.PoolTimeout < 0 {
+		return fmt.Errorf("pool timeout cannot be negative")
+	}
+	
+	if c.PoolAlertThreshold < 0 || c.PoolAlertThreshold > 1 {
+		return fmt.Errorf("pool alert threshold must be between 0 and 1")
+	}
+	
+	if c.PoolAlertThreshold > 0 && c.PoolAlertDuration <= 0 {
+		return fmt.Errorf("pool alert duration must be positive when the pool alert threshold is set")
+	}
+	
 	if c.DefaultQueue == "" {
 		return fmt.Errorf("default queue name cannot be empty")
 	}
@@ -135,20 +577,258 @@ func (c *Config) validate() error {
 		return fmt.Errorf("max retries cannot be negative")
 	}
 	
-	if c.RetryDelay < time.Millisecond {
-		return fmt.Errorf("retry delay must be at least 1ms")
+	if c.MinRetryBackoff < 0 || c.MaxRetryBackoff < c.MinRetryBackoff {
+		return fmt.Errorf("retry backoff cannot be negative and its maximum cannot be below its minimum")
+	}
+	
+	if c.RetryDelay < 0 {
+		return fmt.Errorf("retry delay cannot be negative")
+	}
+	
+	switch strings.ToLower(c.RateLimitMode) {
+	case "", RateLimitModeWait, RateLimitModeReject:
+	default:
+		return fmt.Errorf("rate limit mode must be %q or %q", RateLimitModeWait, RateLimitModeReject)
+	}
+	
+	if c.RateLimitAdaptive {
+		if c.RateLimitMinRequests < 1 || c.RateLimitMinRequests > c.RateLimitRequests {
+			return fmt.Errorf("adaptive rate limit minimum must be between 1 and the rate limit")
+		}
+		if c.RateLimitLatencyTarget <= 0 {
+			return fmt.Errorf("adaptive rate limit latency target must be positive")
+		}
+		if c.RateLimitAdjustInterval <= 0 {
+			return fmt.Errorf("adaptive rate limit adjust interval must be positive")
+		}
+	}
+	
+	switch strings.ToLower(c.Backend) {
+	case "", BackendList, BackendStream, BackendMemory:
+	default:
+		if _, ok := registeredBackend(c.Backend); !ok {
+			return fmt.Errorf("backend must be %q, %q, %q or a registered backend, not %q", BackendList, BackendStream, BackendMemory, c.Backend)
+		}
+	}
+	
+	switch strings.ToLower(c.TTLStrategy) {
+	case "", TTLStrategyList, TTLStrategyCreate, TTLStrategyMessage:
+	default:
+		return fmt.Errorf("TTL strategy must be %q, %q or %q", TTLStrategyList, TTLStrategyCreate, TTLStrategyMessage)
+	}
+	
+	switch strings.ToLower(c.EnvelopeChecksum) {
+	case "", ChecksumCRC32, ChecksumSHA256:
+	default:
+		return fmt.Errorf("envelope checksum must be %q, %q or empty", ChecksumCRC32, ChecksumSHA256)
+	}
+	
+	if c.BatchChunkSize < 0 {
+		return fmt.Errorf("batch chunk size cannot be negative")
 	}
 	
-	// TLS validation
+	if c.MaxMessageBytes < 0 {
+		return fmt.Errorf("max message bytes cannot be negative")
+	}
+	
+	if c.LingerInterval < 0 {
+		return fmt.Errorf("linger interval cannot be negative")
+	}
+	
+	if c.LingerMaxMessages < 0 {
+		return fmt.Errorf("linger max messages cannot be negative")
+	}
+	
+	if c.MaxQueueLength < 0 {
+		return fmt.Errorf("max queue length cannot be negative")
+	}
+	
+	switch strings.ToLower(c.OverflowPolicy) {
+	case "", OverflowPolicyReject, OverflowPolicyDropOldest, OverflowPolicyBlock:
+	default:
+		return fmt.Errorf("overflow policy must be %q, %q or %q", OverflowPolicyReject, OverflowPolicyDropOldest, OverflowPolicyBlock)
+	}
+	
+	if c.OverflowBlockTimeout < 0 {
+		return fmt.Errorf("overflow block timeout cannot be negative")
+	}
+	
+	if c.FrameSize < 0 {
+		return fmt.Errorf("frame size cannot be negative")
+	}
+	
+	if c.MaxFrameBytes < 0 {
+		return fmt.Errorf("max frame bytes cannot be negative")
+	}
+	
+	if c.FrameCompression != "" {
+		if _, err := frameCompressor(c.FrameCompression); err != nil {
+			return err
+		}
+	}
+	
+	if c.queuesErr != nil {
+		return fmt.Errorf("invalid queue profiles: %w", c.queuesErr)
+	}
+	
+	if _, err := newQueueNameRules(c); err != nil {
+		return err
+	}
+	
+	for queue, profile := range c.Queues {
+		if queue == "" {
+			return fmt.Errorf("queue profile name cannot be empty")
+		}
+		if err := profile.validate(); err != nil {
+			return fmt.Errorf("queue profile %s: %w", queue, err)
+		}
+	}
+	
+	if c.SweepInterval < 0 {
+		return fmt.Errorf("sweep interval cannot be negative")
+	}
+	
+	if c.SweepChunkSize < 0 {
+		return fmt.Errorf("sweep chunk size cannot be negative")
+	}
+	
+	if c.WatchInterval < 0 {
+		return fmt.Errorf("watch interval cannot be negative")
+	}
+	
+	if c.WatchInterval > 0 && len(c.WatchQueues) == 0 {
+		return fmt.Errorf("watch queues cannot be empty when the watch interval is set")
+	}
+	
+	if c.WatchInterval > 0 && c.QueueAlertThreshold < 1 {
+		return fmt.Errorf("queue alert threshold must be at least 1")
+	}
+	
+	if c.BreakerFailureRatio < 0 || c.BreakerFailureRatio > 1 {
+		return fmt.Errorf("breaker failure ratio must be between 0 and 1")
+	}
+	
+	if c.HealthCheckInterval < 0 {
+		return fmt.Errorf("health check interval cannot be negative")
+	}
+	
+	if c.ReconnectBackoffMax < 0 {
+		return fmt.Errorf("reconnect backoff maximum cannot be negative")
+	}
+	
+	if c.ReconnectBackoffMax > 0 && (c.ReconnectBackoffMin <= 0 || c.ReconnectBackoffMin > c.ReconnectBackoffMax) {
+		return fmt.Errorf("reconnect backoff minimum must be positive and cannot exceed the maximum")
+	}
+	
+	if c.ReconnectJitter < 0 || c.ReconnectJitter >= 1 {
+		return fmt.Errorf("reconnect jitter must be at least 0 and below 1")
+	}
+	
+	if c.ClientCaching && c.ClientCacheTTL <= 0 {
+		return fmt.Errorf("client cache TTL must be positive when client caching is enabled")
+	}
+	
+	if c.HealthWindow < 0 {
+		return fmt.Errorf("health window cannot be negative")
+	}
+	
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("reload interval cannot be negative")
+	}
+	
+	switch strings.ToLower(c.LogFormat) {
+	case "", LogFormatJSON, LogFormatText, LogFormatConsole:
+	default:
+		return fmt.Errorf("log format must be %q, %q or %q", LogFormatJSON, LogFormatText, LogFormatConsole)
+	}
+	
+	if c.SlowSendThreshold < 0 {
+		return fmt.Errorf("slow send threshold cannot be negative")
+	}
+	
+	if c.LargePayloadBytes < 0 {
+		return fmt.Errorf("large payload bytes cannot be negative")
+	}
+	
+	if c.StatsdAddress != "" {
+		if _, _, err := net.SplitHostPort(c.StatsdAddress); err != nil {
+			return fmt.Errorf("invalid statsd address: %w", err)
+		}
+	}
+	
+	if c.AuditStreamMaxLen < 0 {
+		return fmt.Errorf("audit stream max length cannot be negative")
+	}
+	if c.AuditStream != "" && !c.listBackend() {
+		return fmt.Errorf("audit stream requires the %q backend", BackendList)
+	}
+	
+	if c.ArchiveMaxLen < 0 {
+		return fmt.Errorf("archive max length cannot be negative")
+	}
+	if c.ArchiveMaxLen > 0 && !c.listBackend() {
+		return fmt.Errorf("message archive requires the %q backend", BackendList)
+	}
+	
+	if c.ShadowQueueRatio < 0 || c.ShadowQueueRatio > 1 {
+		return fmt.Errorf("shadow queue ratio must be between 0 and 1")
+	}
+	if c.ShadowMaxLen < 0 {
+		return fmt.Errorf("shadow max length cannot be negative")
+	}
+	if c.ShadowURL != "" {
+		shadow := *c
+		if err := shadow.ApplyURL(c.ShadowURL); err != nil {
+			return fmt.Errorf("invalid shadow URL: %w", err)
+		}
+	} else if c.ShadowQueueRatio > 0 && !c.listBackend() {
+		return fmt.Errorf("shadow traffic requires the %q backend or a shadow URL", BackendList)
+	}
+	
+	if c.UsageRetention < 0 {
+		return fmt.Errorf("usage retention cannot be negative")
+	}
+	if c.UsageAccounting && !c.listBackend() {
+		return fmt.Errorf("usage accounting requires the %q backend", BackendList)
+	}
+	
+	if c.IngestMaxBodyBytes < 0 {
+		return fmt.Errorf("ingest max body bytes cannot be negative")
+	}
+	if c.IngestAddress != "" {
+		if _, _, err := net.SplitHostPort(c.IngestAddress); err != nil {
+			return fmt.Errorf("invalid ingest address: %w", err)
+		}
+	}
+	
+	if _, err := parseBridgeRoutes(c.BridgeRoutes); err != nil {
+		return err
+	}
+	if c.BridgeCommitBatch < 0 || c.BridgeCommitInterval < 0 {
+		return fmt.Errorf("bridge commit batch and interval cannot be negative")
+	}
+	
+	// TLS validation (client certificates are optional: managed services
+	// usually only need server-side TLS)
 	if c.TLSEnabled {
-		if c.TLSCertFile == "" || c.TLSKeyFile == "" {
-			return fmt.Errorf("TLS cert file and key file are required when TLS is enabled")
+		if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+			return fmt.Errorf("TLS cert file and key file must be provided together")
+		}
+		
+		if _, err := parseTLSVersion(c.TLSMinVersion); err != nil {
+			return err
 		}
 	}
 	
 	return nil
 }
 
+// listBackend reports whether queues are stored in Valkey lists, which
+// features writing other Valkey keys need
+func (c *Config) listBackend() bool {
+	return c.Backend == "" || strings.EqualFold(c.Backend, BackendList)
+}
+
 func (c *Config) LogSlogLevel() slog.Level {
 	switch strings.ToUpper(c.LogLevel) {
 	case "DEBUG":
@@ -166,15 +846,18 @@ func (c *Config) LogSlogLevel() slog.Level {
 
 // Helper functions
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+// configSource looks up a raw configuration value by its environment variable name
+type configSource func(key string) string
+
+func (lookup configSource) get(key, defaultValue string) string {
+	if value := lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func parseDurationOrDefault(key, defaultValue string) time.Duration {
-	if value := os.Getenv(key); value != "" {
+func (lookup configSource) duration(key, defaultValue string) time.Duration {
+	if value := lookup(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
 			return duration
 		}
@@ -183,8 +866,8 @@ func parseDurationOrDefault(key, defaultValue string) time.Duration {
 	return duration
 }
 
-func parseIntOrDefault(key, defaultValue string) int {
-	if value := os.Getenv(key); value != "" {
+func (lookup configSource) int(key, defaultValue string) int {
+	if value := lookup(key); value != "" {
 		if intVal, err := strconv.Atoi(value); err == nil {
 			return intVal
 		}
@@ -193,8 +876,8 @@ func parseIntOrDefault(key, defaultValue string) int {
 	return intVal
 }
 
-func parseUint32OrDefault(key, defaultValue string) uint32 {
-	if value := os.Getenv(key); value != "" {
+func (lookup configSource) uint32(key, defaultValue string) uint32 {
+	if value := lookup(key); value != "" {
 		if intVal, err := strconv.ParseUint(value, 10, 32); err == nil {
 			return uint32(intVal)
 		}
@@ -203,12 +886,42 @@ func parseUint32OrDefault(key, defaultValue string) uint32 {
 	return uint32(intVal)
 }
 
-func parseBoolOrDefault(key, defaultValue string) bool {
-	if value := os.Getenv(key); value != "" {
+func (lookup configSource) float64(key, defaultValue string) float64 {
+	if value := lookup(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	floatVal, _ := strconv.ParseFloat(defaultValue, 64)
+	return floatVal
+}
+
+// list splits a comma-separated value, dropping empty items
+func (lookup configSource) list(key string) []string {
+	var items []string
+	for _, item := range strings.Split(lookup(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// profiles parses queue profiles from a JSON value, see parseQueueProfiles
+func (lookup configSource) profiles(key string) (map[string]QueueProfile, error) {
+	value := lookup(key)
+	if value == "" {
+		return nil, nil
+	}
+	return parseQueueProfiles(value)
+}
+
+func (lookup configSource) bool(key, defaultValue string) bool {
+	if value := lookup(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
 			return boolVal
 		}
 	}
 	boolVal, _ := strconv.ParseBool(defaultValue)
 	return boolVal
-}
\ No newline at end of file
+}
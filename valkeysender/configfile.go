@@ -0,0 +1,132 @@
+package valkeysender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix shared by all environment variables
+const envPrefix = "VALKEY_SENDER_"
+
+// queuesEnv holds the queue profiles, see Config.Queues
+const queuesEnv = envPrefix + "QUEUES"
+
+// LoadConfigFromFile loads configuration from a YAML, TOML or JSON file,
+// selected by extension (.yaml/.yml, .toml, .json). Keys are the
+// environment variable names without the VALKEY_SENDER_ prefix, in any case;
+// nested sections are joined with underscores, so
+//
+//	tls:
+//	  enabled: true
+//	  ca_file: /etc/valkey/ca.pem
+//
+// is equivalent to VALKEY_SENDER_TLS_ENABLED and VALKEY_SENDER_TLS_CA_FILE.
+// The queues section holds per-queue profiles keyed by queue name, see
+// Config.Queues. Settings missing from the file keep their defaults.
+func LoadConfigFromFile(path string) (*Config, error) {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadValidatedConfig(values.lookup)
+}
+
+// LoadConfigWithOverrides loads configuration from a file and lets
+// environment variables override individual values, so a shared checked-in
+// file can be adjusted per environment.
+func LoadConfigWithOverrides(path string) (*Config, error) {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadValidatedConfig(func(key string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return values.lookup(key)
+	})
+}
+
+// fileValues holds flattened file settings keyed by environment variable name
+type fileValues map[string]string
+
+func (v fileValues) lookup(key string) string {
+	return v[key]
+}
+
+// readConfigFile parses a config file into flattened values
+func readConfigFile(path string) (fileValues, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".json":
+		// Keep numbers as literals so large integers aren't rendered in exponent form
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (expected .yaml, .yml, .toml or .json)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(fileValues)
+	flattenConfigValues(values, "", raw)
+	return values, nil
+}
+
+// flattenConfigValues converts nested file sections into environment variable names
+func flattenConfigValues(values fileValues, prefix string, raw map[string]interface{}) {
+	for key, value := range raw {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			// Queue profiles are keyed by queue names, which may contain
+			// underscores, so they are kept whole in their JSON form
+			if envName(name) == queuesEnv {
+				if data, err := json.Marshal(v); err == nil {
+					values[queuesEnv] = string(data)
+				}
+				continue
+			}
+			flattenConfigValues(values, name, v)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[envName(name)] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[envName(name)] = fmt.Sprint(v)
+		}
+	}
+}
+
+// envName adds the environment variable prefix to a file key if missing
+func envName(name string) string {
+	if strings.HasPrefix(name, envPrefix) {
+		return name
+	}
+	return envPrefix + name
+}
@@ -0,0 +1,31 @@
+package valkeysender
+
+import (
+	"context"
+)
+
+// SendAndConfirm sends a message like SendMessage and then reads it back
+// from the queue with LPOS, returning its current position (1 = next to be
+// consumed). It fails with ErrNotConfirmed if the push stored nothing,
+// because the message was skipped as a duplicate or trimmed right away by
+// the drop-oldest policy. A position of 0 means the message was stored but
+// a consumer took it before it could be read back.
+//
+// If reading back fails the message may still have been stored; the error
+// is returned for the confirm operation, not the send.
+func (s *valkeySender) SendAndConfirm(ctx context.Context, queue string, message interface{}) (int64, error) {
+	batch, err := s.sendOne(ctx, opSendMessage, "", queue, message, SendOptions{TTL: s.messageTTL(queue)})
+	if err != nil {
+		return 0, err
+	}
+	
+	if len(batch.positions) == 0 || batch.positions[0] == 0 {
+		return 0, &Error{Op: opConfirm, Queue: queue, Kind: ErrNotConfirmed}
+	}
+	
+	position, err := s.backend.position(ctx, batch.key, memoryValue(batch.data[0]))
+	if err != nil {
+		return 0, classifyError(opConfirm, queue, err)
+	}
+	return position, nil
+}
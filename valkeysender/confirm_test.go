@@ -0,0 +1,43 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSendAndConfirm(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			s.options.EnableDeduplication = true
+			
+			for i, message := range []string{"a", "b", "c"} {
+				position, err := s.SendAndConfirm(ctx, "orders", message)
+				if err != nil {
+					t.Fatalf("SendAndConfirm failed: %v", err)
+				}
+				if position != int64(i+1) {
+					t.Errorf("Expected position %d, got %d", i+1, position)
+				}
+			}
+			
+			// A duplicate is not stored, so it cannot be confirmed
+			_, err := s.SendAndConfirm(ctx, "orders", "b")
+			if !errors.Is(err, ErrNotConfirmed) {
+				t.Errorf("Expected ErrNotConfirmed for a duplicate, got %v", err)
+			}
+			if size, _ := s.GetQueueSize(ctx, "orders"); size != 3 {
+				t.Errorf("Expected 3 messages, got %d", size)
+			}
+			
+			// Raw payloads are found by their bytes
+			s.options.EnableDeduplication = false
+			s.options.RawPayload = true
+			if position, err := s.SendAndConfirm(ctx, "orders", "raw"); err != nil || position != 4 {
+				t.Errorf("Expected raw message at position 4, got %d (%v)", position, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Consumer defaults
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultBlockTimeout      = time.Second
+	defaultConsumerRetries   = 3
+)
+
+// Key prefixes used by reliable consumers
+const (
+	processingKeyPrefix = "processing:"
+	consumersKeyPrefix  = "consumers:"
+)
+
+// nackScript re-queues a message only if it is still held in the processing
+// list, so a message reclaimed by the reaper is never pushed twice
+var nackScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call('LPUSH', KEYS[2], ARGV[2])
+return 1
+`)
+
+// ConsumerConfig configures a reliable consumer
+type ConsumerConfig struct {
+	// Queue to consume from
+	Queue string
+
+	// Tenant selects the tenant's copy of Queue, as written by
+	// SendMessageForTenant (empty for the shared queue)
+	Tenant string
+
+	// Name identifies this consumer; it must be unique per queue and stable
+	// across restarts so unacknowledged messages can be recovered
+	Name string
+
+	// VisibilityTimeout is how long a consumer may go without a heartbeat
+	// before its unacknowledged messages are reclaimed (default 30s)
+	VisibilityTimeout time.Duration
+
+	// BlockTimeout bounds each blocking BLMOVE call (default 1s)
+	BlockTimeout time.Duration
+
+	// MaxRetries is the number of Nacks after which a message is moved to
+	// the dead letter queue (default 3)
+	MaxRetries int
+
+	// DeadLetterQueue receives messages that exceeded MaxRetries or could not
+	// be decoded (if empty, messages are re-queued indefinitely)
+	DeadLetterQueue string
+
+	// ReapInterval is how often to look for dead consumers (default VisibilityTimeout)
+	ReapInterval time.Duration
+}
+
+// Delivery is a message received by a Consumer. It must be passed to Ack or
+// Nack once processing has finished.
+type Delivery struct {
+	// Envelope is the decoded message envelope (the first one of a frame)
+	Envelope MessageEnvelope
+
+	// Batch holds every envelope of a frame written by SendBatchFramed, nil
+	// for plain messages. Ack and Nack apply to the whole frame.
+	Batch []MessageEnvelope
+
+	// Queue the message was received from
+	Queue string
+
+	// ReceivedAt is when the message was moved into the processing list
+	ReceivedAt time.Time
+
+	// raw is the exact list element, used to remove it from the processing list
+	raw string
+}
+
+// Consumer provides at-least-once consumption of a queue. Received messages
+// are atomically moved into processing:<queue>:<consumer> with BLMOVE and stay
+// there until acknowledged. Consumers send heartbeats, and messages held by a
+// consumer whose heartbeat is older than the visibility timeout are moved
+// back to the queue by the reaper running in every live consumer.
+type Consumer struct {
+	config   *Config
+	consumer ConsumerConfig
+	client   redis.UniversalClient
+	logger   *slog.Logger
+	options  *SenderOptions
+	codec    EnvelopeCodec
+
+	keyPrefix     string // Config.KeyPrefix plus the tenant segment
+	queueKey      string
+	processingKey string
+	consumersKey  string
+	deadLetterKey string
+
+	corruptMessages int64 // received with a mismatching checksum
+
+	// Context for cancellation
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConsumer creates a reliable consumer. The envelope codec, queue naming
+// and logger are taken from options and must match the producing sender.
+func NewConsumer(config *Config, consumerConfig ConsumerConfig, options *SenderOptions) (*Consumer, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	if options == nil {
+		options = &SenderOptions{}
+	}
+	if strings.EqualFold(config.Backend, BackendStream) {
+		return nil, fmt.Errorf("consumers read list queues, read stream queues with XREADGROUP")
+	}
+
+	if consumerConfig.Queue == "" {
+		return nil, fmt.Errorf("consumer queue cannot be empty")
+	}
+	if consumerConfig.Name == "" {
+		return nil, fmt.Errorf("consumer name cannot be empty")
+	}
+	if consumerConfig.VisibilityTimeout <= 0 {
+		consumerConfig.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if consumerConfig.BlockTimeout <= 0 {
+		consumerConfig.BlockTimeout = defaultBlockTimeout
+	}
+	if consumerConfig.MaxRetries <= 0 {
+		consumerConfig.MaxRetries = defaultConsumerRetries
+	}
+	if consumerConfig.ReapInterval <= 0 {
+		consumerConfig.ReapInterval = consumerConfig.VisibilityTimeout
+	}
+
+	// Resolve the connection URL and secret files on a copy so the caller's config is untouched
+	resolved := *config
+	if err := resolved.resolve(); err != nil {
+		return nil, err
+	}
+	config = &resolved
+
+	logger, _, err := resolveLogger(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := options.EnvelopeCodec
+	if codec == nil {
+		codec = NewJSONEnvelopeCodec()
+	}
+
+	if consumerConfig.Tenant != "" {
+		if err := validateTenantID(consumerConfig.Tenant); err != nil {
+			return nil, err
+		}
+	}
+
+	// Share the application's client if given one
+	if err := checkSharedClient(options.Client); err != nil {
+		return nil, err
+	}
+	client := options.Client
+	if client == nil {
+		client, err = newRedisClient(config, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+	}
+	keyPrefix := config.KeyPrefix
+	if consumerConfig.Tenant != "" {
+		keyPrefix += TenantKeyPrefix(consumerConfig.Tenant)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &Consumer{
+		config:        config,
+		consumer:      consumerConfig,
+		client:        client,
+		logger:        logger,
+		options:       options,
+		codec:         codec,
+		keyPrefix:     keyPrefix,
+		queueKey:      tenantQueueKey(config, options, consumerConfig.Tenant, consumerConfig.Queue),
+		processingKey: keyPrefix + processingKey(consumerConfig.Queue, consumerConfig.Name),
+		consumersKey:  keyPrefix + consumersKeyPrefix + consumerConfig.Queue,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	if consumerConfig.DeadLetterQueue != "" {
+		c.deadLetterKey = tenantQueueKey(config, options, consumerConfig.Tenant, consumerConfig.DeadLetterQueue)
+	}
+
+	// Register and test the connection in one round trip
+	pingCtx, pingCancel := context.WithTimeout(ctx, config.DialTimeout)
+	defer pingCancel()
+	if err := c.heartbeat(pingCtx); err != nil {
+		cancel()
+		c.closeClient()
+		return nil, fmt.Errorf("failed to connect to Valkey: %w", err)
+	}
+
+	// Recover our own messages left over from a previous run before receiving
+	c.reclaim(c.consumer.Name)
+
+	c.startHeartbeat()
+	c.startReaper()
+
+	c.logger.Info("Valkey consumer created",
+		slog.String("queue", consumerConfig.Queue),
+		slog.String("consumer", consumerConfig.Name),
+		slog.Duration("visibility_timeout", consumerConfig.VisibilityTimeout),
+	)
+
+	return c, nil
+}
+
+// processingKey returns the processing list key for a consumer
+func processingKey(queue, consumer string) string {
+	return processingKeyPrefix + queue + ":" + consumer
+}
+
+// Receive blocks until a message is available or ctx is done. If the message
+// cannot be decoded, the delivery is returned together with an ErrSerialization
+// error, or ErrCorrupt if it failed its checksum, so it can still be passed
+// to Nack.
+func (c *Consumer) Receive(ctx context.Context) (*Delivery, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if c.ctx.Err() != nil {
+			return nil, classifyError("receive", c.consumer.Queue, redis.ErrClosed)
+		}
+
+		raw, err := c.client.BLMove(ctx, c.queueKey, c.processingKey, "RIGHT", "LEFT", c.consumer.BlockTimeout).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, classifyError("receive", c.consumer.Queue, err)
+		}
+
+		delivery := &Delivery{
+			Queue:      c.consumer.Queue,
+			ReceivedAt: time.Now(),
+			raw:        raw,
+		}
+
+		if IsFrame([]byte(raw)) {
+			return delivery, c.decodeFrame(delivery)
+		}
+
+		if c.options.RawPayload {
+			delivery.Envelope = MessageEnvelope{Queue: c.consumer.Queue, Payload: []byte(raw)}
+			return delivery, nil
+		}
+
+		envelope, err := c.codec.Decode([]byte(raw))
+		if err != nil {
+			delivery.Envelope = MessageEnvelope{Queue: c.consumer.Queue, Payload: []byte(raw)}
+			return delivery, c.decodeError(err)
+		}
+		delivery.Envelope = envelope
+
+		return delivery, nil
+	}
+}
+
+// decodeFrame fills a delivery with the envelopes of a frame
+func (c *Consumer) decodeFrame(delivery *Delivery) error {
+	limit := c.config.MaxFrameBytes
+	if limit <= 0 {
+		limit = DefaultMaxFrameBytes
+	}
+	elements, err := DecodeFrameLimit([]byte(delivery.raw), limit)
+	if err != nil {
+		delivery.Envelope = MessageEnvelope{Queue: c.consumer.Queue, Payload: []byte(delivery.raw)}
+		return &Error{Op: "receive", Queue: c.consumer.Queue, Kind: ErrSerialization, Err: err}
+	}
+
+	delivery.Batch = make([]MessageEnvelope, len(elements))
+	for i, element := range elements {
+		if c.options.RawPayload {
+			delivery.Batch[i] = MessageEnvelope{Queue: c.consumer.Queue, Payload: element}
+			continue
+		}
+		envelope, err := c.codec.Decode(element)
+		if err != nil {
+			delivery.Batch = nil
+			delivery.Envelope = MessageEnvelope{Queue: c.consumer.Queue, Payload: []byte(delivery.raw)}
+			return c.decodeError(fmt.Errorf("frame element %d: %w", i, err))
+		}
+		delivery.Batch[i] = envelope
+	}
+	if len(delivery.Batch) > 0 {
+		delivery.Envelope = delivery.Batch[0]
+	}
+	return nil
+}
+
+// decodeError reports a message that could not be decoded, counting those
+// that failed their checksum
+func (c *Consumer) decodeError(err error) error {
+	if !errors.Is(err, ErrCorrupt) {
+		return &Error{Op: "receive", Queue: c.consumer.Queue, Kind: ErrSerialization, Err: err}
+	}
+	atomic.AddInt64(&c.corruptMessages, 1)
+	c.logger.Warn("Corrupt message received",
+		slog.String("queue", c.consumer.Queue),
+		slog.Any("error", err),
+	)
+	return &Error{Op: "receive", Queue: c.consumer.Queue, Kind: ErrCorrupt, Err: err}
+}
+
+// CorruptMessages returns how many messages were received with a payload
+// not matching their checksum. Receive returns them with an error wrapping
+// ErrCorrupt, and Nack moves them to the dead letter queue.
+func (c *Consumer) CorruptMessages() int64 {
+	return atomic.LoadInt64(&c.corruptMessages)
+}
+
+// nackFrame re-encodes a frame with the retry counter of every envelope
+// incremented, reporting whether any of them exceeded MaxRetries
+func (c *Consumer) nackFrame(delivery *Delivery) (string, bool, error) {
+	name := c.config.FrameCompression
+	if name == "" {
+		name = FrameCompressionGzip
+	}
+	compressor, err := frameCompressor(name)
+	if err != nil {
+		return "", false, err
+	}
+
+	exceeded := false
+	elements := make([][]byte, len(delivery.Batch))
+	for i, envelope := range delivery.Batch {
+		envelope.Retries++
+		if envelope.Retries > c.consumer.MaxRetries {
+			exceeded = true
+		}
+		encoded, err := c.codec.Encode(envelope)
+		if err != nil {
+			return "", false, err
+		}
+		elements[i] = encoded
+	}
+
+	frame, err := EncodeFrame(compressor, elements)
+	if err != nil {
+		return "", false, err
+	}
+	return string(frame), exceeded, nil
+}
+
+// Ack removes a processed message from the processing list. It returns
+// ErrDeliveryLost if the message was already reclaimed by the reaper.
+func (c *Consumer) Ack(ctx context.Context, delivery *Delivery) error {
+	removed, err := c.client.LRem(ctx, c.processingKey, 1, delivery.raw).Result()
+	if err != nil {
+		return classifyError("ack", c.consumer.Queue, err)
+	}
+	if removed == 0 {
+		return &Error{Op: "ack", Queue: c.consumer.Queue, Kind: ErrDeliveryLost, Err: ErrDeliveryLost}
+	}
+	return nil
+}
+
+// Nack returns a message that could not be processed. The retry counter is
+// incremented and the message is re-queued, or moved to the dead letter
+// queue once MaxRetries is exceeded. Undecodable messages go straight to the
+// dead letter queue.
+func (c *Consumer) Nack(ctx context.Context, delivery *Delivery) error {
+	target := c.queueKey
+	data := delivery.raw
+
+	switch {
+	case delivery.Batch != nil && !c.options.RawPayload:
+		frame, exceeded, err := c.nackFrame(delivery)
+		if err != nil {
+			return &Error{Op: "nack", Queue: c.consumer.Queue, Kind: ErrSerialization, Err: err}
+		}
+		if exceeded && c.deadLetterKey != "" {
+			target = c.deadLetterKey
+		}
+		data = frame
+	case delivery.Envelope.ID == "" && !c.options.RawPayload:
+		// Not a decodable envelope, retrying won't help
+		if c.deadLetterKey != "" {
+			target = c.deadLetterKey
+		}
+	case delivery.Envelope.ID != "":
+		envelope := delivery.Envelope
+		envelope.Retries++
+		if envelope.Retries > c.consumer.MaxRetries && c.deadLetterKey != "" {
+			target = c.deadLetterKey
+		}
+
+		encoded, err := c.codec.Encode(envelope)
+		if err != nil {
+			return &Error{Op: "nack", Queue: c.consumer.Queue, Kind: ErrSerialization, Err: err}
+		}
+		data = string(encoded)
+	}
+
+	moved, err := nackScript.Run(ctx, c.client, []string{c.processingKey, target}, delivery.raw, data).Int()
+	if err != nil {
+		return classifyError("nack", c.consumer.Queue, err)
+	}
+	if moved == 0 {
+		return &Error{Op: "nack", Queue: c.consumer.Queue, Kind: ErrDeliveryLost, Err: ErrDeliveryLost}
+	}
+
+	if target == c.deadLetterKey {
+		c.logger.Warn("Message moved to dead letter queue",
+			slog.String("queue", c.consumer.Queue),
+			slog.String("dead_letter_queue", c.consumer.DeadLetterQueue),
+			slog.String("message_id", delivery.Envelope.ID),
+			slog.Int("retries", delivery.Envelope.Retries),
+		)
+	}
+
+	return nil
+}
+
+// Close stops the heartbeat and reaper and closes the connection, unless
+// it was passed in SenderOptions.Client.
+// Unacknowledged messages stay in the processing list and are reclaimed
+// by another consumer after the visibility timeout, or by this consumer
+// when it is restarted with the same name.
+func (c *Consumer) Close() error {
+	c.logger.Info("Closing Valkey consumer", slog.String("consumer", c.consumer.Name))
+
+	c.cancel()
+	c.wg.Wait()
+
+	return c.closeClient()
+}
+
+// closeClient closes the client unless it belongs to the application
+func (c *Consumer) closeClient() error {
+	if c.options.Client != nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// heartbeat records that this consumer is alive
+func (c *Consumer) heartbeat(ctx context.Context) error {
+	return c.client.HSet(ctx, c.consumersKey, c.consumer.Name, time.Now().UnixMilli()).Err()
+}
+
+// startHeartbeat refreshes the heartbeat several times per visibility timeout
+func (c *Consumer) startHeartbeat() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.consumer.VisibilityTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(c.ctx, c.config.WriteTimeout)
+				if err := c.heartbeat(ctx); err != nil && c.ctx.Err() == nil {
+					c.logger.Warn("Consumer heartbeat failed", slog.Any("error", err))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// startReaper periodically reclaims messages from dead consumers
+func (c *Consumer) startReaper() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.consumer.ReapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.reap(); err != nil && c.ctx.Err() == nil {
+					c.logger.Warn("Consumer reaper failed", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// reap reclaims the processing lists of consumers whose heartbeat expired
+func (c *Consumer) reap() error {
+	ctx, cancel := context.WithTimeout(c.ctx, c.config.ReadTimeout)
+	defer cancel()
+
+	heartbeats, err := c.client.HGetAll(ctx, c.consumersKey).Result()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(-c.consumer.VisibilityTimeout).UnixMilli()
+	for name, value := range heartbeats {
+		if name == c.consumer.Name {
+			continue
+		}
+		lastSeen, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && lastSeen >= deadline {
+			continue
+		}
+
+		c.reclaim(name)
+		c.client.HDel(ctx, c.consumersKey, name)
+	}
+
+	return nil
+}
+
+// reclaim moves every message held by a consumer back to the queue,
+// preserving their order at the consuming end
+func (c *Consumer) reclaim(name string) {
+	source := c.keyPrefix + processingKey(c.consumer.Queue, name)
+	reclaimed := 0
+
+	for {
+		ctx, cancel := context.WithTimeout(c.ctx, c.config.ReadTimeout)
+		err := c.client.LMove(ctx, source, c.queueKey, "LEFT", "RIGHT").Err()
+		cancel()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && c.ctx.Err() == nil {
+				c.logger.Warn("Failed to reclaim message",
+					slog.String("consumer", name),
+					slog.Any("error", err),
+				)
+			}
+			break
+		}
+		reclaimed++
+	}
+
+	if reclaimed > 0 {
+		c.logger.Info("Reclaimed unacknowledged messages",
+			slog.String("queue", c.consumer.Queue),
+			slog.String("consumer", name),
+			slog.Int("count", reclaimed),
+		)
+	}
+}
@@ -0,0 +1,150 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestConsumer(t *testing.T, server *miniredis.Miniredis, consumerConfig ConsumerConfig) *Consumer {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	options := &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	consumer, err := NewConsumer(config, consumerConfig, options)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	t.Cleanup(func() { consumer.Close() })
+	return consumer
+}
+
+func pushEnvelope(t *testing.T, server *miniredis.Miniredis, queue, id string) {
+	t.Helper()
+
+	data, err := SerializeMessageEnvelope(MessageEnvelope{ID: id, Queue: queue, Payload: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("Failed to encode envelope: %v", err)
+	}
+	server.Lpush(DefaultQueuePrefix+queue, string(data))
+}
+
+func TestConsumerAckNack(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	consumer := newTestConsumer(t, server, ConsumerConfig{
+		Queue:           "jobs",
+		Name:            "worker-1",
+		MaxRetries:      1,
+		DeadLetterQueue: "jobs-dead",
+	})
+
+	pushEnvelope(t, server, "jobs", "first")
+	pushEnvelope(t, server, "jobs", "second")
+
+	delivery, err := consumer.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if delivery.Envelope.ID != "first" {
+		t.Fatalf("Expected FIFO order, got %s", delivery.Envelope.ID)
+	}
+	if held, _ := server.List("processing:jobs:worker-1"); len(held) != 1 {
+		t.Fatalf("Expected 1 message in processing, got %d", len(held))
+	}
+
+	if err := consumer.Ack(ctx, delivery); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	if err := consumer.Ack(ctx, delivery); !errors.Is(err, ErrDeliveryLost) {
+		t.Errorf("Expected ErrDeliveryLost on second ack, got %v", err)
+	}
+
+	// First nack re-queues, second exceeds MaxRetries and dead-letters
+	for attempt := 1; attempt <= 2; attempt++ {
+		delivery, err = consumer.Receive(ctx)
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		if delivery.Envelope.ID != "second" || delivery.Envelope.Retries != attempt-1 {
+			t.Fatalf("Unexpected delivery %s with %d retries", delivery.Envelope.ID, delivery.Envelope.Retries)
+		}
+		if err := consumer.Nack(ctx, delivery); err != nil {
+			t.Fatalf("Nack failed: %v", err)
+		}
+	}
+
+	if queued, _ := server.List("queue:jobs"); len(queued) != 0 {
+		t.Errorf("Expected empty queue, got %d messages", len(queued))
+	}
+	dead, _ := server.List("queue:jobs-dead")
+	if len(dead) != 1 {
+		t.Fatalf("Expected 1 dead-lettered message, got %d", len(dead))
+	}
+	envelope, err := DeserializeMessageEnvelope([]byte(dead[0]))
+	if err != nil || envelope.Retries != 2 {
+		t.Errorf("Expected dead-lettered envelope with 2 retries, got %+v (%v)", envelope, err)
+	}
+}
+
+func TestConsumerReceiveInvalidEnvelope(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	consumer := newTestConsumer(t, server, ConsumerConfig{
+		Queue:           "jobs",
+		Name:            "worker-1",
+		DeadLetterQueue: "jobs-dead",
+	})
+	server.Lpush("queue:jobs", "not an envelope")
+
+	delivery, err := consumer.Receive(ctx)
+	if !errors.Is(err, ErrSerialization) || delivery == nil {
+		t.Fatalf("Expected delivery with ErrSerialization, got %v", err)
+	}
+	if err := consumer.Nack(ctx, delivery); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	if dead, _ := server.List("queue:jobs-dead"); len(dead) != 1 || dead[0] != "not an envelope" {
+		t.Errorf("Expected raw message in dead letter queue, got %v", dead)
+	}
+}
+
+func TestConsumerReap(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	// A consumer that died while holding two messages
+	server.Lpush("processing:jobs:dead-worker", "a")
+	server.Lpush("processing:jobs:dead-worker", "b")
+	server.HSet("consumers:jobs", "dead-worker", "0")
+
+	consumer := newTestConsumer(t, server, ConsumerConfig{
+		Queue:             "jobs",
+		Name:              "worker-1",
+		VisibilityTimeout: time.Minute,
+		ReapInterval:      time.Hour,
+	})
+
+	if err := consumer.reap(); err != nil {
+		t.Fatalf("Reap failed: %v", err)
+	}
+
+	queued, _ := server.List("queue:jobs")
+	if len(queued) != 2 || queued[1] != "a" {
+		t.Errorf("Expected reclaimed messages with oldest at the consuming end, got %v", queued)
+	}
+	if server.Exists("processing:jobs:dead-worker") {
+		t.Error("Expected dead consumer processing list to be emptied")
+	}
+	if names, _ := server.HKeys("consumers:jobs"); len(names) != 1 || names[0] != "worker-1" {
+		t.Errorf("Expected only the live consumer to stay registered, got %v", names)
+	}
+}
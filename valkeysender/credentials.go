@@ -0,0 +1,62 @@
+package valkeysender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CredentialsProvider supplies the username and password of every new
+// connection, for credentials that expire, such as Vault dynamic secrets or
+// cloud IAM auth tokens. It is called on every dial: when the pool opens a
+// connection, after a connection failed and when connections are recycled
+// after Config.ConnMaxLifetime. Established connections stay authenticated
+// with the credentials they were opened with, so ConnMaxLifetime should be
+// shorter than the credential lifetime. Wrap slow providers with
+// CachedCredentials, since they run on the dial path.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (username, password string, err error)
+}
+
+// CredentialsProviderFunc adapts a function to a CredentialsProvider
+type CredentialsProviderFunc func(ctx context.Context) (username, password string, err error)
+
+// Credentials calls f
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// cachedCredentials remembers the credentials of a provider for a while
+type cachedCredentials struct {
+	provider CredentialsProvider
+	refresh  time.Duration
+
+	mu        sync.Mutex
+	username  string
+	password  string
+	fetchedAt time.Time
+}
+
+// CachedCredentials returns a provider asking provider at most once per
+// refresh interval; dials in between reuse the last credentials. The
+// interval must be shorter than the credential lifetime. Errors are not
+// cached, so the next dial asks again.
+func CachedCredentials(provider CredentialsProvider, refresh time.Duration) CredentialsProvider {
+	return &cachedCredentials{provider: provider, refresh: refresh}
+}
+
+// Credentials returns the cached credentials, refreshing them when they are due
+func (c *cachedCredentials) Credentials(ctx context.Context) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < c.refresh {
+		return c.username, c.password, nil
+	}
+	username, password, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	c.username, c.password, c.fetchedAt = username, password, time.Now()
+	return username, password, nil
+}
@@ -0,0 +1,90 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCredentialsProvider(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("app", "token-1")
+	ctx := context.Background()
+	
+	var token atomic.Value
+	token.Store("token-1")
+	var calls int32
+	provider := CredentialsProviderFunc(func(context.Context) (string, string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "app", token.Load().(string), nil
+	})
+	
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.ConnMaxLifetime = 20 * time.Millisecond
+	sender, err := NewSender(config, &SenderOptions{
+		CredentialsProvider: provider,
+		Logger:              slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Expected the provider's credentials to be accepted, got %v", err)
+	}
+	defer sender.Close()
+	if err := sender.SendMessage(ctx, "orders", 1); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	
+	// Rotate the token; connections opened from now on must use the new one
+	server.RequireUserAuth("app", "token-2")
+	token.Store("token-2")
+	before := atomic.LoadInt32(&calls)
+	time.Sleep(50 * time.Millisecond)
+	if err := sender.SendMessage(ctx, "orders", 2); err != nil {
+		t.Fatalf("Expected send with rotated credentials to succeed, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) == before {
+		t.Error("Expected the provider to be asked again when reconnecting")
+	}
+	if size, _ := sender.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected 2 messages, got %d", size)
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	fail := false
+	provider := CachedCredentials(CredentialsProviderFunc(func(context.Context) (string, string, error) {
+		atomic.AddInt32(&calls, 1)
+		if fail {
+			return "", "", errors.New("vault unavailable")
+		}
+		return "app", "secret", nil
+	}), 30*time.Millisecond)
+	
+	for i := 0; i < 3; i++ {
+		if username, password, err := provider.Credentials(ctx); err != nil || username != "app" || password != "secret" {
+			t.Fatalf("Unexpected credentials %q %q (%v)", username, password, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected credentials to be fetched once, got %d", calls)
+	}
+	
+	time.Sleep(40 * time.Millisecond)
+	fail = true
+	if _, _, err := provider.Credentials(ctx); err == nil {
+		t.Error("Expected a failed refresh to return the error")
+	}
+	fail = false
+	if _, _, err := provider.Credentials(ctx); err != nil || calls != 3 {
+		t.Errorf("Expected the next call to fetch again, got %d calls (%v)", calls, err)
+	}
+}
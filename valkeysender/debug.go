@@ -0,0 +1,47 @@
+package valkeysender
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+)
+
+// DebugState is the internal state published by PublishExpvar and
+// DebugHandler
+type DebugState struct {
+	Health   HealthStatus  `json:"health"`
+	Metrics  SenderMetrics `json:"metrics"`
+	Pressure float64       `json:"pressure"`
+}
+
+// debugState reads the current state of a sender
+func debugState(sender Sender) DebugState {
+	return DebugState{
+		Health:   sender.Health(),
+		Metrics:  sender.Metrics(),
+		Pressure: sender.Pressure(),
+	}
+}
+
+// PublishExpvar publishes the sender's counters, circuit breaker state, rate
+// limiter tokens and pool statistics as the expvar variable name, so they
+// appear on /debug/vars. Like expvar.Publish, it panics if name is already
+// in use.
+func PublishExpvar(name string, sender Sender) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return debugState(sender)
+	}))
+}
+
+// DebugHandler returns an http.Handler serving the sender's DebugState as
+// JSON, for services that do not expose expvar
+func DebugHandler(sender Sender) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(debugState(sender))
+	})
+}
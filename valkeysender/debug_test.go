@@ -0,0 +1,52 @@
+package valkeysender
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDebugHandler(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	recorder := httptest.NewRecorder()
+	DebugHandler(s).ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/valkeysender", nil))
+	
+	var state DebugState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", recorder.Body.String(), err)
+	}
+	if recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+	if state.Metrics.MessagesSent != 1 || state.Health.CircuitBreaker != "closed" || state.Metrics.RateLimitTokens <= 0 {
+		t.Errorf("Unexpected debug state: %+v", state)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, nil)
+	
+	// Unique per run, expvar names cannot be reused
+	name := fmt.Sprintf("valkeysender_test_%p", s)
+	PublishExpvar(name, s)
+	
+	var state DebugState
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &state); err != nil {
+		t.Fatalf("Expected a JSON variable: %v", err)
+	}
+	if state.Health.ConnectionState != ConnectionStateConnected || state.Metrics.ConnectionPool.TotalConns == 0 {
+		t.Errorf("Unexpected published state: %+v", state)
+	}
+}
@@ -0,0 +1,61 @@
+package valkeysender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// DefaultDeduplicationWindow is used when deduplication is enabled without a window
+const DefaultDeduplicationWindow = 5 * time.Minute
+
+// Companion key suffixes of a queue
+const (
+	countersKeySuffix = ":stats"
+	dedupKeySuffix    = ":dedup:"
+)
+
+// Fields of the per-queue counter hash
+const (
+	counterEnqueued   = "enqueued"
+	counterDuplicates = "duplicates"
+	counterDropped    = "dropped"
+)
+
+// countersKey returns the hash holding the enqueue counters of a queue
+func countersKey(listKey string) string {
+	return listKey + countersKeySuffix
+}
+
+// dedupKey returns the marker key recording that a payload was recently
+// pushed to a queue
+func dedupKey(listKey, hash string) string {
+	return listKey + dedupKeySuffix + hash
+}
+
+// payloadHash identifies a payload for deduplication. Envelope IDs and
+// timestamps differ between sends, so only the serialized payload counts.
+func payloadHash(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyHash identifies a caller-supplied idempotency key. The prefix
+// keeps keys apart from payloads with the same bytes.
+func idempotencyHash(key string) string {
+	return payloadHash([]byte("idempotency-key:" + key))
+}
+
+// deduplicated reports whether the batch carries a deduplication hash for
+// every message
+func (b *queueBatch) deduplicated() bool {
+	return len(b.dedup) > 0 && len(b.dedup) == len(b.data)
+}
+
+// dedupWindow returns how long pushed payloads and idempotency keys are remembered
+func (s *valkeySender) dedupWindow() time.Duration {
+	if s.options.DeduplicationWindow <= 0 {
+		return DefaultDeduplicationWindow
+	}
+	return s.options.DeduplicationWindow
+}
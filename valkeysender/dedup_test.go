@@ -0,0 +1,183 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// enqueueBackends runs a test against the Valkey and the in-memory backend
+var enqueueBackends = map[string]func(t *testing.T) *valkeySender{
+	"list":   func(t *testing.T) *valkeySender { return newTestSender(t, miniredis.RunT(t), nil) },
+	"stream": newStreamSender,
+	"memory": newMemorySender,
+}
+
+func TestDeduplication(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			s.options.EnableDeduplication = true
+	
+			if err := s.SendMessage(ctx, "orders", map[string]string{"id": "o1"}); err != nil {
+				t.Fatalf("SendMessage failed: %v", err)
+			}
+			if err := s.SendMessage(ctx, "orders", map[string]string{"id": "o1"}); err != nil {
+				t.Fatalf("Expected duplicate send to succeed, got %v", err)
+			}
+			if err := s.SendBatch(ctx, "orders", []interface{}{"a", "a", map[string]string{"id": "o1"}}); err != nil {
+				t.Fatalf("SendBatch failed: %v", err)
+			}
+			if err := s.SendMessage(ctx, "invoices", map[string]string{"id": "o1"}); err != nil {
+				t.Fatalf("SendMessage failed: %v", err)
+			}
+	
+			stats, err := s.GetQueueStats(ctx, "orders")
+			if err != nil {
+				t.Fatalf("GetQueueStats failed: %v", err)
+			}
+			if stats.Length != 2 || stats.Enqueued != 2 || stats.Duplicates != 3 {
+				t.Errorf("Expected 2 enqueued messages and 3 duplicates, got %+v", stats)
+			}
+			if size, _ := s.GetQueueSize(ctx, "invoices"); size != 1 {
+				t.Errorf("Expected deduplication to be per queue, got size %d", size)
+			}
+		})
+	}
+}
+
+func TestDeduplicationWindow(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, &SenderOptions{EnableDeduplication: true, DeduplicationWindow: time.Minute})
+	
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	server.FastForward(2 * time.Minute)
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected payload to be accepted again after the window, got size %d", size)
+	}
+}
+
+func TestDeduplicationDisabled(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	for i := 0; i < 2; i++ {
+		if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected duplicates without deduplication, got size %d", size)
+	}
+	for _, key := range server.Keys() {
+		if key != "queue:orders" && key != "queue:orders:stats" {
+			t.Errorf("Unexpected key %q", key)
+		}
+	}
+}
+
+func TestEnqueueCounters(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			s.config.MaxQueueLength = 2
+			s.config.OverflowPolicy = OverflowPolicyDropOldest
+	
+			if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b", "c"}); err != nil {
+				t.Fatalf("SendBatch failed: %v", err)
+			}
+			s.config.OverflowPolicy = OverflowPolicyReject
+			if err := s.SendMessage(ctx, "orders", "d"); !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("Expected ErrQueueFull, got %v", err)
+			}
+	
+			stats, _ := s.GetQueueStats(ctx, "orders")
+			if stats.Enqueued != 3 || stats.Dropped != 1 || stats.Duplicates != 0 {
+				t.Errorf("Unexpected counters %+v", stats)
+			}
+	
+			if err := s.DeleteQueue(ctx, "orders"); err != nil {
+				t.Fatalf("DeleteQueue failed: %v", err)
+			}
+			if stats, _ := s.GetQueueStats(ctx, "orders"); stats.Enqueued != 0 || stats.Dropped != 0 {
+				t.Errorf("Expected DeleteQueue to reset the counters, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestEnqueueScriptReloaded(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	if err := s.getClient().ScriptFlush(ctx).Err(); err != nil {
+		t.Fatalf("SCRIPT FLUSH failed: %v", err)
+	}
+	if err := s.SendToQueues(ctx, []string{"a", "b"}, "1"); err != nil {
+		t.Fatalf("Expected send to reload the script, got %v", err)
+	}
+	
+	if size, _ := s.GetQueueSize(ctx, "b"); size != 1 {
+		t.Errorf("Expected message after reload, got size %d", size)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	opts := SendOptions{MessageID: "order-1", IdempotencyKey: "order-1"}
+	if err := s.SendMessageWithOptions(ctx, "orders", "a", opts); err != nil {
+		t.Fatalf("SendMessageWithOptions failed: %v", err)
+	}
+	if err := s.SendMessageWithOptions(ctx, "orders", "retried", opts); err != nil {
+		t.Fatalf("Expected duplicate send to succeed, got %v", err)
+	}
+	
+	// A restarted producer sees the same keys
+	restarted := newTestSender(t, server, nil)
+	if err := restarted.SendMessageWithOptions(ctx, "orders", "a", opts); err != nil {
+		t.Fatalf("SendMessageWithOptions failed: %v", err)
+	}
+	
+	envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+	if len(envelopes) != 1 || envelopes[0].ID != "order-1" || string(envelopes[0].Payload) != "a" {
+		t.Errorf("Expected one message with the caller's ID, got %+v", envelopes)
+	}
+	
+	// Without deduplication enabled a message ID alone doesn't deduplicate
+	for i := 0; i < 2; i++ {
+		if err := s.SendMessageWithOptions(ctx, "invoices", "a", SendOptions{MessageID: "invoice-1"}); err != nil {
+			t.Fatalf("SendMessageWithOptions failed: %v", err)
+		}
+	}
+	if size, _ := s.GetQueueSize(ctx, "invoices"); size != 2 {
+		t.Errorf("Expected 2 invoices, got %d", size)
+	}
+	
+	s.options.EnableDeduplication = true
+	for _, payload := range []string{"b", "c"} {
+		if err := s.SendMessageWithOptions(ctx, "refunds", payload, SendOptions{MessageID: "refund-1"}); err != nil {
+			t.Fatalf("SendMessageWithOptions failed: %v", err)
+		}
+	}
+	if size, _ := s.GetQueueSize(ctx, "refunds"); size != 1 {
+		t.Errorf("Expected the message ID to deduplicate, got %d refunds", size)
+	}
+}
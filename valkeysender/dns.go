@@ -0,0 +1,112 @@
+package valkeysender
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+)
+
+// lookupHost resolves the address host for DNS re-resolution
+var lookupHost = net.DefaultResolver.LookupHost
+
+// customDialer wraps SenderOptions.Dialer, starting TLS on its connections
+// since go-redis leaves TLS to custom dialers
+func customDialer(dial dialFunc, tlsConfig *tls.Config) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return startTLS(ctx, conn, addr, tlsConfig)
+	}
+}
+
+// resolveAddress returns the sorted addresses of host
+func resolveAddress(ctx context.Context, host string) ([]string, error) {
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(addrs)
+	return addrs, nil
+}
+
+// startDNSWatcher re-resolves the host of Config.Address every
+// DNSRefreshInterval. When its addresses change, e.g. because the pod behind
+// a headless Kubernetes Service was rescheduled, the client is replaced, so
+// the pool dials the new address instead of waiting on connections to the
+// old one. Addresses resolved by a proxy or SSH jump host aren't watched.
+func (s *valkeySender) startDNSWatcher() {
+	if s.config.DNSRefreshInterval <= 0 || s.getClient() == nil || s.options.Client != nil {
+		return
+	}
+	if s.config.ProxyURL != "" || s.config.SSHTunnel.Host != "" {
+		return
+	}
+	host, _, err := net.SplitHostPort(s.config.Address)
+	if err != nil || net.ParseIP(host) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.DialTimeout)
+	current, err := resolveAddress(ctx, host)
+	cancel()
+	if err != nil {
+		s.logger.Warn("Failed to resolve Valkey address", slog.String("host", host), slog.Any("error", err))
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.DNSRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(s.ctx, s.config.DialTimeout)
+			resolved, err := resolveAddress(ctx, host)
+			cancel()
+			if err != nil {
+				// Keep the current connections through DNS hiccups
+				s.logger.Warn("Failed to resolve Valkey address", slog.String("host", host), slog.Any("error", err))
+				continue
+			}
+			if slices.Equal(resolved, current) {
+				continue
+			}
+
+			if err := s.reresolveClient(); err != nil {
+				s.logger.Error("Failed to connect to the new Valkey address", slog.Any("addresses", resolved), slog.Any("error", err))
+				continue
+			}
+			s.logger.Info("Valkey address changed, reconnected",
+				slog.String("host", host),
+				slog.Any("previous", current),
+				slog.Any("addresses", resolved),
+			)
+			current = resolved
+		}
+	}()
+}
+
+// reresolveClient replaces the client, so every connection is dialed afresh
+func (s *valkeySender) reresolveClient() error {
+	config := *s.config
+	if err := config.LoadSecretFiles(); err != nil {
+		return err
+	}
+	if err := s.replaceClient(&config); err != nil {
+		return fmt.Errorf("failed to replace client: %w", err)
+	}
+	return nil
+}
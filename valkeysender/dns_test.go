@@ -0,0 +1,79 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDNSRefresh(t *testing.T) {
+	servers := map[string]*miniredis.Miniredis{
+		"10.0.0.1": miniredis.RunT(t),
+		"10.0.0.2": miniredis.RunT(t),
+	}
+	
+	// valkey.test resolves to the IP in record, which the dialer maps to a server
+	var record atomic.Value
+	record.Store("10.0.0.1")
+	previous := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{record.Load().(string)}, nil
+	}
+	t.Cleanup(func() { lookupHost = previous })
+	var dials int32
+	dialer := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		var d net.Dialer
+		return d.DialContext(ctx, network, servers[record.Load().(string)].Addr())
+	}
+	
+	config := DefaultConfig()
+	config.Address = "valkey.test:6379"
+	config.HealthCheckInterval = 0
+	config.DNSRefreshInterval = 10 * time.Millisecond
+	sender, err := NewSender(config, &SenderOptions{Dialer: dialer, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+	ctx := context.Background()
+	
+	if err := sender.SendMessage(ctx, "orders", 1); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if atomic.LoadInt32(&dials) == 0 {
+		t.Error("Expected the custom dialer to be used")
+	}
+	
+	// The pod moved; sends must follow it instead of using the pooled connections
+	record.Store("10.0.0.2")
+	waitFor(t, func() bool {
+		sender.SendMessage(ctx, "orders", 2)
+		stored, _ := servers["10.0.0.2"].List("queue:orders")
+		return len(stored) > 0
+	})
+	if stored, _ := servers["10.0.0.1"].List("queue:orders"); len(stored) == 0 {
+		t.Error("Expected the first message on the old server")
+	}
+}
+
+func TestCustomDialerConflicts(t *testing.T) {
+	config := DefaultConfig()
+	config.ProxyURL = "socks5://proxy:1080"
+	dialer := func(ctx context.Context, network, addr string) (net.Conn, error) { return nil, nil }
+	if _, err := newRedisOptions(config, &SenderOptions{Dialer: dialer}); err == nil {
+		t.Error("Expected a custom dialer with a proxy to be rejected")
+	}
+	
+	config = DefaultConfig()
+	config.DNSRefreshInterval = -time.Second
+	if err := config.validate(); err == nil {
+		t.Error("Expected a negative DNS refresh interval to be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// beginSend registers an in-flight send of count messages and returns a
+// context that is cancelled when a drain deadline aborts outstanding sends.
+// The returned function must be called with the send result. Sends are
+// refused once the sender is draining or closed.
+func (s *valkeySender) beginSend(ctx context.Context, count int) (context.Context, func(error), error) {
+	s.drainMutex.RLock()
+	defer s.drainMutex.RUnlock()
+
+	if s.draining {
+		return nil, nil, &Error{Kind: ErrClosed}
+	}
+	s.inflight.Add(1)
+	atomic.AddInt64(&s.inflightSends, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.ctx, cancel)
+
+	return ctx, func(err error) {
+		if err != nil && s.ctx.Err() != nil {
+			atomic.AddInt64(&s.messagesDropped, int64(count))
+		}
+		stop()
+		cancel()
+		atomic.AddInt64(&s.inflightSends, -1)
+		s.inflight.Done()
+	}, nil
+}
+
+// stopAccepting makes every later send fail with ErrClosed
+func (s *valkeySender) stopAccepting() {
+	s.drainMutex.Lock()
+	defer s.drainMutex.Unlock()
+	s.draining = true
+}
+
+// Drain stops accepting new sends and waits for in-flight sends to finish.
+// If ctx ends first, the outstanding sends are aborted and the number of
+// messages they carried is returned together with the context error. An
+// aborted drain also stops the background workers, so the sender should be
+// closed afterwards.
+func (s *valkeySender) Drain(ctx context.Context) (int64, error) {
+	s.stopAccepting()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	// Abort what is still in flight and wait for it to unwind
+	s.cancel()
+	<-done
+
+	dropped := atomic.LoadInt64(&s.messagesDropped)
+	s.logger.Warn("Drain deadline exceeded, in-flight messages dropped",
+		slog.Int64("dropped", dropped),
+	)
+
+	return dropped, ctx.Err()
+}
+
+// CloseWithContext drains the sender until ctx ends and then closes it. It
+// returns the number of messages dropped because the drain did not finish.
+func (s *valkeySender) CloseWithContext(ctx context.Context) (int64, error) {
+	dropped, drainErr := s.Drain(ctx)
+	if err := s.Close(); err != nil {
+		return dropped, err
+	}
+	return dropped, drainErr
+}
@@ -0,0 +1,67 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"golang.org/x/time/rate"
+)
+
+func TestDrainWaitsForInFlightSends(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	// The second send waits ~50ms for a rate limit token
+	s.rateLimiter = rate.NewLimiter(20, 1)
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	result := make(chan error, 1)
+	go func() { result <- s.SendMessage(ctx, "orders", "b") }()
+	time.Sleep(10 * time.Millisecond)
+	
+	drainCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if dropped, err := s.Drain(drainCtx); err != nil || dropped != 0 {
+		t.Fatalf("Expected clean drain, got %d dropped (%v)", dropped, err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Expected in-flight send to complete, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected 2 messages after drain, got %d", size)
+	}
+	
+	if err := s.SendMessage(ctx, "orders", "c"); !errors.Is(err, ErrClosed) || IsRetryable(err) {
+		t.Errorf("Expected ErrClosed after drain, got %v", err)
+	}
+}
+
+func TestDrainDeadlineDropsInFlightSends(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	// The batch waits a minute for a rate limit token
+	s.rateLimiter = rate.NewLimiter(rate.Every(time.Minute), 1)
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	result := make(chan error, 1)
+	go func() { result <- s.SendBatch(ctx, "orders", []interface{}{"b", "c"}) }()
+	time.Sleep(10 * time.Millisecond)
+	
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	dropped, err := s.CloseWithContext(drainCtx)
+	if !errors.Is(err, context.DeadlineExceeded) || dropped != 2 {
+		t.Fatalf("Expected 2 dropped messages with a deadline error, got %d (%v)", dropped, err)
+	}
+	if err := <-result; err == nil {
+		t.Error("Expected aborted send to fail")
+	}
+}
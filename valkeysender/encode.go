@@ -0,0 +1,227 @@
+package valkeysender
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// invalidUTF8 is how encoding/json writes a byte of invalid UTF-8, which
+// differs between Go releases: escaped as \ufffd or the replacement
+// character itself
+var invalidUTF8 = func() string {
+	quoted, _ := json.Marshal("\xff")
+	return string(quoted[1 : len(quoted)-1])
+}()
+
+// maxPooledBuffer is the largest encode buffer returned to the pool, so one
+// huge message does not pin its buffer for the life of the process
+const maxPooledBuffer = 64 << 10
+
+// encodeBuffer is a scratch buffer with a JSON encoder bound to it
+type encodeBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+// encodeBuffers holds the scratch buffers envelopes and payloads are encoded
+// into before being copied out at their exact size
+var encodeBuffers = sync.Pool{
+	New: func() any {
+		buf := new(encodeBuffer)
+		buf.enc = json.NewEncoder(&buf.Buffer)
+		return buf
+	},
+}
+
+// getEncodeBuffer returns an empty buffer from the pool
+func getEncodeBuffer() *encodeBuffer {
+	buf := encodeBuffers.Get().(*encodeBuffer)
+	buf.Reset()
+	return buf
+}
+
+// putEncodeBuffer returns a buffer to the pool unless it grew too large
+func putEncodeBuffer(buf *encodeBuffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		encodeBuffers.Put(buf)
+	}
+}
+
+// encodeEnvelope encodes an envelope in the versioned JSON layout, using the
+// reflection-free encoder where it can
+func encodeEnvelope(envelope *MessageEnvelope) ([]byte, error) {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	
+	if b, ok := appendEnvelopeJSON(buf.AvailableBuffer(), envelope); ok {
+		// Write keeps any growth in the pooled buffer for the next envelope
+		buf.Write(b)
+		return bytes.Clone(buf.Bytes()), nil
+	}
+	return encodeJSON(buf, envelope)
+}
+
+// appendEnvelopeJSON appends the JSON encoding of an envelope to b without
+// reflection. The output is byte for byte what json.Marshal produces; ok is
+// false for envelopes it does not handle (metadata values other than
+// strings and ints, out of range timestamps), which the caller encodes with
+// json.Marshal instead.
+func appendEnvelopeJSON(b []byte, envelope *MessageEnvelope) (_ []byte, ok bool) {
+	for _, value := range envelope.Metadata {
+		switch value.(type) {
+		case string, int:
+		default:
+			return b, false
+		}
+	}
+	if year := envelope.Timestamp.Year(); year < 0 || year > 9999 {
+		return b, false
+	}
+	
+	b = append(b, `{"version":`...)
+	b = strconv.AppendInt(b, int64(envelope.Version), 10)
+	b = append(b, `,"id":`...)
+	b = appendJSONString(b, envelope.ID)
+	b = append(b, `,"queue":`...)
+	b = appendJSONString(b, envelope.Queue)
+	
+	b = append(b, `,"payload":`...)
+	if envelope.Payload == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '"')
+		b = base64.StdEncoding.AppendEncode(b, envelope.Payload)
+		b = append(b, '"')
+	}
+	
+	if len(envelope.Headers) > 0 {
+		b = append(b, `,"headers":`...)
+		b = appendJSONObject(b, envelope.Headers, appendJSONString)
+	}
+	
+	b = append(b, `,"timestamp":"`...)
+	b = envelope.Timestamp.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, `","ttl":`...)
+	b = strconv.AppendInt(b, int64(envelope.TTL), 10)
+	b = append(b, `,"retries":`...)
+	b = strconv.AppendInt(b, int64(envelope.Retries), 10)
+	
+	if len(envelope.Metadata) > 0 {
+		b = append(b, `,"metadata":`...)
+		b = appendJSONObject(b, envelope.Metadata, func(b []byte, value interface{}) []byte {
+			if n, ok := value.(int); ok {
+				return strconv.AppendInt(b, int64(n), 10)
+			}
+			return appendJSONString(b, value.(string))
+		})
+	}
+	if envelope.Checksum != "" {
+		b = append(b, `,"checksum":`...)
+		b = appendJSONString(b, envelope.Checksum)
+	}
+	b = append(b, '}')
+	return b, true
+}
+
+// appendJSONObject appends m as a JSON object with sorted keys, encoding
+// the values with appendValue
+func appendJSONObject[V any](b []byte, m map[string]V, appendValue func([]byte, V) []byte) []byte {
+	// Most envelopes have a handful of entries, sorted without allocating
+	var stack [8]string
+	keys := stack[:0]
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	
+	b = append(b, '{')
+	for i, key := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, key)
+		b = append(b, ':')
+		b = appendValue(b, m[key])
+	}
+	return append(b, '}')
+}
+
+// appendJSONString appends s as a JSON string, escaped like encoding/json:
+// HTML characters and the JavaScript line separators are escaped and
+// invalid UTF-8 is replaced with U+FFFD
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, `\b`...)
+			case '\f':
+				b = append(b, `\f`...)
+			case '\n':
+				b = append(b, `\n`...)
+			case '\r':
+				b = append(b, `\r`...)
+			case '\t':
+				b = append(b, `\t`...)
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, invalidUTF8...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// marshalJSON encodes v like json.Marshal, through a pooled buffer and
+// encoder, and returns a copy of exactly the encoded size
+func marshalJSON(v any) ([]byte, error) {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	return encodeJSON(buf, v)
+}
+
+// encodeJSON encodes v into buf and returns a copy of the result
+func encodeJSON(buf *encodeBuffer, v any) ([]byte, error) {
+	if err := buf.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates the value with a newline, Marshal does not
+	return bytes.Clone(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})), nil
+}
@@ -0,0 +1,94 @@
+package valkeysender
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAppendEnvelopeJSONMatchesMarshal(t *testing.T) {
+	base := benchEnvelope()
+	
+	tests := []struct {
+		name   string
+		modify func(*MessageEnvelope)
+	}{
+		{"typical", func(e *MessageEnvelope) {}},
+		{"no headers", func(e *MessageEnvelope) { e.Headers = nil }},
+		{"nil payload", func(e *MessageEnvelope) { e.Payload = nil }},
+		{"empty payload", func(e *MessageEnvelope) { e.Payload = []byte{} }},
+		{"many headers", func(e *MessageEnvelope) {
+			e.Headers = map[string]string{}
+			for _, k := range []string{"j", "i", "h", "g", "f", "e", "d", "c", "b", "a"} {
+				e.Headers[k] = k + k
+			}
+		}},
+		{"escaping", func(e *MessageEnvelope) {
+			e.Queue = "q\"\\\n\r\t\b\f\x01<>&  é\xff"
+			e.Headers = map[string]string{"<k>": "v&\x7f\x00"}
+		}},
+		{"local timestamp", func(e *MessageEnvelope) {
+			e.Timestamp = time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("x", 5*3600+1800))
+		}},
+		{"zero values", func(e *MessageEnvelope) { *e = MessageEnvelope{} }},
+		{"producer metadata", func(e *MessageEnvelope) {
+			e.Metadata = map[string]interface{}{"producer": "svc<1>", "pid": 4242, "schema_version": 1, "host": ""}
+		}},
+		{"checksum", func(e *MessageEnvelope) { e.Checksum = "crc32:cbf43926" }},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := base
+			tt.modify(&envelope)
+			
+			want, err := json.Marshal(envelope)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			got, ok := appendEnvelopeJSON(nil, &envelope)
+			if !ok {
+				t.Fatal("Expected envelope to be handled")
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeEnvelopeFallsBack(t *testing.T) {
+	envelope := benchEnvelope()
+	envelope.Metadata = map[string]interface{}{"trace": "abc", "sampled": true}
+	
+	if _, ok := appendEnvelopeJSON(nil, &envelope); ok {
+		t.Fatal("Expected non-string metadata to need the fallback encoder")
+	}
+	
+	want, _ := json.Marshal(envelope)
+	got, err := encodeEnvelope(&envelope)
+	if err != nil {
+		t.Fatalf("encodeEnvelope failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestMarshalJSONMatchesMarshal(t *testing.T) {
+	value := map[string]interface{}{"html": "<a&b>", "n": 1.5, "list": []int{1, 2}}
+	
+	want, _ := json.Marshal(value)
+	got, err := marshalJSON(value)
+	if err != nil {
+		t.Fatalf("marshalJSON failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Encoding mismatch:\n got %s\nwant %s", got, want)
+	}
+	
+	if _, err := marshalJSON(make(chan int)); err == nil {
+		t.Error("Expected an error for an unsupported type")
+	}
+}
@@ -0,0 +1,78 @@
+package valkeysender
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EnvelopeVersion is the envelope schema version written by JSONEnvelopeCodec
+const EnvelopeVersion = 1
+
+// EnvelopeCodec encodes message envelopes into the bytes stored in the queue.
+// Implementations can provide alternative layouts (e.g. CloudEvents).
+type EnvelopeCodec interface {
+	// Encode converts an envelope to its wire format
+	Encode(envelope MessageEnvelope) ([]byte, error)
+	
+	// Decode parses the wire format back into an envelope
+	Decode(data []byte) (MessageEnvelope, error)
+	
+	// ContentType describes the wire format
+	ContentType() string
+}
+
+// JSONEnvelopeCodec implements EnvelopeCodec using the versioned JSON layout
+type JSONEnvelopeCodec struct{}
+
+// NewJSONEnvelopeCodec creates the default JSON envelope codec
+func NewJSONEnvelopeCodec() *JSONEnvelopeCodec {
+	return &JSONEnvelopeCodec{}
+}
+
+// Encode converts an envelope to JSON, stamping the current version if unset
+func (c *JSONEnvelopeCodec) Encode(envelope MessageEnvelope) ([]byte, error) {
+	if envelope.Version == 0 {
+		envelope.Version = EnvelopeVersion
+	}
+	
+	data, err := encodeEnvelope(&envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	
+	return data, nil
+}
+
+// Decode parses a JSON envelope. Envelopes written before versioning
+// was introduced carry no version and are treated as version 1. Envelopes
+// whose payload doesn't match their checksum fail with ErrCorrupt.
+func (c *JSONEnvelopeCodec) Decode(data []byte) (MessageEnvelope, error) {
+	var envelope MessageEnvelope
+	
+	if len(data) == 0 {
+		return envelope, fmt.Errorf("data cannot be empty")
+	}
+	
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return envelope, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	
+	if envelope.Version == 0 {
+		envelope.Version = 1
+	}
+	
+	if envelope.Version > EnvelopeVersion {
+		return envelope, fmt.Errorf("unsupported envelope version %d", envelope.Version)
+	}
+	
+	if err := envelope.VerifyChecksum(); err != nil {
+		return envelope, err
+	}
+	
+	return envelope, nil
+}
+
+// ContentType returns the content type for JSON envelopes
+func (c *JSONEnvelopeCodec) ContentType() string {
+	return "application/json"
+}
@@ -0,0 +1,246 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
+)
+
+// Error classes. Errors returned by send operations wrap one of these
+// together with the underlying cause, so both can be matched with errors.Is.
+var (
+	// ErrNotConnected indicates the connection to Valkey failed or was lost
+	ErrNotConnected = errors.New("not connected")
+
+	// ErrCircuitOpen indicates the circuit breaker rejected the request
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrRateLimited is returned in reject rate limit mode when no token is available
+	ErrRateLimited = errors.New("rate limit exceeded")
+
+	// ErrSerialization indicates the message or envelope could not be encoded
+	ErrSerialization = errors.New("serialization failed")
+
+	// ErrCorrupt indicates a message read from a queue doesn't match the
+	// checksum recorded when it was sent, e.g. a payload truncated in storage
+	ErrCorrupt = errors.New("message corrupt")
+
+	// ErrValidation indicates a message was rejected by a validation hook
+	ErrValidation = errors.New("validation failed")
+
+	// ErrInvalidQueue indicates a queue name broke the queue name rules of
+	// the configuration or is not one of Config.AllowedQueues
+	ErrInvalidQueue = errors.New("invalid queue")
+
+	// ErrMessageTooLarge indicates an encoded message exceeds Config.MaxMessageBytes
+	ErrMessageTooLarge = errors.New("message too large")
+
+	// ErrQueueFull indicates the queue reached its maximum length
+	ErrQueueFull = errors.New("queue full")
+
+	// ErrTimeout indicates the operation did not complete in time
+	ErrTimeout = errors.New("timeout")
+
+	// ErrPoolTimeout indicates no pool connection became free within
+	// Config.PoolTimeout, because every connection was busy: the pool is
+	// too small for the load, or commands hold connections too long
+	ErrPoolTimeout = errors.New("connection pool timeout")
+
+	// ErrNoRoute indicates no binding matched the routing key passed to Route
+	ErrNoRoute = errors.New("no route")
+
+	// ErrNotConfirmed indicates SendAndConfirm did not find the message in
+	// the queue after pushing it
+	ErrNotConfirmed = errors.New("message not confirmed")
+
+	// ErrClosed indicates the sender is draining or closed and no longer accepts sends
+	ErrClosed = errors.New("sender closed")
+
+	// ErrDeliveryLost indicates a consumer no longer holds the message it
+	// tried to Ack or Nack, usually because it was reclaimed by the reaper
+	ErrDeliveryLost = errors.New("delivery no longer held by consumer")
+)
+
+// Operations reported in Error.Op
+const (
+	opSendMessage  = "send message"
+	opSendBatch    = "send batch"
+	opSendRaw      = "send raw message"
+	opSendMulti    = "send multi-queue batch"
+	opSendToQueues = "fan out message"
+	opRoute        = "route message"
+	opSendTx       = "send transaction"
+	opConfirm      = "confirm message"
+	opSendFramed   = "send framed batch"
+	opPing         = "ping"
+)
+
+// Error describes a failed operation with its class and cause
+type Error struct {
+	// Op is the operation that failed, e.g. "send message"
+	Op string
+
+	// Queue is the target queue, if the operation had a single one
+	Queue string
+
+	// Kind is one of the Err* classes, or nil if the error is unclassified
+	Kind error
+
+	// Err is the underlying cause
+	Err error
+}
+
+// Error formats the operation, class and cause
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Op != "" {
+		b.WriteString("failed to ")
+		b.WriteString(e.Op)
+		if e.Queue != "" {
+			b.WriteString(" to queue ")
+			b.WriteString(e.Queue)
+		}
+		b.WriteString(": ")
+	}
+
+	switch {
+	case e.Kind != nil && e.Err != nil && e.Err != e.Kind:
+		b.WriteString(e.Kind.Error())
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	case e.Kind != nil:
+		b.WriteString(e.Kind.Error())
+	case e.Err != nil:
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap exposes both the class and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// IsRetryable reports whether an operation failed for a transient reason
+// (connection loss, open circuit, rate limiting, full queue, timeout or an
+// exhausted connection pool) and may succeed if retried later
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, kind := range []error{ErrNotConnected, ErrCircuitOpen, ErrRateLimited, ErrQueueFull, ErrTimeout, ErrPoolTimeout} {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError wraps err in an *Error carrying its class
+func classifyError(op, queue string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var typed *Error
+	if errors.As(err, &typed) {
+		if typed.Op == "" {
+			// Fill in the operation for errors classified at their origin
+			if typed.Queue != "" {
+				queue = typed.Queue
+			}
+			return &Error{Op: op, Queue: queue, Kind: typed.Kind, Err: typed.Err}
+		}
+		return err
+	}
+
+	return &Error{Op: op, Queue: queue, Kind: errorKind(err), Err: err}
+}
+
+// errorKind determines the class of an untyped error
+func errorKind(err error) error {
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return ErrRateLimited
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		return ErrCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled):
+		return nil
+	}
+
+	if isPoolTimeout(err) {
+		return ErrPoolTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+
+	if isConnectionError(err) {
+		return ErrNotConnected
+	}
+
+	return nil
+}
+
+// redisPoolTimeout is the message of the pool timeout error of go-redis,
+// which keeps the error itself in an internal package
+const redisPoolTimeout = "redis: connection pool timeout"
+
+// isPoolTimeout reports whether err is go-redis failing to get a connection
+// from its pool in time
+func isPoolTimeout(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == redisPoolTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// isConnectionError reports whether err means the connection is unusable,
+// as opposed to a command error returned by the server
+func isConnectionError(err error) bool {
+	if errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// isBreakerSuccess keeps caller errors (bad payloads, full queues, cancelled
+// contexts) from counting as infrastructure failures in the circuit breaker
+func isBreakerSuccess(err error) bool {
+	return err == nil ||
+		errors.Is(err, ErrSerialization) ||
+		errors.Is(err, ErrValidation) ||
+		errors.Is(err, ErrQueueFull) ||
+		errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,86 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		kind      error
+		retryable bool
+	}{
+		{"circuit open", gobreaker.ErrOpenState, ErrCircuitOpen, true},
+		{"half-open limit", gobreaker.ErrTooManyRequests, ErrCircuitOpen, true},
+		{"rate limited", ErrRateLimited, ErrRateLimited, true},
+		{"deadline exceeded", fmt.Errorf("rate limiter error: %w", context.DeadlineExceeded), ErrTimeout, true},
+		{"client closed", redis.ErrClosed, ErrNotConnected, true},
+		{"pool timeout", errors.New("redis: connection pool timeout"), ErrPoolTimeout, true},
+		{"connection dropped", io.EOF, ErrNotConnected, true},
+		{"dial failure", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ErrNotConnected, true},
+		{"serialization", &Error{Kind: ErrSerialization, Err: errors.New("bad payload")}, ErrSerialization, false},
+		{"cancelled", context.Canceled, nil, false},
+		{"server error", errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(opSendMessage, "orders", tt.err)
+
+			var typed *Error
+			if !errors.As(err, &typed) {
+				t.Fatalf("Expected *Error, got %T", err)
+			}
+			if typed.Op != opSendMessage || typed.Queue != "orders" {
+				t.Errorf("Expected op and queue to be set, got %q/%q", typed.Op, typed.Queue)
+			}
+			if typed.Kind != tt.kind {
+				t.Errorf("Expected kind %v, got %v", tt.kind, typed.Kind)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("Expected errors.Is to match %v", tt.kind)
+			}
+			if !errors.Is(err, tt.err) && !errors.Is(tt.err, typed.Err) {
+				t.Errorf("Expected the cause to be preserved")
+			}
+			if got := IsRetryable(err); got != tt.retryable {
+				t.Errorf("Expected IsRetryable=%t, got %t", tt.retryable, got)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := classifyError(opSendBatch, "orders", gobreaker.ErrOpenState)
+	expected := "failed to send batch to queue orders: circuit breaker open: circuit breaker is open"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+
+	err = classifyError(opSendMulti, "", ErrRateLimited)
+	if !strings.HasSuffix(err.Error(), ": rate limit exceeded") || strings.Count(err.Error(), "rate limit exceeded") != 1 {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
+
+func TestIsBreakerSuccess(t *testing.T) {
+	if !isBreakerSuccess(nil) {
+		t.Error("Expected nil to be a success")
+	}
+	if !isBreakerSuccess(&Error{Kind: ErrQueueFull}) {
+		t.Error("Expected a full queue not to count against the breaker")
+	}
+	if isBreakerSuccess(io.EOF) {
+		t.Error("Expected connection errors to count against the breaker")
+	}
+}
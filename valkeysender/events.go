@@ -0,0 +1,138 @@
+package valkeysender
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// EventType identifies a sender lifecycle event
+type EventType string
+
+// Sender lifecycle events delivered to Subscribe listeners
+const (
+	// EventConnected: the connection to Valkey was established or restored
+	EventConnected EventType = "connected"
+
+	// EventDisconnected: the connection was lost; Err holds the cause
+	EventDisconnected EventType = "disconnected"
+
+	// EventBreakerOpened: the circuit breaker opened and rejects sends
+	EventBreakerOpened EventType = "breaker_opened"
+
+	// EventBreakerHalfOpen: the circuit breaker lets trial sends through
+	EventBreakerHalfOpen EventType = "breaker_half_open"
+
+	// EventBreakerClosed: the circuit breaker closed again
+	EventBreakerClosed EventType = "breaker_closed"
+
+	// EventFailover: sends started going to a standby cluster; Err holds
+	// the cause
+	EventFailover EventType = "failover"
+
+	// EventFailback: sends reach the primary again; see FailoverReport
+	EventFailback EventType = "failback"
+
+	// EventRateLimited: a send had to wait for, or was refused, a rate
+	// limiter token
+	EventRateLimited EventType = "rate_limited"
+
+	// EventMessageSent: a message was stored; Metadata describes it
+	EventMessageSent EventType = "message_sent"
+
+	// EventMessageFailed: an operation failed; Err holds the classified error
+	EventMessageFailed EventType = "message_failed"
+)
+
+// SenderEvent is a lifecycle event of a sender. Fields that do not apply to
+// the event type are left empty.
+type SenderEvent struct {
+	Type     EventType
+	Time     time.Time
+	Queue    string           // message events
+	Metadata *MessageMetadata // EventMessageSent
+	Err      error            // EventDisconnected, EventFailover, EventMessageFailed
+}
+
+// EventListener receives sender events. Listeners are called synchronously
+// on the goroutine that caused the event, so they must return quickly and
+// must not call back into the sender's send methods.
+type EventListener func(SenderEvent)
+
+// eventBus fans events out to the registered listeners
+type eventBus struct {
+	mu        sync.RWMutex
+	listeners map[int]EventListener
+	nextID    int
+}
+
+// subscribe registers a listener and returns the function removing it
+func (b *eventBus) subscribe(listener EventListener) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.listeners == nil {
+		b.listeners = make(map[int]EventListener)
+	}
+	id := b.nextID
+	b.nextID++
+	b.listeners[id] = listener
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.listeners, id)
+		})
+	}
+}
+
+// active reports whether any listener is registered, so callers can skip
+// building events nobody receives
+func (b *eventBus) active() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.listeners) > 0
+}
+
+// publish delivers an event to every listener. Listeners are called
+// outside the lock, so they may unsubscribe themselves.
+func (b *eventBus) publish(event SenderEvent) {
+	b.mu.RLock()
+	if len(b.listeners) == 0 {
+		b.mu.RUnlock()
+		return
+	}
+	listeners := make([]EventListener, 0, len(b.listeners))
+	for _, listener := range b.listeners {
+		listeners = append(listeners, listener)
+	}
+	b.mu.RUnlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// breakerEvent returns the event reporting a transition to the given state
+func breakerEvent(to gobreaker.State) EventType {
+	switch to {
+	case gobreaker.StateOpen:
+		return EventBreakerOpened
+	case gobreaker.StateHalfOpen:
+		return EventBreakerHalfOpen
+	}
+	return EventBreakerClosed
+}
+
+// Subscribe registers a listener for the sender's lifecycle events and
+// returns a function that unsubscribes it. Any number of subsystems can
+// subscribe independently.
+func (s *valkeySender) Subscribe(listener EventListener) func() {
+	return s.events.subscribe(listener)
+}
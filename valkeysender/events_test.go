@@ -0,0 +1,142 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sony/gobreaker"
+	"golang.org/x/time/rate"
+)
+
+// eventRecorder collects events from any goroutine
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []SenderEvent
+}
+
+func (r *eventRecorder) listen(event SenderEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]EventType, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func (r *eventRecorder) of(eventType EventType) []SenderEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []SenderEvent
+	for _, event := range r.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestSubscribeMessageEvents(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	recorder := &eventRecorder{}
+	s.Subscribe(recorder.listen)
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	sent := recorder.of(EventMessageSent)
+	if len(sent) != 2 || sent[0].Queue != "orders" || sent[0].Metadata == nil || sent[0].Metadata.MessageID == "" || sent[0].Time.IsZero() {
+		t.Fatalf("Expected two message sent events with metadata, got %+v", sent)
+	}
+	if sent[0].Metadata.MessageID == sent[1].Metadata.MessageID {
+		t.Error("Expected each event to describe its own message")
+	}
+	
+	s.SendMessage(ctx, "orders", make(chan int))
+	failed := recorder.of(EventMessageFailed)
+	if len(failed) != 1 || failed[0].Queue != "orders" || !errors.Is(failed[0].Err, ErrSerialization) {
+		t.Errorf("Expected a message failed event, got %+v", failed)
+	}
+}
+
+func TestSubscribeRateLimitedEvent(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.RateLimitMode = RateLimitModeReject
+	s.rateLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	
+	recorder := &eventRecorder{}
+	s.Subscribe(recorder.listen)
+	
+	s.SendMessage(ctx, "orders", "a")
+	s.SendMessage(ctx, "orders", "b")
+	if limited := recorder.of(EventRateLimited); len(limited) != 1 {
+		t.Errorf("Expected one rate limited event, got %v", recorder.types())
+	}
+}
+
+func TestSubscribeConnectionAndBreakerEvents(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, &SenderOptions{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	
+	recorder := &eventRecorder{}
+	s.Subscribe(recorder.listen)
+	
+	server.Close()
+	s.SendMessage(ctx, "orders", "a")
+	
+	disconnected := recorder.of(EventDisconnected)
+	if len(disconnected) != 1 || disconnected[0].Err == nil {
+		t.Errorf("Expected a disconnected event with its cause, got %v", recorder.types())
+	}
+	if len(recorder.of(EventBreakerOpened)) != 1 {
+		t.Errorf("Expected a breaker opened event, got %v", recorder.types())
+	}
+	
+	s.setConnectionState(ConnectionStateConnected, nil)
+	if len(recorder.of(EventConnected)) != 1 {
+		t.Errorf("Expected a connected event, got %v", recorder.types())
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	first, second := &eventRecorder{}, &eventRecorder{}
+	unsubscribe := s.Subscribe(first.listen)
+	s.Subscribe(second.listen)
+	
+	// A listener may unsubscribe itself while an event is delivered
+	var once sync.Once
+	s.Subscribe(func(SenderEvent) { once.Do(unsubscribe) })
+	
+	s.SendMessage(ctx, "orders", "a")
+	s.SendMessage(ctx, "orders", "b")
+	unsubscribe()
+	
+	if got := len(second.of(EventMessageSent)); got != 2 {
+		t.Errorf("Expected the remaining listener to get 2 events, got %d", got)
+	}
+	if got := len(first.of(EventMessageSent)); got > 1 {
+		t.Errorf("Expected no events after unsubscribing, got %d", got)
+	}
+}
@@ -0,0 +1,274 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
+)
+
+// HeaderFailover marks messages sent to a standby cluster; its value is the
+// address of the standby
+const HeaderFailover = "failover"
+
+// FailoverReport describes the messages sent to standby clusters during the
+// current or last failover, so they can be reconciled with the primary.
+// Failed over messages carry HeaderFailover.
+type FailoverReport struct {
+	Active   bool             // sends are failing over right now
+	Started  time.Time        // first send to a standby
+	Ended    time.Time        // first send to the primary again, zero while active
+	Cause    string           // error that made the first send fail over
+	Messages int64            // messages sent to standbys
+	Queues   map[string]int64 // messages per queue
+	Standbys map[string]int64 // messages per standby address
+}
+
+// standby is a standby cluster of Config.FallbackAddresses
+type standby struct {
+	address string
+	client  *redis.Client
+	backend *redisBackend
+}
+
+// failover sends to standby clusters while the primary can't be used. A
+// nil failover sends nothing.
+type failover struct {
+	standbys []*standby
+	queues   map[string]bool // failed over queues, nil for all
+
+	mu      sync.Mutex
+	current int // index of the standby sends go to
+	report  FailoverReport
+	active  atomic.Bool // report.Active, read without the lock on every send
+}
+
+// standbyKey carries the backend of the standby a failed over push goes to
+type standbyKey struct{}
+
+// newFailover connects to the standby clusters of the configuration, or
+// returns nil if Config.FallbackAddresses is empty. Standbys use the
+// settings of the primary apart from the address.
+func (s *valkeySender) newFailover() (*failover, error) {
+	if len(s.config.FallbackAddresses) == 0 {
+		return nil, nil
+	}
+
+	f := &failover{}
+	for _, address := range s.config.FallbackAddresses {
+		config := *s.config
+		config.Address = address
+		client, err := newRedisClient(&config, s.options)
+		if err != nil {
+			f.close()
+			return nil, fmt.Errorf("failed to create client for standby %s: %w", address, err)
+		}
+		f.standbys = append(f.standbys, &standby{
+			address: address,
+			client:  client,
+			backend: &redisBackend{client: func() redis.UniversalClient { return client }},
+		})
+	}
+	if len(s.config.FailoverQueues) > 0 {
+		f.queues = make(map[string]bool, len(s.config.FailoverQueues))
+		for _, queue := range s.config.FailoverQueues {
+			f.queues[queue] = true
+		}
+	}
+	return f, nil
+}
+
+// covers reports whether every queue of the batches fails over
+func (f *failover) covers(batches []*queueBatch) bool {
+	if f == nil {
+		return false
+	}
+	for _, batch := range batches {
+		if f.queues != nil && !f.queues[batch.queue] {
+			return false
+		}
+	}
+	return true
+}
+
+// standby returns the standby sends go to
+func (f *failover) standby() *standby {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.standbys[f.current]
+}
+
+// unreachable moves on to the next standby after a connection error on sb
+func (f *failover) unreachable(sb *standby) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.standbys[f.current] == sb {
+		f.current = (f.current + 1) % len(f.standbys)
+	}
+}
+
+// record adds the batches sent to sb to the report, returning true if they
+// started a failover
+func (f *failover) record(sb *standby, batches []*queueBatch, cause error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	started := !f.report.Active
+	if started {
+		f.active.Store(true)
+		f.report = FailoverReport{
+			Active:   true,
+			Started:  time.Now(),
+			Cause:    cause.Error(),
+			Queues:   make(map[string]int64),
+			Standbys: make(map[string]int64),
+		}
+	}
+	for _, batch := range batches {
+		count := int64(len(batch.data))
+		f.report.Messages += count
+		f.report.Queues[batch.queue] += count
+		f.report.Standbys[sb.address] += count
+	}
+	return started
+}
+
+// recovered ends the failover in progress, if any, returning its report
+func (f *failover) recovered() (FailoverReport, bool) {
+	if f == nil || !f.active.Load() {
+		return FailoverReport{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.report.Active {
+		return FailoverReport{}, false
+	}
+	f.active.Store(false)
+	f.report.Active = false
+	f.report.Ended = time.Now()
+	f.current = 0
+	return f.snapshot(), true
+}
+
+// snapshot copies the report; the caller must hold the lock
+func (f *failover) snapshot() FailoverReport {
+	report := f.report
+	report.Queues = maps.Clone(f.report.Queues)
+	report.Standbys = maps.Clone(f.report.Standbys)
+	return report
+}
+
+// close closes the standby clients
+func (f *failover) close() error {
+	if f == nil {
+		return nil
+	}
+	var errs []error
+	for _, sb := range f.standbys {
+		errs = append(errs, sb.client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// failsOver reports whether a send refused by the circuit breaker fails over
+func (s *valkeySender) failsOver(err error, batches []*queueBatch) bool {
+	return (errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)) &&
+		s.failover.covers(batches)
+}
+
+// failOver pushes batches to the current standby, marking their messages
+// with HeaderFailover. Raw and framed messages are sent unmarked. A
+// connection error moves later sends on to the next standby.
+func (s *valkeySender) failOver(ctx context.Context, batches []*queueBatch, push func(ctx context.Context) error, cause error) error {
+	sb := s.failover.standby()
+	if err := s.markFailover(batches, sb.address); err != nil {
+		return err
+	}
+
+	if err := push(context.WithValue(ctx, standbyKey{}, sb.backend)); err != nil {
+		if isConnectionError(err) {
+			s.failover.unreachable(sb)
+		}
+		return fmt.Errorf("failed to send to standby %s: %w", sb.address, err)
+	}
+
+	if s.failover.record(sb, batches, cause) {
+		s.logger.Warn("Primary unavailable, sending to standby",
+			slog.String("standby", sb.address),
+			slog.Any("cause", cause),
+		)
+		s.events.publish(SenderEvent{Type: EventFailover, Err: cause})
+	}
+	return nil
+}
+
+// markFailover sets HeaderFailover on the envelopes of the batches and
+// encodes them again
+func (s *valkeySender) markFailover(batches []*queueBatch, address string) error {
+	if s.options.RawPayload {
+		return nil
+	}
+	for _, batch := range batches {
+		if len(batch.envelopes) != len(batch.data) {
+			continue
+		}
+		for i := range batch.envelopes {
+			envelope := &batch.envelopes[i]
+			if envelope.Headers == nil {
+				envelope.Headers = make(map[string]string, 1)
+			}
+			envelope.Headers[HeaderFailover] = address
+			data, err := s.codec.Encode(*envelope)
+			if err != nil {
+				return &Error{Kind: ErrSerialization, Err: fmt.Errorf("envelope %d: %w", i, err)}
+			}
+			batch.data[i] = data
+		}
+	}
+	return nil
+}
+
+// failedBack ends the failover in progress, if any, after a send reached
+// the primary, and logs its report
+func (s *valkeySender) failedBack() {
+	report, ok := s.failover.recovered()
+	if !ok {
+		return
+	}
+	s.logger.Info("Primary available again, failover ended",
+		slog.Time("started", report.Started),
+		slog.Duration("duration", report.Ended.Sub(report.Started)),
+		slog.Int64("messages", report.Messages),
+		slog.Any("queues", report.Queues),
+		slog.Any("standbys", report.Standbys),
+	)
+	s.events.publish(SenderEvent{Type: EventFailback})
+}
+
+// pushBackend returns the backend a push goes to: the standby of a failed
+// over send, the sender's backend otherwise
+func (s *valkeySender) pushBackend(ctx context.Context) backend {
+	if standby, ok := ctx.Value(standbyKey{}).(backend); ok {
+		return standby
+	}
+	return s.backend
+}
+
+// FailoverReport returns the report of the current or last failover to a
+// standby of Config.FallbackAddresses, empty if sends never failed over
+func (s *valkeySender) FailoverReport() FailoverReport {
+	if s.failover == nil {
+		return FailoverReport{}
+	}
+	s.failover.mu.Lock()
+	defer s.failover.mu.Unlock()
+	return s.failover.snapshot()
+}
@@ -0,0 +1,90 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestFailover(t *testing.T) {
+	primary, standbyServer := miniredis.RunT(t), miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(primary.Addr(),
+		WithFailover([]string{standbyServer.Addr()}, "payments"),
+		WithCircuitBreaker(1, 0, 100*time.Millisecond),
+		WithRetries(0, 0, 0),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	s := sender.(*valkeySender)
+	recorder := &eventRecorder{}
+	s.Subscribe(recorder.listen)
+
+	// Fail until the breaker opens, then the send goes to the standby
+	primary.Close()
+	waitFor(t, func() bool { return s.SendMessage(ctx, "payments", "p1") == nil })
+
+	values, _ := standbyServer.List(s.getQueueKey("payments"))
+	if len(values) != 1 {
+		t.Fatalf("Expected the message on the standby, got %v", values)
+	}
+	envelope, err := NewJSONEnvelopeCodec().Decode([]byte(values[0]))
+	if err != nil || envelope.Headers[HeaderFailover] != standbyServer.Addr() {
+		t.Errorf("Expected the failover header, got %v (%v)", envelope.Headers, err)
+	}
+
+	report := s.FailoverReport()
+	if !report.Active || report.Messages != 1 || report.Queues["payments"] != 1 || report.Standbys[standbyServer.Addr()] != 1 {
+		t.Errorf("Expected an active report of one message, got %+v", report)
+	}
+	if len(recorder.of(EventFailover)) != 1 {
+		t.Errorf("Expected a failover event, got %v", recorder.types())
+	}
+
+	// Queues not listed don't fail over
+	if err := s.SendMessage(ctx, "orders", "o1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected orders to fail with the breaker open, got %v", err)
+	}
+
+	// Once the primary is back, sends return to it and the failover ends
+	if err := primary.Restart(); err != nil {
+		t.Fatalf("Failed to restart primary: %v", err)
+	}
+	waitFor(t, func() bool {
+		s.SendMessage(ctx, "payments", "p2")
+		return !s.FailoverReport().Active
+	})
+	report = s.FailoverReport()
+	if report.Ended.IsZero() || report.Messages < 1 {
+		t.Errorf("Expected the report of the ended failover, got %+v", report)
+	}
+	if values, _ := primary.List(s.getQueueKey("payments")); len(values) != 1 {
+		t.Errorf("Expected the last send on the primary, got %v", values)
+	}
+	if len(recorder.of(EventFailback)) != 1 {
+		t.Errorf("Expected a failback event, got %v", recorder.types())
+	}
+}
+
+func TestFailoverConfigValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.FallbackAddresses = []string{"standby"}
+	if err := config.validate(); err == nil {
+		t.Error("Expected an address without port to be rejected")
+	}
+
+	config.FallbackAddresses = []string{"standby:6379"}
+	config.Backend = BackendMemory
+	if err := config.validate(); err == nil {
+		t.Error("Expected failover to require the list backend")
+	}
+}
@@ -0,0 +1,404 @@
+package valkeysender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Frame compressions available without registration
+const (
+	// FrameCompressionGzip compresses frames with compress/gzip (default)
+	FrameCompressionGzip = "gzip"
+
+	// FrameCompressionZstd compresses frames with Zstandard
+	FrameCompressionZstd = "zstd"
+
+	// FrameCompressionNone stores frames uncompressed
+	FrameCompressionNone = "none"
+)
+
+// DefaultFrameSize is the number of envelopes packed into one frame when
+// Config.FrameSize is unset
+const DefaultFrameSize = 100
+
+// DefaultMaxFrameBytes is the largest decompressed frame body accepted when
+// Config.MaxFrameBytes is unset, so a small malicious frame cannot inflate
+// into an unbounded allocation
+const DefaultMaxFrameBytes = 64 << 20
+
+// frameMagic starts every frame, so consumers can tell frames from envelopes
+const frameMagic = "VSFR"
+
+// frameVersion is the version of the frame layout
+const frameVersion = 1
+
+// FrameCompressor compresses the body of frames written by SendBatchFramed.
+// The name is recorded in the frame header, so consumers decoding frames
+// must have a compressor of the same name registered.
+type FrameCompressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// LimitedDecompressor is implemented by compressors that can stop
+// decompressing once the output exceeds limit bytes. Bodies of compressors
+// without it are checked after they are decompressed.
+type LimitedDecompressor interface {
+	DecompressLimit(data []byte, limit int) ([]byte, error)
+}
+
+var (
+	frameCompressorsMutex sync.RWMutex
+	frameCompressors      = map[string]FrameCompressor{
+		FrameCompressionGzip: gzipCompressor{},
+		FrameCompressionZstd: zstdCompressor{},
+		FrameCompressionNone: noCompressor{},
+	}
+)
+
+// RegisterFrameCompressor makes a compressor available to SendBatchFramed
+// and DecodeFrame under its name, replacing any compressor of that name.
+// Register it before creating senders that use it.
+func RegisterFrameCompressor(compressor FrameCompressor) {
+	frameCompressorsMutex.Lock()
+	defer frameCompressorsMutex.Unlock()
+	frameCompressors[compressor.Name()] = compressor
+}
+
+// frameCompressor returns the compressor registered under name
+func frameCompressor(name string) (FrameCompressor, error) {
+	frameCompressorsMutex.RLock()
+	defer frameCompressorsMutex.RUnlock()
+	compressor, ok := frameCompressors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown frame compression %q", name)
+	}
+	return compressor, nil
+}
+
+// IsFrame reports whether a list element is a frame written by SendBatchFramed
+func IsFrame(data []byte) bool {
+	return len(data) > len(frameMagic) && string(data[:len(frameMagic)]) == frameMagic
+}
+
+// EncodeFrame packs list elements into one frame:
+//
+//	"VSFR" | version (1 byte) | compression name length (1 byte) | name |
+//	element count (uvarint) | compressed body
+//
+// where the body is every element prefixed with its length as a uvarint
+func EncodeFrame(compressor FrameCompressor, elements [][]byte) ([]byte, error) {
+	name := compressor.Name()
+	if name == "" || len(name) > 255 {
+		return nil, fmt.Errorf("frame compression name must be 1 to 255 bytes")
+	}
+
+	size := 0
+	for _, element := range elements {
+		size += binary.MaxVarintLen64 + len(element)
+	}
+	body := make([]byte, 0, size)
+	for _, element := range elements {
+		body = binary.AppendUvarint(body, uint64(len(element)))
+		body = append(body, element...)
+	}
+
+	compressed, err := compressor.Compress(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress frame: %w", err)
+	}
+
+	frame := make([]byte, 0, len(frameMagic)+2+len(name)+binary.MaxVarintLen64+len(compressed))
+	frame = append(frame, frameMagic...)
+	frame = append(frame, frameVersion, byte(len(name)))
+	frame = append(frame, name...)
+	frame = binary.AppendUvarint(frame, uint64(len(elements)))
+	return append(frame, compressed...), nil
+}
+
+// DecodeFrame unpacks the elements of a frame, usually encoded envelopes,
+// rejecting bodies larger than DefaultMaxFrameBytes
+func DecodeFrame(data []byte) ([][]byte, error) {
+	return DecodeFrameLimit(data, DefaultMaxFrameBytes)
+}
+
+// DecodeFrameLimit unpacks the elements of a frame like DecodeFrame,
+// rejecting bodies that decompress to more than maxBytes with an error
+// wrapping ErrMessageTooLarge
+func DecodeFrameLimit(data []byte, maxBytes int) ([][]byte, error) {
+	if !IsFrame(data) {
+		return nil, errors.New("not a frame")
+	}
+	data = data[len(frameMagic):]
+	if len(data) < 2 || data[0] != frameVersion {
+		return nil, errors.New("unsupported frame version")
+	}
+	nameLength := int(data[1])
+	data = data[2:]
+	if len(data) < nameLength {
+		return nil, errors.New("truncated frame header")
+	}
+	compressor, err := frameCompressor(string(data[:nameLength]))
+	if err != nil {
+		return nil, err
+	}
+	data = data[nameLength:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errors.New("truncated frame header")
+	}
+	body, err := decompressLimit(compressor, data[n:], maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress frame: %w", err)
+	}
+
+	// Every element takes at least one byte for its length
+	if count > uint64(len(body)) {
+		return nil, errors.New("corrupt frame body")
+	}
+	elements := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		length, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < length {
+			return nil, errors.New("corrupt frame body")
+		}
+		elements = append(elements, body[n:n+int(length)])
+		body = body[n+int(length):]
+	}
+	if len(body) != 0 {
+		return nil, errors.New("corrupt frame body")
+	}
+	return elements, nil
+}
+
+// decompressLimit decompresses data with compressor, failing with an error
+// wrapping ErrMessageTooLarge if the result exceeds limit bytes
+func decompressLimit(compressor FrameCompressor, data []byte, limit int) ([]byte, error) {
+	var body []byte
+	var err error
+	if limited, ok := compressor.(LimitedDecompressor); ok {
+		body, err = limited.DecompressLimit(data, limit)
+	} else {
+		body, err = compressor.Decompress(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > limit {
+		return nil, errFrameTooLarge(limit)
+	}
+	return body, nil
+}
+
+// readAllLimit reads r to the end, failing once more than limit bytes come out
+func readAllLimit(r io.Reader, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, errFrameTooLarge(limit)
+	}
+	return data, nil
+}
+
+// errFrameTooLarge reports a body decompressing to more than limit bytes
+func errFrameTooLarge(limit int) error {
+	return fmt.Errorf("decompressed size exceeds %d bytes: %w", limit, ErrMessageTooLarge)
+}
+
+// gzipCompressor compresses frames with compress/gzip
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string {
+	return FrameCompressionGzip
+}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxFrameBytes)
+}
+
+func (gzipCompressor) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readAllLimit(reader, limit)
+}
+
+// zstdEncoder is shared by every zstd compression; EncodeAll is safe for
+// concurrent use
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+// zstdCompressor compresses frames with Zstandard
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return FrameCompressionZstd
+}
+
+func (zstdCompressor) Compress(data []byte) ([]byte, error) {
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (c zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	return c.DecompressLimit(data, DefaultMaxFrameBytes)
+}
+
+func (zstdCompressor) DecompressLimit(data []byte, limit int) ([]byte, error) {
+	// The memory limit also caps the window a hostile header can ask for
+	decoder, err := zstd.NewReader(bytes.NewReader(data),
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(uint64(max(limit, 1))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	body, err := readAllLimit(decoder, limit)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return nil, errFrameTooLarge(limit)
+	}
+	return body, err
+}
+
+// noCompressor stores frame bodies as they are
+type noCompressor struct{}
+
+func (noCompressor) Name() string {
+	return FrameCompressionNone
+}
+
+func (noCompressor) Compress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (noCompressor) Decompress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// frameSize returns the configured frame size or the default
+func (s *valkeySender) frameSize() int {
+	if s.config.FrameSize > 0 {
+		return s.config.FrameSize
+	}
+	return DefaultFrameSize
+}
+
+// frameCompression returns the configured frame compression or the default
+func (s *valkeySender) frameCompression() string {
+	if s.config.FrameCompression != "" {
+		return s.config.FrameCompression
+	}
+	return FrameCompressionGzip
+}
+
+// SendBatchFramed sends messages for bulk loads: their envelopes are packed
+// into compressed frames of Config.FrameSize envelopes, each stored as a
+// single list element, which saves most of the memory small JSON messages
+// take in Valkey. Consumers recognize frames with IsFrame and unpack them
+// with DecodeFrame; a Consumer does this itself.
+//
+// All frames are pushed atomically. Deduplication does not apply to
+// framed messages, and the queue length cap counts frames, not messages.
+// Config.MaxMessageBytes applies to every frame, so a batch whose frames
+// grow over it is rejected; lower Config.FrameSize to send it.
+func (s *valkeySender) SendBatchFramed(ctx context.Context, queue string, messages []interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages slice cannot be empty")
+	}
+
+	startTime := time.Now()
+
+	batch, err := s.newQueueBatch(ctx, queue, messages, s.messageTTL(queue), HeadersFromContext(ctx))
+	if err != nil {
+		return s.fail(opSendFramed, queue, err)
+	}
+
+	framed, err := s.frameBatch(batch)
+	if err != nil {
+		return s.fail(opSendFramed, queue, err)
+	}
+
+	if err := s.execute(ctx, opSendFramed, queue, []*queueBatch{framed}, false); err != nil {
+		return err
+	}
+
+	// Update metrics
+	s.recordSuccess(len(messages))
+
+	s.logger.Debug("Framed batch sent successfully",
+		slog.String("queue", queue),
+		slog.Int("message_count", len(messages)),
+		slog.Int("frame_count", len(framed.data)),
+	)
+
+	// Call success handler for each message
+	s.notifySuccess([]*queueBatch{batch}, "", startTime)
+
+	return nil
+}
+
+// frameBatch packs the encoded messages of a batch into frames, each held
+// to Config.MaxMessageBytes like any other list element
+func (s *valkeySender) frameBatch(batch *queueBatch) (*queueBatch, error) {
+	compressor, err := frameCompressor(s.frameCompression())
+	if err != nil {
+		return nil, &Error{Kind: ErrSerialization, Err: err}
+	}
+
+	size := s.frameSize()
+	framed := &queueBatch{
+		queue: batch.queue,
+		key:   batch.key,
+		ttl:   batch.ttl,
+		data:  make([]interface{}, 0, (len(batch.data)+size-1)/size),
+	}
+	for start := 0; start < len(batch.data); start += size {
+		chunk := batch.data[start:min(start+size, len(batch.data))]
+		elements := make([][]byte, len(chunk))
+		for i, data := range chunk {
+			elements[i] = []byte(memoryValue(data))
+		}
+		frame, err := EncodeFrame(compressor, elements)
+		if err != nil {
+			return nil, &Error{Kind: ErrSerialization, Err: fmt.Errorf("frame %d: %w", len(framed.data), err)}
+		}
+		if err := s.checkElementSize("frame", len(framed.data), frame); err != nil {
+			return nil, err
+		}
+		framed.data = append(framed.data, frame)
+	}
+	return framed, nil
+}
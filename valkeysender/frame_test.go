@@ -0,0 +1,244 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	elements := [][]byte{[]byte(`{"id":1}`), {}, bytes.Repeat([]byte("x"), 300)}
+
+	for _, name := range []string{FrameCompressionGzip, FrameCompressionZstd, FrameCompressionNone} {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := frameCompressor(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frame, err := EncodeFrame(compressor, elements)
+			if err != nil {
+				t.Fatalf("EncodeFrame failed: %v", err)
+			}
+			if !IsFrame(frame) {
+				t.Fatal("Expected IsFrame to detect the frame")
+			}
+
+			decoded, err := DecodeFrame(frame)
+			if err != nil {
+				t.Fatalf("DecodeFrame failed: %v", err)
+			}
+			if len(decoded) != len(elements) {
+				t.Fatalf("Expected %d elements, got %d", len(elements), len(decoded))
+			}
+			for i := range elements {
+				if !bytes.Equal(decoded[i], elements[i]) {
+					t.Errorf("Element %d: expected %q, got %q", i, elements[i], decoded[i])
+				}
+			}
+
+			if _, err := DecodeFrame(frame[:len(frame)-2]); err == nil {
+				t.Error("Expected a truncated frame to fail")
+			}
+		})
+	}
+
+	if IsFrame([]byte(`{"id":"1"}`)) {
+		t.Error("Expected an envelope not to be detected as a frame")
+	}
+}
+
+func TestFrameDecompressionLimit(t *testing.T) {
+	// A megabyte of zeros compresses to a few hundred bytes
+	elements := [][]byte{make([]byte, 1<<20)}
+
+	for _, name := range []string{FrameCompressionGzip, FrameCompressionZstd, FrameCompressionNone} {
+		t.Run(name, func(t *testing.T) {
+			compressor, _ := frameCompressor(name)
+			frame, err := EncodeFrame(compressor, elements)
+			if err != nil {
+				t.Fatalf("EncodeFrame failed: %v", err)
+			}
+
+			if _, err := DecodeFrameLimit(frame, 64<<10); !errors.Is(err, ErrMessageTooLarge) {
+				t.Errorf("Expected a body over the limit to wrap ErrMessageTooLarge, got %v", err)
+			}
+			decoded, err := DecodeFrameLimit(frame, 2<<20)
+			if err != nil || len(decoded) != 1 || len(decoded[0]) != 1<<20 {
+				t.Errorf("Expected the frame within the limit to decode, got %d elements (%v)", len(decoded), err)
+			}
+		})
+	}
+
+	// The compressors stop at the limit instead of inflating everything
+	bomb, _ := zstdCompressor{}.Compress(make([]byte, 8<<20))
+	if _, err := (zstdCompressor{}).DecompressLimit(bomb, 1<<20); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected zstd to stop at the limit, got %v", err)
+	}
+	bomb, _ = gzipCompressor{}.Compress(make([]byte, 8<<20))
+	if _, err := (gzipCompressor{}).DecompressLimit(bomb, 1<<20); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected gzip to stop at the limit, got %v", err)
+	}
+
+	config := DefaultConfig()
+	config.MaxFrameBytes = -1
+	if err := config.validate(); err == nil {
+		t.Error("Expected a negative frame limit to be rejected")
+	}
+}
+
+func TestFrameSize(t *testing.T) {
+	s := newMemorySender(t)
+
+	messages := make([]interface{}, 1000)
+	for i := range messages {
+		messages[i] = map[string]interface{}{"user_id": i, "event": "signup", "plan": "free"}
+	}
+	batch, err := s.newQueueBatch(context.Background(), "events", messages, s.messageTTL("events"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed, err := s.frameBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, compressed := 0, 0
+	for _, data := range batch.data {
+		plain += len(memoryValue(data))
+	}
+	for _, data := range framed.data {
+		compressed += len(memoryValue(data))
+	}
+	if len(framed.data) != 10 {
+		t.Errorf("Expected 10 frames of %d envelopes, got %d", DefaultFrameSize, len(framed.data))
+	}
+	if compressed*10 > plain*3 {
+		t.Errorf("Expected frames to save at least 70%%, %d bytes became %d", plain, compressed)
+	}
+}
+
+func TestSendBatchFramed(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			s.config.FrameSize = 2
+
+			if err := s.SendBatchFramed(ctx, "imports", []interface{}{"a", "b", "c"}); err != nil {
+				t.Fatalf("SendBatchFramed failed: %v", err)
+			}
+			if size, _ := s.GetQueueSize(ctx, "imports"); size != 2 {
+				t.Fatalf("Expected 2 frames, got %d", size)
+			}
+			if sent := atomic.LoadInt64(&s.messagesSent); sent != 3 {
+				t.Errorf("Expected 3 messages counted, got %d", sent)
+			}
+
+			peeked, err := s.PeekMessages(ctx, "imports", 0, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payloads []string
+			for _, frame := range peeked {
+				elements, err := DecodeFrame(frame.Payload)
+				if err != nil {
+					t.Fatalf("DecodeFrame failed: %v", err)
+				}
+				for _, element := range elements {
+					envelope, err := s.codec.Decode(element)
+					if err != nil {
+						t.Fatal(err)
+					}
+					payloads = append(payloads, string(envelope.Payload))
+				}
+			}
+			if fmt.Sprint(payloads) != "[a b c]" {
+				t.Errorf("Expected the messages in order, got %v", payloads)
+			}
+
+			s.config.FrameCompression = "missing"
+			err = s.SendBatchFramed(ctx, "imports", []interface{}{"d"})
+			if !errors.Is(err, ErrSerialization) {
+				t.Errorf("Expected ErrSerialization for an unknown compression, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSendBatchFramedTooLarge(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.FrameCompression = FrameCompressionNone
+	s.config.MaxMessageBytes = 1024
+
+	// Every message fits, but not a frame of all of them
+	messages := make([]interface{}, 20)
+	for i := range messages {
+		messages[i] = fmt.Sprintf("message %d", i)
+	}
+	err := s.SendBatchFramed(ctx, "imports", messages)
+	if !errors.Is(err, ErrMessageTooLarge) || !strings.Contains(err.Error(), "frame 0 is") {
+		t.Fatalf("Expected the frame to be rejected, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "imports"); size != 0 {
+		t.Errorf("Expected nothing pushed, got %d frames", size)
+	}
+	if rejected := s.Metrics().MessagesTooLarge; rejected != 1 {
+		t.Errorf("Expected 1 rejection counted, got %d", rejected)
+	}
+
+	s.config.FrameSize = 2
+	if err := s.SendBatchFramed(ctx, "imports", messages); err != nil {
+		t.Fatalf("Expected smaller frames to fit, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "imports"); size != 10 {
+		t.Errorf("Expected 10 frames, got %d", size)
+	}
+}
+
+func TestConsumerReceiveFrame(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	s := newTestSender(t, server, nil)
+	if err := s.SendBatchFramed(ctx, "jobs", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatchFramed failed: %v", err)
+	}
+
+	consumer := newTestConsumer(t, server, ConsumerConfig{Queue: "jobs", Name: "worker-1", MaxRetries: 1, DeadLetterQueue: "jobs-dead"})
+
+	delivery, err := consumer.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if len(delivery.Batch) != 2 || delivery.Envelope.ID != delivery.Batch[0].ID {
+		t.Fatalf("Expected a delivery of 2 envelopes, got %+v", delivery.Batch)
+	}
+
+	if err := consumer.Nack(ctx, delivery); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	delivery, err = consumer.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	for _, envelope := range delivery.Batch {
+		if envelope.Retries != 1 {
+			t.Errorf("Expected retries to be counted per envelope, got %d", envelope.Retries)
+		}
+	}
+
+	if err := consumer.Nack(ctx, delivery); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	dead, _ := server.List(DefaultQueuePrefix + "jobs-dead")
+	if len(dead) != 1 || !IsFrame([]byte(dead[0])) {
+		t.Errorf("Expected the frame in the dead letter queue, got %d elements", len(dead))
+	}
+}
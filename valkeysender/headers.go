@@ -0,0 +1,90 @@
+package valkeysender
+
+import (
+	"context"
+	"maps"
+	"strconv"
+)
+
+// Standard envelope headers, set on every envelope unless
+// Config.StandardHeaders is disabled. Headers set by the send, its context
+// or the serializer take precedence.
+const (
+	// HeaderContentType carries the serializer's ContentType, e.g. "application/json"
+	HeaderContentType = "content-type"
+
+	// HeaderContentEncoding carries the encoding of an EncodingSerializer,
+	// e.g. "gzip"; it is left out for unencoded payloads
+	HeaderContentEncoding = "content-encoding"
+
+	// HeaderProducer carries Config.ProducerName, or the executable name
+	HeaderProducer = "producer"
+
+	// HeaderSchemaVersion carries the envelope schema version, see EnvelopeVersion
+	HeaderSchemaVersion = "schema-version"
+)
+
+// standardHeaders returns the standard headers of payloads written by
+// serializer, nil if Config.StandardHeaders is disabled
+func (s *valkeySender) standardHeaders(serializer MessageSerializer) map[string]string {
+	if !s.config.StandardHeaders {
+		return nil
+	}
+
+	headers := map[string]string{
+		HeaderContentType:   serializer.ContentType(),
+		HeaderSchemaVersion: strconv.Itoa(EnvelopeVersion),
+	}
+	if es, ok := serializer.(EncodingSerializer); ok && es.ContentEncoding() != "" {
+		headers[HeaderContentEncoding] = es.ContentEncoding()
+	}
+	if s.producer != "" {
+		headers[HeaderProducer] = s.producer
+	}
+	return headers
+}
+
+// headersKey is the context key of headers attached with WithHeader
+type headersKey struct{}
+
+// WithHeader returns a copy of ctx carrying an envelope header. Every
+// message sent under the returned context gets the header, unless the send
+// sets it itself, so middleware such as HTTP handlers or gRPC interceptors
+// can attach correlation or tenant IDs once for everything sent downstream.
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	return WithHeaders(ctx, map[string]string{key: value})
+}
+
+// WithHeaders returns a copy of ctx carrying several envelope headers,
+// added to and overriding those already attached to ctx
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	merged := make(map[string]string, len(headers))
+	maps.Copy(merged, HeadersFromContext(ctx))
+	maps.Copy(merged, headers)
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext returns the headers attached to ctx with WithHeader
+// and WithHeaders, or nil. The map must not be modified.
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// contextHeaders merges the headers attached to ctx with the headers of a
+// send, which take precedence
+func contextHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	attached := HeadersFromContext(ctx)
+	if len(attached) == 0 {
+		return headers
+	}
+	if len(headers) == 0 {
+		return attached
+	}
+	merged := maps.Clone(attached)
+	maps.Copy(merged, headers)
+	return merged
+}
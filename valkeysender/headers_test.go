@@ -0,0 +1,127 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestContextHeaders(t *testing.T) {
+	s := newMemorySender(t)
+
+	ctx := WithHeader(context.Background(), "correlation-id", "c1")
+	ctx = WithHeaders(ctx, map[string]string{"tenant": "acme", "source": "http"})
+
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendBatch(ctx, "orders", []interface{}{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendMessageWithOptions(ctx, "orders", "c", SendOptions{Headers: map[string]string{"source": "explicit"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.SendTransactional(ctx, func(tx TxSender) error {
+		return tx.SendMessage("orders", "d")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelopes, err := s.PeekMessages(context.Background(), "orders", 0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelopes) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(envelopes))
+	}
+	for i, envelope := range envelopes {
+		source := "http"
+		if i == 2 {
+			// Headers set by the send take precedence
+			source = "explicit"
+		}
+		headers := envelope.Headers
+		if headers["correlation-id"] != "c1" || headers["tenant"] != "acme" || headers["source"] != source {
+			t.Errorf("Message %d: unexpected headers %v", i, headers)
+		}
+	}
+
+	if headers := HeadersFromContext(context.Background()); headers != nil {
+		t.Errorf("Expected no headers, got %v", headers)
+	}
+	child := WithHeader(ctx, "source", "grpc")
+	if HeadersFromContext(ctx)["source"] != "http" || HeadersFromContext(child)["source"] != "grpc" {
+		t.Error("Expected the child context to override without changing its parent")
+	}
+}
+
+func TestStandardHeaders(t *testing.T) {
+	s := newMemorySender(t)
+	s.producer = "billing-api"
+	ctx := context.Background()
+
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatal(err)
+	}
+	custom := SendOptions{Headers: map[string]string{HeaderContentType: "text/plain"}}
+	if err := s.SendMessageWithOptions(ctx, "orders", "b", custom); err != nil {
+		t.Fatal(err)
+	}
+
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 2)
+	if err != nil || len(envelopes) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(envelopes), err)
+	}
+	headers := envelopes[0].Headers
+	if headers[HeaderContentType] != "application/json" || headers[HeaderProducer] != "billing-api" || headers[HeaderSchemaVersion] != "1" {
+		t.Errorf("Expected the standard headers, got %v", headers)
+	}
+	if _, ok := headers[HeaderContentEncoding]; ok {
+		t.Errorf("Expected no content-encoding for an unencoded payload, got %v", headers)
+	}
+	// Headers set by the send take precedence
+	if contentType := envelopes[1].Headers[HeaderContentType]; contentType != "text/plain" {
+		t.Errorf("Expected the send's content type, got %s", contentType)
+	}
+
+	s.config.StandardHeaders = false
+	if err := s.SendMessage(ctx, "plain", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if envelopes, _ := s.PeekMessages(ctx, "plain", 0, 1); len(envelopes) != 1 || envelopes[0].Headers != nil {
+		t.Errorf("Expected no headers with standard headers disabled, got %+v", envelopes)
+	}
+}
+
+func TestStandardHeadersContentEncoding(t *testing.T) {
+	s := newMemorySender(t)
+	ctx := context.Background()
+
+	serializer, err := NewCompressedSerializer(NewProtoSerializer(), FrameCompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.options.SerializerFor = func(string) MessageSerializer { return serializer }
+	if err := s.SendMessage(ctx, "events", wrapperspb.String("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	envelopes, _ := s.PeekMessages(ctx, "events", 0, 1)
+	if len(envelopes) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(envelopes))
+	}
+	headers := envelopes[0].Headers
+	if headers[HeaderContentType] != "application/x-protobuf" || headers[HeaderContentEncoding] != FrameCompressionGzip || headers[HeaderProtoMessage] != "google.protobuf.StringValue" {
+		t.Errorf("Expected protobuf content compressed with gzip, got %v", headers)
+	}
+
+	var decoded wrapperspb.StringValue
+	if err := serializer.Deserialize(envelopes[0].Payload, &decoded); err != nil || decoded.Value != "hello" {
+		t.Errorf("Expected the payload to decompress, got %q (%v)", decoded.Value, err)
+	}
+	if _, err := NewCompressedSerializer(NewJSONSerializer(), "lz4"); err == nil {
+		t.Error("Expected an unknown compression to be rejected")
+	}
+}
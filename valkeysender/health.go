@@ -0,0 +1,95 @@
+package valkeysender
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultHealthWindow is the window Health computes the error rate over
+// when Config.HealthWindow is unset
+const DefaultHealthWindow = 5 * time.Minute
+
+// healthBuckets is the number of buckets the health window is split into
+const healthBuckets = 30
+
+// Error rates at which Health reports a sender degraded or unhealthy
+const (
+	degradedErrorRate  = 0.1
+	unhealthyErrorRate = 0.5
+)
+
+// outcomeWindow counts sent messages and failed operations over a rolling
+// window, in healthBuckets buckets
+type outcomeWindow struct {
+	mu      sync.Mutex
+	width   time.Duration // of one bucket
+	buckets [healthBuckets]outcomeBucket
+}
+
+// outcomeBucket holds the counts of one bucket
+type outcomeBucket struct {
+	slot      int64 // index of the time slice the bucket belongs to
+	successes int64
+	failures  int64
+}
+
+// newOutcomeWindow creates a window of the given length, DefaultHealthWindow if zero
+func newOutcomeWindow(window time.Duration) *outcomeWindow {
+	if window <= 0 {
+		window = DefaultHealthWindow
+	}
+	return &outcomeWindow{width: max(window/healthBuckets, 1)}
+}
+
+// add records successes and failures at the given time
+func (w *outcomeWindow) add(successes, failures int64, now time.Time) {
+	slot := now.UnixNano() / int64(w.width)
+	bucket := &w.buckets[slot%healthBuckets]
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if bucket.slot != slot {
+		*bucket = outcomeBucket{slot: slot}
+	}
+	bucket.successes += successes
+	bucket.failures += failures
+}
+
+// totals returns the successes and failures within the window
+func (w *outcomeWindow) totals(now time.Time) (successes, failures int64) {
+	oldest := now.UnixNano()/int64(w.width) - healthBuckets + 1
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, bucket := range w.buckets {
+		if bucket.slot >= oldest {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	return successes, failures
+}
+
+// length returns the length of the whole window
+func (w *outcomeWindow) length() time.Duration {
+	return w.width * healthBuckets
+}
+
+// errorRate returns the share of failures among all outcomes, 0 without any
+func errorRate(successes, failures int64) float64 {
+	if successes+failures == 0 {
+		return 0
+	}
+	return float64(failures) / float64(successes+failures)
+}
+
+// healthStatus maps an error rate to healthy, degraded or unhealthy
+func healthStatus(rate float64) string {
+	switch {
+	case rate > unhealthyErrorRate:
+		return "unhealthy"
+	case rate > degradedErrorRate:
+		return "degraded"
+	}
+	return "healthy"
+}
@@ -0,0 +1,82 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestOutcomeWindow(t *testing.T) {
+	window := newOutcomeWindow(30 * time.Second) // 1s buckets
+	start := time.Unix(1000, 0)
+	
+	window.add(8, 0, start)
+	window.add(0, 2, start.Add(10*time.Second))
+	if sent, failed := window.totals(start.Add(20 * time.Second)); sent != 8 || failed != 2 {
+		t.Errorf("Expected 8 sent and 2 failed within the window, got %d and %d", sent, failed)
+	}
+	if sent, failed := window.totals(start.Add(35 * time.Second)); sent != 0 || failed != 2 {
+		t.Errorf("Expected the first bucket to age out, got %d and %d", sent, failed)
+	}
+	
+	// A reused bucket starts from zero
+	window.add(1, 0, start.Add(30*time.Second))
+	if sent, _ := window.totals(start.Add(30 * time.Second)); sent != 1 {
+		t.Errorf("Expected a reset bucket, got %d sent", sent)
+	}
+	if window.length() != 30*time.Second {
+		t.Errorf("Unexpected window length %v", window.length())
+	}
+}
+
+func TestHealthErrorRateWindow(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.outcomes = newOutcomeWindow(300 * time.Millisecond)
+	
+	s.SendMessage(ctx, "orders", "a")
+	for i := 0; i < 3; i++ {
+		s.SendMessage(ctx, "orders", make(chan int))
+	}
+	
+	health := s.Health()
+	if health.Status != "unhealthy" || health.ErrorRate != 0.75 || health.LifetimeErrorRate != 0.75 {
+		t.Fatalf("Expected an unhealthy 75%% error rate, got %+v", health)
+	}
+	if health.WindowMessagesSent != 1 || health.WindowErrorCount != 3 || health.HealthWindow != 300*time.Millisecond {
+		t.Errorf("Unexpected window counts: %+v", health)
+	}
+	
+	// The failures age out of the window, the lifetime rate keeps them
+	time.Sleep(350 * time.Millisecond)
+	s.SendMessage(ctx, "orders", "b")
+	health = s.Health()
+	if health.Status != "healthy" || health.ErrorRate != 0 || health.LifetimeErrorRate != 0.6 {
+		t.Errorf("Expected a healthy sender with a 60%% lifetime error rate, got %+v", health)
+	}
+}
+
+func TestHealthConnectionPool(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	// A dedicated connection stays checked out of the pool until closed
+	conn := s.getClient().(*redis.Client).Conn()
+	if err := conn.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	pool := s.Health().ConnectionPool
+	if pool.MaxConns != int32(s.config.PoolSize) || pool.ActiveConns != 1 || pool.TotalConns != pool.IdleConns+1 {
+		t.Errorf("Expected one active connection, got %+v", pool)
+	}
+	
+	conn.Close()
+	if pool := s.Health().ConnectionPool; pool.ActiveConns != 0 || pool.Hits+pool.Misses == 0 {
+		t.Errorf("Expected the connection back in the pool, got %+v", pool)
+	}
+}
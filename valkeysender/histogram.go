@@ -0,0 +1,104 @@
+package valkeysender
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Latency histogram layout: bucket i counts durations up to
+// latencyBucketBase << i, the last bucket everything above the largest bound
+const (
+	latencyBucketBase = 50 * time.Microsecond
+	latencyBuckets    = 24 // largest bound ~7m
+)
+
+// latencyHistogram records durations into exponential buckets without locks.
+// Quantiles are reported as the upper bound of the bucket they fall into,
+// capped at the largest recorded duration.
+type latencyHistogram struct {
+	counts [latencyBuckets + 1]int64
+	count  int64
+	sum    int64 // nanoseconds
+	max    int64 // nanoseconds
+}
+
+// record adds one duration
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.AddInt64(&h.counts[latencyBucket(d)], 1)
+	atomic.AddInt64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+	for {
+		current := atomic.LoadInt64(&h.max)
+		if int64(d) <= current || atomic.CompareAndSwapInt64(&h.max, current, int64(d)) {
+			return
+		}
+	}
+}
+
+// latencyBucket returns the index of the bucket holding d
+func latencyBucket(d time.Duration) int {
+	bound := latencyBucketBase
+	for i := 0; i < latencyBuckets; i++ {
+		if d <= bound {
+			return i
+		}
+		bound <<= 1
+	}
+	return latencyBuckets
+}
+
+// latencySnapshot summarizes a histogram
+type latencySnapshot struct {
+	count         int64
+	avg, max      time.Duration
+	p50, p95, p99 time.Duration
+}
+
+// snapshot reads the histogram. Concurrent records may be partially
+// included, which only skews the result by those records.
+func (h *latencyHistogram) snapshot() latencySnapshot {
+	var counts [latencyBuckets + 1]int64
+	var total int64
+	for i := range counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+		total += counts[i]
+	}
+
+	snapshot := latencySnapshot{
+		count: total,
+		max:   time.Duration(atomic.LoadInt64(&h.max)),
+	}
+	if total == 0 {
+		return snapshot
+	}
+	if count := atomic.LoadInt64(&h.count); count > 0 {
+		snapshot.avg = time.Duration(atomic.LoadInt64(&h.sum) / count)
+	}
+
+	quantile := func(q float64) time.Duration {
+		rank := int64(q*float64(total) + 0.5)
+		if rank < 1 {
+			rank = 1
+		}
+		var seen int64
+		bound := latencyBucketBase
+		for i, n := range counts {
+			seen += n
+			if seen >= rank {
+				if i == latencyBuckets || bound > snapshot.max {
+					return snapshot.max
+				}
+				return bound
+			}
+			bound <<= 1
+		}
+		return snapshot.max
+	}
+	snapshot.p50 = quantile(0.50)
+	snapshot.p95 = quantile(0.95)
+	snapshot.p99 = quantile(0.99)
+	return snapshot
+}
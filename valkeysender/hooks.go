@@ -0,0 +1,35 @@
+package valkeysender
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// beforeSend passes an envelope to the BeforeSend hook, if one is set. The
+// hook gets its own copy of the producer metadata and a non-nil header map.
+func (s *valkeySender) beforeSend(ctx context.Context, envelope *MessageEnvelope) error {
+	if s.options.BeforeSend == nil {
+		return nil
+	}
+	if envelope.Headers == nil {
+		envelope.Headers = make(map[string]string)
+	}
+	envelope.Metadata = maps.Clone(envelope.Metadata)
+	return s.options.BeforeSend(ctx, envelope)
+}
+
+// afterSend calls the AfterSend hook for every message of the batches with
+// the outcome of pushing them. The tenant is taken from the tenant header.
+func (s *valkeySender) afterSend(ctx context.Context, batches []*queueBatch, startTime time.Time, err error) {
+	if s.options.AfterSend == nil {
+		return
+	}
+	for _, batch := range batches {
+		for i := range batch.data {
+			metadata := batch.metadata(i, "", startTime)
+			metadata.Tenant = metadata.Headers[HeaderTenant]
+			s.options.AfterSend(ctx, metadata, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSendHooks(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var outcomes []MessageMetadata
+	var failures []error
+	s := newTestSender(t, server, &SenderOptions{
+		BeforeSend: func(ctx context.Context, envelope *MessageEnvelope) error {
+			if string(envelope.Payload) == "secret" {
+				return errors.New("secrets are not sent")
+			}
+			envelope.Payload = []byte(strings.ToUpper(string(envelope.Payload)))
+			envelope.Headers["hooked"] = "yes"
+			envelope.Metadata["hook"] = "before-send"
+			return nil
+		},
+		AfterSend: func(ctx context.Context, metadata MessageMetadata, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			outcomes = append(outcomes, metadata)
+			failures = append(failures, err)
+		},
+	})
+
+	if err := s.SendMessageForTenant(ctx, "acme", "orders", "a"); err != nil {
+		t.Fatalf("SendMessageForTenant failed: %v", err)
+	}
+	if err := s.SendBatch(ctx, "orders", []interface{}{"b", "c"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 2)
+	if err != nil || len(envelopes) != 2 {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if envelope := envelopes[0]; string(envelope.Payload) != "B" || envelope.Headers["hooked"] != "yes" || envelope.Metadata["hook"] != "before-send" {
+		t.Errorf("Expected the hook's changes on the envelope, got %+v", envelope)
+	}
+	if _, ok := s.metadata["hook"]; ok {
+		t.Error("Expected the shared producer metadata to be left alone")
+	}
+
+	mu.Lock()
+	if len(outcomes) != 3 || outcomes[0].Tenant != "acme" || outcomes[0].MessageID == "" || outcomes[2].Position != 2 || outcomes[2].Size != 1 {
+		t.Errorf("Expected AfterSend for every message, got %+v", outcomes)
+	}
+	for _, err := range failures {
+		if err != nil {
+			t.Errorf("Expected successful outcomes, got %v", err)
+		}
+	}
+	outcomes, failures = nil, nil
+	mu.Unlock()
+
+	// Rejected messages fail the send before anything is pushed
+	err = s.SendBatch(ctx, "orders", []interface{}{"d", "secret"})
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "secrets are not sent") {
+		t.Errorf("Expected the hook's rejection, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected no messages of the rejected batch, got %d", size)
+	}
+
+	// Failed pushes are reported with the send's error
+	server.Close()
+	err = s.SendMessage(ctx, "orders", "e")
+	if err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(outcomes) != 1 || outcomes[0].Queue != "orders" || failures[0] != err {
+		t.Errorf("Expected AfterSend with the send's error, got %+v %v", outcomes, failures)
+	}
+}
@@ -0,0 +1,164 @@
+package valkeysender
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator creates the IDs of message envelopes. Implementations must be
+// safe for concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to the IDGenerator interface
+type IDGeneratorFunc func() string
+
+// NewID calls f
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// uuidGenerator creates random (version 4) UUIDs
+type uuidGenerator struct{}
+
+// NewUUIDGenerator creates the default generator of random (version 4) UUIDs
+func NewUUIDGenerator() IDGenerator {
+	return uuidGenerator{}
+}
+
+// NewID returns a new UUID
+func (uuidGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// crockford is the Crockford base32 alphabet ULIDs are encoded with
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator creates monotonic ULIDs: 26 character IDs that sort by
+// creation time, as a 48-bit millisecond timestamp followed by 80 random
+// bits. IDs created in the same millisecond increment the random bits of
+// the previous one, so the IDs of one generator are strictly increasing
+// even when the clock stands still or goes back.
+type ULIDGenerator struct {
+	mu   sync.Mutex
+	last [16]byte
+}
+
+// NewULIDGenerator creates a monotonic ULID generator
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{}
+}
+
+// NewID returns a new ULID
+func (g *ULIDGenerator) NewID() string {
+	now := uint64(time.Now().UnixMilli())
+
+	g.mu.Lock()
+	lastMs := uint64(g.last[0])<<40 | uint64(binary.BigEndian.Uint32(g.last[1:5]))<<8 | uint64(g.last[5])
+	var id [16]byte
+	if now > lastMs {
+		id[0] = byte(now >> 40)
+		binary.BigEndian.PutUint32(id[1:5], uint32(now>>8))
+		id[5] = byte(now)
+		rand.Read(id[6:])
+	} else {
+		// Same millisecond or the clock went back: increment the previous ID,
+		// carrying into the timestamp if the random bits overflow
+		id = g.last
+		for i := len(id) - 1; i >= 0; i-- {
+			id[i]++
+			if id[i] != 0 {
+				break
+			}
+		}
+	}
+	g.last = id
+	g.mu.Unlock()
+
+	return encodeULID(id)
+}
+
+// encodeULID encodes the 128 bits of a ULID as 26 Crockford base32
+// characters, the first holding the top 3 bits
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// SnowflakeEpoch is the epoch of the timestamps in snowflake IDs
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MaxSnowflakeNode is the highest node ID of a snowflake generator
+const MaxSnowflakeNode = 1<<10 - 1
+
+// SnowflakeGenerator creates snowflake IDs: 63-bit integers, formatted in
+// decimal, made of the milliseconds since SnowflakeEpoch (41 bits), the
+// node ID (10 bits) and a sequence number within the millisecond (12
+// bits). IDs of one node are strictly increasing; generators on different
+// producers need distinct node IDs.
+type SnowflakeGenerator struct {
+	node int64
+	mu   sync.Mutex
+	last int64 // timestamp and sequence of the previous ID
+}
+
+// NewSnowflakeGenerator creates a snowflake generator for a node ID from 0
+// to MaxSnowflakeNode
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node must be between 0 and %d", MaxSnowflakeNode)
+	}
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+// NewID returns a new snowflake ID
+func (g *SnowflakeGenerator) NewID() string {
+	now := time.Since(SnowflakeEpoch).Milliseconds() << 12
+
+	g.mu.Lock()
+	next := g.last + 1
+	if now > g.last {
+		next = now
+	}
+	// An exhausted sequence borrows the next millisecond
+	g.last = next
+	g.mu.Unlock()
+
+	ms, seq := next>>12, next&(1<<12-1)
+	return strconv.FormatInt(ms<<22|g.node<<12|seq, 10)
+}
+
+// sequentialGenerator creates a prefix followed by an increasing counter
+type sequentialGenerator struct {
+	prefix string
+	next   atomic.Uint64
+}
+
+// NewSequentialGenerator creates a generator of IDs made of the prefix and
+// a counter starting at 1, e.g. "order-1", "order-2". The counter starts
+// over with every generator, so the IDs are only unique within a process;
+// they suit tests and consumers numbering their own messages.
+func NewSequentialGenerator(prefix string) IDGenerator {
+	return &sequentialGenerator{prefix: prefix}
+}
+
+// NewID returns the next ID
+func (g *sequentialGenerator) NewID() string {
+	return g.prefix + strconv.FormatUint(g.next.Add(1), 10)
+}
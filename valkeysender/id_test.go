@@ -0,0 +1,144 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestULIDGenerator(t *testing.T) {
+	g := NewULIDGenerator()
+
+	before := time.Now().UnixMilli()
+	id := g.NewID()
+	if len(id) != 26 || strings.Trim(id, crockford) != "" || id[0] > '7' {
+		t.Fatalf("Expected a 26 character Crockford base32 ULID, got %s", id)
+	}
+
+	// The first 10 characters hold the millisecond timestamp
+	var ms int64
+	for _, c := range id[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	if ms < before || ms > time.Now().UnixMilli() {
+		t.Errorf("Expected the ULID timestamp to be now, got %d", ms)
+	}
+
+	previous := id
+	for range 10000 {
+		id := g.NewID()
+		if id <= previous {
+			t.Fatalf("Expected %s to sort after %s", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestULIDGeneratorOverflow(t *testing.T) {
+	g := NewULIDGenerator()
+	g.NewID()
+
+	// A maxed out random part carries into the timestamp
+	g.last = [16]byte{0xff, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if id := g.NewID(); id != "7Z000000020000000000000000" {
+		t.Errorf("Expected the timestamp to be incremented, got %s", id)
+	}
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(MaxSnowflakeNode + 1); err == nil {
+		t.Error("Expected an out of range node to be rejected")
+	}
+
+	g, err := NewSnowflakeGenerator(5)
+	if err != nil {
+		t.Fatalf("NewSnowflakeGenerator failed: %v", err)
+	}
+
+	var previous int64
+	for range 10000 {
+		id, err := strconv.ParseInt(g.NewID(), 10, 64)
+		if err != nil {
+			t.Fatalf("Expected a decimal ID: %v", err)
+		}
+		if id <= previous {
+			t.Fatalf("Expected %d to be greater than %d", id, previous)
+		}
+		if node := id >> 12 & MaxSnowflakeNode; node != 5 {
+			t.Fatalf("Expected node 5 in the ID, got %d", node)
+		}
+		previous = id
+	}
+	if at := SnowflakeEpoch.Add(time.Duration(previous>>22) * time.Millisecond); time.Since(at) > time.Minute {
+		t.Errorf("Expected the ID timestamp to be recent, got %v", at)
+	}
+}
+
+func TestSequentialGenerator(t *testing.T) {
+	g := NewSequentialGenerator("order-")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				id := g.NewID()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 400 || !seen["order-1"] || !seen["order-400"] {
+		t.Errorf("Expected IDs order-1 to order-400, got %d distinct IDs", len(seen))
+	}
+}
+
+func TestSenderIDGenerator(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithIDGenerator(NewSequentialGenerator("msg-")),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	s := sender.(*valkeySender)
+
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	values, _ := server.List(s.getQueueKey("orders"))
+	if len(values) != 2 || s.decodeElement("orders", values[1]).ID != "msg-1" || s.decodeElement("orders", values[0]).ID != "msg-2" {
+		t.Errorf("Expected the generated IDs on the envelopes, got %v", values)
+	}
+
+	// Replaying with new IDs uses the generator too
+	if _, err := s.ReplayWithOptions(ctx, "orders", "retry", ReplayOptions{NewID: true}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	replayed, _ := server.List(s.getQueueKey("retry"))
+	if len(replayed) != 2 {
+		t.Fatalf("Expected 2 replayed messages, got %v", replayed)
+	}
+	for _, value := range replayed {
+		if id := s.decodeElement("retry", value).ID; id != "msg-3" && id != "msg-4" {
+			t.Errorf("Expected a new generated ID on the replayed message, got %s", id)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package valkeysender
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultIngestMaxBodyBytes limits request bodies when IngestConfig.MaxBodyBytes is unset
+const DefaultIngestMaxBodyBytes = 1 << 20 // 1 MiB
+
+// ingestShutdownTimeout bounds how long Close waits for ingest requests in progress
+const ingestShutdownTimeout = 5 * time.Second
+
+// IngestConfig configures IngestHandler
+type IngestConfig struct {
+	// Token required as "Authorization: Bearer <token>". Without a token
+	// every request is refused, unless AllowUnauthenticated is set.
+	Token string
+
+	// AllowUnauthenticated serves requests without a token, e.g. behind a
+	// proxy that authenticates them
+	AllowUnauthenticated bool
+
+	// MaxBodyBytes limits request bodies (0 uses DefaultIngestMaxBodyBytes)
+	MaxBodyBytes int64
+
+	// Queues accepting messages over HTTP (empty allows all)
+	Queues []string
+
+	// Logger receiving the server errors hidden from clients (nil discards them)
+	Logger *slog.Logger
+}
+
+// ingestResponse is the JSON body of every ingest response
+type ingestResponse struct {
+	Queued int    `json:"queued"`
+	Error  string `json:"error,omitempty"`
+}
+
+// IngestHandler returns an http.Handler bridging HTTP to the sender, for
+// webhooks and producers that can't link this library:
+//
+//	POST /queues/{name}/messages        one JSON message, sent with SendMessage
+//	POST /queues/{name}/messages/batch  a JSON array, sent with SendBatch
+//
+// Messages are passed on as raw JSON. An Idempotency-Key header on a single
+// message is used as its idempotency key. Accepted messages are answered
+// with 202 and {"queued": n}, failures with {"error": "..."} and a status
+// reflecting the error class, e.g. 429 when rate limited or 503 while
+// Valkey is unreachable. Server errors are answered with their status
+// text only and logged, so backend details don't reach clients.
+func IngestHandler(sender Sender, config IngestConfig) http.Handler {
+	h := &ingestHandler{sender: sender, config: config}
+	if h.config.MaxBodyBytes <= 0 {
+		h.config.MaxBodyBytes = DefaultIngestMaxBodyBytes
+	}
+	if h.config.Logger == nil {
+		h.config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if len(config.Queues) > 0 {
+		h.queues = make(map[string]bool, len(config.Queues))
+		for _, queue := range config.Queues {
+			h.queues[queue] = true
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /queues/{name}/messages", h.serveMessage)
+	mux.HandleFunc("POST /queues/{name}/messages/batch", h.serveBatch)
+	return h.authenticate(mux)
+}
+
+type ingestHandler struct {
+	sender Sender
+	config IngestConfig
+	queues map[string]bool // allowed queues, nil for all
+}
+
+// authenticate rejects requests without the configured bearer token, and
+// every request if there is no token and authentication is not disabled
+func (h *ingestHandler) authenticate(next http.Handler) http.Handler {
+	if h.config.Token == "" && h.config.AllowUnauthenticated {
+		return next
+	}
+	expected := []byte("Bearer " + h.config.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.config.Token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeIngestResponse(w, http.StatusUnauthorized, ingestResponse{Error: "invalid or missing token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveMessage sends the request body as one message
+func (h *ingestHandler) serveMessage(w http.ResponseWriter, r *http.Request) {
+	queue, body, ok := h.readRequest(w, r)
+	if !ok {
+		return
+	}
+	if !json.Valid(body) {
+		writeIngestResponse(w, http.StatusBadRequest, ingestResponse{Error: "body is not valid JSON"})
+		return
+	}
+
+	var err error
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		err = h.sender.SendMessageWithOptions(r.Context(), queue, json.RawMessage(body), SendOptions{IdempotencyKey: key})
+	} else {
+		err = h.sender.SendMessage(r.Context(), queue, json.RawMessage(body))
+	}
+	if err != nil {
+		h.writeError(w, queue, err)
+		return
+	}
+	writeIngestResponse(w, http.StatusAccepted, ingestResponse{Queued: 1})
+}
+
+// serveBatch sends the elements of the JSON array in the request body
+func (h *ingestHandler) serveBatch(w http.ResponseWriter, r *http.Request) {
+	queue, body, ok := h.readRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(body, &elements); err != nil {
+		writeIngestResponse(w, http.StatusBadRequest, ingestResponse{Error: "body must be a JSON array"})
+		return
+	}
+	if len(elements) == 0 {
+		writeIngestResponse(w, http.StatusBadRequest, ingestResponse{Error: "batch cannot be empty"})
+		return
+	}
+
+	messages := make([]interface{}, len(elements))
+	for i, element := range elements {
+		messages[i] = element
+	}
+	if err := h.sender.SendBatch(r.Context(), queue, messages); err != nil {
+		h.writeError(w, queue, err)
+		return
+	}
+	writeIngestResponse(w, http.StatusAccepted, ingestResponse{Queued: len(messages)})
+}
+
+// readRequest checks the queue and reads the body, answering the request
+// itself if either is unacceptable
+func (h *ingestHandler) readRequest(w http.ResponseWriter, r *http.Request) (string, []byte, bool) {
+	queue := r.PathValue("name")
+	if h.queues != nil && !h.queues[queue] {
+		writeIngestResponse(w, http.StatusForbidden, ingestResponse{Error: fmt.Sprintf("queue %q does not accept messages over HTTP", queue)})
+		return "", nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeIngestResponse(w, http.StatusRequestEntityTooLarge, ingestResponse{Error: fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit)})
+		} else {
+			writeIngestResponse(w, http.StatusBadRequest, ingestResponse{Error: "failed to read body"})
+		}
+		return "", nil, false
+	}
+	return queue, body, true
+}
+
+// writeError answers with the status matching the class of a send error.
+// Client errors carry the error text; server errors only their status text,
+// and are logged instead.
+func (h *ingestHandler) writeError(w http.ResponseWriter, queue string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrSerialization), errors.Is(err, ErrInvalidQueue):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrMessageTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrNoRoute):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, ErrTimeout):
+		status = http.StatusGatewayTimeout
+	case IsRetryable(err), errors.Is(err, ErrClosed):
+		status = http.StatusServiceUnavailable
+	}
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	if status >= http.StatusInternalServerError {
+		h.config.Logger.Warn("HTTP ingestion failed",
+			slog.String("queue", queue),
+			slog.Int("status", status),
+			slog.Any("error", err),
+		)
+		writeIngestResponse(w, status, ingestResponse{Error: strings.ToLower(http.StatusText(status))})
+		return
+	}
+	writeIngestResponse(w, status, ingestResponse{Error: err.Error()})
+}
+
+// writeIngestResponse writes a JSON response
+func writeIngestResponse(w http.ResponseWriter, status int, response ingestResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ingestConfig returns the IngestHandler settings of the sender's configuration
+func (c *Config) ingestConfig() IngestConfig {
+	return IngestConfig{
+		Token:                c.IngestToken,
+		AllowUnauthenticated: c.IngestAllowUnauthenticated,
+		MaxBodyBytes:         c.IngestMaxBodyBytes,
+		Queues:               c.IngestQueues,
+	}
+}
+
+// startIngestServer serves IngestHandler on Config.IngestAddress if set.
+// The address is bound right away, so a port in use fails NewSender, and
+// so does a missing token unless unauthenticated ingestion is allowed.
+func (s *valkeySender) startIngestServer() error {
+	if s.config.IngestAddress == "" {
+		return nil
+	}
+	if s.config.IngestToken == "" && !s.config.IngestAllowUnauthenticated {
+		return fmt.Errorf("HTTP ingestion requires a token; set IngestAllowUnauthenticated to serve it without one")
+	}
+
+	listener, err := net.Listen("tcp", s.config.IngestAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTP ingestion: %w", err)
+	}
+	ingestConfig := s.config.ingestConfig()
+	ingestConfig.Logger = s.logger
+	s.ingestServer = &http.Server{
+		Handler:           IngestHandler(s, ingestConfig),
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+	}
+	s.ingestAddr = listener.Addr()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.ingestServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("HTTP ingestion server failed", slog.Any("error", err))
+		}
+	}()
+
+	s.logger.Info("HTTP ingestion server listening", slog.String("address", listener.Addr().String()))
+	return nil
+}
+
+// stopIngestServer stops accepting HTTP requests and waits for those in progress
+func (s *valkeySender) stopIngestServer() {
+	if s.ingestServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ingestShutdownTimeout)
+	defer cancel()
+	if err := s.ingestServer.Shutdown(ctx); err != nil {
+		s.logger.Warn("HTTP ingestion server did not shut down cleanly", slog.Any("error", err))
+	}
+}
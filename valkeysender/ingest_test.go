@@ -0,0 +1,223 @@
+package valkeysender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// ingest posts body to the handler and returns the status and decoded response
+func ingest(t *testing.T, handler http.Handler, path, token, body string, header ...string) (int, ingestResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response ingestResponse
+	if rec.Header().Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, response
+}
+
+func TestIngestHandler(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	handler := IngestHandler(s, IngestConfig{Token: "secret", MaxBodyBytes: 64, Queues: []string{"webhooks"}})
+
+	if code, response := ingest(t, handler, "/queues/webhooks/messages", "secret", `{"event":"paid"}`); code != http.StatusAccepted || response.Queued != 1 {
+		t.Fatalf("Expected message to be accepted, got %d %+v", code, response)
+	}
+	if code, response := ingest(t, handler, "/queues/webhooks/messages/batch", "secret", `[1, "two", {"n":3}]`); code != http.StatusAccepted || response.Queued != 3 {
+		t.Fatalf("Expected batch to be accepted, got %d %+v", code, response)
+	}
+
+	envelopes, err := s.PeekMessages(ctx, "webhooks", 0, 10)
+	if err != nil {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if len(envelopes) != 4 || string(envelopes[0].Payload) != `{"event":"paid"}` {
+		t.Fatalf("Expected the raw JSON messages to be queued, got %d messages", len(envelopes))
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"missing token", "/queues/webhooks/messages", "", `{}`, http.StatusUnauthorized},
+		{"wrong token", "/queues/webhooks/messages", "guess", `{}`, http.StatusUnauthorized},
+		{"queue not allowed", "/queues/payments/messages", "secret", `{}`, http.StatusForbidden},
+		{"invalid JSON", "/queues/webhooks/messages", "secret", `{"event":`, http.StatusBadRequest},
+		{"batch not an array", "/queues/webhooks/messages/batch", "secret", `{}`, http.StatusBadRequest},
+		{"empty batch", "/queues/webhooks/messages/batch", "secret", `[]`, http.StatusBadRequest},
+		{"body too large", "/queues/webhooks/messages", "secret", `"` + strings.Repeat("x", 100) + `"`, http.StatusRequestEntityTooLarge},
+		{"unknown path", "/queues/webhooks", "secret", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := ingest(t, handler, tt.path, tt.token, tt.body); code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, code)
+			}
+		})
+	}
+
+	if size, _ := s.GetQueueSize(ctx, "webhooks"); size != 4 {
+		t.Errorf("Expected rejected requests not to queue messages, got size %d", size)
+	}
+}
+
+func TestIngestIdempotencyKey(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	handler := IngestHandler(s, IngestConfig{AllowUnauthenticated: true})
+
+	for i := 0; i < 2; i++ {
+		if code, _ := ingest(t, handler, "/queues/webhooks/messages", "", `{"delivery":1}`, "Idempotency-Key", "delivery-1"); code != http.StatusAccepted {
+			t.Fatalf("Expected redelivered webhook to be accepted, got %d", code)
+		}
+	}
+	if size, _ := s.GetQueueSize(ctx, "webhooks"); size != 1 {
+		t.Errorf("Expected redelivered webhook to be queued once, got size %d", size)
+	}
+}
+
+func TestIngestErrorStatus(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, nil)
+	s.config.MaxQueueLength = 1
+	s.config.OverflowPolicy = OverflowPolicyReject
+	handler := IngestHandler(s, IngestConfig{AllowUnauthenticated: true})
+
+	if code, _ := ingest(t, handler, "/queues/webhooks/messages/batch", "", `[1, 2]`); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected full queue to answer 503, got %d", code)
+	}
+
+	s.config.MaxMessageBytes = 100
+	if code, _ := ingest(t, handler, "/queues/events/messages", "", `"`+strings.Repeat("x", 200)+`"`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a message over the limit to answer 413, got %d", code)
+	}
+}
+
+// failingSender fails every send with err
+type failingSender struct {
+	Sender
+	err error
+}
+
+func (f failingSender) SendMessage(context.Context, string, interface{}) error {
+	return f.err
+}
+
+func TestIngestServerErrorsHidden(t *testing.T) {
+	internal := errors.New("dial tcp 10.0.0.5:6379: connect: connection refused")
+	for _, tt := range []struct {
+		err     error
+		status  int
+		message string
+	}{
+		{internal, http.StatusInternalServerError, "internal server error"},
+		{&Error{Op: opSendMessage, Queue: "webhooks", Kind: ErrNotConnected, Err: internal}, http.StatusServiceUnavailable, "service unavailable"},
+		{&Error{Op: opSendMessage, Queue: "webhooks", Kind: ErrValidation, Err: errors.New("missing field id")}, http.StatusBadRequest, ""},
+	} {
+		handler := IngestHandler(failingSender{err: tt.err}, IngestConfig{AllowUnauthenticated: true})
+		code, response := ingest(t, handler, "/queues/webhooks/messages", "", `{}`)
+		if code != tt.status {
+			t.Errorf("Expected status %d for %v, got %d", tt.status, tt.err, code)
+		}
+		if tt.message != "" && response.Error != tt.message {
+			t.Errorf("Expected %q for a server error, got %q", tt.message, response.Error)
+		}
+		if tt.message == "" && !strings.Contains(response.Error, "missing field id") {
+			t.Errorf("Expected the client error to be reported, got %q", response.Error)
+		}
+	}
+}
+
+func TestIngestRequiresToken(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+
+	// Without a token the handler refuses every request
+	handler := IngestHandler(s, IngestConfig{})
+	if code, _ := ingest(t, handler, "/queues/webhooks/messages", "", `{}`); code != http.StatusUnauthorized {
+		t.Errorf("Expected requests to be refused without a configured token, got %d", code)
+	}
+	if size, _ := s.GetQueueSize(ctx, "webhooks"); size != 0 {
+		t.Errorf("Expected nothing to be queued, got size %d", size)
+	}
+
+	// And the sender does not start the server without one
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.IngestAddress = "127.0.0.1:0"
+	if _, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}); err == nil || !strings.Contains(err.Error(), "requires a token") {
+		t.Fatalf("Expected NewSender to refuse unauthenticated ingestion, got %v", err)
+	}
+
+	config.IngestAllowUnauthenticated = true
+	sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("Expected the explicit opt-out to start the server, got %v", err)
+	}
+	sender.Close()
+}
+
+func TestIngestServer(t *testing.T) {
+	server := miniredis.RunT(t)
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.IngestAddress = "127.0.0.1:0"
+	config.IngestToken = "secret"
+
+	sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+	s := sender.(*valkeySender)
+
+	url := "http://" + s.ingestAddr.String() + "/queues/webhooks/messages"
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"event":"paid"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected message to be accepted, got %d", resp.StatusCode)
+	}
+	if size, _ := sender.GetQueueSize(context.Background(), "webhooks"); size != 1 {
+		t.Errorf("Expected message to be queued, got size %d", size)
+	}
+
+	sender.Close()
+	if _, err := http.Post(url, "application/json", strings.NewReader(`{}`)); err == nil {
+		t.Error("Expected ingest server to stop with the sender")
+	}
+}
@@ -0,0 +1,185 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// opSendLingered is the operation of a flushed linger window, reported in
+// statsd and slow send warnings; callers see the operation they started
+const opSendLingered = "send lingered messages"
+
+// lingerWindow collects single-message sends until Config.LingerInterval
+// has passed since the first one or Config.LingerMaxMessages have joined.
+// Windows are flushed one at a time, so they reach Valkey in order, and
+// sends arriving during a flush join the next window.
+type lingerWindow struct {
+	mu      sync.Mutex
+	pending []*lingerSend
+	timer   *time.Timer // flushes the window, nil while it is empty
+
+	flushing sync.Mutex // held while a window is pushed
+}
+
+// lingerSend is a send waiting in the window for its flush
+type lingerSend struct {
+	batch    *queueBatch
+	complete func(err error) // called with the result of the flush
+}
+
+// linger adds a single-message batch to the linger window; complete is
+// called with the result once the window was pushed. The send is
+// registered with Drain and takes its rate limit token right away, so
+// while draining or in reject rate limit mode linger fails at once and
+// complete is never called.
+func (s *valkeySender) linger(ctx context.Context, op, queue string, batch *queueBatch, complete func(error)) error {
+	_, done, err := s.beginSend(ctx, 1)
+	if err != nil {
+		return classifyError(op, queue, err)
+	}
+	if err := s.acquireRateLimit(ctx, queue); err != nil {
+		done(err)
+		return classifyError(op, queue, err)
+	}
+
+	s.addLingering(&lingerSend{batch: batch, complete: func(err error) {
+		err = lingerError(op, queue, err)
+		done(err)
+		complete(err)
+	}})
+	return nil
+}
+
+// lingerAndWait adds a batch to the linger window and waits for its push,
+// or until ctx is done, in which case the message may still be pushed
+func (s *valkeySender) lingerAndWait(ctx context.Context, op, queue string, batch *queueBatch) error {
+	result := make(chan error, 1)
+	if err := s.linger(ctx, op, queue, batch, func(err error) { result <- err }); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+	}
+	select {
+	case err := <-result:
+		return err
+	default:
+		return classifyError(op, queue, ctx.Err())
+	}
+}
+
+// addLingering adds a send to the window, flushing the window once full
+func (s *valkeySender) addLingering(send *lingerSend) {
+	w := &s.lingerWindow
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, send)
+	switch {
+	case s.config.LingerMaxMessages > 0 && len(w.pending) == s.config.LingerMaxMessages:
+		go s.flushLingerWindow()
+	case w.timer == nil:
+		w.timer = time.AfterFunc(s.config.LingerInterval, s.flushLingerWindow)
+	}
+}
+
+// take empties the window and returns its sends; the caller must hold the lock
+func (w *lingerWindow) take() []*lingerSend {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	pending := w.pending
+	w.pending = nil
+	return pending
+}
+
+// flushLingerWindow waits for the flush in progress, if any, and pushes
+// the sends waiting in the window
+func (s *valkeySender) flushLingerWindow() {
+	s.lingerWindow.flushing.Lock()
+	defer s.lingerWindow.flushing.Unlock()
+
+	s.lingerWindow.mu.Lock()
+	pending := s.lingerWindow.take()
+	s.lingerWindow.mu.Unlock()
+	s.flushLingering(pending)
+}
+
+// flushLingering pushes the batches of the sends in one pipeline and
+// passes the result to every send. The pipeline is not atomic: when it
+// fails, some of the messages may have been stored.
+func (s *valkeySender) flushLingering(sends []*lingerSend) {
+	if len(sends) == 0 {
+		return
+	}
+
+	batches := make([]*queueBatch, len(sends))
+	for i, send := range sends {
+		batches[i] = send.batch
+	}
+	err := s.guardSend(s.ctx, opSendLingered, "", batches, true, func(ctx context.Context) error {
+		return s.pushBatches(ctx, batches, false)
+	})
+	for _, send := range sends {
+		send.complete(err)
+	}
+}
+
+// closeLingerWindow fails the sends still waiting in the window
+func (s *valkeySender) closeLingerWindow() {
+	s.lingerWindow.mu.Lock()
+	pending := s.lingerWindow.take()
+	s.lingerWindow.mu.Unlock()
+	for _, send := range pending {
+		send.complete(&Error{Kind: ErrClosed})
+	}
+}
+
+// lingerError reports a failed flush as a failure of the operation and
+// queue of one of its sends, nil if the flush succeeded
+func lingerError(op, queue string, err error) error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return &Error{Op: op, Queue: queue, Kind: typed.Kind, Err: typed.Err}
+	}
+	return classifyError(op, queue, err)
+}
+
+// SendAsync sends a message like SendMessage without waiting for it. The
+// message is encoded, validated and rate limited before SendAsync returns;
+// the returned channel receives the result, with the message metadata on
+// success. Once SendAsync returned, the send is no longer bound by ctx but
+// completes with its window, within Config.SendTimeout.
+//
+// Asynchronous sends always go through the linger window, with an interval
+// of 0 unless Config.LingerInterval is set: messages sent while a window is
+// being pushed are pushed together in the next one, so many concurrent sends
+// need neither a goroutine nor a round trip each. Messages sent from one
+// goroutine keep their order.
+func (s *valkeySender) SendAsync(ctx context.Context, queue string, message interface{}) <-chan MessageResult {
+	result := make(chan MessageResult, 1)
+	startTime := time.Now()
+
+	batch, err := s.newOneBatch(ctx, opSendMessage, "", queue, message, SendOptions{TTL: s.messageTTL(queue)})
+	if err == nil {
+		err = s.linger(ctx, opSendMessage, queue, batch, func(err error) {
+			sent := MessageResult{Success: err == nil, Error: err, Duration: time.Since(startTime)}
+			if err == nil {
+				s.sentOne(batch, "", startTime)
+				metadata := batch.metadata(0, "", startTime)
+				sent.Metadata = &metadata
+			}
+			result <- sent
+		})
+	}
+	if err != nil {
+		result <- MessageResult{Error: err, Duration: time.Since(startTime)}
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newLingerSender creates a sender with a linger window
+func newLingerSender(t *testing.T, server *miniredis.Miniredis, interval time.Duration, maxMessages int) *valkeySender {
+	t.Helper()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithLinger(interval, maxMessages),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender)
+}
+
+func TestLinger(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newLingerSender(t, server, 200*time.Millisecond, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.SendMessage(ctx, "telemetry", i)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+
+	if size, _ := s.GetQueueSize(ctx, "telemetry"); size != 20 {
+		t.Errorf("Expected 20 messages, got %d", size)
+	}
+	if pushes := s.sendLatency.snapshot().count; pushes != 1 {
+		t.Errorf("Expected the sends to share one push, got %d", pushes)
+	}
+	if sent := s.Metrics().MessagesSent; sent != 20 {
+		t.Errorf("Expected every message to be counted, got %d", sent)
+	}
+}
+
+func TestLingerMaxMessages(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newLingerSender(t, server, time.Hour, 5)
+
+	results := make([]<-chan MessageResult, 5)
+	for i := range results {
+		results[i] = s.SendAsync(ctx, "telemetry", i)
+	}
+	for _, result := range results {
+		select {
+		case sent := <-result:
+			if sent.Error != nil {
+				t.Fatalf("SendAsync failed: %v", sent.Error)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a full window to be flushed at once")
+		}
+	}
+
+	// Oldest first from the consuming end
+	envelopes, err := s.PeekMessages(ctx, "telemetry", 0, 10)
+	if err != nil || len(envelopes) != 5 {
+		t.Fatalf("Expected 5 messages, got %d, %v", len(envelopes), err)
+	}
+	for i, envelope := range envelopes {
+		if string(envelope.Payload) != string('0'+rune(i)) {
+			t.Errorf("Expected message %d in order, got %s", i, envelope.Payload)
+		}
+	}
+}
+
+func TestLingerClose(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newLingerSender(t, server, time.Hour, 0)
+
+	result := s.SendAsync(context.Background(), "telemetry", "pending")
+	s.Close()
+	if sent := <-result; !errors.Is(sent.Error, ErrClosed) || sent.Success {
+		t.Errorf("Expected a lingering send to fail with ErrClosed, got %v", sent.Error)
+	}
+}
+
+func TestLingerDrain(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newLingerSender(t, server, 50*time.Millisecond, 0)
+
+	result := s.SendAsync(ctx, "telemetry", "lingering")
+	if dropped, err := s.Drain(ctx); dropped != 0 || err != nil {
+		t.Fatalf("Drain failed: %d, %v", dropped, err)
+	}
+	if sent := <-result; sent.Error != nil {
+		t.Errorf("Expected Drain to wait for the window, got %v", sent.Error)
+	}
+	if err := s.SendMessage(ctx, "telemetry", "late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected sends after Drain to be refused, got %v", err)
+	}
+}
+
+func TestSendAsync(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+
+	sent := <-s.SendAsync(ctx, "orders", "o1")
+	if !sent.Success || sent.Error != nil {
+		t.Fatalf("SendAsync failed: %v", sent.Error)
+	}
+	if sent.Metadata == nil || sent.Metadata.MessageID == "" || sent.Metadata.Queue != "orders" {
+		t.Errorf("Expected the message metadata in the result, got %+v", sent.Metadata)
+	}
+	if sent := <-s.SendAsync(ctx, "orders", make(chan int)); !errors.Is(sent.Error, ErrSerialization) {
+		t.Errorf("Expected the encoding error on the channel, got %v", sent.Error)
+	}
+
+	// Without a linger interval, sends issued while a push is in flight
+	// still share the next one, and keep their order
+	results := make([]<-chan MessageResult, 50)
+	for i := range results {
+		results[i] = s.SendAsync(ctx, "events", i)
+	}
+	for _, result := range results {
+		if sent := <-result; sent.Error != nil {
+			t.Fatalf("SendAsync failed: %v", sent.Error)
+		}
+	}
+	envelopes, err := s.PeekMessages(ctx, "events", 0, 100)
+	if err != nil || len(envelopes) != 50 {
+		t.Fatalf("Expected 50 messages, got %d, %v", len(envelopes), err)
+	}
+	for i, envelope := range envelopes {
+		if string(envelope.Payload) != strconv.Itoa(i) {
+			t.Fatalf("Expected message %d in order, got %s", i, envelope.Payload)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Logger is the logging interface accepted by SenderOptions.Logger. args are
+// alternating keys and values, as passed to slog. *slog.Logger implements it
+// directly; ZapLogger and LoggerFunc adapt other logging libraries.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// ZapSugaredLogger is the subset of *zap.SugaredLogger used by ZapLogger
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// ZapLogger adapts a zap sugared logger, e.g. ZapLogger(zapLogger.Sugar())
+func ZapLogger(logger ZapSugaredLogger) Logger {
+	return zapLogger{logger}
+}
+
+type zapLogger struct {
+	logger ZapSugaredLogger
+}
+
+func (z zapLogger) Debug(msg string, args ...any) { z.logger.Debugw(msg, args...) }
+func (z zapLogger) Info(msg string, args ...any)  { z.logger.Infow(msg, args...) }
+func (z zapLogger) Warn(msg string, args ...any)  { z.logger.Warnw(msg, args...) }
+func (z zapLogger) Error(msg string, args ...any) { z.logger.Errorw(msg, args...) }
+
+// LoggerFunc adapts a function receiving each entry with its fields, for
+// loggers with a builder style API such as zerolog:
+//
+//	valkeysender.LoggerFunc(func(level slog.Level, msg string, fields map[string]any) {
+//		zl.WithLevel(zerolog.Level((level + 4) / 4)).Fields(fields).Msg(msg)
+//	})
+type LoggerFunc func(level slog.Level, msg string, fields map[string]any)
+
+func (f LoggerFunc) Debug(msg string, args ...any) { f.log(slog.LevelDebug, msg, args) }
+func (f LoggerFunc) Info(msg string, args ...any)  { f.log(slog.LevelInfo, msg, args) }
+func (f LoggerFunc) Warn(msg string, args ...any)  { f.log(slog.LevelWarn, msg, args) }
+func (f LoggerFunc) Error(msg string, args ...any) { f.log(slog.LevelError, msg, args) }
+
+func (f LoggerFunc) log(level slog.Level, msg string, args []any) {
+	fields := make(map[string]any, len(args)/2)
+	for _, attr := range attrsFromArgs(args) {
+		fields[attr.Key] = attr.Value.Resolve().Any()
+	}
+	f(level, msg, fields)
+}
+
+// attrsFromArgs turns slog style arguments into attributes, the way
+// slog.Record.Add does
+func attrsFromArgs(args []any) []slog.Attr {
+	var record slog.Record
+	record.Add(args...)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return attrs
+}
+
+// slogLogger returns logger as a *slog.Logger, bridging other
+// implementations through loggerHandler
+func slogLogger(logger Logger) *slog.Logger {
+	if l, ok := logger.(*slog.Logger); ok {
+		return l
+	}
+	return slog.New(&loggerHandler{logger: logger})
+}
+
+// loggerHandler is a slog.Handler writing to a Logger. Level filtering is
+// left to the Logger; groups are flattened into dotted keys and secrets are
+// masked as by RedactAttr.
+type loggerHandler struct {
+	logger Logger
+	attrs  []any
+	group  string
+}
+
+func (h *loggerHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *loggerHandler) Handle(_ context.Context, record slog.Record) error {
+	args := append([]any(nil), h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		args = h.appendAttr(args, h.group, attr)
+		return true
+	})
+
+	switch {
+	case record.Level >= slog.LevelError:
+		h.logger.Error(record.Message, args...)
+	case record.Level >= slog.LevelWarn:
+		h.logger.Warn(record.Message, args...)
+	case record.Level >= slog.LevelInfo:
+		h.logger.Info(record.Message, args...)
+	default:
+		h.logger.Debug(record.Message, args...)
+	}
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]any(nil), h.attrs...)
+	for _, attr := range attrs {
+		next.attrs = h.appendAttr(next.attrs, h.group, attr)
+	}
+	return &next
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.group = joinGroup(h.group, name)
+	return &next
+}
+
+// appendAttr appends attr as a key and value, flattening groups
+func (h *loggerHandler) appendAttr(args []any, group string, attr slog.Attr) []any {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		group = joinGroup(group, attr.Key)
+		for _, member := range value.Group() {
+			args = h.appendAttr(args, group, member)
+		}
+		return args
+	}
+	if attr.Key == "" {
+		return args
+	}
+	if isSensitiveKey(attr.Key) {
+		value = slog.StringValue(redactedValue)
+	}
+	return append(args, joinGroup(group, attr.Key), value.Any())
+}
+
+func joinGroup(group, key string) string {
+	if group == "" || key == "" {
+		return group + key
+	}
+	return group + "." + key
+}
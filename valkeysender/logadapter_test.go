@@ -0,0 +1,73 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// recordingZap records calls the way *zap.SugaredLogger receives them
+type recordingZap struct {
+	entries []string
+}
+
+func (r *recordingZap) log(level, msg string, kv []interface{}) {
+	r.entries = append(r.entries, fmt.Sprint(level, " ", msg, " ", kv))
+}
+
+func (r *recordingZap) Debugw(msg string, kv ...interface{}) { r.log("debug", msg, kv) }
+func (r *recordingZap) Infow(msg string, kv ...interface{})  { r.log("info", msg, kv) }
+func (r *recordingZap) Warnw(msg string, kv ...interface{})  { r.log("warn", msg, kv) }
+func (r *recordingZap) Errorw(msg string, kv ...interface{}) { r.log("error", msg, kv) }
+
+func TestZapLogger(t *testing.T) {
+	zap := &recordingZap{}
+	logger := slogLogger(ZapLogger(zap))
+	
+	logger.With("component", "valkeysender").WithGroup("send").Warn("Slow send",
+		slog.String("queue", "orders"),
+		slog.Group("batch", slog.Int("size", 2)),
+	)
+	logger.Debug("Pushed")
+	
+	want := []string{
+		"warn Slow send [component valkeysender send.queue orders send.batch.size 2]",
+		"debug Pushed []",
+	}
+	if !reflect.DeepEqual(zap.entries, want) {
+		t.Errorf("Expected %q, got %q", want, zap.entries)
+	}
+}
+
+func TestLoggerFunc(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	var fields []map[string]any
+	s := newTestSender(t, server, &SenderOptions{
+		Logger: LoggerFunc(func(level slog.Level, msg string, f map[string]any) {
+			if level == slog.LevelWarn {
+				fields = append(fields, f)
+			}
+		}),
+	})
+	s.config.SlowSendThreshold = 1
+	
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if len(fields) != 1 || fields[0]["queue"] != "orders" || fields[0]["message_count"] != int64(1) {
+		t.Errorf("Expected one slow send warning with fields, got %v", fields)
+	}
+}
+
+func TestSlogLoggerPassthrough(t *testing.T) {
+	logger := slog.Default()
+	if slogLogger(logger) != logger {
+		t.Error("Expected a *slog.Logger to be used as is")
+	}
+}
@@ -0,0 +1,199 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Log formats for Config.LogFormat
+const (
+	// LogFormatJSON writes one JSON object per entry, for log collectors
+	LogFormatJSON = "json"
+
+	// LogFormatText writes slog's key=value text format
+	LogFormatText = "text"
+
+	// LogFormatConsole writes short colorized lines for local development;
+	// files and writers get the text format. Colors are disabled when the
+	// NO_COLOR environment variable is set.
+	LogFormatConsole = "console"
+)
+
+// ANSI escape sequences used by the console format
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiGray   = "\033[90m"
+)
+
+// newFormatHandler returns a handler writing entries to out in the given
+// format. color only applies to the console format.
+func newFormatHandler(format string, out io.Writer, opts *slog.HandlerOptions, color bool) slog.Handler {
+	switch strings.ToLower(format) {
+	case LogFormatText:
+		return slog.NewTextHandler(out, opts)
+	case LogFormatConsole:
+		if !color {
+			return slog.NewTextHandler(out, opts)
+		}
+		return newConsoleHandler(out, opts, os.Getenv("NO_COLOR") == "")
+	default:
+		return slog.NewJSONHandler(out, opts)
+	}
+}
+
+// consoleHandler writes entries as "15:04:05.000 INF message key=value"
+type consoleHandler struct {
+	out   io.Writer
+	mu    *sync.Mutex
+	opts  slog.HandlerOptions
+	color bool
+	attrs string // preformatted attributes from WithAttrs
+	group string // dotted prefix from WithGroup
+}
+
+func newConsoleHandler(out io.Writer, opts *slog.HandlerOptions, color bool) *consoleHandler {
+	h := &consoleHandler{out: out, mu: &sync.Mutex{}, color: color}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minimum := slog.LevelInfo
+	if h.opts.Level != nil {
+		minimum = h.opts.Level.Level()
+	}
+	return level >= minimum
+}
+
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+
+	timestamp := record.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	line.WriteString(h.paint(ansiGray, timestamp.Format("15:04:05.000")))
+	line.WriteByte(' ')
+	line.WriteString(h.levelLabel(record.Level))
+	line.WriteByte(' ')
+	line.WriteString(record.Message)
+	line.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		h.appendAttr(&line, h.group, attr)
+		return true
+	})
+	line.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, line.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var formatted strings.Builder
+	for _, attr := range attrs {
+		h.appendAttr(&formatted, h.group, attr)
+	}
+	next := *h
+	next.attrs += formatted.String()
+	return &next
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.group = joinGroup(h.group, name)
+	return &next
+}
+
+// appendAttr writes " key=value", flattening groups and applying ReplaceAttr
+func (h *consoleHandler) appendAttr(line *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		group = joinGroup(group, attr.Key)
+		for _, member := range attr.Value.Group() {
+			h.appendAttr(line, group, member)
+		}
+		return
+	}
+	if h.opts.ReplaceAttr != nil {
+		var groups []string
+		if group != "" {
+			groups = strings.Split(group, ".")
+		}
+		attr = h.opts.ReplaceAttr(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	if attr.Key == "" {
+		return
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(h.paint(ansiDim, joinGroup(group, attr.Key)+"="))
+	line.WriteString(consoleValue(attr.Value))
+}
+
+// levelLabel returns the three letter, colored label of a level
+func (h *consoleHandler) levelLabel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return h.paint(ansiRed, "ERR")
+	case level >= slog.LevelWarn:
+		return h.paint(ansiYellow, "WRN")
+	case level >= slog.LevelInfo:
+		return h.paint(ansiGreen, "INF")
+	default:
+		return h.paint(ansiGray, "DBG")
+	}
+}
+
+// paint wraps s in an ANSI color if colors are enabled
+func (h *consoleHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// consoleValue formats a value, quoting strings that would be ambiguous
+func consoleValue(value slog.Value) string {
+	var s string
+	switch value.Kind() {
+	case slog.KindString:
+		s = value.String()
+	case slog.KindTime:
+		s = value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprint(value.Any())
+		}
+	default:
+		s = value.String()
+	}
+
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '=' || r == '"' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package valkeysender
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var out bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: RedactAttr}
+	logger := slog.New(newConsoleHandler(&out, opts, false))
+	
+	logger.With("component", "valkeysender").WithGroup("send").Warn("Slow send",
+		slog.String("queue", "orders"),
+		slog.String("password", "hunter2"),
+		slog.Group("batch", slog.Int("size", 2)),
+		slog.Any("error", errors.New("connection refused")),
+	)
+	logger.Debug("Pushed", slog.String("note", ""))
+	
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		`WRN Slow send component=valkeysender send.queue=orders send.password=REDACTED send.batch.size=2 send.error="connection refused"`,
+		`DBG Pushed note=""`,
+	}
+	stamp := regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3} `)
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %q", len(want), lines)
+	}
+	for i, line := range lines {
+		if !stamp.MatchString(line) || stamp.ReplaceAllString(line, "") != want[i] {
+			t.Errorf("Line %d: expected %q after the time, got %q", i, want[i], line)
+		}
+	}
+}
+
+func TestConsoleHandlerColors(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(newConsoleHandler(&out, nil, true))
+	
+	logger.Debug("hidden")
+	logger.Error("Send failed", slog.String("queue", "orders"))
+	
+	logged := out.String()
+	if strings.Contains(logged, "hidden") {
+		t.Errorf("Expected DEBUG to be filtered at the default level, got %q", logged)
+	}
+	if !strings.Contains(logged, ansiRed+"ERR"+ansiReset) || !strings.Contains(logged, ansiDim+"queue="+ansiReset+"orders") {
+		t.Errorf("Expected colorized output, got %q", logged)
+	}
+}
+
+func TestLogFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", `"msg":"hello"`},
+		{LogFormatJSON, `"msg":"hello"`},
+		{LogFormatText, `msg=hello`},
+		{LogFormatConsole, `msg=hello`}, // writers get plain text
+	}
+	
+	for _, tt := range tests {
+		var out bytes.Buffer
+		logger, err := NewLoggerWithOptions(LogOptions{Format: tt.format, Writer: &out})
+		if err != nil {
+			t.Fatalf("NewLoggerWithOptions(%q) failed: %v", tt.format, err)
+		}
+		logger.Info("hello")
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("Format %q: expected %s in %q", tt.format, tt.want, out.String())
+		}
+	}
+}
@@ -2,43 +2,77 @@ package valkeysender
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
 )
 
-// NewLogger creates a new structured logger for valkeysender
-func NewLogger(level slog.Level, logFilePath string) (*slog.Logger, error) {
-	var handler slog.Handler
+// LogOptions configures NewLoggerWithOptions
+type LogOptions struct {
+	// Level is the minimum level logged, fixed or a *slog.LevelVar to change
+	// it at runtime (nil logs INFO and above)
+	Level slog.Leveler
+	
+	// Format is LogFormatJSON (default), LogFormatText or LogFormatConsole
+	Format string
 	
-	// Create JSON handler for structured logging
+	// File, if set, also receives every entry and is rotated according to
+	// Rotation; the zero value rotates at DefaultLogMaxSize and keeps every
+	// backup
+	File     string
+	Rotation RotationOptions
+	
+	// Writer, if set, also receives every entry, e.g. a lumberjack.Logger
+	Writer io.Writer
+}
+
+// NewLogger creates a new structured logger for valkeysender. If
+// logFilePath is set, entries are also appended to that file, rotated with
+// DefaultRotationOptions.
+func NewLogger(level slog.Level, logFilePath string) (*slog.Logger, error) {
+	return NewLoggerWithOptions(LogOptions{
+		Level:    level,
+		File:     logFilePath,
+		Rotation: DefaultRotationOptions(),
+	})
+}
+
+// NewLoggerWithOptions creates a structured logger writing to stdout and to
+// the configured file and writer, as JSON unless another format is set
+func NewLoggerWithOptions(options LogOptions) (*slog.Logger, error) {
+	// Create handlers in the configured format for structured logging
 	opts := &slog.HandlerOptions{
-		Level: level,
+		Level: options.Level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			// Add component identifier to all log entries
 			if a.Key == slog.SourceKey {
 				return slog.Attr{}
 			}
-			return a
+			// Never emit credentials
+			return RedactAttr(groups, a)
 		},
 	}
 	
 	// Always log to stdout for container environments
-	handler = slog.NewJSONHandler(os.Stdout, opts)
+	handlers := []slog.Handler{newFormatHandler(options.Format, os.Stdout, opts, true)}
 	
 	// If log file path is specified, also log to file
-	if logFilePath != "" {
-		logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if options.File != "" {
+		logFile, err := OpenRotatingFile(options.File, options.Rotation)
 		if err != nil {
 			return nil, err
 		}
-		
-		// Use file handler for file output
-		fileHandler := slog.NewJSONHandler(logFile, opts)
-		
-		// Create a multi-handler that writes to both stdout and file
-		handler = &multiHandler{
-			handlers: []slog.Handler{handler, fileHandler},
-		}
+		handlers = append(handlers, newFormatHandler(options.Format, logFile, opts, false))
+	}
+	
+	if options.Writer != nil {
+		handlers = append(handlers, newFormatHandler(options.Format, options.Writer, opts, false))
+	}
+	
+	// Create a multi-handler when writing to more than stdout
+	handler := handlers[0]
+	if len(handlers) > 1 {
+		handler = &multiHandler{handlers: handlers}
 	}
 	
 	logger := slog.New(handler)
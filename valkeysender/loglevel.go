@@ -0,0 +1,77 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SetLogLevel changes the level of the default logger at runtime. A logger
+// passed in SenderOptions.Logger keeps the level set by its owner.
+func (s *valkeySender) SetLogLevel(level slog.Level) {
+	if s.logLevel == nil {
+		s.logger.Warn("Log level not changed, the logger is not the default one",
+			slog.String("level", level.String()),
+		)
+		return
+	}
+
+	previous := s.logLevel.Level()
+	s.logLevel.Set(level)
+	s.logger.Info("Log level changed",
+		slog.String("from", previous.String()),
+		slog.String("to", level.String()),
+	)
+}
+
+// LogLevel returns the lowest level the logger currently emits
+func (s *valkeySender) LogLevel() slog.Level {
+	if s.logLevel != nil {
+		return s.logLevel.Level()
+	}
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+		if s.logger.Enabled(context.Background(), level) {
+			return level
+		}
+	}
+	return slog.LevelError
+}
+
+// LogLevelSetter is implemented by senders whose log level can change at runtime
+type LogLevelSetter interface {
+	LogLevel() slog.Level
+	SetLogLevel(level slog.Level)
+}
+
+// ToggleDebugOnSignal switches the sender to DEBUG logging when one of the
+// signals arrives (SIGHUP if none are given) and back to the previous level
+// on the next one, until ctx is done
+func ToggleDebugOnSignal(ctx context.Context, sender LogLevelSetter, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	go func() {
+		defer signal.Stop(received)
+
+		normal := sender.LogLevel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-received:
+				if sender.LogLevel() == slog.LevelDebug {
+					sender.SetLogLevel(normal)
+					continue
+				}
+				normal = sender.LogLevel()
+				sender.SetLogLevel(slog.LevelDebug)
+			}
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, nil)
+	
+	var out bytes.Buffer
+	level := new(slog.LevelVar)
+	s.logger = slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: level}))
+	s.logLevel = level
+	
+	s.logger.Debug("hidden")
+	s.SetLogLevel(slog.LevelDebug)
+	s.logger.Debug("shown")
+	if s.LogLevel() != slog.LevelDebug {
+		t.Errorf("Expected DEBUG, got %v", s.LogLevel())
+	}
+	
+	s.SetLogLevel(slog.LevelWarn)
+	s.logger.Info("hidden again")
+	
+	logged := out.String()
+	if strings.Contains(logged, "hidden") || !strings.Contains(logged, "msg=shown") {
+		t.Errorf("Expected only entries at the current level, got %s", logged)
+	}
+}
+
+func TestSetLogLevelCustomLogger(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, nil)
+	
+	s.SetLogLevel(slog.LevelDebug)
+	if s.LogLevel() != slog.LevelInfo {
+		t.Errorf("Expected the custom logger to keep its INFO level, got %v", s.LogLevel())
+	}
+}
+
+func TestResolveLoggerLevel(t *testing.T) {
+	config := DefaultConfig()
+	config.LogLevel = "WARN"
+	
+	logger, level, err := resolveLogger(config, &SenderOptions{})
+	if err != nil || level == nil {
+		t.Fatalf("Expected a default logger with a level variable, got %v (%v)", level, err)
+	}
+	if logger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("Expected INFO to be disabled at WARN")
+	}
+	level.Set(slog.LevelDebug)
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Expected DEBUG to be enabled after changing the level")
+	}
+}
+
+// levelRecorder is a LogLevelSetter recording the levels set
+type levelRecorder struct {
+	mu     sync.Mutex
+	level  slog.Level
+	levels []slog.Level
+}
+
+func (r *levelRecorder) LogLevel() slog.Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.level
+}
+
+func (r *levelRecorder) SetLogLevel(level slog.Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.level = level
+	r.levels = append(r.levels, level)
+}
+
+func (r *levelRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.levels)
+}
+
+func TestToggleDebugOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the own process on Windows")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	recorder := &levelRecorder{level: slog.LevelWarn}
+	ToggleDebugOnSignal(ctx, recorder)
+	
+	process, _ := os.FindProcess(os.Getpid())
+	for want := 1; want <= 2; want++ {
+		if err := process.Signal(syscall.SIGHUP); err != nil {
+			t.Fatalf("Signal failed: %v", err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for recorder.count() < want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.levels) != 2 || recorder.levels[0] != slog.LevelDebug || recorder.levels[1] != slog.LevelWarn {
+		t.Errorf("Expected DEBUG then WARN, got %v", recorder.levels)
+	}
+}
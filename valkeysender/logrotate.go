@@ -0,0 +1,281 @@
+package valkeysender
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for log file rotation
+const (
+	DefaultLogMaxSize    = 100 << 20 // 100 MiB
+	DefaultLogMaxBackups = 5
+)
+
+// backupTimeFormat is the timestamp inserted into rotated file names,
+// e.g. "sender-2025-05-28T10-30-00.000.log"
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressSuffix is appended to compressed backups
+const compressSuffix = ".gz"
+
+// RotationOptions configures log file rotation
+type RotationOptions struct {
+	// MaxSize is the size in bytes at which the file is rotated (0 uses
+	// DefaultLogMaxSize, a negative value disables rotation)
+	MaxSize int64
+
+	// MaxAge removes backups older than this (0 keeps them regardless of age)
+	MaxAge time.Duration
+
+	// MaxBackups is the number of backups kept (0 keeps all of them)
+	MaxBackups int
+
+	// Compress gzips backups after rotation
+	Compress bool
+}
+
+// DefaultRotationOptions returns the rotation used by NewLogger
+func DefaultRotationOptions() RotationOptions {
+	return RotationOptions{
+		MaxSize:    DefaultLogMaxSize,
+		MaxBackups: DefaultLogMaxBackups,
+	}
+}
+
+// RotatingFile is an io.WriteCloser appending to a file that is renamed to a
+// timestamped backup once it reaches the maximum size. Old backups are
+// compressed and removed in the background.
+type RotatingFile struct {
+	path    string
+	options RotationOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	millMu sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory
+// if needed
+func OpenRotatingFile(path string, options RotationOptions) (*RotatingFile, error) {
+	if options.MaxSize == 0 {
+		options.MaxSize = DefaultLogMaxSize
+	}
+
+	r := &RotatingFile{path: path, options: options}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file and reads its size
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file over the
+// maximum size. A single entry larger than the maximum is still written.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.options.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.options.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it to a backup and starts a new one
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.path, r.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.mill()
+	}()
+	return nil
+}
+
+// Close closes the file and waits for background compression and cleanup
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return err
+}
+
+// backupName returns the name of a backup rotated at t
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits the path into directory, backup prefix and extension
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// logBackup is a rotated file found on disk
+type logBackup struct {
+	path       string
+	rotated    time.Time
+	compressed bool
+}
+
+// backups lists the rotated files, newest first
+func (r *RotatingFile) backups() ([]logBackup, error) {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix)
+		compressed := strings.HasSuffix(stamp, ext+compressSuffix)
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, compressSuffix), ext)
+
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{
+			path:       filepath.Join(dir, name),
+			rotated:    rotated,
+			compressed: compressed,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotated.After(backups[j].rotated)
+	})
+	return backups, nil
+}
+
+// mill removes backups beyond MaxBackups or older than MaxAge and
+// compresses the remaining ones if enabled. Failures are ignored: the next
+// rotation tries again.
+func (r *RotatingFile) mill() {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-r.options.MaxAge)
+	for i, backup := range backups {
+		tooMany := r.options.MaxBackups > 0 && i >= r.options.MaxBackups
+		tooOld := r.options.MaxAge > 0 && backup.rotated.Before(cutoff)
+		if tooMany || tooOld {
+			os.Remove(backup.path)
+			continue
+		}
+		if r.options.Compress && !backup.compressed {
+			compressFile(backup.path)
+		}
+	}
+}
+
+// compressFile gzips path into path.gz and removes the original
+func compressFile(path string) error {
+	if err := gzipFile(path, path+compressSuffix); err != nil {
+		os.Remove(path + compressSuffix)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// gzipFile writes a gzipped copy of source to target
+func gzipFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := gzip.NewWriter(out)
+	if _, err := io.Copy(writer, in); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,102 @@
+package valkeysender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sender.log")
+	
+	file, err := OpenRotatingFile(path, RotationOptions{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(entry)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		// Keep backup names distinct
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	
+	if current, _ := os.ReadFile(path); string(current) != "fourth\n" {
+		t.Errorf("Expected the current file to hold the last entry, got %q", current)
+	}
+	backups, err := file.backups()
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v (%v)", backups, err)
+	}
+	if newest, _ := os.ReadFile(backups[0].path); string(newest) != "third\n" {
+		t.Errorf("Expected the newest backup to hold the third entry, got %q", newest)
+	}
+	
+	if _, err := file.Write([]byte("late")); err == nil {
+		t.Error("Expected writing to a closed file to fail")
+	}
+}
+
+func TestRotatingFileCompressAndMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sender.log")
+	
+	// A backup left by an earlier run, past the maximum age
+	stale := filepath.Join(dir, "sender-"+time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	if err := os.WriteFile(stale, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	
+	file, err := OpenRotatingFile(path, RotationOptions{MaxAge: 24 * time.Hour, Compress: true})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	file.Write([]byte("entry\n"))
+	if err := file.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	file.Close()
+	
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale backup to be removed, got %v", err)
+	}
+	backups, _ := file.backups()
+	if len(backups) != 1 || !backups[0].compressed {
+		t.Fatalf("Expected one compressed backup, got %v", backups)
+	}
+	
+	compressed, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		t.Fatalf("Expected a gzip backup: %v", err)
+	}
+	if content, _ := io.ReadAll(reader); string(content) != "entry\n" {
+		t.Errorf("Unexpected backup content %q", content)
+	}
+}
+
+func TestNewLoggerWithWriter(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := NewLoggerWithOptions(LogOptions{Writer: &out})
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions failed: %v", err)
+	}
+	
+	logger.Info("hello")
+	if !strings.Contains(out.String(), `"msg":"hello"`) || !strings.Contains(out.String(), `"component":"valkeysender"`) {
+		t.Errorf("Expected the entry in the writer, got %q", out.String())
+	}
+}
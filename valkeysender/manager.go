@@ -0,0 +1,182 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Manager owns named senders, e.g. one per cluster, database or tenant, so a
+// service talking to several Valkey deployments can look them up by name,
+// report their combined health and close them together. It is safe for
+// concurrent use.
+type Manager struct {
+	mu      sync.RWMutex
+	senders map[string]Sender
+}
+
+// ManagerHealth is the combined health of a manager's senders
+type ManagerHealth struct {
+	Status  string                  `json:"status"` // the worst status of any sender
+	Senders map[string]HealthStatus `json:"senders"`
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	return &Manager{senders: make(map[string]Sender)}
+}
+
+// Add registers a sender under name. Names must be unique.
+func (m *Manager) Add(name string, sender Sender) error {
+	if name == "" {
+		return fmt.Errorf("sender name cannot be empty")
+	}
+	if sender == nil {
+		return fmt.Errorf("sender cannot be nil")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.senders[name]; ok {
+		return fmt.Errorf("sender %q already exists", name)
+	}
+	m.senders[name] = sender
+	return nil
+}
+
+// Open creates a sender from config and options and registers it under
+// name. The sender is closed again if the name is taken.
+func (m *Manager) Open(name string, config *Config, options *SenderOptions) (Sender, error) {
+	sender, err := NewSender(config, options)
+	if err != nil {
+		return nil, fmt.Errorf("sender %q: %w", name, err)
+	}
+	if err := m.Add(name, sender); err != nil {
+		sender.Close()
+		return nil, err
+	}
+	return sender, nil
+}
+
+// Get returns the sender registered under name
+func (m *Manager) Get(name string) (Sender, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sender, ok := m.senders[name]
+	return sender, ok
+}
+
+// Remove unregisters the sender under name and returns it without closing it
+func (m *Manager) Remove(name string) (Sender, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sender, ok := m.senders[name]
+	delete(m.senders, name)
+	return sender, ok
+}
+
+// Names returns the names of the registered senders, sorted
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.senders))
+	for name := range m.senders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Health returns the health of every sender and the worst of their
+// statuses; a manager without senders is healthy
+func (m *Manager) Health() ManagerHealth {
+	senders := m.snapshot()
+	health := ManagerHealth{
+		Status:  "healthy",
+		Senders: make(map[string]HealthStatus, len(senders)),
+	}
+	for name, sender := range senders {
+		status := sender.Health()
+		health.Senders[name] = status
+		if healthSeverity(status.Status) > healthSeverity(health.Status) {
+			health.Status = status.Status
+		}
+	}
+	return health
+}
+
+// Close closes every sender and unregisters them, returning the errors of
+// the senders that failed to close
+func (m *Manager) Close() error {
+	var errs []error
+	for name, sender := range m.takeAll() {
+		if err := sender.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sender %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseWithContext drains all senders concurrently until ctx ends, closes
+// them and unregisters them. It returns the total number of messages
+// dropped because a drain did not finish.
+func (m *Manager) CloseWithContext(ctx context.Context) (int64, error) {
+	senders := m.takeAll()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		dropped int64
+		errs    []error
+	)
+	for name, sender := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := sender.CloseWithContext(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			dropped += n
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sender %q: %w", name, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return dropped, errors.Join(errs...)
+}
+
+// snapshot copies the registered senders
+func (m *Manager) snapshot() map[string]Sender {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	senders := make(map[string]Sender, len(m.senders))
+	for name, sender := range m.senders {
+		senders[name] = sender
+	}
+	return senders
+}
+
+// takeAll unregisters and returns every sender
+func (m *Manager) takeAll() map[string]Sender {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	senders := m.senders
+	m.senders = make(map[string]Sender)
+	return senders
+}
+
+// healthSeverity orders health statuses from healthy to unhealthy
+func healthSeverity(status string) int {
+	switch status {
+	case "healthy":
+		return 0
+	case "degraded":
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,103 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	primary := miniredis.RunT(t)
+	secondary := miniredis.RunT(t)
+	m := NewManager()
+	
+	open := func(name string, server *miniredis.Miniredis) (Sender, error) {
+		config := DefaultConfig()
+		config.Address = server.Addr()
+		config.HealthCheckInterval = 0
+		return m.Open(name, config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	}
+	if _, err := open("primary", primary); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := open("secondary", secondary); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := open("primary", secondary); err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"primary", "secondary"}) {
+		t.Errorf("Unexpected names %v", names)
+	}
+	
+	sender, ok := m.Get("secondary")
+	if !ok {
+		t.Fatal("Expected the secondary sender")
+	}
+	if err := sender.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if !secondary.Exists("queue:orders") || primary.Exists("queue:orders") {
+		t.Error("Expected the message on the secondary server only")
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Expected no sender for an unknown name")
+	}
+	
+	if health := m.Health(); health.Status != "healthy" || len(health.Senders) != 2 {
+		t.Errorf("Unexpected health %+v", health)
+	}
+	
+	// The worst sender decides the combined status
+	primary.Close()
+	failing, _ := m.Get("primary")
+	for i := 0; i < 3; i++ {
+		failing.SendMessage(ctx, "orders", "b")
+	}
+	if health := m.Health(); health.Status != "unhealthy" || health.Senders["secondary"].Status != "healthy" {
+		t.Errorf("Expected unhealthy combined status, got %+v", health)
+	}
+	
+	dropped, err := m.CloseWithContext(ctx)
+	if dropped != 0 {
+		t.Errorf("Expected nothing dropped, got %d (%v)", dropped, err)
+	}
+	if len(m.Names()) != 0 {
+		t.Error("Expected closing to unregister every sender")
+	}
+	if err := sender.SendMessage(ctx, "orders", "c"); err == nil {
+		t.Error("Expected closed senders to refuse sends")
+	}
+}
+
+func TestManagerAddRemove(t *testing.T) {
+	m := NewManager()
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	
+	if err := m.Add("", s); err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+	if err := m.Add("a", nil); err == nil {
+		t.Error("Expected an error for a nil sender")
+	}
+	if err := m.Add("a", s); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	
+	removed, ok := m.Remove("a")
+	if !ok || removed != Sender(s) {
+		t.Fatal("Expected Remove to return the sender")
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	// Removed senders are left open
+	if err := s.SendMessage(context.Background(), "orders", "a"); err != nil {
+		t.Errorf("Expected the removed sender to stay open, got %v", err)
+	}
+}
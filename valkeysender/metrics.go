@@ -0,0 +1,123 @@
+package valkeysender
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordLatency adds a send duration to the latency histogram and statsd
+// and warns about sends slower than Config.SlowSendThreshold
+func (s *valkeySender) recordLatency(op, tenant, queue string, count, size int, duration time.Duration, err error) {
+	s.sendLatency.record(duration)
+	s.emitSend(op, tenant, queue, count, duration, err)
+
+	threshold := s.config.SlowSendThreshold
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+	s.logger.Warn("Slow send",
+		slog.String("op", op),
+		slog.String("queue", queue),
+		slog.Int("message_count", count),
+		slog.Int("size", size),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", threshold),
+		slog.String("circuit_breaker", s.circuitBreaker.State().String()),
+		slog.Bool("failed", err != nil),
+	)
+}
+
+// checkPayloadSize warns about sends carrying a message of at least
+// Config.LargePayloadBytes. size is the total of all messages.
+func (s *valkeySender) checkPayloadSize(op, queue string, count, size, largest int) {
+	threshold := s.config.LargePayloadBytes
+	if threshold <= 0 || largest < threshold {
+		return
+	}
+	s.logger.Warn("Large payload",
+		slog.String("op", op),
+		slog.String("queue", queue),
+		slog.Int("message_count", count),
+		slog.Int("size", size),
+		slog.Int("largest", largest),
+		slog.Int("threshold", threshold),
+		slog.String("circuit_breaker", s.circuitBreaker.State().String()),
+	)
+}
+
+// batchSizes returns the number of messages in the batches, their total
+// encoded size in bytes and the size of the largest one
+func batchSizes(batches []*queueBatch) (count, size, largest int) {
+	for _, batch := range batches {
+		count += len(batch.data)
+		for _, data := range batch.data {
+			var n int
+			switch v := data.(type) {
+			case []byte:
+				n = len(v)
+			case string:
+				n = len(v)
+			}
+			size += n
+			largest = max(largest, n)
+		}
+	}
+	return count, size, largest
+}
+
+// Metrics returns the send counters, the latency distribution of sends
+// (including rate limiter and overflow waits) and connection pool statistics
+func (s *valkeySender) Metrics() SenderMetrics {
+	latency := s.sendLatency.snapshot()
+
+	metrics := SenderMetrics{
+		MessagesSent:        atomic.LoadInt64(&s.messagesSent),
+		MessagesFailedTotal: atomic.LoadInt64(&s.errorCount),
+		MessagesFailedLast:  s.failures.total(time.Now()),
+		MessagesTooLarge:    atomic.LoadInt64(&s.messagesTooLarge),
+		CorruptMessages:     atomic.LoadInt64(&s.corruptMessages),
+		ShadowedMessages:    s.shadow.count(),
+		AvgLatency:          latency.avg,
+		MaxLatency:          latency.max,
+		P50Latency:          latency.p50,
+		P95Latency:          latency.p95,
+		P99Latency:          latency.p99,
+		CircuitBreakerState: s.circuitBreaker.State().String(),
+		RateLimitHits:       atomic.LoadInt64(&s.rateLimitHits),
+		RateLimitTokens:     s.rateLimiter.Tokens(),
+		RateLimit:           float64(s.rateLimiter.Limit()),
+		StartTime:           s.startTime,
+	}
+
+	metrics.ConnectionPool = s.poolMetrics()
+	metrics.Mirror = s.mirror.metrics()
+
+	return metrics
+}
+
+// poolMetrics returns the statistics of the client's connection pool, zero
+// without a client
+func (s *valkeySender) poolMetrics() PoolMetrics {
+	client := s.getClient()
+	if client == nil {
+		return PoolMetrics{}
+	}
+
+	stats := client.PoolStats()
+	pool := PoolMetrics{
+		TotalConns:  int32(stats.TotalConns),
+		IdleConns:   int32(stats.IdleConns),
+		ActiveConns: int32(stats.TotalConns) - int32(stats.IdleConns),
+		StaleConns:  int32(stats.StaleConns),
+		Hits:        stats.Hits,
+		Misses:      stats.Misses,
+		Timeouts:    stats.Timeouts,
+	}
+	if c, ok := client.(*redis.Client); ok {
+		pool.MaxConns = int32(c.Options().PoolSize)
+	}
+	return pool
+}
@@ -0,0 +1,114 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if snapshot := h.snapshot(); snapshot != (latencySnapshot{}) {
+		t.Fatalf("Expected empty snapshot, got %+v", snapshot)
+	}
+	
+	for i := 0; i < 98; i++ {
+		h.record(80 * time.Microsecond)
+	}
+	h.record(time.Millisecond)
+	h.record(30 * time.Millisecond)
+	
+	snapshot := h.snapshot()
+	if snapshot.count != 100 || snapshot.max != 30*time.Millisecond {
+		t.Fatalf("Expected 100 records with a 30ms max, got %+v", snapshot)
+	}
+	if snapshot.p50 != 100*time.Microsecond || snapshot.p95 != 100*time.Microsecond {
+		t.Errorf("Expected p50 and p95 in the 100µs bucket, got %v and %v", snapshot.p50, snapshot.p95)
+	}
+	if snapshot.p99 != 1600*time.Microsecond {
+		t.Errorf("Expected p99 in the 1.6ms bucket, got %v", snapshot.p99)
+	}
+	want := (98*80*time.Microsecond + time.Millisecond + 30*time.Millisecond) / 100
+	if snapshot.avg != want {
+		t.Errorf("Expected avg %v, got %v", want, snapshot.avg)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	for i := 0; i < 3; i++ {
+		if err := s.SendMessage(ctx, "orders", i); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+	s.SendMessage(ctx, "orders", make(chan int))
+	
+	metrics := s.Metrics()
+	if metrics.MessagesSent != 3 || metrics.MessagesFailedTotal != 1 || metrics.MessagesFailedLast != 1 {
+		t.Errorf("Unexpected counters: %+v", metrics)
+	}
+	if metrics.P50Latency <= 0 || metrics.MaxLatency < metrics.P99Latency || metrics.AvgLatency <= 0 {
+		t.Errorf("Unexpected latencies: %+v", metrics)
+	}
+	if metrics.CircuitBreakerState != "closed" || metrics.ConnectionPool.TotalConns == 0 {
+		t.Errorf("Unexpected breaker or pool metrics: %+v", metrics)
+	}
+}
+
+func TestSlowSendWarning(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	var logs bytes.Buffer
+	s := newTestSender(t, server, &SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	
+	s.config.SlowSendThreshold = time.Hour
+	if err := s.SendMessage(ctx, "orders", "fast"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if strings.Contains(logs.String(), "Slow send") {
+		t.Fatalf("Expected no warning below the threshold, got %s", logs.String())
+	}
+	
+	s.config.SlowSendThreshold = time.Nanosecond
+	if err := s.SendMessage(ctx, "orders", "slow"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if out := logs.String(); !strings.Contains(out, "Slow send") || !strings.Contains(out, "queue=orders") || !strings.Contains(out, "circuit_breaker=closed") {
+		t.Errorf("Expected a slow send warning, got %s", out)
+	}
+}
+
+func TestLargePayloadWarning(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	var logs bytes.Buffer
+	s := newTestSender(t, server, &SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	s.config.LargePayloadBytes = 1024
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"small", "also small"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if strings.Contains(logs.String(), "Large payload") {
+		t.Fatalf("Expected no warning below the threshold, got %s", logs.String())
+	}
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"small", strings.Repeat("x", 2048)}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "Large payload") || !strings.Contains(out, "message_count=2") || !strings.Contains(out, "threshold=1024") {
+		t.Errorf("Expected a large payload warning, got %s", out)
+	}
+}
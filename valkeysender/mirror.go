@@ -0,0 +1,234 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Targets for Config.MirrorBestEffort
+const (
+	// MirrorTargetPrimary lets sends succeed on the mirror alone
+	MirrorTargetPrimary = "primary"
+
+	// MirrorTargetMirror lets sends succeed on the primary alone
+	MirrorTargetMirror = "mirror"
+)
+
+// MirrorMetrics counts the messages written to each target while
+// mirroring; a message that failed on a best-effort target still counts
+// as sent if the other target stored it
+type MirrorMetrics struct {
+	PrimarySent   int64 `json:"primary_sent"`
+	PrimaryFailed int64 `json:"primary_failed"`
+	MirrorSent    int64 `json:"mirror_sent"`
+	MirrorFailed  int64 `json:"mirror_failed"`
+}
+
+// mirror is the second cluster every push is written to during a
+// migration, see Config.MirrorURL
+type mirror struct {
+	address    string
+	client     *redis.Client
+	backend    *redisBackend
+	bestEffort string // target whose failures don't fail sends, if any
+	logger     *slog.Logger
+
+	primarySent, primaryFailed int64
+	mirrorSent, mirrorFailed   int64
+}
+
+// newMirror connects to the mirror cluster and wraps the sender's backend,
+// so every push goes to both, or returns nil if Config.MirrorURL is not
+// set. Settings missing from the URL are taken from the primary.
+func (s *valkeySender) newMirror() (*mirror, error) {
+	if s.config.MirrorURL == "" {
+		return nil, nil
+	}
+
+	config := *s.config
+	if err := config.ApplyURL(s.config.MirrorURL); err != nil {
+		return nil, fmt.Errorf("invalid mirror URL: %w", err)
+	}
+	client, err := newRedisClient(&config, s.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mirror client: %w", err)
+	}
+
+	m := &mirror{
+		address:    config.Address,
+		client:     client,
+		backend:    &redisBackend{client: func() redis.UniversalClient { return client }},
+		bestEffort: s.config.MirrorBestEffort,
+		logger:     s.logger,
+	}
+	s.backend = &mirrorBackend{backend: s.backend, mirror: m}
+	return m, nil
+}
+
+// metrics returns the per-target counters; nil without a mirror
+func (m *mirror) metrics() *MirrorMetrics {
+	if m == nil {
+		return nil
+	}
+	return &MirrorMetrics{
+		PrimarySent:   atomic.LoadInt64(&m.primarySent),
+		PrimaryFailed: atomic.LoadInt64(&m.primaryFailed),
+		MirrorSent:    atomic.LoadInt64(&m.mirrorSent),
+		MirrorFailed:  atomic.LoadInt64(&m.mirrorFailed),
+	}
+}
+
+// close closes the mirror client
+func (m *mirror) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.client.Close()
+}
+
+// mirrorBackend pushes to the primary backend and the mirror at the same
+// time; every other operation reads and manages the primary only.
+//
+// With the block overflow policy a group retried because the primary was
+// full may be written to the mirror again.
+type mirrorBackend struct {
+	backend // the primary
+	mirror  *mirror
+}
+
+// push pushes the groups to both targets
+func (b *mirrorBackend) push(ctx context.Context, groups [][]*queueBatch, policy pushPolicy) ([][]*queueBatch, *queueBatch, error) {
+	copies := make([][]*queueBatch, len(groups))
+	for i, group := range groups {
+		copies[i] = copyBatches(group)
+	}
+
+	var full [][]*queueBatch
+	var fullBatch *queueBatch
+	primaryErr, mirrorErr := b.both(func() error {
+		var err error
+		full, fullBatch, err = b.backend.push(ctx, groups, policy)
+		return err
+	}, func() error {
+		_, mirrorFull, err := b.mirror.backend.push(ctx, copies, policy)
+		if err == nil && mirrorFull != nil {
+			err = &Error{Queue: mirrorFull.queue, Kind: ErrQueueFull, Err: fmt.Errorf("queue reached its max length on the mirror")}
+		}
+		return err
+	})
+
+	var sent int64
+	for _, group := range groups {
+		sent += groupSize(group)
+	}
+	for _, group := range full {
+		sent -= groupSize(group)
+	}
+	err := b.settle(sent, primaryErr, mirrorErr)
+	if err == nil && primaryErr != nil {
+		// Stored on the mirror only
+		for i, group := range groups {
+			copyPositions(group, copies[i])
+		}
+		return nil, nil, nil
+	}
+	return full, fullBatch, err
+}
+
+// transact applies the transaction to both targets
+func (b *mirrorBackend) transact(ctx context.Context, batches []*queueBatch, ops []keyOp, policy pushPolicy) (*queueBatch, error) {
+	copies := copyBatches(batches)
+
+	var fullBatch *queueBatch
+	primaryErr, mirrorErr := b.both(func() error {
+		var err error
+		fullBatch, err = b.backend.transact(ctx, batches, ops, policy)
+		return err
+	}, func() error {
+		mirrorFull, err := b.mirror.backend.transact(ctx, copies, ops, policy)
+		if err == nil && mirrorFull != nil {
+			err = &Error{Queue: mirrorFull.queue, Kind: ErrQueueFull, Err: fmt.Errorf("queue reached its max length on the mirror")}
+		}
+		return err
+	})
+
+	sent := groupSize(batches)
+	if fullBatch != nil {
+		sent = 0
+	}
+	err := b.settle(sent, primaryErr, mirrorErr)
+	if err == nil && primaryErr != nil {
+		copyPositions(batches, copies)
+		return nil, nil
+	}
+	return fullBatch, err
+}
+
+// both runs the primary and the mirror push concurrently and returns
+// their errors
+func (b *mirrorBackend) both(primary, mirror func() error) (primaryErr, mirrorErr error) {
+	done := make(chan error, 1)
+	go func() { done <- mirror() }()
+	primaryErr = primary()
+	return primaryErr, <-done
+}
+
+// settle counts the outcome of a push of sent messages per target and
+// decides the error of the send, ignoring a failure of the best-effort target
+func (b *mirrorBackend) settle(sent int64, primaryErr, mirrorErr error) error {
+	m := b.mirror
+	if primaryErr != nil {
+		atomic.AddInt64(&m.primaryFailed, sent)
+	} else {
+		atomic.AddInt64(&m.primarySent, sent)
+	}
+	if mirrorErr != nil {
+		atomic.AddInt64(&m.mirrorFailed, sent)
+		mirrorErr = fmt.Errorf("mirror %s: %w", m.address, mirrorErr)
+	} else {
+		atomic.AddInt64(&m.mirrorSent, sent)
+	}
+
+	switch {
+	case primaryErr != nil && mirrorErr == nil && m.bestEffort == MirrorTargetPrimary:
+		m.logger.Warn("Failed to write to the primary, messages stored on the mirror only", slog.Any("error", primaryErr))
+		return nil
+	case mirrorErr != nil && m.bestEffort == MirrorTargetMirror:
+		m.logger.Warn("Failed to mirror messages", slog.String("mirror", m.address), slog.Any("error", mirrorErr))
+		return primaryErr
+	}
+	return errors.Join(primaryErr, mirrorErr)
+}
+
+// copyBatches copies batches for the mirror push, which must not set the
+// positions of the originals
+func copyBatches(batches []*queueBatch) []*queueBatch {
+	copies := make([]*queueBatch, len(batches))
+	for i, batch := range batches {
+		c := *batch
+		c.positions = nil
+		copies[i] = &c
+	}
+	return copies
+}
+
+// copyPositions takes the queue positions from the mirror copies
+func copyPositions(batches, copies []*queueBatch) {
+	for i, batch := range batches {
+		batch.positions = copies[i].positions
+	}
+}
+
+// groupSize returns the number of messages in the batches
+func groupSize(batches []*queueBatch) int64 {
+	var size int64
+	for _, batch := range batches {
+		size += int64(len(batch.data))
+	}
+	return size
+}
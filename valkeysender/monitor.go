@@ -0,0 +1,70 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// startHealthMonitor runs a background PING loop so connection state and
+// latency stay accurate while the sender is idle
+func (s *valkeySender) startHealthMonitor() {
+	if s.config.HealthCheckInterval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkHealth()
+			}
+		}
+	}()
+}
+
+// checkHealth pings Valkey once and updates the connection state
+func (s *valkeySender) checkHealth() {
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.ReadTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.backend.ping(ctx)
+	atomic.StoreInt64(&s.lastPing, time.Now().UnixNano())
+
+	if err != nil {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if s.getConnectionState() == ConnectionStateConnected {
+			s.logger.Warn("Valkey health check failed", slog.Any("error", err))
+		}
+		s.markDisconnected(err)
+		return
+	}
+
+	latency := time.Since(start)
+	s.recordPing(latency)
+
+	if s.getConnectionState() != ConnectionStateConnected {
+		s.logger.Info("Valkey connection restored", slog.Duration("latency", latency))
+	}
+	s.setConnectionState(ConnectionStateConnected, nil)
+}
+
+// lastHealthCheck returns the time of the last background health check
+func (s *valkeySender) lastHealthCheck() time.Time {
+	if ns := atomic.LoadInt64(&s.lastPing); ns > 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
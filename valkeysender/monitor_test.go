@@ -0,0 +1,137 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// connectionEvents records the events of a ConnectionHandler
+type connectionEvents struct {
+	mu     sync.Mutex
+	events []ConnectionEvent
+}
+
+func (c *connectionEvents) handle(event ConnectionEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+// transitions returns the recorded events as "previous>state"
+func (c *connectionEvents) transitions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	transitions := make([]string, len(c.events))
+	for i, event := range c.events {
+		transitions[i] = event.PreviousState + ">" + event.State
+	}
+	return transitions
+}
+
+func TestHealthMonitor(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	events := &connectionEvents{}
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 20 * time.Millisecond
+	config.ReconnectBackoffMax = 0
+	sender, err := NewSender(config, &SenderOptions{
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ConnectionHandler: events.handle,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+
+	// The monitor pings while the sender is idle
+	waitFor(t, func() bool { return !sender.Health().LastHealthCheck.IsZero() })
+	if health := sender.Health(); health.ConnectionState != ConnectionStateConnected || health.Latency <= 0 {
+		t.Errorf("Expected a connected sender with a ping latency, got %+v", health)
+	}
+
+	// A lost connection is noticed without any send
+	addr := server.Addr()
+	server.Close()
+	waitFor(t, func() bool { return sender.Health().ConnectionState == ConnectionStateReconnecting })
+
+	// And the recovery as well
+	if err := server.StartAddr(addr); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+	waitFor(t, func() bool { return sender.Health().ConnectionState == ConnectionStateConnected })
+	if err := sender.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Errorf("Expected sends to work after the recovery, got %v", err)
+	}
+
+	want := []string{
+		ConnectionStateDisconnected + ">" + ConnectionStateConnecting,
+		ConnectionStateConnecting + ">" + ConnectionStateConnected,
+		ConnectionStateConnected + ">" + ConnectionStateReconnecting,
+		ConnectionStateReconnecting + ">" + ConnectionStateConnected,
+	}
+	transitions := events.transitions()
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Transition %d: expected %s, got %s", i, want[i], transitions[i])
+		}
+	}
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if events.events[2].Error == nil {
+		t.Error("Expected the ping error on the lost connection event")
+	}
+}
+
+func TestHealthMonitorDisabled(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.ReconnectBackoffMax = 0
+	config.MaxRetries = 0
+	sender, err := NewSender(config, &SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+
+	// Nothing pings, and without a monitor a failure means disconnected
+	addr := server.Addr()
+	server.Close()
+	time.Sleep(50 * time.Millisecond)
+	if health := sender.Health(); health.ConnectionState != ConnectionStateConnected || !health.LastHealthCheck.IsZero() {
+		t.Errorf("Expected no health checks, got %+v", health)
+	}
+	if err := sender.SendMessage(ctx, "orders", "a"); err == nil {
+		t.Fatal("Expected the send to fail while Valkey is down")
+	}
+	if state := sender.Health().ConnectionState; state != ConnectionStateDisconnected {
+		t.Errorf("Expected state %s, got %s", ConnectionStateDisconnected, state)
+	}
+
+	if err := server.StartAddr(addr); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+	if err := sender.SendMessage(ctx, "orders", "b"); err != nil {
+		t.Fatalf("Expected the send to succeed after the restart, got %v", err)
+	}
+	if state := sender.Health().ConnectionState; state != ConnectionStateConnected {
+		t.Errorf("Expected state %s, got %s", ConnectionStateConnected, state)
+	}
+}
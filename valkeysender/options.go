@@ -0,0 +1,512 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Option configures a sender created with NewSenderWithOptions or NewConfig
+type Option func(config *Config, options *SenderOptions)
+
+// NewSenderWithOptions creates a sender for the given address, starting from
+// DefaultConfig and applying the options in order
+func NewSenderWithOptions(addr string, opts ...Option) (Sender, error) {
+	config, options, err := NewConfig(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSender(config, options)
+}
+
+// NewConfig builds and validates a configuration for the given address,
+// starting from DefaultConfig and applying the options in order
+func NewConfig(addr string, opts ...Option) (*Config, *SenderOptions, error) {
+	config := DefaultConfig()
+	config.Address = addr
+	options := &SenderOptions{}
+
+	for _, opt := range opts {
+		opt(config, options)
+	}
+
+	if err := config.resolve(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return config, options, nil
+}
+
+// WithURL applies a redis://, rediss://, valkey:// or valkeys:// connection URL
+func WithURL(rawURL string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.URL = rawURL
+	}
+}
+
+// WithUsername sets the ACL username
+func WithUsername(username string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.Username = username
+	}
+}
+
+// WithPassword sets the password used for AUTH
+func WithPassword(password string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.Password = password
+	}
+}
+
+// WithDatabase selects the logical database
+func WithDatabase(db int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.Database = db
+	}
+}
+
+// WithTLS enables TLS using the given client certificate, key and CA files
+func WithTLS(certFile, keyFile, caFile string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.TLSEnabled = true
+		c.TLSCertFile = certFile
+		c.TLSKeyFile = keyFile
+		c.TLSCAFile = caFile
+	}
+}
+
+// WithTLSSkipVerify disables server certificate verification (not recommended for production)
+func WithTLSSkipVerify() Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.TLSSkipVerify = true
+	}
+}
+
+// WithTimeouts sets the dial, read and write timeouts
+func WithTimeouts(dial, read, write time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.DialTimeout = dial
+		c.ReadTimeout = read
+		c.WriteTimeout = write
+	}
+}
+
+// WithSendTimeout bounds each send, including rate limiting (0 disables)
+func WithSendTimeout(timeout time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.SendTimeout = timeout
+	}
+}
+
+// WithRetries sets the client-level retries of commands failing on a network
+// error and the backoff between them
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.MaxRetries = maxRetries
+		c.MinRetryBackoff = minBackoff
+		c.MaxRetryBackoff = maxBackoff
+	}
+}
+
+// WithPoolSize sets the connection pool size and minimum idle connections
+func WithPoolSize(size, minIdle int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.PoolSize = size
+		c.MinIdleConns = minIdle
+	}
+}
+
+// WithDefaultQueue sets the default queue name
+func WithDefaultQueue(queue string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.DefaultQueue = queue
+	}
+}
+
+// WithQueuePrefix sets the prefix used to build queue list keys
+func WithQueuePrefix(prefix string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.QueuePrefix = prefix
+	}
+}
+
+// WithMessageTTL sets the default message TTL
+func WithMessageTTL(ttl time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.MessageTTL = ttl
+	}
+}
+
+// WithQueueProfile overrides settings for one queue
+func WithQueueProfile(queue string, profile QueueProfile) Option {
+	return func(c *Config, _ *SenderOptions) {
+		// Copy so configs sharing the map aren't changed
+		queues := maps.Clone(c.Queues)
+		if queues == nil {
+			queues = make(map[string]QueueProfile)
+		}
+		queues[queue] = profile
+		c.Queues = queues
+	}
+}
+
+// WithQueueNameRules rejects sends to queues whose names don't match
+// pattern (a regular expression, empty allows any characters), are longer
+// than maxLength bytes (0 for no limit) or start with a reserved prefix
+func WithQueueNameRules(pattern string, maxLength int, reservedPrefixes ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.QueueNamePattern = pattern
+		c.QueueNameMaxLength = maxLength
+		c.ReservedQueuePrefixes = reservedPrefixes
+	}
+}
+
+// WithAllowedQueues rejects sends to queues other than the given names or
+// glob patterns
+func WithAllowedQueues(queues ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.AllowedQueues = queues
+	}
+}
+
+// WithFrames sets the compression and envelopes per frame of SendBatchFramed
+func WithFrames(compression string, size int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.FrameCompression = compression
+		c.FrameSize = size
+	}
+}
+
+// WithMaxFrameBytes sets the largest decompressed frame body consumers accept
+func WithMaxFrameBytes(maxBytes int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.MaxFrameBytes = maxBytes
+	}
+}
+
+// WithRateLimit sets the requests per second and burst size of the rate limiter
+func WithRateLimit(requests, burst int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.RateLimitRequests = requests
+		c.RateLimitBurst = burst
+	}
+}
+
+// WithLinger holds single-message sends for up to interval and pushes them
+// together in one pipeline, at once when maxMessages are waiting
+func WithLinger(interval time.Duration, maxMessages int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.LingerInterval = interval
+		c.LingerMaxMessages = maxMessages
+	}
+}
+
+// WithAdaptiveRateLimit lets the rate limit fall to as low as minRequests
+// per second while the average push round trip exceeds latencyTarget or
+// pushes fail, and recover once Valkey keeps up again
+func WithAdaptiveRateLimit(minRequests int, latencyTarget time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.RateLimitAdaptive = true
+		c.RateLimitMinRequests = minRequests
+		c.RateLimitLatencyTarget = latencyTarget
+	}
+}
+
+// WithCircuitBreaker sets the circuit breaker half-open requests, reset interval and open timeout
+func WithCircuitBreaker(maxRequests uint32, interval, timeout time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.BreakerMaxRequests = maxRequests
+		c.BreakerInterval = interval
+		c.BreakerTimeout = timeout
+	}
+}
+
+// WithLogLevel sets the level of the default logger (DEBUG, INFO, WARN, ERROR)
+func WithLogLevel(level string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.LogLevel = level
+	}
+}
+
+// WithLogFormat sets the format of the default logger (json, text, console)
+func WithLogFormat(format string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.LogFormat = format
+	}
+}
+
+// WithClient sends through an existing client instead of creating one from
+// the connection settings; the client is not closed by Close
+func WithClient(client redis.UniversalClient) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.Client = client
+	}
+}
+
+// WithLogger sets the logger used by the sender, a *slog.Logger or an
+// adapter such as ZapLogger
+func WithLogger(logger Logger) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.Logger = logger
+	}
+}
+
+// WithSerializer sets the payload serializer
+func WithSerializer(serializer MessageSerializer) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.Serializer = serializer
+	}
+}
+
+// WithSerializerFor sets the serializer of each queue, falling back to the
+// payload serializer where serializerFor returns nil
+func WithSerializerFor(serializerFor func(queue string) MessageSerializer) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.SerializerFor = serializerFor
+	}
+}
+
+// WithEnvelopeCodec sets the envelope codec
+func WithEnvelopeCodec(codec EnvelopeCodec) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.EnvelopeCodec = codec
+	}
+}
+
+// WithIDGenerator sets the generator of envelope IDs
+func WithIDGenerator(generator IDGenerator) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.IDGenerator = generator
+	}
+}
+
+// WithQueueNamer sets a custom queue naming strategy
+func WithQueueNamer(namer func(queue string) string) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.QueueNamer = namer
+	}
+}
+
+// WithReconnectHandler sets the handler called when a lost connection is restored
+func WithReconnectHandler(handler func(ReconnectEvent)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.ReconnectHandler = handler
+	}
+}
+
+// WithReconnectBackoff sets the first and the longest delay between reconnect
+// attempts (a maximum of 0 disables the reconnect manager)
+func WithReconnectBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.ReconnectBackoffMin = minDelay
+		c.ReconnectBackoffMax = maxDelay
+	}
+}
+
+// WithReconnectFailFast refuses sends with ErrNotConnected while reconnecting
+func WithReconnectFailFast() Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.ReconnectFailFast = true
+	}
+}
+
+// WithClientCaching serves queue lengths from a cache invalidated by Valkey,
+// refreshing entries at least every ttl
+func WithClientCaching(ttl time.Duration) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.ClientCaching = true
+		c.ClientCacheTTL = ttl
+	}
+}
+
+// WithValidator sets the hook validating every message before it is sent
+func WithValidator(validator func(queue string, message interface{}) error) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.Validator = validator
+	}
+}
+
+// WithErrorHandler sets the error handler
+func WithErrorHandler(handler func(error)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.ErrorHandler = handler
+	}
+}
+
+// WithSendHooks sets the hooks called before every envelope is encoded and
+// after every message was pushed; either may be nil
+func WithSendHooks(before func(ctx context.Context, envelope *MessageEnvelope) error, after func(ctx context.Context, metadata MessageMetadata, err error)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.BeforeSend = before
+		o.AfterSend = after
+	}
+}
+
+// WithSuccessHandler sets the success handler
+func WithSuccessHandler(handler func(MessageMetadata)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.SuccessHandler = handler
+	}
+}
+
+// WithRawPayload pushes payloads without the envelope wrapper
+func WithRawPayload() Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.RawPayload = true
+	}
+}
+
+// WithStandardHeaders turns the content-type, content-encoding, producer
+// and schema-version headers of every envelope on or off
+func WithStandardHeaders(enabled bool) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.StandardHeaders = enabled
+	}
+}
+
+// WithMaxMessageBytes rejects encoded messages larger than maxBytes with
+// ErrMessageTooLarge before they are sent (0 for no limit)
+func WithMaxMessageBytes(maxBytes int) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.MaxMessageBytes = maxBytes
+	}
+}
+
+// WithEnvelopeChecksum records a checksum of the payload in every
+// envelope, ChecksumCRC32 or ChecksumSHA256, verified when it is decoded
+func WithEnvelopeChecksum(algorithm string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.EnvelopeChecksum = algorithm
+	}
+}
+
+// WithQueueWatcher polls the given queues every interval and calls handler
+// when one reaches threshold messages and again when it drops below
+func WithQueueWatcher(interval time.Duration, threshold int, handler func(queue string, depth int64), queues ...string) Option {
+	return func(c *Config, o *SenderOptions) {
+		c.WatchInterval = interval
+		c.QueueAlertThreshold = threshold
+		c.WatchQueues = queues
+		o.QueueAlertHandler = handler
+	}
+}
+
+// WithPoolAlert calls handler when at least threshold (0 to 1) of the pool
+// connections stay in use for duration, and again when usage drops back
+func WithPoolAlert(threshold float64, duration time.Duration, handler func(PoolAlert)) Option {
+	return func(c *Config, o *SenderOptions) {
+		c.PoolAlertThreshold = threshold
+		c.PoolAlertDuration = duration
+		o.PoolAlertHandler = handler
+	}
+}
+
+// WithAuditLog records every message sent to the given queues (all queues
+// if none are given) in the Valkey stream and the file; an empty name
+// disables that sink
+func WithAuditLog(stream, file string, queues ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.AuditStream = stream
+		c.AuditFile = file
+		c.AuditQueues = queues
+	}
+}
+
+// WithAuditHandler sets the handler called with the audit record of every audited message
+func WithAuditHandler(handler func(AuditRecord)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.AuditHandler = handler
+	}
+}
+
+// WithArchive copies every message sent to the given queues (all queues
+// if none are given) into a stream per queue keeping about maxLen entries
+func WithArchive(maxLen int, queues ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.ArchiveMaxLen = maxLen
+		c.ArchiveQueues = queues
+	}
+}
+
+// WithShadowTraffic copies a ratio (0 to 1) of the sent messages to shadow
+// queues, keyed with keyPrefix (DefaultShadowKeyPrefix if empty) on the
+// cluster at rawURL (the primary if empty)
+func WithShadowTraffic(ratio float64, keyPrefix, rawURL string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.ShadowQueueRatio = ratio
+		c.ShadowKeyPrefix = keyPrefix
+		c.ShadowURL = rawURL
+	}
+}
+
+// WithUsageAccounting counts the messages and bytes sent per tenant, named
+// by the header envelope header (HeaderTenant if empty), for GetUsage
+func WithUsageAccounting(header string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.UsageAccounting = true
+		c.UsageHeader = header
+	}
+}
+
+// WithFailover sends to the standby clusters at addresses, in order of
+// preference, while the circuit breaker is open; queues limits failover to
+// those queues
+func WithFailover(addresses []string, queues ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.FallbackAddresses = addresses
+		c.FailoverQueues = queues
+	}
+}
+
+// WithMirror also writes every message to the cluster at rawURL, e.g. the
+// new cluster of a migration; bestEffort is MirrorTargetPrimary,
+// MirrorTargetMirror or empty
+func WithMirror(rawURL, bestEffort string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.MirrorURL = rawURL
+		c.MirrorBestEffort = bestEffort
+	}
+}
+
+// WithIngest serves the HTTP ingestion endpoint on address, requiring the
+// bearer token and accepting messages for the given queues (all queues if
+// none are given)
+func WithIngest(address, token string, queues ...string) Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.IngestAddress = address
+		c.IngestToken = token
+		c.IngestQueues = queues
+	}
+}
+
+// WithUnauthenticatedIngest lets the HTTP ingestion endpoint serve requests
+// without a token, e.g. behind a proxy that authenticates them
+func WithUnauthenticatedIngest() Option {
+	return func(c *Config, _ *SenderOptions) {
+		c.IngestAllowUnauthenticated = true
+	}
+}
+
+// WithCredentialsProvider asks provider for the credentials of every new
+// connection, for tokens that expire and are rotated
+func WithCredentialsProvider(provider CredentialsProvider) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.CredentialsProvider = provider
+	}
+}
+
+// WithDialer opens connections to the address with dialer instead of the
+// default TCP dialer
+func WithDialer(dialer func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(_ *Config, o *SenderOptions) {
+		o.Dialer = dialer
+	}
+}
@@ -0,0 +1,81 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// HeaderOrderingKey is the envelope header carrying the ordering key of messages sent with SendOrdered
+const HeaderOrderingKey = "ordering-key"
+
+// orderedKeys queues the sends sharing an ordering key, so each one starts
+// once the sends queued before it completed
+type orderedKeys struct {
+	mu    sync.Mutex
+	tails map[string]chan struct{} // closed once the last send queued for the key completed
+}
+
+// acquire queues a send for the key and waits for its turn. The returned
+// release must be called once the send completed; if ctx is done first,
+// the send leaves the queue without holding back the ones behind it.
+func (o *orderedKeys) acquire(ctx context.Context, key string) (func(), error) {
+	o.mu.Lock()
+	if o.tails == nil {
+		o.tails = make(map[string]chan struct{})
+	}
+	previous := o.tails[key]
+	turn := make(chan struct{})
+	o.tails[key] = turn
+	o.mu.Unlock()
+
+	release := func() {
+		o.mu.Lock()
+		if o.tails[key] == turn {
+			delete(o.tails, key)
+		}
+		o.mu.Unlock()
+		close(turn)
+	}
+	if previous == nil {
+		return release, nil
+	}
+
+	select {
+	case <-previous:
+		return release, nil
+	case <-ctx.Done():
+		// The sends queued behind this one still wait for the ones before it
+		go func() {
+			<-previous
+			release()
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// SendOrdered sends a message like SendMessage, after every send made
+// earlier with the same queue and ordering key completed, so messages of
+// one key reach the queue in the order SendOrdered was called even from
+// concurrent goroutines. Messages of different keys are not held back by
+// each other. The key is recorded in the ordering-key header.
+//
+// The order is kept within this sender only. A failed send doesn't stop
+// the ones queued behind it: to keep a key in order across failures,
+// retry the failed message before sending the next one for its key.
+func (s *valkeySender) SendOrdered(ctx context.Context, queue, orderingKey string, message interface{}) error {
+	if orderingKey == "" {
+		return s.fail(opSendMessage, queue, &Error{Kind: ErrValidation, Err: fmt.Errorf("ordering key cannot be empty")})
+	}
+
+	release, err := s.ordered.acquire(ctx, queue+"\x00"+orderingKey)
+	if err != nil {
+		return classifyError(opSendMessage, queue, err)
+	}
+	defer release()
+
+	return s.sendMessage(ctx, opSendMessage, "", queue, message, SendOptions{
+		TTL:     s.messageTTL(queue),
+		Headers: map[string]string{HeaderOrderingKey: orderingKey},
+	})
+}
@@ -0,0 +1,86 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// orderedTail returns the channel of the last send queued for a key
+func orderedTail(s *valkeySender, key string) chan struct{} {
+	s.ordered.mu.Lock()
+	defer s.ordered.mu.Unlock()
+	return s.ordered.tails[key]
+}
+
+func TestSendOrdered(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// Hold the first message of user-1 in validation
+	validating := make(chan struct{})
+	resume := make(chan struct{})
+	s := newTestSender(t, server, &SenderOptions{Validator: func(_ string, message interface{}) error {
+		if message == "first" {
+			close(validating)
+			<-resume
+		}
+		return nil
+	}})
+	key := "events\x00user-1"
+
+	first := make(chan error, 1)
+	go func() { first <- s.SendOrdered(ctx, "events", "user-1", "first") }()
+	<-validating
+	held := orderedTail(s, key)
+
+	second := make(chan error, 1)
+	go func() { second <- s.SendOrdered(ctx, "events", "user-1", "second") }()
+	waitFor(t, func() bool { return orderedTail(s, key) != held })
+
+	// Other keys are not held back
+	if err := s.SendOrdered(ctx, "events", "user-2", "other"); err != nil {
+		t.Fatalf("SendOrdered failed: %v", err)
+	}
+
+	// A send giving up leaves the queue without releasing the ones behind it
+	expired, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := s.SendOrdered(expired, "events", "user-1", "abandoned"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected waiting past the deadline to time out, got %v", err)
+	}
+	select {
+	case err := <-second:
+		t.Fatalf("Expected the second send to wait for the first, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(resume)
+	if err := <-first; err != nil {
+		t.Fatalf("SendOrdered failed: %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("SendOrdered failed: %v", err)
+	}
+
+	envelopes, err := s.PeekMessages(ctx, "events", 0, 10)
+	if err != nil || len(envelopes) != 3 {
+		t.Fatalf("Expected 3 messages, got %d, %v", len(envelopes), err)
+	}
+	for i, expected := range []string{"other", "first", "second"} {
+		if string(envelopes[i].Payload) != expected {
+			t.Errorf("Expected message %d to be %s, got %s", i, expected, envelopes[i].Payload)
+		}
+	}
+	if envelopes[1].Headers[HeaderOrderingKey] != "user-1" {
+		t.Errorf("Expected the ordering key header, got %v", envelopes[1].Headers)
+	}
+	waitFor(t, func() bool { return orderedTail(s, key) == nil })
+
+	if err := s.SendOrdered(ctx, "events", "", "keyless"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an empty ordering key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package valkeysender
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SQL dialects supported by the outbox
+const (
+	// OutboxDialectPostgres uses $n placeholders and FOR UPDATE SKIP LOCKED (default)
+	OutboxDialectPostgres = "postgres"
+
+	// OutboxDialectMySQL uses ? placeholders and FOR UPDATE SKIP LOCKED (MySQL 8)
+	OutboxDialectMySQL = "mysql"
+
+	// OutboxDialectSQLite uses ? placeholders; SQLite serializes writers itself
+	OutboxDialectSQLite = "sqlite"
+)
+
+// Outbox defaults
+const (
+	DefaultOutboxTable        = "valkeysender_outbox"
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxRetention    = 24 * time.Hour
+)
+
+// outboxTablePattern restricts table names, which are spliced into statements
+var outboxTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// OutboxConfig configures an Outbox
+type OutboxConfig struct {
+	// Table holding the outbox rows (default DefaultOutboxTable)
+	Table string
+
+	// Dialect of the database: "postgres" (default), "mysql" or "sqlite"
+	Dialect string
+
+	// Serializer encoding messages when they are stored (default JSON). The
+	// relay sends the stored bytes, so the sender's serializer must pass
+	// []byte payloads through, as the JSON serializer does.
+	Serializer MessageSerializer
+
+	// PollInterval is how often the relay looks for unpublished rows (default 1s)
+	PollInterval time.Duration
+
+	// BatchSize is the most rows published per poll (default 100)
+	BatchSize int
+
+	// Retention is how long published rows are kept before the relay
+	// deletes them (default 24h, negative keeps them)
+	Retention time.Duration
+
+	// Logger for the relay (default slog.Default())
+	Logger Logger
+}
+
+// Outbox implements the transactional outbox pattern: StoreInTx writes a
+// message to an outbox table in the caller's database transaction, so it
+// is stored if and only if the transaction commits, and a relay goroutine
+// publishes committed rows to Valkey in order.
+//
+// A row is marked published in the same database transaction that selected
+// it, after it was sent with the row ID as idempotency key. Should the
+// relay fail between sending and committing, the row is sent again and
+// skipped by Valkey as a duplicate, so every row reaches its queue exactly
+// once as long as it is retried within the sender's deduplication window.
+type Outbox struct {
+	db         *sql.DB
+	sender     Sender
+	config     OutboxConfig
+	serializer MessageSerializer
+	logger     *slog.Logger
+
+	insertSQL  string
+	selectSQL  string
+	markSQL    string
+	cleanupSQL string
+
+	relayMutex sync.Mutex // one relay pass at a time per outbox
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// outboxRow is an unpublished outbox row
+type outboxRow struct {
+	id      string
+	queue   string
+	payload []byte
+	headers map[string]string
+}
+
+// NewOutbox creates an outbox storing messages in db and starts its relay,
+// which publishes them through sender. The table must exist; see CreateTable.
+func NewOutbox(db *sql.DB, sender Sender, config OutboxConfig) (*Outbox, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	if sender == nil {
+		return nil, fmt.Errorf("sender cannot be nil")
+	}
+
+	if config.Table == "" {
+		config.Table = DefaultOutboxTable
+	}
+	if !outboxTablePattern.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid outbox table name %q", config.Table)
+	}
+	config.Dialect = strings.ToLower(config.Dialect)
+	switch config.Dialect {
+	case "":
+		config.Dialect = OutboxDialectPostgres
+	case OutboxDialectPostgres, OutboxDialectMySQL, OutboxDialectSQLite:
+	default:
+		return nil, fmt.Errorf("outbox dialect must be %q, %q or %q", OutboxDialectPostgres, OutboxDialectMySQL, OutboxDialectSQLite)
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultOutboxPollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultOutboxBatchSize
+	}
+	if config.Retention == 0 {
+		config.Retention = defaultOutboxRetention
+	}
+
+	serializer := config.Serializer
+	if serializer == nil {
+		serializer = NewJSONSerializer()
+	}
+	logger := slog.Default()
+	if config.Logger != nil {
+		logger = slogLogger(config.Logger)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &Outbox{
+		db:         db,
+		sender:     sender,
+		config:     config,
+		serializer: serializer,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	o.prepareStatements()
+
+	o.startRelay()
+
+	return o, nil
+}
+
+// prepareStatements builds the statements for the table and dialect
+func (o *Outbox) prepareStatements() {
+	table := o.config.Table
+	lock := " FOR UPDATE SKIP LOCKED"
+	if o.config.Dialect == OutboxDialectSQLite {
+		lock = ""
+	}
+
+	o.insertSQL = o.bind("INSERT INTO " + table + " (id, queue, payload, headers, created_at) VALUES (?, ?, ?, ?, ?)")
+	o.selectSQL = o.bind("SELECT id, queue, payload, headers FROM " + table +
+		" WHERE published_at IS NULL ORDER BY created_at, id LIMIT ?" + lock)
+	o.markSQL = o.bind("UPDATE " + table + " SET published_at = ? WHERE id = ?")
+	o.cleanupSQL = o.bind("DELETE FROM " + table + " WHERE published_at IS NOT NULL AND published_at < ?")
+}
+
+// bind rewrites ? placeholders into the dialect's placeholders
+func (o *Outbox) bind(query string) string {
+	if o.config.Dialect != OutboxDialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CreateTable creates the outbox table and its index if they don't exist.
+// Times are stored as unix microseconds.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	payloadType := "BYTEA"
+	switch o.config.Dialect {
+	case OutboxDialectMySQL:
+		payloadType = "LONGBLOB"
+	case OutboxDialectSQLite:
+		payloadType = "BLOB"
+	}
+
+	table := o.config.Table
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (" +
+			"id VARCHAR(36) PRIMARY KEY, " +
+			"queue VARCHAR(255) NOT NULL, " +
+			"payload " + payloadType + " NOT NULL, " +
+			"headers TEXT, " +
+			"created_at BIGINT NOT NULL, " +
+			"published_at BIGINT NULL)",
+		"CREATE INDEX " + o.indexIfNotExists() + strings.ReplaceAll(table, ".", "_") + "_pending ON " + table + " (published_at, created_at)",
+	}
+	for _, statement := range statements {
+		if _, err := o.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create outbox table: %w", err)
+		}
+	}
+	return nil
+}
+
+// indexIfNotExists returns the IF NOT EXISTS clause of CREATE INDEX, which MySQL lacks
+func (o *Outbox) indexIfNotExists() string {
+	if o.config.Dialect == OutboxDialectMySQL {
+		return ""
+	}
+	return "IF NOT EXISTS "
+}
+
+// StoreInTx writes a message for queue to the outbox within tx. It is
+// published once tx commits and discarded if tx rolls back.
+func (o *Outbox) StoreInTx(tx *sql.Tx, queue string, message interface{}) error {
+	return o.StoreInTxContext(context.Background(), tx, queue, message)
+}
+
+// StoreInTxContext is StoreInTx with a context, whose headers attached
+// with WithHeader are stored with the message
+func (o *Outbox) StoreInTxContext(ctx context.Context, tx *sql.Tx, queue string, message interface{}) error {
+	if tx == nil {
+		return fmt.Errorf("transaction cannot be nil")
+	}
+	if queue == "" {
+		return fmt.Errorf("queue cannot be empty")
+	}
+
+	payload, err := o.serializer.Serialize(message)
+	if err != nil {
+		return &Error{Op: "store in outbox", Queue: queue, Kind: ErrSerialization, Err: err}
+	}
+
+	headers := maps.Clone(HeadersFromContext(ctx))
+	if hs, ok := o.serializer.(HeaderSerializer); ok {
+		if extra := hs.Headers(message); len(extra) > 0 {
+			if headers == nil {
+				headers = make(map[string]string, len(extra))
+			}
+			maps.Copy(headers, extra)
+		}
+	}
+	var encodedHeaders sql.NullString
+	if len(headers) > 0 {
+		data, err := json.Marshal(headers)
+		if err != nil {
+			return &Error{Op: "store in outbox", Queue: queue, Kind: ErrSerialization, Err: err}
+		}
+		encodedHeaders = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Version 7 IDs sort by creation time, keeping rows of one instant in order
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, o.insertSQL, id.String(), queue, payload, encodedHeaders, time.Now().UnixMicro())
+	if err != nil {
+		return fmt.Errorf("failed to store message in outbox: %w", err)
+	}
+	return nil
+}
+
+// startRelay publishes stored rows every poll interval until Close
+func (o *Outbox) startRelay() {
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+
+		ticker := time.NewTicker(o.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// Drain the backlog before waiting for the next tick
+			for {
+				published, err := o.Relay(o.ctx)
+				if err != nil {
+					if o.ctx.Err() == nil {
+						o.logger.Warn("Outbox relay failed", slog.Any("error", err))
+					}
+					break
+				}
+				if published < o.config.BatchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// Relay publishes up to BatchSize unpublished rows in order and marks them
+// published, returning how many were published. It stops at the first row
+// that fails to send, so later rows don't overtake it. The relay goroutine
+// calls it every poll interval; calling it directly publishes right away.
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	o.relayMutex.Lock()
+	defer o.relayMutex.Unlock()
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := o.pending(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var sendErr error
+	for _, row := range rows {
+		// The row ID doubles as idempotency key, so a row sent again after
+		// a failed commit is skipped as a duplicate
+		sendErr = o.sender.SendMessageWithOptions(ctx, row.queue, row.payload, SendOptions{
+			MessageID:      row.id,
+			IdempotencyKey: row.id,
+			Headers:        row.headers,
+		})
+		if sendErr != nil {
+			break
+		}
+		if _, err := tx.ExecContext(ctx, o.markSQL, time.Now().UnixMicro(), row.id); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox row %s published: %w", row.id, err)
+		}
+		published++
+	}
+
+	if o.config.Retention > 0 {
+		cutoff := time.Now().Add(-o.config.Retention).UnixMicro()
+		if _, err := tx.ExecContext(ctx, o.cleanupSQL, cutoff); err != nil {
+			return 0, fmt.Errorf("failed to delete published outbox rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox transaction: %w", err)
+	}
+
+	if published > 0 {
+		o.logger.Debug("Outbox rows published", slog.Int("count", published))
+	}
+	if sendErr != nil {
+		return published, fmt.Errorf("failed to publish outbox row: %w", sendErr)
+	}
+	return published, nil
+}
+
+// pending selects and locks the next unpublished rows
+func (o *Outbox) pending(ctx context.Context, tx *sql.Tx) ([]outboxRow, error) {
+	result, err := tx.QueryContext(ctx, o.selectSQL, o.config.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer result.Close()
+
+	var rows []outboxRow
+	for result.Next() {
+		var row outboxRow
+		var headers sql.NullString
+		if err := result.Scan(&row.id, &row.queue, &row.payload, &headers); err != nil {
+			return nil, fmt.Errorf("failed to read outbox row: %w", err)
+		}
+		if headers.Valid && headers.String != "" {
+			if err := json.Unmarshal([]byte(headers.String), &row.headers); err != nil {
+				return nil, fmt.Errorf("invalid headers in outbox row %s: %w", row.id, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return rows, nil
+}
+
+// Close stops the relay. Unpublished rows stay in the table and are
+// published by the next outbox using it.
+func (o *Outbox) Close() error {
+	o.cancel()
+	o.wg.Wait()
+	return nil
+}
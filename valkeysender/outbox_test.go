@@ -0,0 +1,349 @@
+package valkeysender
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// outboxTable is the single outbox table of a fake database
+type outboxTable struct {
+	mu       sync.Mutex
+	rows     map[string]outboxTestRow
+	failMark bool // fail the next UPDATE, as if the relay crashed before marking
+}
+
+type outboxTestRow struct {
+	id          string
+	queue       string
+	payload     []byte
+	headers     driver.Value
+	createdAt   int64
+	publishedAt driver.Value
+}
+
+var (
+	outboxTablesMutex sync.Mutex
+	outboxTables      = map[string]*outboxTable{}
+)
+
+func init() {
+	sql.Register("outboxtest", outboxDriver{})
+}
+
+// openOutboxDB opens a fake database understanding the statements of the
+// sqlite dialect of the outbox
+func openOutboxDB(t *testing.T) (*sql.DB, *outboxTable) {
+	t.Helper()
+
+	table := &outboxTable{rows: make(map[string]outboxTestRow)}
+	outboxTablesMutex.Lock()
+	outboxTables[t.Name()] = table
+	outboxTablesMutex.Unlock()
+
+	db, err := sql.Open("outboxtest", t.Name())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, table
+}
+
+type outboxDriver struct{}
+
+func (outboxDriver) Open(name string) (driver.Conn, error) {
+	outboxTablesMutex.Lock()
+	defer outboxTablesMutex.Unlock()
+	table, ok := outboxTables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %q", name)
+	}
+	return &outboxConn{table: table}, nil
+}
+
+// outboxConn applies statements to a copy of the rows while a transaction is open
+type outboxConn struct {
+	table *outboxTable
+	tx    map[string]outboxTestRow
+}
+
+func (c *outboxConn) Prepare(query string) (driver.Stmt, error) {
+	return &outboxStmt{conn: c, query: query}, nil
+}
+
+func (c *outboxConn) Close() error {
+	return nil
+}
+
+func (c *outboxConn) Begin() (driver.Tx, error) {
+	c.table.mu.Lock()
+	c.tx = maps.Clone(c.table.rows)
+	c.table.mu.Unlock()
+	return c, nil
+}
+
+func (c *outboxConn) Commit() error {
+	c.table.mu.Lock()
+	c.table.rows = c.tx
+	c.table.mu.Unlock()
+	c.tx = nil
+	return nil
+}
+
+func (c *outboxConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+// exec runs a statement against the transaction or, outside one, the table
+func (c *outboxConn) exec(query string, args []driver.Value) (driver.Rows, error) {
+	rows := c.tx
+	if rows == nil {
+		c.table.mu.Lock()
+		defer c.table.mu.Unlock()
+		rows = c.table.rows
+	}
+
+	switch {
+	case strings.HasPrefix(query, "CREATE"):
+		return nil, nil
+	case strings.HasPrefix(query, "INSERT"):
+		rows[args[0].(string)] = outboxTestRow{
+			id:        args[0].(string),
+			queue:     args[1].(string),
+			payload:   args[2].([]byte),
+			headers:   args[3],
+			createdAt: args[4].(int64),
+		}
+		return nil, nil
+	case strings.HasPrefix(query, "SELECT"):
+		var pending []outboxTestRow
+		for _, row := range rows {
+			if row.publishedAt == nil {
+				pending = append(pending, row)
+			}
+		}
+		slices.SortFunc(pending, func(a, b outboxTestRow) int {
+			if a.createdAt != b.createdAt {
+				return int(a.createdAt - b.createdAt)
+			}
+			return strings.Compare(a.id, b.id)
+		})
+		return &outboxRows{rows: pending[:min(len(pending), int(args[0].(int64)))]}, nil
+	case strings.HasPrefix(query, "UPDATE"):
+		if c.table.failMark {
+			c.table.failMark = false
+			return nil, errors.New("connection lost")
+		}
+		row := rows[args[1].(string)]
+		row.publishedAt = args[0]
+		rows[row.id] = row
+		return nil, nil
+	case strings.HasPrefix(query, "DELETE"):
+		for id, row := range rows {
+			if row.publishedAt != nil && row.publishedAt.(int64) < args[0].(int64) {
+				delete(rows, id)
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported statement %q", query)
+}
+
+type outboxStmt struct {
+	conn  *outboxConn
+	query string
+}
+
+func (s *outboxStmt) Close() error {
+	return nil
+}
+
+func (s *outboxStmt) NumInput() int {
+	return -1
+}
+
+func (s *outboxStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.conn.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *outboxStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.exec(s.query, args)
+}
+
+type outboxRows struct {
+	rows []outboxTestRow
+}
+
+func (r *outboxRows) Columns() []string {
+	return []string{"id", "queue", "payload", "headers"}
+}
+
+func (r *outboxRows) Close() error {
+	return nil
+}
+
+func (r *outboxRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	dest[0], dest[1], dest[2], dest[3] = row.id, row.queue, row.payload, row.headers
+	return nil
+}
+
+// newTestOutbox creates an outbox whose relay only runs when a test calls Relay
+func newTestOutbox(t *testing.T, db *sql.DB, sender Sender) *Outbox {
+	t.Helper()
+
+	outbox, err := NewOutbox(db, sender, OutboxConfig{Dialect: OutboxDialectSQLite, PollInterval: 1 << 62})
+	if err != nil {
+		t.Fatalf("NewOutbox failed: %v", err)
+	}
+	t.Cleanup(func() { outbox.Close() })
+	if err := outbox.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	return outbox
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openOutboxDB(t)
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	outbox := newTestOutbox(t, db, s)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := outbox.StoreInTxContext(WithHeader(ctx, "trace-id", "t1"), tx, "registrations", map[string]string{"user": "u1"}); err != nil {
+		t.Fatalf("StoreInTx failed: %v", err)
+	}
+	if err := outbox.StoreInTx(tx, "registrations", map[string]string{"user": "u2"}); err != nil {
+		t.Fatalf("StoreInTx failed: %v", err)
+	}
+
+	// Nothing is published before the transaction commits
+	if published, err := outbox.Relay(ctx); err != nil || published != 0 {
+		t.Fatalf("Expected nothing to publish before commit, got %d, %v", published, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if published, err := outbox.Relay(ctx); err != nil || published != 2 {
+		t.Fatalf("Expected 2 rows published, got %d, %v", published, err)
+	}
+	if published, err := outbox.Relay(ctx); err != nil || published != 0 {
+		t.Fatalf("Expected published rows not to be relayed again, got %d, %v", published, err)
+	}
+
+	envelopes, err := s.PeekMessages(ctx, "registrations", 0, 10)
+	if err != nil {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if len(envelopes) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(envelopes))
+	}
+	if got := string(envelopes[0].Payload); got != `{"user":"u1"}` {
+		t.Errorf("Expected messages in order, got %s first", got)
+	}
+	if envelopes[0].Headers["trace-id"] != "t1" {
+		t.Errorf("Expected context headers to be stored, got %v", envelopes[0].Headers)
+	}
+}
+
+func TestOutboxRollback(t *testing.T) {
+	ctx := context.Background()
+	db, table := openOutboxDB(t)
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	outbox := newTestOutbox(t, db, s)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := outbox.StoreInTx(tx, "registrations", "u1"); err != nil {
+		t.Fatalf("StoreInTx failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if published, err := outbox.Relay(ctx); err != nil || published != 0 {
+		t.Fatalf("Expected nothing to publish after rollback, got %d, %v", published, err)
+	}
+	if len(table.rows) != 0 {
+		t.Errorf("Expected no rows after rollback, got %d", len(table.rows))
+	}
+}
+
+func TestOutboxRelayRetry(t *testing.T) {
+	ctx := context.Background()
+	db, table := openOutboxDB(t)
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	outbox := newTestOutbox(t, db, s)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := outbox.StoreInTx(tx, "registrations", "u1"); err != nil {
+		t.Fatalf("StoreInTx failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// The row is sent, but marking it fails, so the next pass sends it again
+	table.failMark = true
+	if _, err := outbox.Relay(ctx); err == nil {
+		t.Fatal("Expected relay to fail when marking fails")
+	}
+	if published, err := outbox.Relay(ctx); err != nil || published != 1 {
+		t.Fatalf("Expected row to be published on retry, got %d, %v", published, err)
+	}
+
+	if size, _ := s.GetQueueSize(ctx, "registrations"); size != 1 {
+		t.Errorf("Expected the resent row to be skipped as duplicate, got size %d", size)
+	}
+}
+
+func TestOutboxConfig(t *testing.T) {
+	db, _ := openOutboxDB(t)
+	s := newMemorySender(t)
+
+	if _, err := NewOutbox(db, s, OutboxConfig{Table: "outbox; DROP TABLE users"}); err == nil {
+		t.Error("Expected invalid table name to be rejected")
+	}
+	if _, err := NewOutbox(db, s, OutboxConfig{Dialect: "oracle"}); err == nil {
+		t.Error("Expected unknown dialect to be rejected")
+	}
+
+	outbox, err := NewOutbox(db, s, OutboxConfig{})
+	if err != nil {
+		t.Fatalf("NewOutbox failed: %v", err)
+	}
+	defer outbox.Close()
+	if outbox.markSQL != "UPDATE "+DefaultOutboxTable+" SET published_at = $1 WHERE id = $2" {
+		t.Errorf("Expected postgres placeholders, got %q", outbox.markSQL)
+	}
+	if !strings.HasSuffix(outbox.selectSQL, "LIMIT $1 FOR UPDATE SKIP LOCKED") {
+		t.Errorf("Expected rows to be locked, got %q", outbox.selectSQL)
+	}
+}
@@ -0,0 +1,73 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Overflow policies for Config.OverflowPolicy
+const (
+	// OverflowPolicyReject fails the send with ErrQueueFull
+	OverflowPolicyReject = "reject"
+
+	// OverflowPolicyDropOldest pushes the messages and trims the oldest ones
+	OverflowPolicyDropOldest = "drop-oldest"
+
+	// OverflowPolicyBlock waits for consumers to make room, up to OverflowBlockTimeout
+	OverflowPolicyBlock = "block"
+)
+
+// overflowPollInterval is how often a blocked send re-checks the queue length
+const overflowPollInterval = 50 * time.Millisecond
+
+// pushGroups pushes every group through the storage backend. Each group
+// succeeds or fails as a whole. In block mode groups that hit the length cap
+// are retried until they fit or the timeout expires.
+func (s *valkeySender) pushGroups(ctx context.Context, groups [][]*queueBatch) error {
+	return s.pushWithOverflow(ctx, func(policy pushPolicy) (*queueBatch, error) {
+		full, fullBatch, err := s.pushBackend(ctx).push(ctx, groups, policy)
+		groups = full
+		return fullBatch, err
+	})
+}
+
+// pushWithOverflow calls push until it stores everything, applying the
+// overflow policy to the first batch it reports as not fitting
+func (s *valkeySender) pushWithOverflow(ctx context.Context, push func(policy pushPolicy) (*queueBatch, error)) error {
+	policy := pushPolicy{
+		ttlStrategy: s.ttlStrategy(),
+		limits:      s.queueLimits,
+	}
+	deadline := time.Now().Add(s.config.OverflowBlockTimeout)
+
+	for {
+		policy.now = time.Now()
+		fullBatch, err := push(policy)
+		if err != nil || fullBatch == nil {
+			return err
+		}
+
+		// Give consumers a chance to drain the queue
+		if !s.queueLimits(fullBatch.queue).block || time.Now().Add(overflowPollInterval).After(deadline) {
+			return s.queueFullError(fullBatch)
+		}
+
+		timer := time.NewTimer(overflowPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// queueFullError reports that a batch does not fit into its queue
+func (s *valkeySender) queueFullError(batch *queueBatch) error {
+	return &Error{
+		Queue: batch.queue,
+		Kind:  ErrQueueFull,
+		Err:   fmt.Errorf("queue reached max length %d", s.queueLimits(batch.queue).maxLength),
+	}
+}
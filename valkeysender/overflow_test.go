@@ -0,0 +1,87 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newCappedSender(t *testing.T, server *miniredis.Miniredis, policy string, maxLength int) *valkeySender {
+	t.Helper()
+	
+	s := newTestSender(t, server, nil)
+	s.config.MaxQueueLength = maxLength
+	s.config.OverflowPolicy = policy
+	s.config.OverflowBlockTimeout = 200 * time.Millisecond
+	return s
+}
+
+func TestOverflowReject(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newCappedSender(t, server, OverflowPolicyReject, 2)
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("Expected batch within the cap to succeed, got %v", err)
+	}
+	
+	err := s.SendMessage(ctx, "orders", "c")
+	if !errors.Is(err, ErrQueueFull) || !IsRetryable(err) {
+		t.Fatalf("Expected retryable ErrQueueFull, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 2 {
+		t.Errorf("Expected rejected message not to be pushed, got size %d", size)
+	}
+	if ttl := server.TTL("queue:orders"); ttl != 24*time.Hour {
+		t.Errorf("Expected list TTL to be set, got %v", ttl)
+	}
+	if s.circuitBreaker.Counts().TotalFailures != 0 {
+		t.Error("Expected a full queue not to count against the circuit breaker")
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newCappedSender(t, server, OverflowPolicyDropOldest, 2)
+	
+	for _, message := range []string{"a", "b", "c"} {
+		if err := s.SendMessage(ctx, "orders", message); err != nil {
+			t.Fatalf("Expected drop-oldest send to succeed, got %v", err)
+		}
+	}
+	
+	envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+	if len(envelopes) != 2 || string(envelopes[0].Payload) != "b" || string(envelopes[1].Payload) != "c" {
+		t.Errorf("Expected oldest message to be dropped, got %+v", envelopes)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newCappedSender(t, server, OverflowPolicyBlock, 1)
+	
+	s.SendMessage(ctx, "orders", "a")
+	
+	// Times out while nobody consumes
+	start := time.Now()
+	if err := s.SendMessage(ctx, "orders", "b"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull after blocking, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected send to block before failing, returned after %v", elapsed)
+	}
+	
+	// Succeeds once a consumer makes room
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		server.RPop("queue:orders")
+	}()
+	if err := s.SendMessage(ctx, "orders", "b"); err != nil {
+		t.Errorf("Expected blocked send to succeed after a pop, got %v", err)
+	}
+}
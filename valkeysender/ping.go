@@ -0,0 +1,66 @@
+package valkeysender
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rttSamples is the number of PING round trips the RTT percentiles in
+// HealthStatus are computed over, about an hour at the default health
+// check interval
+const rttSamples = 128
+
+// rttWindow keeps the most recent PING round trips
+type rttWindow struct {
+	mu      sync.Mutex
+	samples [rttSamples]time.Duration
+	next    int // index the next sample is written to
+	count   int // samples held, up to rttSamples
+}
+
+// add records a round trip, replacing the oldest once the window is full
+func (w *rttWindow) add(rtt time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = rtt
+	w.next = (w.next + 1) % rttSamples
+	w.count = min(w.count+1, rttSamples)
+}
+
+// percentiles returns the median and 99th percentile of the window, zero
+// while it is empty
+func (w *rttWindow) percentiles() (p50, p99 time.Duration) {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples[:w.count])
+	w.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	slices.Sort(sorted)
+	return sorted[(len(sorted)-1)/2], sorted[(len(sorted)-1)*99/100]
+}
+
+// Ping measures the round trip of a PING to Valkey. Besides the background
+// health checks, every successful Ping feeds the RTT percentiles reported by
+// Health, which tell Valkey or the network being slow apart from a slow
+// producer: send latencies in Metrics rising while the RTT stays flat point
+// at the producer.
+func (s *valkeySender) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if err := s.backend.ping(ctx); err != nil {
+		return 0, classifyError(opPing, "", err)
+	}
+	rtt := time.Since(start)
+	s.recordPing(rtt)
+	return rtt, nil
+}
+
+// recordPing stores the round trip of a successful PING
+func (s *valkeySender) recordPing(rtt time.Duration) {
+	atomic.StoreInt64(&s.pingLatency, int64(rtt))
+	s.rtt.add(rtt)
+}
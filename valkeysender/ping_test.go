@@ -0,0 +1,54 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRTTWindow(t *testing.T) {
+	var w rttWindow
+	if p50, p99 := w.percentiles(); p50 != 0 || p99 != 0 {
+		t.Errorf("Expected zero percentiles while empty, got %v and %v", p50, p99)
+	}
+	
+	// 100 samples of 1ms..100ms, then overwrite the oldest with slow ones
+	for i := 1; i <= 100; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	if p50, p99 := w.percentiles(); p50 != 50*time.Millisecond || p99 != 99*time.Millisecond {
+		t.Errorf("Expected 50ms and 99ms, got %v and %v", p50, p99)
+	}
+	for i := 0; i < rttSamples; i++ {
+		w.add(time.Second)
+	}
+	if p50, _ := w.percentiles(); p50 != time.Second {
+		t.Errorf("Expected the old samples to roll out, got p50 %v", p50)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	rtt, err := s.Ping(ctx)
+	if err != nil || rtt <= 0 {
+		t.Fatalf("Expected a round trip, got %v, %v", rtt, err)
+	}
+	health := s.Health()
+	if health.Latency != rtt || health.LatencyP50 <= 0 || health.LatencyP99 < health.LatencyP50 {
+		t.Errorf("Expected the ping to feed Health, got %+v", health)
+	}
+	if s.Metrics().MessagesSent != 0 {
+		t.Error("Expected pings not to count as sends")
+	}
+	
+	server.Close()
+	if _, err := s.Ping(ctx); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected from a stopped server, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package valkeysender
+
+import (
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// poolSampleInterval is how often the pool watcher samples utilization, at
+// most a tenth of Config.PoolAlertDuration
+const poolSampleInterval = time.Second
+
+// poolTimeoutLogInterval limits pool timeout warnings, which every send
+// failing on an exhausted pool would log otherwise
+const poolTimeoutLogInterval = 10 * time.Second
+
+// poolHint is logged with pool exhaustion warnings
+const poolHint = "raise VALKEY_SENDER_POOL_SIZE, or find what holds connections so long"
+
+// poolWatch is the state the pool watcher keeps between samples
+type poolWatch struct {
+	aboveSince time.Time // when utilization reached the threshold, zero while below
+	alerting   bool
+}
+
+// startPoolWatcher samples pool utilization and alerts when it stays at or
+// above Config.PoolAlertThreshold for Config.PoolAlertDuration
+func (s *valkeySender) startPoolWatcher() {
+	if s.config.PoolAlertThreshold <= 0 || s.getClient() == nil {
+		return
+	}
+	interval := min(poolSampleInterval, max(s.config.PoolAlertDuration/10, time.Millisecond))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var watch poolWatch
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.watchPool(&watch, now)
+			}
+		}
+	}()
+}
+
+// watchPool takes one utilization sample and reports the pool once it
+// stayed above the threshold long enough, and again once it recovers
+func (s *valkeySender) watchPool(watch *poolWatch, now time.Time) {
+	pool := s.poolMetrics()
+	utilization := float64(pool.ActiveConns) / float64(s.poolMaxConns(pool))
+
+	if utilization < s.config.PoolAlertThreshold {
+		watch.aboveSince = time.Time{}
+		if !watch.alerting {
+			return
+		}
+		watch.alerting = false
+		s.logger.Info("Connection pool usage recovered",
+			slog.Float64("utilization", utilization),
+			slog.Int("active_conns", int(pool.ActiveConns)),
+			slog.Int("max_conns", s.poolMaxConns(pool)),
+		)
+		if s.options.PoolAlertHandler != nil {
+			s.options.PoolAlertHandler(PoolAlert{Utilization: utilization, Pool: pool, Recovered: true, Timestamp: now})
+		}
+		return
+	}
+
+	if watch.aboveSince.IsZero() {
+		watch.aboveSince = now
+	}
+	if watch.alerting || now.Sub(watch.aboveSince) < s.config.PoolAlertDuration {
+		return
+	}
+	watch.alerting = true
+
+	suggested := suggestedPoolSize(s.poolMaxConns(pool))
+	s.logger.Warn("Connection pool nearly exhausted",
+		slog.Float64("utilization", utilization),
+		slog.Int("active_conns", int(pool.ActiveConns)),
+		slog.Int("max_conns", s.poolMaxConns(pool)),
+		slog.Duration("for", now.Sub(watch.aboveSince)),
+		slog.Uint64("timeouts", uint64(pool.Timeouts)),
+		slog.Int("suggested_pool_size", suggested),
+		slog.String("hint", poolHint),
+	)
+	if s.options.PoolAlertHandler != nil {
+		s.options.PoolAlertHandler(PoolAlert{Utilization: utilization, Pool: pool, SuggestedSize: suggested, Timestamp: now})
+	}
+}
+
+// warnPoolTimeout logs a pool timeout with tuning advice, at most once per
+// poolTimeoutLogInterval, so an exhausted pool is told apart from Valkey
+// failing
+func (s *valkeySender) warnPoolTimeout(err error) {
+	if !errors.Is(err, ErrPoolTimeout) {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&s.poolTimeoutLog)
+	if last != 0 && now-last < int64(poolTimeoutLogInterval) || !atomic.CompareAndSwapInt64(&s.poolTimeoutLog, last, now) {
+		return
+	}
+
+	pool := s.poolMetrics()
+	s.logger.Warn("Timed out waiting for a pool connection",
+		slog.Int("active_conns", int(pool.ActiveConns)),
+		slog.Int("max_conns", s.poolMaxConns(pool)),
+		slog.Uint64("timeouts", uint64(pool.Timeouts)),
+		slog.Int("suggested_pool_size", suggestedPoolSize(s.poolMaxConns(pool))),
+		slog.String("hint", poolHint),
+	)
+}
+
+// poolMaxConns returns the pool size, from the configuration when the
+// client doesn't report it
+func (s *valkeySender) poolMaxConns(pool PoolMetrics) int {
+	if pool.MaxConns > 0 {
+		return int(pool.MaxConns)
+	}
+	return max(s.config.PoolSize, 1)
+}
+
+// suggestedPoolSize returns a pool size with half as many connections again
+func suggestedPoolSize(size int) int {
+	return size + (size+1)/2
+}
@@ -0,0 +1,110 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newPoolSender creates a sender with a single pooled connection
+func newPoolSender(t *testing.T, server *miniredis.Miniredis, logs *bytes.Buffer, handler func(PoolAlert)) *valkeySender {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.PoolSize = 1
+	config.MinIdleConns = 0
+	config.PoolTimeout = 50 * time.Millisecond
+	config.PoolAlertThreshold = 1
+	config.PoolAlertDuration = 50 * time.Millisecond
+
+	sender, err := NewSender(config, &SenderOptions{
+		Logger:           slog.New(slog.NewTextHandler(logs, nil)),
+		PoolAlertHandler: handler,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender)
+}
+
+// holdConnection checks the only pool connection out until the returned function is called
+func holdConnection(t *testing.T, s *valkeySender) func() {
+	t.Helper()
+	conn := s.getClient().(*redis.Client).Conn()
+	if err := conn.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	return func() { conn.Close() }
+}
+
+func TestPoolTimeout(t *testing.T) {
+	server := miniredis.RunT(t)
+	var logs bytes.Buffer
+	s := newPoolSender(t, server, &logs, nil)
+	release := holdConnection(t, s)
+	defer release()
+
+	for i := 0; i < 2; i++ {
+		err := s.SendMessage(context.Background(), "orders", i)
+		if !errors.Is(err, ErrPoolTimeout) || !IsRetryable(err) {
+			t.Fatalf("Expected a retryable ErrPoolTimeout, got %v", err)
+		}
+	}
+	if count := strings.Count(logs.String(), "Timed out waiting for a pool connection"); count != 1 {
+		t.Errorf("Expected one rate limited warning, got %d in %s", count, logs.String())
+	}
+	if !strings.Contains(logs.String(), "suggested_pool_size=2") {
+		t.Errorf("Expected a pool size suggestion, got %s", logs.String())
+	}
+	if pool := s.Health().ConnectionPool; pool.Timeouts != 2 {
+		t.Errorf("Expected two pool timeouts, got %+v", pool)
+	}
+}
+
+func TestPoolAlert(t *testing.T) {
+	server := miniredis.RunT(t)
+	var mu sync.Mutex
+	var alerts []PoolAlert
+	s := newPoolSender(t, server, &bytes.Buffer{}, func(alert PoolAlert) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, alert)
+	})
+	received := func() []PoolAlert {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]PoolAlert(nil), alerts...)
+	}
+
+	release := holdConnection(t, s)
+	waitFor(t, func() bool { return len(received()) == 1 })
+	alert := received()[0]
+	if alert.Recovered || alert.Utilization != 1 || alert.SuggestedSize != 2 || alert.Pool.ActiveConns != 1 {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+
+	release()
+	waitFor(t, func() bool { return len(received()) == 2 })
+	if alert := received()[1]; !alert.Recovered || alert.Utilization != 0 {
+		t.Errorf("Expected a recovery, got %+v", alert)
+	}
+}
+
+func TestSuggestedPoolSize(t *testing.T) {
+	for size, want := range map[int]int{1: 2, 2: 3, 10: 15, 25: 38} {
+		if got := suggestedPoolSize(size); got != want {
+			t.Errorf("Expected %d for %d, got %d", want, size, got)
+		}
+	}
+}
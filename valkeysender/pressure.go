@@ -0,0 +1,69 @@
+package valkeysender
+
+import (
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// defaultPressureWait is the rate limiter wait reported as full pressure
+// when Config.SendTimeout is unset
+const defaultPressureWait = time.Second
+
+// Pressure returns a backpressure score between 0 (idle) and 1 (sends are
+// about to be refused or time out), so callers can shed load early. It is the
+// highest of three signals:
+//
+//   - the circuit breaker: 1 when open, 0.5 when half-open
+//   - the rate limiter: the wait for the next token as a share of
+//     Config.SendTimeout (1s if unset); any wait counts fully in reject mode
+//   - in-flight sends as a share of Config.PoolSize
+func (s *valkeySender) Pressure() float64 {
+	return max(s.breakerPressure(), s.rateLimitPressure(time.Now()), s.inflightPressure())
+}
+
+// breakerPressure maps the circuit breaker state to a pressure
+func (s *valkeySender) breakerPressure() float64 {
+	switch s.circuitBreaker.State() {
+	case gobreaker.StateOpen:
+		return 1
+	case gobreaker.StateHalfOpen:
+		return 0.5
+	}
+	return 0
+}
+
+// rateLimitPressure is the wait for the next rate limiter token relative to
+// the send timeout
+func (s *valkeySender) rateLimitPressure(now time.Time) float64 {
+	tokens := s.rateLimiter.TokensAt(now)
+	if tokens >= 1 {
+		return 0
+	}
+	if strings.EqualFold(s.config.RateLimitMode, RateLimitModeReject) {
+		return 1
+	}
+
+	limit := float64(s.rateLimiter.Limit())
+	if limit <= 0 {
+		return 1
+	}
+	wait := time.Duration((1 - tokens) / limit * float64(time.Second))
+	budget := s.config.SendTimeout
+	if budget <= 0 {
+		budget = defaultPressureWait
+	}
+	return math.Min(float64(wait)/float64(budget), 1)
+}
+
+// inflightPressure is the share of the connection pool taken by in-flight sends
+func (s *valkeySender) inflightPressure() float64 {
+	poolSize := s.config.PoolSize
+	if poolSize <= 0 {
+		return 0
+	}
+	return math.Min(float64(atomic.LoadInt64(&s.inflightSends))/float64(poolSize), 1)
+}
@@ -0,0 +1,62 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"golang.org/x/time/rate"
+)
+
+func TestPressure(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.SendTimeout = 10 * time.Second
+	
+	if p := s.Pressure(); p != 0 {
+		t.Errorf("Expected no pressure on an idle sender, got %v", p)
+	}
+	
+	// One token per second, used up: the next send waits 1s of a 10s budget
+	s.rateLimiter = rate.NewLimiter(1, 1)
+	s.rateLimiter.Allow()
+	if p := s.Pressure(); p < 0.08 || p > 0.1 {
+		t.Errorf("Expected rate limit pressure around 0.1, got %v", p)
+	}
+	
+	// Any wait is full pressure when sends are rejected instead
+	s.config.RateLimitMode = RateLimitModeReject
+	if p := s.Pressure(); p != 1 {
+		t.Errorf("Expected full pressure in reject mode, got %v", p)
+	}
+	s.config.RateLimitMode = RateLimitModeWait
+	s.rateLimiter = rate.NewLimiter(rate.Inf, 1)
+	
+	// In-flight sends fill the pool
+	s.config.PoolSize = 4
+	var releases []func(error)
+	for i := 0; i < 3; i++ {
+		_, done, err := s.beginSend(ctx, 1)
+		if err != nil {
+			t.Fatalf("beginSend failed: %v", err)
+		}
+		releases = append(releases, done)
+	}
+	if p := s.Pressure(); p != 0.75 {
+		t.Errorf("Expected in-flight pressure 0.75, got %v", p)
+	}
+	for _, done := range releases {
+		done(nil)
+	}
+	
+	// An open breaker is full pressure
+	server.Close()
+	for i := 0; i < 5; i++ {
+		s.SendMessage(ctx, "orders", "a")
+	}
+	if p := s.Pressure(); p != 1 {
+		t.Errorf("Expected full pressure with the breaker open, got %v", p)
+	}
+}
@@ -0,0 +1,71 @@
+package valkeysender
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+)
+
+// Envelope metadata keys stamped by the sender unless
+// Config.ProducerMetadata is disabled
+const (
+	MetadataProducer       = "producer"        // Config.ProducerName, or the executable name
+	MetadataHost           = "host"            // hostname of the producing machine
+	MetadataPID            = "pid"             // process ID of the producer
+	MetadataLibraryVersion = "library_version" // valkeysender module version
+	MetadataSchemaVersion  = "schema_version"  // envelope schema version, see EnvelopeVersion
+)
+
+// modulePath is the module path of this library, looked up in the build info
+const modulePath = "github.com/prilive-com/valkeysender"
+
+// libraryVersion returns the version of this module the binary was built
+// with, "(devel)" when built from a working copy
+var libraryVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+})
+
+// producerName returns Config.ProducerName, or the executable name if it is empty
+func producerName(config *Config) string {
+	if config.ProducerName != "" {
+		return config.ProducerName
+	}
+	if executable, err := os.Executable(); err == nil {
+		return filepath.Base(executable)
+	}
+	return ""
+}
+
+// producerMetadata builds the metadata identifying this producer, nil if
+// Config.ProducerMetadata is disabled. The map is shared by every envelope
+// the sender creates and must not be modified.
+func producerMetadata(config *Config) map[string]interface{} {
+	if !config.ProducerMetadata {
+		return nil
+	}
+
+	host, _ := os.Hostname()
+	return map[string]interface{}{
+		MetadataProducer:       producerName(config),
+		MetadataHost:           host,
+		MetadataPID:            os.Getpid(),
+		MetadataLibraryVersion: libraryVersion(),
+		MetadataSchemaVersion:  EnvelopeVersion,
+	}
+}
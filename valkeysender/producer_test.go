@@ -0,0 +1,53 @@
+package valkeysender
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestProducerMetadata(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.metadata = producerMetadata(&Config{ProducerName: "billing-api", ProducerMetadata: true})
+	
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	envelopes, err := s.PeekMessages(ctx, "orders", 0, 1)
+	if err != nil || len(envelopes) != 1 {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	
+	host, _ := os.Hostname()
+	metadata := envelopes[0].Metadata
+	if metadata[MetadataProducer] != "billing-api" || metadata[MetadataHost] != host {
+		t.Errorf("Unexpected producer identity: %v", metadata)
+	}
+	// Numbers come back as float64 from JSON
+	if metadata[MetadataPID] != float64(os.Getpid()) || metadata[MetadataSchemaVersion] != float64(EnvelopeVersion) {
+		t.Errorf("Unexpected pid or schema version: %v", metadata)
+	}
+	if metadata[MetadataLibraryVersion] == "" {
+		t.Errorf("Expected a library version: %v", metadata)
+	}
+}
+
+func TestProducerMetadataDefaults(t *testing.T) {
+	config := DefaultConfig()
+	if !config.ProducerMetadata {
+		t.Fatal("Expected producer metadata by default")
+	}
+	executable, _ := os.Executable()
+	if metadata := producerMetadata(config); metadata[MetadataProducer] == "" || metadata[MetadataProducer] == executable {
+		t.Errorf("Expected the executable base name as producer, got %v", metadata[MetadataProducer])
+	}
+	
+	config.ProducerMetadata = false
+	if metadata := producerMetadata(config); metadata != nil {
+		t.Errorf("Expected no metadata when disabled, got %v", metadata)
+	}
+}
@@ -0,0 +1,226 @@
+package valkeysender
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Serializer names for QueueProfile.Serializer
+const (
+	// SerializerJSON encodes payloads with JSONSerializer
+	SerializerJSON = "json"
+
+	// SerializerProtobuf encodes payloads with ProtoSerializer
+	SerializerProtobuf = "protobuf"
+)
+
+// QueueProfile overrides sender-wide settings for one queue, see
+// Config.Queues. Zero values keep the sender-wide setting.
+type QueueProfile struct {
+	// Default message TTL of the queue
+	MessageTTL time.Duration
+
+	// Rate limit of sends to the queue, replacing the sender-wide limiter
+	// (RateLimitBurst defaults to RateLimitRequests)
+	RateLimitRequests int
+	RateLimitBurst    int
+
+	// Queue length cap (-1 removes the sender-wide cap) and overflow policy
+	MaxQueueLength int
+	OverflowPolicy string
+
+	// Payload serializer: "json" or "protobuf" (empty uses SenderOptions.Serializer)
+	Serializer string
+
+	// Deduplication on or off (nil follows SenderOptions.EnableDeduplication)
+	// and its window
+	Deduplication       *bool
+	DeduplicationWindow time.Duration
+}
+
+// queueLimits is the part of a push policy that can differ per queue
+type queueLimits struct {
+	maxLength   int
+	dropOldest  bool
+	block       bool
+	dedupWindow time.Duration
+}
+
+// validate checks the settings of a profile
+func (p QueueProfile) validate() error {
+	if p.MessageTTL != 0 && p.MessageTTL < time.Second {
+		return fmt.Errorf("message TTL must be at least 1 second")
+	}
+
+	if p.RateLimitRequests < 0 || p.RateLimitBurst < 0 {
+		return fmt.Errorf("rate limit cannot be negative")
+	}
+
+	if p.MaxQueueLength < -1 {
+		return fmt.Errorf("max queue length must be positive, or -1 to remove the cap")
+	}
+
+	switch strings.ToLower(p.OverflowPolicy) {
+	case "", OverflowPolicyReject, OverflowPolicyDropOldest, OverflowPolicyBlock:
+	default:
+		return fmt.Errorf("overflow policy must be %q, %q or %q", OverflowPolicyReject, OverflowPolicyDropOldest, OverflowPolicyBlock)
+	}
+
+	switch strings.ToLower(p.Serializer) {
+	case "", SerializerJSON, SerializerProtobuf:
+	default:
+		return fmt.Errorf("serializer must be %q or %q", SerializerJSON, SerializerProtobuf)
+	}
+
+	if p.DeduplicationWindow < 0 {
+		return fmt.Errorf("deduplication window cannot be negative")
+	}
+
+	return nil
+}
+
+// parseQueueProfiles parses the JSON form of Config.Queues, keyed by queue
+// name with settings named like the matching environment variables:
+//
+//	{"payments": {"message_ttl": "720h", "max_queue_length": 100000}}
+func parseQueueProfiles(value string) (map[string]QueueProfile, error) {
+	var raw map[string]map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]QueueProfile, len(raw))
+	for queue, settings := range raw {
+		var profile QueueProfile
+		for key, v := range settings {
+			// Settings are read as strings, like environment variables
+			setting := fmt.Sprint(v)
+			var err error
+			switch strings.ToLower(strings.ReplaceAll(key, "-", "_")) {
+			case "message_ttl":
+				profile.MessageTTL, err = time.ParseDuration(setting)
+			case "rate_limit_requests":
+				profile.RateLimitRequests, err = strconv.Atoi(setting)
+			case "rate_limit_burst":
+				profile.RateLimitBurst, err = strconv.Atoi(setting)
+			case "max_queue_length":
+				profile.MaxQueueLength, err = strconv.Atoi(setting)
+			case "overflow_policy":
+				profile.OverflowPolicy = setting
+			case "serializer":
+				profile.Serializer = setting
+			case "deduplication":
+				var enabled bool
+				enabled, err = strconv.ParseBool(setting)
+				profile.Deduplication = &enabled
+			case "deduplication_window":
+				profile.DeduplicationWindow, err = time.ParseDuration(setting)
+			default:
+				err = fmt.Errorf("unknown setting")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("queue %s: %s: %w", queue, key, err)
+			}
+		}
+		profiles[queue] = profile
+	}
+	return profiles, nil
+}
+
+// initQueueProfiles creates the serializers and rate limiters of the queue profiles
+func (s *valkeySender) initQueueProfiles() {
+	for queue, profile := range s.config.Queues {
+		switch strings.ToLower(profile.Serializer) {
+		case SerializerJSON:
+			s.setQueueSerializer(queue, NewJSONSerializer())
+		case SerializerProtobuf:
+			s.setQueueSerializer(queue, NewProtoSerializer())
+		}
+
+		if profile.RateLimitRequests > 0 {
+			burst := profile.RateLimitBurst
+			if burst == 0 {
+				burst = profile.RateLimitRequests
+			}
+			if s.queueLimiters == nil {
+				s.queueLimiters = make(map[string]*rate.Limiter)
+			}
+			s.queueLimiters[queue] = rate.NewLimiter(rate.Limit(profile.RateLimitRequests), burst)
+		}
+	}
+}
+
+// setQueueSerializer records the serializer of a queue's profile
+func (s *valkeySender) setQueueSerializer(queue string, serializer MessageSerializer) {
+	if s.queueSerializers == nil {
+		s.queueSerializers = make(map[string]MessageSerializer)
+	}
+	s.queueSerializers[queue] = serializer
+}
+
+// messageTTL returns the default TTL of messages sent to queue
+func (s *valkeySender) messageTTL(queue string) time.Duration {
+	if profile, ok := s.config.Queues[queue]; ok && profile.MessageTTL > 0 {
+		return profile.MessageTTL
+	}
+	return s.config.MessageTTL
+}
+
+// serializerFor returns the payload serializer of queue
+func (s *valkeySender) serializerFor(queue string) MessageSerializer {
+	if serializer, ok := s.queueSerializers[queue]; ok {
+		return serializer
+	}
+	if s.options.SerializerFor != nil {
+		if serializer := s.options.SerializerFor(queue); serializer != nil {
+			return serializer
+		}
+	}
+	return s.serializer
+}
+
+// deduplicates reports whether messages sent to queue are deduplicated
+func (s *valkeySender) deduplicates(queue string) bool {
+	if profile, ok := s.config.Queues[queue]; ok && profile.Deduplication != nil {
+		return *profile.Deduplication
+	}
+	return s.options.EnableDeduplication
+}
+
+// rateLimiterFor returns the rate limiter of queue
+func (s *valkeySender) rateLimiterFor(queue string) *rate.Limiter {
+	if limiter, ok := s.queueLimiters[queue]; ok {
+		return limiter
+	}
+	return s.rateLimiter
+}
+
+// queueLimits returns the length cap, overflow policy and deduplication window of queue
+func (s *valkeySender) queueLimits(queue string) queueLimits {
+	maxLength, policy, window := s.config.MaxQueueLength, s.config.OverflowPolicy, s.dedupWindow()
+	if profile, ok := s.config.Queues[queue]; ok {
+		if profile.MaxQueueLength != 0 {
+			maxLength = max(profile.MaxQueueLength, 0)
+		}
+		if profile.OverflowPolicy != "" {
+			policy = profile.OverflowPolicy
+		}
+		if profile.DeduplicationWindow > 0 {
+			window = profile.DeduplicationWindow
+		}
+	}
+
+	return queueLimits{
+		maxLength:   maxLength,
+		dropOldest:  strings.EqualFold(policy, OverflowPolicyDropOldest),
+		block:       maxLength > 0 && strings.EqualFold(policy, OverflowPolicyBlock),
+		dedupWindow: window,
+	}
+}
@@ -0,0 +1,291 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseQueueProfiles(t *testing.T) {
+	profiles, err := parseQueueProfiles(`{
+		"payments": {"message_ttl": "720h", "max_queue_length": 100000, "overflow_policy": "block", "deduplication": true, "deduplication_window": "1h"},
+		"notifications": {"MESSAGE-TTL": "5m", "rate_limit_requests": 50, "rate_limit_burst": "100", "serializer": "protobuf"}
+	}`)
+	if err != nil {
+		t.Fatalf("parseQueueProfiles failed: %v", err)
+	}
+	
+	payments := profiles["payments"]
+	if payments.MessageTTL != 720*time.Hour || payments.MaxQueueLength != 100000 || payments.OverflowPolicy != OverflowPolicyBlock {
+		t.Errorf("Unexpected payments profile: %+v", payments)
+	}
+	if payments.Deduplication == nil || !*payments.Deduplication || payments.DeduplicationWindow != time.Hour {
+		t.Errorf("Expected deduplication with a 1h window, got %+v", payments)
+	}
+	
+	notifications := profiles["notifications"]
+	if notifications.MessageTTL != 5*time.Minute || notifications.RateLimitRequests != 50 || notifications.RateLimitBurst != 100 {
+		t.Errorf("Unexpected notifications profile: %+v", notifications)
+	}
+	if notifications.Serializer != SerializerProtobuf || notifications.Deduplication != nil {
+		t.Errorf("Unexpected notifications profile: %+v", notifications)
+	}
+	
+	for _, invalid := range []string{
+		`not json`,
+		`{"payments": "720h"}`,
+		`{"payments": {"message_ttl": "forever"}}`,
+		`{"payments": {"max_queue_length": "many"}}`,
+		`{"payments": {"priority": 1}}`,
+	} {
+		if _, err := parseQueueProfiles(invalid); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestQueueProfileValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile QueueProfile
+		wantErr string
+	}{
+		{"short TTL", QueueProfile{MessageTTL: time.Millisecond}, "message TTL"},
+		{"negative rate", QueueProfile{RateLimitRequests: -1}, "rate limit"},
+		{"negative length", QueueProfile{MaxQueueLength: -2}, "max queue length"},
+		{"bad policy", QueueProfile{OverflowPolicy: "spill"}, "overflow policy"},
+		{"bad serializer", QueueProfile{Serializer: "xml"}, "serializer"},
+		{"negative window", QueueProfile{DeduplicationWindow: -time.Second}, "deduplication window"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Queues = map[string]QueueProfile{"payments": tt.profile}
+			err := config.validate()
+			if err == nil || !strings.Contains(err.Error(), "queue profile payments: "+tt.wantErr) {
+				t.Errorf("Expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	
+	config := DefaultConfig()
+	config.Queues = map[string]QueueProfile{"audit": {MaxQueueLength: -1, Serializer: SerializerJSON}}
+	if err := config.validate(); err != nil {
+		t.Errorf("Expected valid profile, got %v", err)
+	}
+}
+
+func TestLoadQueueProfiles(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("VALKEY_SENDER_QUEUES", `{"audit": {"message_ttl": "2160h"}}`)
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if config.Queues["audit"].MessageTTL != 2160*time.Hour {
+			t.Errorf("Expected audit TTL 2160h, got %+v", config.Queues)
+		}
+		
+		t.Setenv("VALKEY_SENDER_QUEUES", `{"audit": {"message_ttl": 1}}`)
+		if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "invalid queue profiles") {
+			t.Errorf("Expected invalid queue profiles error, got %v", err)
+		}
+	})
+	
+	t.Run("file", func(t *testing.T) {
+		path := t.TempDir() + "/config.yaml"
+		content := `
+queues:
+  user_events:
+    message_ttl: 5m
+    max_queue_length: 1000
+  payments:
+    deduplication: true
+`
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		
+		config, err := LoadConfigFromFile(path)
+		if err != nil {
+			t.Fatalf("LoadConfigFromFile failed: %v", err)
+		}
+		if profile := config.Queues["user_events"]; profile.MessageTTL != 5*time.Minute || profile.MaxQueueLength != 1000 {
+			t.Errorf("Unexpected user_events profile: %+v", profile)
+		}
+		if profile := config.Queues["payments"]; profile.Deduplication == nil || !*profile.Deduplication {
+			t.Errorf("Unexpected payments profile: %+v", profile)
+		}
+	})
+	
+	t.Run("option", func(t *testing.T) {
+		config, _, err := NewConfig("localhost:6379",
+			WithQueueProfile("payments", QueueProfile{MessageTTL: 720 * time.Hour}),
+			WithQueueProfile("notifications", QueueProfile{MessageTTL: time.Minute}),
+		)
+		if err != nil {
+			t.Fatalf("NewConfig failed: %v", err)
+		}
+		if len(config.Queues) != 2 || config.Queues["payments"].MessageTTL != 720*time.Hour {
+			t.Errorf("Unexpected profiles: %+v", config.Queues)
+		}
+	})
+}
+
+func TestQueueProfiles(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			dedup := true
+			s.config.MaxQueueLength = 2
+			s.config.Queues = map[string]QueueProfile{
+				"audit":         {MessageTTL: 720 * time.Hour, MaxQueueLength: -1},
+				"notifications": {MessageTTL: time.Minute, MaxQueueLength: 1, Deduplication: &dedup},
+			}
+			
+			// TTL
+			for _, queue := range []string{"audit", "notifications", "orders"} {
+				if err := s.SendMessage(ctx, queue, "m"); err != nil {
+					t.Fatalf("SendMessage to %s failed: %v", queue, err)
+				}
+			}
+			for queue, want := range map[string]time.Duration{"audit": 720 * time.Hour, "notifications": time.Minute, "orders": 24 * time.Hour} {
+				envelopes, _ := s.PeekMessages(ctx, queue, 0, 1)
+				if len(envelopes) != 1 || envelopes[0].TTL != want {
+					t.Errorf("Expected %s TTL %v, got %+v", queue, want, envelopes)
+				}
+			}
+			
+			// Deduplication only applies to notifications
+			if err := s.SendMessage(ctx, "notifications", "m"); err != nil {
+				t.Errorf("Expected duplicate to be skipped, got %v", err)
+			}
+			if err := s.SendMessage(ctx, "orders", "m"); err != nil {
+				t.Errorf("SendMessage failed: %v", err)
+			}
+			
+			// Length caps: audit is unbounded, notifications holds one message
+			for i := 0; i < 3; i++ {
+				if err := s.SendMessage(ctx, "audit", i); err != nil {
+					t.Errorf("Expected uncapped audit queue, got %v", err)
+				}
+			}
+			if err := s.SendMessage(ctx, "notifications", "n"); !errors.Is(err, ErrQueueFull) || !strings.Contains(err.Error(), "max length 1") {
+				t.Errorf("Expected notifications to be full at 1, got %v", err)
+			}
+			if err := s.SendMessage(ctx, "orders", "o"); !errors.Is(err, ErrQueueFull) {
+				t.Errorf("Expected orders to be full at 2, got %v", err)
+			}
+			
+			for queue, want := range map[string]int64{"audit": 4, "notifications": 1, "orders": 2} {
+				if size, _ := s.GetQueueSize(ctx, queue); size != want {
+					t.Errorf("Expected %d messages in %s, got %d", want, queue, size)
+				}
+			}
+			
+			// Fan-out copies follow the profile of their queue
+			if err := s.SendToQueues(ctx, []string{"orders2", "audit"}, "f"); err != nil {
+				t.Fatalf("SendToQueues failed: %v", err)
+			}
+			if envelopes, _ := s.PeekMessages(ctx, "audit", 4, 1); len(envelopes) != 1 || envelopes[0].TTL != 720*time.Hour {
+				t.Errorf("Expected fan-out copy with the audit TTL, got %+v", envelopes)
+			}
+		})
+	}
+}
+
+func TestQueueProfileRateLimitAndSerializer(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.RateLimitMode = RateLimitModeReject
+	s.config.Queues = map[string]QueueProfile{
+		"notifications": {RateLimitRequests: 1},
+		"events":        {Serializer: SerializerProtobuf},
+	}
+	s.initQueueProfiles()
+	
+	if err := s.SendMessage(ctx, "notifications", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if err := s.SendMessage(ctx, "notifications", "b"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected notifications to be rate limited, got %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Errorf("Expected other queues to use the sender-wide limit, got %v", err)
+	}
+	
+	if err := s.SendMessage(ctx, "events", wrapperspb.String("hello")); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	envelopes, _ := s.PeekMessages(ctx, "events", 0, 1)
+	if len(envelopes) != 1 || envelopes[0].Headers[HeaderProtoMessage] != "google.protobuf.StringValue" {
+		t.Errorf("Expected protobuf payload, got %+v", envelopes)
+	}
+	if err := s.SendMessage(ctx, "orders", wrapperspb.String("hello")); err != nil {
+		t.Errorf("Expected JSON serializer for other queues, got %v", err)
+	}
+	envelopes, _ = s.PeekMessages(ctx, "orders", 1, 1)
+	if len(envelopes) != 1 || envelopes[0].Headers[HeaderProtoMessage] != "" {
+		t.Errorf("Expected JSON payload for orders, got %+v", envelopes)
+	}
+}
+
+func TestQueueProfileRateLimitRefund(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.config.RateLimitMode = RateLimitModeReject
+	s.config.Queues = map[string]QueueProfile{
+		"notifications": {RateLimitRequests: 1},
+		"audit":         {RateLimitRequests: 1},
+	}
+	s.initQueueProfiles()
+	
+	if err := s.SendMessage(ctx, "audit", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	// audit rejects after notifications granted its token
+	if err := s.SendToQueues(ctx, []string{"notifications", "audit"}, "b"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected audit to be rate limited, got %v", err)
+	}
+	if err := s.SendMessage(ctx, "notifications", "c"); err != nil {
+		t.Errorf("Expected the rejected send to return the notifications token, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "notifications"); size != 1 {
+		t.Errorf("Expected 1 message in notifications, got %d", size)
+	}
+}
+
+func TestSerializerFor(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	s.options.SerializerFor = func(queue string) MessageSerializer {
+		if queue == "analytics" || queue == "audit" {
+			return NewProtoSerializer()
+		}
+		return nil
+	}
+	s.config.Queues = map[string]QueueProfile{"audit": {Serializer: SerializerJSON}}
+	s.initQueueProfiles()
+	
+	for queue, want := range map[string]string{
+		"analytics": "google.protobuf.StringValue",
+		"audit":     "", // the profile wins
+		"orders":    "", // nil falls back to the sender's serializer
+	} {
+		if err := s.SendMessage(ctx, queue, wrapperspb.String("hello")); err != nil {
+			t.Fatalf("SendMessage to %s failed: %v", queue, err)
+		}
+		envelopes, _ := s.PeekMessages(ctx, queue, 0, 1)
+		if len(envelopes) != 1 || envelopes[0].Headers[HeaderProtoMessage] != want {
+			t.Errorf("Expected %s to carry proto message %q, got %+v", queue, want, envelopes)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package valkeysender
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// HeaderProtoMessage is the envelope header carrying the fully-qualified protobuf message name
+const HeaderProtoMessage = "proto-message"
+
+// ProtoSerializer implements MessageSerializer using protobuf binary encoding
+type ProtoSerializer struct {
+	// Registry used to resolve message names (if nil, the global registry is used)
+	Registry *protoregistry.Types
+}
+
+// NewProtoSerializer creates a new protobuf serializer backed by the global registry
+func NewProtoSerializer() *ProtoSerializer {
+	return &ProtoSerializer{}
+}
+
+// Serialize converts a proto.Message to protobuf bytes
+func (s *ProtoSerializer) Serialize(message interface{}) ([]byte, error) {
+	msg, ok := message.(proto.Message)
+	if !ok || msg == nil {
+		return nil, fmt.Errorf("message must implement proto.Message, got %T", message)
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize message to protobuf: %w", err)
+	}
+
+	return data, nil
+}
+
+// Deserialize converts protobuf bytes back into the target proto.Message
+func (s *ProtoSerializer) Deserialize(data []byte, target interface{}) error {
+	msg, ok := target.(proto.Message)
+	if !ok || msg == nil {
+		return fmt.Errorf("target must implement proto.Message, got %T", target)
+	}
+
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("failed to deserialize protobuf to target: %w", err)
+	}
+
+	return nil
+}
+
+// ContentType returns the content type for protobuf
+func (s *ProtoSerializer) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// Headers records the fully-qualified message name so consumers can decode the payload
+func (s *ProtoSerializer) Headers(message interface{}) map[string]string {
+	msg, ok := message.(proto.Message)
+	if !ok || msg == nil {
+		return nil
+	}
+	return map[string]string{
+		HeaderProtoMessage: string(msg.ProtoReflect().Descriptor().FullName()),
+	}
+}
+
+// DeserializeByName decodes data into a new message of the named type looked up in the registry
+func (s *ProtoSerializer) DeserializeByName(name string, data []byte) (proto.Message, error) {
+	registry := s.Registry
+	if registry == nil {
+		registry = protoregistry.GlobalTypes
+	}
+
+	messageType, err := registry.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown protobuf message %s: %w", name, err)
+	}
+
+	msg := messageType.New().Interface()
+	if err := s.Deserialize(data, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// DeserializeEnvelope decodes an envelope payload using the message name stored in its headers
+func (s *ProtoSerializer) DeserializeEnvelope(envelope MessageEnvelope) (proto.Message, error) {
+	name := envelope.Headers[HeaderProtoMessage]
+	if name == "" {
+		return nil, fmt.Errorf("envelope %s has no %s header", envelope.ID, HeaderProtoMessage)
+	}
+	return s.DeserializeByName(name, envelope.Payload)
+}
@@ -0,0 +1,247 @@
+package valkeysender
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// dialFunc dials a connection to addr
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// parseProxyURL parses and checks Config.ProxyURL. Errors never include
+// the URL, which may carry a password.
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected socks5, socks5h or http", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy URL must include a host and port")
+	}
+	return u, nil
+}
+
+// newProxyDialer returns a dialer tunnelling connections through the proxy,
+// then starting TLS with the server if tlsConfig is set, since go-redis
+// leaves TLS to custom dialers
+func newProxyDialer(rawURL string, dialTimeout time.Duration, tlsConfig *tls.Config) (dialFunc, error) {
+	proxy, err := parseProxyURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	netDialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 5 * time.Minute}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := netDialer.DialContext(ctx, "tcp", proxy.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+		}
+
+		// Bound the handshake by the dial timeout and the caller's context
+		deadline := time.Now().Add(dialTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetDeadline(deadline)
+
+		tunnel := conn
+		if proxy.Scheme == "http" {
+			tunnel, err = httpConnect(conn, proxy, addr)
+		} else {
+			err = socks5Connect(conn, proxy, addr)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy failed to connect to %s: %w", addr, err)
+		}
+		tunnel.SetDeadline(time.Time{})
+
+		return startTLS(ctx, tunnel, addr, tlsConfig)
+	}, nil
+}
+
+// startTLS starts TLS with addr over a tunnelled connection, if tlsConfig is set
+func startTLS(ctx context.Context, conn net.Conn, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	if tlsConfig == nil {
+		return conn, nil
+	}
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// SOCKS5 protocol values (RFC 1928 and RFC 1929)
+const (
+	socks5Version      = 5
+	socks5NoAuth       = 0
+	socks5PasswordAuth = 2
+	socks5NoAcceptable = 0xff
+	socks5CmdConnect   = 1
+	socks5IPv4         = 1
+	socks5Domain       = 3
+	socks5IPv6         = 4
+)
+
+// socks5Connect asks a SOCKS5 proxy to connect to addr. Host names are
+// resolved by the proxy, so internal names work with both socks5 and socks5h.
+func socks5Connect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portText)
+	}
+
+	method := byte(socks5NoAuth)
+	if proxy.User != nil {
+		method = socks5PasswordAuth
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("not a SOCKS5 proxy")
+	}
+	if reply[1] != method {
+		return fmt.Errorf("proxy rejected the authentication method")
+	}
+
+	if method == socks5PasswordAuth {
+		username := proxy.User.Username()
+		password, _ := proxy.User.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return fmt.Errorf("proxy username and password are limited to 255 bytes")
+		}
+		request := []byte{1, byte(len(username))}
+		request = append(request, username...)
+		request = append(request, byte(len(password)))
+		request = append(request, password...)
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return fmt.Errorf("proxy rejected the credentials")
+		}
+	}
+
+	request := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long")
+		}
+		request = append(request, socks5Domain, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, socks5IPv4)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, socks5IPv6)
+		request = append(request, ip...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	// Reply: version, status, reserved, bound address type, address, port
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0 {
+		return fmt.Errorf("SOCKS5 connect failed with status %d", header[1])
+	}
+	var length int
+	switch header[3] {
+	case socks5IPv4:
+		length = net.IPv4len
+	case socks5IPv6:
+		length = net.IPv6len
+	case socks5Domain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		length = int(size[0])
+	default:
+		return fmt.Errorf("invalid SOCKS5 reply")
+	}
+	_, err = io.ReadFull(conn, make([]byte, length+2))
+	return err
+}
+
+// httpConnect opens a tunnel to addr with an HTTP CONNECT request
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := request.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("proxy answered " + response.Status)
+	}
+
+	// Keep anything the proxy sent past its response
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads through a reader holding data already read from Conn
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,182 @@
+package valkeysender
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testProxy accepts tunnels with serve and pipes them to the requested address
+type testProxy struct {
+	listener net.Listener
+	tunnels  int32
+}
+
+func startTestProxy(t *testing.T, serve func(conn net.Conn) (string, bool)) *testProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &testProxy{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				addr, ok := serve(conn)
+				if !ok {
+					return
+				}
+				target, err := net.Dial("tcp", addr)
+				if err != nil {
+					return
+				}
+				defer target.Close()
+				atomic.AddInt32(&proxy.tunnels, 1)
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return proxy
+}
+
+// serveSOCKS5 answers a SOCKS5 handshake requiring the given credentials
+func serveSOCKS5(username, password string) func(net.Conn) (string, bool) {
+	return func(conn net.Conn) (string, bool) {
+		greeting := make([]byte, 2)
+		io.ReadFull(conn, greeting)
+		methods := make([]byte, greeting[1])
+		io.ReadFull(conn, methods)
+		if !strings.Contains(string(methods), "\x02") {
+			conn.Write([]byte{5, 0xff})
+			return "", false
+		}
+		conn.Write([]byte{5, 2})
+		
+		header := make([]byte, 2)
+		io.ReadFull(conn, header)
+		user := make([]byte, header[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, header[:1])
+		pass := make([]byte, header[0])
+		io.ReadFull(conn, pass)
+		if string(user) != username || string(pass) != password {
+			conn.Write([]byte{1, 1})
+			return "", false
+		}
+		conn.Write([]byte{1, 0})
+		
+		request := make([]byte, 4)
+		io.ReadFull(conn, request)
+		var host string
+		switch request[3] {
+		case socks5IPv4:
+			ip := make([]byte, 4)
+			io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case socks5Domain:
+			io.ReadFull(conn, header[:1])
+			name := make([]byte, header[0])
+			io.ReadFull(conn, name)
+			host = string(name)
+		}
+		port := make([]byte, 2)
+		io.ReadFull(conn, port)
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+	}
+}
+
+// serveConnect answers an HTTP CONNECT request
+func serveConnect(conn net.Conn) (string, bool) {
+	request, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || request.Method != http.MethodConnect {
+		return "", false
+	}
+	if request.Header.Get("Proxy-Authorization") != "Basic YnJpZGdlOnMzY3JldA==" { // bridge:s3cret
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return "", false
+	}
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	return request.Host, true
+}
+
+func TestProxy(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(net.Conn) (string, bool)
+		url   func(addr string) string
+	}{
+		{"socks5", serveSOCKS5("bridge", "s3cret"), func(addr string) string { return "socks5://bridge:s3cret@" + addr }},
+		{"http connect", serveConnect, func(addr string) string { return "http://bridge:s3cret@" + addr }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			proxy := startTestProxy(t, tt.serve)
+			
+			config := DefaultConfig()
+			config.Address = "localhost:" + server.Port()
+			config.HealthCheckInterval = 0
+			config.ProxyURL = tt.url(proxy.listener.Addr().String())
+			sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+			if err != nil {
+				t.Fatalf("Failed to connect through proxy: %v", err)
+			}
+			defer sender.Close()
+			
+			if err := sender.SendMessage(context.Background(), "orders", 1); err != nil {
+				t.Fatalf("Failed to send through proxy: %v", err)
+			}
+			if stored, _ := server.List("queue:orders"); len(stored) != 1 {
+				t.Errorf("Expected message to arrive, got %d", len(stored))
+			}
+			if atomic.LoadInt32(&proxy.tunnels) == 0 {
+				t.Error("Expected connections to go through the proxy")
+			}
+			
+			// Wrong credentials are rejected by the proxy
+			config.ProxyURL = strings.Replace(config.ProxyURL, "s3cret", "guess", 1)
+			opts, _ := newRedisOptions(config, nil)
+			if _, err := opts.Dialer(context.Background(), "tcp", config.Address); err == nil {
+				t.Error("Expected the proxy to reject wrong credentials")
+			}
+		})
+	}
+}
+
+func TestProxyURLValidation(t *testing.T) {
+	config := DefaultConfig()
+	for _, proxyURL := range []string{"ftp://proxy:21", "socks5://proxy", "socks5://user:hunter2@%zz"} {
+		config.ProxyURL = proxyURL
+		err := config.validate()
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", proxyURL)
+		} else if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("Expected the error not to leak the password: %v", err)
+		}
+	}
+	
+	t.Setenv("VALKEY_SENDER_PROXY_URL", "socks5h://bastion.internal:1080")
+	loaded, err := LoadConfig()
+	if err != nil || loaded.ProxyURL != "socks5h://bastion.internal:1080" {
+		t.Errorf("Expected proxy URL to load, got %q (%v)", loaded.ProxyURL, err)
+	}
+}
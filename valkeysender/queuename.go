@@ -0,0 +1,83 @@
+package valkeysender
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// queueNameRules are the compiled queue name rules of a configuration
+type queueNameRules struct {
+	pattern   *regexp.Regexp
+	source    string // pattern as configured
+	maxLength int
+	reserved  []string
+	allowed   []string
+}
+
+// newQueueNameRules compiles the queue name rules of config
+func newQueueNameRules(config *Config) (*queueNameRules, error) {
+	rules := &queueNameRules{
+		maxLength: config.QueueNameMaxLength,
+		reserved:  config.ReservedQueuePrefixes,
+		allowed:   config.AllowedQueues,
+	}
+	if rules.maxLength < 0 {
+		return nil, fmt.Errorf("queue name max length cannot be negative")
+	}
+	if config.QueueNamePattern != "" {
+		// Anchored, so the pattern describes the whole name
+		pattern, err := regexp.Compile("^(?:" + config.QueueNamePattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid queue name pattern: %w", err)
+		}
+		rules.pattern, rules.source = pattern, config.QueueNamePattern
+	}
+	for _, allowed := range rules.allowed {
+		if _, err := path.Match(allowed, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed queue %q: %w", allowed, err)
+		}
+	}
+	return rules, nil
+}
+
+// check returns an ErrInvalidQueue error if queue breaks a rule
+func (r *queueNameRules) check(queue string) error {
+	if queue == "" {
+		return &Error{Kind: ErrInvalidQueue, Err: fmt.Errorf("queue name cannot be empty")}
+	}
+	if r == nil {
+		return nil
+	}
+	if r.maxLength > 0 && len(queue) > r.maxLength {
+		return &Error{Kind: ErrInvalidQueue, Err: fmt.Errorf("queue name %q is longer than %d bytes", queue, r.maxLength)}
+	}
+	if r.pattern != nil && !r.pattern.MatchString(queue) {
+		return &Error{Kind: ErrInvalidQueue, Err: fmt.Errorf("queue name %q does not match %q", queue, r.source)}
+	}
+	for _, prefix := range r.reserved {
+		if strings.HasPrefix(queue, prefix) {
+			return &Error{Kind: ErrInvalidQueue, Err: fmt.Errorf("queue name %q uses the reserved prefix %q", queue, prefix)}
+		}
+	}
+	if len(r.allowed) == 0 {
+		return nil
+	}
+	for _, allowed := range r.allowed {
+		if matched, _ := path.Match(allowed, queue); matched {
+			return nil
+		}
+	}
+	return &Error{Kind: ErrInvalidQueue, Err: fmt.Errorf("queue %q is not one of the allowed queues", queue)}
+}
+
+// ValidateQueueName checks a queue name against the queue name rules of the
+// configuration, as every send does. Errors wrap ErrInvalidQueue.
+func (c *Config) ValidateQueueName(queue string) error {
+	rules, err := newQueueNameRules(c)
+	if err != nil {
+		return err
+	}
+	return rules.check(queue)
+}
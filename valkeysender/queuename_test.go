@@ -0,0 +1,95 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestQueueNameRules(t *testing.T) {
+	config := DefaultConfig()
+	config.QueueNamePattern = `[a-z0-9.-]+`
+	config.QueueNameMaxLength = 20
+	config.ReservedQueuePrefixes = []string{"internal."}
+
+	for queue, want := range map[string]string{
+		"user-registration":             "",
+		"":                              "cannot be empty",
+		"User_Registration":             "does not match",
+		"a-very-long-queue-name-indeed": "longer than 20 bytes",
+		"internal.jobs":                 "reserved prefix",
+	} {
+		err := config.ValidateQueueName(queue)
+		if want == "" {
+			if err != nil {
+				t.Errorf("Expected %q to be valid, got %v", queue, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidQueue) || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q to be rejected with %q, got %v", queue, want, err)
+		}
+	}
+
+	config.AllowedQueues = []string{"user-registration", "billing-*"}
+	for queue, valid := range map[string]bool{"user-registration": true, "billing-eu": true, "user-registation": false} {
+		if err := config.ValidateQueueName(queue); (err == nil) != valid {
+			t.Errorf("Expected %q valid=%v, got %v", queue, valid, err)
+		}
+	}
+}
+
+func TestQueueNameRulesConfigValidation(t *testing.T) {
+	for name, configure := range map[string]func(*Config){
+		"pattern":    func(c *Config) { c.QueueNamePattern = "[a-z" },
+		"max length": func(c *Config) { c.QueueNameMaxLength = -1 },
+		"allowed":    func(c *Config) { c.AllowedQueues = []string{"user-["} },
+	} {
+		config := DefaultConfig()
+		configure(config)
+		if err := config.validate(); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+func TestAllowedQueues(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithAllowedQueues("user-registration", "audit"),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	s := sender.(*valkeySender)
+
+	if err := s.SendMessage(ctx, "user-registration", "a"); err != nil {
+		t.Errorf("Expected an allowed queue to be accepted, got %v", err)
+	}
+	err = s.SendMessage(ctx, "user-registation", "b")
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Kind != ErrInvalidQueue || typed.Queue != "user-registation" || typed.Op != opSendMessage {
+		t.Errorf("Expected ErrInvalidQueue for the typo, got %v", err)
+	}
+	if err := s.SendRaw(ctx, "typo", []byte("c")); !errors.Is(err, ErrInvalidQueue) {
+		t.Errorf("Expected SendRaw to the typo to be rejected, got %v", err)
+	}
+	if err := s.SendToQueues(ctx, []string{"audit", "audti"}, "d"); !errors.Is(err, ErrInvalidQueue) {
+		t.Errorf("Expected a fan-out to the typo to be rejected, got %v", err)
+	}
+	if server.Exists(s.getQueueKey("user-registation")) || server.Exists(s.getQueueKey("audit")) {
+		t.Error("Expected nothing pushed for rejected sends")
+	}
+	if IsRetryable(err) {
+		t.Error("Expected an invalid queue not to be retryable")
+	}
+}
@@ -0,0 +1,231 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ListQueues returns the names of existing queues matching a glob pattern
+// (e.g. "user-*"; empty matches all). Names are returned without the queue
+// prefix unless a custom QueueNamer is configured, in which case the raw
+// keys are returned.
+func (s *valkeySender) ListQueues(ctx context.Context, pattern string) ([]string, error) {
+	queues, _, err := s.scanQueues(ctx, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+	return queues, nil
+}
+
+// DiscoverQueues finds every queue with SCAN, which unlike KEYS doesn't
+// block the server, and reads their lengths in one pipeline. Queues are
+// sorted by name; names follow the same rules as ListQueues.
+func (s *valkeySender) DiscoverQueues(ctx context.Context) ([]QueueInfo, error) {
+	queues, keys, err := s.scanQueues(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover queues: %w", err)
+	}
+	
+	lengths, err := s.backend.lengths(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover queues: %w", err)
+	}
+	
+	infos := make([]QueueInfo, 0, len(queues))
+	for i, queue := range queues {
+		// Skip queues emptied since the scan
+		if lengths[i] > 0 {
+			infos = append(infos, QueueInfo{Name: queue, Length: lengths[i]})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	
+	return infos, nil
+}
+
+// TotalBacklog returns the number of messages waiting in all queues, see
+// DiscoverQueues
+func (s *valkeySender) TotalBacklog(ctx context.Context) (int64, error) {
+	queues, err := s.DiscoverQueues(ctx)
+	if err != nil {
+		return 0, err
+	}
+	
+	var total int64
+	for _, queue := range queues {
+		total += queue.Length
+	}
+	return total, nil
+}
+
+// scanQueues returns the names and keys of the queues matching a glob pattern
+func (s *valkeySender) scanQueues(ctx context.Context, pattern string) ([]string, []string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	match := s.getQueueKey(pattern)
+	prefix := s.getQueueKey("")
+	
+	keys, err := s.backend.keys(ctx, match, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	var queues []string
+	for _, key := range keys {
+		if s.options.QueueNamer == nil {
+			key = strings.TrimPrefix(key, prefix)
+		}
+		queues = append(queues, key)
+	}
+	
+	return queues, keys, nil
+}
+
+// PurgeQueue removes all messages from a queue and returns how many were removed
+func (s *valkeySender) PurgeQueue(ctx context.Context, queue string) (int64, error) {
+	listKey := s.getQueueKey(queue)
+	
+	size, err := s.backend.purge(ctx, listKey, expiryKey(listKey))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge queue %s: %w", queue, err)
+	}
+	
+	s.logger.Info("Queue purged",
+		slog.String("queue", queue),
+		slog.Int64("removed", size),
+	)
+	
+	return size, nil
+}
+
+// DeleteQueue removes a queue together with its expiry set, counters, consumer
+// processing lists and heartbeat registry. Messages held by running consumers are lost.
+func (s *valkeySender) DeleteQueue(ctx context.Context, queue string) error {
+	listKey := s.getQueueKey(queue)
+	keys := []string{listKey, expiryKey(listKey), countersKey(listKey), s.config.KeyPrefix + consumersKeyPrefix + queue}
+	
+	processing, err := s.backend.keys(ctx, s.config.KeyPrefix+processingKey(queue, "*"), false)
+	if err != nil {
+		return fmt.Errorf("failed to delete queue %s: %w", queue, err)
+	}
+	keys = append(keys, processing...)
+	
+	if err := s.backend.del(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to delete queue %s: %w", queue, err)
+	}
+	
+	s.logger.Info("Queue deleted", slog.String("queue", queue))
+	
+	return nil
+}
+
+// moveChunk bounds how many messages MoveMessages moves per round trip
+const moveChunk = 100
+
+// MoveMessages moves up to count messages (0 moves every message queued
+// when it starts) from one queue to another, oldest first, and returns how
+// many were moved. Each message is moved with LMOVE, which pops and pushes
+// it atomically, so messages are neither lost nor duplicated if the move
+// is interrupted; consumers of either queue may keep running. Moves take
+// tokens from the rate limiter of toQueue, waiting regardless of
+// RateLimitMode. Per-message expiries of the "message" TTL strategy stay
+// with fromQueue.
+func (s *valkeySender) MoveMessages(ctx context.Context, fromQueue, toQueue string, count int64) (int64, error) {
+	if fromQueue == toQueue {
+		return 0, fmt.Errorf("cannot move messages of queue %s onto itself", fromQueue)
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("count cannot be negative")
+	}
+	from, to := s.getQueueKey(fromQueue), s.getQueueKey(toQueue)
+	
+	if count == 0 {
+		size, err := s.backend.length(ctx, from)
+		if err != nil {
+			return 0, fmt.Errorf("failed to move messages of queue %s: %w", fromQueue, err)
+		}
+		count = size
+	}
+	
+	limiter := s.rateLimiterFor(toQueue)
+	var moved int64
+	for moved < count {
+		chunk := min(count-moved, moveChunk, int64(max(limiter.Burst(), 1)))
+		if err := limiter.WaitN(ctx, int(chunk)); err != nil {
+			return moved, fmt.Errorf("failed to move messages of queue %s: %w", fromQueue, err)
+		}
+		n, err := s.backend.move(ctx, from, to, chunk)
+		moved += n
+		if err != nil {
+			return moved, fmt.Errorf("failed to move messages of queue %s: %w", fromQueue, err)
+		}
+		if n < chunk {
+			break // fromQueue is empty
+		}
+	}
+	
+	s.logger.Info("Messages moved",
+		slog.String("from", fromQueue),
+		slog.String("to", toQueue),
+		slog.Int64("moved", moved),
+	)
+	
+	return moved, nil
+}
+
+// PeekMessages returns up to count messages without removing them. Offset 0
+// is the next message a consumer will receive. Elements that cannot be
+// decoded are returned with only Queue and Payload set.
+func (s *valkeySender) PeekMessages(ctx context.Context, queue string, offset, count int64) ([]MessageEnvelope, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative")
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+	
+	// Consumers pop from the right, so walk the list from its tail
+	values, err := s.backend.lrange(ctx, s.getQueueKey(queue), -(offset + count), -(offset + 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek queue %s: %w", queue, err)
+	}
+	
+	envelopes := make([]MessageEnvelope, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		envelopes = append(envelopes, s.decodeElement(queue, values[i]))
+	}
+	
+	return envelopes, nil
+}
+
+// decodeElement decodes a list element into an envelope, falling back to the
+// raw bytes for payloads pushed without an envelope
+func (s *valkeySender) decodeElement(queue, value string) MessageEnvelope {
+	if !s.options.RawPayload {
+		envelope, err := s.codec.Decode([]byte(value))
+		if err == nil {
+			return envelope
+		}
+		s.checkCorrupt(queue, err)
+	}
+	return MessageEnvelope{Queue: queue, Payload: []byte(value)}
+}
+
+// checkCorrupt counts and logs a message that failed its checksum when
+// read back
+func (s *valkeySender) checkCorrupt(queue string, err error) {
+	if !errors.Is(err, ErrCorrupt) {
+		return
+	}
+	atomic.AddInt64(&s.corruptMessages, 1)
+	s.logger.Warn("Corrupt message detected",
+		slog.String("queue", queue),
+		slog.Any("error", err),
+	)
+}
@@ -0,0 +1,147 @@
+package valkeysender
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestQueueManagement(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	for _, id := range []string{"first", "second", "third"} {
+		if err := s.SendMessage(ctx, "orders", map[string]string{"id": id}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+	s.SendMessage(ctx, "users", map[string]string{"id": "u1"})
+	server.Set("queue:not-a-list", "x")
+	
+	queues, err := s.ListQueues(ctx, "")
+	if err != nil {
+		t.Fatalf("ListQueues failed: %v", err)
+	}
+	sort.Strings(queues)
+	if len(queues) != 2 || queues[0] != "orders" || queues[1] != "users" {
+		t.Errorf("Expected [orders users], got %v", queues)
+	}
+	
+	queues, _ = s.ListQueues(ctx, "ord*")
+	if len(queues) != 1 || queues[0] != "orders" {
+		t.Errorf("Expected [orders], got %v", queues)
+	}
+	
+	envelopes, err := s.PeekMessages(ctx, "orders", 1, 5)
+	if err != nil {
+		t.Fatalf("PeekMessages failed: %v", err)
+	}
+	if len(envelopes) != 2 || string(envelopes[0].Payload) != `{"id":"second"}` {
+		t.Errorf("Expected second and third message, got %+v", envelopes)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 3 {
+		t.Errorf("Expected peek to leave queue untouched, got size %d", size)
+	}
+	
+	removed, err := s.PurgeQueue(ctx, "orders")
+	if err != nil || removed != 3 {
+		t.Errorf("Expected 3 messages purged, got %d (%v)", removed, err)
+	}
+	
+	server.Lpush("processing:users:worker-1", "held")
+	server.HSet("consumers:users", "worker-1", "0")
+	if err := s.DeleteQueue(ctx, "users"); err != nil {
+		t.Fatalf("DeleteQueue failed: %v", err)
+	}
+	for _, key := range []string{"queue:users", "processing:users:worker-1", "consumers:users"} {
+		if server.Exists(key) {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+}
+
+func TestDiscoverQueues(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			
+			if queues, err := s.DiscoverQueues(ctx); err != nil || len(queues) != 0 {
+				t.Fatalf("Expected no queues, got %v (%v)", queues, err)
+			}
+			
+			s.SendBatch(ctx, "users", []interface{}{"u1", "u2"})
+			s.SendBatch(ctx, "orders", []interface{}{"o1", "o2", "o3"})
+			s.SendMessage(ctx, "audit", "a1")
+			
+			queues, err := s.DiscoverQueues(ctx)
+			if err != nil {
+				t.Fatalf("DiscoverQueues failed: %v", err)
+			}
+			want := []QueueInfo{{"audit", 1}, {"orders", 3}, {"users", 2}}
+			if len(queues) != len(want) {
+				t.Fatalf("Expected %v, got %v", want, queues)
+			}
+			for i := range want {
+				if queues[i] != want[i] {
+					t.Errorf("Expected %v, got %v", want[i], queues[i])
+				}
+			}
+			
+			backlog, err := s.TotalBacklog(ctx)
+			if err != nil || backlog != 6 {
+				t.Errorf("Expected backlog 6, got %d (%v)", backlog, err)
+			}
+		})
+	}
+}
+
+func TestMoveMessages(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			
+			for i := 1; i <= 5; i++ {
+				if err := s.SendMessage(ctx, "poisoned", i); err != nil {
+					t.Fatalf("Failed to send: %v", err)
+				}
+			}
+			s.SendMessage(ctx, "quarantine", 0)
+			
+			moved, err := s.MoveMessages(ctx, "poisoned", "quarantine", 2)
+			if err != nil || moved != 2 {
+				t.Fatalf("Expected 2 messages moved, got %d (%v)", moved, err)
+			}
+			moved, err = s.MoveMessages(ctx, "poisoned", "quarantine", 0)
+			if err != nil || moved != 3 {
+				t.Fatalf("Expected the remaining 3 messages moved, got %d (%v)", moved, err)
+			}
+			
+			envelopes, err := s.PeekMessages(ctx, "quarantine", 0, 10)
+			if err != nil {
+				t.Fatalf("PeekMessages failed: %v", err)
+			}
+			var order string
+			for _, envelope := range envelopes {
+				order += string(envelope.Payload)
+			}
+			if order != "012345" {
+				t.Errorf("Expected moved messages behind the existing one in order, got %s", order)
+			}
+			if size, _ := s.GetQueueSize(ctx, "poisoned"); size != 0 {
+				t.Errorf("Expected source queue to be empty, got size %d", size)
+			}
+			
+			if moved, err := s.MoveMessages(ctx, "poisoned", "quarantine", 10); err != nil || moved != 0 {
+				t.Errorf("Expected nothing to move from an empty queue, got %d (%v)", moved, err)
+			}
+			if _, err := s.MoveMessages(ctx, "quarantine", "quarantine", 1); err == nil {
+				t.Error("Expected moving a queue onto itself to fail")
+			}
+		})
+	}
+}
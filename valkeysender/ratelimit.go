@@ -0,0 +1,97 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limit modes for Config.RateLimitMode
+const (
+	// RateLimitModeWait blocks until a token is available or the context is done
+	RateLimitModeWait = "wait"
+
+	// RateLimitModeReject fails immediately with ErrRateLimited
+	RateLimitModeReject = "reject"
+)
+
+// acquireRateLimit takes one token from the rate limiter of every given
+// queue, or from the sender-wide limiter if there are none, according to
+// the configured mode. Queues sharing a limiter take a single token. If a
+// limiter fails, the tokens already taken from the others are returned;
+// tokens are reserved at one instant so they can still be.
+func (s *valkeySender) acquireRateLimit(ctx context.Context, queues ...string) error {
+	now := time.Now()
+	if len(queues) == 0 {
+		_, err := s.acquireToken(ctx, s.rateLimiter, now)
+		return err
+	}
+	
+	acquired := make(map[*rate.Limiter]*rate.Reservation, 1)
+	for _, queue := range queues {
+		limiter := s.rateLimiterFor(queue)
+		if _, ok := acquired[limiter]; ok {
+			continue
+		}
+		reservation, err := s.acquireToken(ctx, limiter, now)
+		if err != nil {
+			for _, held := range acquired {
+				held.CancelAt(now)
+			}
+			return err
+		}
+		acquired[limiter] = reservation
+	}
+	return nil
+}
+
+// acquireToken takes one token from limiter at now according to the
+// configured mode, returning its reservation
+func (s *valkeySender) acquireToken(ctx context.Context, limiter *rate.Limiter, now time.Time) (*rate.Reservation, error) {
+	reservation := limiter.ReserveN(now, 1)
+	if strings.EqualFold(s.config.RateLimitMode, RateLimitModeReject) {
+		if !reservation.OK() || reservation.DelayFrom(now) > 0 {
+			reservation.CancelAt(now)
+			atomic.AddInt64(&s.rateLimitHits, 1)
+			s.events.publish(SenderEvent{Type: EventRateLimited})
+			return nil, ErrRateLimited
+		}
+		return reservation, nil
+	}
+
+	if !reservation.OK() {
+		return nil, fmt.Errorf("rate limiter error: burst size too small")
+	}
+
+	delay := reservation.Delay()
+	if delay == 0 {
+		return reservation, nil
+	}
+	atomic.AddInt64(&s.rateLimitHits, 1)
+	s.events.publish(SenderEvent{Type: EventRateLimited})
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return reservation, nil
+	case <-ctx.Done():
+		// Return the token so other callers aren't penalized
+		reservation.Cancel()
+		return nil, fmt.Errorf("rate limiter error: %w", ctx.Err())
+	}
+}
+
+// batchQueues returns the queue of every batch
+func batchQueues(batches []*queueBatch) []string {
+	queues := make([]string, len(batches))
+	for i, batch := range batches {
+		queues[i] = batch.queue
+	}
+	return queues
+}
@@ -0,0 +1,138 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// reconnectEnabled reports whether lost connections are restored by the
+// reconnect manager
+func (s *valkeySender) reconnectEnabled() bool {
+	return s.config.ReconnectBackoffMax > 0
+}
+
+// startReconnector runs the reconnect manager, which waits for the
+// connection to be lost and then probes Valkey with exponential backoff
+// until it answers again
+func (s *valkeySender) startReconnector() {
+	if !s.reconnectEnabled() {
+		return
+	}
+
+	s.reconnectSignal = make(chan error, 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case cause := <-s.reconnectSignal:
+				// A send may have restored the connection in the meantime
+				if s.getConnectionState() != ConnectionStateConnected {
+					s.reconnect(cause)
+				}
+			}
+		}
+	}()
+}
+
+// signalReconnect wakes the reconnect manager, unless it is already running
+func (s *valkeySender) signalReconnect(cause error) {
+	if s.reconnectSignal == nil {
+		return
+	}
+	select {
+	case s.reconnectSignal <- cause:
+	default:
+	}
+}
+
+// reconnect pings Valkey, doubling the delay between attempts from
+// ReconnectBackoffMin up to ReconnectBackoffMax, until the connection is
+// restored by a ping or by a send, then reports the outage
+func (s *valkeySender) reconnect(cause error) {
+	lostAt := time.Now()
+	delay := s.config.ReconnectBackoffMin
+	attempts := 0
+
+	for s.getConnectionState() != ConnectionStateConnected {
+		timer := time.NewTimer(s.reconnectDelay(delay))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if s.getConnectionState() == ConnectionStateConnected {
+			break
+		}
+
+		attempts++
+		ctx, cancel := context.WithTimeout(s.ctx, s.config.DialTimeout)
+		start := time.Now()
+		err := s.backend.ping(ctx)
+		cancel()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.logger.Debug("Reconnect attempt failed",
+				slog.Int("attempt", attempts),
+				slog.Duration("delay", delay),
+				slog.Any("error", err),
+			)
+			delay = min(delay*2, s.config.ReconnectBackoffMax)
+			continue
+		}
+
+		s.recordPing(time.Since(start))
+		s.setConnectionState(ConnectionStateConnected, nil)
+	}
+
+	// Drop signals raised before the connection came back
+	select {
+	case <-s.reconnectSignal:
+	default:
+	}
+
+	event := ReconnectEvent{
+		Attempts:  attempts,
+		Downtime:  time.Since(lostAt),
+		Cause:     cause,
+		Timestamp: time.Now(),
+	}
+
+	s.logger.Info("Reconnected to Valkey",
+		slog.Int("attempts", event.Attempts),
+		slog.Duration("downtime", event.Downtime),
+	)
+
+	if s.options.ReconnectHandler != nil {
+		s.options.ReconnectHandler(event)
+	}
+}
+
+// reconnectDelay spreads delay randomly by up to ReconnectJitter of itself,
+// so senders that lost the same server don't reconnect in lockstep
+func (s *valkeySender) reconnectDelay(delay time.Duration) time.Duration {
+	jitter := s.config.ReconnectJitter
+	if jitter <= 0 {
+		return delay
+	}
+	return time.Duration(float64(delay) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// refuseWhileReconnecting fails sends fast with ErrNotConnected while the
+// reconnect manager is restoring the connection, if ReconnectFailFast is set
+func (s *valkeySender) refuseWhileReconnecting() error {
+	if !s.config.ReconnectFailFast || s.getConnectionState() != ConnectionStateReconnecting {
+		return nil
+	}
+	return &Error{Kind: ErrNotConnected, Err: ErrNotConnected}
+}
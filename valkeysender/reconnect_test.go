@@ -0,0 +1,86 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestReconnect(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	reconnected := make(chan ReconnectEvent, 1)
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.ReconnectBackoffMin = 5 * time.Millisecond
+	config.ReconnectBackoffMax = 20 * time.Millisecond
+	config.ReconnectFailFast = true
+	sender, err := NewSender(config, &SenderOptions{
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ReconnectHandler: func(event ReconnectEvent) { reconnected <- event },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+	s := sender.(*valkeySender)
+
+	addr := server.Addr()
+	server.Close()
+	if err := s.SendMessage(ctx, "orders", "a"); err == nil {
+		t.Fatal("Expected the send to fail while Valkey is down")
+	}
+	if state := s.getConnectionState(); state != ConnectionStateReconnecting {
+		t.Fatalf("Expected state %s, got %s", ConnectionStateReconnecting, state)
+	}
+
+	// Fail fast: refused before reaching Valkey or the breaker
+	err = s.SendMessage(ctx, "orders", "b")
+	if !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected while reconnecting, got %v", err)
+	}
+
+	// Let a few attempts fail before Valkey comes back
+	time.Sleep(30 * time.Millisecond)
+	if err := server.StartAddr(addr); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+
+	select {
+	case event := <-reconnected:
+		if event.Attempts < 1 || event.Cause == nil || event.Downtime <= 0 {
+			t.Errorf("Unexpected reconnect event %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the reconnect handler to be called")
+	}
+
+	if state := s.getConnectionState(); state != ConnectionStateConnected {
+		t.Errorf("Expected state %s, got %s", ConnectionStateConnected, state)
+	}
+	if err := s.SendMessage(ctx, "orders", "c"); err != nil {
+		t.Errorf("Expected sends to succeed after reconnecting, got %v", err)
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	s := &valkeySender{config: &Config{ReconnectJitter: 0.2}}
+	for i := 0; i < 100; i++ {
+		delay := s.reconnectDelay(time.Second)
+		if delay < 800*time.Millisecond || delay > 1200*time.Millisecond {
+			t.Fatalf("Expected the delay within 20%% of 1s, got %v", delay)
+		}
+	}
+
+	s.config.ReconnectJitter = 0
+	if delay := s.reconnectDelay(time.Second); delay != time.Second {
+		t.Errorf("Expected no jitter, got %v", delay)
+	}
+}
@@ -0,0 +1,94 @@
+package valkeysender
+
+import (
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// redactedValue replaces secrets in redacted configs and log entries
+const redactedValue = "REDACTED"
+
+// sensitiveKeys are the substrings marking a log attribute as secret
+var sensitiveKeys = []string{"password", "secret", "token"}
+
+// Redacted returns a copy of the config with the password, the TLS key
+// passphrase, the ingest token and the passwords of URL, MirrorURL,
+// ShadowURL and ProxyURL
+// replaced, safe for logging or dumping.
+// File paths, including TLSKeyFile, are kept: they name secrets but are
+// not secret themselves.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.WatchQueues = slices.Clone(c.WatchQueues)
+
+	if redacted.Password != "" {
+		redacted.Password = redactedValue
+	}
+	if redacted.TLSKeyPassword != "" {
+		redacted.TLSKeyPassword = redactedValue
+	}
+	if redacted.IngestToken != "" {
+		redacted.IngestToken = redactedValue
+	}
+	if redacted.URL != "" {
+		redacted.URL = redactURL(redacted.URL)
+	}
+	if redacted.MirrorURL != "" {
+		redacted.MirrorURL = redactURL(redacted.MirrorURL)
+	}
+	if redacted.ShadowURL != "" {
+		redacted.ShadowURL = redactURL(redacted.ShadowURL)
+	}
+	if redacted.ProxyURL != "" {
+		redacted.ProxyURL = redactURL(redacted.ProxyURL)
+	}
+	return &redacted
+}
+
+// LogValue logs the config redacted, so slog.Any("config", config) never
+// emits credentials
+func (c *Config) LogValue() slog.Value {
+	return slog.AnyValue(redactedConfig(*c.Redacted()))
+}
+
+// redactedConfig has the fields of Config without its LogValue method
+type redactedConfig Config
+
+// redactURL masks the password of a connection URL. A URL that cannot be
+// parsed is masked entirely, since it may still contain one.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+	return u.String()
+}
+
+// RedactAttr is a slog.HandlerOptions.ReplaceAttr function masking the
+// value of attributes whose key contains password, secret or token. The
+// default logger uses it; add it to custom handlers to get the same
+// protection.
+func RedactAttr(_ []string, attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redactedValue)
+	}
+	return attr
+}
+
+// isSensitiveKey reports whether a log attribute key names a secret
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
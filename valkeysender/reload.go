@@ -0,0 +1,144 @@
+package valkeysender
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// credentialFiles returns the secret and certificate files watched for changes
+func (c *Config) credentialFiles() []string {
+	var files []string
+	for _, path := range []string{
+		c.UsernameFile,
+		c.PasswordFile,
+		c.TLSKeyPasswordFile,
+		c.TLSCertFile,
+		c.TLSKeyFile,
+		c.TLSCAFile,
+	} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// fingerprintFiles hashes the contents of the files so rotations done via
+// symlink swaps (as Kubernetes does for secret volumes) are detected
+func fingerprintFiles(files []string) (string, error) {
+	hash := sha256.New()
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s:%d:", path, len(data))
+		hash.Write(data)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// startCredentialWatcher polls credential files and rebuilds the client when they change
+func (s *valkeySender) startCredentialWatcher() {
+	files := s.config.credentialFiles()
+	if s.config.ReloadInterval <= 0 || len(files) == 0 || s.getClient() == nil || s.options.Client != nil {
+		return
+	}
+
+	fingerprint, err := fingerprintFiles(files)
+	if err != nil {
+		s.logger.Warn("Failed to read credential files for reload watcher", slog.Any("error", err))
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.ReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := fingerprintFiles(files)
+			if err != nil {
+				// Files may be missing briefly while a rotation is in progress
+				s.logger.Warn("Failed to read credential files", slog.Any("error", err))
+				continue
+			}
+			if current == fingerprint {
+				continue
+			}
+
+			if err := s.reloadClient(); err != nil {
+				s.logger.Error("Failed to reload credentials", slog.Any("error", err))
+				continue
+			}
+			fingerprint = current
+		}
+	}()
+}
+
+// reloadClient re-reads the credential files and replaces the client with
+// one using them
+func (s *valkeySender) reloadClient() error {
+	config := *s.config
+	if err := config.LoadSecretFiles(); err != nil {
+		return err
+	}
+
+	if err := s.replaceClient(&config); err != nil {
+		return fmt.Errorf("new credentials were rejected: %w", err)
+	}
+
+	s.logger.Info("Reloaded Valkey credentials",
+		slog.String("address", s.config.Address),
+	)
+	return nil
+}
+
+// replaceClient builds and verifies a new client and swaps it in. The
+// previous client is closed after a grace period so in-flight sends can
+// complete.
+func (s *valkeySender) replaceClient(config *Config) error {
+	client, err := newRedisClient(config, s.options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.DialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return err
+	}
+
+	s.clientMutex.Lock()
+	previous := s.client
+	s.client = client
+	s.clientMutex.Unlock()
+
+	// Give commands already running on the previous client time to finish
+	grace := s.config.ReadTimeout + s.config.WriteTimeout
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-time.After(grace):
+		case <-s.ctx.Done():
+		}
+		if err := previous.Close(); err != nil {
+			s.logger.Warn("Error closing previous Redis client", slog.Any("error", err))
+		}
+	}()
+
+	return nil
+}
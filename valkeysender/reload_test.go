@@ -0,0 +1,75 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCredentialReload(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret-1")
+	ctx := context.Background()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("secret-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.PasswordFile = passwordFile
+	config.ReloadInterval = 20 * time.Millisecond
+	config.ReadTimeout = 50 * time.Millisecond
+	config.WriteTimeout = 50 * time.Millisecond
+	config.HealthCheckInterval = 0
+	sender, err := NewSender(config, &SenderOptions{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	defer sender.Close()
+	s := sender.(*valkeySender)
+
+	if err := s.SendMessage(ctx, "orders", 1); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	previous := s.getClient()
+
+	// Rotate the password on the server and in the file
+	server.RequireAuth("secret-2")
+	os.WriteFile(passwordFile, []byte("secret-2\n"), 0o600)
+	waitFor(t, func() bool { return s.getClient() != previous })
+	if err := s.SendMessage(ctx, "orders", 2); err != nil {
+		t.Fatalf("Expected sends to keep working after the swap, got %v", err)
+	}
+
+	// The previous client is closed after the grace period
+	waitFor(t, func() bool { return errors.Is(previous.Ping(ctx).Err(), redis.ErrClosed) })
+	if err := s.SendMessage(ctx, "orders", 3); err != nil {
+		t.Errorf("Expected sends to work once the previous client is closed, got %v", err)
+	}
+
+	// A password the server rejects keeps the current client
+	current := s.getClient()
+	os.WriteFile(passwordFile, []byte("wrong"), 0o600)
+	time.Sleep(100 * time.Millisecond)
+	if s.getClient() != current {
+		t.Error("Expected rejected credentials to keep the current client")
+	}
+	if err := s.SendMessage(ctx, "orders", 4); err != nil {
+		t.Errorf("Expected sends to work after a rejected rotation, got %v", err)
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 4 {
+		t.Errorf("Expected 4 messages, got %d", size)
+	}
+}
@@ -0,0 +1,166 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+)
+
+// HeaderReplayedFrom names the queue a replayed message was taken from
+const HeaderReplayedFrom = "replayed-from"
+
+// replayChunk bounds how many messages Replay examines per round trip
+const replayChunk = 100
+
+// Retry counter handling for ReplayOptions.Retries
+const (
+	// ReplayRetriesKeep leaves the retry counter as it is (default)
+	ReplayRetriesKeep = ""
+
+	// ReplayRetriesReset sets the retry counter to 0, giving the message
+	// the full number of retries again
+	ReplayRetriesReset = "reset"
+
+	// ReplayRetriesIncrement adds one to the retry counter
+	ReplayRetriesIncrement = "increment"
+)
+
+// ReplayOptions configures ReplayWithOptions
+type ReplayOptions struct {
+	// Filter selects the messages to replay (nil replays all). Elements that
+	// are not envelopes are passed with only Queue and Payload set.
+	Filter func(MessageEnvelope) bool
+
+	// Count limits how many messages are replayed (0 for no limit)
+	Count int64
+
+	// NewID gives replayed messages a new ID, so consumers that deduplicate
+	// by ID process them again
+	NewID bool
+
+	// Retries is ReplayRetriesKeep, ReplayRetriesReset or ReplayRetriesIncrement
+	Retries string
+
+	// Headers are set on every replayed message
+	Headers map[string]string
+}
+
+// Replay moves the messages of sourceQueue accepted by filter (nil for all)
+// to targetQueue, e.g. to re-drive a dead letter queue after a bug fix,
+// and returns how many were replayed
+func (s *valkeySender) Replay(ctx context.Context, sourceQueue, targetQueue string, filter func(MessageEnvelope) bool) (int64, error) {
+	return s.ReplayWithOptions(ctx, sourceQueue, targetQueue, ReplayOptions{Filter: filter})
+}
+
+// ReplayWithOptions walks sourceQueue oldest first, decoding every message,
+// and moves those accepted by the filter to targetQueue, rewritten as the
+// options ask and with HeaderReplayedFrom set. Only the messages queued when
+// the replay starts are examined, and messages not accepted stay where they
+// are. Each message is removed and its rewritten copy pushed in one atomic
+// step, so an interrupted replay neither loses nor duplicates messages; a
+// message consumed from sourceQueue meanwhile is not replayed, but may let
+// the replay skip others, which then stay in sourceQueue.
+// Replays take tokens from the rate limiter of targetQueue.
+func (s *valkeySender) ReplayWithOptions(ctx context.Context, sourceQueue, targetQueue string, opts ReplayOptions) (int64, error) {
+	if sourceQueue == targetQueue {
+		return 0, fmt.Errorf("cannot replay queue %s onto itself", sourceQueue)
+	}
+	if opts.Count < 0 {
+		return 0, fmt.Errorf("count cannot be negative")
+	}
+	switch opts.Retries {
+	case ReplayRetriesKeep, ReplayRetriesReset, ReplayRetriesIncrement:
+	default:
+		return 0, fmt.Errorf("invalid retries option %q", opts.Retries)
+	}
+	from, to := s.getQueueKey(sourceQueue), s.getQueueKey(targetQueue)
+	
+	remaining, err := s.backend.length(ctx, from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay queue %s: %w", sourceQueue, err)
+	}
+	
+	limiter := s.rateLimiterFor(targetQueue)
+	var replayed, kept int64 // kept messages stay at the consumer end, ahead of the next chunk
+	for remaining > 0 && (opts.Count == 0 || replayed < opts.Count) {
+		values, err := s.backend.lrange(ctx, from, -(kept + min(remaining, replayChunk)), -(kept + 1))
+		if err != nil {
+			return replayed, fmt.Errorf("failed to replay queue %s: %w", sourceQueue, err)
+		}
+		if len(values) == 0 {
+			break // consumed since the replay started
+		}
+		remaining -= int64(len(values))
+		
+		// Walk the chunk from its oldest message
+		var originals, replacements []string
+		for i := len(values) - 1; i >= 0; i-- {
+			if opts.Count > 0 && replayed+int64(len(originals)) == opts.Count {
+				remaining = 0
+				break
+			}
+			envelope := s.decodeElement(sourceQueue, values[i])
+			if opts.Filter != nil && !opts.Filter(envelope) {
+				kept++
+				continue
+			}
+			replacement, err := s.replayElement(envelope, values[i], sourceQueue, targetQueue, opts)
+			if err != nil {
+				return replayed, &Error{Op: "replay", Queue: sourceQueue, Kind: ErrSerialization, Err: err}
+			}
+			originals = append(originals, values[i])
+			replacements = append(replacements, replacement)
+		}
+		if len(originals) == 0 {
+			continue
+		}
+		
+		if err := limiter.WaitN(ctx, min(len(originals), max(limiter.Burst(), 1))); err != nil {
+			return replayed, fmt.Errorf("failed to replay queue %s: %w", sourceQueue, err)
+		}
+		n, err := s.backend.replace(ctx, from, to, originals, replacements)
+		replayed += n
+		if err != nil {
+			return replayed, fmt.Errorf("failed to replay queue %s: %w", sourceQueue, err)
+		}
+	}
+	
+	s.logger.Info("Messages replayed",
+		slog.String("from", sourceQueue),
+		slog.String("to", targetQueue),
+		slog.Int64("replayed", replayed),
+	)
+	
+	return replayed, nil
+}
+
+// replayElement returns the element to push for a replayed message. Elements
+// that are not envelopes are replayed unchanged.
+func (s *valkeySender) replayElement(envelope MessageEnvelope, raw, sourceQueue, targetQueue string, opts ReplayOptions) (string, error) {
+	if envelope.ID == "" {
+		return raw, nil
+	}
+	
+	envelope.Queue = targetQueue
+	if opts.NewID {
+		envelope.ID = s.ids.NewID()
+	}
+	switch opts.Retries {
+	case ReplayRetriesReset:
+		envelope.Retries = 0
+	case ReplayRetriesIncrement:
+		envelope.Retries++
+	}
+	headers := make(map[string]string, len(envelope.Headers)+len(opts.Headers)+1)
+	maps.Copy(headers, envelope.Headers)
+	maps.Copy(headers, opts.Headers)
+	headers[HeaderReplayedFrom] = sourceQueue
+	envelope.Headers = headers
+	
+	data, err := s.codec.Encode(envelope)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
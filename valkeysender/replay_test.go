@@ -0,0 +1,100 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			
+			for i, reason := range []string{"timeout", "invalid", "timeout", "timeout"} {
+				if err := s.SendMessageWithOptions(ctx, "registrations-dlq", i, SendOptions{Headers: map[string]string{"reason": reason}}); err != nil {
+					t.Fatalf("Failed to send: %v", err)
+				}
+			}
+			original, _ := s.PeekMessages(ctx, "registrations-dlq", 0, 1)
+			
+			timedOut := func(envelope MessageEnvelope) bool { return envelope.Headers["reason"] == "timeout" }
+			replayed, err := s.ReplayWithOptions(ctx, "registrations-dlq", "registrations", ReplayOptions{
+				Filter:  timedOut,
+				Count:   2,
+				NewID:   true,
+				Retries: ReplayRetriesIncrement,
+				Headers: map[string]string{"fixed-in": "v1.4.2"},
+			})
+			if err != nil || replayed != 2 {
+				t.Fatalf("Expected 2 messages replayed, got %d (%v)", replayed, err)
+			}
+			
+			envelopes, _ := s.PeekMessages(ctx, "registrations", 0, 10)
+			if len(envelopes) != 2 || string(envelopes[0].Payload) != "0" || string(envelopes[1].Payload) != "2" {
+				t.Fatalf("Expected the first two timed out messages in order, got %+v", envelopes)
+			}
+			first := envelopes[0]
+			if first.ID == original[0].ID || first.Retries != 1 || first.Queue != "registrations" {
+				t.Errorf("Expected a new ID, retries 1 and the target queue, got %+v", first)
+			}
+			if first.Headers["reason"] != "timeout" || first.Headers["fixed-in"] != "v1.4.2" || first.Headers[HeaderReplayedFrom] != "registrations-dlq" {
+				t.Errorf("Unexpected headers %v", first.Headers)
+			}
+			
+			left, _ := s.PeekMessages(ctx, "registrations-dlq", 0, 10)
+			if len(left) != 2 || string(left[0].Payload) != "1" || string(left[1].Payload) != "3" {
+				t.Fatalf("Expected the other messages to stay in order, got %+v", left)
+			}
+			
+			// Without options the rest is moved as is
+			if replayed, err := s.Replay(ctx, "registrations-dlq", "registrations", nil); err != nil || replayed != 2 {
+				t.Fatalf("Expected the remaining 2 messages replayed, got %d (%v)", replayed, err)
+			}
+			envelopes, _ = s.PeekMessages(ctx, "registrations", 2, 10)
+			if len(envelopes) != 2 || envelopes[0].ID != left[0].ID || envelopes[0].Retries != 0 {
+				t.Errorf("Expected replayed message to keep its ID and retries, got %+v", envelopes)
+			}
+			
+			if _, err := s.Replay(ctx, "registrations", "registrations", nil); err == nil {
+				t.Error("Expected replaying a queue onto itself to fail")
+			}
+			if _, err := s.ReplayWithOptions(ctx, "registrations", "other", ReplayOptions{Retries: "twice"}); err == nil {
+				t.Error("Expected an invalid retries option to fail")
+			}
+		})
+	}
+}
+
+func TestReplayLargeQueue(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	
+	// Span several chunks, replaying every third message
+	messages := make([]interface{}, 250)
+	for i := range messages {
+		messages[i] = i
+	}
+	if err := s.SendBatch(ctx, "dlq", messages); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	
+	var seen int
+	replayed, err := s.Replay(ctx, "dlq", "retry", func(MessageEnvelope) bool {
+		seen++
+		return seen%3 == 0
+	})
+	if err != nil || replayed != 83 {
+		t.Fatalf("Expected 83 messages replayed, got %d (%v)", replayed, err)
+	}
+	if seen != 250 {
+		t.Errorf("Expected every message to be examined once, got %d", seen)
+	}
+	if size, _ := s.GetQueueSize(ctx, "dlq"); size != 167 {
+		t.Errorf("Expected 167 messages left, got %d", size)
+	}
+	envelopes, _ := s.PeekMessages(ctx, "retry", 0, 1)
+	if len(envelopes) != 1 || string(envelopes[0].Payload) != "2" {
+		t.Errorf("Expected the third message replayed first, got %+v", envelopes)
+	}
+}
@@ -0,0 +1,156 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HeaderRoutingKey is the envelope header carrying the routing key of a routed message
+const HeaderRoutingKey = "routing-key"
+
+// binding routes messages whose routing key matches pattern to queues
+type binding struct {
+	pattern string
+	words   []string
+	queues  []string
+}
+
+// Bind routes messages whose routing key matches pattern to the given queues.
+// Routing keys and patterns are dot-separated words; in patterns "*" matches
+// exactly one word and "#" matches zero or more words (e.g. "order.*.created",
+// "audit.#"). Binding the same pattern again adds queues to it.
+func (s *valkeySender) Bind(pattern string, queues ...string) error {
+	words, err := parseRoutingPattern(pattern)
+	if err != nil {
+		return err
+	}
+	if len(queues) == 0 {
+		return fmt.Errorf("binding %s must have at least one queue", pattern)
+	}
+	
+	s.bindingsMutex.Lock()
+	defer s.bindingsMutex.Unlock()
+	
+	for i := range s.bindings {
+		if s.bindings[i].pattern == pattern {
+			s.bindings[i].queues = appendUnique(s.bindings[i].queues, queues...)
+			return nil
+		}
+	}
+	
+	s.bindings = append(s.bindings, binding{
+		pattern: pattern,
+		words:   words,
+		queues:  appendUnique(nil, queues...),
+	})
+	return nil
+}
+
+// Unbind removes all bindings for pattern
+func (s *valkeySender) Unbind(pattern string) {
+	s.bindingsMutex.Lock()
+	defer s.bindingsMutex.Unlock()
+	
+	for i := range s.bindings {
+		if s.bindings[i].pattern == pattern {
+			s.bindings = append(s.bindings[:i], s.bindings[i+1:]...)
+			return
+		}
+	}
+}
+
+// Route delivers a message to every queue bound to a pattern matching the
+// routing key, atomically and with one shared envelope ID. The routing key
+// is stored in the routing-key header. It returns ErrNoRoute if no binding
+// matches.
+func (s *valkeySender) Route(ctx context.Context, routingKey string, message interface{}) error {
+	queues := s.matchQueues(routingKey)
+	if len(queues) == 0 {
+		return &Error{Op: opRoute, Kind: ErrNoRoute, Err: fmt.Errorf("routing key %s", routingKey)}
+	}
+	
+	return s.sendToQueues(ctx, opRoute, queues, message, map[string]string{
+		HeaderRoutingKey: routingKey,
+	})
+}
+
+// matchQueues returns the queues bound to patterns matching the routing key,
+// in binding order and without duplicates
+func (s *valkeySender) matchQueues(routingKey string) []string {
+	words := strings.Split(routingKey, ".")
+	
+	s.bindingsMutex.RLock()
+	defer s.bindingsMutex.RUnlock()
+	
+	var queues []string
+	for _, b := range s.bindings {
+		if matchRoutingKey(b.words, words) {
+			queues = appendUnique(queues, b.queues...)
+		}
+	}
+	return queues
+}
+
+// MatchRoutingKey reports whether a routing key matches a binding pattern
+// using the same rules as Bind
+func MatchRoutingKey(pattern, routingKey string) bool {
+	words, err := parseRoutingPattern(pattern)
+	if err != nil {
+		return false
+	}
+	return matchRoutingKey(words, strings.Split(routingKey, "."))
+}
+
+// parseRoutingPattern splits a binding pattern into words
+func parseRoutingPattern(pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("binding pattern cannot be empty")
+	}
+	words := strings.Split(pattern, ".")
+	for _, word := range words {
+		if word == "" {
+			return nil, fmt.Errorf("binding pattern %s contains an empty word", pattern)
+		}
+	}
+	return words, nil
+}
+
+// matchRoutingKey reports whether the routing key words match the pattern words
+func matchRoutingKey(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	
+	switch pattern[0] {
+	case "#":
+		// Match zero or more words
+		for i := 0; i <= len(words); i++ {
+			if matchRoutingKey(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchRoutingKey(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchRoutingKey(pattern[1:], words[1:])
+	}
+}
+
+// appendUnique appends the values not already present in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,69 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestMatchRoutingKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		matches bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.updated", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order.eu.created", false},
+		{"order.*.created", "order.eu.created", true},
+		{"order.#", "order", true},
+		{"order.#", "order.eu.created", true},
+		{"#.created", "order.eu.created", true},
+		{"#.created", "order.eu.updated", false},
+		{"#", "anything.at.all", true},
+		{"audit.#.done", "audit.done", true},
+	}
+	
+	for _, tt := range tests {
+		pattern, _ := parseRoutingPattern(tt.pattern)
+		if got := matchRoutingKey(pattern, strings.Split(tt.key, ".")); got != tt.matches {
+			t.Errorf("Pattern %s with key %s: expected %t, got %t", tt.pattern, tt.key, tt.matches, got)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	if err := s.Bind("order..created", "billing"); err == nil {
+		t.Error("Expected pattern with an empty word to be rejected")
+	}
+	
+	s.Bind("order.*", "billing")
+	s.Bind("order.#", "analytics", "billing")
+	s.Bind("#", "audit")
+	
+	if err := s.Route(ctx, "order.created", "event"); err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	for _, queue := range []string{"billing", "analytics", "audit"} {
+		envelopes, _ := s.PeekMessages(ctx, queue, 0, 10)
+		if len(envelopes) != 1 {
+			t.Fatalf("Expected one message in %s, got %d", queue, len(envelopes))
+		}
+		if envelopes[0].Headers[HeaderRoutingKey] != "order.created" {
+			t.Errorf("Expected routing key header in %s, got %v", queue, envelopes[0].Headers)
+		}
+	}
+	
+	s.Unbind("#")
+	if err := s.Route(ctx, "user.created", "event"); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, got %v", err)
+	}
+}
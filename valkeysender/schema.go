@@ -0,0 +1,469 @@
+package valkeysender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// JSONSchemaValidator validates messages against a JSON Schema per queue.
+// Its Validate method is meant for SenderOptions.Validator:
+//
+//	validator := valkeysender.NewJSONSchemaValidator()
+//	if err := validator.Register("orders", orderSchema); err != nil {
+//		return err
+//	}
+//	options := &valkeysender.SenderOptions{Validator: validator.Validate}
+//
+// Messages are checked in their JSON form: []byte, string and
+// json.RawMessage messages are parsed as JSON, anything else is encoded
+// with encoding/json first. Queues without a schema accept every message.
+//
+// The supported keywords are type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum (as
+// numbers), allOf, anyOf, oneOf and not. Other keywords, including $ref and
+// format, are ignored.
+type JSONSchemaValidator struct {
+	mu      sync.RWMutex
+	schemas map[string]*jsonSchema
+}
+
+// NewJSONSchemaValidator creates a validator without schemas
+func NewJSONSchemaValidator() *JSONSchemaValidator {
+	return &JSONSchemaValidator{schemas: make(map[string]*jsonSchema)}
+}
+
+// Register compiles schema and uses it for messages sent to queue,
+// replacing any schema registered for it before
+func (v *JSONSchemaValidator) Register(queue string, schema []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		return fmt.Errorf("invalid schema for queue %s: %w", queue, err)
+	}
+	compiled, err := compileJSONSchema(raw, "")
+	if err != nil {
+		return fmt.Errorf("invalid schema for queue %s: %w", queue, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.schemas[queue] = compiled
+	return nil
+}
+
+// Validate checks message against the schema registered for queue
+func (v *JSONSchemaValidator) Validate(queue string, message interface{}) error {
+	v.mu.RLock()
+	schema := v.schemas[queue]
+	v.mu.RUnlock()
+	if schema == nil {
+		return nil
+	}
+
+	var data []byte
+	switch m := message.(type) {
+	case []byte:
+		data = m
+	case json.RawMessage:
+		data = m
+	case string:
+		data = []byte(m)
+	default:
+		var err error
+		if data, err = json.Marshal(message); err != nil {
+			return fmt.Errorf("message is not JSON: %w", err)
+		}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("message is not JSON: %w", err)
+	}
+	return schema.validate(value, "")
+}
+
+// jsonSchema is a compiled schema. A nil *jsonSchema accepts everything.
+type jsonSchema struct {
+	reject bool // the false schema
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+
+	items              *jsonSchema
+	minItems, maxItems int // -1 if unset
+
+	minLength, maxLength int // -1 if unset
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+
+	allOf, anyOf, oneOf []*jsonSchema
+	not                 *jsonSchema
+}
+
+// jsonSchemaTypes are the valid values of the type keyword
+var jsonSchemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// compileJSONSchema compiles a decoded schema; path locates it in error messages
+func compileJSONSchema(raw interface{}, path string) (*jsonSchema, error) {
+	switch raw := raw.(type) {
+	case bool:
+		if raw {
+			return nil, nil
+		}
+		return &jsonSchema{reject: true}, nil
+	case map[string]interface{}:
+		return compileJSONSchemaObject(raw, path)
+	}
+	return nil, fmt.Errorf("%s: schema must be an object or a boolean", schemaPath(path))
+}
+
+// compileJSONSchemaObject compiles the keywords of a schema object
+func compileJSONSchemaObject(raw map[string]interface{}, path string) (*jsonSchema, error) {
+	s := &jsonSchema{minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	var err error
+
+	switch t := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			name, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: type must be a string or an array of strings", schemaPath(path))
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%s: type must be a string or an array of strings", schemaPath(path))
+	}
+	for _, name := range s.types {
+		if !jsonSchemaTypes[name] {
+			return nil, fmt.Errorf("%s: unknown type %q", schemaPath(path), name)
+		}
+	}
+
+	if enum, ok := raw["enum"]; ok {
+		if s.enum, ok = enum.([]interface{}); !ok {
+			return nil, fmt.Errorf("%s: enum must be an array", schemaPath(path))
+		}
+	}
+	s.constant, s.hasConst = raw["const"]
+
+	if properties, ok := raw["properties"]; ok {
+		properties, ok := properties.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: properties must be an object", schemaPath(path))
+		}
+		s.properties = make(map[string]*jsonSchema, len(properties))
+		for name, property := range properties {
+			if s.properties[name], err = compileJSONSchema(property, path+"/properties/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if required, ok := raw["required"]; ok {
+		list, ok := required.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: required must be an array of strings", schemaPath(path))
+		}
+		for _, name := range list {
+			name, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: required must be an array of strings", schemaPath(path))
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if additional, ok := raw["additionalProperties"]; ok {
+		if allowed, ok := additional.(bool); ok {
+			s.noAdditional = !allowed
+		} else if s.additionalProperties, err = compileJSONSchema(additional, path+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+
+	if items, ok := raw["items"]; ok {
+		if s.items, err = compileJSONSchema(items, path+"/items"); err != nil {
+			return nil, err
+		}
+	}
+
+	for keyword, target := range map[string]*int{
+		"minItems": &s.minItems, "maxItems": &s.maxItems,
+		"minLength": &s.minLength, "maxLength": &s.maxLength,
+	} {
+		if value, ok := raw[keyword]; ok {
+			n, ok := value.(float64)
+			if !ok || n < 0 || n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s: %s must be a non-negative integer", schemaPath(path), keyword)
+			}
+			*target = int(n)
+		}
+	}
+
+	for keyword, target := range map[string]**float64{
+		"minimum": &s.minimum, "maximum": &s.maximum,
+		"exclusiveMinimum": &s.exclusiveMinimum, "exclusiveMaximum": &s.exclusiveMaximum,
+	} {
+		if value, ok := raw[keyword]; ok {
+			n, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a number", schemaPath(path), keyword)
+			}
+			*target = &n
+		}
+	}
+
+	if pattern, ok := raw["pattern"]; ok {
+		expr, ok := pattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: pattern must be a string", schemaPath(path))
+		}
+		if s.pattern, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", schemaPath(path), err)
+		}
+	}
+
+	for keyword, target := range map[string]*[]*jsonSchema{"allOf": &s.allOf, "anyOf": &s.anyOf, "oneOf": &s.oneOf} {
+		value, ok := raw[keyword]
+		if !ok {
+			continue
+		}
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s: %s must be a non-empty array", schemaPath(path), keyword)
+		}
+		for i, sub := range list {
+			compiled, err := compileJSONSchema(sub, fmt.Sprintf("%s/%s/%d", path, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*target = append(*target, compiled)
+		}
+	}
+	if not, ok := raw["not"]; ok {
+		if s.not, err = compileJSONSchema(not, path+"/not"); err != nil {
+			return nil, err
+		}
+		if s.not == nil {
+			// not: true rejects everything
+			s.not = &jsonSchema{}
+		}
+	}
+
+	return s, nil
+}
+
+// validate checks a decoded JSON value; path is its JSON pointer
+func (s *jsonSchema) validate(value interface{}, path string) error {
+	if s == nil {
+		return nil
+	}
+	if s.reject {
+		return schemaError(path, "not allowed")
+	}
+
+	if len(s.types) > 0 && !matchesJSONType(value, s.types) {
+		return schemaError(path, "must be of type %s, got %s", strings.Join(s.types, " or "), jsonType(value))
+	}
+	if s.enum != nil && !containsJSONValue(s.enum, value) {
+		return schemaError(path, "must be one of the enumerated values")
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constant, value) {
+		return schemaError(path, "must equal the constant value")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if err := s.validateObject(v, path); err != nil {
+			return err
+		}
+	case []interface{}:
+		if s.minItems >= 0 && len(v) < s.minItems {
+			return schemaError(path, "must have at least %d items", s.minItems)
+		}
+		if s.maxItems >= 0 && len(v) > s.maxItems {
+			return schemaError(path, "must have at most %d items", s.maxItems)
+		}
+		for i, item := range v {
+			if err := s.items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength >= 0 && length < s.minLength {
+			return schemaError(path, "must be at least %d characters long", s.minLength)
+		}
+		if s.maxLength >= 0 && length > s.maxLength {
+			return schemaError(path, "must be at most %d characters long", s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return schemaError(path, "must match pattern %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return schemaError(path, "must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return schemaError(path, "must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return schemaError(path, "must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return schemaError(path, "must be < %v", *s.exclusiveMaximum)
+		}
+	}
+
+	return s.validateCombinators(value, path)
+}
+
+// validateObject checks required, properties and additionalProperties
+func (s *jsonSchema) validateObject(object map[string]interface{}, path string) error {
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			return schemaError(path, "missing required property %q", name)
+		}
+	}
+
+	// Sorted so the first reported error is stable
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, declared := s.properties[name]
+		switch {
+		case declared:
+			if err := property.validate(object[name], path+"/"+name); err != nil {
+				return err
+			}
+		case s.noAdditional:
+			return schemaError(path, "unexpected property %q", name)
+		default:
+			if err := s.additionalProperties.validate(object[name], path+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateCombinators checks allOf, anyOf, oneOf and not
+func (s *jsonSchema) validateCombinators(value interface{}, path string) error {
+	for _, sub := range s.allOf {
+		if err := sub.validate(value, path); err != nil {
+			return err
+		}
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.validate(value, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return schemaError(path, "must match at least one schema of anyOf")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.validate(value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return schemaError(path, "must match exactly one schema of oneOf, matched %d", matches)
+		}
+	}
+	if s.not != nil && s.not.validate(value, path) == nil {
+		return schemaError(path, "must not match the schema of not")
+	}
+	return nil
+}
+
+// matchesJSONType reports whether value is of one of the types
+func matchesJSONType(value interface{}, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value; whole numbers are integers
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// containsJSONValue reports whether values contains value
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaPath formats a schema location for compile errors
+func schemaPath(path string) string {
+	if path == "" {
+		return "schema"
+	}
+	return "schema " + path
+}
+
+// schemaError reports a validation failure at a JSON pointer
+func schemaError(path, format string, args ...interface{}) error {
+	if path == "" {
+		path = "/"
+	}
+	return errors.New(path + ": " + fmt.Sprintf(format, args...))
+}
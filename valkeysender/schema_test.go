@@ -0,0 +1,176 @@
+package valkeysender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testOrderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^o[0-9]+$"},
+		"status": {"enum": ["new", "paid"]},
+		"note": {"type": ["string", "null"], "maxLength": 5},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["sku", "quantity"],
+				"properties": {
+					"sku": {"type": "string", "minLength": 1},
+					"quantity": {"type": "integer", "minimum": 1},
+					"price": {"type": "number", "exclusiveMinimum": 0}
+				}
+			}
+		}
+	}
+}`
+
+func TestJSONSchemaValidator(t *testing.T) {
+	v := NewJSONSchemaValidator()
+	if err := v.Register("orders", []byte(testOrderSchema)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	
+	tests := []struct {
+		name    string
+		message interface{}
+		wantErr string
+	}{
+		{"valid map", map[string]interface{}{"id": "o1", "items": []map[string]interface{}{{"sku": "a", "quantity": 2, "price": 1.5}}}, ""},
+		{"valid bytes", []byte(`{"id":"o1","status":"paid","note":null,"items":[{"sku":"a","quantity":1}]}`), ""},
+		{"valid string", `{"id":"o1","items":[{"sku":"a","quantity":1}]}`, ""},
+		{"valid raw", json.RawMessage(`{"id":"o1","items":[{"sku":"a","quantity":1}]}`), ""},
+		{"missing required", map[string]interface{}{"id": "o1"}, `/: missing required property "items"`},
+		{"wrong type", `[]`, "/: must be of type object, got array"},
+		{"pattern", `{"id":"x1","items":[{"sku":"a","quantity":1}]}`, "/id: must match pattern"},
+		{"enum", `{"id":"o1","status":"lost","items":[{"sku":"a","quantity":1}]}`, "/status: must be one of"},
+		{"max length", `{"id":"o1","note":"too long","items":[{"sku":"a","quantity":1}]}`, "/note: must be at most 5 characters"},
+		{"additional property", `{"id":"o1","extra":1,"items":[{"sku":"a","quantity":1}]}`, `/: unexpected property "extra"`},
+		{"min items", `{"id":"o1","items":[]}`, "/items: must have at least 1 items"},
+		{"nested integer", `{"id":"o1","items":[{"sku":"a","quantity":1.5}]}`, "/items/0/quantity: must be of type integer"},
+		{"nested minimum", `{"id":"o1","items":[{"sku":"a","quantity":1},{"sku":"b","quantity":0}]}`, "/items/1/quantity: must be >= 1"},
+		{"exclusive minimum", `{"id":"o1","items":[{"sku":"a","quantity":1,"price":0}]}`, "/items/0/price: must be > 0"},
+		{"not json", "not json", "message is not JSON"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate("orders", tt.message)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid message, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	
+	// Queues without a schema accept anything
+	if err := v.Validate("events", "not json"); err != nil {
+		t.Errorf("Expected queue without schema to accept message, got %v", err)
+	}
+}
+
+func TestJSONSchemaValidatorCombinators(t *testing.T) {
+	v := NewJSONSchemaValidator()
+	schema := `{
+		"anyOf": [{"type": "string"}, {"type": "integer"}],
+		"oneOf": [{"type": "integer", "minimum": 10}, {"type": "integer", "maximum": 5}, {"type": "string"}],
+		"not": {"const": "forbidden"},
+		"allOf": [true]
+	}`
+	if err := v.Register("q", []byte(schema)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	
+	for message, valid := range map[string]bool{
+		`"ok"`:        true,
+		`20`:          true,
+		`3`:           true,
+		`7`:           false, // matches no oneOf branch
+		`1.5`:         false, // not a string or integer
+		`"forbidden"`: false,
+	} {
+		if err := v.Validate("q", message); (err == nil) != valid {
+			t.Errorf("Validate(%s): expected valid=%v, got %v", message, valid, err)
+		}
+	}
+	
+	if err := v.Register("closed", []byte(`false`)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := v.Validate("closed", `{}`); err == nil {
+		t.Error("Expected false schema to reject every message")
+	}
+}
+
+func TestJSONSchemaValidatorInvalidSchema(t *testing.T) {
+	v := NewJSONSchemaValidator()
+	for _, schema := range []string{
+		`not json`,
+		`42`,
+		`{"type": "decimal"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"properties": {"id": {"type": 1}}}`,
+		`{"anyOf": []}`,
+	} {
+		if err := v.Register("q", []byte(schema)); err == nil {
+			t.Errorf("Expected Register to reject schema %s", schema)
+		}
+	}
+}
+
+func TestSenderValidator(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySender(t)
+	
+	v := NewJSONSchemaValidator()
+	if err := v.Register("orders", []byte(`{"type": "object", "required": ["id"]}`)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	s.options.Validator = v.Validate
+	
+	valid := map[string]string{"id": "o1"}
+	invalid := map[string]string{"name": "x"}
+	
+	if err := s.SendMessage(ctx, "orders", valid); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	err := s.SendMessage(ctx, "orders", invalid)
+	if !errors.Is(err, ErrValidation) || IsRetryable(err) {
+		t.Errorf("Expected non-retryable ErrValidation, got %v", err)
+	}
+	if err := s.SendBatch(ctx, "orders", []interface{}{valid, invalid}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for batch, got %v", err)
+	}
+	if err := s.SendRaw(ctx, "orders", []byte(`{"name":"x"}`)); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for raw message, got %v", err)
+	}
+	
+	// Every queue of a fan-out is checked before anything is sent
+	if err := v.Register("audit", []byte(`{"required": ["user"]}`)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := s.SendToQueues(ctx, []string{"orders", "audit"}, valid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for fan-out, got %v", err)
+	}
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected only the valid message to be sent, got %d", size)
+	}
+	if size, _ := s.GetQueueSize(ctx, "audit"); size != 0 {
+		t.Errorf("Expected nothing sent to audit, got %d", size)
+	}
+}
@@ -0,0 +1,54 @@
+package valkeysender
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolve applies the connection URL and reads secret files, so a Config
+// built by hand behaves like one returned from LoadConfig
+func (c *Config) resolve() error {
+	if c.URL != "" {
+		if err := c.ApplyURL(c.URL); err != nil {
+			return err
+		}
+	}
+	return c.LoadSecretFiles()
+}
+
+// LoadSecretFiles reads credentials from the configured secret files
+// (UsernameFile, PasswordFile, TLSKeyPasswordFile, IngestTokenFile), as mounted by Docker or
+// Kubernetes secrets. A configured file takes precedence over the plain
+// value; trailing newlines are stripped.
+func (c *Config) LoadSecretFiles() error {
+	for _, secret := range []struct {
+		name   string
+		path   string
+		target *string
+	}{
+		{"username", c.UsernameFile, &c.Username},
+		{"password", c.PasswordFile, &c.Password},
+		{"TLS key password", c.TLSKeyPasswordFile, &c.TLSKeyPassword},
+		{"ingest token", c.IngestTokenFile, &c.IngestToken},
+	} {
+		if secret.path == "" {
+			continue
+		}
+		value, err := readSecretFile(secret.path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s file: %w", secret.name, err)
+		}
+		*secret.target = value
+	}
+	return nil
+}
+
+// readSecretFile returns the contents of a secret file without trailing newlines
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,93 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SendAll sends every message to the queue as an individual message,
+// spread over concurrency workers that serialize and push in parallel. It
+// is meant for migrations and backfills where a single sending goroutine
+// is the bottleneck; a concurrency of 0 or less uses GOMAXPROCS workers.
+//
+// Unlike SendBatch the messages are not sent atomically and their order in
+// the queue is not preserved. A failed message does not stop the others:
+// Results holds the outcome of every message at its index, and the
+// returned error reports how many failed along with the first failure.
+// Messages not yet started when ctx is done fail with its error.
+func (s *valkeySender) SendAll(ctx context.Context, queue string, messages []interface{}, concurrency int) (*BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	concurrency = min(concurrency, len(messages))
+
+	start := time.Now()
+	result := &BatchResult{Results: make([]MessageResult, len(messages))}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range next {
+				sendStart := time.Now()
+				err := s.SendMessage(ctx, queue, messages[index])
+				result.Results[index] = MessageResult{
+					Success:  err == nil,
+					Error:    err,
+					Duration: time.Since(sendStart),
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for ; dispatched < len(messages); dispatched++ {
+		select {
+		case next <- dispatched:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for index := dispatched; index < len(messages); index++ {
+		result.Results[index] = MessageResult{Error: ctx.Err()}
+	}
+
+	for _, message := range result.Results {
+		if message.Success {
+			result.TotalSent++
+			continue
+		}
+		result.Failed++
+		if result.Error == nil {
+			result.Error = message.Error
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if result.Failed > 0 {
+		s.logger.Warn("Concurrent send finished with failures",
+			slog.String("queue", queue),
+			slog.Int("sent", result.TotalSent),
+			slog.Int("failed", result.Failed),
+			slog.Int("concurrency", concurrency),
+			slog.Any("error", result.Error),
+		)
+		return result, fmt.Errorf("%d of %d messages failed: %w", result.Failed, len(messages), result.Error)
+	}
+
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSendAll(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	messages := make([]interface{}, 50)
+	for i := range messages {
+		messages[i] = i
+	}
+	result, err := s.SendAll(ctx, "backfill", messages, 4)
+	if err != nil || !result.Success || result.TotalSent != 50 || result.Failed != 0 {
+		t.Fatalf("Expected 50 messages sent, got %+v (%v)", result, err)
+	}
+	if len(result.Results) != 50 || !result.Results[49].Success {
+		t.Errorf("Expected a result per message, got %d", len(result.Results))
+	}
+	if size, _ := s.GetQueueSize(ctx, "backfill"); size != 50 {
+		t.Errorf("Expected 50 messages in the queue, got %d", size)
+	}
+	if sent := s.Health().MessagesSent; sent != 50 {
+		t.Errorf("Expected 50 messages counted, got %d", sent)
+	}
+}
+
+func TestSendAllPartialFailure(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	// A channel cannot be serialized, so only that message fails
+	result, err := s.SendAll(ctx, "backfill", []interface{}{1, make(chan int), 3}, 0)
+	if err == nil || result.Error == nil || !errors.Is(err, result.Error) {
+		t.Fatalf("Expected the serialization failure to be reported, got %v", err)
+	}
+	if result.Success || result.TotalSent != 2 || result.Failed != 1 || result.Results[1].Success {
+		t.Errorf("Expected message 1 to fail and the others to be sent, got %+v", result)
+	}
+	if size, _ := s.GetQueueSize(ctx, "backfill"); size != 2 {
+		t.Errorf("Expected 2 messages in the queue, got %d", size)
+	}
+}
+
+func TestSendAllCanceled(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := newTestSender(t, server, nil)
+	
+	result, err := s.SendAll(ctx, "backfill", []interface{}{1, 2, 3}, 1)
+	if !errors.Is(err, context.Canceled) || result.TotalSent == 3 {
+		t.Errorf("Expected a canceled send to fail, got %+v (%v)", result, err)
+	}
+}
+
+func TestSendAllEmpty(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestSender(t, server, nil)
+	
+	if _, err := s.SendAll(context.Background(), "backfill", nil, 4); err == nil {
+		t.Error("Expected an error for an empty slice")
+	}
+}
@@ -2,39 +2,105 @@ package valkeysender
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/sony/gobreaker"
 	"golang.org/x/time/rate"
 )
 
+// HeaderMessageType is the envelope header carrying the type name passed to SendTyped
+const HeaderMessageType = "message-type"
+
+// defaultBreakerConsecutiveFailures is used when Config.BreakerConsecutiveFailures is unset
+const defaultBreakerConsecutiveFailures = 3
+
 // valkeySender implements the Sender interface using Redis Lists
 type valkeySender struct {
 	config     *Config
-	client     *redis.Client
+	client     redis.UniversalClient
+	clientMutex sync.RWMutex
+	backend    backend
 	logger     *slog.Logger
+	logLevel   *slog.LevelVar // level of the default logger, nil for a custom one
 	options    *SenderOptions
 	serializer MessageSerializer
+	codec      EnvelopeCodec
+	ids        IDGenerator
+	queueNames *queueNameRules
+	metadata   map[string]interface{} // producer metadata stamped on every envelope, read-only
+	producer   string                 // producer name of the producer header
 	
 	// Circuit breaker and rate limiter
 	circuitBreaker *gobreaker.CircuitBreaker
 	rateLimiter    *rate.Limiter
+	adaptive       *adaptiveLimiter // nil unless Config.RateLimitAdaptive is set
+	
+	// Serializers and rate limiters of queues whose profile sets one
+	queueSerializers map[string]MessageSerializer
+	queueLimiters    map[string]*rate.Limiter
 	
 	// Metrics and health
 	startTime      time.Time
 	messagesSent   int64
 	errorCount     int64
+	rateLimitHits  int64
+	messagesExpired int64
+	messagesDropped int64 // aborted by a drain deadline
+	messagesTooLarge int64 // rejected for exceeding Config.MaxMessageBytes
+	corruptMessages int64 // read back with a mismatching checksum
 	lastSuccess    time.Time
 	lastError      string
-	isConnected    bool
+	lastMutex      sync.Mutex // guards lastSuccess and lastError
+	connectionState string
 	connectionMutex sync.RWMutex
+	reconnectSignal chan error // wakes the reconnect manager, nil when disabled
+	lengthCache     *lengthCache // nil unless Config.ClientCaching is set and supported
+	pingLatency     int64 // nanoseconds, last successful PING round trip
+	rtt             rttWindow // recent PING round trips
+	lastPing        int64 // unix nanoseconds of the last health check
+	queueRates      sync.Map // queue name -> *rateCounter
+	ratesEvictedAt  int64    // unix second idle queue rates were last evicted
+	failures        rateCounter      // failed operations, for the last-minute count
+	outcomes        *outcomeWindow   // sent messages and failures over Config.HealthWindow
+	sendLatency     latencyHistogram // duration of every send
+	statsd          *statsdClient    // nil unless Config.StatsdAddress is set
+	audit           *auditLog        // nil unless an audit sink is configured
+	archive         *archive         // nil unless Config.ArchiveMaxLen is set
+	failover        *failover        // nil unless Config.FallbackAddresses is set
+	mirror          *mirror          // nil unless Config.MirrorURL is set
+	shadow          *shadow          // nil unless Config.ShadowQueueRatio is set
+	usage           *usageMeter      // nil unless Config.UsageAccounting is set
+	ingestServer    *http.Server     // nil unless Config.IngestAddress is set
+	ingestAddr      net.Addr         // address the ingest server listens on
+	poolTimeoutLog  int64            // unix nanoseconds of the last pool timeout warning
+	
+	// Lifecycle event listeners
+	events eventBus
+	
+	// Topic routing bindings
+	bindings      []binding
+	bindingsMutex sync.RWMutex
+	
+	// Single-message sends waiting to be pushed together
+	lingerWindow lingerWindow
+	
+	// Sends waiting for their turn in SendOrdered
+	ordered orderedKeys
+	
+	// In-flight sends, tracked so Drain can wait for them
+	draining   bool
+	drainMutex sync.RWMutex
+	inflight   sync.WaitGroup
+	inflightSends int64 // in-flight sends, for Pressure
 	
 	// Context for cancellation
 	ctx    context.Context
@@ -51,23 +117,20 @@ func NewSender(config *Config, options *SenderOptions) (Sender, error) {
 	if options == nil {
 		options = &SenderOptions{}
 	}
+	if err := checkSharedClient(options.Client); err != nil {
+		return nil, err
+	}
 	
-	// Create logger if not provided
-	var logger *slog.Logger
-	if options.Logger != nil {
-		if l, ok := options.Logger.(*slog.Logger); ok {
-			logger = l
-		} else {
-			return nil, fmt.Errorf("logger must be of type *slog.Logger")
-		}
+	// Resolve the connection URL and secret files on a copy so the caller's config is untouched
+	resolved := *config
+	if err := resolved.resolve(); err != nil {
+		return nil, err
 	}
+	config = &resolved
 	
-	if logger == nil {
-		var err error
-		logger, err = NewLogger(config.LogSlogLevel(), "")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create logger: %w", err)
-		}
+	logger, logLevel, err := resolveLogger(config, options)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Create serializer if not provided
@@ -76,15 +139,41 @@ func NewSender(config *Config, options *SenderOptions) (Sender, error) {
 		serializer = NewJSONSerializer()
 	}
 	
+	// Create envelope codec if not provided
+	codec := options.EnvelopeCodec
+	if codec == nil {
+		codec = NewJSONEnvelopeCodec()
+	}
+	
+	// Compile the queue name rules
+	queueNames, err := newQueueNameRules(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Create ID generator if not provided
+	ids := options.IDGenerator
+	if ids == nil {
+		ids = NewUUIDGenerator()
+	}
+	
 	// Create context for cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	sender := &valkeySender{
 		config:     config,
 		logger:     logger,
+		logLevel:   logLevel,
 		options:    options,
 		serializer: serializer,
+		codec:      codec,
+		ids:        ids,
+		queueNames: queueNames,
+		metadata:   producerMetadata(config),
+		producer:   producerName(config),
 		startTime:  time.Now(),
+		outcomes:   newOutcomeWindow(config.HealthWindow),
+		connectionState: ConnectionStateDisconnected,
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -95,29 +184,46 @@ func NewSender(config *Config, options *SenderOptions) (Sender, error) {
 		MaxRequests: config.BreakerMaxRequests,
 		Interval:    config.BreakerInterval,
 		Timeout:     config.BreakerTimeout,
-		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			return counts.ConsecutiveFailures > 3
-		},
+		ReadyToTrip: sender.readyToTrip,
+		IsSuccessful: isBreakerSuccess,
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			sender.logger.Info("Circuit breaker state change",
 				slog.String("name", name),
 				slog.String("from", from.String()),
 				slog.String("to", to.String()),
 			)
+			
+			if options.BreakerStateHandler != nil {
+				options.BreakerStateHandler(from.String(), to.String())
+			}
+			sender.events.publish(SenderEvent{Type: breakerEvent(to)})
 		},
 	})
 	
 	// Initialize rate limiter
 	sender.rateLimiter = rate.NewLimiter(rate.Limit(config.RateLimitRequests), config.RateLimitBurst)
+	sender.adaptive = newAdaptiveLimiter(config, sender.rateLimiter)
 	
-	// Initialize Redis client
-	if err := sender.initClient(); err != nil {
-		return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+	// Apply per-queue serializers and rate limits
+	sender.initQueueProfiles()
+	
+	// Initialize the storage backend
+	backend, err := sender.newBackend()
+	if err != nil {
+		return nil, err
 	}
+	sender.backend = backend
 	
-	// Test connection
-	if err := sender.testConnection(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Valkey: %w", err)
+	// Release everything opened so far if a later step fails
+	if err := sender.start(); err != nil {
+		sender.Close()
+		return nil, err
+	}
+	
+	if config.RetryDelay != 0 {
+		sender.logger.Warn("RetryDelay is deprecated and has no effect, set MinRetryBackoff and MaxRetryBackoff instead",
+			slog.Duration("retry_delay", config.RetryDelay),
+		)
 	}
 	
 	sender.logger.Info("Valkey sender created",
@@ -129,41 +235,227 @@ func NewSender(config *Config, options *SenderOptions) (Sender, error) {
 	return sender, nil
 }
 
-// initClient initializes the Redis client with proper configuration
+// start verifies the connection, opens the optional clients and files and
+// starts the background tasks of a sender whose backend exists. The caller
+// closes the sender if it fails, releasing whatever was opened.
+func (s *valkeySender) start() error {
+	// Test connection
+	if err := s.testConnection(); err != nil {
+		return fmt.Errorf("failed to connect to Valkey: %w", err)
+	}
+	
+	// Load the enqueue script before the first send
+	if err := s.prepareBackend(); err != nil {
+		return fmt.Errorf("failed to prepare backend: %w", err)
+	}
+	
+	// Write to the mirror cluster as well once the primary is verified
+	var err error
+	if s.mirror, err = s.newMirror(); err != nil {
+		return err
+	}
+	
+	// Push metrics to a statsd agent if configured
+	if s.statsd, err = newStatsdClient(s.config); err != nil {
+		return err
+	}
+	
+	// Record every send for the audit trail if configured
+	if s.audit, err = s.newAuditLog(); err != nil {
+		return err
+	}
+	
+	// Copy sent messages into the archive streams if configured
+	if s.archive, err = s.newArchive(); err != nil {
+		return err
+	}
+	
+	// Copy a sample of the sent messages to shadow queues if configured
+	if s.shadow, err = s.newShadow(); err != nil {
+		return err
+	}
+	
+	// Count what every tenant sends if configured
+	if s.usage, err = s.newUsageMeter(); err != nil {
+		return err
+	}
+	
+	// Connect to the standby clusters if configured
+	if s.failover, err = s.newFailover(); err != nil {
+		return err
+	}
+	
+	// Watch credential files for rotation
+	s.startCredentialWatcher()
+	
+	// Reconnect when the address resolves elsewhere
+	s.startDNSWatcher()
+	
+	// Keep connection state fresh while idle
+	s.startHealthMonitor()
+	
+	// Restore lost connections with backoff
+	s.startReconnector()
+	
+	// Cache queue lengths until Valkey invalidates them
+	s.startClientCache()
+	
+	// Remove messages whose TTL has elapsed
+	s.startSweeper()
+	
+	// Alert when watched queues back up
+	s.startQueueWatcher()
+	
+	// Warn before the connection pool runs dry
+	s.startPoolWatcher()
+	
+	// Follow Valkey latency with the rate limit
+	s.startAdaptiveRateLimit()
+	
+	// Accept messages over HTTP if configured
+	return s.startIngestServer()
+}
+
+// resolveLogger returns the logger from the options or creates the default
+// one, together with the level variable of the default logger (nil for a
+// logger from the options)
+func resolveLogger(config *Config, options *SenderOptions) (*slog.Logger, *slog.LevelVar, error) {
+	if options.Logger != nil {
+		return slogLogger(options.Logger), nil, nil
+	}
+	
+	level := new(slog.LevelVar)
+	level.Set(config.LogSlogLevel())
+	logger, err := NewLoggerWithOptions(LogOptions{Level: level, Format: config.LogFormat})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	return logger, level, nil
+}
+
+// initClient initializes the Redis client with proper configuration, or
+// uses the client from the options
 func (s *valkeySender) initClient() error {
+	if s.options.Client != nil {
+		s.setClient(s.options.Client)
+		return nil
+	}
+	
+	client, err := newRedisClient(s.config, s.options)
+	if err != nil {
+		return err
+	}
+	s.setClient(client)
+	return nil
+}
+
+// checkSharedClient rejects shared clients that spread keys over several
+// nodes. A send updates a queue's list, expiry, counters and deduplication
+// keys in one script, which a cluster refuses with CROSSSLOT as the keys
+// hash to different slots.
+func checkSharedClient(client redis.UniversalClient) error {
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return fmt.Errorf("%T is not supported, use a *redis.Client: sends update several keys of a queue atomically", client)
+	}
+	return nil
+}
+
+// newRedisClient creates a Redis client from the configuration and the
+// connection hooks of options
+func newRedisClient(config *Config, options *SenderOptions) (*redis.Client, error) {
+	opts, err := newRedisOptions(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewClient(opts), nil
+}
+
+// newRedisOptions builds the client options from the configuration and the
+// connection hooks of options (which may be nil)
+func newRedisOptions(config *Config, options *SenderOptions) (*redis.Options, error) {
+	if options == nil {
+		options = &SenderOptions{}
+	}
 	opts := &redis.Options{
-		Addr:         s.config.Address,
-		Username:     s.config.Username,
-		Password:     s.config.Password,
-		DB:           s.config.Database,
-		DialTimeout:  s.config.DialTimeout,
-		ReadTimeout:  s.config.ReadTimeout,
-		WriteTimeout: s.config.WriteTimeout,
-		PoolSize:     s.config.PoolSize,
-		MinIdleConns: s.config.MinIdleConns,
-		ConnMaxIdleTime: s.config.MaxIdleTime,
-		ConnMaxLifetime: s.config.ConnMaxLifetime,
+		Addr:         config.Address,
+		Username:     config.Username,
+		Password:     config.Password,
+		DB:           config.Database,
+		DialTimeout:  config.DialTimeout,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		ConnMaxIdleTime: config.MaxIdleTime,
+		ConnMaxLifetime: config.ConnMaxLifetime,
+		PoolTimeout:     config.PoolTimeout,
+		MaxRetries:      redisSetting(config.MaxRetries),
+		MinRetryBackoff: redisSetting(config.MinRetryBackoff),
+		MaxRetryBackoff: redisSetting(config.MaxRetryBackoff),
+	}
+	if options.CredentialsProvider != nil {
+		opts.CredentialsProviderContext = options.CredentialsProvider.Credentials
 	}
 	
 	// Configure TLS if enabled
-	if s.config.TLSEnabled {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: s.config.TLSSkipVerify,
-		}
-		
-		if s.config.TLSCertFile != "" && s.config.TLSKeyFile != "" {
-			cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
-			if err != nil {
-				return fmt.Errorf("failed to load TLS certificate: %w", err)
-			}
-			tlsConfig.Certificates = []tls.Certificate{cert}
+	if config.TLSEnabled {
+		tlsConfig, err := buildTLSConfig(config)
+		if err != nil {
+			return nil, err
 		}
-		
 		opts.TLSConfig = tlsConfig
 	}
 	
-	s.client = redis.NewClient(opts)
-	return nil
+	if config.ProxyURL != "" {
+		dialer, err := newProxyDialer(config.ProxyURL, config.DialTimeout, opts.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		opts.Dialer = dialer
+	}
+	if config.SSHTunnel.Host != "" {
+		opts.Dialer = newSSHDialer(config.SSHTunnel, opts.TLSConfig)
+	}
+	if options.Dialer != nil {
+		if opts.Dialer != nil {
+			return nil, fmt.Errorf("a custom dialer cannot be combined with a proxy or SSH tunnel")
+		}
+		opts.Dialer = customDialer(options.Dialer, opts.TLSConfig)
+	}
+	
+	return opts, nil
+}
+
+// redisSetting translates a setting where 0 means none into go-redis, where
+// 0 selects its default and -1 means none
+func redisSetting[T int | time.Duration](value T) T {
+	if value == 0 {
+		return -1
+	}
+	return value
+}
+
+// setClient replaces the Redis client thread-safely
+func (s *valkeySender) setClient(client redis.UniversalClient) {
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+	s.client = client
+}
+
+// getClient gets the Redis client thread-safely
+func (s *valkeySender) getClient() redis.UniversalClient {
+	s.clientMutex.RLock()
+	defer s.clientMutex.RUnlock()
+	return s.client
+}
+
+// prepareBackend readies the backend for pushes
+func (s *valkeySender) prepareBackend() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.DialTimeout)
+	defer cancel()
+	
+	return s.backend.prepare(ctx)
 }
 
 // testConnection tests the connection to Valkey
@@ -172,19 +464,19 @@ func (s *valkeySender) testConnection() error {
 	defer cancel()
 	
 	// Test basic connectivity
-	pong, err := s.client.Ping(ctx).Result()
-	if err != nil {
-		s.setConnectionState(false)
-		return fmt.Errorf("failed to ping Valkey: %w", err)
-	}
+	s.setConnectionState(ConnectionStateConnecting, nil)
 	
-	if pong != "PONG" {
-		s.setConnectionState(false)
-		return fmt.Errorf("unexpected ping response: %s", pong)
+	start := time.Now()
+	if err := s.backend.ping(ctx); err != nil {
+		s.setConnectionState(ConnectionStateDisconnected, err)
+		return fmt.Errorf("failed to ping Valkey: %w", err)
 	}
 	
-	s.setConnectionState(true)
+	s.recordPing(time.Since(start))
+	s.setConnectionState(ConnectionStateConnected, nil)
+	s.lastMutex.Lock()
 	s.lastSuccess = time.Now()
+	s.lastMutex.Unlock()
 	
 	s.logger.Info("Successfully connected to Valkey",
 		slog.String("address", s.config.Address),
@@ -194,240 +486,775 @@ func (s *valkeySender) testConnection() error {
 	return nil
 }
 
-// setConnectionState updates the connection state thread-safely
-func (s *valkeySender) setConnectionState(connected bool) {
+// setConnectionState updates the connection state thread-safely and
+// notifies the connection handler on transitions
+func (s *valkeySender) setConnectionState(state string, cause error) {
 	s.connectionMutex.Lock()
-	defer s.connectionMutex.Unlock()
-	s.isConnected = connected
+	previous := s.connectionState
+	s.connectionState = state
+	s.connectionMutex.Unlock()
+	
+	if previous == state {
+		return
+	}
+	
+	if s.options.ConnectionHandler != nil {
+		s.options.ConnectionHandler(ConnectionEvent{
+			PreviousState: previous,
+			State:         state,
+			Latency:       time.Duration(atomic.LoadInt64(&s.pingLatency)),
+			Error:         cause,
+			Timestamp:     time.Now(),
+		})
+	}
+	
+	switch {
+	case state == ConnectionStateConnected:
+		s.events.publish(SenderEvent{Type: EventConnected})
+	case previous == ConnectionStateConnected:
+		s.events.publish(SenderEvent{Type: EventDisconnected, Err: cause})
+	}
+}
+
+// markDisconnected records a connection failure. While the reconnect
+// manager or the health monitor is running the sender is reconnecting,
+// otherwise it is disconnected.
+func (s *valkeySender) markDisconnected(cause error) {
+	state := ConnectionStateDisconnected
+	if s.reconnectEnabled() || s.config.HealthCheckInterval > 0 {
+		state = ConnectionStateReconnecting
+	}
+	s.setConnectionState(state, cause)
+	s.signalReconnect(cause)
 }
 
 // getConnectionState gets the connection state thread-safely
-func (s *valkeySender) getConnectionState() bool {
+func (s *valkeySender) getConnectionState() string {
 	s.connectionMutex.RLock()
 	defer s.connectionMutex.RUnlock()
-	return s.isConnected
+	return s.connectionState
 }
 
 // SendMessage sends a message to the specified queue
 func (s *valkeySender) SendMessage(ctx context.Context, queue string, message interface{}) error {
-	return s.SendMessageWithTTL(ctx, queue, message, s.config.MessageTTL)
+	return s.SendMessageWithTTL(ctx, queue, message, s.messageTTL(queue))
 }
 
 // SendMessageWithTTL sends a message with custom TTL
 func (s *valkeySender) SendMessageWithTTL(ctx context.Context, queue string, message interface{}, ttl time.Duration) error {
+	return s.sendMessage(ctx, opSendMessage, "", queue, message, SendOptions{TTL: ttl})
+}
+
+// SendMessageWithOptions sends a message with a caller-supplied ID,
+// idempotency key, TTL or headers
+func (s *valkeySender) SendMessageWithOptions(ctx context.Context, queue string, message interface{}, opts SendOptions) error {
+	if opts.TTL == 0 {
+		opts.TTL = s.messageTTL(queue)
+	}
+	return s.sendMessage(ctx, opSendMessage, "", queue, message, opts)
+}
+
+// SendTyped sends a message and records its type name in the message-type
+// header, so consumers of mixed queues can dispatch on it
+func (s *valkeySender) SendTyped(ctx context.Context, queue, typeName string, message interface{}) error {
+	if typeName == "" {
+		return fmt.Errorf("type name cannot be empty")
+	}
+	return s.sendMessage(ctx, opSendMessage, "", queue, message, SendOptions{
+		TTL:     s.messageTTL(queue),
+		Headers: map[string]string{HeaderMessageType: typeName},
+	})
+}
+
+// sendMessage sends a single message with the given options, to the
+// tenant's copy of the queue if a tenant is given. opts.TTL is used as is.
+func (s *valkeySender) sendMessage(ctx context.Context, op, tenant, queue string, message interface{}, opts SendOptions) error {
+	_, err := s.sendOne(ctx, op, tenant, queue, message, opts)
+	return err
+}
+
+// sendOne is sendMessage returning the pushed batch
+func (s *valkeySender) sendOne(ctx context.Context, op, tenant, queue string, message interface{}, opts SendOptions) (*queueBatch, error) {
 	startTime := time.Now()
 	
-	// Apply rate limiting
-	if err := s.rateLimiter.Wait(ctx); err != nil {
-		return fmt.Errorf("rate limiter error: %w", err)
+	batch, err := s.newOneBatch(ctx, op, tenant, queue, message, opts)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Use circuit breaker
-	_, err := s.circuitBreaker.Execute(func() (interface{}, error) {
-		return nil, s.sendMessageInternal(ctx, queue, message, ttl)
-	})
-	
+	if s.config.LingerInterval > 0 {
+		err = s.lingerAndWait(ctx, op, queue, batch)
+	} else {
+		err = s.execute(ctx, op, queue, []*queueBatch{batch}, false)
+	}
 	if err != nil {
-		atomic.AddInt64(&s.errorCount, 1)
-		s.lastError = err.Error()
-		
-		if s.options.ErrorHandler != nil {
-			s.options.ErrorHandler(err)
-		}
-		
-		return err
+		return nil, err
 	}
 	
+	s.sentOne(batch, tenant, startTime)
+	return batch, nil
+}
+
+// newOneBatch encodes a single message with the given options, for the
+// tenant's copy of the queue if a tenant is given
+func (s *valkeySender) newOneBatch(ctx context.Context, op, tenant, queue string, message interface{}, opts SendOptions) (*queueBatch, error) {
+	batch, err := s.newQueueBatch(ctx, queue, []interface{}{message}, opts.TTL, contextHeaders(ctx, opts.Headers))
+	if err != nil {
+		return nil, s.failTenant(op, tenant, queue, err)
+	}
+	if err := s.applySendOptions(batch, opts); err != nil {
+		return nil, s.failTenant(op, tenant, queue, err)
+	}
+	if tenant != "" {
+		batch.key = tenantQueueKey(s.config, s.options, tenant, queue)
+		batch.tenant = tenant
+	}
+	return batch, nil
+}
+
+// sentOne records a single message sent
+func (s *valkeySender) sentOne(batch *queueBatch, tenant string, startTime time.Time) {
 	// Update metrics
-	atomic.AddInt64(&s.messagesSent, 1)
-	s.lastSuccess = time.Now()
+	s.recordSuccess(1)
 	
-	// Call success handler
-	if s.options.SuccessHandler != nil {
-		metadata := MessageMetadata{
-			Queue:     queue,
-			MessageID: uuid.New().String(),
-			Timestamp: startTime,
-			TTL:       ttl,
-		}
-		s.options.SuccessHandler(metadata)
-	}
+	s.logger.Debug("Message sent successfully",
+		slog.String("queue", batch.queue),
+		slog.String("tenant", tenant),
+		slog.String("message_id", batch.envelopes[0].ID),
+		slog.Int("payload_size", len(batch.envelopes[0].Payload)),
+		slog.Duration("ttl", batch.ttl),
+	)
 	
-	return nil
+	// Call success handler for each message
+	s.notifySuccess([]*queueBatch{batch}, tenant, startTime)
 }
 
-// sendMessageInternal performs the actual message sending
-func (s *valkeySender) sendMessageInternal(ctx context.Context, queue string, message interface{}, ttl time.Duration) error {
-	// Create message envelope
-	envelope := MessageEnvelope{
-		ID:        uuid.New().String(),
-		Queue:     queue,
-		Timestamp: time.Now(),
-		TTL:       ttl,
-		Headers:   make(map[string]string),
+
+// SendBatch sends multiple messages to the same queue atomically. If
+// Config.BatchChunkSize is set, larger batches are sent in chunks instead,
+// each atomic on its own, see SendBatchChunked.
+func (s *valkeySender) SendBatch(ctx context.Context, queue string, messages []interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages slice cannot be empty")
 	}
 	
-	// Serialize the message payload
-	payload, err := s.serializer.Serialize(message)
-	if err != nil {
-		return fmt.Errorf("failed to serialize message: %w", err)
+	if chunk := s.config.BatchChunkSize; chunk > 0 && len(messages) > chunk {
+		_, err := s.SendBatchChunked(ctx, queue, messages)
+		return err
 	}
-	envelope.Payload = payload
+	_, err := s.sendBatch(ctx, queue, messages)
+	return err
+}
+
+// sendBatch sends messages to the queue in one atomic push and returns the
+// batch, which is nil if it could not be built
+func (s *valkeySender) sendBatch(ctx context.Context, queue string, messages []interface{}) (*queueBatch, error) {
+	startTime := time.Now()
 	
-	// Serialize the envelope
-	envelopeData, err := SerializeMessageEnvelope(envelope)
+	batch, err := s.newQueueBatch(ctx, queue, messages, s.messageTTL(queue), HeadersFromContext(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to serialize envelope: %w", err)
+		return nil, s.fail(opSendBatch, queue, err)
 	}
 	
-	// Send to Redis List using LPUSH (add to left side)
-	listKey := s.getQueueKey(queue)
+	// Rate limiting is applied once for the batch
+	if err := s.execute(ctx, opSendBatch, queue, []*queueBatch{batch}, false); err != nil {
+		return batch, err
+	}
 	
-	pipe := s.client.Pipeline()
+	// Update metrics
+	s.recordSuccess(len(messages))
 	
-	// Add message to list
-	pipe.LPush(ctx, listKey, envelopeData)
+	s.logger.Debug("Batch sent successfully",
+		slog.String("queue", queue),
+		slog.Int("message_count", len(messages)),
+	)
 	
-	// Set TTL on the list itself if it doesn't exist
-	pipe.Expire(ctx, listKey, ttl)
+	// Call success handler for each message
+	s.notifySuccess([]*queueBatch{batch}, "", startTime)
 	
-	// Execute pipeline
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		s.setConnectionState(false)
-		return fmt.Errorf("failed to send message to queue %s: %w", queue, err)
+	return batch, nil
+}
+
+// SendRaw pushes pre-encoded bytes to the queue without serialization or envelope
+func (s *valkeySender) SendRaw(ctx context.Context, queue string, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("data cannot be empty")
 	}
 	
-	s.setConnectionState(true)
+	if err := s.queueNames.check(queue); err != nil {
+		return s.fail(opSendRaw, queue, err)
+	}
+	if err := s.validateMessages(queue, []interface{}{data}); err != nil {
+		return s.fail(opSendRaw, queue, err)
+	}
+	if err := s.checkMessageSize(0, data); err != nil {
+		return s.fail(opSendRaw, queue, err)
+	}
 	
-	s.logger.Debug("Message sent successfully",
+	startTime := time.Now()
+	
+	batch := &queueBatch{
+		queue: queue,
+		key:   s.getQueueKey(queue),
+		ttl:   s.messageTTL(queue),
+		data:  []interface{}{data},
+	}
+	if s.deduplicates(queue) {
+		batch.dedup = []string{payloadHash(data)}
+	}
+	
+	if err := s.execute(ctx, opSendRaw, queue, []*queueBatch{batch}, false); err != nil {
+		return err
+	}
+	
+	// Update metrics
+	s.recordSuccess(1)
+	
+	s.logger.Debug("Raw message sent successfully",
 		slog.String("queue", queue),
-		slog.String("message_id", envelope.ID),
-		slog.Int("payload_size", len(payload)),
-		slog.Duration("ttl", ttl),
+		slog.Int("payload_size", len(data)),
 	)
 	
+	// Call success handler for each message
+	s.notifySuccess([]*queueBatch{batch}, "", startTime)
+	
 	return nil
 }
 
-
-// SendBatch sends multiple messages to the same queue atomically
-func (s *valkeySender) SendBatch(ctx context.Context, queue string, messages []interface{}) error {
+// SendMulti sends messages to several queues using a single pipeline
+func (s *valkeySender) SendMulti(ctx context.Context, messages map[string][]interface{}) error {
 	if len(messages) == 0 {
-		return fmt.Errorf("messages slice cannot be empty")
+		return fmt.Errorf("messages map cannot be empty")
 	}
 	
+	// Sort queue names so the pipeline order is deterministic
+	queues := make([]string, 0, len(messages))
+	total := 0
+	for queue, queueMessages := range messages {
+		if len(queueMessages) == 0 {
+			return fmt.Errorf("messages slice for queue %s cannot be empty", queue)
+		}
+		queues = append(queues, queue)
+		total += len(queueMessages)
+	}
+	sort.Strings(queues)
+	
 	startTime := time.Now()
 	
-	// Apply rate limiting (once for the batch)
-	if err := s.rateLimiter.Wait(ctx); err != nil {
-		return fmt.Errorf("rate limiter error: %w", err)
+	batches := make([]*queueBatch, 0, len(queues))
+	for _, queue := range queues {
+		batch, err := s.newQueueBatch(ctx, queue, messages[queue], s.messageTTL(queue), HeadersFromContext(ctx))
+		if err != nil {
+			return s.fail(opSendMulti, queue, err)
+		}
+		batches = append(batches, batch)
 	}
 	
-	// Use circuit breaker
-	_, err := s.circuitBreaker.Execute(func() (interface{}, error) {
-		return nil, s.sendBatchInternal(ctx, queue, messages)
-	})
+	// Rate limiting is applied once for the whole round trip
+	if err := s.execute(ctx, opSendMulti, "", batches, false); err != nil {
+		return err
+	}
+	
+	// Update metrics
+	s.recordSuccess(total)
+	
+	s.logger.Debug("Multi-queue send completed",
+		slog.Int("queue_count", len(queues)),
+		slog.Int("message_count", total),
+	)
+	
+	// Call success handler for each message
+	s.notifySuccess(batches, "", startTime)
 	
+	return nil
+}
+
+// SendToQueues sends one message to several queues atomically. Every copy
+// shares the same envelope ID, so consumers of different queues can
+// correlate them, and either all queues receive the message or none.
+func (s *valkeySender) SendToQueues(ctx context.Context, queues []string, message interface{}) error {
+	return s.sendToQueues(ctx, opSendToQueues, queues, message, nil)
+}
+
+// sendToQueues pushes copies of one envelope to every queue in a single transaction
+func (s *valkeySender) sendToQueues(ctx context.Context, op string, queues []string, message interface{}, headers map[string]string) error {
+	if len(queues) == 0 {
+		return fmt.Errorf("queues slice cannot be empty")
+	}
+	
+	seen := make(map[string]bool, len(queues))
+	for _, queue := range queues {
+		if seen[queue] {
+			return fmt.Errorf("duplicate queue %s", queue)
+		}
+		seen[queue] = true
+	}
+	
+	startTime := time.Now()
+	
+	first, err := s.newQueueBatch(ctx, queues[0], []interface{}{message}, s.messageTTL(queues[0]), contextHeaders(ctx, headers))
 	if err != nil {
-		atomic.AddInt64(&s.errorCount, 1)
-		s.lastError = err.Error()
-		
-		if s.options.ErrorHandler != nil {
-			s.options.ErrorHandler(err)
+		return s.fail(op, queues[0], err)
+	}
+	
+	batches := []*queueBatch{first}
+	for _, queue := range queues[1:] {
+		if err := s.validateMessages(queue, []interface{}{message}); err != nil {
+			return s.fail(op, queue, err)
 		}
-		
+		batch, err := s.newFanOutBatch(first, queue)
+		if err != nil {
+			return s.fail(op, queue, err)
+		}
+		batches = append(batches, batch)
+	}
+	
+	if err := s.execute(ctx, op, "", batches, true); err != nil {
 		return err
 	}
 	
 	// Update metrics
-	atomic.AddInt64(&s.messagesSent, int64(len(messages)))
-	s.lastSuccess = time.Now()
+	s.recordSuccess(len(queues))
+	
+	messageID := first.envelopes[0].ID
+	s.logger.Debug("Fan-out send completed",
+		slog.String("message_id", messageID),
+		slog.Any("queues", queues),
+	)
 	
 	// Call success handler for each message
-	if s.options.SuccessHandler != nil {
-		for i := range messages {
-			metadata := MessageMetadata{
-				Queue:     queue,
-				Position:  int64(i),
-				MessageID: uuid.New().String(),
-				Timestamp: startTime,
-				TTL:       s.config.MessageTTL,
-			}
-			s.options.SuccessHandler(metadata)
+	s.notifySuccess(batches, "", startTime)
+	
+	return nil
+}
+
+// newFanOutBatch copies a single-message batch for another queue, keeping
+// the envelope ID and payload. The TTL and deduplication follow the queue's
+// profile.
+func (s *valkeySender) newFanOutBatch(source *queueBatch, queue string) (*queueBatch, error) {
+	if err := s.queueNames.check(queue); err != nil {
+		return nil, err
+	}
+	envelope := source.envelopes[0]
+	envelope.Queue = queue
+	envelope.TTL = s.messageTTL(queue)
+	
+	batch := &queueBatch{
+		queue:     queue,
+		key:       s.getQueueKey(queue),
+		ttl:       envelope.TTL,
+		envelopes: []MessageEnvelope{envelope},
+		data:      make([]interface{}, 1),
+	}
+	if s.deduplicates(queue) {
+		batch.dedup = source.dedup
+		if batch.dedup == nil {
+			batch.dedup = []string{payloadHash(envelope.Payload)}
 		}
 	}
 	
+	if s.options.RawPayload {
+		batch.data[0] = envelope.Payload
+		return batch, nil
+	}
+	
+	data, err := s.codec.Encode(envelope)
+	if err != nil {
+		return nil, &Error{Kind: ErrSerialization, Err: fmt.Errorf("envelope 0: %w", err)}
+	}
+	if err := s.checkMessageSize(0, data); err != nil {
+		return nil, err
+	}
+	batch.data[0] = data
+	
+	return batch, nil
+}
+
+// applySendOptions gives a single-message batch the caller's message ID and
+// idempotency key. A caller's message ID doubles as the idempotency key when
+// deduplication is enabled.
+func (s *valkeySender) applySendOptions(batch *queueBatch, opts SendOptions) error {
+	key := opts.IdempotencyKey
+	if key == "" && s.deduplicates(batch.queue) {
+		key = opts.MessageID
+	}
+	if key != "" {
+		batch.dedup = []string{idempotencyHash(key)}
+	}
+	
+	if opts.MessageID == "" {
+		return nil
+	}
+	batch.envelopes[0].ID = opts.MessageID
+	if s.options.RawPayload {
+		return nil
+	}
+	
+	data, err := s.codec.Encode(batch.envelopes[0])
+	if err != nil {
+		return &Error{Kind: ErrSerialization, Err: fmt.Errorf("envelope 0: %w", err)}
+	}
+	if err := s.checkMessageSize(0, data); err != nil {
+		return err
+	}
+	batch.data[0] = data
 	return nil
 }
 
-// sendBatchInternal performs the actual batch message sending
-func (s *valkeySender) sendBatchInternal(ctx context.Context, queue string, messages []interface{}) error {
-	listKey := s.getQueueKey(queue)
+// checkMessageSize rejects the i-th encoded message of a send if it
+// exceeds Config.MaxMessageBytes, counting the rejection
+func (s *valkeySender) checkMessageSize(i int, data []byte) error {
+	return s.checkElementSize("message", i, data)
+}
+
+// checkElementSize rejects the i-th list element of a send, a message or
+// a frame as named by kind, if it exceeds Config.MaxMessageBytes
+func (s *valkeySender) checkElementSize(kind string, i int, data []byte) error {
+	limit := s.config.MaxMessageBytes
+	if limit <= 0 || len(data) <= limit {
+		return nil
+	}
+	atomic.AddInt64(&s.messagesTooLarge, 1)
+	return &Error{Kind: ErrMessageTooLarge, Err: fmt.Errorf("%s %d is %d bytes, over the limit of %d", kind, i, len(data), limit)}
+}
+
+// queueBatch holds the encoded envelopes destined for a single queue
+type queueBatch struct {
+	queue     string
+	key       string
+	ttl       time.Duration
+	envelopes []MessageEnvelope
+	data      []interface{}
+	dedup     []string // payload hashes, only with deduplication enabled
+	positions []int64  // set by the backend once pushed
+	tenant    string   // owner of the tenant copy of the queue, empty for the shared queue
+}
+
+// batchTenant returns the tenant the batches are sent for, empty unless
+// they all go to copies of the same tenant
+func batchTenant(batches []*queueBatch) string {
+	if len(batches) == 0 {
+		return ""
+	}
+	tenant := batches[0].tenant
+	for _, batch := range batches[1:] {
+		if batch.tenant != tenant {
+			return ""
+		}
+	}
+	return tenant
+}
+
+// validateMessages runs the validation hook on messages bound for queue
+func (s *valkeySender) validateMessages(queue string, messages []interface{}) error {
+	if s.options.Validator == nil {
+		return nil
+	}
+	for i, message := range messages {
+		if err := s.options.Validator(queue, message); err != nil {
+			return &Error{Kind: ErrValidation, Err: fmt.Errorf("message %d: %w", i, err)}
+		}
+	}
+	return nil
+}
+
+// newQueueBatch wraps each message in an envelope carrying the given headers
+// and encodes it for the queue
+func (s *valkeySender) newQueueBatch(ctx context.Context, queue string, messages []interface{}, ttl time.Duration, headers map[string]string) (*queueBatch, error) {
+	if err := s.queueNames.check(queue); err != nil {
+		return nil, err
+	}
+	if err := s.validateMessages(queue, messages); err != nil {
+		return nil, err
+	}
 	
-	// Prepare all envelopes
-	envelopes := make([]interface{}, len(messages))
+	batch := &queueBatch{
+		queue:     queue,
+		key:       s.getQueueKey(queue),
+		ttl:       ttl,
+		envelopes: make([]MessageEnvelope, len(messages)),
+		data:      make([]interface{}, len(messages)),
+	}
+	serializer, dedup := s.serializerFor(queue), s.deduplicates(queue)
+	standard := s.standardHeaders(serializer)
 	
 	for i, message := range messages {
 		envelope := MessageEnvelope{
-			ID:        uuid.New().String(),
+			Version:   EnvelopeVersion,
+			ID:        s.ids.NewID(),
 			Queue:     queue,
 			Timestamp: time.Now(),
-			TTL:       s.config.MessageTTL,
-			Headers:   make(map[string]string),
+			TTL:       ttl,
+			Metadata:  s.metadata,
+		}
+		// Headers stay nil unless something sets one
+		if len(standard) > 0 {
+			envelope.Headers = maps.Clone(standard)
+			maps.Copy(envelope.Headers, headers)
+		} else if len(headers) > 0 {
+			envelope.Headers = maps.Clone(headers)
 		}
 		
 		// Serialize the message payload
-		payload, err := s.serializer.Serialize(message)
+		payload, err := serializer.Serialize(message)
 		if err != nil {
-			return fmt.Errorf("failed to serialize message %d: %w", i, err)
+			return nil, &Error{Kind: ErrSerialization, Err: fmt.Errorf("message %d: %w", i, err)}
 		}
 		envelope.Payload = payload
 		
+		// Let the serializer describe the payload in the headers
+		if hs, ok := serializer.(HeaderSerializer); ok {
+			if extra := hs.Headers(message); len(extra) > 0 {
+				if envelope.Headers == nil {
+					envelope.Headers = make(map[string]string, len(extra))
+				}
+				maps.Copy(envelope.Headers, extra)
+			}
+		}
+		
+		// Let the BeforeSend hook change or reject the envelope
+		if err := s.beforeSend(ctx, &envelope); err != nil {
+			return nil, &Error{Kind: ErrValidation, Err: fmt.Errorf("message %d: %w", i, err)}
+		}
+		payload = envelope.Payload
+		if s.config.EnvelopeChecksum != "" {
+			if envelope.Checksum, err = PayloadChecksum(s.config.EnvelopeChecksum, payload); err != nil {
+				return nil, &Error{Kind: ErrSerialization, Err: fmt.Errorf("message %d: %w", i, err)}
+			}
+		}
+		
+		batch.envelopes[i] = envelope
+		if dedup {
+			batch.dedup = append(batch.dedup, payloadHash(payload))
+		}
+		
+		// Raw mode pushes the payload without the envelope wrapper
+		if s.options.RawPayload {
+			if err := s.checkMessageSize(i, payload); err != nil {
+				return nil, err
+			}
+			batch.data[i] = payload
+			continue
+		}
+		
 		// Serialize the envelope
-		envelopeData, err := SerializeMessageEnvelope(envelope)
+		envelopeData, err := s.codec.Encode(envelope)
 		if err != nil {
-			return fmt.Errorf("failed to serialize envelope %d: %w", i, err)
+			return nil, &Error{Kind: ErrSerialization, Err: fmt.Errorf("envelope %d: %w", i, err)}
+		}
+		if err := s.checkMessageSize(i, envelopeData); err != nil {
+			return nil, err
 		}
 		
-		envelopes[i] = envelopeData
+		batch.data[i] = envelopeData
+	}
+	
+	return batch, nil
+}
+
+// pushBatches pushes every batch to the storage backend and applies the TTL
+// strategy, enforcing the queue length cap if one is configured. If atomic is
+// set, either all batches are pushed or none.
+func (s *valkeySender) pushBatches(ctx context.Context, batches []*queueBatch, atomic bool) error {
+	return s.pushed(ctx, batches, s.pushGroups(ctx, groupBatches(batches, atomic)))
+}
+
+// pushed updates the connection state and queue rates after a push to the
+// primary; pushes to a standby leave them alone
+func (s *valkeySender) pushed(ctx context.Context, batches []*queueBatch, err error) error {
+	if ctx.Value(standbyKey{}) != nil {
+		return err
 	}
+	if err != nil {
+		if isConnectionError(err) {
+			s.markDisconnected(err)
+		}
+		return err
+	}
+	
+	for _, batch := range batches {
+		s.recordQueueRate(batch.queue, len(batch.data))
+	}
+	
+	s.setConnectionState(ConnectionStateConnected, nil)
+	return nil
+}
+
+// execute applies rate limiting and the circuit breaker around pushing the batches
+func (s *valkeySender) execute(ctx context.Context, op, queue string, batches []*queueBatch, atomic bool) error {
+	return s.guard(ctx, op, queue, batches, func(ctx context.Context) error {
+		return s.pushBatches(ctx, batches, atomic)
+	})
+}
+
+// guard applies the send timeout, drain tracking, rate limiting and the
+// circuit breaker around push, and records how long the send took
+func (s *valkeySender) guard(ctx context.Context, op, queue string, batches []*queueBatch, push func(ctx context.Context) error) error {
+	return s.guardSend(ctx, op, queue, batches, false, push)
+}
+
+// guardSend is guard, skipping drain tracking and rate limiting for
+// lingered sends, which went through both when they joined the window
+func (s *valkeySender) guardSend(ctx context.Context, op, queue string, batches []*queueBatch, lingered bool, push func(ctx context.Context) error) (err error) {
+	count, size, largest := batchSizes(batches)
+	s.checkPayloadSize(op, queue, count, size, largest)
+	tenant := batchTenant(batches)
 	
-	// Send all messages atomically using LPUSH
-	pipe := s.client.Pipeline()
+	start := time.Now()
+	defer func() { s.recordLatency(op, tenant, queue, count, size, time.Since(start), err) }()
+	defer func(ctx context.Context) { s.afterSend(ctx, batches, start, err) }(ctx)
+	defer func() { s.audit.audit(batches, err) }()
+	failedOver := false
+	defer func() {
+		if !failedOver {
+			s.archive.tee(batches, err)
+			s.shadow.tee(batches, err)
+			s.usage.count(batches, err)
+		}
+	}()
 	
-	// Add all messages to list
-	pipe.LPush(ctx, listKey, envelopes...)
+	// Bound the whole send, however long the caller is willing to wait
+	if s.config.SendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.SendTimeout)
+		defer cancel()
+	}
 	
-	// Set TTL on the list
-	pipe.Expire(ctx, listKey, s.config.MessageTTL)
+	// Refuse sends once draining, and let a drain deadline abort this one
+	if !lingered {
+		var done func(error)
+		ctx, done, err = s.beginSend(ctx, count)
+		if err != nil {
+			return classifyError(op, queue, err)
+		}
+		defer func() { done(err) }()
+	}
 	
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		s.setConnectionState(false)
-		return fmt.Errorf("failed to send batch to queue %s: %w", queue, err)
+	// Don't wait on a connection known to be down, nor count it against
+	// the breaker, unless the send can go to a standby
+	refused := s.refuseWhileReconnecting()
+	if refused != nil && !s.failover.covers(batches) {
+		return s.failTenant(op, tenant, queue, refused)
 	}
 	
-	s.setConnectionState(true)
+	// Apply rate limiting
+	if !lingered {
+		if err := s.acquireRateLimit(ctx, batchQueues(batches)...); err != nil {
+			return classifyError(op, queue, err)
+		}
+	}
 	
-	s.logger.Debug("Batch sent successfully",
-		slog.String("queue", queue),
-		slog.Int("message_count", len(messages)),
-	)
+	// Use circuit breaker, sending to a standby while it rejects sends
+	err = refused
+	if err == nil {
+		_, err = s.circuitBreaker.Execute(func() (interface{}, error) {
+			pushStart := time.Now()
+			err := push(ctx)
+			s.adaptive.observe(time.Since(pushStart), err)
+			return nil, err
+		})
+		if err == nil {
+			s.failedBack()
+		}
+	}
+	if refused != nil || s.failsOver(err, batches) {
+		failedOver = true
+		err = s.failOver(ctx, batches, push, err)
+	}
+	if err != nil {
+		return s.failTenant(op, tenant, queue, err)
+	}
 	
 	return nil
 }
 
+// notifySuccess calls the success handler for every message of the pushed
+// batches with its envelope ID, headers, payload size and queue position
+func (s *valkeySender) notifySuccess(batches []*queueBatch, tenant string, startTime time.Time) {
+	if s.options.SuccessHandler == nil && !s.events.active() {
+		return
+	}
+	
+	for _, batch := range batches {
+		for i := range batch.data {
+			metadata := batch.metadata(i, tenant, startTime)
+			if s.options.SuccessHandler != nil {
+				s.options.SuccessHandler(metadata)
+			}
+			s.events.publish(SenderEvent{Type: EventMessageSent, Queue: batch.queue, Metadata: &metadata})
+		}
+	}
+}
+
+// metadata describes the i-th message of a pushed batch
+func (b *queueBatch) metadata(i int, tenant string, startTime time.Time) MessageMetadata {
+	metadata := MessageMetadata{
+		Queue:     b.queue,
+		Tenant:    tenant,
+		Timestamp: startTime,
+		TTL:       b.ttl,
+	}
+	if i < len(b.positions) {
+		metadata.Position = b.positions[i]
+	}
+	if i < len(b.envelopes) {
+		envelope := b.envelopes[i]
+		metadata.MessageID = envelope.ID
+		metadata.Headers = envelope.Headers
+		metadata.Size = len(envelope.Payload)
+	} else if raw, ok := b.data[i].([]byte); ok {
+		metadata.Size = len(raw)
+	}
+	return metadata
+}
+
+// fail classifies a send error, records it and notifies the error handler
+func (s *valkeySender) fail(op, queue string, err error) error {
+	return s.failTenant(op, "", queue, err)
+}
+
+// failTenant is fail for a send to the tenant copy of a queue, whose error
+// metric is tagged with the tenant
+func (s *valkeySender) failTenant(op, tenant, queue string, err error) error {
+	err = classifyError(op, queue, err)
+	s.warnPoolTimeout(err)
+	s.recordFailure(err)
+	s.emitFailure(op, tenant, queue)
+	s.events.publish(SenderEvent{Type: EventMessageFailed, Queue: queue, Err: err})
+	return err
+}
+
+// recordSuccess updates counters after messages were sent
+func (s *valkeySender) recordSuccess(count int) {
+	now := time.Now()
+	atomic.AddInt64(&s.messagesSent, int64(count))
+	s.outcomes.add(int64(count), 0, now)
+	s.lastMutex.Lock()
+	s.lastSuccess = now
+	s.lastMutex.Unlock()
+}
+
+// recordFailure updates counters and notifies the error handler
+func (s *valkeySender) recordFailure(err error) {
+	now := time.Now()
+	atomic.AddInt64(&s.errorCount, 1)
+	s.failures.add(1, now)
+	s.outcomes.add(0, 1, now)
+	s.lastMutex.Lock()
+	s.lastError = err.Error()
+	s.lastMutex.Unlock()
+	
+	if s.options.ErrorHandler != nil {
+		s.options.ErrorHandler(err)
+	}
+}
+
 // GetQueueSize returns the current size of a queue
 func (s *valkeySender) GetQueueSize(ctx context.Context, queue string) (int64, error) {
 	listKey := s.getQueueKey(queue)
 	
-	size, err := s.client.LLen(ctx, listKey).Result()
+	size, err := s.queueLength(ctx, listKey)
 	if err != nil {
-		if err == redis.Nil {
-			return 0, nil // Queue doesn't exist, so size is 0
-		}
 		return 0, fmt.Errorf("failed to get queue size for %s: %w", queue, err)
 	}
 	
@@ -438,58 +1265,123 @@ func (s *valkeySender) GetQueueSize(ctx context.Context, queue string) (int64, e
 func (s *valkeySender) Close() error {
 	s.logger.Info("Closing Valkey sender")
 	
+	// Finish HTTP requests in progress while sends are still accepted
+	s.stopIngestServer()
+	
+	// Refuse new sends
+	s.stopAccepting()
+	s.closeLingerWindow()
+	
 	// Cancel context to stop all operations
 	s.cancel()
 	
 	// Wait for all goroutines to finish
 	s.wg.Wait()
 	
-	// Close Redis client
-	if s.client != nil {
-		if err := s.client.Close(); err != nil {
+	s.statsd.close()
+	
+	if err := s.audit.close(); err != nil {
+		s.logger.Error("Error closing audit file", slog.Any("error", err))
+	}
+	
+	if s.lengthCache != nil {
+		s.lengthCache.close()
+	}
+	
+	if err := s.failover.close(); err != nil {
+		s.logger.Error("Error closing standby clients", slog.Any("error", err))
+	}
+	if err := s.mirror.close(); err != nil {
+		s.logger.Error("Error closing mirror client", slog.Any("error", err))
+	}
+	if err := s.shadow.close(); err != nil {
+		s.logger.Error("Error closing shadow client", slog.Any("error", err))
+	}
+	
+	// Close a registered backend that holds resources
+	if custom, ok := s.backend.(*customBackend); ok {
+		if err := custom.close(); err != nil {
+			s.logger.Error("Error closing backend", slog.String("backend", custom.name), slog.Any("error", err))
+		}
+	}
+	
+	// Close Redis client, unless it belongs to the application
+	if client := s.getClient(); client != nil && s.options.Client == nil {
+		if err := client.Close(); err != nil {
 			s.logger.Error("Error closing Redis client", slog.Any("error", err))
 			return err
 		}
 	}
 	
-	s.setConnectionState(false)
+	s.setConnectionState(ConnectionStateDisconnected, nil)
 	s.logger.Info("Valkey sender closed")
 	
 	return nil
 }
 
-// Health returns the health status of the sender
+// Health returns the health status of the sender. The status follows the
+// error rate over the health window, so past incidents age out.
 func (s *valkeySender) Health() HealthStatus {
-	connectionState := "disconnected"
-	if s.getConnectionState() {
-		connectionState = "connected"
-	}
-	
-	status := "healthy"
-	errorRate := float64(atomic.LoadInt64(&s.errorCount)) / float64(atomic.LoadInt64(&s.messagesSent)+1)
-	switch {
-	case errorRate > 0.5:
-		status = "unhealthy"
-	case errorRate > 0.1:
-		status = "degraded"
-	}
+	errorCount := atomic.LoadInt64(&s.errorCount)
+	messagesSent := atomic.LoadInt64(&s.messagesSent)
+	windowSent, windowErrors := s.outcomes.totals(time.Now())
+	windowRate := errorRate(windowSent, windowErrors)
+	s.lastMutex.Lock()
+	lastSuccess, lastError := s.lastSuccess, s.lastError
+	s.lastMutex.Unlock()
+	rttP50, rttP99 := s.rtt.percentiles()
 	
 	return HealthStatus{
-		Status:          status,
-		LastSuccess:     s.lastSuccess,
-		LastError:       s.lastError,
-		ErrorCount:      atomic.LoadInt64(&s.errorCount),
-		MessagesSent:    atomic.LoadInt64(&s.messagesSent),
+		Status:          healthStatus(windowRate),
+		LastSuccess:     lastSuccess,
+		LastError:       lastError,
+		ErrorCount:      errorCount,
+		MessagesSent:    messagesSent,
+		ErrorRate:          windowRate,
+		LifetimeErrorRate:  errorRate(messagesSent, errorCount),
+		HealthWindow:       s.outcomes.length(),
+		WindowMessagesSent: windowSent,
+		WindowErrorCount:   windowErrors,
+		MessagesExpired: atomic.LoadInt64(&s.messagesExpired),
 		Uptime:          time.Since(s.startTime),
-		ConnectionState: connectionState,
+		ConnectionState: s.getConnectionState(),
 		CircuitBreaker:  s.circuitBreaker.State().String(),
+		Latency:         time.Duration(atomic.LoadInt64(&s.pingLatency)),
+		LatencyP50:      rttP50,
+		LatencyP99:      rttP99,
+		LastHealthCheck: s.lastHealthCheck(),
+		ConnectionPool:  s.poolMetrics(),
 	}
 }
 
+// readyToTrip decides whether the circuit breaker should open
+func (s *valkeySender) readyToTrip(counts gobreaker.Counts) bool {
+	if s.options.ReadyToTrip != nil {
+		return s.options.ReadyToTrip(counts)
+	}
+	
+	threshold := s.config.BreakerConsecutiveFailures
+	if threshold == 0 {
+		threshold = defaultBreakerConsecutiveFailures
+	}
+	if counts.ConsecutiveFailures > threshold {
+		return true
+	}
+	
+	if s.config.BreakerFailureRatio > 0 && counts.Requests >= s.config.BreakerMinRequests {
+		ratio := float64(counts.TotalFailures) / float64(counts.Requests)
+		return ratio >= s.config.BreakerFailureRatio
+	}
+	
+	return false
+}
+
 // getQueueKey returns the Redis key for a queue
 func (s *valkeySender) getQueueKey(queue string) string {
-	if s.options.QueueNamer != nil {
-		return s.options.QueueNamer(queue)
-	}
-	return fmt.Sprintf("queue:%s", queue)
-}
\ No newline at end of file
+	return queueKey(s.config, s.options, queue)
+}
+
+// queueKey builds the list key for a queue from the namer or prefixes
+func queueKey(config *Config, options *SenderOptions, queue string) string {
+	return tenantQueueKey(config, options, "", queue)
+}
@@ -0,0 +1,413 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sony/gobreaker"
+	"golang.org/x/time/rate"
+)
+
+func newTestSender(t testing.TB, server *miniredis.Miniredis, options *SenderOptions) *valkeySender {
+	t.Helper()
+	
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	if options == nil {
+		options = &SenderOptions{}
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	
+	sender, err := NewSender(config, options)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender)
+}
+
+func TestReadyToTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		options  *SenderOptions
+		counts   gobreaker.Counts
+		expected bool
+	}{
+		{
+			name:     "default threshold not exceeded",
+			config:   &Config{},
+			options:  &SenderOptions{},
+			counts:   gobreaker.Counts{Requests: 3, TotalFailures: 3, ConsecutiveFailures: 3},
+			expected: false,
+		},
+		{
+			name:     "default threshold exceeded",
+			config:   &Config{},
+			options:  &SenderOptions{},
+			counts:   gobreaker.Counts{Requests: 4, TotalFailures: 4, ConsecutiveFailures: 4},
+			expected: true,
+		},
+		{
+			name:     "custom consecutive failures",
+			config:   &Config{BreakerConsecutiveFailures: 10},
+			options:  &SenderOptions{},
+			counts:   gobreaker.Counts{Requests: 8, TotalFailures: 8, ConsecutiveFailures: 8},
+			expected: false,
+		},
+		{
+			name:     "failure ratio below minimum requests",
+			config:   &Config{BreakerFailureRatio: 0.5, BreakerMinRequests: 10},
+			options:  &SenderOptions{},
+			counts:   gobreaker.Counts{Requests: 6, TotalFailures: 3, ConsecutiveFailures: 1},
+			expected: false,
+		},
+		{
+			name:     "failure ratio reached",
+			config:   &Config{BreakerFailureRatio: 0.5, BreakerMinRequests: 10},
+			options:  &SenderOptions{},
+			counts:   gobreaker.Counts{Requests: 20, TotalFailures: 10, ConsecutiveFailures: 1},
+			expected: true,
+		},
+		{
+			name:   "custom ReadyToTrip overrides config",
+			config: &Config{},
+			options: &SenderOptions{ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.TotalFailures > 0
+			}},
+			counts:   gobreaker.Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &valkeySender{config: tt.config, options: tt.options}
+			if got := s.readyToTrip(tt.counts); got != tt.expected {
+				t.Errorf("Expected readyToTrip=%t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAcquireRateLimit(t *testing.T) {
+	t.Run("reject mode fails fast", func(t *testing.T) {
+		s := &valkeySender{
+			config:      &Config{RateLimitMode: RateLimitModeReject},
+			rateLimiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+		}
+		
+		if err := s.acquireRateLimit(context.Background()); err != nil {
+			t.Fatalf("Expected first token to be granted, got %v", err)
+		}
+		if err := s.acquireRateLimit(context.Background()); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+		if s.rateLimitHits != 1 {
+			t.Errorf("Expected 1 rate limit hit, got %d", s.rateLimitHits)
+		}
+	})
+	
+	t.Run("wait mode honors context", func(t *testing.T) {
+		s := &valkeySender{
+			config:      &Config{RateLimitMode: RateLimitModeWait},
+			rateLimiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+		}
+		s.acquireRateLimit(context.Background())
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := s.acquireRateLimit(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context deadline error, got %v", err)
+		}
+	})
+}
+
+func TestSuccessMetadata(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			var metadata []MessageMetadata
+			s.options.SuccessHandler = func(m MessageMetadata) { metadata = append(metadata, m) }
+			
+			if err := s.SendBatch(ctx, "orders", []interface{}{"a", "bb", "ccc"}); err != nil {
+				t.Fatalf("SendBatch failed: %v", err)
+			}
+			if err := s.SendTyped(ctx, "orders", "order_created", "dddd"); err != nil {
+				t.Fatalf("SendTyped failed: %v", err)
+			}
+			
+			envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+			if len(metadata) != 4 || len(envelopes) != 4 {
+				t.Fatalf("Expected 4 messages and callbacks, got %d and %d", len(envelopes), len(metadata))
+			}
+			for i, m := range metadata {
+				if m.MessageID != envelopes[i].ID || m.Position != int64(i+1) || m.Size != i+1 {
+					t.Errorf("Message %d: expected ID %s at position %d with size %d, got %+v", i, envelopes[i].ID, i+1, i+1, m)
+				}
+			}
+			if metadata[3].Headers[HeaderMessageType] != "order_created" {
+				t.Errorf("Expected envelope headers in metadata, got %v", metadata[3].Headers)
+			}
+			
+			// Positions account for messages trimmed in drop-oldest mode
+			metadata = nil
+			s.config.MaxQueueLength = 4
+			s.config.OverflowPolicy = OverflowPolicyDropOldest
+			if err := s.SendBatch(ctx, "orders", []interface{}{"e", "f"}); err != nil {
+				t.Fatalf("SendBatch failed: %v", err)
+			}
+			if len(metadata) != 2 || metadata[0].Position != 3 || metadata[1].Position != 4 {
+				t.Errorf("Expected positions 3 and 4 after trimming, got %+v", metadata)
+			}
+		})
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.SendTimeout = 50 * time.Millisecond
+	
+	// Waiting for a rate limit token
+	s.rateLimiter = rate.NewLimiter(rate.Every(time.Minute), 1)
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	start := time.Now()
+	if err := s.SendMessage(ctx, "orders", "b"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout behind the rate limiter, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the send timeout to bound the wait, took %v", elapsed)
+	}
+	
+	// Blocked on a full queue
+	s.rateLimiter = rate.NewLimiter(rate.Inf, 1)
+	s.config.MaxQueueLength = 1
+	s.config.OverflowPolicy = OverflowPolicyBlock
+	s.config.OverflowBlockTimeout = time.Minute
+	if err := s.SendMessage(ctx, "orders", "c"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout while blocked on a full queue, got %v", err)
+	}
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.MaxMessageBytes = 1024
+	
+	large := strings.Repeat("x", 2000)
+	err := s.SendMessage(ctx, "orders", large)
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Expected ErrMessageTooLarge, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "over the limit of 1024") {
+		t.Errorf("Expected the size and limit in the error, got %v", err)
+	}
+	if err := s.SendBatch(ctx, "orders", []interface{}{"small", large}); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected a batch with a large message to be rejected, got %v", err)
+	}
+	if err := s.SendRaw(ctx, "orders", []byte(large)); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected a large raw message to be rejected, got %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "small"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected only the small message to be sent, got %d", size)
+	}
+	if rejected := s.Metrics().MessagesTooLarge; rejected != 3 {
+		t.Errorf("Expected 3 rejections counted, got %d", rejected)
+	}
+	if s.circuitBreaker.Counts().TotalFailures != 0 {
+		t.Error("Expected rejections not to reach the circuit breaker")
+	}
+}
+
+func TestSendToQueues(t *testing.T) {
+	for _, capped := range []bool{false, true} {
+		server := miniredis.RunT(t)
+		ctx := context.Background()
+		s := newTestSender(t, server, nil)
+		if capped {
+			s.config.MaxQueueLength = 1
+		}
+		
+		queues := []string{"billing", "analytics", "audit"}
+		if err := s.SendToQueues(ctx, queues, "event"); err != nil {
+			t.Fatalf("capped=%t: SendToQueues failed: %v", capped, err)
+		}
+		
+		var ids []string
+		for _, queue := range queues {
+			envelopes, _ := s.PeekMessages(ctx, queue, 0, 10)
+			if len(envelopes) != 1 || envelopes[0].Queue != queue {
+				t.Fatalf("capped=%t: expected one copy in %s, got %+v", capped, queue, envelopes)
+			}
+			ids = append(ids, envelopes[0].ID)
+		}
+		if ids[0] != ids[1] || ids[1] != ids[2] {
+			t.Errorf("capped=%t: expected a shared envelope ID, got %v", capped, ids)
+		}
+		
+		if err := s.SendToQueues(ctx, []string{"a", "a"}, "event"); err == nil {
+			t.Error("Expected duplicate queues to be rejected")
+		}
+	}
+}
+
+func TestSendToQueuesAllOrNothing(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.MaxQueueLength = 1
+	
+	s.SendMessage(ctx, "audit", "existing")
+	
+	err := s.SendToQueues(ctx, []string{"billing", "audit"}, "event")
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	var typed *Error
+	if errors.As(err, &typed) && typed.Queue != "audit" {
+		t.Errorf("Expected the full queue to be reported, got %q", typed.Queue)
+	}
+	if size, _ := s.GetQueueSize(ctx, "billing"); size != 0 {
+		t.Errorf("Expected no partial delivery, billing has %d messages", size)
+	}
+}
+
+func TestSendMulti(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.RateLimitMode = RateLimitModeReject
+	s.rateLimiter = rate.NewLimiter(rate.Every(time.Hour), 2)
+	s.queueNames = &queueNameRules{allowed: []string{"billing", "orders"}}
+	
+	// The invalid queue sorts last, after the others were prepared
+	err := s.SendMulti(ctx, map[string][]interface{}{
+		"billing": {"b1"},
+		"orders":  {"o1"},
+		"typo":    {"t1"},
+	})
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Kind != ErrInvalidQueue || typed.Queue != "typo" || typed.Op != opSendMulti {
+		t.Fatalf("Expected ErrInvalidQueue for typo, got %v", err)
+	}
+	if server.Exists(s.getQueueKey("billing")) || server.Exists(s.getQueueKey("orders")) {
+		t.Fatal("Expected nothing pushed for a rejected call")
+	}
+	
+	messages := map[string][]interface{}{
+		"billing": {"b1", "b2"},
+		"orders":  {"o1", "o2", "o3"},
+	}
+	if err := s.SendMulti(ctx, messages); err != nil {
+		t.Fatalf("SendMulti failed: %v", err)
+	}
+	for queue, want := range messages {
+		list, err := server.List(s.getQueueKey(queue))
+		if err != nil || len(list) != len(want) {
+			t.Fatalf("Expected %d messages in %s, got %v (%v)", len(want), queue, list, err)
+		}
+		slices.Reverse(list) // pushed at the head
+		for i, element := range list {
+			var envelope MessageEnvelope
+			if err := json.Unmarshal([]byte(element), &envelope); err != nil {
+				t.Fatalf("Invalid envelope %q: %v", element, err)
+			}
+			if envelope.Queue != queue || string(envelope.Payload) != want[i] {
+				t.Errorf("Expected %s at %s[%d], got %+v", want[i], queue, i, envelope)
+			}
+		}
+	}
+	
+	// The call took one token of the two, the rejected one none
+	if err := s.SendMessage(ctx, "orders", "o4"); err != nil {
+		t.Errorf("Expected a token left after SendMulti, got %v", err)
+	}
+	if err := s.SendMessage(ctx, "orders", "o5"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected the tokens to be used up, got %v", err)
+	}
+}
+
+func TestSendRaw(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.MaxMessageBytes = 64
+	s.queueNames = &queueNameRules{allowed: []string{"orders"}}
+	
+	raw := []byte(`{"legacy":true,"id":"o1"}`)
+	if err := s.SendRaw(ctx, "orders", raw); err != nil {
+		t.Fatalf("SendRaw failed: %v", err)
+	}
+	list, _ := server.List(s.getQueueKey("orders"))
+	if len(list) != 1 || list[0] != string(raw) {
+		t.Errorf("Expected the raw bytes without an envelope, got %q", list)
+	}
+	
+	if err := s.SendRaw(ctx, "orders", bytes.Repeat([]byte("x"), 65)); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+	if err := s.SendRaw(ctx, "typo", raw); !errors.Is(err, ErrInvalidQueue) {
+		t.Errorf("Expected ErrInvalidQueue, got %v", err)
+	}
+	if err := s.SendRaw(ctx, "orders", nil); err == nil {
+		t.Error("Expected empty data to be rejected")
+	}
+	if server.Exists(s.getQueueKey("typo")) {
+		t.Error("Expected nothing pushed to the invalid queue")
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected only the first message in orders, got %d", size)
+	}
+}
+
+func TestRetryDelayDeprecated(t *testing.T) {
+	server := miniredis.RunT(t)
+	if delay := DefaultConfig().RetryDelay; delay != 0 {
+		t.Fatalf("Expected no retry delay by default, got %v", delay)
+	}
+	
+	for _, delay := range []time.Duration{0, time.Second} {
+		var logs bytes.Buffer
+		config := DefaultConfig()
+		config.Address = server.Addr()
+		config.HealthCheckInterval = 0
+		config.RetryDelay = delay
+		sender, err := NewSender(config, &SenderOptions{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+		if err != nil {
+			t.Fatalf("Failed to create sender: %v", err)
+		}
+		sender.Close()
+		
+		if warned := strings.Contains(logs.String(), "RetryDelay is deprecated"); warned != (delay != 0) {
+			t.Errorf("RetryDelay %v: expected a warning only when set, got %s", delay, logs.String())
+		}
+	}
+	
+	config := DefaultConfig()
+	config.RetryDelay = -time.Second
+	if err := config.validate(); err == nil {
+		t.Error("Expected a negative retry delay to be invalid")
+	}
+}
@@ -30,7 +30,7 @@ func (s *JSONSerializer) Serialize(message interface{}) ([]byte, error) {
 	}
 	
 	// Serialize other types as JSON
-	data, err := json.Marshal(message)
+	data, err := marshalJSON(message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize message to JSON: %w", err)
 	}
@@ -74,17 +74,71 @@ func (s *JSONSerializer) ContentType() string {
 	return "application/json"
 }
 
+// CompressedSerializer compresses the payloads of another serializer with
+// a frame compressor (see RegisterFrameCompressor). The compression is
+// recorded in the content-encoding header, the content type stays that of
+// the wrapped serializer.
+type CompressedSerializer struct {
+	serializer MessageSerializer
+	compressor FrameCompressor
+}
+
+// NewCompressedSerializer wraps serializer, compressing its payloads with
+// the named compression, e.g. FrameCompressionGzip
+func NewCompressedSerializer(serializer MessageSerializer, compression string) (*CompressedSerializer, error) {
+	compressor, err := frameCompressor(compression)
+	if err != nil {
+		return nil, err
+	}
+	return &CompressedSerializer{serializer: serializer, compressor: compressor}, nil
+}
+
+// Serialize serializes and compresses a message
+func (s *CompressedSerializer) Serialize(message interface{}) ([]byte, error) {
+	data, err := s.serializer.Serialize(message)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := s.compressor.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	return compressed, nil
+}
+
+// Deserialize decompresses and deserializes a payload
+func (s *CompressedSerializer) Deserialize(data []byte, target interface{}) error {
+	decompressed, err := s.compressor.Decompress(data)
+	if err != nil {
+		return fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	return s.serializer.Deserialize(decompressed, target)
+}
+
+// ContentType returns the content type of the wrapped serializer
+func (s *CompressedSerializer) ContentType() string {
+	return s.serializer.ContentType()
+}
+
+// ContentEncoding returns the name of the compression
+func (s *CompressedSerializer) ContentEncoding() string {
+	return s.compressor.Name()
+}
+
+// Headers returns the headers of the wrapped serializer, if it records any
+func (s *CompressedSerializer) Headers(message interface{}) map[string]string {
+	if hs, ok := s.serializer.(HeaderSerializer); ok {
+		return hs.Headers(message)
+	}
+	return nil
+}
 
-// SerializeMessageEnvelope serializes a message envelope
+// SerializeMessageEnvelope serializes a message envelope using the default codec
 func SerializeMessageEnvelope(envelope MessageEnvelope) ([]byte, error) {
-	serializer := NewJSONSerializer()
-	return serializer.Serialize(envelope)
+	return NewJSONEnvelopeCodec().Encode(envelope)
 }
 
-// DeserializeMessageEnvelope deserializes a message envelope
+// DeserializeMessageEnvelope deserializes a message envelope using the default codec
 func DeserializeMessageEnvelope(data []byte) (MessageEnvelope, error) {
-	var envelope MessageEnvelope
-	serializer := NewJSONSerializer()
-	err := serializer.Deserialize(data, &envelope)
-	return envelope, err
+	return NewJSONEnvelopeCodec().Decode(data)
 }
\ No newline at end of file
@@ -1,9 +1,12 @@
 package valkeysender
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestJSONSerializer(t *testing.T) {
@@ -172,4 +175,154 @@ func TestSerializerErrorCases(t *testing.T) {
 			t.Error("Expected error for invalid JSON")
 		}
 	})
-}
\ No newline at end of file
+}
+func TestProtoSerializer(t *testing.T) {
+	serializer := NewProtoSerializer()
+	
+	if serializer.ContentType() != "application/x-protobuf" {
+		t.Errorf("Expected content type application/x-protobuf, got %s", serializer.ContentType())
+	}
+	
+	message := wrapperspb.String("hello proto")
+	data, err := serializer.Serialize(message)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	
+	headers := serializer.Headers(message)
+	if headers[HeaderProtoMessage] != "google.protobuf.StringValue" {
+		t.Errorf("Expected message name google.protobuf.StringValue, got %s", headers[HeaderProtoMessage])
+	}
+	
+	t.Run("deserialize to target", func(t *testing.T) {
+		var result wrapperspb.StringValue
+		if err := serializer.Deserialize(data, &result); err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		if result.GetValue() != "hello proto" {
+			t.Errorf("Expected 'hello proto', got %s", result.GetValue())
+		}
+	})
+	
+	t.Run("deserialize from envelope headers", func(t *testing.T) {
+		envelope := MessageEnvelope{ID: "proto-1", Payload: data, Headers: headers}
+		result, err := serializer.DeserializeEnvelope(envelope)
+		if err != nil {
+			t.Fatalf("DeserializeEnvelope failed: %v", err)
+		}
+		if value, ok := result.(*wrapperspb.StringValue); !ok || value.GetValue() != "hello proto" {
+			t.Errorf("Expected StringValue 'hello proto', got %v", result)
+		}
+	})
+	
+	t.Run("reject non-proto message", func(t *testing.T) {
+		if _, err := serializer.Serialize("plain string"); err == nil {
+			t.Error("Expected error for non-proto message")
+		}
+	})
+}
+
+func TestEnvelopeVersioning(t *testing.T) {
+	codec := NewJSONEnvelopeCodec()
+	
+	t.Run("encode stamps current version", func(t *testing.T) {
+		data, err := codec.Encode(MessageEnvelope{ID: "v1", Payload: []byte("x")})
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		result, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if result.Version != EnvelopeVersion {
+			t.Errorf("Expected version %d, got %d", EnvelopeVersion, result.Version)
+		}
+	})
+	
+	t.Run("legacy envelope without version", func(t *testing.T) {
+		result, err := codec.Decode([]byte(`{"id":"legacy","queue":"q","payload":"eA=="}`))
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if result.Version != 1 {
+			t.Errorf("Expected legacy envelope to decode as version 1, got %d", result.Version)
+		}
+	})
+	
+	t.Run("reject future version", func(t *testing.T) {
+		if _, err := codec.Decode([]byte(`{"version":99,"id":"future"}`)); err == nil {
+			t.Error("Expected error for unsupported envelope version")
+		}
+	})
+}
+
+func TestCloudEventsCodec(t *testing.T) {
+	codec := NewCloudEventsCodec("/services/registration", "com.example.user.registered")
+	
+	envelope := MessageEnvelope{
+		ID:        "ce-123",
+		Queue:     "registrations",
+		Payload:   []byte(`{"user_id":42}`),
+		Headers:   map[string]string{"trace-id": "abc"},
+		Timestamp: time.Date(2025, 5, 28, 12, 0, 0, 0, time.UTC),
+		TTL:       time.Hour,
+	}
+	
+	data, err := codec.Encode(envelope)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	
+	var event map[string]interface{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Encoded event is not JSON: %v", err)
+	}
+	for key, expected := range map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "ce-123",
+		"source":          "/services/registration",
+		"type":            "com.example.user.registered",
+		"time":            "2025-05-28T12:00:00Z",
+		"datacontenttype": "application/json",
+		"traceid":         "abc",
+	} {
+		if event[key] != expected {
+			t.Errorf("Expected %s=%v, got %v", key, expected, event[key])
+		}
+	}
+	if data, ok := event["data"].(map[string]interface{}); !ok || data["user_id"] != float64(42) {
+		t.Errorf("Expected embedded JSON data, got %v", event["data"])
+	}
+	
+	result, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if result.ID != envelope.ID || result.Queue != envelope.Queue || result.TTL != envelope.TTL {
+		t.Errorf("Round trip mismatch: %+v", result)
+	}
+	if string(result.Payload) != string(envelope.Payload) {
+		t.Errorf("Expected payload %s, got %s", envelope.Payload, result.Payload)
+	}
+	if result.Headers["traceid"] != "abc" || result.Headers["ce-type"] != "com.example.user.registered" {
+		t.Errorf("Unexpected headers: %v", result.Headers)
+	}
+	
+	t.Run("binary payload uses data_base64", func(t *testing.T) {
+		binary := envelope
+		binary.Payload = []byte{0xff, 0x00, 0x01}
+		binary.Headers = map[string]string{"content-type": "application/x-protobuf"}
+		
+		data, err := codec.Encode(binary)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		result, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if !reflect.DeepEqual(result.Payload, binary.Payload) {
+			t.Errorf("Expected payload %v, got %v", binary.Payload, result.Payload)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultShadowKeyPrefix is prepended to the key of a queue to form the key
+// of its shadow queue when Config.ShadowKeyPrefix is empty
+const DefaultShadowKeyPrefix = "shadow:"
+
+// shadow copies a sample of the sent messages into shadow queues, where a
+// new consumer version can be tried on real traffic. A nil shadow copies
+// nothing.
+type shadow struct {
+	client    func() redis.UniversalClient
+	own       *redis.Client // client of Config.ShadowURL, nil on the primary
+	ratio     float64
+	keyPrefix string
+	maxLen    int64
+	timeout   time.Duration
+	logger    *slog.Logger
+
+	shadowed int64 // messages copied
+}
+
+// newShadow returns the shadow of the configuration, or nil if
+// Config.ShadowQueueRatio is not set
+func (s *valkeySender) newShadow() (*shadow, error) {
+	config := s.config
+	if config.ShadowQueueRatio == 0 {
+		return nil, nil
+	}
+
+	shadow := &shadow{
+		client:    s.getClient,
+		ratio:     config.ShadowQueueRatio,
+		keyPrefix: config.ShadowKeyPrefix,
+		maxLen:    int64(config.ShadowMaxLen),
+		timeout:   config.WriteTimeout,
+		logger:    s.logger,
+	}
+	if shadow.keyPrefix == "" {
+		shadow.keyPrefix = DefaultShadowKeyPrefix
+	}
+
+	if config.ShadowURL != "" {
+		shadowConfig := *config
+		if err := shadowConfig.ApplyURL(config.ShadowURL); err != nil {
+			return nil, fmt.Errorf("invalid shadow URL: %w", err)
+		}
+		client, err := newRedisClient(&shadowConfig, s.options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shadow client: %w", err)
+		}
+		shadow.own = client
+		shadow.client = func() redis.UniversalClient { return client }
+	} else if s.getClient() == nil {
+		return nil, fmt.Errorf("shadow traffic requires the %q backend or a shadow URL", BackendList)
+	}
+	return shadow, nil
+}
+
+// tee pushes a random sample of the messages of a successful send to the
+// shadow queues in one round trip, trimming each to its max length.
+// Messages skipped as duplicates are not copied. Failures are logged but
+// never fail the send.
+func (sh *shadow) tee(batches []*queueBatch, sendErr error) {
+	if sh == nil || sendErr != nil {
+		return
+	}
+
+	// The send's context may be done already; the copy has its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), sh.timeout)
+	defer cancel()
+
+	var pipe redis.Pipeliner
+	var count int64
+	for _, batch := range batches {
+		var sampled []interface{}
+		for i, data := range batch.data {
+			if i < len(batch.positions) && batch.positions[i] == 0 {
+				continue
+			}
+			if rand.Float64() < sh.ratio {
+				sampled = append(sampled, data)
+			}
+		}
+		if len(sampled) == 0 {
+			continue
+		}
+		if pipe == nil {
+			pipe = sh.client().Pipeline()
+		}
+		key := sh.keyPrefix + batch.key
+		pipe.LPush(ctx, key, sampled...)
+		if sh.maxLen > 0 {
+			pipe.LTrim(ctx, key, 0, sh.maxLen-1)
+		}
+		count += int64(len(sampled))
+	}
+	if pipe == nil {
+		return
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		sh.logger.Error("Failed to copy messages to shadow queues", slog.Any("error", err))
+		return
+	}
+	atomic.AddInt64(&sh.shadowed, count)
+}
+
+// count returns the number of messages copied so far
+func (sh *shadow) count() int64 {
+	if sh == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&sh.shadowed)
+}
+
+// close closes the client of the shadow cluster, if it has its own
+func (sh *shadow) close() error {
+	if sh == nil || sh.own == nil {
+		return nil
+	}
+	return sh.own.Close()
+}
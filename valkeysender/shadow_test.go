@@ -0,0 +1,93 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestShadowTraffic(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithShadowTraffic(1, "", ""),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	s := sender.(*valkeySender)
+	s.shadow.maxLen = 2
+
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b", "c"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+
+	key := s.getQueueKey("orders")
+	if values, _ := server.List(key); len(values) != 3 {
+		t.Errorf("Expected the messages on the queue, got %v", values)
+	}
+	shadowed, _ := server.List(DefaultShadowKeyPrefix + key)
+	if len(shadowed) != 2 || string(s.decodeElement("orders", shadowed[0]).Payload) != "c" {
+		t.Errorf("Expected the newest 2 messages on the shadow queue, got %v", shadowed)
+	}
+	if count := s.Metrics().ShadowedMessages; count != 3 {
+		t.Errorf("Expected 3 shadowed messages, got %d", count)
+	}
+}
+
+func TestShadowTrafficSampling(t *testing.T) {
+	primary, shadowServer := miniredis.RunT(t), miniredis.RunT(t)
+	ctx := context.Background()
+
+	sender, err := NewSenderWithOptions(primary.Addr(),
+		WithShadowTraffic(0.5, "canary:", "redis://"+shadowServer.Addr()),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	s := sender.(*valkeySender)
+
+	messages := make([]interface{}, 200)
+	for i := range messages {
+		messages[i] = i
+	}
+	if err := s.SendBatch(ctx, "orders", messages); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+
+	key := "canary:" + s.getQueueKey("orders")
+	shadowed, _ := shadowServer.List(key)
+	if len(shadowed) == 0 || len(shadowed) == len(messages) {
+		t.Errorf("Expected a sample of the messages on the shadow cluster, got %d", len(shadowed))
+	}
+	if primary.Exists(key) {
+		t.Error("Expected no shadow queue on the primary")
+	}
+}
+
+func TestShadowConfigValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.ShadowQueueRatio = 1.5
+	if err := config.validate(); err == nil {
+		t.Error("Expected a ratio above 1 to be rejected")
+	}
+
+	config.ShadowQueueRatio = 0.1
+	config.Backend = BackendMemory
+	if err := config.validate(); err == nil {
+		t.Error("Expected shadow traffic on the memory backend to need a shadow URL")
+	}
+
+	config.ShadowURL = "redis://valkey-canary:6379"
+	if err := config.validate(); err != nil {
+		t.Errorf("Expected a shadow URL to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package valkeysender
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHTunnel reaches Valkey through an SSH jump host, as "ssh -J" would
+type SSHTunnel struct {
+	Host    string // jump host, as host or host:port (empty disables the tunnel)
+	User    string // login user (default from the ssh configuration)
+	KeyFile string // private key (default from ssh-agent and the ssh configuration)
+}
+
+// sshCommand is the ssh client run for every tunnelled connection
+var sshCommand = []string{"ssh"}
+
+// sshExitWait bounds how long a read that hit the end of the tunnel waits
+// for ssh to exit and explain why
+const sshExitWait = time.Second
+
+// newSSHDialer returns a dialer running "ssh -W" through the jump host for
+// every connection, then starting TLS with the server if tlsConfig is set.
+// Using the system ssh client keeps host key checking, ssh-agent and
+// ~/.ssh/config working as they do for the user.
+func newSSHDialer(tunnel SSHTunnel, tlsConfig *tls.Config) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		args := slices.Clone(sshCommand[1:])
+		// Fail instead of prompting, and if the jump host can't reach addr
+		args = append(args, "-o", "BatchMode=yes", "-o", "ExitOnForwardFailure=yes", "-o", "ServerAliveInterval=30")
+		host := tunnel.Host
+		if h, port, err := net.SplitHostPort(tunnel.Host); err == nil {
+			host = h
+			args = append(args, "-p", port)
+		}
+		if tunnel.User != "" {
+			args = append(args, "-l", tunnel.User)
+		}
+		if tunnel.KeyFile != "" {
+			args = append(args, "-i", tunnel.KeyFile, "-o", "IdentitiesOnly=yes")
+		}
+		args = append(args, "-W", addr, "--", host)
+
+		conn, err := startSSHConn(exec.Command(sshCommand[0], args...), addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start SSH tunnel: %w", err)
+		}
+		return startTLS(ctx, conn, addr, tlsConfig)
+	}
+}
+
+// sshConn is a connection carried over the standard streams of an ssh process
+type sshConn struct {
+	cmd    *exec.Cmd
+	reader *os.File // stdout of ssh
+	writer *os.File // stdin of ssh
+	stderr *syncBuffer
+	addr   string
+	exited chan struct{} // closed once ssh exited and stderr was read
+
+	closeOnce sync.Once
+}
+
+// startSSHConn starts cmd with pipes that, unlike those of exec.Cmd,
+// support the deadlines go-redis sets
+func startSSHConn(cmd *exec.Cmd, addr string) (*sshConn, error) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdinWriter.Close()
+		return nil, err
+	}
+
+	conn := &sshConn{cmd: cmd, reader: stdoutReader, writer: stdinWriter, stderr: &syncBuffer{}, addr: addr, exited: make(chan struct{})}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdinReader, stdoutWriter, conn.stderr
+	err = cmd.Start()
+
+	// The child has its own copies now
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdoutReader.Close()
+		stdinWriter.Close()
+		return nil, err
+	}
+	go func() {
+		cmd.Wait()
+		close(conn.exited)
+	}()
+	return conn, nil
+}
+
+// Read reads from ssh, reporting why it exited once it did
+func (c *sshConn) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		// ssh exits right after its output ends; wait for its last words
+		select {
+		case <-c.exited:
+		case <-time.After(sshExitWait):
+		}
+		if message := strings.TrimSpace(c.stderr.String()); message != "" {
+			err = fmt.Errorf("SSH tunnel closed: %s", message)
+		}
+	}
+	return n, err
+}
+
+func (c *sshConn) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+// Close closes the streams and stops ssh
+func (c *sshConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.writer.Close()
+		c.reader.Close()
+		c.cmd.Process.Kill()
+		<-c.exited
+	})
+	return nil
+}
+
+func (c *sshConn) LocalAddr() net.Addr  { return sshAddr("ssh") }
+func (c *sshConn) RemoteAddr() net.Addr { return sshAddr(c.addr) }
+
+func (c *sshConn) SetDeadline(t time.Time) error {
+	if err := c.reader.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.writer.SetWriteDeadline(t)
+}
+
+func (c *sshConn) SetReadDeadline(t time.Time) error  { return c.reader.SetReadDeadline(t) }
+func (c *sshConn) SetWriteDeadline(t time.Time) error { return c.writer.SetWriteDeadline(t) }
+
+// sshAddr is the address of a tunnelled connection
+type sshAddr string
+
+func (a sshAddr) Network() string { return "ssh" }
+func (a sshAddr) String() string  { return string(a) }
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
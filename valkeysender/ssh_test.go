@@ -0,0 +1,121 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// TestSSHHelperProcess stands in for the ssh client: it records its
+// arguments and forwards its standard streams to the -W address
+func TestSSHHelperProcess(t *testing.T) {
+	if os.Getenv("VALKEYSENDER_SSH_HELPER") == "" {
+		return
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	// The pool dials several connections at once, so replace the file atomically
+	argsFile := os.Getenv("VALKEYSENDER_SSH_HELPER")
+	tmp := fmt.Sprintf("%s.%d", argsFile, os.Getpid())
+	os.WriteFile(tmp, []byte(strings.Join(args, " ")), 0o644)
+	os.Rename(tmp, argsFile)
+	
+	if args[len(args)-1] == "denied.example.com" {
+		fmt.Fprintln(os.Stderr, "bastion@denied.example.com: Permission denied (publickey).")
+		os.Exit(255)
+	}
+	var addr string
+	for i, arg := range args {
+		if arg == "-W" {
+			addr = args[i+1]
+		}
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "channel 0: open failed: %v\n", err)
+		os.Exit(255)
+	}
+	go io.Copy(conn, os.Stdin)
+	io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+func TestSSHTunnel(t *testing.T) {
+	server := miniredis.RunT(t)
+	argsFile := t.TempDir() + "/args"
+	t.Setenv("VALKEYSENDER_SSH_HELPER", argsFile)
+	previous := sshCommand
+	sshCommand = []string{os.Args[0], "-test.run=^TestSSHHelperProcess$", "--"}
+	t.Cleanup(func() { sshCommand = previous })
+	
+	config := DefaultConfig()
+	config.Address = server.Addr()
+	config.HealthCheckInterval = 0
+	config.SSHTunnel = SSHTunnel{Host: "bastion.example.com:2222", User: "deploy", KeyFile: "/keys/id_ed25519"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sender, err := NewSender(config, &SenderOptions{Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to connect through SSH tunnel: %v", err)
+	}
+	defer sender.Close()
+	
+	if err := sender.SendMessage(context.Background(), "orders", 1); err != nil {
+		t.Fatalf("Failed to send through SSH tunnel: %v", err)
+	}
+	if stored, _ := server.List("queue:orders"); len(stored) != 1 {
+		t.Errorf("Expected message to arrive, got %d", len(stored))
+	}
+	
+	args, _ := os.ReadFile(argsFile)
+	expected := "-p 2222 -l deploy -i /keys/id_ed25519 -o IdentitiesOnly=yes -W " + server.Addr() + " -- bastion.example.com"
+	if !strings.Contains(string(args), "-o BatchMode=yes") || !strings.HasSuffix(string(args), expected) {
+		t.Errorf("Unexpected ssh arguments %q", args)
+	}
+	
+	// The reason ssh gave up is reported
+	config.SSHTunnel = SSHTunnel{Host: "denied.example.com"}
+	opts, _ := newRedisOptions(config, nil)
+	conn, err := opts.Dialer(context.Background(), "tcp", config.Address)
+	if err != nil {
+		t.Fatalf("Failed to start ssh: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Read(make([]byte, 1)); err == nil || !strings.Contains(err.Error(), "Permission denied (publickey)") {
+		t.Errorf("Expected the ssh error, got %v", err)
+	}
+}
+
+func TestSSHTunnelValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.SSHTunnel = SSHTunnel{User: "deploy"}
+	if err := config.validate(); err == nil {
+		t.Error("Expected a user without host to be rejected")
+	}
+	config.SSHTunnel = SSHTunnel{Host: "bastion"}
+	config.ProxyURL = "socks5://proxy:1080"
+	if err := config.validate(); err == nil {
+		t.Error("Expected an SSH tunnel and a proxy together to be rejected")
+	}
+	
+	t.Setenv("VALKEY_SENDER_SSH_HOST", "bastion.example.com")
+	t.Setenv("VALKEY_SENDER_SSH_USER", "deploy")
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.SSHTunnel.Host != "bastion.example.com" || loaded.SSHTunnel.User != "deploy" {
+		t.Errorf("Unexpected SSH tunnel %+v", loaded.SSHTunnel)
+	}
+}
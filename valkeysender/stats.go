@@ -0,0 +1,154 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateWindow is the window over which per-queue send rates are computed
+const rateWindow = 60
+
+// rateCounter counts events in one-second buckets over a rolling window
+type rateCounter struct {
+	mu      sync.Mutex
+	start   time.Time
+	counts  [rateWindow]int64
+	seconds [rateWindow]int64 // unix second each bucket belongs to
+	evicted bool              // dropped from the queue rates, see evictIdle
+}
+
+// newRateCounter creates an empty rate counter
+func newRateCounter(now time.Time) *rateCounter {
+	return &rateCounter{start: now}
+}
+
+// add records n events at the given time. It returns false, recording
+// nothing, once the counter was evicted.
+func (r *rateCounter) add(n int64, now time.Time) bool {
+	second := now.Unix()
+	i := second % rateWindow
+	
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.evicted {
+		return false
+	}
+	if r.seconds[i] != second {
+		r.seconds[i] = second
+		r.counts[i] = 0
+	}
+	r.counts[i] += n
+	return true
+}
+
+// total returns the number of events within the window
+func (r *rateCounter) total(now time.Time) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totalLocked(now)
+}
+
+// totalLocked is total for callers holding the lock
+func (r *rateCounter) totalLocked(now time.Time) int64 {
+	oldest := now.Unix() - rateWindow + 1
+	var total int64
+	for i, second := range r.seconds {
+		if second >= oldest {
+			total += r.counts[i]
+		}
+	}
+	return total
+}
+
+// rate returns events per second over the window, or over the counter's
+// lifetime if it is younger than the window
+func (r *rateCounter) rate(now time.Time) float64 {
+	total := r.total(now)
+	
+	elapsed := now.Sub(r.start).Seconds()
+	switch {
+	case elapsed > rateWindow:
+		elapsed = rateWindow
+	case elapsed < 1:
+		elapsed = 1
+	}
+	return float64(total) / elapsed
+}
+
+// evictIdle marks the counter evicted if it counted nothing within the
+// window, after which add refuses events
+func (r *rateCounter) evictIdle(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.totalLocked(now) == 0 {
+		r.evicted = true
+	}
+	return r.evicted
+}
+
+// recordQueueRate adds sent messages to the queue's rolling rate
+func (s *valkeySender) recordQueueRate(queue string, count int) {
+	now := time.Now()
+	for {
+		counter, _ := s.queueRates.LoadOrStore(queue, newRateCounter(now))
+		if counter.(*rateCounter).add(int64(count), now) {
+			break
+		}
+		// Evicted since it was loaded, the next LoadOrStore stores a new one
+	}
+	s.evictIdleRates(now)
+}
+
+// evictIdleRates drops the rates of queues nothing was sent to within the
+// window, at most once per window, so a sender writing to ever new queues
+// doesn't keep a counter for each of them. An evicted queue reports no rate,
+// as it would have.
+func (s *valkeySender) evictIdleRates(now time.Time) {
+	last := atomic.LoadInt64(&s.ratesEvictedAt)
+	if now.Unix()-last < rateWindow || !atomic.CompareAndSwapInt64(&s.ratesEvictedAt, last, now.Unix()) {
+		return
+	}
+	
+	s.queueRates.Range(func(queue, counter any) bool {
+		if counter.(*rateCounter).evictIdle(now) {
+			s.queueRates.CompareAndDelete(queue, counter)
+		}
+		return true
+	})
+}
+
+// GetQueueStats returns the length, memory usage and last activity of a queue
+// together with the rate at which this sender has been writing to it and
+// the counters kept by the enqueue path
+func (s *valkeySender) GetQueueStats(ctx context.Context, queue string) (*QueueStats, error) {
+	listKey := s.getQueueKey(queue)
+	
+	usage, err := s.backend.usage(ctx, listKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats for %s: %w", queue, err)
+	}
+	
+	stats := &QueueStats{
+		Name:        queue,
+		Length:      usage.length,
+		MemoryUsage: usage.memory,
+		Enqueued:    usage.enqueued,
+		Duplicates:  usage.duplicates,
+		Dropped:     usage.dropped,
+	}
+	if stats.Length > 0 {
+		stats.AvgMessageSize = float64(stats.MemoryUsage) / float64(stats.Length)
+		if usage.idleKnown {
+			stats.LastActivity = time.Now().Add(-usage.idle).Truncate(time.Second)
+		}
+	}
+	
+	if counter, ok := s.queueRates.Load(queue); ok {
+		stats.MessagesPerSec = counter.(*rateCounter).rate(time.Now())
+	}
+	
+	return stats, nil
+}
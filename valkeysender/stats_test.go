@@ -0,0 +1,94 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRateCounter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	counter := newRateCounter(start)
+	
+	counter.add(10, start)
+	counter.add(10, start.Add(time.Second))
+	if got := counter.rate(start.Add(2 * time.Second)); got != 10 {
+		t.Errorf("Expected 10 msg/s over the first 2s, got %f", got)
+	}
+	
+	// Events older than the window no longer count
+	counter.add(60, start.Add(90*time.Second))
+	if got := counter.rate(start.Add(90 * time.Second)); got != 1 {
+		t.Errorf("Expected 1 msg/s over the full window, got %f", got)
+	}
+}
+
+func TestGetQueueStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	stats, err := s.GetQueueStats(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetQueueStats failed for missing queue: %v", err)
+	}
+	if stats.Length != 0 || stats.MemoryUsage != 0 || !stats.LastActivity.IsZero() {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b", "c"}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	
+	stats, err = s.GetQueueStats(ctx, "orders")
+	if err != nil {
+		t.Fatalf("GetQueueStats failed: %v", err)
+	}
+	if stats.Name != "orders" || stats.Length != 3 {
+		t.Errorf("Expected 3 messages in orders, got %+v", stats)
+	}
+	if stats.MemoryUsage <= 0 || stats.AvgMessageSize <= 0 {
+		t.Errorf("Expected memory usage to be reported, got %+v", stats)
+	}
+	if stats.MessagesPerSec <= 0 {
+		t.Errorf("Expected a send rate, got %f", stats.MessagesPerSec)
+	}
+}
+
+func TestQueueRatesEvictIdle(t *testing.T) {
+	s := newTestSender(t, miniredis.RunT(t), nil)
+	
+	s.recordQueueRate("orders", 1)
+	s.recordQueueRate("payments", 1)
+	counter, _ := s.queueRates.Load("orders")
+	
+	// Payments is still sent to a window later, orders is not
+	later := time.Now().Add(2 * rateWindow * time.Second)
+	payments, _ := s.queueRates.Load("payments")
+	payments.(*rateCounter).add(1, later)
+	s.evictIdleRates(later)
+	
+	if _, ok := s.queueRates.Load("orders"); ok {
+		t.Error("Expected the idle queue's rate to be evicted")
+	}
+	if _, ok := s.queueRates.Load("payments"); !ok {
+		t.Error("Expected the active queue's rate to be kept")
+	}
+	if counter.(*rateCounter).add(1, later) {
+		t.Error("Expected an evicted counter to refuse events")
+	}
+	
+	// Sending again starts a new counter
+	s.recordQueueRate("orders", 1)
+	if stats, _ := s.GetQueueStats(context.Background(), "orders"); stats.MessagesPerSec <= 0 {
+		t.Errorf("Expected a send rate after the queue came back, got %f", stats.MessagesPerSec)
+	}
+	
+	// Eviction runs at most once per window
+	s.evictIdleRates(later.Add(time.Second))
+	if _, ok := s.queueRates.Load("orders"); !ok {
+		t.Error("Expected no second eviction within the window")
+	}
+}
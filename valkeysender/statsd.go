@@ -0,0 +1,157 @@
+package valkeysender
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStatsdPrefix is prepended to metric names when Config.StatsdPrefix is empty
+const DefaultStatsdPrefix = "valkeysender."
+
+// Metric names emitted to statsd, after the prefix
+const (
+	statsdSendLatency  = "send.latency"  // timing per send, in milliseconds
+	statsdSendMessages = "send.messages" // counter of messages sent
+	statsdSendErrors   = "send.errors"   // counter of failed operations
+	statsdQueueDepth   = "queue.depth"   // gauge per watched queue
+	statsdQueueBacklog = "queue.backlog" // gauge of messages in all queues
+)
+
+// statsdClient writes metrics over UDP in the statsd line format with
+// Datadog style tags. Writes are fire and forget: a lost packet or an absent
+// agent never slows down or fails a send. A nil client discards everything.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   []string // constant tags from the config
+}
+
+// newStatsdClient connects to the statsd agent at config.StatsdAddress, or
+// returns nil if no address is configured
+func newStatsdClient(config *Config) (*statsdClient, error) {
+	if config.StatsdAddress == "" {
+		return nil, nil
+	}
+
+	conn, err := net.Dial("udp", config.StatsdAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd: %w", err)
+	}
+
+	prefix := config.StatsdPrefix
+	if prefix == "" {
+		prefix = DefaultStatsdPrefix
+	}
+	tags := make([]string, len(config.StatsdTags))
+	for i, tag := range config.StatsdTags {
+		tags[i] = sanitizeStatsdTag(tag)
+	}
+	return &statsdClient{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+// count adds value to a counter
+func (c *statsdClient) count(name string, value int64, tags ...string) {
+	c.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// timing records a duration in milliseconds
+func (c *statsdClient) timing(name string, d time.Duration, tags ...string) {
+	ms := float64(d) / float64(time.Millisecond)
+	c.send(name, strconv.FormatFloat(ms, 'f', 3, 64), "ms", tags)
+}
+
+// gauge sets the current value of a gauge
+func (c *statsdClient) gauge(name string, value int64, tags ...string) {
+	c.send(name, strconv.FormatInt(value, 10), "g", tags)
+}
+
+// send writes one metric line, e.g. "valkeysender.send.errors:1|c|#queue:orders"
+func (c *statsdClient) send(name, value, kind string, tags []string) {
+	if c == nil {
+		return
+	}
+
+	var line strings.Builder
+	line.WriteString(c.prefix)
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(kind)
+
+	separator := "|#"
+	for _, tag := range c.tags {
+		line.WriteString(separator)
+		line.WriteString(tag)
+		separator = ","
+	}
+	for _, tag := range tags {
+		line.WriteString(separator)
+		line.WriteString(sanitizeStatsdTag(tag))
+		separator = ","
+	}
+
+	c.conn.Write([]byte(line.String()))
+}
+
+// close releases the UDP socket
+func (c *statsdClient) close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// statsdTag builds a "key:value" tag
+func statsdTag(key, value string) string {
+	return key + ":" + value
+}
+
+// sanitizeStatsdTag replaces the characters that delimit the statsd format
+func sanitizeStatsdTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '|', ',', '#', '\n':
+			return '_'
+		}
+		return r
+	}, tag)
+}
+
+// emitSend reports the duration of one send of count messages to statsd,
+// and the messages if it succeeded
+func (s *valkeySender) emitSend(op, tenant, queue string, count int, duration time.Duration, err error) {
+	if s.statsd == nil {
+		return
+	}
+
+	tags := sendTags(op, tenant, queue)
+	s.statsd.timing(statsdSendLatency, duration, tags...)
+	if err == nil {
+		s.statsd.count(statsdSendMessages, int64(count), tags...)
+	}
+}
+
+// emitFailure reports a failed operation to statsd
+func (s *valkeySender) emitFailure(op, tenant, queue string) {
+	if s.statsd == nil {
+		return
+	}
+	s.statsd.count(statsdSendErrors, 1, sendTags(op, tenant, queue)...)
+}
+
+// sendTags returns the tags of a send metric, tagged with the tenant for
+// sends to tenant queues
+func sendTags(op, tenant, queue string) []string {
+	tags := []string{statsdTag("op", strings.ReplaceAll(op, " ", "_"))}
+	if queue != "" {
+		tags = append(tags, statsdTag("queue", queue))
+	}
+	if tenant != "" {
+		tags = append(tags, statsdTag("tenant", tenant))
+	}
+	return tags
+}
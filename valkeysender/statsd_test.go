@@ -0,0 +1,159 @@
+package valkeysender
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// listenStatsd starts a UDP listener standing in for a statsd agent
+func listenStatsd(t *testing.T) net.PacketConn {
+	t.Helper()
+	
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readStatsd reads n metric lines, replacing timing values with "T"
+func readStatsd(t *testing.T, conn net.PacketConn, n int) []string {
+	t.Helper()
+	
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1500)
+	var lines []string
+	for len(lines) < n {
+		size, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Expected %d metrics, got %q: %v", n, lines, err)
+		}
+		line := string(buf[:size])
+		if name, rest, ok := strings.Cut(line, ":"); ok && strings.Contains(rest, "|ms") {
+			_, suffix, _ := strings.Cut(rest, "|")
+			line = name + ":T|" + suffix
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsdEmitter(t *testing.T) {
+	server := miniredis.RunT(t)
+	agent := listenStatsd(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	config := DefaultConfig()
+	config.StatsdAddress = agent.LocalAddr().String()
+	config.StatsdTags = []string{"env:test"}
+	statsd, err := newStatsdClient(config)
+	if err != nil {
+		t.Fatalf("newStatsdClient failed: %v", err)
+	}
+	defer statsd.close()
+	s.statsd = statsd
+	
+	if err := s.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	want := []string{
+		"valkeysender.send.latency:T|ms|#env:test,op:send_batch,queue:orders",
+		"valkeysender.send.messages:2|c|#env:test,op:send_batch,queue:orders",
+	}
+	if got := readStatsd(t, agent, 2); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	
+	s.SendMessage(ctx, "orders", make(chan int))
+	want = []string{
+		"valkeysender.send.errors:1|c|#env:test,op:send_message,queue:orders",
+	}
+	if got := readStatsd(t, agent, 1); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	
+	s.config.WatchQueues = []string{"orders"}
+	s.watchQueues(ctx, map[string]bool{})
+	want = []string{
+		"valkeysender.queue.backlog:2|g|#env:test",
+		"valkeysender.queue.depth:2|g|#env:test,queue:orders",
+	}
+	if got := readStatsd(t, agent, 2); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestStatsdTenantTag(t *testing.T) {
+	server := miniredis.RunT(t)
+	agent := listenStatsd(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	config := DefaultConfig()
+	config.StatsdAddress = agent.LocalAddr().String()
+	statsd, err := newStatsdClient(config)
+	if err != nil {
+		t.Fatalf("newStatsdClient failed: %v", err)
+	}
+	defer statsd.close()
+	s.statsd = statsd
+	
+	// Shared queues are not tagged with a tenant
+	if err := s.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	want := []string{
+		"valkeysender.send.latency:T|ms|#op:send_message,queue:orders",
+		"valkeysender.send.messages:1|c|#op:send_message,queue:orders",
+	}
+	if got := readStatsd(t, agent, 2); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	
+	if err := s.SendMessageForTenant(ctx, "acme", "orders", "b"); err != nil {
+		t.Fatalf("SendMessageForTenant failed: %v", err)
+	}
+	want = []string{
+		"valkeysender.send.latency:T|ms|#op:send_message,queue:orders,tenant:acme",
+		"valkeysender.send.messages:1|c|#op:send_message,queue:orders,tenant:acme",
+	}
+	if got := readStatsd(t, agent, 2); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	
+	s.SendMessageForTenant(ctx, "acme", "orders", make(chan int))
+	want = []string{
+		"valkeysender.send.errors:1|c|#op:send_message,queue:orders,tenant:acme",
+	}
+	if got := readStatsd(t, agent, 1); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestStatsdDisabled(t *testing.T) {
+	statsd, err := newStatsdClient(DefaultConfig())
+	if err != nil || statsd != nil {
+		t.Fatalf("Expected no client without an address, got %v (%v)", statsd, err)
+	}
+	
+	// A nil client discards metrics
+	statsd.count(statsdSendMessages, 1)
+	if err := statsd.close(); err != nil {
+		t.Errorf("Expected closing a nil client to succeed, got %v", err)
+	}
+}
+
+func TestSanitizeStatsdTag(t *testing.T) {
+	if got := sanitizeStatsdTag("queue:a|b,c#d"); got != "queue:a_b_c_d" {
+		t.Errorf("Unexpected sanitized tag %q", got)
+	}
+}
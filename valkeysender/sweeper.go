@@ -0,0 +1,135 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// defaultSweepChunkSize is used when Config.SweepChunkSize is unset
+const defaultSweepChunkSize = 100
+
+// startSweeper periodically removes messages whose envelope TTL has elapsed
+func (s *valkeySender) startSweeper() {
+	if s.config.SweepInterval <= 0 || s.options.RawPayload {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.SweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.sweep(s.ctx)
+			}
+		}
+	}()
+}
+
+// sweep removes expired messages from every queue except the expired queue.
+// Queues are swept by the keys found, which with a QueueNamer are also the
+// names ListQueues returns.
+func (s *valkeySender) sweep(ctx context.Context) {
+	queues, keys, err := s.scanQueues(ctx, "")
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("Sweeper failed to list queues", slog.Any("error", err))
+		}
+		return
+	}
+
+	var expiredKey string
+	if s.config.ExpiredQueue != "" {
+		expiredKey = s.getQueueKey(s.config.ExpiredQueue)
+	}
+	for i, queue := range queues {
+		if keys[i] == expiredKey {
+			continue
+		}
+		if _, err := s.sweepQueue(ctx, queue, keys[i]); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("Sweeper failed",
+				slog.String("queue", queue),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// sweepQueue scans the list of a queue in chunks and removes the envelopes
+// whose TTL has elapsed, routing them to the expired queue if one is
+// configured
+func (s *valkeySender) sweepQueue(ctx context.Context, queue, listKey string) (int64, error) {
+	chunkSize := int64(s.config.SweepChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = defaultSweepChunkSize
+	}
+
+	var target string
+	if s.config.ExpiredQueue != "" {
+		target = s.getQueueKey(s.config.ExpiredQueue)
+	}
+
+	var removed int64
+
+	// Walk from the head: new pushes only cause elements to be re-scanned,
+	// and consumers popping from the tail don't shift head indexes
+	for start := int64(0); ; {
+		values, err := s.backend.lrange(ctx, listKey, start, start+chunkSize-1)
+		if err != nil {
+			return removed, err
+		}
+
+		now := time.Now()
+		var expired []string
+		for _, value := range values {
+			if s.isExpired(value, now) {
+				expired = append(expired, value)
+			}
+		}
+
+		var chunkRemoved int64
+		if len(expired) > 0 {
+			chunkRemoved, err = s.backend.remove(ctx, listKey, target, expired)
+			if err != nil {
+				return removed, err
+			}
+			removed += chunkRemoved
+		}
+
+		if int64(len(values)) < chunkSize {
+			break
+		}
+		start += chunkSize - chunkRemoved
+	}
+
+	if removed > 0 {
+		atomic.AddInt64(&s.messagesExpired, removed)
+		s.logger.Info("Expired messages swept",
+			slog.String("queue", queue),
+			slog.Int64("removed", removed),
+			slog.String("expired_queue", s.config.ExpiredQueue),
+		)
+	}
+
+	return removed, nil
+}
+
+// isExpired reports whether a list element is an envelope whose TTL has elapsed
+func (s *valkeySender) isExpired(value string, now time.Time) bool {
+	envelope, err := s.codec.Decode([]byte(value))
+	if err != nil || envelope.TTL <= 0 || envelope.Timestamp.IsZero() {
+		return false
+	}
+	return now.After(envelope.Timestamp.Add(envelope.TTL))
+}
@@ -0,0 +1,82 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSweepQueue(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.SweepChunkSize = 3
+	s.config.ExpiredQueue = "expired"
+	
+	// Interleave expired and live envelopes across several chunks
+	for i := 0; i < 10; i++ {
+		timestamp := time.Now()
+		if i%2 == 0 {
+			timestamp = timestamp.Add(-2 * time.Hour)
+		}
+		data, _ := SerializeMessageEnvelope(MessageEnvelope{
+			ID:        fmt.Sprintf("m%d", i),
+			Timestamp: timestamp,
+			TTL:       time.Hour,
+		})
+		server.Lpush("queue:orders", string(data))
+	}
+	server.Lpush("queue:orders", "not an envelope")
+	
+	s.sweep(ctx)
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 6 {
+		t.Errorf("Expected 5 live envelopes and the raw element to remain, got %d", size)
+	}
+	if size, _ := s.GetQueueSize(ctx, "expired"); size != 5 {
+		t.Errorf("Expected 5 envelopes routed to the expired queue, got %d", size)
+	}
+	if expired := s.Health().MessagesExpired; expired != 5 {
+		t.Errorf("Expected 5 expired messages reported, got %d", expired)
+	}
+	
+	// The expired queue itself is never swept
+	s.sweep(ctx)
+	if size, _ := s.GetQueueSize(ctx, "expired"); size != 5 {
+		t.Errorf("Expected the expired queue to be left alone, got %d", size)
+	}
+}
+
+func TestSweepQueueNamer(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, &SenderOptions{
+		QueueNamer: func(queue string) string { return "q:" + queue },
+	})
+	s.config.ExpiredQueue = "expired"
+	
+	for i, timestamp := range []time.Time{time.Now().Add(-2 * time.Hour), time.Now()} {
+		data, _ := SerializeMessageEnvelope(MessageEnvelope{
+			ID:        fmt.Sprintf("m%d", i),
+			Timestamp: timestamp,
+			TTL:       time.Hour,
+		})
+		server.Lpush("q:orders", string(data))
+		server.Lpush("q:expired", string(data))
+	}
+	
+	s.sweep(ctx)
+	
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected the live envelope to remain in q:orders, got %d", size)
+	}
+	if size, _ := s.GetQueueSize(ctx, "expired"); size != 3 {
+		t.Errorf("Expected the expired queue to be left alone and receive 1 envelope, got %d", size)
+	}
+	if server.Exists("q:q:orders") || server.Exists("q:q:expired") {
+		t.Error("Expected raw keys not to be renamed")
+	}
+}
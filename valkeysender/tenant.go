@@ -0,0 +1,61 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// HeaderTenant is the envelope header carrying the tenant of messages sent with SendMessageForTenant
+const HeaderTenant = "tenant"
+
+// tenantKeyPrefix is prepended to the queue key of tenant queues
+const tenantKeyPrefix = "tenant:"
+
+// TenantKeyPrefix returns the key segment isolating a tenant's queues, e.g.
+// "tenant:{acme}:". The tenant ID is a Valkey Cluster hash tag, so all
+// queues of one tenant live in the same slot.
+func TenantKeyPrefix(tenantID string) string {
+	return tenantKeyPrefix + "{" + tenantID + "}:"
+}
+
+// validateTenantID rejects tenant IDs that would break the key layout
+func validateTenantID(tenantID string) error {
+	if tenantID == "" {
+		return fmt.Errorf("tenant ID cannot be empty")
+	}
+	if strings.ContainsAny(tenantID, "{}") {
+		return fmt.Errorf("tenant ID %q cannot contain braces", tenantID)
+	}
+	return nil
+}
+
+// tenantQueueKey returns the key of a queue, namespaced by tenant if one is given
+func tenantQueueKey(config *Config, options *SenderOptions, tenantID, queue string) string {
+	var tenant string
+	if tenantID != "" {
+		tenant = TenantKeyPrefix(tenantID)
+	}
+	if options.QueueNamer != nil {
+		return tenant + options.QueueNamer(queue)
+	}
+
+	prefix := config.QueuePrefix
+	if prefix == "" {
+		prefix = DefaultQueuePrefix
+	}
+	return config.KeyPrefix + tenant + prefix + queue
+}
+
+// SendMessageForTenant sends a message to a tenant's own copy of a queue,
+// stored under a key like "tenant:{acme}:queue:registrations". The tenant
+// is also recorded in the tenant header and in the success metadata.
+func (s *valkeySender) SendMessageForTenant(ctx context.Context, tenantID, queue string, message interface{}) error {
+	if err := validateTenantID(tenantID); err != nil {
+		return s.fail(opSendMessage, queue, &Error{Kind: ErrValidation, Err: err})
+	}
+	return s.sendMessage(ctx, opSendMessage, tenantID, queue, message, SendOptions{
+		TTL:     s.messageTTL(queue),
+		Headers: map[string]string{HeaderTenant: tenantID},
+	})
+}
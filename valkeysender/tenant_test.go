@@ -0,0 +1,71 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSendMessageForTenant(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	var metadata MessageMetadata
+	s := newTestSender(t, server, &SenderOptions{
+		SuccessHandler: func(m MessageMetadata) { metadata = m },
+	})
+	s.config.KeyPrefix = "app:"
+	
+	if err := s.SendMessageForTenant(ctx, "acme", "registrations", "hello"); err != nil {
+		t.Fatalf("SendMessageForTenant failed: %v", err)
+	}
+	
+	values, _ := server.List("app:tenant:{acme}:queue:registrations")
+	if len(values) != 1 {
+		t.Fatalf("Expected message under the tenant key, got keys %v", server.Keys())
+	}
+	envelope, err := DeserializeMessageEnvelope([]byte(values[0]))
+	if err != nil || envelope.Headers[HeaderTenant] != "acme" || envelope.Queue != "registrations" {
+		t.Errorf("Unexpected envelope %+v (%v)", envelope, err)
+	}
+	if metadata.Tenant != "acme" {
+		t.Errorf("Expected tenant in success metadata, got %q", metadata.Tenant)
+	}
+	
+	if err := s.SendMessage(ctx, "registrations", "shared"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if !server.Exists("app:queue:registrations") {
+		t.Errorf("Expected shared queue under the key prefix, got keys %v", server.Keys())
+	}
+	
+	for _, tenant := range []string{"", "a{b}"} {
+		if err := s.SendMessageForTenant(ctx, tenant, "registrations", "x"); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation for tenant %q, got %v", tenant, err)
+		}
+	}
+}
+
+func TestConsumerForTenant(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	if err := s.SendMessageForTenant(ctx, "acme", "jobs", "work"); err != nil {
+		t.Fatalf("SendMessageForTenant failed: %v", err)
+	}
+	
+	consumer := newTestConsumer(t, server, ConsumerConfig{Queue: "jobs", Tenant: "acme", Name: "worker-1"})
+	delivery, err := consumer.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if !server.Exists("tenant:{acme}:processing:jobs:worker-1") {
+		t.Errorf("Expected tenant processing list, got keys %v", server.Keys())
+	}
+	if err := consumer.Ack(ctx, delivery); err != nil {
+		t.Errorf("Ack failed: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package valkeysender
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// buildTLSConfig creates the client TLS configuration
+func buildTLSConfig(config *Config) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(config.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.TLSSkipVerify,
+		ServerName:         config.TLSServerName,
+		MinVersion:         minVersion,
+	}
+
+	// Verify the server against a private CA instead of the system pool
+	if config.TLSCAFile != "" {
+		caPEM, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in TLS CA file %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Client certificate for mutual TLS
+	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+		cert, err := loadX509KeyPair(config.TLSCertFile, config.TLSKeyFile, config.TLSKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion converts a version string such as "1.2" to a tls constant
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min version %q (expected 1.0, 1.1, 1.2 or 1.3)", version)
+	}
+}
+
+// loadX509KeyPair loads a client certificate, decrypting the key with the
+// passphrase when it is an encrypted PEM block (RFC 1423)
+func loadX509KeyPair(certFile, keyFile, password string) (tls.Certificate, error) {
+	if password == "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM data found in %s", keyFile)
+	}
+
+	// Legacy PEM encryption is what `openssl rsa -aes256` produces
+	if x509.IsEncryptedPEMBlock(block) {
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to decrypt TLS key: %w", err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
@@ -0,0 +1,96 @@
+package valkeysender
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+// writeTestCA writes a self-signed CA certificate and returns its path
+func writeTestCA(t *testing.T) string {
+	t.Helper()
+	
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "valkeysender test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	
+	path := t.TempDir() + "/ca.pem"
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	return path
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	caFile := writeTestCA(t)
+	
+	t.Run("server verification with private CA", func(t *testing.T) {
+		tlsConfig, err := buildTLSConfig(&Config{
+			TLSEnabled:    true,
+			TLSCAFile:     caFile,
+			TLSServerName: "valkey.internal",
+			TLSMinVersion: "1.3",
+		})
+		if err != nil {
+			t.Fatalf("buildTLSConfig failed: %v", err)
+		}
+		if tlsConfig.RootCAs == nil {
+			t.Error("Expected RootCAs to be populated from the CA file")
+		}
+		if tlsConfig.ServerName != "valkey.internal" {
+			t.Errorf("Expected server name valkey.internal, got %s", tlsConfig.ServerName)
+		}
+		if tlsConfig.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Expected TLS 1.3 minimum, got %x", tlsConfig.MinVersion)
+		}
+		if len(tlsConfig.Certificates) != 0 {
+			t.Error("Expected no client certificate")
+		}
+	})
+	
+	t.Run("default min version", func(t *testing.T) {
+		tlsConfig, err := buildTLSConfig(&Config{TLSEnabled: true, TLSCAFile: caFile})
+		if err != nil {
+			t.Fatalf("buildTLSConfig failed: %v", err)
+		}
+		if tlsConfig.MinVersion != tls.VersionTLS12 {
+			t.Errorf("Expected TLS 1.2 minimum, got %x", tlsConfig.MinVersion)
+		}
+	})
+	
+	t.Run("invalid CA file", func(t *testing.T) {
+		path := t.TempDir() + "/bad.pem"
+		os.WriteFile(path, []byte("not a certificate"), 0600)
+		if _, err := buildTLSConfig(&Config{TLSEnabled: true, TLSCAFile: path}); err == nil {
+			t.Error("Expected error for invalid CA file")
+		}
+	})
+	
+	t.Run("invalid min version", func(t *testing.T) {
+		if _, err := buildTLSConfig(&Config{TLSEnabled: true, TLSMinVersion: "2.0"}); err == nil {
+			t.Error("Expected error for unsupported TLS version")
+		}
+	})
+}
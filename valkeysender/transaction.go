@@ -0,0 +1,168 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TxSender stages the messages and key updates of a SendTransactional call.
+// Nothing is written until the callback returns; staged keys get the
+// configured key prefix like queues do.
+type TxSender interface {
+	// SendMessage stages a message for queue
+	SendMessage(queue string, message interface{}) error
+
+	// SendBatch stages several messages for queue
+	SendBatch(queue string, messages []interface{}) error
+
+	// Set stages SET key value, expiring after expiration if it is positive
+	Set(key, value string, expiration time.Duration)
+
+	// IncrBy stages INCRBY key n
+	IncrBy(key string, n int64)
+
+	// Del stages DEL of keys
+	Del(keys ...string)
+}
+
+// Key operations staged by a TxSender
+const (
+	keyOpSet    = "set"
+	keyOpIncrBy = "incrby"
+	keyOpDel    = "del"
+)
+
+// keyOp is one key update applied with the messages of a transaction
+type keyOp struct {
+	kind       string
+	keys       []string
+	value      string
+	n          int64
+	expiration time.Duration
+}
+
+// apply queues the operation on a Redis pipeline
+func (op keyOp) apply(ctx context.Context, pipe redis.Pipeliner) {
+	switch op.kind {
+	case keyOpSet:
+		pipe.Set(ctx, op.keys[0], op.value, op.expiration)
+	case keyOpIncrBy:
+		pipe.IncrBy(ctx, op.keys[0], op.n)
+	case keyOpDel:
+		pipe.Del(ctx, op.keys...)
+	}
+}
+
+// txSender collects the batches and key operations of a transaction
+type txSender struct {
+	s       *valkeySender
+	ctx     context.Context // carries headers attached with WithHeader
+	batches []*queueBatch
+	ops     []keyOp
+	count   int
+}
+
+// SendMessage stages a message for queue
+func (tx *txSender) SendMessage(queue string, message interface{}) error {
+	return tx.SendBatch(queue, []interface{}{message})
+}
+
+// SendBatch stages several messages for queue. Messages for the same queue
+// are pushed in staging order.
+func (tx *txSender) SendBatch(queue string, messages []interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages slice cannot be empty")
+	}
+
+	batch, err := tx.s.newQueueBatch(tx.ctx, queue, messages, tx.s.messageTTL(queue), HeadersFromContext(tx.ctx))
+	if err != nil {
+		return classifyError(opSendTx, queue, err)
+	}
+	tx.count += len(messages)
+
+	for _, staged := range tx.batches {
+		if staged.key == batch.key {
+			staged.envelopes = append(staged.envelopes, batch.envelopes...)
+			staged.data = append(staged.data, batch.data...)
+			staged.dedup = append(staged.dedup, batch.dedup...)
+			return nil
+		}
+	}
+	tx.batches = append(tx.batches, batch)
+	return nil
+}
+
+// Set stages SET key value, expiring after expiration if it is positive
+func (tx *txSender) Set(key, value string, expiration time.Duration) {
+	tx.ops = append(tx.ops, keyOp{kind: keyOpSet, keys: []string{tx.key(key)}, value: value, expiration: expiration})
+}
+
+// IncrBy stages INCRBY key n
+func (tx *txSender) IncrBy(key string, n int64) {
+	tx.ops = append(tx.ops, keyOp{kind: keyOpIncrBy, keys: []string{tx.key(key)}, n: n})
+}
+
+// Del stages DEL of keys
+func (tx *txSender) Del(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = tx.key(key)
+	}
+	tx.ops = append(tx.ops, keyOp{kind: keyOpDel, keys: prefixed})
+}
+
+// key applies the configured key prefix
+func (tx *txSender) key(key string) string {
+	return tx.s.config.KeyPrefix + key
+}
+
+// SendTransactional stages messages and key updates with fn and applies
+// them in one MULTI/EXEC, so a queue and a related key (a processed marker,
+// a per-user counter) never disagree. If fn returns an error nothing is
+// written. If any queue is full under the reject or block overflow policy,
+// none of the messages or key updates are applied.
+//
+// Messages skipped by deduplication don't abort the transaction, and like
+// any MULTI/EXEC a failing command (INCRBY on a non-integer value) does not
+// undo the others.
+func (s *valkeySender) SendTransactional(ctx context.Context, fn func(tx TxSender) error) error {
+	startTime := time.Now()
+
+	tx := &txSender{s: s, ctx: ctx}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.batches) == 0 && len(tx.ops) == 0 {
+		return nil
+	}
+
+	err := s.guard(ctx, opSendTx, "", tx.batches, func(ctx context.Context) error {
+		return s.pushed(ctx, tx.batches, s.pushWithOverflow(ctx, func(policy pushPolicy) (*queueBatch, error) {
+			return s.pushBackend(ctx).transact(ctx, tx.batches, tx.ops, policy)
+		}))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Update metrics
+	s.recordSuccess(tx.count)
+
+	s.logger.Debug("Transaction sent successfully",
+		slog.Int("queue_count", len(tx.batches)),
+		slog.Int("message_count", tx.count),
+		slog.Int("key_updates", len(tx.ops)),
+	)
+
+	// Call success handler for each message
+	s.notifySuccess(tx.batches, "", startTime)
+
+	return nil
+}
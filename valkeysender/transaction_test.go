@@ -0,0 +1,151 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// storedKey reads a plain key from either backend
+func storedKey(s *valkeySender, key string) (string, bool) {
+	if b, ok := s.backend.(*memoryBackend); ok {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if value := b.str(key, time.Now()); value != nil {
+			return value.value, true
+		}
+		return "", false
+	}
+	value, err := s.getClient().Get(context.Background(), key).Result()
+	return value, err == nil
+}
+
+func TestSendTransactional(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			
+			err := s.SendTransactional(ctx, func(tx TxSender) error {
+				if err := tx.SendMessage("orders", "a"); err != nil {
+					return err
+				}
+				if err := tx.SendBatch("orders", []interface{}{"b", "c"}); err != nil {
+					return err
+				}
+				tx.Set("order:o1:sent", "yes", time.Hour)
+				tx.IncrBy("user:u1:orders", 3)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("SendTransactional failed: %v", err)
+			}
+			
+			envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+			if len(envelopes) != 3 || string(envelopes[0].Payload) != "a" || string(envelopes[2].Payload) != "c" {
+				t.Errorf("Expected staged messages in order, got %+v", envelopes)
+			}
+			if value, _ := storedKey(s, "order:o1:sent"); value != "yes" {
+				t.Errorf("Expected marker to be set, got %q", value)
+			}
+			if value, _ := storedKey(s, "user:u1:orders"); value != "3" {
+				t.Errorf("Expected counter 3, got %q", value)
+			}
+			
+			// A failing command doesn't undo the rest, as with EXEC
+			err = s.SendTransactional(ctx, func(tx TxSender) error {
+				tx.IncrBy("order:o1:sent", 1)
+				tx.IncrBy("user:u1:orders", 1)
+				tx.Del("order:o1:sent")
+				return nil
+			})
+			if err == nil {
+				t.Error("Expected INCRBY on a non-integer to fail")
+			}
+			if value, _ := storedKey(s, "user:u1:orders"); value != "4" {
+				t.Errorf("Expected counter 4, got %q", value)
+			}
+			if _, ok := storedKey(s, "order:o1:sent"); ok {
+				t.Error("Expected marker to be deleted")
+			}
+		})
+	}
+}
+
+func TestSendTransactionalKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	s.config.KeyPrefix = "app:"
+	
+	err := s.SendTransactional(ctx, func(tx TxSender) error {
+		tx.Set("marker", "1", time.Minute)
+		tx.IncrBy("counter", 2)
+		tx.Set("stale", "x", 0)
+		tx.Del("stale")
+		return tx.SendMessage("orders", "a")
+	})
+	if err != nil {
+		t.Fatalf("SendTransactional failed: %v", err)
+	}
+	
+	if value, _ := server.Get("app:marker"); value != "1" || server.TTL("app:marker") != time.Minute {
+		t.Errorf("Expected prefixed marker with TTL, got %q (%v)", value, server.TTL("app:marker"))
+	}
+	if value, _ := server.Get("app:counter"); value != "2" {
+		t.Errorf("Expected counter 2, got %q", value)
+	}
+	if server.Exists("app:stale") {
+		t.Error("Expected staged DEL to remove the key")
+	}
+	if size, _ := s.GetQueueSize(ctx, "orders"); size != 1 {
+		t.Errorf("Expected 1 message, got %d", size)
+	}
+}
+
+func TestSendTransactionalAborted(t *testing.T) {
+	for name, newSender := range enqueueBackends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newSender(t)
+			s.config.MaxQueueLength = 2
+			s.config.OverflowPolicy = OverflowPolicyReject
+			
+			abort := errors.New("abort")
+			err := s.SendTransactional(ctx, func(tx TxSender) error {
+				tx.IncrBy("counter", 1)
+				if err := tx.SendMessage("orders", "a"); err != nil {
+					return err
+				}
+				return abort
+			})
+			if !errors.Is(err, abort) {
+				t.Fatalf("Expected the callback error, got %v", err)
+			}
+			
+			err = s.SendTransactional(ctx, func(tx TxSender) error {
+				tx.IncrBy("counter", 1)
+				if err := tx.SendMessage("invoices", "a"); err != nil {
+					return err
+				}
+				return tx.SendBatch("orders", []interface{}{"a", "b", "c"})
+			})
+			if !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("Expected ErrQueueFull, got %v", err)
+			}
+			
+			// Nothing from either transaction was applied
+			if _, ok := storedKey(s, "counter"); ok {
+				t.Error("Expected counter not to be written")
+			}
+			for _, queue := range []string{"orders", "invoices"} {
+				if size, _ := s.GetQueueSize(ctx, queue); size != 0 {
+					t.Errorf("Expected %s to stay empty, got %d", queue, size)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// TTL strategies for Config.TTLStrategy
+const (
+	// TTLStrategyList expires the whole list. Each push extends the list
+	// expiry to cover the new messages but never shortens it, so the list
+	// lives as long as its longest-lived message.
+	TTLStrategyList = "list"
+
+	// TTLStrategyCreate sets the list expiry only when the list is created;
+	// later pushes don't extend it
+	TTLStrategyCreate = "create"
+
+	// TTLStrategyMessage additionally records every message's expiry in a
+	// companion sorted set, so PurgeExpired can remove individual messages
+	TTLStrategyMessage = "message"
+)
+
+// expiryKeySuffix is appended to a queue key to build its companion expiry set
+const expiryKeySuffix = ":expiry"
+
+// purgeChunkSize bounds the work done by one purge script call
+const purgeChunkSize = 100
+
+// expiryKey returns the companion expiry set key for a queue list key
+func expiryKey(listKey string) string {
+	return listKey + expiryKeySuffix
+}
+
+// ttlStrategy returns the configured TTL strategy, defaulting to TTLStrategyList
+func (s *valkeySender) ttlStrategy() string {
+	if s.config.TTLStrategy == "" {
+		return TTLStrategyList
+	}
+	return strings.ToLower(s.config.TTLStrategy)
+}
+
+// PurgeExpired removes messages whose TTL has elapsed from a queue and
+// returns how many were removed. It requires TTLStrategyMessage; messages
+// already consumed are dropped from the expiry set without being counted.
+func (s *valkeySender) PurgeExpired(ctx context.Context, queue string) (int64, error) {
+	if s.ttlStrategy() != TTLStrategyMessage {
+		return 0, fmt.Errorf("purging expired messages requires the %q TTL strategy", TTLStrategyMessage)
+	}
+	
+	listKey := s.getQueueKey(queue)
+	now := time.Now()
+	
+	var removed int64
+	for {
+		examined, chunkRemoved, err := s.backend.purgeExpired(ctx, listKey, now, purgeChunkSize)
+		if err != nil {
+			return removed, fmt.Errorf("failed to purge expired messages from %s: %w", queue, err)
+		}
+		removed += chunkRemoved
+		if examined < purgeChunkSize {
+			break
+		}
+	}
+	
+	if removed > 0 {
+		s.logger.Info("Expired messages purged",
+			slog.String("queue", queue),
+			slog.Int64("removed", removed),
+		)
+	}
+	
+	return removed, nil
+}
@@ -0,0 +1,78 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestTTLStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		expected time.Duration
+	}{
+		{TTLStrategyList, time.Hour},     // extended by the longer TTL, never shortened
+		{TTLStrategyCreate, time.Minute}, // fixed when the list is created
+	}
+	
+	for _, tt := range tests {
+		for _, capped := range []bool{false, true} {
+			server := miniredis.RunT(t)
+			ctx := context.Background()
+			s := newTestSender(t, server, nil)
+			s.config.TTLStrategy = tt.strategy
+			if capped {
+				s.config.MaxQueueLength = 100
+			}
+			
+			for _, ttl := range []time.Duration{time.Minute, time.Hour, time.Second} {
+				if err := s.SendMessageWithTTL(ctx, "orders", "m", ttl); err != nil {
+					t.Fatalf("Failed to send: %v", err)
+				}
+			}
+			
+			if got := server.TTL("queue:orders"); got != tt.expected {
+				t.Errorf("%s (capped=%t): expected list TTL %v, got %v", tt.strategy, capped, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	for _, capped := range []bool{false, true} {
+		server := miniredis.RunT(t)
+		ctx := context.Background()
+		s := newTestSender(t, server, nil)
+		
+		if _, err := s.PurgeExpired(ctx, "orders"); err == nil {
+			t.Error("Expected PurgeExpired to require the message TTL strategy")
+		}
+		
+		s.config.TTLStrategy = TTLStrategyMessage
+		if capped {
+			s.config.MaxQueueLength = 100
+		}
+		
+		s.SendMessageWithTTL(ctx, "orders", "short-lived", time.Millisecond)
+		s.SendMessageWithTTL(ctx, "orders", "long-lived", time.Hour)
+		time.Sleep(5 * time.Millisecond)
+		
+		removed, err := s.PurgeExpired(ctx, "orders")
+		if err != nil {
+			t.Fatalf("PurgeExpired failed: %v", err)
+		}
+		if removed != 1 {
+			t.Errorf("capped=%t: expected 1 expired message, got %d", capped, removed)
+		}
+		
+		envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+		if len(envelopes) != 1 || string(envelopes[0].Payload) != "long-lived" {
+			t.Errorf("capped=%t: expected only the long-lived message to remain, got %+v", capped, envelopes)
+		}
+		if members, _ := server.ZMembers("queue:orders:expiry"); len(members) != 1 {
+			t.Errorf("capped=%t: expected 1 entry left in the expiry set, got %d", capped, len(members))
+		}
+	}
+}
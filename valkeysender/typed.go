@@ -0,0 +1,98 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Validatable is implemented by message types that can check themselves
+// before being sent
+type Validatable interface {
+	Validate() error
+}
+
+// TypedSender sends messages of a single Go type to a fixed queue
+type TypedSender[T any] struct {
+	sender     Sender
+	queue      string
+	validators []func(T) error
+}
+
+// TypedOption configures a TypedSender
+type TypedOption[T any] func(*TypedSender[T])
+
+// WithValidation adds a validation hook run before every send. Messages that
+// fail validation are not sent and the error wraps ErrValidation.
+func WithValidation[T any](validate func(T) error) TypedOption[T] {
+	return func(t *TypedSender[T]) {
+		t.validators = append(t.validators, validate)
+	}
+}
+
+// NewTypedSender creates a sender for messages of type T on queue. If T
+// implements Validatable, Validate is called before every send in addition
+// to the hooks added with WithValidation.
+func NewTypedSender[T any](sender Sender, queue string, opts ...TypedOption[T]) *TypedSender[T] {
+	t := &TypedSender[T]{
+		sender: sender,
+		queue:  queue,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Queue returns the queue messages are sent to
+func (t *TypedSender[T]) Queue() string {
+	return t.queue
+}
+
+// Send validates and sends a message
+func (t *TypedSender[T]) Send(ctx context.Context, message T) error {
+	if err := t.validate(message); err != nil {
+		return err
+	}
+	return t.sender.SendMessage(ctx, t.queue, message)
+}
+
+// SendWithTTL validates and sends a message with a custom TTL
+func (t *TypedSender[T]) SendWithTTL(ctx context.Context, message T, ttl time.Duration) error {
+	if err := t.validate(message); err != nil {
+		return err
+	}
+	return t.sender.SendMessageWithTTL(ctx, t.queue, message, ttl)
+}
+
+// SendBatch validates all messages and sends them atomically
+func (t *TypedSender[T]) SendBatch(ctx context.Context, messages []T) error {
+	batch := make([]interface{}, len(messages))
+	for i, message := range messages {
+		if err := t.validate(message); err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+		batch[i] = message
+	}
+	return t.sender.SendBatch(ctx, t.queue, batch)
+}
+
+// validate runs the Validatable check and the validation hooks
+func (t *TypedSender[T]) validate(message T) error {
+	if v, ok := any(message).(Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return t.validationError(err)
+		}
+	}
+	for _, validate := range t.validators {
+		if err := validate(message); err != nil {
+			return t.validationError(err)
+		}
+	}
+	return nil
+}
+
+// validationError wraps a failed validation as ErrValidation
+func (t *TypedSender[T]) validationError(err error) error {
+	return &Error{Op: opSendMessage, Queue: t.queue, Kind: ErrValidation, Err: err}
+}
@@ -0,0 +1,77 @@
+package valkeysender
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testOrder struct {
+	ID     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+func (o testOrder) Validate() error {
+	if o.ID == "" {
+		return errors.New("id is required")
+	}
+	return nil
+}
+
+func TestTypedSender(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	orders := NewTypedSender[testOrder](s, "orders", WithValidation(func(o testOrder) error {
+		if o.Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+		return nil
+	}))
+	
+	if err := orders.Send(ctx, testOrder{ID: "o1", Amount: 10}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := orders.SendBatch(ctx, []testOrder{{ID: "o2", Amount: 1}, {ID: "o3", Amount: 2}}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	
+	for _, invalid := range []testOrder{{Amount: 10}, {ID: "o4"}} {
+		if err := orders.Send(ctx, invalid); !errors.Is(err, ErrValidation) || IsRetryable(err) {
+			t.Errorf("Expected non-retryable ErrValidation for %+v, got %v", invalid, err)
+		}
+	}
+	if err := orders.SendBatch(ctx, []testOrder{{ID: "o5", Amount: 1}, {}}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected batch with an invalid message to fail validation, got %v", err)
+	}
+	
+	var decoded testOrder
+	envelopes, _ := s.PeekMessages(ctx, "orders", 0, 10)
+	if len(envelopes) != 3 {
+		t.Fatalf("Expected only valid messages to be sent, got %d", len(envelopes))
+	}
+	if err := NewJSONSerializer().Deserialize(envelopes[0].Payload, &decoded); err != nil || decoded.ID != "o1" {
+		t.Errorf("Expected first order o1, got %+v (%v)", decoded, err)
+	}
+}
+
+func TestSendTyped(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newTestSender(t, server, nil)
+	
+	if err := s.SendTyped(ctx, "events", "", testOrder{ID: "o1"}); err == nil {
+		t.Error("Expected empty type name to be rejected")
+	}
+	if err := s.SendTyped(ctx, "events", "order_placed", testOrder{ID: "o1"}); err != nil {
+		t.Fatalf("SendTyped failed: %v", err)
+	}
+	
+	envelopes, _ := s.PeekMessages(ctx, "events", 0, 1)
+	if len(envelopes) != 1 || envelopes[0].Headers[HeaderMessageType] != "order_placed" {
+		t.Errorf("Expected message-type header, got %+v", envelopes)
+	}
+}
@@ -2,7 +2,12 @@ package valkeysender
 
 import (
 	"context"
+	"log/slog"
+	"net"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
 )
 
 // Sender defines the interface for sending messages to Valkey
@@ -14,29 +19,164 @@ type Sender interface {
 	SendMessageWithTTL(ctx context.Context, queue string, message interface{}, ttl time.Duration) error
 	
 	
-	// SendBatch sends multiple messages to the same queue atomically
+	// SendMessageWithOptions sends a message with a caller-supplied ID, idempotency key, TTL or headers
+	SendMessageWithOptions(ctx context.Context, queue string, message interface{}, opts SendOptions) error
+	
+	// SendAsync sends a message without waiting for it; the channel
+	// receives the result
+	SendAsync(ctx context.Context, queue string, message interface{}) <-chan MessageResult
+	
+	// SendMessageForTenant sends a message to a tenant's own copy of a queue
+	SendMessageForTenant(ctx context.Context, tenantID, queue string, message interface{}) error
+	
+	// SendAndConfirm sends a message, reads it back from the queue and
+	// returns its position (1 = next to be consumed)
+	SendAndConfirm(ctx context.Context, queue string, message interface{}) (int64, error)
+	
+	// SendOrdered sends a message once the earlier sends with the same ordering key completed
+	SendOrdered(ctx context.Context, queue, orderingKey string, message interface{}) error
+	
+	// SendTyped sends a message with its type name recorded in the message-type header
+	SendTyped(ctx context.Context, queue, typeName string, message interface{}) error
+	
+	// SendBatch sends multiple messages to the same queue atomically, or in
+	// chunks if Config.BatchChunkSize is set and the batch is larger
 	SendBatch(ctx context.Context, queue string, messages []interface{}) error
 	
+	// SendBatchChunked sends a large batch in atomic chunks and reports how
+	// much of it was sent
+	SendBatchChunked(ctx context.Context, queue string, messages []interface{}) (*BatchResult, error)
+	
+	// SendBatchWithResult sends a batch like SendBatch and reports its
+	// outcome, duration and per message metadata
+	SendBatchWithResult(ctx context.Context, queue string, messages []interface{}) (*BatchResult, error)
+	
+	// SendBatchFramed packs the batch into compressed frames, each stored as
+	// one list element, to save memory on bulk loads
+	SendBatchFramed(ctx context.Context, queue string, messages []interface{}) error
+	
+	// SendAll sends messages individually over a pool of concurrent workers
+	SendAll(ctx context.Context, queue string, messages []interface{}, concurrency int) (*BatchResult, error)
+	
+	// SendRaw pushes pre-encoded bytes to the queue without the message envelope
+	SendRaw(ctx context.Context, queue string, data []byte) error
+	
+	// SendMulti sends messages to several queues in a single round trip
+	SendMulti(ctx context.Context, messages map[string][]interface{}) error
+	
+	// SendToQueues sends one message to several queues atomically, sharing one envelope ID
+	SendToQueues(ctx context.Context, queues []string, message interface{}) error
+	
+	// SendTransactional applies the messages and key updates staged by fn in one MULTI/EXEC
+	SendTransactional(ctx context.Context, fn func(tx TxSender) error) error
+	
+	// Bind routes messages whose routing key matches pattern to the given queues
+	Bind(pattern string, queues ...string) error
+	
+	// Unbind removes all bindings for pattern
+	Unbind(pattern string)
+	
+	// Route delivers a message to every queue bound to a pattern matching routingKey
+	Route(ctx context.Context, routingKey string, message interface{}) error
+	
 	// GetQueueSize returns the current size of a queue
 	GetQueueSize(ctx context.Context, queue string) (int64, error)
 	
-	// Close gracefully shuts down the sender
+	// GetQueueStats returns length, memory usage, last activity, send rate and enqueue counters of a queue
+	GetQueueStats(ctx context.Context, queue string) (*QueueStats, error)
+	
+	// ListQueues returns the names of existing queues matching a glob pattern
+	ListQueues(ctx context.Context, pattern string) ([]string, error)
+	
+	// DiscoverQueues returns the name and length of every queue, found with SCAN
+	DiscoverQueues(ctx context.Context) ([]QueueInfo, error)
+	
+	// TotalBacklog returns the number of messages waiting in all queues
+	TotalBacklog(ctx context.Context) (int64, error)
+	
+	// PurgeQueue removes all messages from a queue and returns how many were removed
+	PurgeQueue(ctx context.Context, queue string) (int64, error)
+	
+	// PurgeExpired removes messages whose TTL has elapsed (requires the "message" TTL strategy)
+	PurgeExpired(ctx context.Context, queue string) (int64, error)
+	
+	// DeleteQueue removes a queue together with its consumer processing lists
+	DeleteQueue(ctx context.Context, queue string) error
+	
+	// MoveMessages moves up to count messages (0 for all) from one queue to
+	// another, oldest first, and returns how many were moved
+	MoveMessages(ctx context.Context, fromQueue, toQueue string, count int64) (int64, error)
+	
+	// Replay moves the messages of sourceQueue accepted by filter (nil for
+	// all) to targetQueue and returns how many were replayed
+	Replay(ctx context.Context, sourceQueue, targetQueue string, filter func(MessageEnvelope) bool) (int64, error)
+	
+	// ReplayWithOptions replays messages, optionally rewriting their ID,
+	// retry counter and headers
+	ReplayWithOptions(ctx context.Context, sourceQueue, targetQueue string, opts ReplayOptions) (int64, error)
+	
+	// PeekMessages returns up to count messages without removing them, starting
+	// offset messages from the consuming end of the queue
+	PeekMessages(ctx context.Context, queue string, offset, count int64) ([]MessageEnvelope, error)
+	
+	// ReadArchive returns up to count messages archived for a queue since
+	// the given time, oldest first
+	ReadArchive(ctx context.Context, queue string, since time.Time, count int64) ([]ArchivedMessage, error)
+	
+	// GetUsage returns the messages and payload bytes a tenant sent in the
+	// current period
+	GetUsage(ctx context.Context, tenant string, period UsagePeriod) (TenantUsage, error)
+	
+	// Close gracefully shuts down the sender, aborting in-flight sends
 	Close() error
 	
+	// Drain stops accepting sends and waits for in-flight ones until ctx
+	// ends, returning how many messages were dropped
+	Drain(ctx context.Context) (int64, error)
+	
+	// CloseWithContext drains until ctx ends and then closes the sender
+	CloseWithContext(ctx context.Context) (int64, error)
+	
+	// Ping measures the round trip to Valkey, also feeding the RTT
+	// percentiles in Health
+	Ping(ctx context.Context) (time.Duration, error)
+	
 	// Health returns the health status of the sender
 	Health() HealthStatus
+	
+	// Metrics returns send counters, latency percentiles and connection pool statistics
+	Metrics() SenderMetrics
+	
+	// Pressure returns a backpressure score from 0 (idle) to 1 (sends are
+	// about to be refused or time out)
+	Pressure() float64
+	
+	// FailoverReport describes the messages sent to standby clusters during
+	// the current or last failover
+	FailoverReport() FailoverReport
+	
+	// SetLogLevel changes the level of the default logger at runtime
+	SetLogLevel(level slog.Level)
+	
+	// LogLevel returns the lowest level currently logged
+	LogLevel() slog.Level
+	
+	// Subscribe registers a listener for lifecycle events and returns a
+	// function that removes it
+	Subscribe(listener EventListener) func()
 }
 
 
 // MessageMetadata contains metadata about sent messages
 type MessageMetadata struct {
 	Queue      string            `json:"queue"`
-	Position   int64             `json:"position"`        // Position in the list
-	MessageID  string            `json:"message_id"`      // UUID for the message
+	Tenant     string            `json:"tenant,omitempty"` // set by SendMessageForTenant
+	Position   int64             `json:"position"`        // Queue length right after the push (1 = next to be consumed); 0 if skipped as a duplicate or trimmed
+	MessageID  string            `json:"message_id"`      // Envelope ID; empty for SendRaw
 	Headers    map[string]string `json:"headers,omitempty"`
 	Timestamp  time.Time         `json:"timestamp"`
 	TTL        time.Duration     `json:"ttl"`
-	Size       int               `json:"size"`            // Message size in bytes
+	Size       int               `json:"size"`            // Payload size in bytes
 }
 
 // HealthStatus represents the health of the sender
@@ -46,33 +186,99 @@ type HealthStatus struct {
 	LastError       string        `json:"last_error,omitempty"`
 	ErrorCount      int64         `json:"error_count"`
 	MessagesSent    int64         `json:"messages_sent"`
+	MessagesExpired int64         `json:"messages_expired"` // removed by the sweeper
+	
+	// Error rates: failed operations among sent messages and failures, over
+	// the last HealthWindow (which Status is based on) and since start
+	ErrorRate          float64       `json:"error_rate"`
+	LifetimeErrorRate  float64       `json:"lifetime_error_rate"`
+	HealthWindow       time.Duration `json:"health_window"`
+	WindowMessagesSent int64         `json:"window_messages_sent"`
+	WindowErrorCount   int64         `json:"window_error_count"`
+	
 	Uptime          time.Duration `json:"uptime"`
-	ConnectionState string        `json:"connection_state"` // connected, disconnected, connecting
+	ConnectionState string        `json:"connection_state"` // connected, disconnected, connecting, reconnecting
 	CircuitBreaker  string        `json:"circuit_breaker"`  // closed, half-open, open
+	Latency         time.Duration `json:"latency"`          // last PING round trip
+	LatencyP50      time.Duration `json:"latency_p50"`      // over the last 128 PINGs
+	LatencyP99      time.Duration `json:"latency_p99"`
+	LastHealthCheck time.Time     `json:"last_health_check,omitempty"`
+	ConnectionPool  PoolMetrics   `json:"connection_pool"`
+}
+
+// Connection states reported in HealthStatus.ConnectionState
+const (
+	ConnectionStateConnecting   = "connecting"
+	ConnectionStateConnected    = "connected"
+	ConnectionStateReconnecting = "reconnecting"
+	ConnectionStateDisconnected = "disconnected"
+)
+
+// ConnectionEvent describes a connection state transition
+type ConnectionEvent struct {
+	PreviousState string        `json:"previous_state"`
+	State         string        `json:"state"`
+	Latency       time.Duration `json:"latency"`
+	Error         error         `json:"error,omitempty"`
+	Timestamp     time.Time     `json:"timestamp"`
+}
+
+// ReconnectEvent describes a connection restored after it was lost
+type ReconnectEvent struct {
+	Attempts  int           `json:"attempts"` // PINGs sent by the reconnect manager (0 if a send restored the connection)
+	Downtime  time.Duration `json:"downtime"`
+	Cause     error         `json:"cause,omitempty"` // error that marked the connection as lost
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// PoolAlert describes the connection pool crossing
+// Config.PoolAlertThreshold, after staying above it for
+// Config.PoolAlertDuration or on dropping back below it
+type PoolAlert struct {
+	Utilization   float64     `json:"utilization"` // share of MaxConns in use
+	Pool          PoolMetrics `json:"pool"`
+	Recovered     bool        `json:"recovered"`                // utilization dropped below the threshold
+	SuggestedSize int         `json:"suggested_size,omitempty"` // pool size to try, while not recovered
+	Timestamp     time.Time   `json:"timestamp"`
 }
 
-// SenderMetrics contains performance metrics
+// SenderMetrics contains performance metrics. Latencies cover whole sends,
+// including rate limiter and overflow waits; percentiles are the upper bound
+// of their histogram bucket.
 type SenderMetrics struct {
 	MessagesSent        int64         `json:"messages_sent"`
-	MessagesFailedTotal int64         `json:"messages_failed_total"`
+	MessagesFailedTotal int64         `json:"messages_failed_total"` // failed operations
 	MessagesFailedLast  int64         `json:"messages_failed_last_minute"`
+	MessagesTooLarge    int64         `json:"messages_too_large"` // rejected for exceeding Config.MaxMessageBytes
+	CorruptMessages     int64         `json:"corrupt_messages"` // failed their checksum when read back by PeekMessages or ReadArchive
+	ShadowedMessages    int64         `json:"shadowed_messages"` // copied to shadow queues, see Config.ShadowQueueRatio
 	AvgLatency          time.Duration `json:"avg_latency"`
 	MaxLatency          time.Duration `json:"max_latency"`
+	P50Latency          time.Duration `json:"p50_latency"`
+	P95Latency          time.Duration `json:"p95_latency"`
+	P99Latency          time.Duration `json:"p99_latency"`
 	CircuitBreakerState string        `json:"circuit_breaker_state"`
 	RateLimitHits       int64         `json:"rate_limit_hits"`
-	QueueSizes          map[string]int64 `json:"queue_sizes"`
+	RateLimitTokens     float64       `json:"rate_limit_tokens"` // tokens currently available
+	RateLimit           float64       `json:"rate_limit"`        // current requests per second, lowered by adaptive rate limiting
+	QueueSizes          map[string]int64 `json:"queue_sizes,omitempty"` // not collected by Metrics; see GetQueueSize
 	ConnectionPool      PoolMetrics   `json:"connection_pool"`
+	Mirror              *MirrorMetrics `json:"mirror,omitempty"` // per-target counts, nil unless Config.MirrorURL is set
 	StartTime           time.Time     `json:"start_time"`
 }
 
-// PoolMetrics contains connection pool metrics
+// PoolMetrics contains connection pool metrics. Hits, Misses and Timeouts
+// count since the client was created; a growing Timeouts means commands
+// waited PoolTimeout for a connection and failed, i.e. the pool is exhausted.
 type PoolMetrics struct {
-	TotalConns int32 `json:"total_conns"`
-	IdleConns  int32 `json:"idle_conns"`
-	StaleConns int32 `json:"stale_conns"`
-	Hits       uint32 `json:"hits"`
-	Misses     uint32 `json:"misses"`
-	Timeouts   uint32 `json:"timeouts"`
+	MaxConns    int32  `json:"max_conns"`    // pool size, 0 if unknown (custom clients)
+	TotalConns  int32  `json:"total_conns"`
+	IdleConns   int32  `json:"idle_conns"`
+	ActiveConns int32  `json:"active_conns"` // checked out by a command
+	StaleConns  int32  `json:"stale_conns"`
+	Hits        uint32 `json:"hits"`
+	Misses      uint32 `json:"misses"`
+	Timeouts    uint32 `json:"timeouts"`
 }
 
 // MessageResult represents the result of sending a message
@@ -83,6 +289,23 @@ type MessageResult struct {
 	Duration time.Duration     `json:"duration"`
 }
 
+// SendOptions customizes a single SendMessageWithOptions call
+type SendOptions struct {
+	// Envelope ID to use instead of a generated UUID
+	MessageID string
+	
+	// Idempotency key: a message with the same key sent to the same queue
+	// within the deduplication window is skipped, even by another process
+	// or after a restart. Defaults to MessageID when EnableDeduplication is set.
+	IdempotencyKey string
+	
+	// Message TTL (0 uses the queue profile's TTL or Config.MessageTTL)
+	TTL time.Duration
+	
+	// Extra envelope headers
+	Headers map[string]string
+}
+
 // SenderOptions contains optional settings for creating a sender
 type SenderOptions struct {
 	// Custom error handler (optional)
@@ -91,23 +314,107 @@ type SenderOptions struct {
 	// Custom success handler (optional)
 	SuccessHandler func(MessageMetadata)
 	
+	// Hook called with every envelope before it is encoded (optional). It
+	// may change the ID, payload, headers and metadata of the envelope, or
+	// reject it with an error, which fails the send with ErrValidation.
+	BeforeSend func(ctx context.Context, envelope *MessageEnvelope) error
+	
+	// Hook called for every message once pushing it succeeded or failed
+	// (optional), with the error the send returns
+	AfterSend func(ctx context.Context, metadata MessageMetadata, err error)
+	
+	// Custom connection state handler (optional), called on every state transition
+	ConnectionHandler func(ConnectionEvent)
+	
+	// Reconnect handler (optional), called by the reconnect manager once a
+	// lost connection is restored
+	ReconnectHandler func(ReconnectEvent)
+	
+	// Custom circuit breaker trip policy (optional), overrides the Breaker* config settings
+	ReadyToTrip func(counts gobreaker.Counts) bool
+	
+	// Custom circuit breaker state change handler (optional), called with
+	// the previous and new state (closed, half-open, open)
+	BreakerStateHandler func(from, to string)
+	
+	// Chunked batch progress handler (optional), called after every chunk
+	// sent by SendBatchChunked
+	BatchProgressHandler func(BatchProgress)
+	
+	// Queue depth alert handler (optional), called by the queue watcher when
+	// a watched queue reaches Config.QueueAlertThreshold and again when its
+	// depth drops back below it
+	QueueAlertHandler func(queue string, depth int64)
+	
+	// Pool alert handler (optional), called when the share of pool
+	// connections in use stayed at or above Config.PoolAlertThreshold for
+	// Config.PoolAlertDuration and again when it drops back below
+	PoolAlertHandler func(PoolAlert)
+	
+	// Audit handler (optional), called with the audit record of every
+	// message sent to Config.AuditQueues, in addition to the audit stream
+	// and file
+	AuditHandler func(AuditRecord)
+	
 	// Custom metrics handler (optional)
 	MetricsHandler func(SenderMetrics)
 	
-	// Logger for structured logging (if nil, a default logger will be created)
-	Logger interface{}
+	// Existing client to send through (optional), e.g. a shared
+	// *redis.Client with instrumentation hooks or a Sentinel failover
+	// client. Cluster and ring clients are rejected, as sends update
+	// several keys of a queue in one script. Connection settings in Config
+	// are then ignored, credentials are not reloaded and Close leaves the
+	// client open.
+	Client redis.UniversalClient
+	
+	// Credentials provider (optional), asked for the username and password
+	// of every new connection instead of Config.Username and Config.Password
+	CredentialsProvider CredentialsProvider
+	
+	// Dialer (optional) opening the TCP connections to Config.Address, e.g.
+	// to resolve it with a custom resolver or through service discovery.
+	// TLS is started on top of the returned connection when enabled. It
+	// cannot be combined with Config.ProxyURL or Config.SSHTunnel.
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+	
+	// Logger for structured logging: a *slog.Logger or an adapter such as
+	// ZapLogger (if nil, a default logger will be created)
+	Logger Logger
 	
 	// Custom serializer (if nil, JSON will be used)
 	Serializer MessageSerializer
 	
+	// Serializer of each queue (optional), for queues whose consumers need
+	// another encoding than Serializer; returning nil uses Serializer. The
+	// serializer of a queue profile (Config.Queues) takes precedence.
+	SerializerFor func(queue string) MessageSerializer
+	
+	// Custom envelope codec (if nil, versioned JSON will be used)
+	EnvelopeCodec EnvelopeCodec
+	
+	// Generator of envelope IDs (if nil, random UUIDs will be used),
+	// e.g. NewULIDGenerator() for IDs that sort by creation time
+	IDGenerator IDGenerator
+	
 	// Custom queue naming strategy
 	QueueNamer func(queue string) string
 	
-	// Enable message deduplication
+	// Message validation hook (optional), called with the target queue and
+	// every message before it is serialized; messages it rejects are not
+	// sent and the error wraps ErrValidation. See JSONSchemaValidator.
+	Validator func(queue string, message interface{}) error
+	
+	// Enable message deduplication by payload (and by message ID for
+	// SendMessageWithOptions)
 	EnableDeduplication bool
 	
-	// Deduplication window
+	// How long payloads and idempotency keys are remembered
+	// (default DefaultDeduplicationWindow)
 	DeduplicationWindow time.Duration
+	
+	// Push serialized payloads without the MessageEnvelope wrapper, for
+	// consumers that expect plain messages written by other producers
+	RawPayload bool
 }
 
 // MessageSerializer defines the interface for message serialization
@@ -117,8 +424,24 @@ type MessageSerializer interface {
 	ContentType() string
 }
 
+// HeaderSerializer is implemented by serializers that need to record
+// extra information (e.g. a schema or type name) in the envelope headers
+type HeaderSerializer interface {
+	MessageSerializer
+	Headers(message interface{}) map[string]string
+}
+
+// EncodingSerializer is implemented by serializers whose payloads are
+// compressed or otherwise encoded on top of their content type; the
+// encoding (e.g. "gzip") is recorded in the content-encoding header
+type EncodingSerializer interface {
+	MessageSerializer
+	ContentEncoding() string
+}
+
 // MessageEnvelope wraps messages with metadata for the queue
 type MessageEnvelope struct {
+	Version   int                    `json:"version"`
 	ID        string                 `json:"id"`
 	Queue     string                 `json:"queue"`
 	Payload   []byte                 `json:"payload"`
@@ -127,6 +450,7 @@ type MessageEnvelope struct {
 	TTL       time.Duration          `json:"ttl"`
 	Retries   int                    `json:"retries"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Checksum  string                 `json:"checksum,omitempty"` // of the payload, set if Config.EnvelopeChecksum is; see PayloadChecksum
 }
 
 // QueueStats provides statistics about a queue
@@ -137,6 +461,17 @@ type QueueStats struct {
 	LastActivity   time.Time     `json:"last_activity"`
 	MessagesPerSec float64       `json:"messages_per_sec"`
 	AvgMessageSize float64       `json:"avg_message_size"`
+	
+	// Counters kept by the enqueue path across all senders, reset by DeleteQueue
+	Enqueued       int64         `json:"enqueued"`
+	Duplicates     int64         `json:"duplicates"`
+	Dropped        int64         `json:"dropped"`
+}
+
+// QueueInfo is a queue found by DiscoverQueues
+type QueueInfo struct {
+	Name   string `json:"name"`
+	Length int64  `json:"length"`
 }
 
 // ConnectionInfo contains information about the Valkey connection
@@ -162,7 +497,8 @@ type BatchResult struct {
 	Success     bool                `json:"success"`
 	TotalSent   int                 `json:"total_sent"`
 	Failed      int                 `json:"failed"`
-	Results     []MessageResult     `json:"results"`
+	Chunks      int                 `json:"chunks,omitempty"` // round trips used by SendBatchChunked
+	Results     []MessageResult     `json:"results"`          // per message outcomes, in message order
 	Duration    time.Duration       `json:"duration"`
 	Error       error               `json:"error,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,202 @@
+package valkeysender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UsagePeriod is a calendar period, in UTC, that usage is accounted for
+type UsagePeriod string
+
+// Usage periods for GetUsage
+const (
+	UsagePeriodHour  UsagePeriod = "hour"
+	UsagePeriodDay   UsagePeriod = "day"
+	UsagePeriodMonth UsagePeriod = "month"
+)
+
+// usagePeriods are the periods every send is accounted for
+var usagePeriods = []UsagePeriod{UsagePeriodHour, UsagePeriodDay, UsagePeriodMonth}
+
+// usageKeyPrefix starts the keys of the usage counters
+const usageKeyPrefix = "usage:"
+
+// TenantUsage is what a tenant sent in a period
+type TenantUsage struct {
+	Tenant   string      `json:"tenant"`
+	Period   UsagePeriod `json:"period"`
+	Start    time.Time   `json:"start"` // start of the period, UTC
+	Messages int64       `json:"messages"`
+	Bytes    int64       `json:"bytes"` // payload bytes, without envelopes
+}
+
+// Start returns the start of the period containing t, zero for an unknown period
+func (p UsagePeriod) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch p {
+	case UsagePeriodHour:
+		return t.Truncate(time.Hour)
+	case UsagePeriodDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case UsagePeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Time{}
+	}
+}
+
+// end returns the end of the period starting at start
+func (p UsagePeriod) end(start time.Time) time.Time {
+	switch p {
+	case UsagePeriodHour:
+		return start.Add(time.Hour)
+	case UsagePeriodDay:
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// usageKey returns the key of a usage counter of a tenant, e.g.
+// "usage:{acme}:day:2025-05-28:messages"; the tenant is a hash tag, so
+// the counters of a tenant live in one slot
+func usageKey(keyPrefix, tenant string, period UsagePeriod, start time.Time, counter string) string {
+	var bucket string
+	switch period {
+	case UsagePeriodHour:
+		bucket = start.Format("2006-01-02T15")
+	case UsagePeriodDay:
+		bucket = start.Format("2006-01-02")
+	default:
+		bucket = start.Format("2006-01")
+	}
+	return fmt.Sprintf("%s%s{%s}:%s:%s:%s", keyPrefix, usageKeyPrefix, tenant, period, bucket, counter)
+}
+
+// usageMeter counts the messages and payload bytes sent per tenant, taken
+// from an envelope header. A nil meter counts nothing.
+type usageMeter struct {
+	client    func() redis.UniversalClient
+	keyPrefix string
+	header    string
+	retention time.Duration
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+// newUsageMeter returns the usage meter of the configuration, or nil if
+// Config.UsageAccounting is not set
+func (s *valkeySender) newUsageMeter() (*usageMeter, error) {
+	config := s.config
+	if !config.UsageAccounting {
+		return nil, nil
+	}
+	if s.getClient() == nil {
+		return nil, fmt.Errorf("usage accounting requires the %q backend", BackendList)
+	}
+
+	meter := &usageMeter{
+		client:    s.getClient,
+		keyPrefix: config.KeyPrefix,
+		header:    config.UsageHeader,
+		retention: config.UsageRetention,
+		timeout:   config.WriteTimeout,
+		logger:    s.logger,
+	}
+	if meter.header == "" {
+		meter.header = HeaderTenant
+	}
+	return meter, nil
+}
+
+// count adds the messages of a successful send to the counters of their
+// tenants for every period in one round trip. Messages without the tenant
+// header, skipped as duplicates or packed into frames are not counted.
+// Failures are logged but never fail the send.
+func (m *usageMeter) count(batches []*queueBatch, sendErr error) {
+	if m == nil || sendErr != nil {
+		return
+	}
+
+	type usage struct{ messages, bytes int64 }
+	tenants := make(map[string]*usage)
+	for _, batch := range batches {
+		for i, envelope := range batch.envelopes {
+			if i < len(batch.positions) && batch.positions[i] == 0 {
+				continue
+			}
+			tenant := envelope.Headers[m.header]
+			if validateTenantID(tenant) != nil {
+				continue
+			}
+			if tenants[tenant] == nil {
+				tenants[tenant] = &usage{}
+			}
+			tenants[tenant].messages++
+			tenants[tenant].bytes += int64(len(envelope.Payload))
+		}
+	}
+	if len(tenants) == 0 {
+		return
+	}
+
+	// The send's context may be done already; the counters have their own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	now := time.Now()
+	pipe := m.client().Pipeline()
+	for tenant, used := range tenants {
+		for _, period := range usagePeriods {
+			start := period.Start(now)
+			expireAt := period.end(start).Add(m.retention)
+			for counter, n := range map[string]int64{"messages": used.messages, "bytes": used.bytes} {
+				key := usageKey(m.keyPrefix, tenant, period, start, counter)
+				pipe.IncrBy(ctx, key, n)
+				pipe.ExpireAt(ctx, key, expireAt)
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		m.logger.Error("Failed to update usage counters", slog.Any("error", err))
+	}
+}
+
+// GetUsage returns the messages and payload bytes a tenant sent in the
+// current period, as counted with Config.UsageAccounting from the tenant
+// header of every message (Config.UsageHeader). Counters are kept for
+// Config.UsageRetention after their period ends.
+func (s *valkeySender) GetUsage(ctx context.Context, tenant string, period UsagePeriod) (TenantUsage, error) {
+	if err := validateTenantID(tenant); err != nil {
+		return TenantUsage{}, err
+	}
+	start := period.Start(time.Now())
+	if start.IsZero() {
+		return TenantUsage{}, fmt.Errorf("unknown usage period %q", period)
+	}
+	if s.usage == nil {
+		return TenantUsage{}, fmt.Errorf("usage accounting is not enabled")
+	}
+
+	keys := []string{
+		usageKey(s.config.KeyPrefix, tenant, period, start, "messages"),
+		usageKey(s.config.KeyPrefix, tenant, period, start, "bytes"),
+	}
+	values, err := s.usage.client().MGet(ctx, keys...).Result()
+	if err != nil {
+		return TenantUsage{}, fmt.Errorf("failed to get usage of tenant %s: %w", tenant, err)
+	}
+
+	usage := TenantUsage{Tenant: tenant, Period: period, Start: start}
+	for i, target := range []*int64{&usage.Messages, &usage.Bytes} {
+		if value, ok := values[i].(string); ok {
+			*target, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return usage, nil
+}
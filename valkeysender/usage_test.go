@@ -0,0 +1,93 @@
+package valkeysender
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newUsageSender creates a sender accounting usage by header
+func newUsageSender(t *testing.T, server *miniredis.Miniredis, header string) *valkeySender {
+	t.Helper()
+
+	sender, err := NewSenderWithOptions(server.Addr(),
+		WithUsageAccounting(header),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender.(*valkeySender)
+}
+
+func TestUsageAccounting(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	s := newUsageSender(t, server, "")
+
+	if err := s.SendMessageForTenant(ctx, "acme", "orders", "12345"); err != nil {
+		t.Fatalf("SendMessageForTenant failed: %v", err)
+	}
+	tenantCtx := WithHeader(ctx, HeaderTenant, "acme")
+	if err := s.SendBatch(tenantCtx, "orders", []interface{}{"123", "12"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	s.SendMessageForTenant(ctx, "globex", "orders", "1")
+	s.SendMessage(ctx, "orders", "unattributed")
+
+	for _, period := range []UsagePeriod{UsagePeriodHour, UsagePeriodDay, UsagePeriodMonth} {
+		usage, err := s.GetUsage(ctx, "acme", period)
+		if err != nil {
+			t.Fatalf("GetUsage failed: %v", err)
+		}
+		if usage.Messages != 3 || usage.Bytes != 10 || !usage.Start.Equal(period.Start(time.Now())) {
+			t.Errorf("Expected 3 messages and 10 bytes in the %s, got %+v", period, usage)
+		}
+	}
+	if usage, _ := s.GetUsage(ctx, "globex", UsagePeriodDay); usage.Messages != 1 {
+		t.Errorf("Expected 1 message for globex, got %+v", usage)
+	}
+	if usage, _ := s.GetUsage(ctx, "initech", UsagePeriodDay); usage.Messages != 0 || usage.Bytes != 0 {
+		t.Errorf("Expected no usage for an unknown tenant, got %+v", usage)
+	}
+
+	key := usageKey("", "acme", UsagePeriodHour, UsagePeriodHour.Start(time.Now()), "messages")
+	if ttl := server.TTL(key); ttl <= 0 {
+		t.Errorf("Expected the counter to expire, got TTL %v", ttl)
+	}
+
+	if _, err := s.GetUsage(ctx, "acme", "week"); err == nil {
+		t.Error("Expected an unknown period to be rejected")
+	}
+}
+
+func TestUsageAccountingHeader(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := WithHeader(context.Background(), "api-key", "key-1")
+	s := newUsageSender(t, server, "api-key")
+
+	if err := s.SendMessage(ctx, "orders", "abc"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if usage, _ := s.GetUsage(ctx, "key-1", UsagePeriodMonth); usage.Messages != 1 || usage.Bytes != 3 {
+		t.Errorf("Expected usage counted by API key, got %+v", usage)
+	}
+}
+
+func TestUsagePeriodStart(t *testing.T) {
+	at := time.Date(2025, 5, 28, 10, 30, 15, 0, time.FixedZone("CEST", 2*3600))
+	for period, want := range map[UsagePeriod]time.Time{
+		UsagePeriodHour:  time.Date(2025, 5, 28, 8, 0, 0, 0, time.UTC),
+		UsagePeriodDay:   time.Date(2025, 5, 28, 0, 0, 0, 0, time.UTC),
+		UsagePeriodMonth: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if start := period.Start(at); !start.Equal(want) {
+			t.Errorf("Expected the %s to start at %v, got %v", period, want, start)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package valkeysender
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// startQueueWatcher periodically checks the depth of the watched queues
+func (s *valkeySender) startQueueWatcher() {
+	if s.config.WatchInterval <= 0 || len(s.config.WatchQueues) == 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.WatchInterval)
+		defer ticker.Stop()
+
+		alerting := make(map[string]bool, len(s.config.WatchQueues))
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.watchQueues(s.ctx, alerting)
+			}
+		}
+	}()
+}
+
+// watchQueues reads the depth of every watched queue and reports those that
+// crossed the alert threshold in either direction since the last check.
+// alerting holds the queues currently at or above the threshold.
+func (s *valkeySender) watchQueues(ctx context.Context, alerting map[string]bool) {
+	threshold := int64(s.config.QueueAlertThreshold)
+
+	for _, queue := range s.config.WatchQueues {
+		checkCtx, cancel := context.WithTimeout(ctx, s.config.ReadTimeout)
+		depth, err := s.GetQueueSize(checkCtx, queue)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("Queue watcher failed",
+				slog.String("queue", queue),
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		s.statsd.gauge(statsdQueueDepth, depth, statsdTag("queue", queue))
+		
+		above := depth >= threshold
+		if above == alerting[queue] {
+			continue
+		}
+		alerting[queue] = above
+
+		if above {
+			s.logger.Warn("Queue depth above alert threshold",
+				slog.String("queue", queue),
+				slog.Int64("depth", depth),
+				slog.Int64("threshold", threshold),
+			)
+		} else {
+			s.logger.Info("Queue depth recovered",
+				slog.String("queue", queue),
+				slog.Int64("depth", depth),
+				slog.Int64("threshold", threshold),
+			)
+		}
+
+		if s.options.QueueAlertHandler != nil {
+			s.options.QueueAlertHandler(queue, depth)
+		}
+	}
+	
+	// The aggregate backlog costs a SCAN, so only read it for statsd
+	if s.statsd != nil {
+		checkCtx, cancel := context.WithTimeout(ctx, s.config.ReadTimeout)
+		backlog, err := s.TotalBacklog(checkCtx)
+		cancel()
+		if err != nil {
+			s.logger.Warn("Queue watcher failed to read the backlog", slog.Any("error", err))
+			return
+		}
+		s.statsd.gauge(statsdQueueBacklog, backlog)
+	}
+}
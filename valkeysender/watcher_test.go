@@ -0,0 +1,46 @@
+package valkeysender
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestWatchQueues(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	
+	type alert struct {
+		queue string
+		depth int64
+	}
+	var alerts []alert
+	s := newTestSender(t, server, &SenderOptions{
+		QueueAlertHandler: func(queue string, depth int64) {
+			alerts = append(alerts, alert{queue, depth})
+		},
+	})
+	s.config.WatchQueues = []string{"orders", "missing"}
+	s.config.QueueAlertThreshold = 2
+	alerting := make(map[string]bool)
+	
+	server.Lpush("queue:orders", "a")
+	s.watchQueues(ctx, alerting)
+	if len(alerts) != 0 {
+		t.Fatalf("Expected no alert below the threshold, got %v", alerts)
+	}
+	
+	server.Lpush("queue:orders", "b")
+	s.watchQueues(ctx, alerting)
+	s.watchQueues(ctx, alerting)
+	if len(alerts) != 1 || alerts[0] != (alert{"orders", 2}) {
+		t.Fatalf("Expected one alert when the threshold is reached, got %v", alerts)
+	}
+	
+	server.RPop("queue:orders")
+	s.watchQueues(ctx, alerting)
+	if len(alerts) != 2 || alerts[1] != (alert{"orders", 1}) {
+		t.Errorf("Expected a recovery alert, got %v", alerts)
+	}
+}
@@ -0,0 +1,1078 @@
+// Package valkeysendertest provides test doubles and helpers for code using valkeysender.
+package valkeysendertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+// FakeSender is an in-memory valkeysender.Sender for unit tests. Messages are
+// wrapped in envelopes exactly like the real sender and kept per queue in
+// send order; they can be inspected with Messages and LastEnvelope.
+//
+// Failures and latency can be injected with SetError, FailNext and SetLatency,
+// and backpressure with SetPressure.
+type FakeSender struct {
+	mu         sync.Mutex
+	serializer valkeysender.MessageSerializer
+	ids        valkeysender.IDGenerator
+	queues     map[string][]valkeysender.MessageEnvelope
+	bindings   map[string][]string
+	keys       map[string]string
+	seenKeys   map[string]bool // queue + idempotency key
+	last       *valkeysender.MessageEnvelope
+	messageTTL time.Duration
+
+	err       error
+	failNext  int
+	failErr   error
+	latency   time.Duration
+	pressure  float64
+	closed    bool
+	startTime time.Time
+	sent      int64
+	errors    int64
+	lastError string
+	logLevel  slog.Level
+	pending   []valkeysender.SenderEvent // delivered by flush once unlocked
+
+	listenMu     sync.Mutex
+	listeners    map[int]valkeysender.EventListener
+	nextListener int
+}
+
+var _ valkeysender.Sender = (*FakeSender)(nil)
+
+// NewFakeSender creates an empty fake sender using the JSON serializer
+func NewFakeSender() *FakeSender {
+	return &FakeSender{
+		serializer: valkeysender.NewJSONSerializer(),
+		ids:        valkeysender.NewUUIDGenerator(),
+		queues:     make(map[string][]valkeysender.MessageEnvelope),
+		bindings:   make(map[string][]string),
+		keys:       make(map[string]string),
+		seenKeys:   make(map[string]bool),
+		messageTTL: 24 * time.Hour,
+		startTime:  time.Now(),
+	}
+}
+
+// WithSerializer replaces the payload serializer
+func (f *FakeSender) WithSerializer(serializer valkeysender.MessageSerializer) *FakeSender {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.serializer = serializer
+	return f
+}
+
+// WithIDGenerator replaces the generator of envelope IDs
+func (f *FakeSender) WithIDGenerator(generator valkeysender.IDGenerator) *FakeSender {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ids = generator
+	return f
+}
+
+// SetError makes every send fail with err until it is cleared with SetError(nil)
+func (f *FakeSender) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// FailNext makes the next n sends fail with err
+func (f *FakeSender) FailNext(n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext = n
+	f.failErr = err
+}
+
+// SetLatency delays every send by d, or until the context is done
+func (f *FakeSender) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = d
+}
+
+// SetPressure sets the score returned by Pressure
+func (f *FakeSender) SetPressure(pressure float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pressure = pressure
+}
+
+// Messages returns the envelopes sent to a queue, oldest first
+func (f *FakeSender) Messages(queue string) []valkeysender.MessageEnvelope {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]valkeysender.MessageEnvelope(nil), f.queues[queue]...)
+}
+
+// LastEnvelope returns the most recently sent envelope
+func (f *FakeSender) LastEnvelope() (valkeysender.MessageEnvelope, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.last == nil {
+		return valkeysender.MessageEnvelope{}, false
+	}
+	return *f.last, true
+}
+
+// Decode deserializes the payload of the i-th message sent to queue into target
+func (f *FakeSender) Decode(queue string, i int, target interface{}) error {
+	messages := f.Messages(queue)
+	if i < 0 || i >= len(messages) {
+		return fmt.Errorf("queue %s has %d messages, no message at index %d", queue, len(messages), i)
+	}
+	return f.serializer.Deserialize(messages[i].Payload, target)
+}
+
+// Key returns the value of a key written by SendTransactional
+func (f *FakeSender) Key(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.keys[key]
+	return value, ok
+}
+
+// Reset removes all messages and keys and clears injected failures and latency
+func (f *FakeSender) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queues = make(map[string][]valkeysender.MessageEnvelope)
+	f.keys = make(map[string]string)
+	f.seenKeys = make(map[string]bool)
+	f.last = nil
+	f.err = nil
+	f.failNext = 0
+	f.failErr = nil
+	f.latency = 0
+	f.sent = 0
+	f.errors = 0
+	f.lastError = ""
+}
+
+// SendMessage sends a message to the specified queue
+func (f *FakeSender) SendMessage(ctx context.Context, queue string, message interface{}) error {
+	return f.SendMessageWithTTL(ctx, queue, message, f.messageTTL)
+}
+
+// SendAndConfirm sends a message and returns its position, the queue length
+// right after the send (1 = next to be consumed)
+func (f *FakeSender) SendAndConfirm(ctx context.Context, queue string, message interface{}) (int64, error) {
+	if err := f.SendMessage(ctx, queue, message); err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.queues[queue])), nil
+}
+
+// SendMessageWithTTL sends a message with custom TTL
+func (f *FakeSender) SendMessageWithTTL(ctx context.Context, queue string, message interface{}, ttl time.Duration) error {
+	return f.send(ctx, map[string][]interface{}{queue: {message}}, ttl, nil, "")
+}
+
+// SendMessageWithOptions sends a message with the given ID, TTL and headers.
+// A message whose idempotency key was already sent to the queue is skipped;
+// the fake remembers keys until Reset, regardless of the window.
+func (f *FakeSender) SendMessageWithOptions(ctx context.Context, queue string, message interface{}, opts valkeysender.SendOptions) error {
+	if opts.TTL == 0 {
+		opts.TTL = f.messageTTL
+	}
+	if opts.IdempotencyKey != "" {
+		seen := queue + "\x00" + opts.IdempotencyKey
+		f.mu.Lock()
+		duplicate := f.seenKeys[seen]
+		f.mu.Unlock()
+		if duplicate {
+			return f.before(ctx)
+		}
+		if err := f.send(ctx, map[string][]interface{}{queue: {message}}, opts.TTL, opts.Headers, opts.MessageID); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.seenKeys[seen] = true
+		f.mu.Unlock()
+		return nil
+	}
+	return f.send(ctx, map[string][]interface{}{queue: {message}}, opts.TTL, opts.Headers, opts.MessageID)
+}
+
+// SendAsync records the message like SendMessage and returns a channel
+// already holding the result
+func (f *FakeSender) SendAsync(ctx context.Context, queue string, message interface{}) <-chan valkeysender.MessageResult {
+	startTime := time.Now()
+	err := f.SendMessage(ctx, queue, message)
+	result := make(chan valkeysender.MessageResult, 1)
+	result <- valkeysender.MessageResult{Success: err == nil, Error: err, Duration: time.Since(startTime)}
+	return result
+}
+
+// SendTyped sends a message with its type name recorded in the message-type header
+func (f *FakeSender) SendTyped(ctx context.Context, queue, typeName string, message interface{}) error {
+	if typeName == "" {
+		return fmt.Errorf("type name cannot be empty")
+	}
+	headers := map[string]string{valkeysender.HeaderMessageType: typeName}
+	return f.send(ctx, map[string][]interface{}{queue: {message}}, f.messageTTL, headers, "")
+}
+
+// SendOrdered sends a message with its ordering key recorded in the
+// ordering-key header; the fake sends one message at a time, so order is kept
+func (f *FakeSender) SendOrdered(ctx context.Context, queue, orderingKey string, message interface{}) error {
+	if orderingKey == "" {
+		return fmt.Errorf("ordering key cannot be empty")
+	}
+	headers := map[string]string{valkeysender.HeaderOrderingKey: orderingKey}
+	return f.send(ctx, map[string][]interface{}{queue: {message}}, f.messageTTL, headers, "")
+}
+
+// SendMessageForTenant sends a message to the tenant's queue, kept under
+// the name TenantKeyPrefix(tenantID) + queue
+func (f *FakeSender) SendMessageForTenant(ctx context.Context, tenantID, queue string, message interface{}) error {
+	if tenantID == "" {
+		return fmt.Errorf("tenant ID cannot be empty")
+	}
+	headers := map[string]string{valkeysender.HeaderTenant: tenantID}
+	return f.send(ctx, map[string][]interface{}{valkeysender.TenantKeyPrefix(tenantID) + queue: {message}}, f.messageTTL, headers, "")
+}
+
+// SendBatch sends multiple messages to the same queue atomically
+func (f *FakeSender) SendBatch(ctx context.Context, queue string, messages []interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages slice cannot be empty")
+	}
+	return f.send(ctx, map[string][]interface{}{queue: messages}, f.messageTTL, nil, "")
+}
+
+// SendBatchFramed records the messages like SendBatch; the fake stores
+// envelopes, so no frames are built
+func (f *FakeSender) SendBatchFramed(ctx context.Context, queue string, messages []interface{}) error {
+	return f.SendBatch(ctx, queue, messages)
+}
+
+// SendBatchChunked sends the batch in chunks of
+// valkeysender.DefaultBatchChunkSize messages, stopping at the first failure
+func (f *FakeSender) SendBatchChunked(ctx context.Context, queue string, messages []interface{}) (*valkeysender.BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+
+	start := time.Now()
+	size := valkeysender.DefaultBatchChunkSize
+	result := &valkeysender.BatchResult{Chunks: (len(messages) + size - 1) / size}
+	for i := 0; i < len(messages); i += size {
+		end := min(i+size, len(messages))
+		if err := f.send(ctx, map[string][]interface{}{queue: messages[i:end]}, f.messageTTL, nil, ""); err != nil {
+			result.Failed = len(messages) - result.TotalSent
+			result.Duration = time.Since(start)
+			result.Error = err
+			return result, err
+		}
+		result.TotalSent = end
+	}
+	result.Success = true
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// SendBatchWithResult sends the batch like SendBatch and reports its
+// outcome, with the stored envelopes' IDs, positions and sizes as metadata
+func (f *FakeSender) SendBatchWithResult(ctx context.Context, queue string, messages []interface{}) (*valkeysender.BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+
+	start := time.Now()
+	err := f.send(ctx, map[string][]interface{}{queue: messages}, f.messageTTL, nil, "")
+	result := &valkeysender.BatchResult{Chunks: 1, Results: make([]valkeysender.MessageResult, len(messages)), Duration: time.Since(start)}
+	envelopes := f.Messages(queue)
+	sent := len(envelopes) - len(messages)
+	for i := range messages {
+		result.Results[i] = valkeysender.MessageResult{Success: err == nil, Error: err, Duration: result.Duration}
+		if err == nil && sent >= 0 {
+			envelope := envelopes[sent+i]
+			result.Results[i].Metadata = &valkeysender.MessageMetadata{
+				MessageID: envelope.ID,
+				Queue:     queue,
+				Tenant:    envelope.Headers[valkeysender.HeaderTenant],
+				Position:  int64(sent + i + 1),
+				Timestamp: envelope.Timestamp,
+				TTL:       envelope.TTL,
+				Headers:   envelope.Headers,
+				Size:      len(envelope.Payload),
+			}
+		}
+	}
+	if err != nil {
+		result.Failed = len(messages)
+		result.Error = err
+		return result, err
+	}
+	result.TotalSent = len(messages)
+	result.Success = true
+	return result, nil
+}
+
+// SendAll sends the messages one by one, in order, regardless of
+// concurrency, so tests stay deterministic. Failures do not stop the others.
+func (f *FakeSender) SendAll(ctx context.Context, queue string, messages []interface{}, concurrency int) (*valkeysender.BatchResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages slice cannot be empty")
+	}
+
+	start := time.Now()
+	result := &valkeysender.BatchResult{Results: make([]valkeysender.MessageResult, len(messages))}
+	for i, message := range messages {
+		sendStart := time.Now()
+		err := f.send(ctx, map[string][]interface{}{queue: {message}}, f.messageTTL, nil, "")
+		result.Results[i] = valkeysender.MessageResult{Success: err == nil, Error: err, Duration: time.Since(sendStart)}
+		if err != nil {
+			result.Failed++
+			if result.Error == nil {
+				result.Error = err
+			}
+			continue
+		}
+		result.TotalSent++
+	}
+	result.Duration = time.Since(start)
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d messages failed: %w", result.Failed, len(messages), result.Error)
+	}
+	result.Success = true
+	return result, nil
+}
+
+// SendRaw records pre-encoded bytes as an envelope payload
+func (f *FakeSender) SendRaw(ctx context.Context, queue string, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("data cannot be empty")
+	}
+	defer f.flush()
+	if err := f.before(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.push(valkeysender.MessageEnvelope{
+		Version:   valkeysender.EnvelopeVersion,
+		Queue:     queue,
+		Payload:   append([]byte(nil), data...),
+		Timestamp: time.Now(),
+		TTL:       f.messageTTL,
+	})
+	return nil
+}
+
+// SendMulti sends messages to several queues in a single round trip
+func (f *FakeSender) SendMulti(ctx context.Context, messages map[string][]interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages map cannot be empty")
+	}
+	for queue, queueMessages := range messages {
+		if len(queueMessages) == 0 {
+			return fmt.Errorf("messages slice for queue %s cannot be empty", queue)
+		}
+	}
+	return f.send(ctx, messages, f.messageTTL, nil, "")
+}
+
+// SendToQueues sends one message to several queues atomically, sharing one envelope ID
+func (f *FakeSender) SendToQueues(ctx context.Context, queues []string, message interface{}) error {
+	return f.fanOut(ctx, queues, message, nil)
+}
+
+// SendTransactional stores the staged messages and applies the staged key
+// updates, or nothing if fn or the send fails. Expirations are ignored.
+func (f *FakeSender) SendTransactional(ctx context.Context, fn func(tx valkeysender.TxSender) error) error {
+	tx := &fakeTx{messages: make(map[string][]interface{})}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if len(tx.messages) > 0 {
+		if err := f.send(ctx, tx.messages, f.messageTTL, nil, ""); err != nil {
+			return err
+		}
+	} else if err := f.before(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var firstErr error
+	for _, op := range tx.ops {
+		if err := op(f.keys); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Bind routes messages whose routing key matches pattern to the given queues
+func (f *FakeSender) Bind(pattern string, queues ...string) error {
+	if pattern == "" || len(queues) == 0 {
+		return fmt.Errorf("binding needs a pattern and at least one queue")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bindings[pattern] = append(f.bindings[pattern], queues...)
+	return nil
+}
+
+// Unbind removes all bindings for pattern
+func (f *FakeSender) Unbind(pattern string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.bindings, pattern)
+}
+
+// Route delivers a message to every queue bound to a pattern matching routingKey
+func (f *FakeSender) Route(ctx context.Context, routingKey string, message interface{}) error {
+	f.mu.Lock()
+	seen := make(map[string]bool)
+	var queues []string
+	for pattern, bound := range f.bindings {
+		if !valkeysender.MatchRoutingKey(pattern, routingKey) {
+			continue
+		}
+		for _, queue := range bound {
+			if !seen[queue] {
+				seen[queue] = true
+				queues = append(queues, queue)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if len(queues) == 0 {
+		return &valkeysender.Error{Op: "route message", Kind: valkeysender.ErrNoRoute, Err: fmt.Errorf("routing key %s", routingKey)}
+	}
+	sort.Strings(queues)
+	return f.fanOut(ctx, queues, message, map[string]string{valkeysender.HeaderRoutingKey: routingKey})
+}
+
+// GetQueueSize returns the current size of a queue
+func (f *FakeSender) GetQueueSize(ctx context.Context, queue string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.queues[queue])), nil
+}
+
+// GetQueueStats returns the queue length and average payload size
+func (f *FakeSender) GetQueueStats(ctx context.Context, queue string) (*valkeysender.QueueStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := f.queues[queue]
+	stats := &valkeysender.QueueStats{Name: queue, Length: int64(len(messages))}
+	for _, envelope := range messages {
+		stats.MemoryUsage += int64(len(envelope.Payload))
+	}
+	if len(messages) > 0 {
+		stats.AvgMessageSize = float64(stats.MemoryUsage) / float64(len(messages))
+		stats.LastActivity = messages[len(messages)-1].Timestamp
+	}
+	return stats, nil
+}
+
+// ListQueues returns the names of non-empty queues matching a glob pattern
+func (f *FakeSender) ListQueues(ctx context.Context, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var queues []string
+	for queue, messages := range f.queues {
+		if matched, _ := path.Match(pattern, queue); matched && len(messages) > 0 {
+			queues = append(queues, queue)
+		}
+	}
+	sort.Strings(queues)
+	return queues, nil
+}
+
+// DiscoverQueues returns the name and length of every non-empty queue
+func (f *FakeSender) DiscoverQueues(ctx context.Context) ([]valkeysender.QueueInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var queues []valkeysender.QueueInfo
+	for queue, messages := range f.queues {
+		if len(messages) > 0 {
+			queues = append(queues, valkeysender.QueueInfo{Name: queue, Length: int64(len(messages))})
+		}
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	return queues, nil
+}
+
+// TotalBacklog returns the number of messages in all queues
+func (f *FakeSender) TotalBacklog(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var total int64
+	for _, messages := range f.queues {
+		total += int64(len(messages))
+	}
+	return total, nil
+}
+
+// PurgeQueue removes all messages from a queue and returns how many were removed
+func (f *FakeSender) PurgeQueue(ctx context.Context, queue string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed := int64(len(f.queues[queue]))
+	delete(f.queues, queue)
+	return removed, nil
+}
+
+// PurgeExpired removes messages whose TTL has elapsed
+func (f *FakeSender) PurgeExpired(ctx context.Context, queue string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	var kept []valkeysender.MessageEnvelope
+	for _, envelope := range f.queues[queue] {
+		if envelope.TTL <= 0 || now.Before(envelope.Timestamp.Add(envelope.TTL)) {
+			kept = append(kept, envelope)
+		}
+	}
+	removed := int64(len(f.queues[queue]) - len(kept))
+	f.queues[queue] = kept
+	return removed, nil
+}
+
+// DeleteQueue removes a queue
+func (f *FakeSender) DeleteQueue(ctx context.Context, queue string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.queues, queue)
+	return nil
+}
+
+// MoveMessages moves up to count messages (0 for all) from one queue to another, oldest first
+func (f *FakeSender) MoveMessages(ctx context.Context, fromQueue, toQueue string, count int64) (int64, error) {
+	if fromQueue == toQueue {
+		return 0, fmt.Errorf("cannot move messages of queue %s onto itself", fromQueue)
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("count cannot be negative")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := f.queues[fromQueue]
+	if count == 0 || count > int64(len(messages)) {
+		count = int64(len(messages))
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	f.queues[toQueue] = append(f.queues[toQueue], messages[:count]...)
+	f.queues[fromQueue] = messages[count:]
+	return count, nil
+}
+
+// Replay moves the messages of sourceQueue accepted by filter (nil for all) to targetQueue
+func (f *FakeSender) Replay(ctx context.Context, sourceQueue, targetQueue string, filter func(valkeysender.MessageEnvelope) bool) (int64, error) {
+	return f.ReplayWithOptions(ctx, sourceQueue, targetQueue, valkeysender.ReplayOptions{Filter: filter})
+}
+
+// ReplayWithOptions replays messages like the real sender, rewriting them as opts ask
+func (f *FakeSender) ReplayWithOptions(ctx context.Context, sourceQueue, targetQueue string, opts valkeysender.ReplayOptions) (int64, error) {
+	if sourceQueue == targetQueue {
+		return 0, fmt.Errorf("cannot replay queue %s onto itself", sourceQueue)
+	}
+	if opts.Count < 0 {
+		return 0, fmt.Errorf("count cannot be negative")
+	}
+	switch opts.Retries {
+	case valkeysender.ReplayRetriesKeep, valkeysender.ReplayRetriesReset, valkeysender.ReplayRetriesIncrement:
+	default:
+		return 0, fmt.Errorf("invalid retries option %q", opts.Retries)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var kept []valkeysender.MessageEnvelope
+	var replayed int64
+	for _, envelope := range f.queues[sourceQueue] {
+		if (opts.Count > 0 && replayed == opts.Count) || (opts.Filter != nil && !opts.Filter(envelope)) {
+			kept = append(kept, envelope)
+			continue
+		}
+		envelope.Queue = targetQueue
+		if opts.NewID {
+			envelope.ID = f.ids.NewID()
+		}
+		switch opts.Retries {
+		case valkeysender.ReplayRetriesReset:
+			envelope.Retries = 0
+		case valkeysender.ReplayRetriesIncrement:
+			envelope.Retries++
+		}
+		headers := make(map[string]string, len(envelope.Headers)+len(opts.Headers)+1)
+		for key, value := range envelope.Headers {
+			headers[key] = value
+		}
+		for key, value := range opts.Headers {
+			headers[key] = value
+		}
+		headers[valkeysender.HeaderReplayedFrom] = sourceQueue
+		envelope.Headers = headers
+
+		f.queues[targetQueue] = append(f.queues[targetQueue], envelope)
+		replayed++
+	}
+	f.queues[sourceQueue] = kept
+	return replayed, nil
+}
+
+// PeekMessages returns up to count messages starting offset messages from the oldest
+func (f *FakeSender) PeekMessages(ctx context.Context, queue string, offset, count int64) ([]valkeysender.MessageEnvelope, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative")
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+
+	messages := f.Messages(queue)
+	if offset >= int64(len(messages)) {
+		return []valkeysender.MessageEnvelope{}, nil
+	}
+	end := offset + count
+	if end > int64(len(messages)) {
+		end = int64(len(messages))
+	}
+	return messages[offset:end], nil
+}
+
+// ReadArchive returns up to count of the messages recorded for the queue
+// since the given time, oldest first; the fake archives every queue
+func (f *FakeSender) ReadArchive(ctx context.Context, queue string, since time.Time, count int64) ([]valkeysender.ArchivedMessage, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+
+	archived := []valkeysender.ArchivedMessage{}
+	for _, envelope := range f.Messages(queue) {
+		if envelope.Timestamp.Before(since) {
+			continue
+		}
+		if int64(len(archived)) == count {
+			break
+		}
+		archived = append(archived, valkeysender.ArchivedMessage{Timestamp: envelope.Timestamp, Key: queue, Envelope: envelope})
+	}
+	return archived, nil
+}
+
+// GetUsage sums the messages and payload bytes recorded in the current
+// period with the tenant in HeaderTenant
+func (f *FakeSender) GetUsage(ctx context.Context, tenant string, period valkeysender.UsagePeriod) (valkeysender.TenantUsage, error) {
+	start := period.Start(time.Now())
+	if start.IsZero() {
+		return valkeysender.TenantUsage{}, fmt.Errorf("unknown usage period %q", period)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	usage := valkeysender.TenantUsage{Tenant: tenant, Period: period, Start: start}
+	for _, envelopes := range f.queues {
+		for _, envelope := range envelopes {
+			if envelope.Headers[valkeysender.HeaderTenant] == tenant && !envelope.Timestamp.Before(start) {
+				usage.Messages++
+				usage.Bytes += int64(len(envelope.Payload))
+			}
+		}
+	}
+	return usage, nil
+}
+
+// Close marks the sender as closed; later sends fail with ErrClosed
+func (f *FakeSender) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// Drain marks the sender as closed; the fake has nothing in flight to wait for
+func (f *FakeSender) Drain(ctx context.Context) (int64, error) {
+	return 0, f.Close()
+}
+
+// CloseWithContext marks the sender as closed
+func (f *FakeSender) CloseWithContext(ctx context.Context) (int64, error) {
+	return f.Drain(ctx)
+}
+
+// Ping returns the latency set with SetLatency after waiting for it, or the
+// error set with SetError. Pings are not counted as sends.
+func (f *FakeSender) Ping(ctx context.Context) (time.Duration, error) {
+	f.mu.Lock()
+	latency, err, closed := f.latency, f.err, f.closed
+	f.mu.Unlock()
+
+	switch {
+	case closed:
+		return 0, &valkeysender.Error{Op: "ping", Kind: valkeysender.ErrClosed}
+	case err != nil:
+		return 0, err
+	}
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return 0, &valkeysender.Error{Op: "ping", Kind: valkeysender.ErrTimeout, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+	return latency, nil
+}
+
+// Health returns counters of the fake sender
+func (f *FakeSender) Health() valkeysender.HealthStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := valkeysender.HealthStatus{
+		Status:          "healthy",
+		ErrorCount:      f.errors,
+		MessagesSent:    f.sent,
+		LastError:       f.lastError,
+		Uptime:          time.Since(f.startTime),
+		ConnectionState: valkeysender.ConnectionStateConnected,
+		CircuitBreaker:  "closed",
+	}
+	if total := f.sent + f.errors; total > 0 {
+		status.ErrorRate = float64(f.errors) / float64(total)
+		status.LifetimeErrorRate = status.ErrorRate
+	}
+	if f.closed {
+		status.ConnectionState = valkeysender.ConnectionStateDisconnected
+	}
+	if f.err != nil {
+		status.Status = "unhealthy"
+	}
+	return status
+}
+
+// Metrics returns the recorded send and error counts. The fake measures no
+// latencies, so those fields stay zero.
+func (f *FakeSender) Metrics() valkeysender.SenderMetrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return valkeysender.SenderMetrics{
+		MessagesSent:        f.sent,
+		MessagesFailedTotal: f.errors,
+		CircuitBreakerState: "closed",
+		StartTime:           f.startTime,
+	}
+}
+
+// Pressure returns the score set with SetPressure, 0 by default
+func (f *FakeSender) Pressure() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pressure
+}
+
+// FailoverReport returns an empty report; the fake never fails over
+func (f *FakeSender) FailoverReport() valkeysender.FailoverReport {
+	return valkeysender.FailoverReport{}
+}
+
+// SetLogLevel records the level; the fake does not log
+func (f *FakeSender) SetLogLevel(level slog.Level) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logLevel = level
+}
+
+// LogLevel returns the level last set with SetLogLevel, INFO by default
+func (f *FakeSender) LogLevel() slog.Level {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logLevel
+}
+
+// send wraps every message in an envelope and stores them, all or nothing
+func (f *FakeSender) send(ctx context.Context, messages map[string][]interface{}, ttl time.Duration, headers map[string]string, id string) error {
+	defer f.flush()
+	if err := f.before(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queues := make([]string, 0, len(messages))
+	for queue := range messages {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+
+	var envelopes []valkeysender.MessageEnvelope
+	for _, queue := range queues {
+		for i, message := range messages[queue] {
+			payload, err := f.serializer.Serialize(message)
+			if err != nil {
+				return f.record(&valkeysender.Error{
+					Op:    "send message",
+					Queue: queue,
+					Kind:  valkeysender.ErrSerialization,
+					Err:   fmt.Errorf("message %d: %w", i, err),
+				})
+			}
+
+			envelope := valkeysender.MessageEnvelope{
+				Version:   valkeysender.EnvelopeVersion,
+				ID:        id,
+				Queue:     queue,
+				Payload:   payload,
+				Headers:   make(map[string]string, len(headers)),
+				Timestamp: time.Now(),
+				TTL:       ttl,
+			}
+			if envelope.ID == "" {
+				envelope.ID = f.ids.NewID()
+			}
+			// Standard headers, without the producer the fake has no name for
+			envelope.Headers[valkeysender.HeaderContentType] = f.serializer.ContentType()
+			envelope.Headers[valkeysender.HeaderSchemaVersion] = strconv.Itoa(valkeysender.EnvelopeVersion)
+			if es, ok := f.serializer.(valkeysender.EncodingSerializer); ok && es.ContentEncoding() != "" {
+				envelope.Headers[valkeysender.HeaderContentEncoding] = es.ContentEncoding()
+			}
+			for k, v := range valkeysender.HeadersFromContext(ctx) {
+				envelope.Headers[k] = v
+			}
+			for k, v := range headers {
+				envelope.Headers[k] = v
+			}
+			if hs, ok := f.serializer.(valkeysender.HeaderSerializer); ok {
+				for k, v := range hs.Headers(message) {
+					envelope.Headers[k] = v
+				}
+			}
+			envelopes = append(envelopes, envelope)
+		}
+	}
+
+	for _, envelope := range envelopes {
+		f.push(envelope)
+	}
+	return nil
+}
+
+// fanOut stores one envelope ID in every queue
+func (f *FakeSender) fanOut(ctx context.Context, queues []string, message interface{}, headers map[string]string) error {
+	if len(queues) == 0 {
+		return fmt.Errorf("queues slice cannot be empty")
+	}
+	messages := make(map[string][]interface{}, len(queues))
+	for _, queue := range queues {
+		if _, ok := messages[queue]; ok {
+			return fmt.Errorf("duplicate queue %s", queue)
+		}
+		messages[queue] = []interface{}{message}
+	}
+	f.mu.Lock()
+	id := f.ids.NewID()
+	f.mu.Unlock()
+	return f.send(ctx, messages, f.messageTTL, headers, id)
+}
+
+// before applies the injected latency and failures
+func (f *FakeSender) before(ctx context.Context) error {
+	defer f.flush()
+	f.mu.Lock()
+	latency := f.latency
+	f.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return f.lockedRecord(&valkeysender.Error{Op: "send message", Kind: valkeysender.ErrTimeout, Err: ctx.Err()})
+		case <-timer.C:
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case f.closed:
+		return f.record(&valkeysender.Error{Op: "send message", Kind: valkeysender.ErrClosed})
+	case f.failNext > 0:
+		f.failNext--
+		return f.record(f.failErr)
+	case f.err != nil:
+		return f.record(f.err)
+	}
+	return nil
+}
+
+// lockedRecord records a failure, taking the lock
+func (f *FakeSender) lockedRecord(err error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record(err)
+}
+
+// record counts a failure; the caller must hold the lock
+func (f *FakeSender) record(err error) error {
+	f.errors++
+	f.lastError = err.Error()
+
+	event := valkeysender.SenderEvent{Type: valkeysender.EventMessageFailed, Time: time.Now(), Err: err}
+	var sendErr *valkeysender.Error
+	if errors.As(err, &sendErr) {
+		event.Queue = sendErr.Queue
+	}
+	f.pending = append(f.pending, event)
+	return err
+}
+
+// push appends an envelope to its queue; the caller must hold the lock
+func (f *FakeSender) push(envelope valkeysender.MessageEnvelope) {
+	f.queues[envelope.Queue] = append(f.queues[envelope.Queue], envelope)
+	f.last = &envelope
+	f.sent++
+
+	f.pending = append(f.pending, valkeysender.SenderEvent{
+		Type:  valkeysender.EventMessageSent,
+		Time:  time.Now(),
+		Queue: envelope.Queue,
+		Metadata: &valkeysender.MessageMetadata{
+			Queue:     envelope.Queue,
+			MessageID: envelope.ID,
+			Headers:   envelope.Headers,
+			Timestamp: envelope.Timestamp,
+			TTL:       envelope.TTL,
+			Size:      len(envelope.Payload),
+		},
+	})
+}
+
+// Subscribe registers a listener for the fake's events. Only
+// EventMessageSent and EventMessageFailed are emitted, after the send
+// returns its lock, so listeners may inspect the fake.
+func (f *FakeSender) Subscribe(listener valkeysender.EventListener) func() {
+	f.listenMu.Lock()
+	defer f.listenMu.Unlock()
+
+	if f.listeners == nil {
+		f.listeners = make(map[int]valkeysender.EventListener)
+	}
+	id := f.nextListener
+	f.nextListener++
+	f.listeners[id] = listener
+
+	return func() {
+		f.listenMu.Lock()
+		defer f.listenMu.Unlock()
+		delete(f.listeners, id)
+	}
+}
+
+// flush delivers the pending events; the caller must not hold the lock
+func (f *FakeSender) flush() {
+	f.mu.Lock()
+	events := f.pending
+	f.pending = nil
+	f.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+
+	f.listenMu.Lock()
+	listeners := make([]valkeysender.EventListener, 0, len(f.listeners))
+	for _, listener := range f.listeners {
+		listeners = append(listeners, listener)
+	}
+	f.listenMu.Unlock()
+
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// fakeTx stages the messages and key updates of a FakeSender transaction
+type fakeTx struct {
+	messages map[string][]interface{}
+	ops      []func(keys map[string]string) error
+}
+
+// SendMessage stages a message for queue
+func (tx *fakeTx) SendMessage(queue string, message interface{}) error {
+	return tx.SendBatch(queue, []interface{}{message})
+}
+
+// SendBatch stages several messages for queue
+func (tx *fakeTx) SendBatch(queue string, messages []interface{}) error {
+	if len(messages) == 0 {
+		return fmt.Errorf("messages slice cannot be empty")
+	}
+	tx.messages[queue] = append(tx.messages[queue], messages...)
+	return nil
+}
+
+// Set stages writing value to key
+func (tx *fakeTx) Set(key, value string, expiration time.Duration) {
+	tx.ops = append(tx.ops, func(keys map[string]string) error {
+		keys[key] = value
+		return nil
+	})
+}
+
+// IncrBy stages adding n to the integer value of key
+func (tx *fakeTx) IncrBy(key string, n int64) {
+	tx.ops = append(tx.ops, func(keys map[string]string) error {
+		current, err := strconv.ParseInt(keys[key], 10, 64)
+		if err != nil && keys[key] != "" {
+			return fmt.Errorf("value of %s is not an integer", key)
+		}
+		keys[key] = strconv.FormatInt(current+n, 10)
+		return nil
+	})
+}
+
+// Del stages removing keys
+func (tx *fakeTx) Del(keys ...string) {
+	tx.ops = append(tx.ops, func(values map[string]string) error {
+		for _, key := range keys {
+			delete(values, key)
+		}
+		return nil
+	})
+}
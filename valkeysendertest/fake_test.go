@@ -0,0 +1,217 @@
+package valkeysendertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+func TestFakeSender(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	if err := fake.SendMessage(ctx, "orders", map[string]string{"id": "o1"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if err := fake.SendBatch(ctx, "orders", []interface{}{"a", "b"}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+
+	if messages := fake.Messages("orders"); len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	var order map[string]string
+	if err := fake.Decode("orders", 0, &order); err != nil || order["id"] != "o1" {
+		t.Errorf("Expected to decode the first order, got %v (%v)", order, err)
+	}
+	if last, ok := fake.LastEnvelope(); !ok || string(last.Payload) != "b" {
+		t.Errorf("Expected last envelope with payload b, got %+v", last)
+	} else if last.Headers[valkeysender.HeaderContentType] != "application/json" {
+		t.Errorf("Expected the content-type header, got %v", last.Headers)
+	}
+
+	fake.Bind("order.#", "billing", "audit")
+	if err := fake.Route(ctx, "order.created", "event"); err != nil {
+		t.Fatalf("Route failed: %v", err)
+	}
+	billing, audit := fake.Messages("billing"), fake.Messages("audit")
+	if len(billing) != 1 || len(audit) != 1 || billing[0].ID != audit[0].ID {
+		t.Errorf("Expected one shared envelope in billing and audit")
+	}
+	if err := fake.Route(ctx, "user.created", "event"); !errors.Is(err, valkeysender.ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, got %v", err)
+	}
+}
+
+func TestFakeSenderFailureInjection(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+	boom := errors.New("boom")
+
+	fake.FailNext(1, boom)
+	if err := fake.SendMessage(ctx, "orders", "a"); !errors.Is(err, boom) {
+		t.Errorf("Expected injected failure, got %v", err)
+	}
+	if err := fake.SendMessage(ctx, "orders", "a"); err != nil {
+		t.Errorf("Expected FailNext to apply once, got %v", err)
+	}
+
+	fake.SetError(boom)
+	if err := fake.SendRaw(ctx, "orders", []byte("raw")); !errors.Is(err, boom) {
+		t.Errorf("Expected persistent failure, got %v", err)
+	}
+	if health := fake.Health(); health.Status != "unhealthy" || health.ErrorCount != 2 {
+		t.Errorf("Expected unhealthy status with 2 errors, got %+v", health)
+	}
+	fake.SetError(nil)
+
+	fake.SetLatency(time.Second)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := fake.SendMessage(timeoutCtx, "orders", "slow"); !errors.Is(err, valkeysender.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout from simulated latency, got %v", err)
+	}
+
+	fake.Reset()
+	fake.Close()
+	if err := fake.SendMessage(ctx, "orders", "a"); !errors.Is(err, valkeysender.ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestFakeSenderTransactional(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	err := fake.SendTransactional(ctx, func(tx valkeysender.TxSender) error {
+		tx.IncrBy("user:u1:orders", 2)
+		tx.Set("order:o1", "sent", time.Hour)
+		return tx.SendMessage("orders", "o1")
+	})
+	if err != nil {
+		t.Fatalf("SendTransactional failed: %v", err)
+	}
+	if value, _ := fake.Key("user:u1:orders"); value != "2" || len(fake.Messages("orders")) != 1 {
+		t.Errorf("Expected counter 2 and one message, got %q and %d", value, len(fake.Messages("orders")))
+	}
+
+	fake.FailNext(1, errors.New("boom"))
+	err = fake.SendTransactional(ctx, func(tx valkeysender.TxSender) error {
+		tx.Del("order:o1")
+		return tx.SendMessage("orders", "o2")
+	})
+	if err == nil {
+		t.Fatal("Expected injected failure")
+	}
+	if _, ok := fake.Key("order:o1"); !ok || len(fake.Messages("orders")) != 1 {
+		t.Error("Expected a failed transaction to apply nothing")
+	}
+}
+
+func TestFakeSenderIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	opts := valkeysender.SendOptions{MessageID: "m1", IdempotencyKey: "k1"}
+	for i := 0; i < 2; i++ {
+		if err := fake.SendMessageWithOptions(ctx, "orders", "a", opts); err != nil {
+			t.Fatalf("SendMessageWithOptions failed: %v", err)
+		}
+	}
+
+	messages := fake.Messages("orders")
+	if len(messages) != 1 || messages[0].ID != "m1" {
+		t.Errorf("Expected one message with ID m1, got %+v", messages)
+	}
+}
+
+func TestFakeSenderSubscribe(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	var events []valkeysender.SenderEvent
+	unsubscribe := fake.Subscribe(func(event valkeysender.SenderEvent) {
+		// Listeners may inspect the fake
+		fake.Messages(event.Queue)
+		events = append(events, event)
+	})
+
+	fake.SendBatch(ctx, "orders", []interface{}{"a", "b"})
+	fake.FailNext(1, errors.New("boom"))
+	fake.SendMessage(ctx, "orders", "c")
+	if len(events) != 3 || events[0].Type != valkeysender.EventMessageSent || events[0].Metadata.MessageID == "" || events[2].Type != valkeysender.EventMessageFailed {
+		t.Fatalf("Expected two sent events and one failure, got %+v", events)
+	}
+
+	unsubscribe()
+	fake.SendMessage(ctx, "orders", "d")
+	if len(events) != 3 {
+		t.Errorf("Expected no events after unsubscribing, got %d", len(events))
+	}
+}
+
+func TestFakeSenderSendBatchChunked(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	messages := make([]interface{}, valkeysender.DefaultBatchChunkSize+1)
+	for i := range messages {
+		messages[i] = i
+	}
+	result, err := fake.SendBatchChunked(ctx, "orders", messages)
+	if err != nil || result.Chunks != 2 || result.TotalSent != len(messages) {
+		t.Fatalf("Expected %d messages in 2 chunks, got %+v (%v)", len(messages), result, err)
+	}
+	if len(fake.Messages("orders")) != len(messages) {
+		t.Errorf("Expected %d messages, got %d", len(messages), len(fake.Messages("orders")))
+	}
+}
+
+func TestFakeSenderSendAll(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	fake.FailNext(1, errors.New("boom"))
+	result, err := fake.SendAll(ctx, "orders", []interface{}{"a", "b", "c"}, 8)
+	if err == nil || result.TotalSent != 2 || result.Failed != 1 || result.Results[0].Success {
+		t.Fatalf("Expected the first message to fail and the rest to be sent, got %+v (%v)", result, err)
+	}
+	if len(fake.Messages("orders")) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(fake.Messages("orders")))
+	}
+}
+
+func TestFakeSenderPressure(t *testing.T) {
+	fake := NewFakeSender()
+	if p := fake.Pressure(); p != 0 {
+		t.Errorf("Expected no pressure by default, got %v", p)
+	}
+	fake.SetPressure(0.9)
+	if p := fake.Pressure(); p != 0.9 {
+		t.Errorf("Expected pressure 0.9, got %v", p)
+	}
+}
+
+func TestFakeSenderSendBatchWithResult(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeSender()
+
+	fake.SendMessage(ctx, "orders", "a")
+	result, err := fake.SendBatchWithResult(ctx, "orders", []interface{}{"b", "c"})
+	if err != nil || !result.Success || result.TotalSent != 2 || len(result.Results) != 2 {
+		t.Fatalf("Expected 2 messages sent, got %+v (%v)", result, err)
+	}
+	envelopes := fake.Messages("orders")
+	if metadata := result.Results[1].Metadata; metadata == nil || metadata.MessageID != envelopes[2].ID || metadata.Position != 3 {
+		t.Errorf("Expected the metadata of %s, got %+v", envelopes[2].ID, metadata)
+	}
+
+	fake.FailNext(1, errors.New("boom"))
+	result, err = fake.SendBatchWithResult(ctx, "orders", []interface{}{"d"})
+	if err == nil || result.Failed != 1 || result.Results[0].Success {
+		t.Errorf("Expected the batch to fail, got %+v (%v)", result, err)
+	}
+}
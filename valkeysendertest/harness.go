@@ -0,0 +1,300 @@
+package valkeysendertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prilive-com/valkeysender/valkeysender"
+	"github.com/redis/go-redis/v9"
+)
+
+// Environment variables selecting the server used by StartValkey
+const (
+	// EnvAddress points StartValkey at an already running server
+	EnvAddress = "VALKEYSENDERTEST_ADDRESS"
+
+	// EnvContainer makes StartValkey run a container from the given image
+	// (e.g. "valkey/valkey:8"), or DefaultImage if set to "1"
+	EnvContainer = "VALKEYSENDERTEST_CONTAINER"
+)
+
+// DefaultImage is the container image used by StartValkeyContainer when none is given
+const DefaultImage = "valkey/valkey:8"
+
+// Harness is a Valkey server started for a test together with a Config
+// that points at it. Every harness uses its own queue prefix, so tests
+// sharing an external server don't see each other's queues.
+type Harness struct {
+	// Config is ready to pass to valkeysender.NewSender
+	Config *valkeysender.Config
+
+	// Miniredis is the in-process server, or nil for a real one
+	Miniredis *miniredis.Miniredis
+
+	// Codec decodes queued envelopes (default JSON)
+	Codec valkeysender.EnvelopeCodec
+
+	client *redis.Client
+}
+
+// StartValkey starts a server for the test and returns a harness for it.
+// By default it runs miniredis in-process; set EnvAddress to use a running
+// server or EnvContainer to start a container. Everything is torn down
+// when the test ends.
+func StartValkey(t testing.TB) *Harness {
+	t.Helper()
+
+	if addr := os.Getenv(EnvAddress); addr != "" {
+		return newHarness(t, addr, nil)
+	}
+	if image := os.Getenv(EnvContainer); image != "" {
+		if image == "1" {
+			image = ""
+		}
+		return StartValkeyContainer(t, image)
+	}
+
+	server := miniredis.RunT(t)
+	return newHarness(t, server.Addr(), server)
+}
+
+// StartValkeyContainer runs a Valkey container with Docker and returns a
+// harness for it. The test is skipped if Docker isn't available.
+func StartValkeyContainer(t testing.TB, image string) *Harness {
+	t.Helper()
+
+	if image == "" {
+		image = DefaultImage
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::6379", image).Output()
+	if err != nil {
+		t.Fatalf("Failed to start %s container: %v", image, err)
+	}
+	id := strings.TrimSpace(string(out))
+	t.Cleanup(func() { exec.Command("docker", "rm", "-f", id).Run() })
+
+	out, err = exec.Command("docker", "port", id, "6379/tcp").Output()
+	if err != nil {
+		t.Fatalf("Failed to read container port: %v", err)
+	}
+	addr := strings.TrimSpace(strings.Split(string(out), "\n")[0])
+
+	return newHarness(t, addr, nil)
+}
+
+// newHarness waits for the server to answer PING and registers cleanup
+func newHarness(t testing.TB, addr string, server *miniredis.Miniredis) *Harness {
+	t.Helper()
+
+	config := valkeysender.DefaultConfig()
+	config.Address = addr
+	config.HealthCheckInterval = 0
+	config.QueuePrefix = fmt.Sprintf("test:%s:%d:", t.Name(), time.Now().UnixNano())
+
+	h := &Harness{
+		Config:    config,
+		Miniredis: server,
+		Codec:     valkeysender.NewJSONEnvelopeCodec(),
+		client:    redis.NewClient(&redis.Options{Addr: addr}),
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := h.client.Ping(context.Background()).Err()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Valkey at %s is not ready: %v", addr, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		iter := h.client.Scan(ctx, 0, config.QueuePrefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			h.client.Del(ctx, iter.Val())
+		}
+		h.client.Close()
+	})
+
+	return h
+}
+
+// Client returns a client connected to the harness server
+func (h *Harness) Client() *redis.Client {
+	return h.client
+}
+
+// NewSender creates a sender for the harness config and closes it when the
+// test ends. A nil options value gets a logger that discards output.
+func (h *Harness) NewSender(t testing.TB, options *valkeysender.SenderOptions) valkeysender.Sender {
+	t.Helper()
+
+	if options == nil {
+		options = &valkeysender.SenderOptions{}
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	sender, err := valkeysender.NewSender(h.Config, options)
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	t.Cleanup(func() { sender.Close() })
+	return sender
+}
+
+// QueueKey returns the list key of a queue
+func (h *Harness) QueueKey(queue string) string {
+	return h.Config.QueuePrefix + queue
+}
+
+// Envelopes returns the envelopes in a queue, oldest first (the order
+// consumers receive them)
+func (h *Harness) Envelopes(t testing.TB, queue string) []valkeysender.MessageEnvelope {
+	t.Helper()
+
+	values, err := h.client.LRange(context.Background(), h.QueueKey(queue), 0, -1).Result()
+	if err != nil {
+		t.Fatalf("Failed to read queue %s: %v", queue, err)
+	}
+
+	envelopes := make([]valkeysender.MessageEnvelope, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		envelope, err := h.Codec.Decode([]byte(values[i]))
+		if err != nil {
+			t.Fatalf("Failed to decode message %d of queue %s: %v", len(envelopes), queue, err)
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+// RequireQueueLength fails the test unless the queue holds want messages
+func (h *Harness) RequireQueueLength(t testing.TB, queue string, want int64) {
+	t.Helper()
+
+	got, err := h.client.LLen(context.Background(), h.QueueKey(queue)).Result()
+	if err != nil {
+		t.Fatalf("Failed to read length of queue %s: %v", queue, err)
+	}
+	if got != want {
+		t.Fatalf("Expected queue %s to hold %d messages, got %d", queue, want, got)
+	}
+}
+
+// RequireEnvelope returns the envelope at index (0 is the oldest) of a
+// queue, failing the test if there is none
+func (h *Harness) RequireEnvelope(t testing.TB, queue string, index int) valkeysender.MessageEnvelope {
+	t.Helper()
+
+	envelopes := h.Envelopes(t, queue)
+	if index < 0 || index >= len(envelopes) {
+		t.Fatalf("Queue %s has %d messages, no message at index %d", queue, len(envelopes), index)
+	}
+	return envelopes[index]
+}
+
+// EnvelopeExpectation describes the envelope fields checked by AssertEnvelope.
+// Zero fields are not checked.
+type EnvelopeExpectation struct {
+	Queue   string
+	TTL     time.Duration
+	Retries int
+	Headers map[string]string
+
+	// Payload is compared after JSON encoding, so structs, maps and raw
+	// JSON strings can be mixed freely
+	Payload interface{}
+}
+
+// AssertEnvelope reports every field of the envelope that doesn't match the
+// expectation. It also checks the fields every sent envelope must carry.
+func AssertEnvelope(t testing.TB, envelope valkeysender.MessageEnvelope, want EnvelopeExpectation) {
+	t.Helper()
+
+	if envelope.ID == "" {
+		t.Errorf("Expected envelope ID to be set")
+	}
+	if envelope.Version != valkeysender.EnvelopeVersion {
+		t.Errorf("Expected envelope version %d, got %d", valkeysender.EnvelopeVersion, envelope.Version)
+	}
+	if envelope.Timestamp.IsZero() {
+		t.Errorf("Expected envelope timestamp to be set")
+	}
+	if want.Queue != "" && envelope.Queue != want.Queue {
+		t.Errorf("Expected envelope queue %s, got %s", want.Queue, envelope.Queue)
+	}
+	if want.TTL != 0 && envelope.TTL != want.TTL {
+		t.Errorf("Expected envelope TTL %v, got %v", want.TTL, envelope.TTL)
+	}
+	if want.Retries != 0 && envelope.Retries != want.Retries {
+		t.Errorf("Expected %d retries, got %d", want.Retries, envelope.Retries)
+	}
+	for k, v := range want.Headers {
+		AssertHeader(t, envelope, k, v)
+	}
+	if want.Payload != nil {
+		AssertJSONPayload(t, envelope, want.Payload)
+	}
+}
+
+// AssertHeader reports an error unless the envelope carries the header with the given value
+func AssertHeader(t testing.TB, envelope valkeysender.MessageEnvelope, key, want string) {
+	t.Helper()
+
+	got, ok := envelope.Headers[key]
+	if !ok {
+		t.Errorf("Expected header %s=%q, header is missing", key, want)
+	} else if got != want {
+		t.Errorf("Expected header %s=%q, got %q", key, want, got)
+	}
+}
+
+// AssertJSONPayload reports an error unless the envelope payload is JSON
+// equal to want. Strings and byte slices are taken as raw JSON.
+func AssertJSONPayload(t testing.TB, envelope valkeysender.MessageEnvelope, want interface{}) {
+	t.Helper()
+
+	var expected []byte
+	switch v := want.(type) {
+	case string:
+		expected = []byte(v)
+	case []byte:
+		expected = v
+	default:
+		var err error
+		if expected, err = json.Marshal(v); err != nil {
+			t.Fatalf("Failed to encode expected payload: %v", err)
+		}
+	}
+
+	var got, wantValue interface{}
+	if err := json.Unmarshal(envelope.Payload, &got); err != nil {
+		t.Errorf("Envelope payload is not JSON: %v (%s)", err, envelope.Payload)
+		return
+	}
+	if err := json.Unmarshal(expected, &wantValue); err != nil {
+		t.Fatalf("Expected payload is not JSON: %v (%s)", err, expected)
+	}
+	if !reflect.DeepEqual(got, wantValue) {
+		t.Errorf("Expected payload %s, got %s", expected, envelope.Payload)
+	}
+}
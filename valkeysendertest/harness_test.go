@@ -0,0 +1,109 @@
+package valkeysendertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prilive-com/valkeysender/valkeysender"
+)
+
+func TestSenderSendMessage(t *testing.T) {
+	ctx := context.Background()
+	h := StartValkey(t)
+	sender := h.NewSender(t, nil)
+
+	user := map[string]interface{}{"id": 42, "name": "alice"}
+	if err := sender.SendMessage(ctx, "users", user); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	h.RequireQueueLength(t, "users", 1)
+	AssertEnvelope(t, h.RequireEnvelope(t, "users", 0), EnvelopeExpectation{
+		Queue:   "users",
+		TTL:     h.Config.MessageTTL,
+		Payload: `{"id": 42, "name": "alice"}`,
+	})
+}
+
+func TestSenderSendBatchOrder(t *testing.T) {
+	ctx := context.Background()
+	h := StartValkey(t)
+	sender := h.NewSender(t, nil)
+
+	if err := sender.SendBatch(ctx, "jobs", []interface{}{1, 2}); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	if err := sender.SendMessage(ctx, "jobs", 3); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	envelopes := h.Envelopes(t, "jobs")
+	if len(envelopes) != 3 {
+		t.Fatalf("Expected 3 envelopes, got %d", len(envelopes))
+	}
+	for i, envelope := range envelopes {
+		AssertJSONPayload(t, envelope, i+1)
+	}
+	if envelopes[0].ID == envelopes[1].ID {
+		t.Error("Expected every envelope to get its own ID")
+	}
+}
+
+func TestSenderSendMessageWithTTL(t *testing.T) {
+	ctx := context.Background()
+	h := StartValkey(t)
+	sender := h.NewSender(t, nil)
+
+	if err := sender.SendMessageWithTTL(ctx, "sessions", "token", time.Minute); err != nil {
+		t.Fatalf("SendMessageWithTTL failed: %v", err)
+	}
+
+	AssertEnvelope(t, h.RequireEnvelope(t, "sessions", 0), EnvelopeExpectation{TTL: time.Minute})
+	ttl, err := h.Client().TTL(ctx, h.QueueKey("sessions")).Result()
+	if err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected list TTL of at most a minute, got %v (%v)", ttl, err)
+	}
+}
+
+func TestSenderSendTyped(t *testing.T) {
+	ctx := context.Background()
+	h := StartValkey(t)
+	sender := h.NewSender(t, nil)
+
+	if err := sender.SendTyped(ctx, "events", "order_created", map[string]string{"id": "o1"}); err != nil {
+		t.Fatalf("SendTyped failed: %v", err)
+	}
+
+	AssertHeader(t, h.RequireEnvelope(t, "events", 0), valkeysender.HeaderMessageType, "order_created")
+}
+
+func TestSenderServerDown(t *testing.T) {
+	ctx := context.Background()
+	h := StartValkey(t)
+	if h.Miniredis == nil {
+		t.Skip("requires miniredis")
+	}
+	h.Config.BreakerConsecutiveFailures = 2
+	sender := h.NewSender(t, nil)
+
+	h.Miniredis.Close()
+
+	// The breaker trips once the failures exceed the threshold
+	for i := 0; i < 3; i++ {
+		err := sender.SendMessage(ctx, "users", "u")
+		if !errors.Is(err, valkeysender.ErrNotConnected) || !valkeysender.IsRetryable(err) {
+			t.Fatalf("Expected retryable ErrNotConnected, got %v", err)
+		}
+	}
+
+	if err := sender.SendMessage(ctx, "users", "u"); !errors.Is(err, valkeysender.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after consecutive failures, got %v", err)
+	}
+
+	health := sender.Health()
+	if health.ConnectionState == valkeysender.ConnectionStateConnected || health.ErrorCount != 4 {
+		t.Errorf("Expected disconnected sender with 4 errors, got %+v", health)
+	}
+}